@@ -10,7 +10,7 @@ import (
 )
 
 type JWTService interface {
-	GenerateToken(ID int, email, role string, pages []map[string]interface{}) (string, error)
+	GenerateToken(ID int, email, role string, companyID int, pages []map[string]interface{}) (string, error)
 	ValidateToken(tokenString string) (*jwt.Token, error)
 	ParseToken(tokenString string) (map[string]interface{}, error)
 	ParseTokenFromRequest(r *http.Request) (map[string]interface{}, error)
@@ -39,8 +39,8 @@ func New(secretKey string) JWTService {
 	}
 }
 
-// GenerateToken creates a JWT token with user details and permissions.
-func (s *JWTServiceImpl) GenerateToken(ID int, email, role string, pages []map[string]interface{}) (string, error) {
+// GenerateToken creates a JWT token with user details, the selected company and permissions.
+func (s *JWTServiceImpl) GenerateToken(ID int, email, role string, companyID int, pages []map[string]interface{}) (string, error) {
 	// Format pages to structured claims
 	formattedPages := make([]PageClaim, len(pages))
 	for i, page := range pages {
@@ -57,11 +57,12 @@ func (s *JWTServiceImpl) GenerateToken(ID int, email, role string, pages []map[s
 
 	// Create claims
 	claims := jwt.MapClaims{
-		"id":    ID,
-		"email": email,
-		"role":  role,
-		"pages": formattedPages,
-		"exp":   time.Now().Add(24 * time.Hour).Unix(), // Expiration time
+		"id":         ID,
+		"email":      email,
+		"role":       role,
+		"company_id": companyID,
+		"pages":      formattedPages,
+		"exp":        time.Now().Add(24 * time.Hour).Unix(), // Expiration time
 	}
 
 	// Generate token with claims
@@ -111,12 +112,19 @@ func (s *JWTServiceImpl) ParseToken(tokenString string) (map[string]interface{},
 	}
 
 	// Return parsed claims as a map
-	return map[string]interface{}{
+	parsed := map[string]interface{}{
 		"id":    claims["id"],
 		"email": claims["email"],
 		"role":  claims["role"],
 		"pages": claims["pages"],
-	}, nil
+	}
+
+	// Tokens issued before multi-company support carry no company
+	if companyID, exists := claims["company_id"]; exists {
+		parsed["company_id"] = companyID
+	}
+
+	return parsed, nil
 }
 
 // ParseTokenFromRequest extracts and parses a token from the Authorization header.
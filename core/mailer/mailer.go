@@ -0,0 +1,264 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var ErrNotConfigured = errors.New("email delivery is not configured, set SMTP_HOST or SMTP_PICKUP_DIR")
+
+type Attachment struct {
+	FileName    string
+	ContentType string
+	Content     []byte
+}
+
+type Message struct {
+	To          []string
+	Subject     string
+	Body        string
+	Attachments []Attachment
+}
+
+type Mailer interface {
+	Send(msg *Message) error
+}
+
+// Config describes how mail leaves the server. TLS is one of "none",
+// "starttls" or "tls" (implicit TLS, usually port 465).
+type Config struct {
+	Host      string
+	Port      int
+	Username  string
+	Password  string
+	From      string
+	TLS       string
+	PickupDir string
+}
+
+// New returns an SMTP mailer, or a pickup directory mailer that writes each
+// message as an .eml file when PickupDir is set and Host is empty. The pickup
+// directory is meant for local testing without a mail server.
+func New(config Config) Mailer {
+	switch {
+	case config.Host != "":
+		return &smtpMailer{config: config}
+	case config.PickupDir != "":
+		return &pickupMailer{dir: config.PickupDir, from: config.From}
+	default:
+		return disabledMailer{}
+	}
+}
+
+// ============================================
+// SMTP
+// ============================================
+
+type smtpMailer struct {
+	config Config
+}
+
+func (m *smtpMailer) Send(msg *Message) error {
+	data, err := build(m.config.From, msg)
+	if err != nil {
+		return err
+	}
+
+	port := m.config.Port
+	if port == 0 {
+		port = 25
+	}
+	addr := net.JoinHostPort(m.config.Host, fmt.Sprint(port))
+	tlsConfig := &tls.Config{ServerName: m.config.Host}
+
+	var conn net.Conn
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	if m.config.TLS == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("connecting to SMTP server: %w", err)
+	}
+
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("starting SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if m.config.TLS == "starttls" {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("starting TLS: %w", err)
+		}
+	}
+
+	if m.config.Username != "" {
+		auth := smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("authenticating: %w", err)
+		}
+	}
+
+	if err := client.Mail(address(m.config.From)); err != nil {
+		return fmt.Errorf("setting sender: %w", err)
+	}
+	for _, to := range msg.To {
+		if err := client.Rcpt(address(to)); err != nil {
+			return fmt.Errorf("adding recipient %s: %w", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("starting message data: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("sending message: %w", err)
+	}
+
+	return client.Quit()
+}
+
+// ============================================
+// Pickup Directory
+// ============================================
+
+type pickupMailer struct {
+	dir  string
+	from string
+}
+
+func (m *pickupMailer) Send(msg *Message) error {
+	data, err := build(m.from, msg)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return fmt.Errorf("creating pickup directory: %w", err)
+	}
+
+	name := fmt.Sprintf("%s_%s.eml", time.Now().Format("20060102T150405"), randomHex(4))
+	if err := os.WriteFile(filepath.Join(m.dir, name), data, 0o644); err != nil {
+		return fmt.Errorf("writing message: %w", err)
+	}
+
+	return nil
+}
+
+type disabledMailer struct{}
+
+func (disabledMailer) Send(*Message) error {
+	return ErrNotConfigured
+}
+
+// ============================================
+// MIME
+// ============================================
+
+// build renders the message as a multipart/mixed MIME document with a plain
+// text body followed by base64 encoded attachments.
+func build(from string, msg *Message) ([]byte, error) {
+	if len(msg.To) == 0 {
+		return nil, errors.New("message has no recipients")
+	}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+
+	header := func(key, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", key, value)
+	}
+	header("From", from)
+	header("To", strings.Join(msg.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", fmt.Sprintf("<%s@%s>", randomHex(12), domain(from)))
+	header("MIME-Version", "1.0")
+	header("Content-Type", fmt.Sprintf("multipart/mixed; boundary=%q", mw.Boundary()))
+	buf.WriteString("\r\n")
+
+	body, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	writeBase64(body, []byte(msg.Body))
+
+	for _, a := range msg.Attachments {
+		mediaType, params, err := mime.ParseMediaType(a.ContentType)
+		if err != nil {
+			mediaType, params = "application/octet-stream", map[string]string{}
+		}
+		params["name"] = a.FileName
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {mime.FormatMediaType(mediaType, params)},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.FileName})},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		writeBase64(part, a.Content)
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeBase64 writes data base64 encoded in 76 character lines.
+func writeBase64(w io.Writer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		w.Write([]byte(encoded[:76] + "\r\n"))
+		encoded = encoded[76:]
+	}
+	w.Write([]byte(encoded + "\r\n"))
+}
+
+// address strips a display name, "Reports <reports@x.com>" -> "reports@x.com".
+func address(s string) string {
+	if i := strings.LastIndex(s, "<"); i >= 0 {
+		return strings.TrimSuffix(strings.TrimSpace(s[i+1:]), ">")
+	}
+	return strings.TrimSpace(s)
+}
+
+func domain(from string) string {
+	addr := address(from)
+	if i := strings.LastIndex(addr, "@"); i >= 0 {
+		return addr[i+1:]
+	}
+	return "localhost"
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return fmt.Sprintf("%x", b)
+}
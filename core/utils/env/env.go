@@ -15,8 +15,40 @@ type JWTSecret struct {
 	JWTSecret string `env:"JWT_SECRET,required=true"`
 }
 
+// PDFConfig lists TrueType fonts embedded in printed documents. Each value is
+// a comma separated list of .ttf paths tried in order for every character,
+// e.g. a Lao font followed by a Thai font. When empty only Latin text prints.
+type PDFConfig struct {
+	PDFFonts     string `env:"PDF_FONTS"`
+	PDFBoldFonts string `env:"PDF_BOLD_FONTS"`
+}
+
+// SMTPConfig configures outgoing email. SMTPTLS is none, starttls or tls.
+// Leave SMTPHost empty and set SMTPPickupDir to write messages as .eml files
+// instead of sending them, which is handy for local testing.
+type SMTPConfig struct {
+	SMTPHost      string `env:"SMTP_HOST"`
+	SMTPPort      int    `env:"SMTP_PORT,default=587"`
+	SMTPUsername  string `env:"SMTP_USERNAME"`
+	SMTPPassword  string `env:"SMTP_PASSWORD"`
+	SMTPFrom      string `env:"SMTP_FROM,default=FoodHive Reports <reports@foodhive.local>"`
+	SMTPTLS       string `env:"SMTP_TLS,default=starttls"`
+	SMTPPickupDir string `env:"SMTP_PICKUP_DIR"`
+}
+
+// EDIConfig sets the directories EDI files are exchanged through. Partners'
+// files are picked up from EDIInboundDir and ours are written to
+// EDIOutboundDir, so any file transfer tool can move them.
+type EDIConfig struct {
+	EDIInboundDir  string `env:"EDI_INBOUND_DIR"`
+	EDIOutboundDir string `env:"EDI_OUTBOUND_DIR"`
+}
+
 type Config struct {
 	DBConfig
 	StorageConfig
 	JWTSecret
+	PDFConfig
+	SMTPConfig
+	EDIConfig
 }
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
+	"time"
+	_ "time/tzdata" // report schedules use IANA timezones, also on Windows hosts
 
 	envconfig "github.com/Netflix/go-env"
 	"github.com/joho/godotenv"
@@ -15,24 +18,40 @@ import (
 
 	"github.com/anas-dev-92/FoodHive/core/auth"
 	"github.com/anas-dev-92/FoodHive/core/jwt"
+	"github.com/anas-dev-92/FoodHive/core/mailer"
 	"github.com/anas-dev-92/FoodHive/core/postgres"
 	"github.com/anas-dev-92/FoodHive/core/storage"
 	"github.com/anas-dev-92/FoodHive/core/utils/env"
 
 	// _ "github.com/anas-dev-92/FoodHive/registration/docs" // TODO: Enable after generating swagger docs
 	v1 "github.com/anas-dev-92/FoodHive/registration/src/v1"
+	reportService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/report"
+	standingOrderService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/standing_order"
 
 	// Middlewares - Currently implemented
+	mAnalytics "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/analytics"
 	mAP "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/ap"
 	mAR "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/ar"
 	mCatchWeight "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/catch_weight"
+	mCommission "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/commission"
+	mCompany "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/company"
 	mCustomer "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/customer"
+	mCustomerItem "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/customer_item"
+	mDelivery "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/delivery"
+	mDocument "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/document"
+	mEDI "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/edi"
 	mInventory "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/inventory"
+	mLocale "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/locale"
 	mPicking "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/picking"
+	mPortal "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/portal"
 	mPricing "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/pricing"
 	mProduct "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/product"
 	mPurchaseOrder "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/purchase_order"
+	mReport "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/report"
+	mRequisition "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/requisition"
+	mRMA "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/rma"
 	mSalesOrder "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/sales_order"
+	mStandingOrder "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/standing_order"
 	mVendor "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/vendor"
 	mWarehouse "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/warehouse"
 	// TODO: Uncomment as middlewares are implemented
@@ -87,8 +106,10 @@ func main() {
 	log.Println("✓ JWT service initialized")
 
 	// Initialize storage service (MinIO) - optional for development
-	var storageService *storage.MinioStorageService
-	storageService, err = storage.New(
+	// Keep the interface nil (not a typed nil pointer) when MinIO is down so
+	// consumers can check for it.
+	var storageService storage.StorageService
+	minioService, err := storage.New(
 		config.StorageHost,
 		config.StorageKey,
 		config.StorageSecret,
@@ -98,9 +119,43 @@ func main() {
 		log.Printf("⚠ Storage service not available (MinIO): %v", err)
 		log.Println("  File uploads will not work until MinIO is configured")
 	} else {
+		storageService = minioService
 		log.Println("✓ Storage service initialized")
 	}
 
+	// Initialize mailer and the scheduled report service
+	mailService := mailer.New(mailer.Config{
+		Host:      config.SMTPHost,
+		Port:      config.SMTPPort,
+		Username:  config.SMTPUsername,
+		Password:  config.SMTPPassword,
+		From:      config.SMTPFrom,
+		TLS:       config.SMTPTLS,
+		PickupDir: config.SMTPPickupDir,
+	})
+	switch {
+	case config.SMTPHost != "":
+		log.Printf("✓ Mailer initialized (SMTP %s:%d)", config.SMTPHost, config.SMTPPort)
+	case config.SMTPPickupDir != "":
+		log.Printf("✓ Mailer initialized (pickup directory %s)", config.SMTPPickupDir)
+	default:
+		log.Println("⚠ Mailer not configured, scheduled reports will fail until SMTP_HOST is set")
+	}
+
+	reportSvc := reportService.New(db, mailService, mDocument.NewService(db, storageService, config.PDFConfig))
+	go reportSvc.RunScheduler(context.Background(), time.Minute)
+	log.Println("✓ Report scheduler started")
+
+	go standingOrderService.New(db).RunScheduler(context.Background(), 15*time.Minute)
+	log.Println("✓ Standing order scheduler started")
+
+	if config.EDIInboundDir != "" || config.EDIOutboundDir != "" {
+		go mEDI.NewService(db, config.EDIConfig).RunScheduler(context.Background(), time.Minute)
+		log.Println("✓ EDI scheduler started")
+	} else {
+		log.Println("⚠ EDI directories not configured, EDI files can only be uploaded through the API")
+	}
+
 	// Initialize auth service
 	authService := auth.New(db)
 	log.Println("✓ Auth service initialized")
@@ -113,11 +168,12 @@ func main() {
 	app.Use(middleware.Recoverer)
 	app.Use(middleware.RequestID)
 	app.Use(middleware.RealIP)
+	app.Use(mLocale.New())
 	app.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Accept-Language", "Authorization", "Content-Type", "X-CSRF-Token"},
+		ExposedHeaders:   []string{"Link", "Content-Language"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -127,18 +183,30 @@ func main() {
 	// ===========================================
 
 	// Currently implemented
+	app.Use(mCompany.New(db))
 	app.Use(mCustomer.New(db))
 	app.Use(mProduct.New(db))
 	app.Use(mVendor.New(db))
 	app.Use(mWarehouse.New(db))
 	app.Use(mInventory.New(db))
 	app.Use(mPurchaseOrder.New(db))
+	app.Use(mRequisition.New(db))
 	app.Use(mSalesOrder.New(db))
+	app.Use(mStandingOrder.New(db))
+	app.Use(mRMA.New(db))
+	app.Use(mCommission.New(db))
+	app.Use(mAnalytics.New(db))
+	app.Use(mCustomerItem.New(db))
+	app.Use(mPortal.New(db))
 	app.Use(mPicking.New(db))
 	app.Use(mPricing.New(db))
 	app.Use(mAR.New(db))
 	app.Use(mAP.New(db))
 	app.Use(mCatchWeight.New(db))
+	app.Use(mDocument.New(db, storageService, config.PDFConfig))
+	app.Use(mDelivery.New(db, storageService))
+	app.Use(mEDI.New(db, config.EDIConfig))
+	app.Use(mReport.New(reportSvc))
 
 	// TODO: Uncomment as middlewares are implemented
 	// Phase 1: Foundation
@@ -0,0 +1,42 @@
+-- ============================================
+-- Document Printing Tables
+-- Company branding for printed documents and a print log
+-- used to mark reprints
+-- ============================================
+
+-- Company Profile (single row, id = 1)
+CREATE TABLE IF NOT EXISTS company_profile (
+    id SERIAL PRIMARY KEY,
+    company_name VARCHAR(200) NOT NULL,
+    legal_name VARCHAR(200),
+    tax_id VARCHAR(50),
+    address TEXT,
+    phone VARCHAR(50),
+    email VARCHAR(100),
+    website VARCHAR(100),
+    bank_details TEXT,                 -- Printed in the invoice/statement footer
+    footer_text TEXT,
+    logo_bucket VARCHAR(100),          -- Object storage location of the logo
+    logo_path VARCHAR(255),
+    primary_color VARCHAR(7) DEFAULT '#1F4E79', -- Hex colour used for headings
+    updated_by INTEGER REFERENCES employees(id),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+INSERT INTO company_profile (id, company_name)
+VALUES (1, 'FoodHive')
+ON CONFLICT (id) DO NOTHING;
+
+-- Document Print Log
+CREATE TABLE IF NOT EXISTS document_prints (
+    id SERIAL PRIMARY KEY,
+    document_type VARCHAR(30) NOT NULL, -- INVOICE, DELIVERY_NOTE, PURCHASE_ORDER, PICK_LIST, STATEMENT, PAYMENT_VOUCHER, PAYSLIP
+    document_id INTEGER NOT NULL,
+    document_number VARCHAR(50),
+    copy_number INTEGER NOT NULL,       -- 1 = original, > 1 = reprint
+    printed_by INTEGER REFERENCES employees(id),
+    printed_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_prints_document ON document_prints(document_type, document_id);
+CREATE INDEX IF NOT EXISTS idx_document_prints_printed_at ON document_prints(printed_at);
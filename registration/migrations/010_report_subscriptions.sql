@@ -0,0 +1,46 @@
+-- ============================================
+-- Scheduled Report Delivery
+-- Report subscriptions emailed on a schedule and the
+-- delivery log used for retries
+-- ============================================
+
+-- Report Subscriptions
+CREATE TABLE IF NOT EXISTS report_subscriptions (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    report_type VARCHAR(30) NOT NULL,      -- AR_AGING, EXPIRING_INVENTORY, DAILY_SALES_SUMMARY
+    parameters JSONB NOT NULL DEFAULT '{}',
+    format VARCHAR(10) NOT NULL DEFAULT 'PDF', -- PDF, CSV
+    frequency VARCHAR(10) NOT NULL,        -- DAILY, WEEKLY, MONTHLY
+    time_of_day VARCHAR(5) NOT NULL,       -- HH:MM in the subscription timezone
+    day_of_week INTEGER CHECK (day_of_week BETWEEN 0 AND 6),   -- 0 = Sunday, weekly only
+    day_of_month INTEGER CHECK (day_of_month BETWEEN 1 AND 28), -- monthly only
+    timezone VARCHAR(50) NOT NULL DEFAULT 'UTC',
+    recipients TEXT[] NOT NULL,
+    is_active BOOLEAN DEFAULT true,
+    next_run_at TIMESTAMPTZ,
+    last_run_at TIMESTAMPTZ,
+    created_by INTEGER REFERENCES employees(id),
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_subscriptions_due ON report_subscriptions(next_run_at) WHERE is_active;
+
+-- Report Deliveries (one row per scheduled or manual run)
+CREATE TABLE IF NOT EXISTS report_deliveries (
+    id SERIAL PRIMARY KEY,
+    subscription_id INTEGER NOT NULL REFERENCES report_subscriptions(id) ON DELETE CASCADE,
+    scheduled_for TIMESTAMPTZ NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'PENDING', -- PENDING, RETRYING, SENT, FAILED
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ,           -- When a PENDING or RETRYING delivery is picked up
+    recipients TEXT[] NOT NULL,
+    file_name VARCHAR(255),
+    sent_at TIMESTAMPTZ,
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_deliveries_subscription ON report_deliveries(subscription_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_report_deliveries_pending ON report_deliveries(next_attempt_at) WHERE status IN ('PENDING', 'RETRYING');
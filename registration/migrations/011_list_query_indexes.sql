@@ -0,0 +1,62 @@
+-- ============================================
+-- List Query Indexes
+-- Composite indexes matching the default list sorts so keyset
+-- cursors read straight from an index, and NOT NULL on the
+-- sortable columns that keyset comparisons rely on
+-- ============================================
+
+-- Backfill the few rows written before the defaults existed
+UPDATE inventory_transactions SET created_at = NOW() WHERE created_at IS NULL;
+UPDATE customers SET credit_limit = 0 WHERE credit_limit IS NULL;
+UPDATE customers SET current_balance = 0 WHERE current_balance IS NULL;
+UPDATE customers SET payment_terms_days = 30 WHERE payment_terms_days IS NULL;
+UPDATE customers SET created_at = NOW() WHERE created_at IS NULL;
+UPDATE products SET created_at = NOW() WHERE created_at IS NULL;
+UPDATE products SET updated_at = created_at WHERE updated_at IS NULL;
+UPDATE vendors SET payment_terms_days = 30 WHERE payment_terms_days IS NULL;
+UPDATE vendors SET lead_time_days = 7 WHERE lead_time_days IS NULL;
+UPDATE vendors SET created_at = NOW() WHERE created_at IS NULL;
+UPDATE sales_orders SET order_date = created_at::date WHERE order_date IS NULL;
+UPDATE sales_orders SET total_amount = 0 WHERE total_amount IS NULL;
+UPDATE sales_orders SET created_at = NOW() WHERE created_at IS NULL;
+UPDATE purchase_orders SET order_date = created_at::date WHERE order_date IS NULL;
+UPDATE purchase_orders SET total_amount = 0 WHERE total_amount IS NULL;
+UPDATE purchase_orders SET created_at = NOW() WHERE created_at IS NULL;
+
+ALTER TABLE inventory_transactions ALTER COLUMN created_at SET NOT NULL;
+ALTER TABLE customers
+    ALTER COLUMN credit_limit SET NOT NULL,
+    ALTER COLUMN current_balance SET NOT NULL,
+    ALTER COLUMN payment_terms_days SET NOT NULL,
+    ALTER COLUMN created_at SET NOT NULL;
+ALTER TABLE products
+    ALTER COLUMN created_at SET NOT NULL,
+    ALTER COLUMN updated_at SET NOT NULL;
+ALTER TABLE vendors
+    ALTER COLUMN payment_terms_days SET NOT NULL,
+    ALTER COLUMN lead_time_days SET NOT NULL,
+    ALTER COLUMN created_at SET NOT NULL;
+ALTER TABLE sales_orders
+    ALTER COLUMN order_date SET NOT NULL,
+    ALTER COLUMN total_amount SET NOT NULL,
+    ALTER COLUMN created_at SET NOT NULL;
+ALTER TABLE purchase_orders
+    ALTER COLUMN order_date SET NOT NULL,
+    ALTER COLUMN total_amount SET NOT NULL,
+    ALTER COLUMN created_at SET NOT NULL;
+
+-- Inventory transaction history, newest first, overall and per product/warehouse
+CREATE INDEX IF NOT EXISTS idx_inventory_transactions_keyset ON inventory_transactions(created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_inventory_transactions_product_keyset ON inventory_transactions(product_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_inventory_transactions_warehouse_keyset ON inventory_transactions(warehouse_id, created_at DESC, id DESC);
+
+-- Master data, sorted by name
+CREATE INDEX IF NOT EXISTS idx_customers_name_keyset ON customers(name, id);
+CREATE INDEX IF NOT EXISTS idx_products_name_keyset ON products(name, id);
+CREATE INDEX IF NOT EXISTS idx_vendors_name_keyset ON vendors(name, id);
+
+-- Orders, newest first
+CREATE INDEX IF NOT EXISTS idx_sales_orders_date_keyset ON sales_orders(order_date DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_sales_orders_customer_keyset ON sales_orders(customer_id, order_date DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_purchase_orders_date_keyset ON purchase_orders(order_date DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_purchase_orders_vendor_keyset ON purchase_orders(vendor_id, order_date DESC, id DESC);
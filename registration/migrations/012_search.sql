@@ -0,0 +1,78 @@
+-- ============================================
+-- Search
+-- Full-text and trigram indexes behind the unified /search endpoint
+-- for products, customers, vendors and ship-to addresses
+-- ============================================
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- search_normalize folds text the same way for the stored columns and the
+-- query: lower case, Lao composed consonants (ໜ ໝ) written as ຫ + ນ/ມ are
+-- unified, zero-width spaces (common in Lao and Thai text) are dropped and
+-- whitespace is collapsed.
+CREATE OR REPLACE FUNCTION search_normalize(t TEXT) RETURNS TEXT
+LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+    SELECT btrim(regexp_replace(regexp_replace(
+        replace(replace(lower(coalesce(t, '')), 'ຫນ', 'ໜ'), 'ຫມ', 'ໝ'),
+        '[\u200B\u200C\u200D\uFEFF]', '', 'g'), '\s+', ' ', 'g'))
+$$;
+
+-- Lao and Thai are written without spaces between words, so the full-text
+-- parser sees whole phrases as one token. Matching inside those scripts is
+-- done with substring and trigram comparisons on search_text; full-text
+-- vectors give ranked word and prefix matches for Latin text and codes.
+
+-- Products
+ALTER TABLE products ADD COLUMN IF NOT EXISTS search_text TEXT GENERATED ALWAYS AS (
+    search_normalize(sku || ' ' || coalesce(barcode, '') || ' ' || coalesce(upc, '') || ' ' ||
+                     name || ' ' || coalesce(description, ''))
+) STORED;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', sku || ' ' || coalesce(barcode, '') || ' ' || coalesce(upc, '')), 'A') ||
+    setweight(to_tsvector('english', name), 'B') ||
+    setweight(to_tsvector('english', coalesce(description, '')), 'D')
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_products_search_trgm ON products USING GIN (search_text gin_trgm_ops);
+
+-- Customers
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS search_text TEXT GENERATED ALWAYS AS (
+    search_normalize(customer_code || ' ' || name || ' ' || coalesce(delivery_name, '') || ' ' ||
+                     coalesce(default_contact_name, '') || ' ' || coalesce(tel, '') || ' ' ||
+                     coalesce(mobile, '') || ' ' || coalesce(email, ''))
+) STORED;
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', customer_code), 'A') ||
+    setweight(to_tsvector('simple', name || ' ' || coalesce(delivery_name, '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(default_contact_name, '') || ' ' || coalesce(email, '')), 'C')
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_customers_search_vector ON customers USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_customers_search_trgm ON customers USING GIN (search_text gin_trgm_ops);
+
+-- Vendors
+ALTER TABLE vendors ADD COLUMN IF NOT EXISTS search_text TEXT GENERATED ALWAYS AS (
+    search_normalize(vendor_code || ' ' || name || ' ' || coalesce(contact_name, '') || ' ' ||
+                     coalesce(phone, '') || ' ' || coalesce(email, '') || ' ' || coalesce(city, ''))
+) STORED;
+ALTER TABLE vendors ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', vendor_code), 'A') ||
+    setweight(to_tsvector('simple', name), 'B') ||
+    setweight(to_tsvector('simple', coalesce(contact_name, '') || ' ' || coalesce(city, '')), 'C')
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_vendors_search_vector ON vendors USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_vendors_search_trgm ON vendors USING GIN (search_text gin_trgm_ops);
+
+-- Ship-to addresses
+ALTER TABLE customer_ship_to ADD COLUMN IF NOT EXISTS search_text TEXT GENERATED ALWAYS AS (
+    search_normalize(coalesce(ship_to_code, '') || ' ' || coalesce(name, '') || ' ' ||
+                     coalesce(contact_name, '') || ' ' || coalesce(address_line1, '') || ' ' ||
+                     coalesce(address_line2, '') || ' ' || coalesce(city, '') || ' ' || coalesce(phone, ''))
+) STORED;
+ALTER TABLE customer_ship_to ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+    setweight(to_tsvector('simple', coalesce(ship_to_code, '')), 'A') ||
+    setweight(to_tsvector('simple', coalesce(name, '')), 'B') ||
+    setweight(to_tsvector('simple', coalesce(address_line1, '') || ' ' || coalesce(address_line2, '') || ' ' ||
+                                    coalesce(city, '')), 'C')
+) STORED;
+CREATE INDEX IF NOT EXISTS idx_customer_ship_to_search_vector ON customer_ship_to USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_customer_ship_to_search_trgm ON customer_ship_to USING GIN (search_text gin_trgm_ops);
@@ -0,0 +1,22 @@
+-- ============================================
+-- Localization
+-- Language for printed customer and vendor documents, and
+-- translated product and category names
+-- ============================================
+
+-- Invoices, delivery notes and statements print in the customer's language;
+-- purchase orders and payment vouchers in the vendor's.
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS document_language VARCHAR(5) NOT NULL DEFAULT 'en';
+ALTER TABLE vendors ADD COLUMN IF NOT EXISTS document_language VARCHAR(5) NOT NULL DEFAULT 'en';
+
+ALTER TABLE customers DROP CONSTRAINT IF EXISTS chk_customers_document_language;
+ALTER TABLE customers ADD CONSTRAINT chk_customers_document_language
+    CHECK (document_language IN ('en', 'lo', 'th'));
+ALTER TABLE vendors DROP CONSTRAINT IF EXISTS chk_vendors_document_language;
+ALTER TABLE vendors ADD CONSTRAINT chk_vendors_document_language
+    CHECK (document_language IN ('en', 'lo', 'th'));
+
+-- Translated names keyed by language code, e.g. {"lo": "...", "th": "..."}.
+-- The name column stays the primary name used when no translation exists.
+ALTER TABLE products ADD COLUMN IF NOT EXISTS name_translations JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE product_categories ADD COLUMN IF NOT EXISTS name_translations JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,150 @@
+-- ============================================
+-- Multi-Company
+-- Legal entities sharing warehouses and products, with their own
+-- customers, vendors, chart of accounts and fiscal calendar,
+-- intercompany order pairing and consolidation eliminations
+-- ============================================
+
+-- Companies
+CREATE TABLE IF NOT EXISTS companies (
+    id SERIAL PRIMARY KEY,
+    company_code VARCHAR(20) NOT NULL UNIQUE,
+    company_name VARCHAR(200) NOT NULL,
+    legal_name VARCHAR(200),
+    tax_id VARCHAR(50),
+    base_currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    is_active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    updated_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Existing data belongs to company 1
+INSERT INTO companies (id, company_code, company_name, legal_name, tax_id)
+SELECT 1, 'MAIN', cp.company_name, cp.legal_name, cp.tax_id
+FROM company_profile cp
+WHERE cp.id = 1
+ON CONFLICT (id) DO NOTHING;
+
+INSERT INTO companies (id, company_code, company_name)
+VALUES (1, 'MAIN', 'FoodHive')
+ON CONFLICT (id) DO NOTHING;
+
+SELECT setval(pg_get_serial_sequence('companies', 'id'), GREATEST((SELECT MAX(id) FROM companies), 1));
+
+-- Each company's printed branding is the company_profile row with its id
+ALTER TABLE company_profile DROP CONSTRAINT IF EXISTS fk_company_profile_company;
+ALTER TABLE company_profile ADD CONSTRAINT fk_company_profile_company
+    FOREIGN KEY (id) REFERENCES companies(id);
+
+-- Companies an employee may log in to
+CREATE TABLE IF NOT EXISTS employee_companies (
+    employee_id INTEGER NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    company_id INTEGER NOT NULL REFERENCES companies(id),
+    is_default BOOLEAN DEFAULT FALSE,
+    PRIMARY KEY (employee_id, company_id)
+);
+
+INSERT INTO employee_companies (employee_id, company_id, is_default)
+SELECT id, 1, TRUE FROM employees
+ON CONFLICT DO NOTHING;
+
+-- Company dimension on masters and transactions
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id);
+ALTER TABLE vendors ADD COLUMN IF NOT EXISTS company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id);
+ALTER TABLE gl_accounts ADD COLUMN IF NOT EXISTS company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id);
+ALTER TABLE gl_fiscal_years ADD COLUMN IF NOT EXISTS company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id);
+ALTER TABLE gl_journal_entries ADD COLUMN IF NOT EXISTS company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id);
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id);
+ALTER TABLE purchase_orders ADD COLUMN IF NOT EXISTS company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id);
+ALTER TABLE IF EXISTS ar_invoices ADD COLUMN IF NOT EXISTS company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id);
+ALTER TABLE IF EXISTS ap_invoices ADD COLUMN IF NOT EXISTS company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id);
+-- Scheduled reports run without a signed-in user, in the subscriber's company
+ALTER TABLE report_subscriptions ADD COLUMN IF NOT EXISTS company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id);
+
+CREATE INDEX IF NOT EXISTS idx_customers_company ON customers(company_id);
+CREATE INDEX IF NOT EXISTS idx_vendors_company ON vendors(company_id);
+CREATE INDEX IF NOT EXISTS idx_gl_accounts_company ON gl_accounts(company_id);
+CREATE INDEX IF NOT EXISTS idx_gl_fiscal_years_company ON gl_fiscal_years(company_id);
+CREATE INDEX IF NOT EXISTS idx_gl_journal_entries_company ON gl_journal_entries(company_id, entry_date);
+CREATE INDEX IF NOT EXISTS idx_sales_orders_company ON sales_orders(company_id);
+CREATE INDEX IF NOT EXISTS idx_purchase_orders_company ON purchase_orders(company_id);
+
+-- Codes are unique within a company, not across companies
+ALTER TABLE customers DROP CONSTRAINT IF EXISTS customers_customer_code_key;
+ALTER TABLE customers DROP CONSTRAINT IF EXISTS uq_customers_company_code;
+ALTER TABLE customers ADD CONSTRAINT uq_customers_company_code UNIQUE (company_id, customer_code);
+
+ALTER TABLE vendors DROP CONSTRAINT IF EXISTS vendors_vendor_code_key;
+ALTER TABLE vendors DROP CONSTRAINT IF EXISTS uq_vendors_company_code;
+ALTER TABLE vendors ADD CONSTRAINT uq_vendors_company_code UNIQUE (company_id, vendor_code);
+
+ALTER TABLE gl_accounts DROP CONSTRAINT IF EXISTS gl_accounts_account_code_key;
+ALTER TABLE gl_accounts DROP CONSTRAINT IF EXISTS uq_gl_accounts_company_code;
+ALTER TABLE gl_accounts ADD CONSTRAINT uq_gl_accounts_company_code UNIQUE (company_id, account_code);
+
+ALTER TABLE gl_fiscal_years DROP CONSTRAINT IF EXISTS gl_fiscal_years_year_code_key;
+ALTER TABLE gl_fiscal_years DROP CONSTRAINT IF EXISTS uq_gl_fiscal_years_company_code;
+ALTER TABLE gl_fiscal_years ADD CONSTRAINT uq_gl_fiscal_years_company_code UNIQUE (company_id, year_code);
+
+-- Consolidation mapping: accounts with the same consolidation code (or the
+-- same account code when none is set) are added together across companies.
+-- Intercompany accounts are eliminated against the partner company.
+ALTER TABLE gl_accounts ADD COLUMN IF NOT EXISTS consolidation_code VARCHAR(20);
+ALTER TABLE gl_accounts ADD COLUMN IF NOT EXISTS is_intercompany BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE gl_accounts ADD COLUMN IF NOT EXISTS partner_company_id INTEGER REFERENCES companies(id);
+
+-- How a sister company appears in a company's books
+CREATE TABLE IF NOT EXISTS intercompany_partners (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL REFERENCES companies(id),
+    partner_company_id INTEGER NOT NULL REFERENCES companies(id),
+    customer_id INTEGER REFERENCES customers(id), -- Partner as a customer of company_id
+    vendor_id INTEGER REFERENCES vendors(id),     -- Partner as a vendor of company_id
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (company_id, partner_company_id),
+    CONSTRAINT chk_intercompany_partner CHECK (company_id <> partner_company_id)
+);
+
+-- Sales order in the seller paired with the purchase order in the buyer
+CREATE TABLE IF NOT EXISTS intercompany_orders (
+    id SERIAL PRIMARY KEY,
+    sales_order_id INTEGER NOT NULL UNIQUE REFERENCES sales_orders(id),
+    purchase_order_id INTEGER NOT NULL UNIQUE REFERENCES purchase_orders(id),
+    seller_company_id INTEGER NOT NULL REFERENCES companies(id),
+    buyer_company_id INTEGER NOT NULL REFERENCES companies(id),
+    created_by INTEGER REFERENCES employees(id),
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+-- Consolidation eliminations. These live outside the companies' own
+-- ledgers and only affect consolidated reports.
+CREATE TABLE IF NOT EXISTS gl_elimination_entries (
+    id SERIAL PRIMARY KEY,
+    entry_number VARCHAR(30) NOT NULL UNIQUE,
+    entry_date DATE NOT NULL,
+    description TEXT NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'ACTIVE', -- ACTIVE, VOIDED
+    is_generated BOOLEAN DEFAULT FALSE,           -- Created from intercompany balances
+    total_debit DECIMAL(15,2) NOT NULL DEFAULT 0,
+    total_credit DECIMAL(15,2) NOT NULL DEFAULT 0,
+    created_by INTEGER REFERENCES employees(id),
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS gl_elimination_lines (
+    id SERIAL PRIMARY KEY,
+    elimination_id INTEGER NOT NULL REFERENCES gl_elimination_entries(id) ON DELETE CASCADE,
+    line_number INTEGER NOT NULL,
+    company_id INTEGER NOT NULL REFERENCES companies(id),
+    account_id INTEGER NOT NULL REFERENCES gl_accounts(id),
+    description TEXT,
+    debit_amount DECIMAL(15,2) DEFAULT 0,
+    credit_amount DECIMAL(15,2) DEFAULT 0,
+    CONSTRAINT chk_elimination_debit_or_credit CHECK (
+        (debit_amount = 0 AND credit_amount > 0) OR
+        (debit_amount > 0 AND credit_amount = 0)
+    )
+);
+
+CREATE INDEX IF NOT EXISTS idx_gl_elimination_entries_date ON gl_elimination_entries(entry_date);
+CREATE INDEX IF NOT EXISTS idx_gl_elimination_lines_entry ON gl_elimination_lines(elimination_id);
@@ -0,0 +1,31 @@
+-- ============================================
+-- Sales Order Lifecycle
+-- Type-specific rules for quotes, pre-paid, on-hold, advance,
+-- pick-up and credit memo orders
+-- ============================================
+
+-- Quotes end either converted into an order or expired
+ALTER TYPE order_status ADD VALUE IF NOT EXISTS 'CONVERTED';
+ALTER TYPE order_status ADD VALUE IF NOT EXISTS 'EXPIRED';
+
+-- Quotes
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS quote_expiry_date DATE;
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS source_quote_id INTEGER REFERENCES sales_orders(id);
+
+CREATE INDEX IF NOT EXISTS idx_sales_orders_source_quote ON sales_orders(source_quote_id);
+
+-- On-hold orders stay ON_HOLD; the release is recorded next to the hold
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS hold_reason TEXT;
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS hold_released_at TIMESTAMP;
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS hold_released_by INTEGER REFERENCES employees(id);
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS hold_release_reason TEXT;
+
+-- Pick-up orders are collected at the warehouse and never routed
+UPDATE sales_orders SET route_id = NULL WHERE order_type = 'PICK_UP' AND route_id IS NOT NULL;
+
+ALTER TABLE sales_orders DROP CONSTRAINT IF EXISTS chk_sales_orders_pick_up_route;
+ALTER TABLE sales_orders ADD CONSTRAINT chk_sales_orders_pick_up_route
+    CHECK (order_type <> 'PICK_UP' OR route_id IS NULL);
+
+-- Credit memos post to AR as credits with negative amounts
+ALTER TABLE IF EXISTS ar_invoices ADD COLUMN IF NOT EXISTS invoice_type VARCHAR(20) NOT NULL DEFAULT 'INVOICE';
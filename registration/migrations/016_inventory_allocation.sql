@@ -0,0 +1,29 @@
+-- ============================================
+-- Inventory Allocation
+-- Stock earmarked for confirmed sales orders, per inventory row
+-- (lot and location), relieved when the order ships
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS sales_order_allocations (
+    id SERIAL PRIMARY KEY,
+    order_id INTEGER NOT NULL REFERENCES sales_orders(id) ON DELETE CASCADE,
+    order_line_id INTEGER NOT NULL REFERENCES sales_order_lines(id) ON DELETE CASCADE,
+    inventory_id INTEGER NOT NULL REFERENCES inventory(id),
+    quantity DECIMAL(12,3) NOT NULL CHECK (quantity > 0),
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_sales_order_allocations_order ON sales_order_allocations(order_id);
+CREATE INDEX IF NOT EXISTS idx_sales_order_allocations_line ON sales_order_allocations(order_line_id);
+CREATE INDEX IF NOT EXISTS idx_sales_order_allocations_inventory ON sales_order_allocations(inventory_id);
+
+-- Allocated quantity per line, kept in step with sales_order_allocations
+ALTER TABLE sales_order_lines ADD COLUMN IF NOT EXISTS quantity_allocated DECIMAL(10,3) NOT NULL DEFAULT 0;
+
+-- Releases never drive allocation below zero; existing rows are not checked
+ALTER TABLE inventory DROP CONSTRAINT IF EXISTS chk_inventory_allocated_non_negative;
+ALTER TABLE inventory ADD CONSTRAINT chk_inventory_allocated_non_negative
+    CHECK (quantity_allocated >= 0) NOT VALID;
+
+-- Allocation locks every row of a product in a warehouse
+CREATE INDEX IF NOT EXISTS idx_inventory_product_warehouse ON inventory(product_id, warehouse_id);
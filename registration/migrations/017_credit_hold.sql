@@ -0,0 +1,20 @@
+-- ============================================
+-- Credit Hold
+-- Orders that would take a customer past its credit limit, or whose
+-- customer has invoices too long overdue, wait for an AR release
+-- ============================================
+
+-- Days past due after which a customer's new orders are held; 0 turns the
+-- overdue check off
+ALTER TABLE companies ADD COLUMN IF NOT EXISTS credit_hold_overdue_days INTEGER NOT NULL DEFAULT 30
+    CHECK (credit_hold_overdue_days >= 0);
+
+-- The hold and its release are kept on the order
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS credit_hold BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS credit_hold_reason TEXT;
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS credit_held_at TIMESTAMP;
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS credit_released_at TIMESTAMP;
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS credit_released_by INTEGER REFERENCES employees(id);
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS credit_release_reason TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_sales_orders_credit_hold ON sales_orders(company_id) WHERE credit_hold;
@@ -0,0 +1,66 @@
+-- ============================================
+-- Margin Guard
+-- Line cost from the product's costing method, minimum-margin
+-- rules and the below-cost exceptions report
+-- ============================================
+
+-- Which cost a product's sales are measured against
+ALTER TABLE products ADD COLUMN IF NOT EXISTS costing_method VARCHAR(20) NOT NULL DEFAULT 'AVERAGE';
+ALTER TABLE products DROP CONSTRAINT IF EXISTS chk_products_costing_method;
+ALTER TABLE products ADD CONSTRAINT chk_products_costing_method
+    CHECK (costing_method IN ('AVERAGE', 'LAST', 'LANDED', 'MARKET', 'VENDOR', 'ADJUSTED'));
+
+-- Costs maintained through pricing, one per product and method
+CREATE TABLE IF NOT EXISTS product_costs (
+    id SERIAL PRIMARY KEY,
+    product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    costing_method VARCHAR(20) NOT NULL,
+    cost DECIMAL(12,4) NOT NULL DEFAULT 0,
+    effective_date DATE DEFAULT CURRENT_DATE,
+    freight_factor DECIMAL(7,3) DEFAULT 0,
+    duty_factor DECIMAL(7,3) DEFAULT 0,
+    handling_factor DECIMAL(7,3) DEFAULT 0,
+    landed_cost DECIMAL(12,4) DEFAULT 0,
+    notes TEXT,
+    updated_by INTEGER REFERENCES employees(id),
+    updated_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (product_id, costing_method)
+);
+
+-- Minimum margin a sale must keep. Empty dimensions match everything;
+-- every matching rule applies.
+CREATE TABLE IF NOT EXISTS margin_rules (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id),
+    name VARCHAR(100) NOT NULL,
+    category_id INTEGER REFERENCES product_categories(id),
+    customer_group_id INTEGER REFERENCES customer_groups(id),
+    sales_rep_id INTEGER REFERENCES employees(id),
+    min_margin_percent DECIMAL(6,2) NOT NULL,
+    action VARCHAR(10) NOT NULL DEFAULT 'WARN' CHECK (action IN ('BLOCK', 'WARN')),
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by INTEGER REFERENCES employees(id),
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_margin_rules_company ON margin_rules(company_id) WHERE is_active;
+
+-- Lines sold below cost that no rule blocked
+CREATE TABLE IF NOT EXISTS margin_exceptions (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id),
+    order_id INTEGER NOT NULL REFERENCES sales_orders(id) ON DELETE CASCADE,
+    order_line_id INTEGER REFERENCES sales_order_lines(id) ON DELETE SET NULL,
+    product_id INTEGER NOT NULL REFERENCES products(id),
+    customer_id INTEGER NOT NULL REFERENCES customers(id),
+    sales_rep_id INTEGER REFERENCES employees(id),
+    quantity DECIMAL(10,3) NOT NULL,
+    unit_price DECIMAL(12,4) NOT NULL,
+    unit_cost DECIMAL(12,4) NOT NULL,
+    costing_method VARCHAR(20) NOT NULL,
+    margin_percent DECIMAL(8,2) NOT NULL,
+    created_by INTEGER REFERENCES employees(id),
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_margin_exceptions_company_date ON margin_exceptions(company_id, created_at);
@@ -0,0 +1,43 @@
+-- ============================================
+-- Route Calendars
+-- The days each route runs and when orders for a run close, with
+-- company holidays and per-run exceptions
+-- ============================================
+
+-- Weekdays a route delivers on (0 = Sunday). Orders for a run close at
+-- cutoff_time, lead_days before it; no cutoff time means end of that day.
+CREATE TABLE IF NOT EXISTS route_delivery_days (
+    route_id INTEGER NOT NULL REFERENCES routes(id) ON DELETE CASCADE,
+    day_of_week SMALLINT NOT NULL CHECK (day_of_week BETWEEN 0 AND 6),
+    cutoff_time TIME,
+    lead_days INTEGER NOT NULL DEFAULT 1 CHECK (lead_days >= 0),
+    PRIMARY KEY (route_id, day_of_week)
+);
+
+-- Days no route runs unless an exception adds a run
+CREATE TABLE IF NOT EXISTS delivery_holidays (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id),
+    holiday_date DATE NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    UNIQUE (company_id, holiday_date)
+);
+
+-- A run cancelled (is_running = false) or added on a given date; it takes
+-- precedence over the weekly calendar and holidays
+CREATE TABLE IF NOT EXISTS route_calendar_exceptions (
+    id SERIAL PRIMARY KEY,
+    route_id INTEGER NOT NULL REFERENCES routes(id) ON DELETE CASCADE,
+    run_date DATE NOT NULL,
+    is_running BOOLEAN NOT NULL DEFAULT FALSE,
+    cutoff_time TIME,
+    lead_days INTEGER NOT NULL DEFAULT 1 CHECK (lead_days >= 0),
+    reason TEXT,
+    created_by INTEGER REFERENCES employees(id),
+    created_at TIMESTAMP DEFAULT NOW(),
+    UNIQUE (route_id, run_date)
+);
+
+-- Orders are re-routed by run
+CREATE INDEX IF NOT EXISTS idx_sales_orders_route_run ON sales_orders(route_id, requested_ship_date)
+    WHERE route_id IS NOT NULL;
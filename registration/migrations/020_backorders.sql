@@ -0,0 +1,31 @@
+-- ============================================
+-- Backorders and Partial Shipment
+-- Lines ship what was picked; the remainder is backordered on the order,
+-- split into a backorder order or dropped, as the customer prefers.
+-- Invoices bill only what shipped and was not billed before.
+-- ============================================
+
+-- SAME_ORDER keeps the remainder open on the order, NEW_ORDER moves it to a
+-- backorder order, NONE cancels it and records a lost sale
+ALTER TABLE customers ADD COLUMN IF NOT EXISTS backorder_policy VARCHAR(20) NOT NULL DEFAULT 'SAME_ORDER';
+ALTER TABLE customers DROP CONSTRAINT IF EXISTS chk_customers_backorder_policy;
+ALTER TABLE customers ADD CONSTRAINT chk_customers_backorder_policy
+    CHECK (backorder_policy IN ('SAME_ORDER', 'NEW_ORDER', 'NONE'));
+
+-- Picked but not yet shipped, waiting for stock, and billed
+ALTER TABLE sales_order_lines ADD COLUMN IF NOT EXISTS quantity_picked DECIMAL(10,3) NOT NULL DEFAULT 0;
+ALTER TABLE sales_order_lines ADD COLUMN IF NOT EXISTS quantity_backordered DECIMAL(10,3) NOT NULL DEFAULT 0;
+ALTER TABLE sales_order_lines ADD COLUMN IF NOT EXISTS quantity_invoiced DECIMAL(10,3) NOT NULL DEFAULT 0;
+
+-- Orders already invoiced were billed for everything they shipped
+UPDATE sales_order_lines sol SET quantity_invoiced =
+    CASE WHEN so.order_type = 'PRE_PAID' THEN sol.quantity_ordered ELSE sol.quantity_shipped END
+FROM sales_orders so
+WHERE sol.order_id = so.id AND sol.quantity_invoiced = 0
+  AND EXISTS (SELECT 1 FROM ar_invoices i WHERE i.order_id = so.id AND i.status <> 'VOID');
+
+-- Backorder orders point at the order they were split from
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS backorder_of_id INTEGER REFERENCES sales_orders(id);
+
+CREATE INDEX IF NOT EXISTS idx_sales_order_lines_backordered ON sales_order_lines(product_id)
+    WHERE quantity_backordered > 0;
@@ -0,0 +1,74 @@
+-- ============================================
+-- Standing Orders
+-- A customer's repeating order: the same lines delivered on the same
+-- weekdays. Draft sales orders are generated a few days before each
+-- delivery, on the days the customer's route runs.
+-- ============================================
+
+-- How many days before delivery standing orders become draft sales orders
+ALTER TABLE companies ADD COLUMN IF NOT EXISTS standing_order_days_ahead INTEGER NOT NULL DEFAULT 3
+    CHECK (standing_order_days_ahead BETWEEN 0 AND 30);
+
+CREATE TABLE IF NOT EXISTS standing_orders (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id),
+    customer_id INTEGER NOT NULL REFERENCES customers(id),
+    ship_to_id INTEGER REFERENCES customer_ship_to(id),
+    warehouse_id INTEGER NOT NULL REFERENCES warehouses(id),
+    name VARCHAR(100) NOT NULL,
+    delivery_days INTEGER[] NOT NULL,               -- Weekdays, 0 = Sunday
+    effective_from DATE NOT NULL DEFAULT CURRENT_DATE,
+    effective_to DATE,
+    days_ahead INTEGER CHECK (days_ahead BETWEEN 0 AND 30), -- Company setting when NULL
+    paused_at TIMESTAMP,
+    paused_until DATE,                              -- Paused without end when NULL
+    pause_reason TEXT,
+    po_number VARCHAR(50),
+    notes TEXT,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_by INTEGER REFERENCES employees(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (effective_to IS NULL OR effective_to >= effective_from)
+);
+
+CREATE INDEX IF NOT EXISTS idx_standing_orders_customer ON standing_orders(customer_id);
+
+CREATE TABLE IF NOT EXISTS standing_order_lines (
+    id SERIAL PRIMARY KEY,
+    standing_order_id INTEGER NOT NULL REFERENCES standing_orders(id) ON DELETE CASCADE,
+    line_number INTEGER NOT NULL,
+    product_id INTEGER NOT NULL REFERENCES products(id),
+    quantity DECIMAL(10,3) NOT NULL CHECK (quantity > 0),
+    unit_of_measure VARCHAR(20),
+    unit_price DECIMAL(12,4),                       -- Customer price at generation when NULL
+    notes TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_standing_order_lines_order ON standing_order_lines(standing_order_id);
+
+-- Single deliveries the customer does not want
+CREATE TABLE IF NOT EXISTS standing_order_skips (
+    id SERIAL PRIMARY KEY,
+    standing_order_id INTEGER NOT NULL REFERENCES standing_orders(id) ON DELETE CASCADE,
+    delivery_date DATE NOT NULL,
+    reason TEXT,
+    created_by INTEGER REFERENCES employees(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (standing_order_id, delivery_date)
+);
+
+-- What the generator did for each delivery date. The unique key makes sure
+-- a delivery is generated once however many servers run the generator.
+-- Skipped and failed dates are cleared when the standing order changes so
+-- they are looked at again.
+CREATE TABLE IF NOT EXISTS standing_order_runs (
+    id SERIAL PRIMARY KEY,
+    standing_order_id INTEGER NOT NULL REFERENCES standing_orders(id) ON DELETE CASCADE,
+    delivery_date DATE NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('GENERATED', 'SKIPPED', 'FAILED')),
+    sales_order_id INTEGER REFERENCES sales_orders(id) ON DELETE SET NULL,
+    message TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (standing_order_id, delivery_date)
+);
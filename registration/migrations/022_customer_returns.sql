@@ -0,0 +1,78 @@
+-- ============================================
+-- Customer Returns (RMA)
+-- Return authorizations against shipped sales orders. Goods are received
+-- with lot and catch weight, QA restocks, quarantines or destroys them,
+-- and approval issues an AR credit memo posted to the GL.
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS rmas (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id),
+    rma_number VARCHAR(30) NOT NULL,
+    customer_id INTEGER NOT NULL REFERENCES customers(id),
+    order_id INTEGER NOT NULL REFERENCES sales_orders(id),
+    invoice_id INTEGER REFERENCES ar_invoices(id),
+    warehouse_id INTEGER NOT NULL REFERENCES warehouses(id),
+    status VARCHAR(20) NOT NULL DEFAULT 'REQUESTED'
+        CHECK (status IN ('REQUESTED', 'RECEIVED', 'APPROVED', 'CLOSED', 'REJECTED', 'CANCELLED')),
+    reason_code VARCHAR(20) NOT NULL,
+    notes TEXT,
+    received_by INTEGER REFERENCES employees(id),
+    received_at TIMESTAMP,
+    approved_by INTEGER REFERENCES employees(id),
+    approved_at TIMESTAMP,
+    credit_invoice_id INTEGER REFERENCES ar_invoices(id),
+    gl_journal_id INTEGER REFERENCES gl_journal_entries(id),
+    reject_reason TEXT,
+    created_by INTEGER REFERENCES employees(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (company_id, rma_number),
+    CHECK (reason_code IN ('SPOILED', 'DAMAGED', 'WRONG_PRODUCT', 'WRONG_QUANTITY',
+                           'QUALITY', 'SHORT_DATED', 'CUSTOMER_ERROR', 'OTHER'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_rmas_customer ON rmas(customer_id);
+CREATE INDEX IF NOT EXISTS idx_rmas_order ON rmas(order_id);
+
+CREATE TABLE IF NOT EXISTS rma_lines (
+    id SERIAL PRIMARY KEY,
+    rma_id INTEGER NOT NULL REFERENCES rmas(id) ON DELETE CASCADE,
+    line_number INTEGER NOT NULL,
+    order_line_id INTEGER NOT NULL REFERENCES sales_order_lines(id),
+    product_id INTEGER NOT NULL REFERENCES products(id),
+    quantity_authorized DECIMAL(10,3) NOT NULL CHECK (quantity_authorized > 0),
+    quantity_received DECIMAL(10,3) NOT NULL DEFAULT 0 CHECK (quantity_received >= 0),
+    catch_weight_received DECIMAL(10,3),
+    lot_number VARCHAR(50),
+    unit_price DECIMAL(12,4) NOT NULL,              -- Net of the order line discount
+    reason_code VARCHAR(20) NOT NULL,
+    notes TEXT,
+    credit_amount DECIMAL(12,2) NOT NULL DEFAULT 0, -- Set when approved
+    CHECK (reason_code IN ('SPOILED', 'DAMAGED', 'WRONG_PRODUCT', 'WRONG_QUANTITY',
+                           'QUALITY', 'SHORT_DATED', 'CUSTOMER_ERROR', 'OTHER'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_rma_lines_rma ON rma_lines(rma_id);
+CREATE INDEX IF NOT EXISTS idx_rma_lines_order_line ON rma_lines(order_line_id);
+
+-- QA decisions on received goods. Quarantined goods wait in the QUARANTINE
+-- location until released to stock or destroyed.
+CREATE TABLE IF NOT EXISTS rma_dispositions (
+    id SERIAL PRIMARY KEY,
+    rma_line_id INTEGER NOT NULL REFERENCES rma_lines(id) ON DELETE CASCADE,
+    disposition VARCHAR(20) NOT NULL CHECK (disposition IN ('RESTOCK', 'QUARANTINE', 'DESTROY')),
+    quantity DECIMAL(10,3) NOT NULL CHECK (quantity > 0),
+    location_code VARCHAR(50),
+    released_to VARCHAR(20) CHECK (released_to IN ('RESTOCK', 'DESTROY')),
+    released_at TIMESTAMP,
+    released_by INTEGER REFERENCES employees(id),
+    notes TEXT,
+    created_by INTEGER REFERENCES employees(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (released_to IS NULL OR disposition = 'QUARANTINE')
+);
+
+CREATE INDEX IF NOT EXISTS idx_rma_dispositions_line ON rma_dispositions(rma_line_id);
+CREATE INDEX IF NOT EXISTS idx_rma_dispositions_quarantined ON rma_dispositions(rma_line_id)
+    WHERE disposition = 'QUARANTINE' AND released_to IS NULL;
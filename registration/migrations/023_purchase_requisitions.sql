@@ -0,0 +1,21 @@
+-- ============================================
+-- Purchase Requisitions and Special Orders
+-- Requisitions are checked, authorized and converted into purchase
+-- orders. A special order is a requisition raised from a sales order line
+-- for an item the warehouse does not stock; what is received for it is
+-- allocated to that order.
+-- ============================================
+
+ALTER TABLE purchase_requisitions ADD COLUMN IF NOT EXISTS company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id);
+ALTER TABLE purchase_requisitions ADD COLUMN IF NOT EXISTS warehouse_id INTEGER REFERENCES warehouses(id);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_requisitions_company ON purchase_requisitions(company_id);
+
+-- The sales order line a special order is for
+ALTER TABLE purchase_requisition_lines ADD COLUMN IF NOT EXISTS order_line_id INTEGER REFERENCES sales_order_lines(id);
+
+CREATE INDEX IF NOT EXISTS idx_purchase_requisition_lines_order_line ON purchase_requisition_lines(order_line_id)
+    WHERE order_line_id IS NOT NULL;
+
+-- The requisition line a purchase order line was converted from
+ALTER TABLE purchase_order_lines ADD COLUMN IF NOT EXISTS requisition_line_id INTEGER REFERENCES purchase_requisition_lines(id);
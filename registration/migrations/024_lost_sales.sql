@@ -0,0 +1,37 @@
+-- ============================================
+-- Lost Sales Capture
+-- Shortfalls at allocation, picking and backorder cancellation are
+-- recorded automatically with a reason code. Each row keeps the customer,
+-- rep, warehouse and price of the sale so it can be reported without the
+-- order, whose line may since have been deleted.
+-- ============================================
+
+-- OUT_OF_STOCK: the warehouse could not allocate the line
+-- SHORT_PICK: the picker found less than was allocated
+-- BACKORDER_CANCELLED: the order was cancelled while waiting for stock
+-- OTHER: recorded by hand
+ALTER TABLE lost_sales ADD COLUMN IF NOT EXISTS reason_code VARCHAR(30) NOT NULL DEFAULT 'OTHER';
+ALTER TABLE lost_sales DROP CONSTRAINT IF EXISTS chk_lost_sales_reason_code;
+ALTER TABLE lost_sales ADD CONSTRAINT chk_lost_sales_reason_code
+    CHECK (reason_code IN ('OUT_OF_STOCK', 'SHORT_PICK', 'BACKORDER_CANCELLED', 'OTHER'));
+
+ALTER TABLE lost_sales ADD COLUMN IF NOT EXISTS company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id);
+ALTER TABLE lost_sales ADD COLUMN IF NOT EXISTS order_line_id INTEGER REFERENCES sales_order_lines(id) ON DELETE SET NULL;
+ALTER TABLE lost_sales ADD COLUMN IF NOT EXISTS customer_id INTEGER REFERENCES customers(id);
+ALTER TABLE lost_sales ADD COLUMN IF NOT EXISTS sales_rep_id INTEGER REFERENCES employees(id);
+ALTER TABLE lost_sales ADD COLUMN IF NOT EXISTS warehouse_id INTEGER REFERENCES warehouses(id);
+ALTER TABLE lost_sales ADD COLUMN IF NOT EXISTS unit_price DECIMAL(12,4) NOT NULL DEFAULT 0;
+ALTER TABLE lost_sales ADD COLUMN IF NOT EXISTS lost_revenue DECIMAL(12,2) NOT NULL DEFAULT 0;
+ALTER TABLE lost_sales ADD COLUMN IF NOT EXISTS recorded_by INTEGER REFERENCES employees(id);
+
+-- Rows recorded before now take their dimensions from the order
+UPDATE lost_sales ls SET
+    company_id = so.company_id,
+    customer_id = so.customer_id,
+    sales_rep_id = so.sales_rep_id,
+    warehouse_id = so.warehouse_id
+FROM sales_orders so
+WHERE so.id = ls.order_id AND ls.customer_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_lost_sales_company_date ON lost_sales(company_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_lost_sales_product ON lost_sales(product_id, created_at);
@@ -0,0 +1,111 @@
+-- ============================================
+-- Sales Commissions
+-- Commission plans pay reps a rate on revenue or margin, earned when the
+-- sale is invoiced or when it is paid. Plans are assigned to reps for a
+-- range of dates. Statements gather a rep's earnings for a period from AR;
+-- credit memos claw back what was paid on the goods they credit.
+-- ============================================
+
+-- REVENUE pays on the net sale, MARGIN on the sale less its cost.
+-- INVOICED earns when the invoice is posted, PAID as payments are applied.
+CREATE TABLE IF NOT EXISTS commission_plans (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id),
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    basis VARCHAR(20) NOT NULL DEFAULT 'REVENUE' CHECK (basis IN ('REVENUE', 'MARGIN')),
+    earn_on VARCHAR(20) NOT NULL DEFAULT 'INVOICED' CHECK (earn_on IN ('INVOICED', 'PAID')),
+    base_rate DECIMAL(6,3) NOT NULL DEFAULT 0 CHECK (base_rate >= 0),
+    new_customer_bonus_rate DECIMAL(6,3) NOT NULL DEFAULT 0 CHECK (new_customer_bonus_rate >= 0),
+    new_customer_days INTEGER NOT NULL DEFAULT 365 CHECK (new_customer_days >= 0),
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_by INTEGER REFERENCES employees(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (company_id, name)
+);
+
+-- The highest tier a statement's basis reaches sets the rate for all of it
+CREATE TABLE IF NOT EXISTS commission_plan_tiers (
+    id SERIAL PRIMARY KEY,
+    plan_id INTEGER NOT NULL REFERENCES commission_plans(id) ON DELETE CASCADE,
+    threshold DECIMAL(14,2) NOT NULL CHECK (threshold >= 0),
+    rate DECIMAL(6,3) NOT NULL CHECK (rate >= 0),
+    UNIQUE (plan_id, threshold)
+);
+
+-- Products in these categories pay their own rate, whatever the tier
+CREATE TABLE IF NOT EXISTS commission_plan_category_rates (
+    id SERIAL PRIMARY KEY,
+    plan_id INTEGER NOT NULL REFERENCES commission_plans(id) ON DELETE CASCADE,
+    category_id INTEGER NOT NULL REFERENCES product_categories(id),
+    rate DECIMAL(6,3) NOT NULL CHECK (rate >= 0),
+    UNIQUE (plan_id, category_id)
+);
+
+CREATE TABLE IF NOT EXISTS commission_assignments (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id),
+    sales_rep_id INTEGER NOT NULL REFERENCES employees(id),
+    plan_id INTEGER NOT NULL REFERENCES commission_plans(id),
+    effective_from DATE NOT NULL,
+    effective_to DATE,                              -- Open-ended when NULL
+    created_by INTEGER REFERENCES employees(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (effective_to IS NULL OR effective_to >= effective_from)
+);
+
+CREATE INDEX IF NOT EXISTS idx_commission_assignments_rep ON commission_assignments(sales_rep_id, effective_from);
+
+CREATE TABLE IF NOT EXISTS commission_statements (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id),
+    sales_rep_id INTEGER NOT NULL REFERENCES employees(id),
+    period_start DATE NOT NULL,
+    period_end DATE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'DRAFT' CHECK (status IN ('DRAFT', 'APPROVED', 'PAID')),
+    basis_total DECIMAL(14,2) NOT NULL DEFAULT 0,
+    commission_total DECIMAL(12,2) NOT NULL DEFAULT 0,
+    bonus_total DECIMAL(12,2) NOT NULL DEFAULT 0,
+    clawback_total DECIMAL(12,2) NOT NULL DEFAULT 0, -- Negative; included in the other totals
+    total_payable DECIMAL(12,2) NOT NULL DEFAULT 0,
+    approved_by INTEGER REFERENCES employees(id),
+    approved_at TIMESTAMP,
+    paid_at TIMESTAMP,
+    payroll_reference VARCHAR(50),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (company_id, sales_rep_id, period_start, period_end),
+    CHECK (period_end >= period_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_commission_statements_period ON commission_statements(company_id, period_end);
+
+-- One row per invoice line earned: INVOICE when posted, PAYMENT for each
+-- payment applied to it, CREDIT_MEMO for a credit clawed back. A line is
+-- earned on one statement only.
+CREATE TABLE IF NOT EXISTS commission_entries (
+    id SERIAL PRIMARY KEY,
+    statement_id INTEGER NOT NULL REFERENCES commission_statements(id) ON DELETE CASCADE,
+    plan_id INTEGER NOT NULL REFERENCES commission_plans(id),
+    source_type VARCHAR(20) NOT NULL CHECK (source_type IN ('INVOICE', 'PAYMENT', 'CREDIT_MEMO')),
+    source_date DATE NOT NULL,
+    invoice_id INTEGER NOT NULL REFERENCES ar_invoices(id),
+    invoice_line_id INTEGER NOT NULL REFERENCES ar_invoice_lines(id),
+    payment_id INTEGER REFERENCES ar_payments(id),
+    customer_id INTEGER NOT NULL REFERENCES customers(id),
+    product_id INTEGER REFERENCES products(id),
+    category_id INTEGER REFERENCES product_categories(id),
+    revenue DECIMAL(14,2) NOT NULL DEFAULT 0,
+    cost DECIMAL(14,2) NOT NULL DEFAULT 0,
+    basis_amount DECIMAL(14,2) NOT NULL DEFAULT 0,
+    rate DECIMAL(6,3) NOT NULL DEFAULT 0,
+    commission DECIMAL(12,2) NOT NULL DEFAULT 0,
+    new_customer BOOLEAN NOT NULL DEFAULT false,
+    bonus_rate DECIMAL(6,3) NOT NULL DEFAULT 0,
+    bonus DECIMAL(12,2) NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_commission_entries_source
+    ON commission_entries(invoice_line_id, COALESCE(payment_id, 0));
+CREATE INDEX IF NOT EXISTS idx_commission_entries_statement ON commission_entries(statement_id);
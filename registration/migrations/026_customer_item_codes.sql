@@ -0,0 +1,62 @@
+-- ============================================
+-- Customer Item Codes
+-- Chain customers order by their own item numbers. A code maps to one of
+-- our products and units, for one customer or for a whole customer group;
+-- a customer's own mapping wins over its group's. Codes nobody has mapped
+-- yet are queued for the sales desk.
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS customer_item_codes (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id),
+    customer_id INTEGER REFERENCES customers(id) ON DELETE CASCADE,
+    customer_group_id INTEGER REFERENCES customer_groups(id) ON DELETE CASCADE,
+    customer_code VARCHAR(50) NOT NULL,
+    customer_uom VARCHAR(10) NOT NULL DEFAULT '',      -- Empty matches any unit the customer orders in
+    product_id INTEGER NOT NULL REFERENCES products(id),
+    unit_of_measure VARCHAR(10) NOT NULL,              -- Our unit
+    conversion_factor DECIMAL(12,4) NOT NULL DEFAULT 1 CHECK (conversion_factor > 0), -- Our units per customer unit
+    description TEXT,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_by INTEGER REFERENCES employees(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK ((customer_id IS NULL) <> (customer_group_id IS NULL))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_customer_item_codes_customer
+    ON customer_item_codes(customer_id, UPPER(customer_code), UPPER(customer_uom)) WHERE customer_id IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_customer_item_codes_group
+    ON customer_item_codes(customer_group_id, UPPER(customer_code), UPPER(customer_uom)) WHERE customer_group_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_customer_item_codes_product ON customer_item_codes(product_id);
+
+-- Codes ordered before anyone mapped them. One open row per customer, code
+-- and unit, counting how often it came in.
+CREATE TABLE IF NOT EXISTS unmapped_customer_codes (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id),
+    customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    customer_code VARCHAR(50) NOT NULL,
+    customer_uom VARCHAR(10) NOT NULL DEFAULT '',
+    description TEXT,
+    source VARCHAR(20) NOT NULL DEFAULT 'ORDER',       -- ORDER or EDI
+    order_id INTEGER REFERENCES sales_orders(id) ON DELETE SET NULL,
+    occurrences INTEGER NOT NULL DEFAULT 1,
+    first_seen_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    status VARCHAR(20) NOT NULL DEFAULT 'OPEN' CHECK (status IN ('OPEN', 'MAPPED', 'IGNORED')),
+    mapping_id INTEGER REFERENCES customer_item_codes(id) ON DELETE SET NULL,
+    resolved_by INTEGER REFERENCES employees(id),
+    resolved_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_unmapped_customer_codes_open
+    ON unmapped_customer_codes(customer_id, UPPER(customer_code), UPPER(customer_uom)) WHERE status = 'OPEN';
+CREATE INDEX IF NOT EXISTS idx_unmapped_customer_codes_company ON unmapped_customer_codes(company_id, status);
+
+-- The code and unit the customer ordered by, printed on their documents
+ALTER TABLE sales_order_lines ADD COLUMN IF NOT EXISTS customer_item_code VARCHAR(50);
+ALTER TABLE sales_order_lines ADD COLUMN IF NOT EXISTS customer_uom VARCHAR(10);
+
+CREATE INDEX IF NOT EXISTS idx_sales_order_lines_customer_item_code ON sales_order_lines(UPPER(customer_item_code))
+    WHERE customer_item_code IS NOT NULL;
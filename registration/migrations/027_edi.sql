@@ -0,0 +1,96 @@
+-- ============================================
+-- EDI
+-- Trading partners send purchase orders (X12 850 or EDIFACT ORDERS) as
+-- files in a drop directory; they become draft sales orders. We answer
+-- with acknowledgements (855/ORDRSP), ship notices (856/DESADV) and
+-- invoices (810/INVOIC), written to an outbound directory. Every file in
+-- or out is logged with its control numbers.
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS edi_partners (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id),
+    customer_id INTEGER NOT NULL REFERENCES customers(id),
+    name VARCHAR(100) NOT NULL,
+    standard VARCHAR(10) NOT NULL CHECK (standard IN ('X12', 'EDIFACT')),
+    partner_qualifier VARCHAR(4) NOT NULL DEFAULT 'ZZ',  -- ISA05/07, UNB partner code qualifier
+    partner_id VARCHAR(35) NOT NULL,                     -- Their interchange ID
+    our_qualifier VARCHAR(4) NOT NULL DEFAULT 'ZZ',
+    our_id VARCHAR(35) NOT NULL,                         -- Our interchange ID with them
+    warehouse_id INTEGER REFERENCES warehouses(id),      -- Ship-to's, then customer's default when NULL
+    send_acknowledgement BOOLEAN NOT NULL DEFAULT true,
+    send_ship_notice BOOLEAN NOT NULL DEFAULT true,
+    send_invoice BOOLEAN NOT NULL DEFAULT true,
+    test_mode BOOLEAN NOT NULL DEFAULT false,            -- Interchanges flagged as test
+    interchange_control_number INTEGER NOT NULL DEFAULT 0, -- Last one sent
+    group_control_number INTEGER NOT NULL DEFAULT 0,
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    created_by INTEGER REFERENCES employees(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE (company_id, customer_id)
+);
+
+-- Inbound files are routed by sender and receiver, across companies
+CREATE UNIQUE INDEX IF NOT EXISTS idx_edi_partners_ids ON edi_partners(UPPER(partner_id), UPPER(our_id));
+
+-- Partner location codes (N1*ST, NAD+DP) for our ship-to addresses. Codes
+-- not mapped here are matched to the customer's ship-to codes.
+CREATE TABLE IF NOT EXISTS edi_ship_to_mappings (
+    id SERIAL PRIMARY KEY,
+    partner_id INTEGER NOT NULL REFERENCES edi_partners(id) ON DELETE CASCADE,
+    location_code VARCHAR(35) NOT NULL,
+    ship_to_id INTEGER NOT NULL REFERENCES customer_ship_to(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_edi_ship_to_mappings_code ON edi_ship_to_mappings(partner_id, UPPER(location_code));
+
+-- One row per file received or sent
+CREATE TABLE IF NOT EXISTS edi_interchanges (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id),
+    partner_id INTEGER REFERENCES edi_partners(id),      -- NULL when the sender is not a partner
+    direction VARCHAR(3) NOT NULL CHECK (direction IN ('IN', 'OUT')),
+    standard VARCHAR(10),
+    sender_id VARCHAR(35),
+    receiver_id VARCHAR(35),
+    control_number VARCHAR(14),
+    file_name VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('RECEIVED', 'PROCESSED', 'PARTIAL', 'FAILED', 'DUPLICATE', 'SENT')),
+    error_message TEXT,
+    content TEXT NOT NULL,
+    transaction_count INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    processed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_edi_interchanges_company ON edi_interchanges(company_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_edi_interchanges_control ON edi_interchanges(partner_id, direction, control_number);
+
+-- One row per transaction set or message in an interchange
+CREATE TABLE IF NOT EXISTS edi_transactions (
+    id SERIAL PRIMARY KEY,
+    interchange_id INTEGER NOT NULL REFERENCES edi_interchanges(id) ON DELETE CASCADE,
+    document_type VARCHAR(20) NOT NULL CHECK (document_type IN ('PURCHASE_ORDER', 'ACKNOWLEDGEMENT', 'SHIP_NOTICE', 'INVOICE')),
+    message_type VARCHAR(10) NOT NULL,                   -- 850, ORDERS, 810, INVOIC, ...
+    control_number VARCHAR(14) NOT NULL,
+    reference VARCHAR(50),                               -- The PO, order or invoice number
+    order_id INTEGER REFERENCES sales_orders(id) ON DELETE SET NULL,
+    invoice_id INTEGER REFERENCES ar_invoices(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('PROCESSED', 'FAILED', 'SENT')),
+    error_message TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_edi_transactions_interchange ON edi_transactions(interchange_id);
+CREATE INDEX IF NOT EXISTS idx_edi_transactions_order ON edi_transactions(order_id, document_type) WHERE order_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_edi_transactions_invoice ON edi_transactions(invoice_id) WHERE invoice_id IS NOT NULL;
+
+-- The buyer's line numbers on orders received by EDI, echoed back on the
+-- acknowledgement, ship notice and invoice
+CREATE TABLE IF NOT EXISTS edi_order_lines (
+    order_id INTEGER NOT NULL REFERENCES sales_orders(id) ON DELETE CASCADE,
+    line_number INTEGER NOT NULL,
+    buyer_line_number VARCHAR(20) NOT NULL,
+    PRIMARY KEY (order_id, line_number)
+);
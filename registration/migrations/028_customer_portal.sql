@@ -0,0 +1,38 @@
+-- ============================================
+-- Customer Portal
+-- Customer users sign in to their own API to browse the catalog at their
+-- prices, start orders from their order guide and look up invoices,
+-- statements and aging. A user sees the whole account or only the
+-- ship-tos assigned to them. Orders they submit are drafts that a rep
+-- reviews before confirming.
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS customer_portal_users (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id),
+    customer_id INTEGER NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    password_hash VARCHAR(100) NOT NULL,   -- bcrypt
+    name VARCHAR(100) NOT NULL,
+    phone VARCHAR(50),
+    all_ship_tos BOOLEAN NOT NULL DEFAULT true, -- False limits the user to customer_portal_user_ship_tos
+    is_active BOOLEAN NOT NULL DEFAULT true,
+    last_login_at TIMESTAMP,
+    created_by INTEGER REFERENCES employees(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Emails sign in across companies, so they are unique everywhere
+CREATE UNIQUE INDEX IF NOT EXISTS idx_customer_portal_users_email ON customer_portal_users(LOWER(email));
+CREATE INDEX IF NOT EXISTS idx_customer_portal_users_customer ON customer_portal_users(customer_id);
+
+CREATE TABLE IF NOT EXISTS customer_portal_user_ship_tos (
+    user_id INTEGER NOT NULL REFERENCES customer_portal_users(id) ON DELETE CASCADE,
+    ship_to_id INTEGER NOT NULL REFERENCES customer_ship_to(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, ship_to_id)
+);
+
+-- Orders submitted through the portal, for reps to review
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS portal_user_id INTEGER REFERENCES customer_portal_users(id);
+CREATE INDEX IF NOT EXISTS idx_sales_orders_portal_user ON sales_orders(portal_user_id) WHERE portal_user_id IS NOT NULL;
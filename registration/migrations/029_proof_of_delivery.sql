@@ -0,0 +1,53 @@
+-- ============================================
+-- Proof of Delivery
+-- What the customer signed for when a shipped order was delivered, alone
+-- or at a route stop. Refused quantities are returned on an RMA that is
+-- received and credited with the delivery.
+-- ============================================
+
+CREATE TABLE IF NOT EXISTS delivery_confirmations (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id),
+    order_id INTEGER NOT NULL UNIQUE REFERENCES sales_orders(id),
+    route_stop_id INTEGER REFERENCES route_stops(id) ON DELETE SET NULL,
+    recipient_name VARCHAR(100) NOT NULL,
+    signature_path TEXT NOT NULL,                   -- Object in the deliveries bucket
+    delivered_at TIMESTAMP NOT NULL,
+    latitude DECIMAL(9,6) CHECK (latitude BETWEEN -90 AND 90),
+    longitude DECIMAL(9,6) CHECK (longitude BETWEEN -180 AND 180),
+    notes TEXT,
+    rma_id INTEGER REFERENCES rmas(id),             -- Return for the refused goods
+    confirmed_by INTEGER REFERENCES employees(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK ((latitude IS NULL) = (longitude IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_delivery_confirmations_stop ON delivery_confirmations(route_stop_id)
+    WHERE route_stop_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS delivery_confirmation_lines (
+    id SERIAL PRIMARY KEY,
+    confirmation_id INTEGER NOT NULL REFERENCES delivery_confirmations(id) ON DELETE CASCADE,
+    order_line_id INTEGER NOT NULL REFERENCES sales_order_lines(id),
+    product_id INTEGER NOT NULL REFERENCES products(id),
+    quantity_shipped DECIMAL(10,3) NOT NULL,
+    quantity_accepted DECIMAL(10,3) NOT NULL CHECK (quantity_accepted >= 0),
+    quantity_refused DECIMAL(10,3) NOT NULL DEFAULT 0 CHECK (quantity_refused >= 0),
+    refusal_reason VARCHAR(20),
+    notes TEXT,
+    UNIQUE (confirmation_id, order_line_id),
+    CHECK ((quantity_refused > 0) = (refusal_reason IS NOT NULL)),
+    CHECK (refusal_reason IN ('SPOILED', 'DAMAGED', 'WRONG_PRODUCT', 'WRONG_QUANTITY',
+                              'QUALITY', 'SHORT_DATED', 'CUSTOMER_ERROR', 'OTHER'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_delivery_confirmation_lines_confirmation ON delivery_confirmation_lines(confirmation_id);
+
+CREATE TABLE IF NOT EXISTS delivery_photos (
+    id SERIAL PRIMARY KEY,
+    confirmation_id INTEGER NOT NULL REFERENCES delivery_confirmations(id) ON DELETE CASCADE,
+    file_path TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_delivery_photos_confirmation ON delivery_photos(confirmation_id);
@@ -0,0 +1,47 @@
+-- ============================================
+-- Freight Rules
+-- Delivery charges worked out with the order totals: a flat fee per route,
+-- free delivery over an order value, weight tiers and pick-up or delivery
+-- surcharges. The charge can be overridden on the order, and posts to the
+-- company's freight income account.
+-- ============================================
+
+-- Where invoiced freight is credited; other income when not set
+ALTER TABLE companies ADD COLUMN IF NOT EXISTS freight_income_account_id INTEGER REFERENCES gl_accounts(id);
+
+CREATE TABLE IF NOT EXISTS freight_rules (
+    id SERIAL PRIMARY KEY,
+    company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id),
+    name VARCHAR(100) NOT NULL,
+    rule_type VARCHAR(20) NOT NULL
+        CHECK (rule_type IN ('ROUTE_FEE', 'FREE_DELIVERY', 'WEIGHT_TIER', 'SURCHARGE')),
+    route_id INTEGER REFERENCES routes(id) ON DELETE CASCADE,  -- Empty matches every route
+    fulfillment VARCHAR(10) CHECK (fulfillment IN ('DELIVERY', 'PICK_UP')),  -- Surcharges only
+    min_order_value DECIMAL(12,2),                  -- Free delivery from this subtotal
+    min_weight_kg DECIMAL(10,3),                    -- Weight tier, from this weight
+    max_weight_kg DECIMAL(10,3),                    -- up to but not including this one
+    amount DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (amount >= 0),
+    rate_per_kg DECIMAL(10,4) NOT NULL DEFAULT 0 CHECK (rate_per_kg >= 0),  -- Weight tiers only
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by INTEGER REFERENCES employees(id),
+    created_at TIMESTAMP DEFAULT NOW(),
+    CHECK ((rule_type = 'SURCHARGE') = (fulfillment IS NOT NULL)),
+    CHECK ((rule_type = 'FREE_DELIVERY') = (min_order_value IS NOT NULL)),
+    CHECK (rule_type = 'WEIGHT_TIER' OR (min_weight_kg IS NULL AND max_weight_kg IS NULL)),
+    CHECK (max_weight_kg IS NULL OR max_weight_kg > COALESCE(min_weight_kg, 0))
+);
+
+CREATE INDEX IF NOT EXISTS idx_freight_rules_company ON freight_rules(company_id, rule_type) WHERE is_active;
+
+-- Freight set by hand stays as it is when the order totals change
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS freight_overridden BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS freight_override_reason TEXT;
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS freight_overridden_by INTEGER REFERENCES employees(id);
+ALTER TABLE sales_orders ADD COLUMN IF NOT EXISTS freight_overridden_at TIMESTAMP;
+
+-- Overriding the freight rules needs its own page permission: update sets
+-- an order's freight by hand, delete returns it to the rules
+INSERT INTO pages (page_name, route_name, icon, parent_id) VALUES
+    ('Freight Override', '/sales-orders/freight-override', 'truck',
+     (SELECT id FROM pages WHERE route_name = '/sales-orders'))
+ON CONFLICT (route_name) DO NOTHING;
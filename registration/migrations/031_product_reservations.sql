@@ -0,0 +1,41 @@
+-- ============================================
+-- Product Reservations
+-- Stock set aside in a warehouse for a customer, a customer group or a
+-- sales rep's customers until an expiry date. Nobody else can allocate it;
+-- the party's own orders use it up first, and what they release goes back
+-- to the reservation.
+-- ============================================
+
+ALTER TABLE product_reservations ADD COLUMN IF NOT EXISTS company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id);
+ALTER TABLE product_reservations ADD COLUMN IF NOT EXISTS quantity_consumed DECIMAL(10,3) NOT NULL DEFAULT 0;
+ALTER TABLE product_reservations ADD COLUMN IF NOT EXISTS notes TEXT;
+ALTER TABLE product_reservations ADD COLUMN IF NOT EXISTS released_at TIMESTAMP;   -- Given up before it expired
+ALTER TABLE product_reservations ADD COLUMN IF NOT EXISTS released_by INTEGER REFERENCES employees(id);
+ALTER TABLE product_reservations ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT NOW();
+
+ALTER TABLE product_reservations DROP CONSTRAINT IF EXISTS chk_product_reservations_type;
+ALTER TABLE product_reservations ADD CONSTRAINT chk_product_reservations_type
+    CHECK (reserved_for_type IN ('CUSTOMER', 'CUSTOMER_GROUP', 'SALES_REP', 'SALES_ORDER'));
+ALTER TABLE product_reservations DROP CONSTRAINT IF EXISTS chk_product_reservations_consumed;
+ALTER TABLE product_reservations ADD CONSTRAINT chk_product_reservations_consumed
+    CHECK (quantity_consumed >= 0 AND quantity_consumed <= quantity);
+
+CREATE INDEX IF NOT EXISTS idx_product_reservations_stock ON product_reservations(product_id, warehouse_id)
+    WHERE released_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_product_reservations_party ON product_reservations(reserved_for_type, reserved_for_id);
+CREATE INDEX IF NOT EXISTS idx_product_reservations_expiry ON product_reservations(company_id, expiry_date)
+    WHERE released_at IS NULL;
+
+-- What each order line took from a reservation, so releasing the line's
+-- stock hands it back
+CREATE TABLE IF NOT EXISTS product_reservation_consumptions (
+    id SERIAL PRIMARY KEY,
+    reservation_id INTEGER NOT NULL REFERENCES product_reservations(id) ON DELETE CASCADE,
+    order_id INTEGER NOT NULL REFERENCES sales_orders(id) ON DELETE CASCADE,
+    order_line_id INTEGER NOT NULL REFERENCES sales_order_lines(id) ON DELETE CASCADE,
+    quantity DECIMAL(10,3) NOT NULL CHECK (quantity > 0),
+    created_at TIMESTAMP DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_reservation_consumptions_line ON product_reservation_consumptions(order_line_id);
+CREATE INDEX IF NOT EXISTS idx_reservation_consumptions_reservation ON product_reservation_consumptions(reservation_id);
@@ -0,0 +1,19 @@
+-- ============================================
+-- Payment Companies
+-- Receipts and vendor payments belong to the company of their customer or
+-- vendor, like the invoices they settle, so each company only sees its own.
+-- ============================================
+
+ALTER TABLE IF EXISTS ar_payments ADD COLUMN IF NOT EXISTS company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id);
+ALTER TABLE IF EXISTS ap_payments ADD COLUMN IF NOT EXISTS company_id INTEGER NOT NULL DEFAULT 1 REFERENCES companies(id);
+
+UPDATE ar_payments p SET company_id = c.company_id
+FROM customers c
+WHERE c.id = p.customer_id AND p.company_id <> c.company_id;
+
+UPDATE ap_payments p SET company_id = v.company_id
+FROM vendors v
+WHERE v.id = p.vendor_id AND p.company_id <> v.company_id;
+
+CREATE INDEX IF NOT EXISTS idx_ar_payments_company ON ar_payments(company_id, payment_date);
+CREATE INDEX IF NOT EXISTS idx_ap_payments_company ON ap_payments(company_id, payment_date);
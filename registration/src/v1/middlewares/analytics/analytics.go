@@ -0,0 +1,31 @@
+package analytics
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	analyticsService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/analytics"
+)
+
+type contextKey string
+
+const analyticsKey = contextKey("analytics_service")
+
+// New creates a middleware that injects the analytics service into the request context
+func New(db postgres.Executor) func(http.Handler) http.Handler {
+	svc := analyticsService.New(db)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), analyticsKey, svc)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Instance retrieves the analytics service from the context
+func Instance(ctx context.Context) (analyticsService.AnalyticsService, bool) {
+	svc, ok := ctx.Value(analyticsKey).(analyticsService.AnalyticsService)
+	return svc, ok
+}
@@ -8,7 +8,9 @@ import (
 	"github.com/anas-dev-92/FoodHive/core/auth"
 	"github.com/anas-dev-92/FoodHive/core/jwt"
 	"github.com/anas-dev-92/FoodHive/core/postgres"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -57,6 +59,11 @@ func GetRole(ctx context.Context) (string, bool) {
 	return role, ok
 }
 
+// GetCompanyID retrieves the company selected at login from context
+func GetCompanyID(ctx context.Context) int {
+	return tenant.Company(ctx)
+}
+
 // Authenticate validates the JWT token and extracts user information
 func Authenticate(jwtService jwt.JWTService) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -92,9 +99,9 @@ func Authenticate(jwtService jwt.JWTService) func(http.Handler) http.Handler {
 			}
 			userID := int(userIDFloat)
 
-			// Extract role
+			// Extract role (customer portal tokens are only good on the portal)
 			role, ok := claims["role"].(string)
-			if !ok {
+			if !ok || role == models.PortalRole {
 				helper.UnauthorizedResponse(w, r)
 				return
 			}
@@ -102,12 +109,19 @@ func Authenticate(jwtService jwt.JWTService) func(http.Handler) http.Handler {
 			// Extract pages/permissions (optional)
 			pages, _ := claims["pages"].([]interface{})
 
+			// Extract company (tokens issued before multi-company support have none)
+			companyID := tenant.DefaultCompany
+			if c, ok := claims["company_id"].(float64); ok {
+				companyID = int(c)
+			}
+
 			// Add values to context
 			ctx := r.Context()
 			ctx = context.WithValue(ctx, EmailKey, email)
 			ctx = context.WithValue(ctx, UserIDKey, userID)
 			ctx = context.WithValue(ctx, RoleKey, role)
 			ctx = context.WithValue(ctx, PagesKey, pages)
+			ctx = tenant.WithCompany(ctx, companyID)
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
@@ -156,20 +170,7 @@ func Authorize(jwtService jwt.JWTService) func(next http.Handler) http.Handler {
 				// We need to check if route contains the routeName
 				if strings.Contains(route, routeName) || strings.HasPrefix(route, routeName) {
 					// Authorization based on HTTP method and route action
-					switch {
-					case r.Method == http.MethodPost && getBool(permissions, "can_create"):
-						next.ServeHTTP(w, r)
-						return
-					case r.Method == http.MethodGet && getBool(permissions, "can_view"):
-						next.ServeHTTP(w, r)
-						return
-					case r.Method == http.MethodPut && getBool(permissions, "can_update"):
-						next.ServeHTTP(w, r)
-						return
-					case r.Method == http.MethodPatch && getBool(permissions, "can_update"):
-						next.ServeHTTP(w, r)
-						return
-					case r.Method == http.MethodDelete && getBool(permissions, "can_delete"):
+					if methodAllowed(r.Method, permissions) {
 						next.ServeHTTP(w, r)
 						return
 					}
@@ -181,6 +182,42 @@ func Authorize(jwtService jwt.JWTService) func(next http.Handler) http.Handler {
 	}
 }
 
+// AuthorizePage checks the user's permission on one page, for actions that
+// need more than access to the route they are under. Unlike Authorize the
+// page must match exactly.
+func AuthorizePage(jwtService jwt.JWTService, routeName string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tokenData, err := jwtService.ParseTokenFromRequest(r)
+			if err != nil {
+				helper.UnauthorizedResponse(w, r)
+				return
+			}
+
+			pagesInterface, ok := tokenData["pages"].([]interface{})
+			if !ok {
+				helper.ForbiddenResponse(w, r)
+				return
+			}
+
+			for _, page := range pagesInterface {
+				pageData, ok := page.(map[string]interface{})
+				if !ok || pageData["route_name"] != routeName {
+					continue
+				}
+
+				permissions, ok := pageData["permissions"].(map[string]interface{})
+				if ok && methodAllowed(r.Method, permissions) {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			helper.ForbiddenResponse(w, r)
+		})
+	}
+}
+
 // AuthorizeRoles checks if the user has one of the specified roles
 func AuthorizeRoles(allowedRoles ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -209,6 +246,21 @@ func containsAction(route, action string) bool {
 	return strings.Contains(strings.ToLower(route), action)
 }
 
+// methodAllowed reports whether a page's permissions allow the HTTP method.
+func methodAllowed(method string, permissions map[string]interface{}) bool {
+	switch method {
+	case http.MethodPost:
+		return getBool(permissions, "can_create")
+	case http.MethodGet:
+		return getBool(permissions, "can_view")
+	case http.MethodPut, http.MethodPatch:
+		return getBool(permissions, "can_update")
+	case http.MethodDelete:
+		return getBool(permissions, "can_delete")
+	}
+	return false
+}
+
 func getBool(m map[string]interface{}, key string) bool {
 	if val, ok := m[key].(bool); ok {
 		return val
@@ -0,0 +1,31 @@
+package commission
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	commissionService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/commission"
+)
+
+type contextKey string
+
+const commissionKey = contextKey("commission_service")
+
+// New creates a middleware that injects the commission service into the request context
+func New(db postgres.Executor) func(http.Handler) http.Handler {
+	svc := commissionService.New(db)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), commissionKey, svc)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Instance retrieves the commission service from the context
+func Instance(ctx context.Context) (commissionService.CommissionService, bool) {
+	svc, ok := ctx.Value(commissionKey).(commissionService.CommissionService)
+	return svc, ok
+}
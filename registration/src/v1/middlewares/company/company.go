@@ -0,0 +1,31 @@
+package company
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	companyService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/company"
+)
+
+type contextKey string
+
+const companyKey = contextKey("company_service")
+
+// New creates a middleware that injects the company service into the request context
+func New(db postgres.Executor) func(http.Handler) http.Handler {
+	svc := companyService.New(db.(postgres.Connection))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), companyKey, svc)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Instance retrieves the company service from the context
+func Instance(ctx context.Context) (companyService.CompanyService, bool) {
+	svc, ok := ctx.Value(companyKey).(companyService.CompanyService)
+	return svc, ok
+}
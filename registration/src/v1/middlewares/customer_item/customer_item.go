@@ -0,0 +1,31 @@
+package customer_item
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	customerItemService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/customer_item"
+)
+
+type contextKey string
+
+const customerItemKey = contextKey("customer_item_service")
+
+// New creates a middleware that injects the customer item service into the request context
+func New(db postgres.Executor) func(http.Handler) http.Handler {
+	svc := customerItemService.New(db)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), customerItemKey, svc)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Instance retrieves the customer item service from the context
+func Instance(ctx context.Context) (customerItemService.CustomerItemService, bool) {
+	svc, ok := ctx.Value(customerItemKey).(customerItemService.CustomerItemService)
+	return svc, ok
+}
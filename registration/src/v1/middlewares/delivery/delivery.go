@@ -0,0 +1,32 @@
+package delivery
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	"github.com/anas-dev-92/FoodHive/core/storage"
+	deliveryService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/delivery"
+)
+
+type contextKey string
+
+const deliveryKey = contextKey("delivery_service")
+
+// New creates a middleware that injects the delivery service into the request context
+func New(db postgres.Executor, storageService storage.StorageService) func(http.Handler) http.Handler {
+	svc := deliveryService.New(db, storageService)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), deliveryKey, svc)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Instance retrieves the delivery service from the context
+func Instance(ctx context.Context) (deliveryService.DeliveryService, bool) {
+	svc, ok := ctx.Value(deliveryKey).(deliveryService.DeliveryService)
+	return svc, ok
+}
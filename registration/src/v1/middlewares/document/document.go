@@ -0,0 +1,66 @@
+package document
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	"github.com/anas-dev-92/FoodHive/core/storage"
+	"github.com/anas-dev-92/FoodHive/core/utils/env"
+	documentService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/document"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/pdf"
+)
+
+type contextKey string
+
+const documentKey = contextKey("document_service")
+
+// New creates a middleware that injects the document service into the request context
+func New(db postgres.Executor, storageService storage.StorageService, config env.PDFConfig) func(http.Handler) http.Handler {
+	svc := NewService(db, storageService, config)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), documentKey, svc)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Instance retrieves the document service from the context
+func Instance(ctx context.Context) (documentService.DocumentService, bool) {
+	svc, ok := ctx.Value(documentKey).(documentService.DocumentService)
+	return svc, ok
+}
+
+// NewService creates a document service with the configured fonts loaded, for
+// callers that render documents outside a request such as scheduled reports.
+func NewService(db postgres.Executor, storageService storage.StorageService, config env.PDFConfig) documentService.DocumentService {
+	return documentService.New(
+		db.(postgres.Connection),
+		storageService,
+		loadFonts(config.PDFFonts),
+		loadFonts(config.PDFBoldFonts),
+	)
+}
+
+// loadFonts parses a comma separated list of font files, skipping any that
+// cannot be read so a bad path only degrades non-Latin output.
+func loadFonts(paths string) []*pdf.TrueTypeFont {
+	var fonts []*pdf.TrueTypeFont
+	for _, path := range strings.Split(paths, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
+			continue
+		}
+		font, err := pdf.LoadTrueTypeFile(path)
+		if err != nil {
+			log.Printf("⚠ PDF font not loaded: %v", err)
+			continue
+		}
+		fonts = append(fonts, font)
+	}
+	return fonts
+}
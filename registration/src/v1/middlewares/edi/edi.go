@@ -0,0 +1,38 @@
+package edi
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	"github.com/anas-dev-92/FoodHive/core/utils/env"
+	ediService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/edi"
+)
+
+type contextKey string
+
+const ediKey = contextKey("edi_service")
+
+// New creates a middleware that injects the EDI service into the request context
+func New(db postgres.Executor, config env.EDIConfig) func(http.Handler) http.Handler {
+	svc := NewService(db, config)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), ediKey, svc)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Instance retrieves the EDI service from the context
+func Instance(ctx context.Context) (ediService.EDIService, bool) {
+	svc, ok := ctx.Value(ediKey).(ediService.EDIService)
+	return svc, ok
+}
+
+// NewService creates an EDI service on the configured directories, for the
+// scheduler that polls them outside a request.
+func NewService(db postgres.Executor, config env.EDIConfig) ediService.EDIService {
+	return ediService.New(db, config.EDIInboundDir, config.EDIOutboundDir)
+}
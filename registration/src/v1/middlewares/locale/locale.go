@@ -0,0 +1,20 @@
+package locale
+
+import (
+	"net/http"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/i18n"
+)
+
+// New creates a middleware that negotiates the response language from the
+// Accept-Language header and stores it in the request context
+func New() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lang := i18n.Negotiate(r.Header.Get("Accept-Language"))
+			w.Header().Set("Content-Language", string(lang))
+			w.Header().Add("Vary", "Accept-Language")
+			next.ServeHTTP(w, r.WithContext(i18n.WithLang(r.Context(), lang)))
+		})
+	}
+}
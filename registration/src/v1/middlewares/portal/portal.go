@@ -0,0 +1,90 @@
+package portal
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/anas-dev-92/FoodHive/core/jwt"
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	portalService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/portal"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+)
+
+type contextKey string
+
+const (
+	portalKey = contextKey("portal_service")
+	userKey   = contextKey("portal_user")
+)
+
+// New creates a middleware that injects the portal service into the request context
+func New(db postgres.Executor) func(http.Handler) http.Handler {
+	svc := portalService.New(db)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), portalKey, svc)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Instance retrieves the portal service from the context
+func Instance(ctx context.Context) (portalService.PortalService, bool) {
+	svc, ok := ctx.Value(portalKey).(portalService.PortalService)
+	return svc, ok
+}
+
+// User retrieves the signed-in portal user from the context
+func User(ctx context.Context) (*models.PortalIdentity, bool) {
+	user, ok := ctx.Value(userKey).(*models.PortalIdentity)
+	return user, ok
+}
+
+// Authenticate accepts only portal tokens. The user is loaded on every
+// request, so deactivating a user or changing their ship-tos applies to
+// tokens already issued; the company is the user's own, not the token's.
+func Authenticate(jwtService jwt.JWTService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			parts := strings.Fields(r.Header.Get("Authorization"))
+			if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+				helper.UnauthorizedResponse(w, r)
+				return
+			}
+
+			claims, err := jwtService.ParseToken(strings.Trim(parts[1], "\""))
+			if err != nil {
+				helper.UnauthorizedResponse(w, r)
+				return
+			}
+			if role, _ := claims["role"].(string); role != models.PortalRole {
+				helper.UnauthorizedResponse(w, r)
+				return
+			}
+			userID, ok := claims["id"].(float64)
+			if !ok {
+				helper.UnauthorizedResponse(w, r)
+				return
+			}
+
+			svc, ok := Instance(r.Context())
+			if !ok {
+				helper.UnauthorizedResponse(w, r)
+				return
+			}
+			user, err := svc.Identify(r.Context(), int(userID))
+			if err != nil {
+				helper.UnauthorizedResponse(w, r)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), userKey, user)
+			ctx = tenant.WithCompany(ctx, user.CompanyID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package report
+
+import (
+	"context"
+	"net/http"
+
+	reportService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/report"
+)
+
+type contextKey string
+
+const reportKey = contextKey("report_service")
+
+// New creates a middleware that injects the report service into the request
+// context. It takes the service rather than the database because main also
+// runs the scheduler on the same instance.
+func New(svc reportService.ReportService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), reportKey, svc)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Instance retrieves the report service from the context
+func Instance(ctx context.Context) (reportService.ReportService, bool) {
+	svc, ok := ctx.Value(reportKey).(reportService.ReportService)
+	return svc, ok
+}
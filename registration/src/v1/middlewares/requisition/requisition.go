@@ -0,0 +1,31 @@
+package requisition
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	requisitionService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/requisition"
+)
+
+type contextKey string
+
+const requisitionKey = contextKey("requisition_service")
+
+// New creates a middleware that injects the requisition service into the request context
+func New(db postgres.Executor) func(http.Handler) http.Handler {
+	svc := requisitionService.New(db)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), requisitionKey, svc)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Instance retrieves the requisition service from the context
+func Instance(ctx context.Context) (requisitionService.RequisitionService, bool) {
+	svc, ok := ctx.Value(requisitionKey).(requisitionService.RequisitionService)
+	return svc, ok
+}
@@ -0,0 +1,31 @@
+package rma
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	rmaService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/rma"
+)
+
+type contextKey string
+
+const rmaKey = contextKey("rma_service")
+
+// New creates a middleware that injects the RMA service into the request context
+func New(db postgres.Executor) func(http.Handler) http.Handler {
+	svc := rmaService.New(db)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), rmaKey, svc)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Instance retrieves the RMA service from the context
+func Instance(ctx context.Context) (rmaService.RMAService, bool) {
+	svc, ok := ctx.Value(rmaKey).(rmaService.RMAService)
+	return svc, ok
+}
@@ -0,0 +1,31 @@
+package search
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	searchService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/search"
+)
+
+type contextKey string
+
+const searchKey = contextKey("search_service")
+
+// New creates a middleware that injects the search service into the request context
+func New(db postgres.Executor) func(http.Handler) http.Handler {
+	svc := searchService.New(db.(postgres.Connection))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), searchKey, svc)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Instance retrieves the search service from the context
+func Instance(ctx context.Context) (searchService.SearchService, bool) {
+	svc, ok := ctx.Value(searchKey).(searchService.SearchService)
+	return svc, ok
+}
@@ -0,0 +1,31 @@
+package standing_order
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	standingOrderService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/standing_order"
+)
+
+type contextKey string
+
+const standingOrderKey = contextKey("standing_order_service")
+
+// New creates a middleware that injects the standing order service into the request context
+func New(db postgres.Executor) func(http.Handler) http.Handler {
+	svc := standingOrderService.New(db)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), standingOrderKey, svc)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// Instance retrieves the standing order service from the context
+func Instance(ctx context.Context) (standingOrderService.StandingOrderService, bool) {
+	svc, ok := ctx.Value(standingOrderKey).(standingOrderService.StandingOrderService)
+	return svc, ok
+}
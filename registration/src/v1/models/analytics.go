@@ -0,0 +1,141 @@
+package models
+
+import "time"
+
+// ============================================
+// Sales Analytics Models
+// ============================================
+
+// SalesGroup is what sales analytics are totalled by. Each group has a
+// filter of the same name (product_id, category_id, ...) that narrows the
+// analytics or the drill-through documents to one of its rows.
+type SalesGroup string
+
+const (
+	SalesByProduct       SalesGroup = "product"
+	SalesByCategory      SalesGroup = "category"
+	SalesByCustomer      SalesGroup = "customer"
+	SalesByCustomerGroup SalesGroup = "customer_group"
+	SalesByRep           SalesGroup = "rep"
+	SalesByRoute         SalesGroup = "route"
+	SalesByWarehouse     SalesGroup = "warehouse"
+)
+
+// SalesMetrics are sales for a period as billed: posted invoices less
+// credit memos. Cost is what the order lines shipped at, or the product's
+// average cost for lines billed without an order. Weight is in kilograms,
+// the caught weight for catch weight lines.
+type SalesMetrics struct {
+	Revenue       float64  `json:"revenue"`
+	Cost          float64  `json:"cost"`
+	GrossMargin   float64  `json:"gross_margin"`
+	MarginPercent *float64 `json:"margin_percent,omitempty"` // Not set without revenue
+	Quantity      float64  `json:"quantity"`
+	Weight        float64  `json:"weight"`
+	OrderCount    int      `json:"order_count"`
+}
+
+// SalesComparison compares the period with an earlier one. Changes are
+// percentages of the earlier value and are not set when it was zero.
+type SalesComparison struct {
+	SalesMetrics
+	RevenueChange     *float64 `json:"revenue_change,omitempty"`
+	GrossMarginChange *float64 `json:"gross_margin_change,omitempty"`
+	QuantityChange    *float64 `json:"quantity_change,omitempty"`
+	OrderCountChange  *float64 `json:"order_count_change,omitempty"`
+}
+
+// SalesAnalyticsRow is one group's sales. GroupID is the product, category,
+// customer, customer group, rep, route or warehouse; it is not set for
+// sales that have none, such as orders off any route.
+type SalesAnalyticsRow struct {
+	GroupID     *int            `json:"group_id,omitempty"`
+	Label       string          `json:"label"`
+	Current     SalesMetrics    `json:"current"`
+	PriorPeriod SalesComparison `json:"prior_period"`
+	LastYear    SalesComparison `json:"last_year"`
+}
+
+type SalesPeriod struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// SalesAnalytics compares a date range with the same number of days just
+// before it and with the same dates a year earlier.
+type SalesAnalytics struct {
+	GroupBy     SalesGroup          `json:"group_by"`
+	Period      SalesPeriod         `json:"period"`
+	PriorPeriod SalesPeriod         `json:"prior_period"`
+	LastYear    SalesPeriod         `json:"last_year"`
+	Rows        []SalesAnalyticsRow `json:"rows"`
+	Totals      SalesAnalyticsRow   `json:"totals"`
+}
+
+// SalesDocument is an invoice or credit memo behind the analytics, with the
+// order it billed.
+type SalesDocument struct {
+	InvoiceID     int        `json:"invoice_id"`
+	InvoiceNumber string     `json:"invoice_number"`
+	InvoiceType   string     `json:"invoice_type"`
+	InvoiceDate   CustomDate `json:"invoice_date"`
+	OrderID       *int       `json:"order_id,omitempty"`
+	OrderNumber   string     `json:"order_number,omitempty"`
+	CustomerID    int        `json:"customer_id"`
+	CustomerName  string     `json:"customer_name"`
+	Revenue       float64    `json:"revenue"`
+	Cost          float64    `json:"cost"`
+	GrossMargin   float64    `json:"gross_margin"`
+	Quantity      float64    `json:"quantity"`
+	Weight        float64    `json:"weight"`
+}
+
+// ============================================
+// Filters
+// ============================================
+
+// SalesAnalyticsFilters select the sales analysed, by invoice date between
+// DateFrom and DateTo; the month to date by default.
+type SalesAnalyticsFilters struct {
+	GroupBy         SalesGroup
+	DateFrom        string
+	DateTo          string
+	ProductID       *int
+	CategoryID      *int
+	CustomerID      *int
+	CustomerGroupID *int
+	SalesRepID      *int
+	RouteID         *int
+	WarehouseID     *int
+	Page            int // Drill-through documents only
+	PageSize        int
+}
+
+func ValidateSalesAnalytics(v *Validator, f *SalesAnalyticsFilters) {
+	if f.GroupBy != "" {
+		v.Check(ValidSalesGroup(f.GroupBy), "group_by",
+			"Group by must be product, category, customer, customer_group, rep, route or warehouse")
+	}
+	var from, to time.Time
+	var err error
+	if f.DateFrom != "" {
+		from, err = time.Parse("2006-01-02", f.DateFrom)
+		v.Check(err == nil, "date_from", "Date must be YYYY-MM-DD")
+	}
+	if f.DateTo != "" {
+		to, err = time.Parse("2006-01-02", f.DateTo)
+		v.Check(err == nil, "date_to", "Date must be YYYY-MM-DD")
+	}
+	if !from.IsZero() && !to.IsZero() {
+		v.Check(!to.Before(from), "date_to", "Date to must be on or after date from")
+	}
+}
+
+func ValidSalesGroup(g SalesGroup) bool {
+	switch g {
+	case SalesByProduct, SalesByCategory, SalesByCustomer, SalesByCustomerGroup,
+		SalesByRep, SalesByRoute, SalesByWarehouse:
+		return true
+	}
+	return false
+}
@@ -1,5 +1,7 @@
 package models
 
+import "github.com/anas-dev-92/FoodHive/registration/src/v1/utils/query"
+
 // ============================================
 // AP Enums
 // ============================================
@@ -192,6 +194,7 @@ type APInvoiceListFilters struct {
 	Overdue  bool             `json:"overdue,omitempty"`
 	Page     int              `json:"page"`
 	PageSize int              `json:"page_size"`
+	Query    *query.Params    `json:"-"`
 }
 
 // ============================================
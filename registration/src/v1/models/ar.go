@@ -1,5 +1,7 @@
 package models
 
+import "github.com/anas-dev-92/FoodHive/registration/src/v1/utils/query"
+
 // ============================================
 // AR (Accounts Receivable) Enums
 // ============================================
@@ -64,6 +66,9 @@ type ARInvoiceLine struct {
 	TaxPercent  float64 `json:"tax_percent"`
 	LineTotal   float64 `json:"line_total"`
 	OrderLineID *int    `json:"order_line_id,omitempty"`
+
+	// The customer's code for the product, from the order line
+	CustomerItemCode string `json:"customer_item_code,omitempty"`
 }
 
 type ARInvoiceWithDetails struct {
@@ -187,6 +192,17 @@ type CreateARInvoiceLineReq struct {
 	Quantity    float64 `json:"quantity"`
 	UnitPrice   float64 `json:"unit_price"`
 	TaxPercent  float64 `json:"tax_percent,omitempty"`
+	OrderLineID *int    `json:"order_line_id,omitempty"`
+}
+
+// CreateARCreditMemoRequest gives the amounts to credit as positive
+// figures; the credit memo stores them negated.
+type CreateARCreditMemoRequest struct {
+	CustomerID int                      `json:"customer_id"`
+	OrderID    *int                     `json:"order_id,omitempty"`
+	TaxAmount  float64                  `json:"tax_amount,omitempty"`
+	Notes      string                   `json:"notes,omitempty"`
+	Lines      []CreateARInvoiceLineReq `json:"lines"`
 }
 
 type CreateARPaymentRequest struct {
@@ -211,8 +227,11 @@ type ARInvoiceListFilters struct {
 	DateFrom   string           `json:"date_from,omitempty"`
 	DateTo     string           `json:"date_to,omitempty"`
 	Overdue    bool             `json:"overdue,omitempty"`
+	ShipToIDs  []int            `json:"-"` // Invoices for orders to these ship-tos only
+	PostedOnly bool             `json:"-"` // Leaves out drafts
 	Page       int              `json:"page"`
 	PageSize   int              `json:"page_size"`
+	Query      *query.Params    `json:"-"`
 }
 
 // ============================================
@@ -0,0 +1,300 @@
+package models
+
+import "time"
+
+// ============================================
+// Sales Commission Models
+// ============================================
+
+type CommissionBasis string
+
+const (
+	CommissionOnRevenue CommissionBasis = "REVENUE" // Net sale
+	CommissionOnMargin  CommissionBasis = "MARGIN"  // Net sale less cost
+)
+
+type CommissionEarnOn string
+
+const (
+	CommissionEarnedInvoiced CommissionEarnOn = "INVOICED" // When the invoice is posted
+	CommissionEarnedPaid     CommissionEarnOn = "PAID"     // As payments are applied
+)
+
+type CommissionStatementStatus string
+
+const (
+	CommissionStatementDraft    CommissionStatementStatus = "DRAFT"
+	CommissionStatementApproved CommissionStatementStatus = "APPROVED"
+	CommissionStatementPaid     CommissionStatementStatus = "PAID"
+)
+
+type CommissionSource string
+
+const (
+	CommissionSourceInvoice    CommissionSource = "INVOICE"
+	CommissionSourcePayment    CommissionSource = "PAYMENT"
+	CommissionSourceCreditMemo CommissionSource = "CREDIT_MEMO" // Clawback
+)
+
+// CommissionPlan pays its base rate unless the statement's basis reaches a
+// tier, whose rate then applies to the whole period. Category rates take
+// precedence over both. Sales to a customer first invoiced within
+// NewCustomerDays earn the bonus rate on top.
+type CommissionPlan struct {
+	ID                   int                      `json:"id"`
+	Name                 string                   `json:"name"`
+	Description          string                   `json:"description,omitempty"`
+	Basis                CommissionBasis          `json:"basis"`
+	EarnOn               CommissionEarnOn         `json:"earn_on"`
+	BaseRate             float64                  `json:"base_rate"` // Percent
+	NewCustomerBonusRate float64                  `json:"new_customer_bonus_rate"`
+	NewCustomerDays      int                      `json:"new_customer_days"`
+	IsActive             bool                     `json:"is_active"`
+	Tiers                []CommissionTier         `json:"tiers"`
+	CategoryRates        []CommissionCategoryRate `json:"category_rates"`
+	CreatedBy            *int                     `json:"created_by,omitempty"`
+	CreatedAt            time.Time                `json:"created_at"`
+	UpdatedAt            time.Time                `json:"updated_at"`
+}
+
+type CommissionTier struct {
+	Threshold float64 `json:"threshold"` // Basis the period must reach
+	Rate      float64 `json:"rate"`
+}
+
+type CommissionCategoryRate struct {
+	CategoryID   int     `json:"category_id"`
+	CategoryName string  `json:"category_name,omitempty"`
+	Rate         float64 `json:"rate"`
+}
+
+type CommissionAssignment struct {
+	ID            int        `json:"id"`
+	SalesRepID    int        `json:"sales_rep_id"`
+	SalesRepName  string     `json:"sales_rep_name"`
+	PlanID        int        `json:"plan_id"`
+	PlanName      string     `json:"plan_name"`
+	EffectiveFrom CustomDate `json:"effective_from"`
+	EffectiveTo   CustomDate `json:"effective_to"`
+	CreatedBy     *int       `json:"created_by,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// CommissionStatement is what a rep earned in a period. Drafts are
+// recalculated each time statements are generated; approved statements
+// are fixed and wait for payroll.
+type CommissionStatement struct {
+	ID               int                       `json:"id"`
+	SalesRepID       int                       `json:"sales_rep_id"`
+	SalesRepName     string                    `json:"sales_rep_name"`
+	PeriodStart      CustomDate                `json:"period_start"`
+	PeriodEnd        CustomDate                `json:"period_end"`
+	Status           CommissionStatementStatus `json:"status"`
+	BasisTotal       float64                   `json:"basis_total"`
+	CommissionTotal  float64                   `json:"commission_total"`
+	BonusTotal       float64                   `json:"bonus_total"`
+	ClawbackTotal    float64                   `json:"clawback_total"` // Included in the totals above
+	TotalPayable     float64                   `json:"total_payable"`
+	ApprovedBy       *int                      `json:"approved_by,omitempty"`
+	ApprovedAt       *time.Time                `json:"approved_at,omitempty"`
+	PaidAt           *time.Time                `json:"paid_at,omitempty"`
+	PayrollReference string                    `json:"payroll_reference,omitempty"`
+	CreatedAt        time.Time                 `json:"created_at"`
+	UpdatedAt        time.Time                 `json:"updated_at"`
+	Entries          []CommissionEntry         `json:"entries,omitempty"`
+}
+
+type CommissionEntry struct {
+	ID            int              `json:"id"`
+	PlanID        int              `json:"plan_id"`
+	PlanName      string           `json:"plan_name"`
+	SourceType    CommissionSource `json:"source_type"`
+	SourceDate    CustomDate       `json:"source_date"`
+	InvoiceID     int              `json:"invoice_id"`
+	InvoiceNumber string           `json:"invoice_number"`
+	PaymentID     *int             `json:"payment_id,omitempty"`
+	CustomerID    int              `json:"customer_id"`
+	CustomerName  string           `json:"customer_name"`
+	ProductID     *int             `json:"product_id,omitempty"`
+	ProductName   string           `json:"product_name,omitempty"`
+	Revenue       float64          `json:"revenue"`
+	Cost          float64          `json:"cost"`
+	BasisAmount   float64          `json:"basis_amount"`
+	Rate          float64          `json:"rate"`
+	Commission    float64          `json:"commission"`
+	NewCustomer   bool             `json:"new_customer"`
+	BonusRate     float64          `json:"bonus_rate"`
+	Bonus         float64          `json:"bonus"`
+}
+
+// CommissionPayrollTotal is one rep's commission to pay, summed over the
+// statements in the period.
+type CommissionPayrollTotal struct {
+	SalesRepID      int     `json:"sales_rep_id"`
+	SalesRepName    string  `json:"sales_rep_name"`
+	Email           string  `json:"email"`
+	Statements      int     `json:"statements"`
+	StatementIDs    []int   `json:"statement_ids"`
+	CommissionTotal float64 `json:"commission_total"`
+	BonusTotal      float64 `json:"bonus_total"`
+	ClawbackTotal   float64 `json:"clawback_total"`
+	TotalPayable    float64 `json:"total_payable"`
+}
+
+// ============================================
+// Request DTOs
+// ============================================
+
+type CreateCommissionPlanRequest struct {
+	Name                 string                   `json:"name"`
+	Description          string                   `json:"description,omitempty"`
+	Basis                CommissionBasis          `json:"basis,omitempty"`   // Defaults to REVENUE
+	EarnOn               CommissionEarnOn         `json:"earn_on,omitempty"` // Defaults to INVOICED
+	BaseRate             float64                  `json:"base_rate"`
+	NewCustomerBonusRate float64                  `json:"new_customer_bonus_rate,omitempty"`
+	NewCustomerDays      *int                     `json:"new_customer_days,omitempty"` // Defaults to 365
+	Tiers                []CommissionTier         `json:"tiers,omitempty"`
+	CategoryRates        []CommissionCategoryRate `json:"category_rates,omitempty"`
+}
+
+// UpdateCommissionPlanRequest changes a plan. Tiers and category rates,
+// when given, replace the plan's; an empty list removes them.
+type UpdateCommissionPlanRequest struct {
+	Name                 *string                  `json:"name,omitempty"`
+	Description          *string                  `json:"description,omitempty"`
+	Basis                *CommissionBasis         `json:"basis,omitempty"`
+	EarnOn               *CommissionEarnOn        `json:"earn_on,omitempty"`
+	BaseRate             *float64                 `json:"base_rate,omitempty"`
+	NewCustomerBonusRate *float64                 `json:"new_customer_bonus_rate,omitempty"`
+	NewCustomerDays      *int                     `json:"new_customer_days,omitempty"`
+	IsActive             *bool                    `json:"is_active,omitempty"`
+	Tiers                []CommissionTier         `json:"tiers"`
+	CategoryRates        []CommissionCategoryRate `json:"category_rates"`
+}
+
+type AssignCommissionPlanRequest struct {
+	SalesRepID    int    `json:"sales_rep_id"`
+	PlanID        int    `json:"plan_id"`
+	EffectiveFrom string `json:"effective_from"`
+	EffectiveTo   string `json:"effective_to,omitempty"`
+}
+
+type EndCommissionAssignmentRequest struct {
+	EffectiveTo string `json:"effective_to"`
+}
+
+// GenerateCommissionStatementsRequest calculates the period's statements
+// for one rep, or for every rep with a plan in the period.
+type GenerateCommissionStatementsRequest struct {
+	PeriodStart string `json:"period_start"`
+	PeriodEnd   string `json:"period_end"`
+	SalesRepID  *int   `json:"sales_rep_id,omitempty"`
+}
+
+type PayCommissionStatementsRequest struct {
+	StatementIDs     []int  `json:"statement_ids"`
+	PayrollReference string `json:"payroll_reference,omitempty"`
+}
+
+type CommissionAssignmentFilters struct {
+	SalesRepID *int
+	PlanID     *int
+}
+
+type CommissionStatementFilters struct {
+	SalesRepID *int
+	Status     *CommissionStatementStatus
+	DateFrom   string // Period end on or after
+	DateTo     string // Period end on or before
+}
+
+// ============================================
+// Validation
+// ============================================
+
+func ValidateCommissionPlan(v *Validator, req *CreateCommissionPlanRequest) {
+	v.Check(req.Name != "", "name", "Name is required")
+	v.Check(req.Basis == "" || ValidCommissionBasis(req.Basis), "basis", "Basis must be REVENUE or MARGIN")
+	v.Check(req.EarnOn == "" || ValidCommissionEarnOn(req.EarnOn), "earn_on", "Earn on must be INVOICED or PAID")
+	validateCommissionRates(v, req.BaseRate, req.NewCustomerBonusRate, req.NewCustomerDays, req.Tiers, req.CategoryRates)
+}
+
+func ValidateUpdateCommissionPlan(v *Validator, req *UpdateCommissionPlanRequest) {
+	v.Check(req.Name == nil || *req.Name != "", "name", "Name is required")
+	v.Check(req.Basis == nil || ValidCommissionBasis(*req.Basis), "basis", "Basis must be REVENUE or MARGIN")
+	v.Check(req.EarnOn == nil || ValidCommissionEarnOn(*req.EarnOn), "earn_on", "Earn on must be INVOICED or PAID")
+	var baseRate, bonusRate float64
+	if req.BaseRate != nil {
+		baseRate = *req.BaseRate
+	}
+	if req.NewCustomerBonusRate != nil {
+		bonusRate = *req.NewCustomerBonusRate
+	}
+	validateCommissionRates(v, baseRate, bonusRate, req.NewCustomerDays, req.Tiers, req.CategoryRates)
+}
+
+func validateCommissionRates(v *Validator, baseRate, bonusRate float64, newCustomerDays *int, tiers []CommissionTier, categoryRates []CommissionCategoryRate) {
+	v.Check(baseRate >= 0 && baseRate <= 100, "base_rate", "Rate must be between 0 and 100")
+	v.Check(bonusRate >= 0 && bonusRate <= 100, "new_customer_bonus_rate", "Rate must be between 0 and 100")
+	v.Check(newCustomerDays == nil || *newCustomerDays >= 0, "new_customer_days", "New customer days cannot be negative")
+
+	thresholds := make(map[float64]bool)
+	for _, t := range tiers {
+		v.Check(t.Threshold >= 0, "tiers", "Tier threshold cannot be negative")
+		v.Check(t.Rate >= 0 && t.Rate <= 100, "tiers", "Rate must be between 0 and 100")
+		v.Check(!thresholds[t.Threshold], "tiers", "Tier thresholds must be unique")
+		thresholds[t.Threshold] = true
+	}
+	categories := make(map[int]bool)
+	for _, c := range categoryRates {
+		v.Check(c.CategoryID > 0, "category_rates", "Category is required for all category rates")
+		v.Check(c.Rate >= 0 && c.Rate <= 100, "category_rates", "Rate must be between 0 and 100")
+		v.Check(!categories[c.CategoryID], "category_rates", "Each category can have one rate")
+		categories[c.CategoryID] = true
+	}
+}
+
+func ValidateAssignCommissionPlan(v *Validator, req *AssignCommissionPlanRequest) {
+	v.Check(req.SalesRepID > 0, "sales_rep_id", "Sales rep is required")
+	v.Check(req.PlanID > 0, "plan_id", "Plan is required")
+	from, err := time.Parse("2006-01-02", req.EffectiveFrom)
+	v.Check(err == nil, "effective_from", "Effective from must be YYYY-MM-DD")
+	if req.EffectiveTo != "" {
+		to, err := time.Parse("2006-01-02", req.EffectiveTo)
+		v.Check(err == nil, "effective_to", "Effective to must be YYYY-MM-DD")
+		v.Check(err != nil || !to.Before(from), "effective_to", "Effective to cannot be before effective from")
+	}
+}
+
+func ValidateEndCommissionAssignment(v *Validator, req *EndCommissionAssignmentRequest) {
+	_, err := time.Parse("2006-01-02", req.EffectiveTo)
+	v.Check(err == nil, "effective_to", "Effective to must be YYYY-MM-DD")
+}
+
+func ValidateGenerateCommissionStatements(v *Validator, req *GenerateCommissionStatementsRequest) {
+	start, err := time.Parse("2006-01-02", req.PeriodStart)
+	v.Check(err == nil, "period_start", "Period start must be YYYY-MM-DD")
+	end, err := time.Parse("2006-01-02", req.PeriodEnd)
+	v.Check(err == nil, "period_end", "Period end must be YYYY-MM-DD")
+	v.Check(err != nil || !end.Before(start), "period_end", "Period end cannot be before period start")
+}
+
+func ValidatePayCommissionStatements(v *Validator, req *PayCommissionStatementsRequest) {
+	v.Check(len(req.StatementIDs) > 0, "statement_ids", "At least one statement is required")
+}
+
+func ValidCommissionBasis(b CommissionBasis) bool {
+	return b == CommissionOnRevenue || b == CommissionOnMargin
+}
+
+func ValidCommissionEarnOn(e CommissionEarnOn) bool {
+	return e == CommissionEarnedInvoiced || e == CommissionEarnedPaid
+}
+
+func ValidCommissionStatementStatus(s CommissionStatementStatus) bool {
+	switch s {
+	case CommissionStatementDraft, CommissionStatementApproved, CommissionStatementPaid:
+		return true
+	}
+	return false
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"time"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/i18n"
 )
 
 // ============================================
@@ -114,6 +116,38 @@ func (cdt CustomDateTime) IsZero() bool {
 	return time.Time(cdt).IsZero()
 }
 
+// Translations holds a translated name per language code, e.g.
+// {"lo": "ໄກ່ສົດ", "th": "ไก่สด"}, stored as JSONB
+type Translations map[string]string
+
+func (t *Translations) Scan(value interface{}) error {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return errors.New("invalid type for Translations")
+	}
+	return json.Unmarshal(raw, (*map[string]string)(t))
+}
+
+// Value stores a nil map as NULL so updates can leave the column unchanged.
+func (t Translations) Value() (driver.Value, error) {
+	if t == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(map[string]string(t))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
 // ============================================
 // Pagination
 // ============================================
@@ -176,3 +210,20 @@ func (v *Validator) AddError(key, message string) {
 func (v *Validator) Valid() bool {
 	return len(v.Errors) == 0
 }
+
+// ValidateDocumentLanguage checks an optional language code for printed documents.
+func ValidateDocumentLanguage(v *Validator, lang string) {
+	if lang != "" {
+		v.Check(i18n.Valid(lang), "document_language", "Document language must be en, lo or th")
+	}
+}
+
+// ValidateTranslations checks that translated names use supported language codes.
+func ValidateTranslations(v *Validator, key string, t Translations) {
+	for lang := range t {
+		if !i18n.Valid(lang) {
+			v.AddError(key, "Translations must be keyed by en, lo or th")
+			return
+		}
+	}
+}
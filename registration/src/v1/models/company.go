@@ -0,0 +1,145 @@
+package models
+
+// ============================================
+// Company Models
+// ============================================
+
+// Company is a legal entity with its own customers, vendors, chart of
+// accounts and fiscal calendar. Warehouses and products are shared.
+type Company struct {
+	ID           int    `json:"id"`
+	CompanyCode  string `json:"company_code"`
+	CompanyName  string `json:"company_name"`
+	LegalName    string `json:"legal_name,omitempty"`
+	TaxID        string `json:"tax_id,omitempty"`
+	BaseCurrency string `json:"base_currency"`
+	IsActive     bool   `json:"is_active"`
+	// CreditHoldOverdueDays holds new orders of customers with invoices this
+	// many days past due; 0 turns the check off.
+	CreditHoldOverdueDays int `json:"credit_hold_overdue_days"`
+	// StandingOrderDaysAhead is how many days before delivery standing
+	// orders become draft sales orders, unless a standing order sets its own.
+	StandingOrderDaysAhead int `json:"standing_order_days_ahead"`
+	// FreightIncomeAccountID is where invoiced freight is credited; other
+	// income when not set.
+	FreightIncomeAccountID *int           `json:"freight_income_account_id,omitempty"`
+	CreatedAt              CustomDateTime `json:"created_at"`
+	UpdatedAt              CustomDateTime `json:"updated_at"`
+}
+
+// EmployeeCompany is a company an employee may log in to.
+type EmployeeCompany struct {
+	CompanyID   int    `json:"company_id"`
+	CompanyCode string `json:"company_code"`
+	CompanyName string `json:"company_name"`
+	IsDefault   bool   `json:"is_default"`
+}
+
+// IntercompanyPartner records how a sister company appears in a company's
+// books: as a customer when selling to it and as a vendor when buying.
+type IntercompanyPartner struct {
+	ID                 int            `json:"id"`
+	CompanyID          int            `json:"company_id"`
+	PartnerCompanyID   int            `json:"partner_company_id"`
+	PartnerCompanyName string         `json:"partner_company_name"`
+	CustomerID         *int           `json:"customer_id,omitempty"`
+	VendorID           *int           `json:"vendor_id,omitempty"`
+	CreatedAt          CustomDateTime `json:"created_at"`
+}
+
+// IntercompanyOrder pairs a sales order in the selling company with the
+// purchase order for the same goods in the buying company.
+type IntercompanyOrder struct {
+	ID                int            `json:"id"`
+	SalesOrderID      int            `json:"sales_order_id"`
+	SalesOrderNumber  string         `json:"sales_order_number"`
+	PurchaseOrderID   int            `json:"purchase_order_id"`
+	PurchaseOrderNum  string         `json:"purchase_order_number"`
+	SellerCompanyID   int            `json:"seller_company_id"`
+	SellerCompanyName string         `json:"seller_company_name"`
+	BuyerCompanyID    int            `json:"buyer_company_id"`
+	BuyerCompanyName  string         `json:"buyer_company_name"`
+	CreatedBy         int            `json:"created_by"`
+	CreatedAt         CustomDateTime `json:"created_at"`
+}
+
+// ============================================
+// Request/Response DTOs
+// ============================================
+
+type CreateCompanyRequest struct {
+	CompanyCode  string `json:"company_code"`
+	CompanyName  string `json:"company_name"`
+	LegalName    string `json:"legal_name,omitempty"`
+	TaxID        string `json:"tax_id,omitempty"`
+	BaseCurrency string `json:"base_currency"`
+	// CreditHoldOverdueDays defaults to 30 when omitted.
+	CreditHoldOverdueDays *int `json:"credit_hold_overdue_days,omitempty"`
+	// StandingOrderDaysAhead defaults to 3 when omitted.
+	StandingOrderDaysAhead *int `json:"standing_order_days_ahead,omitempty"`
+	// CopyChartFrom copies the chart of accounts of an existing company,
+	// without balances, so the new company can start posting at once.
+	CopyChartFrom *int `json:"copy_chart_from,omitempty"`
+}
+
+type UpdateCompanyRequest struct {
+	CompanyName            *string `json:"company_name,omitempty"`
+	LegalName              *string `json:"legal_name,omitempty"`
+	TaxID                  *string `json:"tax_id,omitempty"`
+	BaseCurrency           *string `json:"base_currency,omitempty"`
+	IsActive               *bool   `json:"is_active,omitempty"`
+	CreditHoldOverdueDays  *int    `json:"credit_hold_overdue_days,omitempty"`
+	StandingOrderDaysAhead *int    `json:"standing_order_days_ahead,omitempty"`
+	FreightIncomeAccountID *int    `json:"freight_income_account_id,omitempty"` // 0 clears it
+}
+
+type SetEmployeeCompaniesRequest struct {
+	CompanyIDs       []int `json:"company_ids"`
+	DefaultCompanyID int   `json:"default_company_id"`
+}
+
+type CreateIntercompanyPartnerRequest struct {
+	PartnerCompanyID int  `json:"partner_company_id"`
+	CustomerID       *int `json:"customer_id,omitempty"`
+	VendorID         *int `json:"vendor_id,omitempty"`
+}
+
+type SwitchCompanyRequest struct {
+	CompanyID int `json:"company_id"`
+}
+
+// ============================================
+// Validation
+// ============================================
+
+func ValidateCompany(v *Validator, req *CreateCompanyRequest) {
+	v.Check(req.CompanyCode != "", "company_code", "Company code is required")
+	v.Check(len(req.CompanyCode) <= 20, "company_code", "Company code must be 20 characters or less")
+	v.Check(req.CompanyName != "", "company_name", "Company name is required")
+	if req.BaseCurrency == "" {
+		req.BaseCurrency = "USD"
+	}
+	v.Check(len(req.BaseCurrency) == 3, "base_currency", "Currency must be a 3-letter code")
+	if req.CreditHoldOverdueDays != nil {
+		v.Check(*req.CreditHoldOverdueDays >= 0, "credit_hold_overdue_days", "Overdue days cannot be negative")
+	}
+	if req.StandingOrderDaysAhead != nil {
+		v.Check(*req.StandingOrderDaysAhead >= 0 && *req.StandingOrderDaysAhead <= 30, "standing_order_days_ahead", "Days ahead must be between 0 and 30")
+	}
+}
+
+func ValidateEmployeeCompanies(v *Validator, req *SetEmployeeCompaniesRequest) {
+	v.Check(len(req.CompanyIDs) > 0, "company_ids", "At least one company is required")
+	found := false
+	for _, id := range req.CompanyIDs {
+		if id == req.DefaultCompanyID {
+			found = true
+		}
+	}
+	v.Check(found, "default_company_id", "Default company must be one of the assigned companies")
+}
+
+func ValidateIntercompanyPartner(v *Validator, req *CreateIntercompanyPartnerRequest) {
+	v.Check(req.PartnerCompanyID > 0, "partner_company_id", "Partner company is required")
+	v.Check(req.CustomerID != nil || req.VendorID != nil, "customer_id", "A customer or vendor for the partner is required")
+}
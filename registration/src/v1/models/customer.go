@@ -1,26 +1,40 @@
 package models
 
+import "github.com/anas-dev-92/FoodHive/registration/src/v1/utils/query"
+
 // ============================================
 // Customer Models
 // ============================================
 
+// BackorderPolicy decides what happens to the part of a line that could not
+// ship.
+type BackorderPolicy string
+
+const (
+	BackorderSameOrder BackorderPolicy = "SAME_ORDER" // Stays open on the order
+	BackorderNewOrder  BackorderPolicy = "NEW_ORDER"  // Moves to a backorder order
+	BackorderNone      BackorderPolicy = "NONE"       // Cancelled as a lost sale
+)
+
 type Customer struct {
-	ID                 int        `json:"id"`
-	CustomerCode       string     `json:"customer_code"`
-	Name               string     `json:"name"`
-	BillingAddressID   *int       `json:"billing_address_id,omitempty"`
-	CreditLimit        float64    `json:"credit_limit"`
-	CurrentBalance     float64    `json:"current_balance"`
-	PaymentTermsDays   int        `json:"payment_terms_days"`
-	Currency           string     `json:"currency"`
-	SalesRepID         *int       `json:"sales_rep_id,omitempty"`
-	DefaultRouteID     *int       `json:"default_route_id,omitempty"`
-	DefaultWarehouseID *int       `json:"default_warehouse_id,omitempty"`
-	TaxExempt          bool       `json:"tax_exempt"`
-	IsActive           bool       `json:"is_active"`
-	CreatedBy          int        `json:"created_by"`
-	CreatedAt          CustomDate `json:"created_at"`
-	UpdatedAt          CustomDate `json:"updated_at"`
+	ID                 int             `json:"id"`
+	CustomerCode       string          `json:"customer_code"`
+	Name               string          `json:"name"`
+	BillingAddressID   *int            `json:"billing_address_id,omitempty"`
+	CreditLimit        float64         `json:"credit_limit"`
+	CurrentBalance     float64         `json:"current_balance"`
+	PaymentTermsDays   int             `json:"payment_terms_days"`
+	Currency           string          `json:"currency"`
+	SalesRepID         *int            `json:"sales_rep_id,omitempty"`
+	DefaultRouteID     *int            `json:"default_route_id,omitempty"`
+	DefaultWarehouseID *int            `json:"default_warehouse_id,omitempty"`
+	TaxExempt          bool            `json:"tax_exempt"`
+	DocumentLanguage   string          `json:"document_language"`
+	BackorderPolicy    BackorderPolicy `json:"backorder_policy"`
+	IsActive           bool            `json:"is_active"`
+	CreatedBy          int             `json:"created_by"`
+	CreatedAt          CustomDate      `json:"created_at"`
+	UpdatedAt          CustomDate      `json:"updated_at"`
 }
 
 type CustomerShipTo struct {
@@ -67,34 +81,39 @@ type CustomerWithDetails struct {
 // ============================================
 
 type CreateCustomerRequest struct {
-	CustomerCode       string  `json:"customer_code"`
-	Name               string  `json:"name"`
-	CreditLimit        float64 `json:"credit_limit"`
-	PaymentTermsDays   int     `json:"payment_terms_days"`
-	Currency           string  `json:"currency"`
-	SalesRepID         *int    `json:"sales_rep_id,omitempty"`
-	DefaultWarehouseID *int    `json:"default_warehouse_id,omitempty"`
-	TaxExempt          bool    `json:"tax_exempt"`
+	CustomerCode       string          `json:"customer_code"`
+	Name               string          `json:"name"`
+	CreditLimit        float64         `json:"credit_limit"`
+	PaymentTermsDays   int             `json:"payment_terms_days"`
+	Currency           string          `json:"currency"`
+	SalesRepID         *int            `json:"sales_rep_id,omitempty"`
+	DefaultWarehouseID *int            `json:"default_warehouse_id,omitempty"`
+	TaxExempt          bool            `json:"tax_exempt"`
+	DocumentLanguage   string          `json:"document_language,omitempty"`
+	BackorderPolicy    BackorderPolicy `json:"backorder_policy,omitempty"`
 }
 
 type UpdateCustomerRequest struct {
-	Name               *string  `json:"name,omitempty"`
-	CreditLimit        *float64 `json:"credit_limit,omitempty"`
-	PaymentTermsDays   *int     `json:"payment_terms_days,omitempty"`
-	Currency           *string  `json:"currency,omitempty"`
-	SalesRepID         *int     `json:"sales_rep_id,omitempty"`
-	DefaultWarehouseID *int     `json:"default_warehouse_id,omitempty"`
-	TaxExempt          *bool    `json:"tax_exempt,omitempty"`
-	IsActive           *bool    `json:"is_active,omitempty"`
+	Name               *string          `json:"name,omitempty"`
+	CreditLimit        *float64         `json:"credit_limit,omitempty"`
+	PaymentTermsDays   *int             `json:"payment_terms_days,omitempty"`
+	Currency           *string          `json:"currency,omitempty"`
+	SalesRepID         *int             `json:"sales_rep_id,omitempty"`
+	DefaultWarehouseID *int             `json:"default_warehouse_id,omitempty"`
+	TaxExempt          *bool            `json:"tax_exempt,omitempty"`
+	DocumentLanguage   *string          `json:"document_language,omitempty"`
+	BackorderPolicy    *BackorderPolicy `json:"backorder_policy,omitempty"`
+	IsActive           *bool            `json:"is_active,omitempty"`
 }
 
 type CustomerListFilters struct {
-	Search      string `json:"search,omitempty"`
-	SalesRepID  *int   `json:"sales_rep_id,omitempty"`
-	IsActive    *bool  `json:"is_active,omitempty"`
-	WarehouseID *int   `json:"warehouse_id,omitempty"`
-	Page        int    `json:"page"`
-	PageSize    int    `json:"page_size"`
+	Search      string        `json:"search,omitempty"`
+	SalesRepID  *int          `json:"sales_rep_id,omitempty"`
+	IsActive    *bool         `json:"is_active,omitempty"`
+	WarehouseID *int          `json:"warehouse_id,omitempty"`
+	Page        int           `json:"page"`
+	PageSize    int           `json:"page_size"`
+	Query       *query.Params `json:"-"`
 }
 
 // ============================================
@@ -110,4 +129,14 @@ func ValidateCustomer(v *Validator, c *CreateCustomerRequest) {
 	if c.Currency != "" {
 		v.Check(len(c.Currency) == 3, "currency", "Currency must be a 3-letter code")
 	}
+	ValidateDocumentLanguage(v, c.DocumentLanguage)
+	v.Check(c.BackorderPolicy == "" || ValidBackorderPolicy(c.BackorderPolicy), "backorder_policy", "Backorder policy must be SAME_ORDER, NEW_ORDER or NONE")
+}
+
+func ValidBackorderPolicy(p BackorderPolicy) bool {
+	switch p {
+	case BackorderSameOrder, BackorderNewOrder, BackorderNone:
+		return true
+	}
+	return false
 }
@@ -0,0 +1,167 @@
+package models
+
+import "time"
+
+// ============================================
+// Customer Item Code Models
+// ============================================
+
+type UnmappedCodeStatus string
+
+const (
+	UnmappedCodeOpen    UnmappedCodeStatus = "OPEN"
+	UnmappedCodeMapped  UnmappedCodeStatus = "MAPPED"
+	UnmappedCodeIgnored UnmappedCodeStatus = "IGNORED"
+)
+
+type CustomerCodeSource string
+
+const (
+	CustomerCodeFromOrder CustomerCodeSource = "ORDER"
+	CustomerCodeFromEDI   CustomerCodeSource = "EDI"
+)
+
+// CustomerItemCode maps a customer's item number, ordered in the
+// customer's unit, to our product and unit. It belongs to a customer or
+// to a customer group, never both. An empty CustomerUOM matches any unit.
+type CustomerItemCode struct {
+	ID                int       `json:"id"`
+	CustomerID        *int      `json:"customer_id,omitempty"`
+	CustomerName      string    `json:"customer_name,omitempty"`
+	CustomerGroupID   *int      `json:"customer_group_id,omitempty"`
+	CustomerGroupName string    `json:"customer_group_name,omitempty"`
+	CustomerCode      string    `json:"customer_code"`
+	CustomerUOM       string    `json:"customer_uom"`
+	ProductID         int       `json:"product_id"`
+	ProductSKU        string    `json:"product_sku"`
+	ProductName       string    `json:"product_name"`
+	UnitOfMeasure     string    `json:"unit_of_measure"`
+	ConversionFactor  float64   `json:"conversion_factor"` // Our units per customer unit
+	Description       string    `json:"description,omitempty"`
+	IsActive          bool      `json:"is_active"`
+	CreatedBy         *int      `json:"created_by,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// UnmappedCustomerCode is a code a customer ordered by that no mapping
+// covered, waiting for someone to map or ignore it.
+type UnmappedCustomerCode struct {
+	ID           int                `json:"id"`
+	CustomerID   int                `json:"customer_id"`
+	CustomerName string             `json:"customer_name"`
+	CustomerCode string             `json:"customer_code"`
+	CustomerUOM  string             `json:"customer_uom"`
+	Description  string             `json:"description,omitempty"`
+	Source       CustomerCodeSource `json:"source"`
+	OrderID      *int               `json:"order_id,omitempty"`
+	OrderNumber  string             `json:"order_number,omitempty"`
+	Occurrences  int                `json:"occurrences"`
+	FirstSeenAt  time.Time          `json:"first_seen_at"`
+	LastSeenAt   time.Time          `json:"last_seen_at"`
+	Status       UnmappedCodeStatus `json:"status"`
+	MappingID    *int               `json:"mapping_id,omitempty"`
+	ResolvedBy   *int               `json:"resolved_by,omitempty"`
+	ResolvedAt   *time.Time         `json:"resolved_at,omitempty"`
+}
+
+// ============================================
+// Request DTOs
+// ============================================
+
+type CreateCustomerItemCodeRequest struct {
+	CustomerID       *int    `json:"customer_id,omitempty"`
+	CustomerGroupID  *int    `json:"customer_group_id,omitempty"`
+	CustomerCode     string  `json:"customer_code"`
+	CustomerUOM      string  `json:"customer_uom,omitempty"`
+	ProductID        int     `json:"product_id"`
+	UnitOfMeasure    string  `json:"unit_of_measure,omitempty"`   // Defaults to the product's base unit
+	ConversionFactor float64 `json:"conversion_factor,omitempty"` // Defaults to 1
+	Description      string  `json:"description,omitempty"`
+}
+
+type UpdateCustomerItemCodeRequest struct {
+	CustomerCode     *string  `json:"customer_code,omitempty"`
+	CustomerUOM      *string  `json:"customer_uom,omitempty"`
+	ProductID        *int     `json:"product_id,omitempty"`
+	UnitOfMeasure    *string  `json:"unit_of_measure,omitempty"`
+	ConversionFactor *float64 `json:"conversion_factor,omitempty"`
+	Description      *string  `json:"description,omitempty"`
+	IsActive         *bool    `json:"is_active,omitempty"`
+}
+
+// QueueCustomerCodeRequest records a code that could not be resolved.
+type QueueCustomerCodeRequest struct {
+	CustomerID   int
+	CustomerCode string
+	CustomerUOM  string
+	Description  string
+	Source       CustomerCodeSource
+	OrderID      *int
+}
+
+// MapCustomerCodeRequest maps a queued code. The mapping is the
+// customer's own unless ForGroup asks for it to cover the customer's
+// group.
+type MapCustomerCodeRequest struct {
+	ProductID        int     `json:"product_id"`
+	UnitOfMeasure    string  `json:"unit_of_measure,omitempty"`
+	ConversionFactor float64 `json:"conversion_factor,omitempty"`
+	AnyUnit          bool    `json:"any_unit,omitempty"` // Match the code in whatever unit is ordered
+	ForGroup         bool    `json:"for_group,omitempty"`
+	Description      string  `json:"description,omitempty"`
+}
+
+type CustomerItemCodeFilters struct {
+	CustomerID      *int
+	CustomerGroupID *int
+	ProductID       *int
+	Search          string // Customer code, SKU or product name
+}
+
+type UnmappedCodeFilters struct {
+	CustomerID *int
+	Status     *UnmappedCodeStatus // Defaults to OPEN
+}
+
+// ============================================
+// Validation
+// ============================================
+
+func ValidateCustomerItemCode(v *Validator, req *CreateCustomerItemCodeRequest) {
+	v.Check((req.CustomerID == nil) != (req.CustomerGroupID == nil), "customer_id", "Give either a customer or a customer group")
+	v.Check(req.CustomerCode != "", "customer_code", "Customer code is required")
+	v.Check(len(req.CustomerCode) <= 50, "customer_code", "Customer code must not be more than 50 characters")
+	v.Check(len(req.CustomerUOM) <= 10, "customer_uom", "Unit of measure must not be more than 10 characters")
+	v.Check(req.ProductID > 0, "product_id", "Product ID is required")
+	v.Check(len(req.UnitOfMeasure) <= 10, "unit_of_measure", "Unit of measure must not be more than 10 characters")
+	v.Check(req.ConversionFactor >= 0, "conversion_factor", "Conversion factor must be positive")
+}
+
+func ValidateUpdateCustomerItemCode(v *Validator, req *UpdateCustomerItemCodeRequest) {
+	if req.CustomerCode != nil {
+		v.Check(*req.CustomerCode != "", "customer_code", "Customer code is required")
+		v.Check(len(*req.CustomerCode) <= 50, "customer_code", "Customer code must not be more than 50 characters")
+	}
+	v.Check(req.CustomerUOM == nil || len(*req.CustomerUOM) <= 10, "customer_uom", "Unit of measure must not be more than 10 characters")
+	v.Check(req.ProductID == nil || *req.ProductID > 0, "product_id", "Product ID is required")
+	if req.UnitOfMeasure != nil {
+		v.Check(*req.UnitOfMeasure != "", "unit_of_measure", "Unit of measure is required")
+		v.Check(len(*req.UnitOfMeasure) <= 10, "unit_of_measure", "Unit of measure must not be more than 10 characters")
+	}
+	v.Check(req.ConversionFactor == nil || *req.ConversionFactor > 0, "conversion_factor", "Conversion factor must be positive")
+}
+
+func ValidateMapCustomerCode(v *Validator, req *MapCustomerCodeRequest) {
+	v.Check(req.ProductID > 0, "product_id", "Product ID is required")
+	v.Check(len(req.UnitOfMeasure) <= 10, "unit_of_measure", "Unit of measure must not be more than 10 characters")
+	v.Check(req.ConversionFactor >= 0, "conversion_factor", "Conversion factor must be positive")
+}
+
+func ValidUnmappedCodeStatus(s UnmappedCodeStatus) bool {
+	switch s {
+	case UnmappedCodeOpen, UnmappedCodeMapped, UnmappedCodeIgnored:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,152 @@
+package models
+
+import "time"
+
+// ============================================
+// Proof of Delivery Models
+// ============================================
+
+// ProofOfDelivery is what the customer signed for when an order was
+// delivered. Refused quantities were returned on the RMA, which credited
+// the customer.
+type ProofOfDelivery struct {
+	ID              int                   `json:"id"`
+	OrderID         int                   `json:"order_id"`
+	OrderNumber     string                `json:"order_number"`
+	CustomerID      int                   `json:"customer_id"`
+	CustomerName    string                `json:"customer_name"`
+	RouteStopID     *int                  `json:"route_stop_id,omitempty"`
+	RecipientName   string                `json:"recipient_name"`
+	SignaturePath   string                `json:"-"`
+	DeliveredAt     CustomDateTime        `json:"delivered_at"`
+	Latitude        *float64              `json:"latitude,omitempty"`
+	Longitude       *float64              `json:"longitude,omitempty"`
+	Notes           string                `json:"notes,omitempty"`
+	RMAID           *int                  `json:"rma_id,omitempty"`
+	RMANumber       string                `json:"rma_number,omitempty"`
+	CreditInvoiceID *int                  `json:"credit_invoice_id,omitempty"`
+	ConfirmedBy     *int                  `json:"confirmed_by,omitempty"`
+	ConfirmedByName string                `json:"confirmed_by_name,omitempty"`
+	CreatedAt       CustomDateTime        `json:"created_at"`
+	Lines           []ProofOfDeliveryLine `json:"lines"`
+	Photos          []DeliveryPhoto       `json:"photos"`
+}
+
+type ProofOfDeliveryLine struct {
+	ID               int          `json:"id"`
+	OrderLineID      int          `json:"order_line_id"`
+	ProductID        int          `json:"product_id"`
+	ProductSKU       string       `json:"product_sku"`
+	ProductName      string       `json:"product_name"`
+	QuantityShipped  float64      `json:"quantity_shipped"`
+	QuantityAccepted float64      `json:"quantity_accepted"`
+	QuantityRefused  float64      `json:"quantity_refused"`
+	RefusalReason    ReturnReason `json:"refusal_reason,omitempty"`
+	Notes            string       `json:"notes,omitempty"`
+}
+
+// DeliveryPhoto is downloaded through the deliveries API; the storage path
+// is not exposed.
+type DeliveryPhoto struct {
+	ID        int            `json:"id"`
+	FilePath  string         `json:"-"`
+	CreatedAt CustomDateTime `json:"created_at"`
+}
+
+// ============================================
+// Request DTOs
+// ============================================
+
+// DeliveryEvidence is captured by the driver at the door. The signature
+// and photos are PNG or JPEG files uploaded with the request rather than
+// JSON. DeliveredAt defaults to now.
+type DeliveryEvidence struct {
+	RecipientName string         `json:"recipient_name"`
+	Signature     []byte         `json:"-"`
+	Photos        [][]byte       `json:"-"`
+	DeliveredAt   CustomDateTime `json:"delivered_at"`
+	Latitude      *float64       `json:"latitude,omitempty"`
+	Longitude     *float64       `json:"longitude,omitempty"`
+	Notes         string         `json:"notes,omitempty"`
+}
+
+// ConfirmDeliveryRequest delivers one shipped order. Lines list only what
+// the customer refused; every other line was accepted in full.
+type ConfirmDeliveryRequest struct {
+	DeliveryEvidence
+	Lines []DeliveryRefusalRequest `json:"lines,omitempty"`
+}
+
+type DeliveryRefusalRequest struct {
+	OrderLineID     int          `json:"order_line_id"`
+	QuantityRefused float64      `json:"quantity_refused"`
+	RefusalReason   ReturnReason `json:"refusal_reason"`
+	CatchWeight     *float64     `json:"catch_weight,omitempty"` // Weight of the refused pieces
+	Notes           string       `json:"notes,omitempty"`
+}
+
+// ConfirmStopDeliveryRequest delivers every shipped order for a route
+// stop's customer and ship-to with one signature. RunDate limits it to the
+// orders requested for that run.
+type ConfirmStopDeliveryRequest struct {
+	DeliveryEvidence
+	RunDate string                `json:"run_date,omitempty"`
+	Orders  []StopRefusalsRequest `json:"orders,omitempty"`
+}
+
+type StopRefusalsRequest struct {
+	OrderID int                      `json:"order_id"`
+	Lines   []DeliveryRefusalRequest `json:"lines"`
+}
+
+// maxDeliveryPhotos limits the photos taken for one delivery.
+const maxDeliveryPhotos = 10
+
+// ============================================
+// Validation
+// ============================================
+
+func validateDeliveryEvidence(v *Validator, e *DeliveryEvidence) {
+	v.Check(e.RecipientName != "", "recipient_name", "Recipient name is required")
+	v.Check(len(e.RecipientName) <= 100, "recipient_name", "Recipient name must not be more than 100 characters")
+	v.Check(len(e.Signature) > 0, "signature", "Signature is required")
+	v.Check(len(e.Photos) <= maxDeliveryPhotos, "photos", "No more than 10 photos can be attached")
+	for _, photo := range e.Photos {
+		v.Check(len(photo) > 0, "photos", "Photos must not be empty")
+	}
+	v.Check((e.Latitude == nil) == (e.Longitude == nil), "latitude", "Latitude and longitude must be provided together")
+	v.Check(e.Latitude == nil || (*e.Latitude >= -90 && *e.Latitude <= 90), "latitude", "Latitude must be between -90 and 90")
+	v.Check(e.Longitude == nil || (*e.Longitude >= -180 && *e.Longitude <= 180), "longitude", "Longitude must be between -180 and 180")
+}
+
+func validateDeliveryRefusals(v *Validator, lines []DeliveryRefusalRequest) {
+	seen := make(map[int]bool)
+	for _, line := range lines {
+		v.Check(line.OrderLineID > 0, "lines", "Order line is required for all lines")
+		v.Check(!seen[line.OrderLineID], "lines", "Each order line can only be listed once")
+		v.Check(line.QuantityRefused > 0, "lines", "Refused quantity must be positive for all lines")
+		v.Check(ValidReturnReason(line.RefusalReason), "lines", "Reason code is not valid")
+		v.Check(line.CatchWeight == nil || *line.CatchWeight > 0, "lines", "Catch weight must be positive")
+		seen[line.OrderLineID] = true
+	}
+}
+
+func ValidateConfirmDelivery(v *Validator, req *ConfirmDeliveryRequest) {
+	validateDeliveryEvidence(v, &req.DeliveryEvidence)
+	validateDeliveryRefusals(v, req.Lines)
+}
+
+func ValidateConfirmStopDelivery(v *Validator, req *ConfirmStopDeliveryRequest) {
+	validateDeliveryEvidence(v, &req.DeliveryEvidence)
+	if req.RunDate != "" {
+		_, err := time.Parse("2006-01-02", req.RunDate)
+		v.Check(err == nil, "run_date", "Run date must be YYYY-MM-DD")
+	}
+	seen := make(map[int]bool)
+	for _, order := range req.Orders {
+		v.Check(order.OrderID > 0, "orders", "Sales order is required for all orders")
+		v.Check(!seen[order.OrderID], "orders", "Each order can only be listed once")
+		seen[order.OrderID] = true
+		validateDeliveryRefusals(v, order.Lines)
+	}
+}
@@ -0,0 +1,101 @@
+package models
+
+import "strings"
+
+// ============================================
+// Document Types
+// ============================================
+
+type DocumentType string
+
+const (
+	DocumentTypeInvoice        DocumentType = "INVOICE"
+	DocumentTypeDeliveryNote   DocumentType = "DELIVERY_NOTE"
+	DocumentTypePurchaseOrder  DocumentType = "PURCHASE_ORDER"
+	DocumentTypePickList       DocumentType = "PICK_LIST"
+	DocumentTypeStatement      DocumentType = "STATEMENT"
+	DocumentTypePaymentVoucher DocumentType = "PAYMENT_VOUCHER"
+	DocumentTypePayslip        DocumentType = "PAYSLIP"
+)
+
+// ============================================
+// Company Profile (Branding)
+// ============================================
+
+type CompanyProfile struct {
+	ID           int            `json:"id"`
+	CompanyName  string         `json:"company_name"`
+	LegalName    string         `json:"legal_name,omitempty"`
+	TaxID        string         `json:"tax_id,omitempty"`
+	Address      string         `json:"address,omitempty"`
+	Phone        string         `json:"phone,omitempty"`
+	Email        string         `json:"email,omitempty"`
+	Website      string         `json:"website,omitempty"`
+	BankDetails  string         `json:"bank_details,omitempty"`
+	FooterText   string         `json:"footer_text,omitempty"`
+	LogoBucket   string         `json:"logo_bucket,omitempty"`
+	LogoPath     string         `json:"logo_path,omitempty"`
+	PrimaryColor string         `json:"primary_color"`
+	UpdatedBy    *int           `json:"updated_by,omitempty"`
+	UpdatedAt    CustomDateTime `json:"updated_at"`
+}
+
+type UpdateCompanyProfileRequest struct {
+	CompanyName  *string `json:"company_name,omitempty"`
+	LegalName    *string `json:"legal_name,omitempty"`
+	TaxID        *string `json:"tax_id,omitempty"`
+	Address      *string `json:"address,omitempty"`
+	Phone        *string `json:"phone,omitempty"`
+	Email        *string `json:"email,omitempty"`
+	Website      *string `json:"website,omitempty"`
+	BankDetails  *string `json:"bank_details,omitempty"`
+	FooterText   *string `json:"footer_text,omitempty"`
+	LogoBucket   *string `json:"logo_bucket,omitempty"`
+	LogoPath     *string `json:"logo_path,omitempty"`
+	PrimaryColor *string `json:"primary_color,omitempty"`
+}
+
+// ============================================
+// Print Log
+// ============================================
+
+type DocumentPrint struct {
+	ID             int            `json:"id"`
+	DocumentType   DocumentType   `json:"document_type"`
+	DocumentID     int            `json:"document_id"`
+	DocumentNumber string         `json:"document_number,omitempty"`
+	CopyNumber     int            `json:"copy_number"`
+	PrintedBy      *int           `json:"printed_by,omitempty"`
+	PrintedByName  string         `json:"printed_by_name,omitempty"`
+	PrintedAt      CustomDateTime `json:"printed_at"`
+}
+
+// RenderedDocument is a generated file ready to be streamed to the client.
+type RenderedDocument struct {
+	FileName    string
+	ContentType string
+	Content     []byte
+}
+
+// ============================================
+// Validation
+// ============================================
+
+func ValidateCompanyProfile(v *Validator, req *UpdateCompanyProfileRequest) {
+	if req.CompanyName != nil {
+		v.Check(strings.TrimSpace(*req.CompanyName) != "", "company_name", "Company name cannot be empty")
+	}
+	if req.PrimaryColor != nil {
+		c := *req.PrimaryColor
+		valid := len(c) == 7 && c[0] == '#'
+		for _, r := range strings.ToLower(c[min(1, len(c)):]) {
+			if !strings.ContainsRune("0123456789abcdef", r) {
+				valid = false
+			}
+		}
+		v.Check(valid, "primary_color", "Primary color must be a hex color such as #1F4E79")
+	}
+	if (req.LogoBucket == nil) != (req.LogoPath == nil) {
+		v.AddError("logo_path", "Logo bucket and path must be provided together")
+	}
+}
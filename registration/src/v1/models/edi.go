@@ -0,0 +1,249 @@
+package models
+
+// ============================================
+// EDI Models
+// ============================================
+
+type EDIStandard string
+
+const (
+	EDIStandardX12     EDIStandard = "X12"
+	EDIStandardEDIFACT EDIStandard = "EDIFACT"
+)
+
+type EDIDirection string
+
+const (
+	EDIInbound  EDIDirection = "IN"
+	EDIOutbound EDIDirection = "OUT"
+)
+
+type EDIInterchangeStatus string
+
+const (
+	EDIInterchangeReceived  EDIInterchangeStatus = "RECEIVED"
+	EDIInterchangeProcessed EDIInterchangeStatus = "PROCESSED"
+	EDIInterchangePartial   EDIInterchangeStatus = "PARTIAL" // Some transactions failed
+	EDIInterchangeFailed    EDIInterchangeStatus = "FAILED"
+	EDIInterchangeDuplicate EDIInterchangeStatus = "DUPLICATE"
+	EDIInterchangeSent      EDIInterchangeStatus = "SENT"
+)
+
+type EDITransactionStatus string
+
+const (
+	EDITransactionProcessed EDITransactionStatus = "PROCESSED"
+	EDITransactionFailed    EDITransactionStatus = "FAILED"
+	EDITransactionSent      EDITransactionStatus = "SENT"
+)
+
+type EDIDocumentType string
+
+const (
+	EDIPurchaseOrder   EDIDocumentType = "PURCHASE_ORDER"  // 850 / ORDERS
+	EDIAcknowledgement EDIDocumentType = "ACKNOWLEDGEMENT" // 855 / ORDRSP
+	EDIShipNotice      EDIDocumentType = "SHIP_NOTICE"     // 856 / DESADV
+	EDIInvoice         EDIDocumentType = "INVOICE"         // 810 / INVOIC
+)
+
+// EDIPartner is a customer that trades with us by EDI. Inbound files are
+// matched to it by their sender and receiver IDs.
+type EDIPartner struct {
+	ID                       int            `json:"id"`
+	CustomerID               int            `json:"customer_id"`
+	CustomerName             string         `json:"customer_name,omitempty"`
+	Name                     string         `json:"name"`
+	Standard                 EDIStandard    `json:"standard"`
+	PartnerQualifier         string         `json:"partner_qualifier"`
+	PartnerID                string         `json:"partner_id"`
+	OurQualifier             string         `json:"our_qualifier"`
+	OurID                    string         `json:"our_id"`
+	WarehouseID              *int           `json:"warehouse_id,omitempty"`
+	SendAcknowledgement      bool           `json:"send_acknowledgement"`
+	SendShipNotice           bool           `json:"send_ship_notice"`
+	SendInvoice              bool           `json:"send_invoice"`
+	TestMode                 bool           `json:"test_mode"`
+	InterchangeControlNumber int            `json:"interchange_control_number"` // Last one sent
+	GroupControlNumber       int            `json:"group_control_number"`
+	IsActive                 bool           `json:"is_active"`
+	CreatedBy                *int           `json:"created_by,omitempty"`
+	CreatedAt                CustomDateTime `json:"created_at"`
+	UpdatedAt                CustomDateTime `json:"updated_at"`
+}
+
+// EDIShipToMapping maps a partner's location code to one of the
+// customer's ship-to addresses.
+type EDIShipToMapping struct {
+	ID           int    `json:"id"`
+	PartnerID    int    `json:"partner_id"`
+	LocationCode string `json:"location_code"`
+	ShipToID     int    `json:"ship_to_id"`
+	ShipToCode   string `json:"ship_to_code"`
+	ShipToName   string `json:"ship_to_name"`
+}
+
+// EDIInterchange is one file received or sent.
+type EDIInterchange struct {
+	ID               int                  `json:"id"`
+	PartnerID        *int                 `json:"partner_id,omitempty"`
+	PartnerName      string               `json:"partner_name,omitempty"`
+	Direction        EDIDirection         `json:"direction"`
+	Standard         EDIStandard          `json:"standard,omitempty"`
+	SenderID         string               `json:"sender_id,omitempty"`
+	ReceiverID       string               `json:"receiver_id,omitempty"`
+	ControlNumber    string               `json:"control_number,omitempty"`
+	FileName         string               `json:"file_name"`
+	Status           EDIInterchangeStatus `json:"status"`
+	ErrorMessage     string               `json:"error_message,omitempty"`
+	TransactionCount int                  `json:"transaction_count"`
+	CreatedAt        CustomDateTime       `json:"created_at"`
+	ProcessedAt      CustomDateTime       `json:"processed_at"`
+}
+
+// EDITransaction is one transaction set (X12) or message (EDIFACT).
+type EDITransaction struct {
+	ID            int                  `json:"id"`
+	InterchangeID int                  `json:"interchange_id"`
+	DocumentType  EDIDocumentType      `json:"document_type"`
+	MessageType   string               `json:"message_type"`
+	ControlNumber string               `json:"control_number"`
+	Reference     string               `json:"reference,omitempty"`
+	OrderID       *int                 `json:"order_id,omitempty"`
+	InvoiceID     *int                 `json:"invoice_id,omitempty"`
+	Status        EDITransactionStatus `json:"status"`
+	ErrorMessage  string               `json:"error_message,omitempty"`
+	CreatedAt     CustomDateTime       `json:"created_at"`
+}
+
+type EDIInterchangeWithDetails struct {
+	EDIInterchange
+	Content      string           `json:"content"`
+	Transactions []EDITransaction `json:"transactions"`
+}
+
+// EDIPollResult reports a pass over the inbound directory.
+type EDIPollResult struct {
+	Files         int `json:"files"`
+	Processed     int `json:"processed"`
+	Failed        int `json:"failed"`
+	OrdersCreated int `json:"orders_created"`
+	DocumentsSent int `json:"documents_sent"`
+}
+
+// ============================================
+// Request DTOs
+// ============================================
+
+type CreateEDIPartnerRequest struct {
+	CustomerID          int         `json:"customer_id"`
+	Name                string      `json:"name"`
+	Standard            EDIStandard `json:"standard"`
+	PartnerQualifier    string      `json:"partner_qualifier,omitempty"` // Defaults to ZZ
+	PartnerID           string      `json:"partner_id"`
+	OurQualifier        string      `json:"our_qualifier,omitempty"` // Defaults to ZZ
+	OurID               string      `json:"our_id"`
+	WarehouseID         *int        `json:"warehouse_id,omitempty"`
+	SendAcknowledgement *bool       `json:"send_acknowledgement,omitempty"` // Default true
+	SendShipNotice      *bool       `json:"send_ship_notice,omitempty"`     // Default true
+	SendInvoice         *bool       `json:"send_invoice,omitempty"`         // Default true
+	TestMode            bool        `json:"test_mode,omitempty"`
+}
+
+type UpdateEDIPartnerRequest struct {
+	Name                *string `json:"name,omitempty"`
+	PartnerQualifier    *string `json:"partner_qualifier,omitempty"`
+	PartnerID           *string `json:"partner_id,omitempty"`
+	OurQualifier        *string `json:"our_qualifier,omitempty"`
+	OurID               *string `json:"our_id,omitempty"`
+	WarehouseID         *int    `json:"warehouse_id,omitempty"`
+	SendAcknowledgement *bool   `json:"send_acknowledgement,omitempty"`
+	SendShipNotice      *bool   `json:"send_ship_notice,omitempty"`
+	SendInvoice         *bool   `json:"send_invoice,omitempty"`
+	TestMode            *bool   `json:"test_mode,omitempty"`
+	IsActive            *bool   `json:"is_active,omitempty"`
+}
+
+type CreateEDIShipToMappingRequest struct {
+	LocationCode string `json:"location_code"`
+	ShipToID     int    `json:"ship_to_id"`
+}
+
+// ReceiveEDIRequest submits a file's content directly, as if it had been
+// dropped in the inbound directory.
+type ReceiveEDIRequest struct {
+	FileName string `json:"file_name"`
+	Content  string `json:"content"`
+}
+
+type EDIInterchangeFilters struct {
+	PartnerID *int
+	Direction *EDIDirection
+	Status    *EDIInterchangeStatus
+	DateFrom  string
+	DateTo    string
+	Limit     int
+}
+
+// ============================================
+// Validation
+// ============================================
+
+func ValidateEDIPartner(v *Validator, req *CreateEDIPartnerRequest) {
+	v.Check(req.CustomerID > 0, "customer_id", "Customer is required")
+	v.Check(req.Name != "", "name", "Name is required")
+	v.Check(len(req.Name) <= 100, "name", "Name must be 100 characters or less")
+	v.Check(ValidEDIStandard(req.Standard), "standard", "Standard must be X12 or EDIFACT")
+	v.Check(req.PartnerID != "", "partner_id", "Partner interchange ID is required")
+	v.Check(len(req.PartnerID) <= 35, "partner_id", "Interchange ID must not be more than 35 characters")
+	v.Check(req.OurID != "", "our_id", "Our interchange ID is required")
+	v.Check(len(req.OurID) <= 35, "our_id", "Interchange ID must not be more than 35 characters")
+	v.Check(len(req.PartnerQualifier) <= 4, "partner_qualifier", "Qualifier must not be more than 4 characters")
+	v.Check(len(req.OurQualifier) <= 4, "our_qualifier", "Qualifier must not be more than 4 characters")
+	if req.Standard == EDIStandardX12 {
+		v.Check(len(req.PartnerID) <= 15, "partner_id", "X12 interchange IDs must not be more than 15 characters")
+		v.Check(len(req.OurID) <= 15, "our_id", "X12 interchange IDs must not be more than 15 characters")
+		v.Check(len(req.PartnerQualifier) <= 2, "partner_qualifier", "X12 qualifiers must not be more than 2 characters")
+		v.Check(len(req.OurQualifier) <= 2, "our_qualifier", "X12 qualifiers must not be more than 2 characters")
+	}
+}
+
+func ValidateUpdateEDIPartner(v *Validator, req *UpdateEDIPartnerRequest) {
+	if req.Name != nil {
+		v.Check(*req.Name != "", "name", "Name is required")
+		v.Check(len(*req.Name) <= 100, "name", "Name must be 100 characters or less")
+	}
+	if req.PartnerID != nil {
+		v.Check(*req.PartnerID != "", "partner_id", "Partner interchange ID is required")
+		v.Check(len(*req.PartnerID) <= 35, "partner_id", "Interchange ID must not be more than 35 characters")
+	}
+	if req.OurID != nil {
+		v.Check(*req.OurID != "", "our_id", "Our interchange ID is required")
+		v.Check(len(*req.OurID) <= 35, "our_id", "Interchange ID must not be more than 35 characters")
+	}
+	v.Check(req.PartnerQualifier == nil || len(*req.PartnerQualifier) <= 4, "partner_qualifier", "Qualifier must not be more than 4 characters")
+	v.Check(req.OurQualifier == nil || len(*req.OurQualifier) <= 4, "our_qualifier", "Qualifier must not be more than 4 characters")
+}
+
+func ValidateEDIShipToMapping(v *Validator, req *CreateEDIShipToMappingRequest) {
+	v.Check(req.LocationCode != "", "location_code", "Location code is required")
+	v.Check(len(req.LocationCode) <= 35, "location_code", "Location code must not be more than 35 characters")
+	v.Check(req.ShipToID > 0, "ship_to_id", "Ship-to is required")
+}
+
+func ValidateReceiveEDI(v *Validator, req *ReceiveEDIRequest) {
+	v.Check(req.Content != "", "content", "File content is required")
+	v.Check(len(req.FileName) <= 255, "file_name", "File name must not be more than 255 characters")
+}
+
+func ValidEDIStandard(s EDIStandard) bool {
+	return s == EDIStandardX12 || s == EDIStandardEDIFACT
+}
+
+func ValidEDIInterchangeStatus(s EDIInterchangeStatus) bool {
+	switch s {
+	case EDIInterchangeReceived, EDIInterchangeProcessed, EDIInterchangePartial,
+		EDIInterchangeFailed, EDIInterchangeDuplicate, EDIInterchangeSent:
+		return true
+	}
+	return false
+}
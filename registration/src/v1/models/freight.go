@@ -0,0 +1,161 @@
+package models
+
+// ============================================
+// Freight Enums
+// ============================================
+
+type FreightRuleType string
+
+const (
+	FreightRuleRouteFee     FreightRuleType = "ROUTE_FEE"     // Flat fee for a delivery on the route
+	FreightRuleFreeDelivery FreightRuleType = "FREE_DELIVERY" // No delivery fees from an order value
+	FreightRuleWeightTier   FreightRuleType = "WEIGHT_TIER"   // Fee for an order weight band
+	FreightRuleSurcharge    FreightRuleType = "SURCHARGE"     // Added to pick-up or delivery orders
+)
+
+// Fulfillment is how the customer gets the goods: pick-up orders are
+// collected, every other order is delivered.
+type Fulfillment string
+
+const (
+	FulfillmentDelivery Fulfillment = "DELIVERY"
+	FulfillmentPickUp   Fulfillment = "PICK_UP"
+)
+
+// ============================================
+// Freight Models
+// ============================================
+
+// FreightRule is one part of the delivery charge. A rule without a route
+// applies to every route; one for the order's route takes its place.
+type FreightRule struct {
+	ID            int             `json:"id"`
+	Name          string          `json:"name"`
+	RuleType      FreightRuleType `json:"rule_type"`
+	RouteID       *int            `json:"route_id,omitempty"`
+	RouteName     string          `json:"route_name,omitempty"`
+	Fulfillment   Fulfillment     `json:"fulfillment,omitempty"`
+	MinOrderValue *float64        `json:"min_order_value,omitempty"`
+	MinWeightKg   *float64        `json:"min_weight_kg,omitempty"`
+	MaxWeightKg   *float64        `json:"max_weight_kg,omitempty"`
+	Amount        float64         `json:"amount"`
+	RatePerKg     float64         `json:"rate_per_kg,omitempty"`
+	IsActive      bool            `json:"is_active"`
+	CreatedAt     CustomDate      `json:"created_at"`
+}
+
+// FreightCalculation is the charge the rules give an order. Free delivery
+// waives the route and weight fees but not surcharges.
+type FreightCalculation struct {
+	Fulfillment  Fulfillment `json:"fulfillment"`
+	RouteID      *int        `json:"route_id,omitempty"`
+	OrderValue   float64     `json:"order_value"`
+	WeightKg     float64     `json:"weight_kg"`
+	RouteFee     float64     `json:"route_fee"`
+	WeightFee    float64     `json:"weight_fee"`
+	Surcharge    float64     `json:"surcharge"`
+	FreeDelivery bool        `json:"free_delivery"`
+	Amount       float64     `json:"amount"`
+	Rules        []string    `json:"rules,omitempty"` // Names of the rules applied
+}
+
+// OrderFreight is an order's freight charge next to what the rules would
+// charge.
+type OrderFreight struct {
+	OrderID        int                 `json:"order_id"`
+	FreightAmount  float64             `json:"freight_amount"`
+	Overridden     bool                `json:"overridden"`
+	OverrideReason string              `json:"override_reason,omitempty"`
+	OverriddenBy   *int                `json:"overridden_by,omitempty"`
+	OverriddenAt   CustomDateTime      `json:"overridden_at,omitempty"`
+	Calculated     *FreightCalculation `json:"calculated"`
+}
+
+// ============================================
+// Request DTOs
+// ============================================
+
+type CreateFreightRuleRequest struct {
+	Name          string          `json:"name"`
+	RuleType      FreightRuleType `json:"rule_type"`
+	RouteID       *int            `json:"route_id,omitempty"`
+	Fulfillment   Fulfillment     `json:"fulfillment,omitempty"`     // Surcharges only
+	MinOrderValue *float64        `json:"min_order_value,omitempty"` // Free delivery only
+	MinWeightKg   *float64        `json:"min_weight_kg,omitempty"`   // Weight tiers only
+	MaxWeightKg   *float64        `json:"max_weight_kg,omitempty"`
+	Amount        float64         `json:"amount"`
+	RatePerKg     float64         `json:"rate_per_kg,omitempty"`
+}
+
+type FreightRequest struct {
+	RouteID     *int        `json:"route_id,omitempty"`
+	Fulfillment Fulfillment `json:"fulfillment"`
+	OrderValue  float64     `json:"order_value"`
+	WeightKg    float64     `json:"weight_kg"`
+}
+
+// OverrideFreightRequest replaces the calculated freight on an order until
+// the override is removed.
+type OverrideFreightRequest struct {
+	FreightAmount float64 `json:"freight_amount"`
+	Reason        string  `json:"reason"`
+}
+
+// ============================================
+// Validation
+// ============================================
+
+func ValidateFreightRule(v *Validator, req *CreateFreightRuleRequest) {
+	v.Check(req.Name != "", "name", "Name is required")
+	v.Check(len(req.Name) <= 100, "name", "Name must not be more than 100 characters")
+	v.Check(req.Amount >= 0, "amount", "Amount cannot be negative")
+	v.Check(req.RatePerKg >= 0, "rate_per_kg", "Rate per kg cannot be negative")
+
+	switch req.RuleType {
+	case FreightRuleRouteFee:
+	case FreightRuleFreeDelivery:
+		v.Check(req.MinOrderValue != nil && *req.MinOrderValue >= 0, "min_order_value", "Minimum order value is required for free delivery")
+	case FreightRuleWeightTier:
+		v.Check(req.MinWeightKg == nil || *req.MinWeightKg >= 0, "min_weight_kg", "Minimum weight cannot be negative")
+		if req.MaxWeightKg != nil {
+			minWeight := 0.0
+			if req.MinWeightKg != nil {
+				minWeight = *req.MinWeightKg
+			}
+			v.Check(*req.MaxWeightKg > minWeight, "max_weight_kg", "Maximum weight must be more than the minimum weight")
+		}
+	case FreightRuleSurcharge:
+		v.Check(ValidFulfillment(req.Fulfillment), "fulfillment", "Fulfillment must be DELIVERY or PICK_UP")
+	default:
+		v.AddError("rule_type", "Rule type must be ROUTE_FEE, FREE_DELIVERY, WEIGHT_TIER or SURCHARGE")
+	}
+
+	// Fields of other rule types are dropped rather than stored
+	if req.RuleType != FreightRuleSurcharge {
+		req.Fulfillment = ""
+	}
+	if req.RuleType != FreightRuleFreeDelivery {
+		req.MinOrderValue = nil
+	}
+	if req.RuleType != FreightRuleWeightTier {
+		req.MinWeightKg, req.MaxWeightKg, req.RatePerKg = nil, nil, 0
+	}
+}
+
+func ValidateFreightRequest(v *Validator, req *FreightRequest) {
+	if req.Fulfillment == "" {
+		req.Fulfillment = FulfillmentDelivery
+	}
+	v.Check(ValidFulfillment(req.Fulfillment), "fulfillment", "Fulfillment must be DELIVERY or PICK_UP")
+	v.Check(req.OrderValue >= 0, "order_value", "Order value cannot be negative")
+	v.Check(req.WeightKg >= 0, "weight_kg", "Weight cannot be negative")
+}
+
+func ValidateOverrideFreight(v *Validator, req *OverrideFreightRequest) {
+	v.Check(req.FreightAmount >= 0, "freight_amount", "Freight amount cannot be negative")
+	v.Check(req.Reason != "", "reason", "Reason is required")
+}
+
+func ValidFulfillment(f Fulfillment) bool {
+	return f == FulfillmentDelivery || f == FulfillmentPickUp
+}
@@ -80,6 +80,7 @@ const (
 
 type GLAccount struct {
 	ID             int              `json:"id"`
+	CompanyID      int              `json:"company_id"`
 	AccountCode    string           `json:"account_code"`
 	AccountName    string           `json:"account_name"`
 	AccountType    GLAccountType    `json:"account_type"`
@@ -96,8 +97,13 @@ type GLAccount struct {
 	CurrentBalance float64          `json:"current_balance"`
 	BudgetAmount   float64          `json:"budget_amount,omitempty"`
 	DepartmentID   *int             `json:"department_id,omitempty"`
-	CreatedAt      CustomDateTime   `json:"created_at"`
-	UpdatedAt      CustomDateTime   `json:"updated_at"`
+	// ConsolidationCode maps the account onto the group chart used by
+	// consolidated reports; empty means the account code itself.
+	ConsolidationCode string         `json:"consolidation_code,omitempty"`
+	IsIntercompany    bool           `json:"is_intercompany"` // Balance is eliminated on consolidation
+	PartnerCompanyID  *int           `json:"partner_company_id,omitempty"`
+	CreatedAt         CustomDateTime `json:"created_at"`
+	UpdatedAt         CustomDateTime `json:"updated_at"`
 }
 
 type GLAccountWithChildren struct {
@@ -112,6 +118,7 @@ type GLAccountWithChildren struct {
 
 type GLFiscalYear struct {
 	ID        int            `json:"id"`
+	CompanyID int            `json:"company_id"`
 	YearCode  string         `json:"year_code"`
 	StartDate CustomDate     `json:"start_date"`
 	EndDate   CustomDate     `json:"end_date"`
@@ -338,35 +345,158 @@ type AccountActivityReport struct {
 	ClosingBalance float64              `json:"closing_balance"`
 }
 
+// ============================================
+// Consolidation Models
+// ============================================
+
+type EliminationStatus string
+
+const (
+	EliminationStatusActive EliminationStatus = "ACTIVE"
+	EliminationStatusVoided EliminationStatus = "VOIDED"
+)
+
+// GLEliminationEntry adjusts consolidated reports only. It is never posted to
+// the ledgers of the companies it touches.
+type GLEliminationEntry struct {
+	ID          int               `json:"id"`
+	EntryNumber string            `json:"entry_number"`
+	EntryDate   CustomDate        `json:"entry_date"`
+	Description string            `json:"description"`
+	Status      EliminationStatus `json:"status"`
+	IsGenerated bool              `json:"is_generated"`
+	TotalDebit  float64           `json:"total_debit"`
+	TotalCredit float64           `json:"total_credit"`
+	CreatedBy   *int              `json:"created_by,omitempty"`
+	CreatedAt   CustomDateTime    `json:"created_at"`
+}
+
+type GLEliminationLine struct {
+	ID            int     `json:"id"`
+	EliminationID int     `json:"elimination_id"`
+	LineNumber    int     `json:"line_number"`
+	CompanyID     int     `json:"company_id"`
+	CompanyName   string  `json:"company_name"`
+	AccountID     int     `json:"account_id"`
+	AccountCode   string  `json:"account_code"`
+	AccountName   string  `json:"account_name"`
+	Description   string  `json:"description,omitempty"`
+	DebitAmount   float64 `json:"debit_amount"`
+	CreditAmount  float64 `json:"credit_amount"`
+}
+
+type GLEliminationEntryWithLines struct {
+	Entry GLEliminationEntry  `json:"entry"`
+	Lines []GLEliminationLine `json:"lines"`
+}
+
+// ConsolidatedCompany is a company included in a consolidated report.
+type ConsolidatedCompany struct {
+	CompanyID   int    `json:"company_id"`
+	CompanyCode string `json:"company_code"`
+	CompanyName string `json:"company_name"`
+}
+
+// ConsolidatedAmount is one company's share of a consolidated line.
+type ConsolidatedAmount struct {
+	CompanyID int     `json:"company_id"`
+	Amount    float64 `json:"amount"`
+}
+
+// ConsolidatedTrialBalanceRow adds up the accounts sharing a consolidation
+// code. Company amounts and eliminations are debit-positive balances.
+type ConsolidatedTrialBalanceRow struct {
+	ConsolidationCode string               `json:"consolidation_code"`
+	AccountName       string               `json:"account_name"`
+	AccountType       GLAccountType        `json:"account_type"`
+	Companies         []ConsolidatedAmount `json:"companies"`
+	Eliminations      float64              `json:"eliminations"`
+	ClosingDebit      float64              `json:"closing_debit"`
+	ClosingCredit     float64              `json:"closing_credit"`
+}
+
+type ConsolidatedTrialBalanceReport struct {
+	DateFrom          string                        `json:"date_from,omitempty"`
+	DateTo            string                        `json:"date_to,omitempty"`
+	Companies         []ConsolidatedCompany         `json:"companies"`
+	Rows              []ConsolidatedTrialBalanceRow `json:"rows"`
+	TotalDebit        float64                       `json:"total_debit"`
+	TotalCredit       float64                       `json:"total_credit"`
+	TotalEliminations float64                       `json:"total_eliminations"`
+}
+
+// ConsolidatedStatementRow is a financial statement line. Amounts carry the
+// sign of the section: revenue, liabilities and equity are credit-positive,
+// expenses and assets debit-positive.
+type ConsolidatedStatementRow struct {
+	ConsolidationCode string               `json:"consolidation_code"`
+	AccountName       string               `json:"account_name"`
+	AccountType       GLAccountType        `json:"account_type"`
+	Companies         []ConsolidatedAmount `json:"companies"`
+	Eliminations      float64              `json:"eliminations"`
+	Amount            float64              `json:"amount"`
+}
+
+type ConsolidatedIncomeStatement struct {
+	DateFrom      string                     `json:"date_from,omitempty"`
+	DateTo        string                     `json:"date_to,omitempty"`
+	Companies     []ConsolidatedCompany      `json:"companies"`
+	Revenue       []ConsolidatedStatementRow `json:"revenue"`
+	TotalRevenue  float64                    `json:"total_revenue"`
+	Expenses      []ConsolidatedStatementRow `json:"expenses"`
+	TotalExpenses float64                    `json:"total_expenses"`
+	NetIncome     float64                    `json:"net_income"`
+}
+
+type ConsolidatedBalanceSheet struct {
+	AsOfDate         string                     `json:"as_of_date,omitempty"`
+	Companies        []ConsolidatedCompany      `json:"companies"`
+	Assets           []ConsolidatedStatementRow `json:"assets"`
+	TotalAssets      float64                    `json:"total_assets"`
+	Liabilities      []ConsolidatedStatementRow `json:"liabilities"`
+	TotalLiabilities float64                    `json:"total_liabilities"`
+	Equity           []ConsolidatedStatementRow `json:"equity"`
+	// CurrentEarnings is consolidated revenue less expenses not yet closed
+	// to retained earnings. It is included in TotalEquity.
+	CurrentEarnings float64 `json:"current_earnings"`
+	TotalEquity     float64 `json:"total_equity"`
+}
+
 // ============================================
 // Request/Response DTOs
 // ============================================
 
 type CreateGLAccountRequest struct {
-	AccountCode    string           `json:"account_code"`
-	AccountName    string           `json:"account_name"`
-	AccountType    GLAccountType    `json:"account_type"`
-	AccountSubType GLAccountSubType `json:"account_sub_type,omitempty"`
-	ParentID       *int             `json:"parent_id,omitempty"`
-	Description    string           `json:"description,omitempty"`
-	Currency       string           `json:"currency"`
-	IsPostable     bool             `json:"is_postable"`
-	IsBankAccount  bool             `json:"is_bank_account"`
-	BankAccountID  *int             `json:"bank_account_id,omitempty"`
-	NormalBalance  string           `json:"normal_balance"` // DEBIT or CREDIT
-	OpeningBalance float64          `json:"opening_balance"`
-	DepartmentID   *int             `json:"department_id,omitempty"`
+	AccountCode       string           `json:"account_code"`
+	AccountName       string           `json:"account_name"`
+	AccountType       GLAccountType    `json:"account_type"`
+	AccountSubType    GLAccountSubType `json:"account_sub_type,omitempty"`
+	ParentID          *int             `json:"parent_id,omitempty"`
+	Description       string           `json:"description,omitempty"`
+	Currency          string           `json:"currency"`
+	IsPostable        bool             `json:"is_postable"`
+	IsBankAccount     bool             `json:"is_bank_account"`
+	BankAccountID     *int             `json:"bank_account_id,omitempty"`
+	NormalBalance     string           `json:"normal_balance"` // DEBIT or CREDIT
+	OpeningBalance    float64          `json:"opening_balance"`
+	DepartmentID      *int             `json:"department_id,omitempty"`
+	ConsolidationCode string           `json:"consolidation_code,omitempty"`
+	IsIntercompany    bool             `json:"is_intercompany"`
+	PartnerCompanyID  *int             `json:"partner_company_id,omitempty"`
 }
 
 type UpdateGLAccountRequest struct {
-	AccountName    *string           `json:"account_name,omitempty"`
-	AccountSubType *GLAccountSubType `json:"account_sub_type,omitempty"`
-	ParentID       *int              `json:"parent_id,omitempty"`
-	Description    *string           `json:"description,omitempty"`
-	IsPostable     *bool             `json:"is_postable,omitempty"`
-	IsActive       *bool             `json:"is_active,omitempty"`
-	BudgetAmount   *float64          `json:"budget_amount,omitempty"`
-	DepartmentID   *int              `json:"department_id,omitempty"`
+	AccountName       *string           `json:"account_name,omitempty"`
+	AccountSubType    *GLAccountSubType `json:"account_sub_type,omitempty"`
+	ParentID          *int              `json:"parent_id,omitempty"`
+	Description       *string           `json:"description,omitempty"`
+	IsPostable        *bool             `json:"is_postable,omitempty"`
+	IsActive          *bool             `json:"is_active,omitempty"`
+	BudgetAmount      *float64          `json:"budget_amount,omitempty"`
+	DepartmentID      *int              `json:"department_id,omitempty"`
+	ConsolidationCode *string           `json:"consolidation_code,omitempty"`
+	IsIntercompany    *bool             `json:"is_intercompany,omitempty"`
+	PartnerCompanyID  *int              `json:"partner_company_id,omitempty"`
 }
 
 type CreateJournalEntryRequest struct {
@@ -441,6 +571,35 @@ type ReportFilters struct {
 	ComparePrior  bool   `json:"compare_prior"`
 }
 
+// ConsolidationFilters selects the companies and dates of a consolidated
+// report. No companies means every company the user can access.
+type ConsolidationFilters struct {
+	CompanyIDs []int  `json:"company_ids,omitempty"`
+	DateFrom   string `json:"date_from,omitempty"`
+	DateTo     string `json:"date_to,omitempty"`
+}
+
+type CreateEliminationEntryRequest struct {
+	EntryDate   string                         `json:"entry_date"`
+	Description string                         `json:"description"`
+	Lines       []CreateEliminationLineRequest `json:"lines"`
+}
+
+type CreateEliminationLineRequest struct {
+	CompanyID    int     `json:"company_id"`
+	AccountID    int     `json:"account_id"`
+	Description  string  `json:"description,omitempty"`
+	DebitAmount  float64 `json:"debit_amount"`
+	CreditAmount float64 `json:"credit_amount"`
+}
+
+// GenerateEliminationsRequest eliminates the balances of intercompany
+// accounts between the given companies as of a date.
+type GenerateEliminationsRequest struct {
+	CompanyIDs []int  `json:"company_ids,omitempty"`
+	AsOfDate   string `json:"as_of_date"`
+}
+
 // ============================================
 // Validation
 // ============================================
@@ -451,6 +610,8 @@ func ValidateGLAccount(v *Validator, req *CreateGLAccountRequest) {
 	v.Check(req.AccountName != "", "account_name", "Account name is required")
 	v.Check(req.AccountType != "", "account_type", "Account type is required")
 	v.Check(req.NormalBalance == "DEBIT" || req.NormalBalance == "CREDIT", "normal_balance", "Normal balance must be DEBIT or CREDIT")
+	v.Check(len(req.ConsolidationCode) <= 20, "consolidation_code", "Consolidation code must be 20 characters or less")
+	v.Check(!req.IsIntercompany || req.PartnerCompanyID != nil, "partner_company_id", "Partner company is required for intercompany accounts")
 	if req.Currency == "" {
 		req.Currency = "USD"
 	}
@@ -492,3 +653,25 @@ func ValidateRecurringEntry(v *Validator, req *CreateRecurringEntryRequest) {
 	v.Check(req.NextRunDate != "", "next_run_date", "Next run date is required")
 	v.Check(len(req.Lines) >= 2, "lines", "At least two lines are required")
 }
+
+func ValidateEliminationEntry(v *Validator, req *CreateEliminationEntryRequest) {
+	v.Check(req.EntryDate != "", "entry_date", "Entry date is required")
+	v.Check(req.Description != "", "description", "Description is required")
+	v.Check(len(req.Lines) >= 2, "lines", "At least two lines are required")
+
+	var totalDebit, totalCredit float64
+	for _, line := range req.Lines {
+		v.Check(line.CompanyID > 0, "lines", "Company is required for all lines")
+		v.Check(line.AccountID > 0, "lines", "Account ID is required for all lines")
+		v.Check((line.DebitAmount > 0) != (line.CreditAmount > 0) && line.DebitAmount >= 0 && line.CreditAmount >= 0,
+			"lines", "Each line must have either a debit or a credit amount")
+		totalDebit += line.DebitAmount
+		totalCredit += line.CreditAmount
+	}
+
+	diff := totalDebit - totalCredit
+	if diff < 0 {
+		diff = -diff
+	}
+	v.Check(diff < 0.01, "lines", "Total debits must equal total credits")
+}
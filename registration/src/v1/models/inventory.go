@@ -111,6 +111,7 @@ type InventorySummary struct {
 	TotalAllocated     float64             `json:"total_allocated"`
 	TotalOnOrder       float64             `json:"total_on_order"`
 	TotalAvailable     float64             `json:"total_available"`
+	TotalReserved      float64             `json:"total_reserved"` // Open reservations, part of available
 	AverageCost        float64             `json:"average_cost"`
 	InventoryValue     float64             `json:"inventory_value"`
 	WarehouseBreakdown []WarehouseQuantity `json:"warehouse_breakdown"`
@@ -122,6 +123,7 @@ type WarehouseQuantity struct {
 	OnHand        float64 `json:"on_hand"`
 	Allocated     float64 `json:"allocated"`
 	Available     float64 `json:"available"`
+	Reserved      float64 `json:"reserved"`
 }
 
 // ============================================
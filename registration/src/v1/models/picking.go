@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 // ============================================
 // Picking & Routing Enums
 // ============================================
@@ -51,6 +53,68 @@ type RouteStop struct {
 	Notes            string `json:"notes,omitempty"`
 }
 
+// ============================================
+// Route Calendar Models
+// ============================================
+
+// RouteDeliveryDay is a weekday a route runs on. Orders for a run close at
+// CutoffTime, LeadDays before it; with no cutoff time they close at the end
+// of that day.
+type RouteDeliveryDay struct {
+	RouteID    int    `json:"route_id"`
+	DayOfWeek  int    `json:"day_of_week"` // 0 = Sunday
+	CutoffTime string `json:"cutoff_time,omitempty"`
+	LeadDays   int    `json:"lead_days"`
+}
+
+// RouteCalendarException cancels a route's run on a date, or adds one. It
+// takes precedence over the weekly calendar and holidays.
+type RouteCalendarException struct {
+	ID         int            `json:"id"`
+	RouteID    int            `json:"route_id"`
+	RunDate    CustomDate     `json:"run_date"`
+	IsRunning  bool           `json:"is_running"`
+	CutoffTime string         `json:"cutoff_time,omitempty"`
+	LeadDays   int            `json:"lead_days"`
+	Reason     string         `json:"reason,omitempty"`
+	CreatedBy  *int           `json:"created_by,omitempty"`
+	CreatedAt  CustomDateTime `json:"created_at"`
+}
+
+type DeliveryHoliday struct {
+	ID          int        `json:"id"`
+	HolidayDate CustomDate `json:"holiday_date"`
+	Name        string     `json:"name"`
+}
+
+type RouteCalendar struct {
+	RouteID      int                      `json:"route_id"`
+	DeliveryDays []RouteDeliveryDay       `json:"delivery_days"`
+	Exceptions   []RouteCalendarException `json:"exceptions"` // Today onwards
+}
+
+// RouteRun is a date a route delivers on and when its orders close.
+type RouteRun struct {
+	RouteID int            `json:"route_id"`
+	RunDate CustomDate     `json:"run_date"`
+	Cutoff  CustomDateTime `json:"cutoff"`
+}
+
+// RerouteResult lists the orders moved off a route run, and those left
+// because they are already being picked.
+type RerouteResult struct {
+	ToRouteID int             `json:"to_route_id"`
+	ToDate    CustomDate      `json:"to_date"`
+	Moved     []ReroutedOrder `json:"moved"`
+	Skipped   []ReroutedOrder `json:"skipped"`
+}
+
+type ReroutedOrder struct {
+	OrderID     int    `json:"order_id"`
+	OrderNumber string `json:"order_number"`
+	Status      string `json:"status"`
+}
+
 // ============================================
 // Pick List Models
 // ============================================
@@ -136,6 +200,40 @@ type AddRouteStopRequest struct {
 	Notes            string `json:"notes,omitempty"`
 }
 
+type SetRouteCalendarRequest struct {
+	DeliveryDays []RouteDeliveryDayRequest `json:"delivery_days"`
+}
+
+type RouteDeliveryDayRequest struct {
+	DayOfWeek  int    `json:"day_of_week"`
+	CutoffTime string `json:"cutoff_time,omitempty"`
+	LeadDays   *int   `json:"lead_days,omitempty"` // Defaults to 1
+}
+
+type CreateRouteExceptionRequest struct {
+	RunDate    string `json:"run_date"`
+	IsRunning  bool   `json:"is_running"`
+	CutoffTime string `json:"cutoff_time,omitempty"`
+	LeadDays   *int   `json:"lead_days,omitempty"` // Defaults to 1
+	Reason     string `json:"reason,omitempty"`
+}
+
+type CreateDeliveryHolidayRequest struct {
+	HolidayDate string `json:"holiday_date"`
+	Name        string `json:"name"`
+}
+
+// RerouteOrdersRequest moves the open orders of one route run. The target
+// route defaults to the same route, and the target date to that route's
+// first run from the original date on.
+type RerouteOrdersRequest struct {
+	FromRouteID int    `json:"from_route_id"`
+	RunDate     string `json:"run_date"`
+	ToRouteID   *int   `json:"to_route_id,omitempty"`
+	ToDate      string `json:"to_date,omitempty"`
+	OrderIDs    []int  `json:"order_ids,omitempty"` // Only these orders; all when empty
+}
+
 type CreatePickListRequest struct {
 	WarehouseID int    `json:"warehouse_id"`
 	RouteID     *int   `json:"route_id,omitempty"`
@@ -195,6 +293,43 @@ func ValidateRoute(v *Validator, req *CreateRouteRequest) {
 	v.Check(req.Name != "", "name", "Name is required")
 }
 
+func ValidateRouteCalendar(v *Validator, req *SetRouteCalendarRequest) {
+	seen := make(map[int]bool)
+	for _, day := range req.DeliveryDays {
+		v.Check(day.DayOfWeek >= 0 && day.DayOfWeek <= 6, "delivery_days", "Day of week must be 0 (Sunday) to 6 (Saturday)")
+		v.Check(!seen[day.DayOfWeek], "delivery_days", "Each day of week can only be listed once")
+		v.Check(day.CutoffTime == "" || timeOfDayRX.MatchString(day.CutoffTime), "delivery_days", "Cutoff time must be HH:MM in 24 hour time")
+		v.Check(day.LeadDays == nil || (*day.LeadDays >= 0 && *day.LeadDays <= 14), "delivery_days", "Lead days must be between 0 and 14")
+		seen[day.DayOfWeek] = true
+	}
+}
+
+func ValidateRouteException(v *Validator, req *CreateRouteExceptionRequest) {
+	_, err := time.Parse("2006-01-02", req.RunDate)
+	v.Check(err == nil, "run_date", "Run date must be YYYY-MM-DD")
+	v.Check(req.CutoffTime == "" || timeOfDayRX.MatchString(req.CutoffTime), "cutoff_time", "Cutoff time must be HH:MM in 24 hour time")
+	v.Check(req.LeadDays == nil || (*req.LeadDays >= 0 && *req.LeadDays <= 14), "lead_days", "Lead days must be between 0 and 14")
+	v.Check(req.IsRunning || (req.CutoffTime == "" && req.LeadDays == nil), "cutoff_time", "Only added runs have a cutoff")
+}
+
+func ValidateDeliveryHoliday(v *Validator, req *CreateDeliveryHolidayRequest) {
+	_, err := time.Parse("2006-01-02", req.HolidayDate)
+	v.Check(err == nil, "holiday_date", "Holiday date must be YYYY-MM-DD")
+	v.Check(req.Name != "", "name", "Name is required")
+	v.Check(len(req.Name) <= 100, "name", "Name must be 100 characters or less")
+}
+
+func ValidateRerouteOrders(v *Validator, req *RerouteOrdersRequest) {
+	v.Check(req.FromRouteID > 0, "from_route_id", "Route is required")
+	_, err := time.Parse("2006-01-02", req.RunDate)
+	v.Check(err == nil, "run_date", "Run date must be YYYY-MM-DD")
+	if req.ToDate != "" {
+		toDate, err := time.Parse("2006-01-02", req.ToDate)
+		v.Check(err == nil, "to_date", "Target date must be YYYY-MM-DD")
+		v.Check(err != nil || !toDate.Before(today()), "to_date", "Target date cannot be in the past")
+	}
+}
+
 func ValidatePickList(v *Validator, req *CreatePickListRequest) {
 	v.Check(req.WarehouseID > 0, "warehouse_id", "Warehouse is required")
 	v.Check(req.PickDate != "", "pick_date", "Pick date is required")
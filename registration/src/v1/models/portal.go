@@ -0,0 +1,319 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// ============================================
+// Customer Portal Models
+// ============================================
+
+// PortalRole is the role carried by portal tokens. Employee routes refuse
+// it and the portal refuses every other role.
+const PortalRole = "customer_portal"
+
+// PortalUser is a customer's user of the ordering portal. A user with
+// AllShipTos sees the whole account; otherwise only the ship-tos listed.
+type PortalUser struct {
+	ID           int            `json:"id"`
+	CompanyID    int            `json:"company_id"`
+	CustomerID   int            `json:"customer_id"`
+	CustomerName string         `json:"customer_name,omitempty"`
+	Email        string         `json:"email"`
+	Name         string         `json:"name"`
+	Phone        string         `json:"phone,omitempty"`
+	AllShipTos   bool           `json:"all_ship_tos"`
+	ShipTos      []PortalShipTo `json:"ship_tos"`
+	IsActive     bool           `json:"is_active"`
+	LastLoginAt  *time.Time     `json:"last_login_at,omitempty"`
+	CreatedBy    *int           `json:"created_by,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
+
+type PortalShipTo struct {
+	ID          int    `json:"id"`
+	ShipToCode  string `json:"ship_to_code"`
+	Name        string `json:"name"`
+	Address     string `json:"address,omitempty"`
+	City        string `json:"city,omitempty"`
+	WarehouseID *int   `json:"warehouse_id,omitempty"`
+	IsDefault   bool   `json:"is_default"`
+}
+
+// PortalIdentity is the signed-in portal user, loaded on every request so
+// a deactivated user or a changed ship-to list takes effect at once.
+type PortalIdentity struct {
+	UserID     int
+	CustomerID int
+	CompanyID  int
+	Email      string
+	Name       string
+	AllShipTos bool
+	ShipToIDs  []int
+}
+
+// CanUseShipTo reports whether the user may order for, or see, a ship-to.
+func (p *PortalIdentity) CanUseShipTo(shipToID int) bool {
+	return p.AllShipTos || slices.Contains(p.ShipToIDs, shipToID)
+}
+
+// PortalCatalogItem is a product at the customer's price, with what the
+// warehouse serving the ship-to has available.
+type PortalCatalogItem struct {
+	ProductID     int        `json:"product_id"`
+	ProductSKU    string     `json:"product_sku"`
+	ProductName   string     `json:"product_name"`
+	Description   string     `json:"description,omitempty"`
+	CategoryName  string     `json:"category_name,omitempty"`
+	UnitOfMeasure string     `json:"unit_of_measure"`
+	Price         float64    `json:"price"`
+	OriginalPrice float64    `json:"original_price,omitempty"` // Before a promotion
+	PriceLevel    PriceLevel `json:"price_level,omitempty"`
+	Available     float64    `json:"available"`
+	InStock       bool       `json:"in_stock"`
+	OnOrderGuide  bool       `json:"on_order_guide"`
+}
+
+// PortalOrder is a sales order as the customer sees it: no costs or
+// margins, and no internal notes beyond what was ordered.
+type PortalOrder struct {
+	ID                int               `json:"id"`
+	OrderNumber       string            `json:"order_number"`
+	OrderType         OrderType         `json:"order_type"`
+	Status            OrderStatus       `json:"status"`
+	OrderDate         CustomDate        `json:"order_date"`
+	RequestedShipDate *CustomDate       `json:"requested_ship_date,omitempty"`
+	ActualShipDate    *CustomDate       `json:"actual_ship_date,omitempty"`
+	ShipToID          *int              `json:"ship_to_id,omitempty"`
+	ShipToName        string            `json:"ship_to_name,omitempty"`
+	PONumber          string            `json:"po_number,omitempty"`
+	Subtotal          float64           `json:"subtotal"`
+	TaxAmount         float64           `json:"tax_amount"`
+	FreightAmount     float64           `json:"freight_amount"`
+	TotalAmount       float64           `json:"total_amount"`
+	Currency          string            `json:"currency"`
+	Notes             string            `json:"notes,omitempty"`
+	FromPortal        bool              `json:"from_portal"`
+	Lines             []PortalOrderLine `json:"lines,omitempty"`
+}
+
+type PortalOrderLine struct {
+	LineNumber      int     `json:"line_number"`
+	ProductID       int     `json:"product_id"`
+	ProductSKU      string  `json:"product_sku"`
+	ProductName     string  `json:"product_name"`
+	Quantity        float64 `json:"quantity"`
+	QuantityShipped float64 `json:"quantity_shipped"`
+	UnitOfMeasure   string  `json:"unit_of_measure"`
+	UnitPrice       float64 `json:"unit_price"`
+	DiscountPercent float64 `json:"discount_percent,omitempty"`
+	LineTotal       float64 `json:"line_total"`
+}
+
+// PortalSubmittedOrder is an order a customer submitted through the
+// portal, listed for reps to review.
+type PortalSubmittedOrder struct {
+	OrderID           int         `json:"order_id"`
+	OrderNumber       string      `json:"order_number"`
+	Status            OrderStatus `json:"status"`
+	CustomerID        int         `json:"customer_id"`
+	CustomerName      string      `json:"customer_name"`
+	ShipToName        string      `json:"ship_to_name,omitempty"`
+	PortalUserID      int         `json:"portal_user_id"`
+	PortalUserName    string      `json:"portal_user_name"`
+	OrderDate         CustomDate  `json:"order_date"`
+	RequestedShipDate *CustomDate `json:"requested_ship_date,omitempty"`
+	PONumber          string      `json:"po_number,omitempty"`
+	TotalAmount       float64     `json:"total_amount"`
+	CreatedAt         time.Time   `json:"created_at"`
+}
+
+// ============================================
+// Request DTOs
+// ============================================
+
+// CreatePortalUserRequest adds a portal user. Without ship-tos the user
+// sees the whole account.
+type CreatePortalUserRequest struct {
+	CustomerID int    `json:"customer_id"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	Name       string `json:"name"`
+	Phone      string `json:"phone,omitempty"`
+	ShipToIDs  []int  `json:"ship_to_ids,omitempty"`
+}
+
+// UpdatePortalUserRequest changes a portal user. An empty ShipToIDs list
+// gives the user the whole account again.
+type UpdatePortalUserRequest struct {
+	Email     *string `json:"email,omitempty"`
+	Password  *string `json:"password,omitempty"`
+	Name      *string `json:"name,omitempty"`
+	Phone     *string `json:"phone,omitempty"`
+	ShipToIDs *[]int  `json:"ship_to_ids,omitempty"`
+	IsActive  *bool   `json:"is_active,omitempty"`
+}
+
+type PortalUserFilters struct {
+	CustomerID      *int
+	Search          string // Name or email
+	IncludeInactive bool
+}
+
+type PortalLoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type PortalLoginResponse struct {
+	Token string     `json:"token"`
+	User  PortalUser `json:"user"`
+}
+
+type PortalCatalogFilters struct {
+	ShipToID   *int // Availability is at the ship-to's warehouse
+	CategoryID *int
+	Search     string // SKU or name
+	InStock    bool
+	Page       int
+	PageSize   int
+}
+
+// PortalOrderLineRequest is a line of a portal order. Lines are priced at
+// the customer's price; the portal cannot set prices or discounts.
+type PortalOrderLineRequest struct {
+	ProductID int     `json:"product_id"`
+	Quantity  float64 `json:"quantity"`
+}
+
+// PortalOrderRequest submits an order from the customer's cart.
+type PortalOrderRequest struct {
+	ShipToID          *int                     `json:"ship_to_id,omitempty"`
+	RequestedShipDate string                   `json:"requested_ship_date,omitempty"`
+	PONumber          string                   `json:"po_number,omitempty"`
+	Notes             string                   `json:"notes,omitempty"`
+	Lines             []PortalOrderLineRequest `json:"lines"`
+}
+
+// PortalGuideLineEdit changes the quantity of a proposed line, or adds a
+// product. A zero quantity drops the line.
+type PortalGuideLineEdit struct {
+	ProductID int     `json:"product_id"`
+	Quantity  float64 `json:"quantity"`
+}
+
+// PortalOrderGuideRequest proposes, or submits, an order from the
+// customer's order guide.
+type PortalOrderGuideRequest struct {
+	ShipToID          *int                  `json:"ship_to_id,omitempty"`
+	RequestedShipDate string                `json:"requested_ship_date,omitempty"`
+	PONumber          string                `json:"po_number,omitempty"`
+	Notes             string                `json:"notes,omitempty"`
+	Lines             []PortalGuideLineEdit `json:"lines,omitempty"`
+}
+
+type PortalOrderFilters struct {
+	ShipToID *int
+	Status   *OrderStatus
+	DateFrom string
+	DateTo   string
+	Page     int
+	PageSize int
+}
+
+type PortalSubmittedOrderFilters struct {
+	CustomerID *int
+	Status     *OrderStatus // Defaults to DRAFT, the orders awaiting review
+}
+
+// ============================================
+// Validation
+// ============================================
+
+func ValidatePortalUser(v *Validator, req *CreatePortalUserRequest) {
+	v.Check(req.CustomerID > 0, "customer_id", "Customer is required")
+	validatePortalEmail(v, req.Email)
+	validatePortalPassword(v, req.Password)
+	v.Check(strings.TrimSpace(req.Name) != "", "name", "Name is required")
+	v.Check(len(req.Name) <= 100, "name", "Name must be 100 characters or less")
+	v.Check(len(req.Phone) <= 50, "phone", "Phone must not be more than 50 characters")
+	validatePortalShipTos(v, req.ShipToIDs)
+}
+
+func ValidateUpdatePortalUser(v *Validator, req *UpdatePortalUserRequest) {
+	if req.Email != nil {
+		validatePortalEmail(v, *req.Email)
+	}
+	if req.Password != nil {
+		validatePortalPassword(v, *req.Password)
+	}
+	if req.Name != nil {
+		v.Check(strings.TrimSpace(*req.Name) != "", "name", "Name is required")
+		v.Check(len(*req.Name) <= 100, "name", "Name must be 100 characters or less")
+	}
+	v.Check(req.Phone == nil || len(*req.Phone) <= 50, "phone", "Phone must not be more than 50 characters")
+	if req.ShipToIDs != nil {
+		validatePortalShipTos(v, *req.ShipToIDs)
+	}
+}
+
+func validatePortalEmail(v *Validator, email string) {
+	v.Check(email != "", "email", "Email is required")
+	v.Check(len(email) <= 255, "email", "Email must be 255 characters or less")
+	v.Check(email == "" || emailRX.MatchString(email), "email", "Invalid email address")
+}
+
+func validatePortalPassword(v *Validator, password string) {
+	v.Check(len(password) >= 8, "password", "Password must be at least 8 characters")
+	// bcrypt ignores anything past 72 bytes
+	v.Check(len(password) <= 72, "password", "Password must not be more than 72 characters")
+}
+
+func validatePortalShipTos(v *Validator, ids []int) {
+	seen := make(map[int]bool)
+	for _, id := range ids {
+		v.Check(id > 0 && !seen[id], "ship_to_ids", "Ship-to IDs must be valid and not repeated")
+		seen[id] = true
+	}
+}
+
+func ValidatePortalLogin(v *Validator, req *PortalLoginRequest) {
+	v.Check(req.Email != "", "email", "Email is required")
+	v.Check(req.Password != "", "password", "Password is required")
+}
+
+func ValidatePortalOrder(v *Validator, req *PortalOrderRequest) {
+	v.Check(len(req.Lines) > 0, "lines", "At least one line is required")
+	seen := make(map[int]bool)
+	for _, line := range req.Lines {
+		v.Check(line.ProductID > 0, "lines", "Product ID is required for all lines")
+		v.Check(!seen[line.ProductID], "lines", "Each product can only be ordered once")
+		v.Check(line.Quantity > 0, "lines", "Quantity must be positive for all lines")
+		seen[line.ProductID] = true
+	}
+	validatePortalOrderHeader(v, req.RequestedShipDate, req.PONumber, req.Notes)
+}
+
+func ValidatePortalOrderGuide(v *Validator, req *PortalOrderGuideRequest) {
+	seen := make(map[int]bool)
+	for _, line := range req.Lines {
+		v.Check(line.ProductID > 0, "lines", "Product ID is required for all lines")
+		v.Check(!seen[line.ProductID], "lines", "Each product can only be edited once")
+		v.Check(line.Quantity >= 0, "lines", "Quantity cannot be negative")
+		seen[line.ProductID] = true
+	}
+	validatePortalOrderHeader(v, req.RequestedShipDate, req.PONumber, req.Notes)
+}
+
+func validatePortalOrderHeader(v *Validator, requestedShipDate, poNumber, notes string) {
+	if requestedShipDate != "" {
+		shipDate, err := time.Parse("2006-01-02", requestedShipDate)
+		v.Check(err == nil, "requested_ship_date", "Requested ship date must be YYYY-MM-DD")
+		v.Check(err != nil || !shipDate.Before(today()), "requested_ship_date", "Requested ship date cannot be in the past")
+	}
+	v.Check(len(poNumber) <= 50, "po_number", "PO number must not be more than 50 characters")
+	v.Check(len(notes) <= 1000, "notes", "Notes must not be more than 1000 characters")
+}
@@ -132,6 +132,68 @@ type PriceListItem struct {
 	Cost          float64 `json:"cost"`
 }
 
+// ============================================
+// Margin Guard
+// ============================================
+
+type MarginAction string
+
+const (
+	MarginActionBlock MarginAction = "BLOCK" // Refuse the line
+	MarginActionWarn  MarginAction = "WARN"  // Accept the line with a warning
+)
+
+// MarginRule sets the minimum margin for sales matching its category,
+// customer group and salesperson. An empty dimension matches everything.
+type MarginRule struct {
+	ID               int          `json:"id"`
+	Name             string       `json:"name"`
+	CategoryID       *int         `json:"category_id,omitempty"`
+	CustomerGroupID  *int         `json:"customer_group_id,omitempty"`
+	SalesRepID       *int         `json:"sales_rep_id,omitempty"`
+	MinMarginPercent float64      `json:"min_margin_percent"`
+	Action           MarginAction `json:"action"`
+	IsActive         bool         `json:"is_active"`
+	CreatedAt        CustomDate   `json:"created_at"`
+}
+
+// MarginCheck is a sale price measured against the product's cost and the
+// margin rules that apply to it.
+type MarginCheck struct {
+	ProductID     int           `json:"product_id"`
+	UnitPrice     float64       `json:"unit_price"`
+	UnitCost      float64       `json:"unit_cost"`
+	CostingMethod CostingMethod `json:"costing_method"`
+	Margin        float64       `json:"margin"`
+	MarginPercent float64       `json:"margin_percent"`
+	IsBelowCost   bool          `json:"is_below_cost"`
+	Blocked       bool          `json:"blocked"`
+	Warnings      []string      `json:"warnings,omitempty"`
+}
+
+// MarginException is a line sold below cost.
+type MarginException struct {
+	ID            int           `json:"id"`
+	OrderID       int           `json:"order_id"`
+	OrderNumber   string        `json:"order_number"`
+	OrderLineID   *int          `json:"order_line_id,omitempty"`
+	ProductID     int           `json:"product_id"`
+	ProductSKU    string        `json:"product_sku"`
+	ProductName   string        `json:"product_name"`
+	CustomerID    int           `json:"customer_id"`
+	CustomerName  string        `json:"customer_name"`
+	SalesRepID    *int          `json:"sales_rep_id,omitempty"`
+	SalesRepName  string        `json:"sales_rep_name,omitempty"`
+	Quantity      float64       `json:"quantity"`
+	UnitPrice     float64       `json:"unit_price"`
+	UnitCost      float64       `json:"unit_cost"`
+	CostingMethod CostingMethod `json:"costing_method"`
+	MarginPercent float64       `json:"margin_percent"`
+	LostMargin    float64       `json:"lost_margin"`
+	CreatedBy     *int          `json:"created_by,omitempty"`
+	CreatedAt     CustomDate    `json:"created_at"`
+}
+
 // ============================================
 // Request/Response Types
 // ============================================
@@ -205,6 +267,32 @@ type PriceLookupRequest struct {
 	AsOfDate   string  `json:"as_of_date,omitempty"`
 }
 
+type CreateMarginRuleRequest struct {
+	Name             string       `json:"name"`
+	CategoryID       *int         `json:"category_id,omitempty"`
+	CustomerGroupID  *int         `json:"customer_group_id,omitempty"`
+	SalesRepID       *int         `json:"sales_rep_id,omitempty"`
+	MinMarginPercent float64      `json:"min_margin_percent"`
+	Action           MarginAction `json:"action"`
+}
+
+type MarginCheckRequest struct {
+	ProductID   int     `json:"product_id"`
+	WarehouseID int     `json:"warehouse_id,omitempty"` // Average cost of this warehouse's stock
+	CustomerID  int     `json:"customer_id,omitempty"`
+	SalesRepID  int     `json:"sales_rep_id,omitempty"` // Defaults to the customer's rep
+	UnitPrice   float64 `json:"unit_price"`             // Net of line discount
+}
+
+type MarginExceptionFilters struct {
+	DateFrom   string `json:"date_from,omitempty"`
+	DateTo     string `json:"date_to,omitempty"`
+	CustomerID *int   `json:"customer_id,omitempty"`
+	SalesRepID *int   `json:"sales_rep_id,omitempty"`
+	Page       int    `json:"page"`
+	PageSize   int    `json:"page_size"`
+}
+
 type PriceListFilters struct {
 	CategoryID    *int   `json:"category_id,omitempty"`
 	EffectiveDate string `json:"effective_date,omitempty"`
@@ -257,3 +345,14 @@ func ValidateMassPriceUpdate(v *Validator, req *MassPriceUpdateRequest) {
 		"adjustment_type", "Must be PERCENT or AMOUNT")
 	v.Check(req.EffectiveDate != "", "effective_date", "Effective date is required")
 }
+
+func ValidateMarginRule(v *Validator, req *CreateMarginRuleRequest) {
+	v.Check(req.Name != "", "name", "Name is required")
+	v.Check(req.MinMarginPercent > -100 && req.MinMarginPercent < 100,
+		"min_margin_percent", "Minimum margin must be between -100 and 100 percent")
+	if req.Action == "" {
+		req.Action = MarginActionWarn
+	}
+	v.Check(req.Action == MarginActionBlock || req.Action == MarginActionWarn,
+		"action", "Must be BLOCK or WARN")
+}
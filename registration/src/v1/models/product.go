@@ -1,40 +1,45 @@
 package models
 
+import "github.com/anas-dev-92/FoodHive/registration/src/v1/utils/query"
+
 // ============================================
 // Product Models
 // ============================================
 
 type Product struct {
-	ID               int        `json:"id"`
-	SKU              string     `json:"sku"`
-	Barcode          string     `json:"barcode,omitempty"`
-	UPC              string     `json:"upc,omitempty"`
-	Name             string     `json:"name"`
-	Description      string     `json:"description,omitempty"`
-	CategoryID       *int       `json:"category_id,omitempty"`
-	BaseUnit         string     `json:"base_unit"`
-	IsCatchWeight    bool       `json:"is_catch_weight"`
-	CatchWeightUnit  string     `json:"catch_weight_unit,omitempty"`
-	CountryOfOrigin  string     `json:"country_of_origin,omitempty"`
-	ShelfLifeDays    int        `json:"shelf_life_days,omitempty"`
-	MinShelfLifeDays int        `json:"min_shelf_life_days,omitempty"`
-	IsLotTracked     bool       `json:"is_lot_tracked"`
-	IsSerialized     bool       `json:"is_serialized"`
-	HACCPCategory    string     `json:"haccp_category,omitempty"`
-	QCRequired       bool       `json:"qc_required"`
-	IsActive         bool       `json:"is_active"`
-	CreatedAt        CustomDate `json:"created_at"`
-	UpdatedAt        CustomDate `json:"updated_at"`
+	ID               int           `json:"id"`
+	SKU              string        `json:"sku"`
+	Barcode          string        `json:"barcode,omitempty"`
+	UPC              string        `json:"upc,omitempty"`
+	Name             string        `json:"name"`
+	NameTranslations Translations  `json:"name_translations,omitempty"`
+	Description      string        `json:"description,omitempty"`
+	CategoryID       *int          `json:"category_id,omitempty"`
+	BaseUnit         string        `json:"base_unit"`
+	IsCatchWeight    bool          `json:"is_catch_weight"`
+	CatchWeightUnit  string        `json:"catch_weight_unit,omitempty"`
+	CountryOfOrigin  string        `json:"country_of_origin,omitempty"`
+	ShelfLifeDays    int           `json:"shelf_life_days,omitempty"`
+	MinShelfLifeDays int           `json:"min_shelf_life_days,omitempty"`
+	IsLotTracked     bool          `json:"is_lot_tracked"`
+	IsSerialized     bool          `json:"is_serialized"`
+	HACCPCategory    string        `json:"haccp_category,omitempty"`
+	QCRequired       bool          `json:"qc_required"`
+	CostingMethod    CostingMethod `json:"costing_method"`
+	IsActive         bool          `json:"is_active"`
+	CreatedAt        CustomDate    `json:"created_at"`
+	UpdatedAt        CustomDate    `json:"updated_at"`
 }
 
 type ProductCategory struct {
-	ID                   int    `json:"id"`
-	Code                 string `json:"code"`
-	Name                 string `json:"name"`
-	ParentID             *int   `json:"parent_id,omitempty"`
-	GLSalesAccountID     *int   `json:"gl_sales_account_id,omitempty"`
-	GLCOGSAccountID      *int   `json:"gl_cogs_account_id,omitempty"`
-	GLInventoryAccountID *int   `json:"gl_inventory_account_id,omitempty"`
+	ID                   int          `json:"id"`
+	Code                 string       `json:"code"`
+	Name                 string       `json:"name"`
+	NameTranslations     Translations `json:"name_translations,omitempty"`
+	ParentID             *int         `json:"parent_id,omitempty"`
+	GLSalesAccountID     *int         `json:"gl_sales_account_id,omitempty"`
+	GLCOGSAccountID      *int         `json:"gl_cogs_account_id,omitempty"`
+	GLInventoryAccountID *int         `json:"gl_inventory_account_id,omitempty"`
 }
 
 type ProductUnit struct {
@@ -62,43 +67,48 @@ type ProductWithDetails struct {
 // ============================================
 
 type CreateProductRequest struct {
-	SKU              string `json:"sku"`
-	Barcode          string `json:"barcode,omitempty"`
-	UPC              string `json:"upc,omitempty"`
-	Name             string `json:"name"`
-	Description      string `json:"description,omitempty"`
-	CategoryID       *int   `json:"category_id,omitempty"`
-	BaseUnit         string `json:"base_unit"`
-	IsCatchWeight    bool   `json:"is_catch_weight"`
-	CatchWeightUnit  string `json:"catch_weight_unit,omitempty"`
-	CountryOfOrigin  string `json:"country_of_origin,omitempty"`
-	ShelfLifeDays    int    `json:"shelf_life_days,omitempty"`
-	MinShelfLifeDays int    `json:"min_shelf_life_days,omitempty"`
-	IsLotTracked     bool   `json:"is_lot_tracked"`
-	IsSerialized     bool   `json:"is_serialized"`
-	HACCPCategory    string `json:"haccp_category,omitempty"`
-	QCRequired       bool   `json:"qc_required"`
+	SKU              string        `json:"sku"`
+	Barcode          string        `json:"barcode,omitempty"`
+	UPC              string        `json:"upc,omitempty"`
+	Name             string        `json:"name"`
+	NameTranslations Translations  `json:"name_translations,omitempty"`
+	Description      string        `json:"description,omitempty"`
+	CategoryID       *int          `json:"category_id,omitempty"`
+	BaseUnit         string        `json:"base_unit"`
+	IsCatchWeight    bool          `json:"is_catch_weight"`
+	CatchWeightUnit  string        `json:"catch_weight_unit,omitempty"`
+	CountryOfOrigin  string        `json:"country_of_origin,omitempty"`
+	ShelfLifeDays    int           `json:"shelf_life_days,omitempty"`
+	MinShelfLifeDays int           `json:"min_shelf_life_days,omitempty"`
+	IsLotTracked     bool          `json:"is_lot_tracked"`
+	IsSerialized     bool          `json:"is_serialized"`
+	HACCPCategory    string        `json:"haccp_category,omitempty"`
+	QCRequired       bool          `json:"qc_required"`
+	CostingMethod    CostingMethod `json:"costing_method,omitempty"` // Defaults to AVERAGE
 }
 
 type UpdateProductRequest struct {
-	Name             *string `json:"name,omitempty"`
-	Description      *string `json:"description,omitempty"`
-	CategoryID       *int    `json:"category_id,omitempty"`
-	BaseUnit         *string `json:"base_unit,omitempty"`
-	CountryOfOrigin  *string `json:"country_of_origin,omitempty"`
-	ShelfLifeDays    *int    `json:"shelf_life_days,omitempty"`
-	MinShelfLifeDays *int    `json:"min_shelf_life_days,omitempty"`
-	HACCPCategory    *string `json:"haccp_category,omitempty"`
-	QCRequired       *bool   `json:"qc_required,omitempty"`
-	IsActive         *bool   `json:"is_active,omitempty"`
+	Name             *string        `json:"name,omitempty"`
+	NameTranslations Translations   `json:"name_translations,omitempty"`
+	Description      *string        `json:"description,omitempty"`
+	CategoryID       *int           `json:"category_id,omitempty"`
+	BaseUnit         *string        `json:"base_unit,omitempty"`
+	CountryOfOrigin  *string        `json:"country_of_origin,omitempty"`
+	ShelfLifeDays    *int           `json:"shelf_life_days,omitempty"`
+	MinShelfLifeDays *int           `json:"min_shelf_life_days,omitempty"`
+	HACCPCategory    *string        `json:"haccp_category,omitempty"`
+	QCRequired       *bool          `json:"qc_required,omitempty"`
+	CostingMethod    *CostingMethod `json:"costing_method,omitempty"`
+	IsActive         *bool          `json:"is_active,omitempty"`
 }
 
 type ProductListFilters struct {
-	Search     string `json:"search,omitempty"`
-	CategoryID *int   `json:"category_id,omitempty"`
-	IsActive   *bool  `json:"is_active,omitempty"`
-	Page       int    `json:"page"`
-	PageSize   int    `json:"page_size"`
+	Search     string        `json:"search,omitempty"`
+	CategoryID *int          `json:"category_id,omitempty"`
+	IsActive   *bool         `json:"is_active,omitempty"`
+	Page       int           `json:"page"`
+	PageSize   int           `json:"page_size"`
+	Query      *query.Params `json:"-"`
 }
 
 // ============================================
@@ -109,6 +119,7 @@ func ValidateProduct(v *Validator, p *CreateProductRequest) {
 	v.Check(p.SKU != "", "sku", "SKU is required")
 	v.Check(len(p.SKU) <= 50, "sku", "SKU must be 50 characters or less")
 	v.Check(p.Name != "", "name", "Product name is required")
+	ValidateTranslations(v, "name_translations", p.NameTranslations)
 	v.Check(p.BaseUnit != "", "base_unit", "Base unit is required")
 	if p.IsCatchWeight {
 		v.Check(p.CatchWeightUnit != "", "catch_weight_unit", "Catch weight unit is required for catch weight items")
@@ -116,4 +127,16 @@ func ValidateProduct(v *Validator, p *CreateProductRequest) {
 	if p.CountryOfOrigin != "" {
 		v.Check(len(p.CountryOfOrigin) == 3, "country_of_origin", "Country of origin must be a 3-letter code")
 	}
+	if p.CostingMethod == "" {
+		p.CostingMethod = CostMethodAverage
+	}
+	v.Check(ValidCostingMethod(p.CostingMethod), "costing_method", "Unknown costing method")
+}
+
+func ValidCostingMethod(m CostingMethod) bool {
+	switch m {
+	case CostMethodAverage, CostMethodLast, CostMethodLanded, CostMethodMarket, CostMethodVendor, CostMethodAdjusted:
+		return true
+	}
+	return false
 }
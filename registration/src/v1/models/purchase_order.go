@@ -1,5 +1,7 @@
 package models
 
+import "github.com/anas-dev-92/FoodHive/registration/src/v1/utils/query"
+
 // ============================================
 // Purchase Order Enums
 // ============================================
@@ -134,14 +136,53 @@ type UpdatePurchaseOrderRequest struct {
 }
 
 type PurchaseOrderListFilters struct {
-	VendorID    *int      `json:"vendor_id,omitempty"`
-	WarehouseID *int      `json:"warehouse_id,omitempty"`
-	Status      *POStatus `json:"status,omitempty"`
-	BuyerID     *int      `json:"buyer_id,omitempty"`
-	DateFrom    string    `json:"date_from,omitempty"`
-	DateTo      string    `json:"date_to,omitempty"`
-	Page        int       `json:"page"`
-	PageSize    int       `json:"page_size"`
+	VendorID    *int          `json:"vendor_id,omitempty"`
+	WarehouseID *int          `json:"warehouse_id,omitempty"`
+	Status      *POStatus     `json:"status,omitempty"`
+	BuyerID     *int          `json:"buyer_id,omitempty"`
+	DateFrom    string        `json:"date_from,omitempty"`
+	DateTo      string        `json:"date_to,omitempty"`
+	Page        int           `json:"page"`
+	PageSize    int           `json:"page_size"`
+	Query       *query.Params `json:"-"`
+}
+
+// ============================================
+// Replenishment
+// ============================================
+
+// ReplenishmentSuggestion is a product a buyer should reorder for a
+// warehouse, with the sales lost for want of it. The position is what is
+// available and on order less what backorders are waiting for; the
+// suggestion brings it back to the reorder point plus the reorder quantity.
+type ReplenishmentSuggestion struct {
+	ProductID         int     `json:"product_id"`
+	ProductSKU        string  `json:"product_sku"`
+	ProductName       string  `json:"product_name"`
+	VendorID          *int    `json:"vendor_id,omitempty"`
+	VendorName        string  `json:"vendor_name,omitempty"`
+	UnitCost          float64 `json:"unit_cost"`
+	Available         float64 `json:"available"`
+	OnOrder           float64 `json:"on_order"`
+	Backordered       float64 `json:"backordered"`
+	Position          float64 `json:"position"`
+	ReorderPoint      float64 `json:"reorder_point"`
+	ReorderQuantity   float64 `json:"reorder_quantity"`
+	SuggestedQuantity float64 `json:"suggested_quantity"`
+
+	// Lost sales over the last Weeks weeks against the Weeks before
+	LostQuantity      float64 `json:"lost_quantity"`
+	LostRevenue       float64 `json:"lost_revenue"`
+	PriorLostQuantity float64 `json:"prior_lost_quantity"`
+	LostTrend         string  `json:"lost_trend"` // UP, DOWN or FLAT
+}
+
+// ReplenishmentFilters lists products at or below their reorder point,
+// and any with lost sales in the period.
+type ReplenishmentFilters struct {
+	WarehouseID int
+	VendorID    *int
+	Weeks       int // Lost sales period, 4 by default
 }
 
 // ============================================
@@ -199,3 +240,8 @@ func ValidateReceiving(v *Validator, req *CreateReceivingRequest) {
 		_ = i
 	}
 }
+
+func ValidateReplenishment(v *Validator, f *ReplenishmentFilters) {
+	v.Check(f.WarehouseID > 0, "warehouse_id", "Warehouse is required")
+	v.Check(f.Weeks >= 0 && f.Weeks <= 52, "weeks", "Weeks must be between 1 and 52")
+}
@@ -0,0 +1,263 @@
+package models
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ============================================
+// Report Enums
+// ============================================
+
+type ReportType string
+
+const (
+	ReportTypeARAging           ReportType = "AR_AGING"
+	ReportTypeExpiringInventory ReportType = "EXPIRING_INVENTORY"
+	ReportTypeDailySales        ReportType = "DAILY_SALES_SUMMARY"
+)
+
+type ReportFormat string
+
+const (
+	ReportFormatPDF ReportFormat = "PDF"
+	ReportFormatCSV ReportFormat = "CSV"
+)
+
+type ReportFrequency string
+
+const (
+	ReportFrequencyDaily   ReportFrequency = "DAILY"
+	ReportFrequencyWeekly  ReportFrequency = "WEEKLY"
+	ReportFrequencyMonthly ReportFrequency = "MONTHLY"
+)
+
+type ReportDeliveryStatus string
+
+const (
+	ReportDeliveryPending  ReportDeliveryStatus = "PENDING"
+	ReportDeliveryRetrying ReportDeliveryStatus = "RETRYING"
+	ReportDeliverySent     ReportDeliveryStatus = "SENT"
+	ReportDeliveryFailed   ReportDeliveryStatus = "FAILED"
+)
+
+// ============================================
+// Report Parameters
+// ============================================
+
+// ReportParameters holds the options understood by the report types. Unused
+// fields are ignored by the other report types.
+type ReportParameters struct {
+	// EXPIRING_INVENTORY: lots expiring within this many days (default 30)
+	DaysToExpiry int `json:"days_to_expiry,omitempty"`
+	// EXPIRING_INVENTORY, DAILY_SALES_SUMMARY
+	WarehouseID *int `json:"warehouse_id,omitempty"`
+	// DAILY_SALES_SUMMARY: days before the run date to report on (default 1, yesterday)
+	DaysBack *int `json:"days_back,omitempty"`
+	// AR_AGING: skip customers owing less than this
+	MinBalance float64 `json:"min_balance,omitempty"`
+}
+
+// ============================================
+// Report Subscription
+// ============================================
+
+type ReportSubscription struct {
+	ID         int              `json:"id"`
+	Name       string           `json:"name"`
+	ReportType ReportType       `json:"report_type"`
+	Parameters ReportParameters `json:"parameters"`
+	Format     ReportFormat     `json:"format"`
+	Frequency  ReportFrequency  `json:"frequency"`
+	TimeOfDay  string           `json:"time_of_day"`
+	DayOfWeek  *int             `json:"day_of_week,omitempty"`
+	DayOfMonth *int             `json:"day_of_month,omitempty"`
+	Timezone   string           `json:"timezone"`
+	Recipients []string         `json:"recipients"`
+	IsActive   bool             `json:"is_active"`
+	NextRunAt  *time.Time       `json:"next_run_at,omitempty"`
+	LastRunAt  *time.Time       `json:"last_run_at,omitempty"`
+	CreatedBy  *int             `json:"created_by,omitempty"`
+	CreatedAt  CustomDateTime   `json:"created_at"`
+	UpdatedAt  CustomDateTime   `json:"updated_at"`
+	CompanyID  int              `json:"company_id"`
+}
+
+type CreateReportSubscriptionRequest struct {
+	Name       string           `json:"name"`
+	ReportType ReportType       `json:"report_type"`
+	Parameters ReportParameters `json:"parameters"`
+	Format     ReportFormat     `json:"format"`
+	Frequency  ReportFrequency  `json:"frequency"`
+	TimeOfDay  string           `json:"time_of_day"`
+	DayOfWeek  *int             `json:"day_of_week,omitempty"`
+	DayOfMonth *int             `json:"day_of_month,omitempty"`
+	Timezone   string           `json:"timezone"`
+	Recipients []string         `json:"recipients"`
+}
+
+type UpdateReportSubscriptionRequest struct {
+	Name       *string           `json:"name,omitempty"`
+	Parameters *ReportParameters `json:"parameters,omitempty"`
+	Format     *ReportFormat     `json:"format,omitempty"`
+	Frequency  *ReportFrequency  `json:"frequency,omitempty"`
+	TimeOfDay  *string           `json:"time_of_day,omitempty"`
+	DayOfWeek  *int              `json:"day_of_week,omitempty"`
+	DayOfMonth *int              `json:"day_of_month,omitempty"`
+	Timezone   *string           `json:"timezone,omitempty"`
+	Recipients []string          `json:"recipients,omitempty"`
+	IsActive   *bool             `json:"is_active,omitempty"`
+}
+
+// ============================================
+// Report Delivery Log
+// ============================================
+
+type ReportDelivery struct {
+	ID               int                  `json:"id"`
+	SubscriptionID   int                  `json:"subscription_id"`
+	SubscriptionName string               `json:"subscription_name,omitempty"`
+	ReportType       ReportType           `json:"report_type"`
+	ScheduledFor     time.Time            `json:"scheduled_for"`
+	Status           ReportDeliveryStatus `json:"status"`
+	Attempts         int                  `json:"attempts"`
+	LastError        string               `json:"last_error,omitempty"`
+	NextAttemptAt    *time.Time           `json:"next_attempt_at,omitempty"`
+	Recipients       []string             `json:"recipients"`
+	FileName         string               `json:"file_name,omitempty"`
+	SentAt           *time.Time           `json:"sent_at,omitempty"`
+	CreatedAt        CustomDateTime       `json:"created_at"`
+}
+
+type ReportDeliveryFilters struct {
+	SubscriptionID *int                 `json:"subscription_id,omitempty"`
+	Status         ReportDeliveryStatus `json:"status,omitempty"`
+	Page           int                  `json:"page"`
+	PageSize       int                  `json:"page_size"`
+}
+
+// ============================================
+// Rendered Report Table
+// ============================================
+
+// ReportTable is the format independent result of running a report. It is
+// rendered to PDF or CSV before being sent.
+type ReportTable struct {
+	Title   string         `json:"title"`
+	Meta    [][2]string    `json:"meta,omitempty"`
+	Columns []ReportColumn `json:"columns"`
+	Rows    [][]string     `json:"rows"`
+	Totals  [][2]string    `json:"totals,omitempty"`
+}
+
+type ReportColumn struct {
+	Title   string  `json:"title"`
+	Width   float64 `json:"width"` // Fraction of the page width
+	Numeric bool    `json:"numeric,omitempty"`
+}
+
+// ============================================
+// Validation
+// ============================================
+
+var timeOfDayRX = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$`)
+
+var emailRX = regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
+
+func ValidateReportSubscription(v *Validator, req *CreateReportSubscriptionRequest) {
+	v.Check(strings.TrimSpace(req.Name) != "", "name", "Name is required")
+	v.Check(req.ReportType == ReportTypeARAging || req.ReportType == ReportTypeExpiringInventory ||
+		req.ReportType == ReportTypeDailySales, "report_type", "Report type must be AR_AGING, EXPIRING_INVENTORY or DAILY_SALES_SUMMARY")
+	if req.Format == "" {
+		req.Format = ReportFormatPDF
+	}
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+	validateReportSchedule(v, req.Format, req.Frequency, req.TimeOfDay, req.DayOfWeek, req.DayOfMonth, req.Timezone)
+	validateReportRecipients(v, req.Recipients)
+	validateReportParameters(v, &req.Parameters)
+}
+
+func ValidateReportSubscriptionUpdate(v *Validator, req *UpdateReportSubscriptionRequest, current *ReportSubscription) {
+	if req.Name != nil {
+		v.Check(strings.TrimSpace(*req.Name) != "", "name", "Name cannot be empty")
+	}
+
+	format, frequency, timeOfDay, timezone := current.Format, current.Frequency, current.TimeOfDay, current.Timezone
+	dayOfWeek, dayOfMonth := current.DayOfWeek, current.DayOfMonth
+	if req.Format != nil {
+		format = *req.Format
+	}
+	if req.Frequency != nil {
+		frequency = *req.Frequency
+	}
+	if req.TimeOfDay != nil {
+		timeOfDay = *req.TimeOfDay
+	}
+	if req.Timezone != nil {
+		timezone = *req.Timezone
+	}
+	if req.DayOfWeek != nil {
+		dayOfWeek = req.DayOfWeek
+	}
+	if req.DayOfMonth != nil {
+		dayOfMonth = req.DayOfMonth
+	}
+	validateReportSchedule(v, format, frequency, timeOfDay, dayOfWeek, dayOfMonth, timezone)
+
+	if req.Recipients != nil {
+		validateReportRecipients(v, req.Recipients)
+	}
+	if req.Parameters != nil {
+		validateReportParameters(v, req.Parameters)
+	}
+}
+
+func validateReportSchedule(v *Validator, format ReportFormat, frequency ReportFrequency, timeOfDay string, dayOfWeek, dayOfMonth *int, timezone string) {
+	v.Check(format == ReportFormatPDF || format == ReportFormatCSV, "format", "Format must be PDF or CSV")
+	v.Check(timeOfDayRX.MatchString(timeOfDay), "time_of_day", "Time of day must be HH:MM in 24 hour time")
+	_, err := time.LoadLocation(timezone)
+	v.Check(err == nil, "timezone", "Timezone must be an IANA name such as Asia/Vientiane")
+
+	switch frequency {
+	case ReportFrequencyDaily:
+	case ReportFrequencyWeekly:
+		v.Check(dayOfWeek != nil && *dayOfWeek >= 0 && *dayOfWeek <= 6, "day_of_week", "Day of week (0 = Sunday to 6 = Saturday) is required for weekly reports")
+	case ReportFrequencyMonthly:
+		v.Check(dayOfMonth != nil && *dayOfMonth >= 1 && *dayOfMonth <= 28, "day_of_month", "Day of month (1 to 28) is required for monthly reports")
+	default:
+		v.AddError("frequency", "Frequency must be DAILY, WEEKLY or MONTHLY")
+	}
+}
+
+func validateReportRecipients(v *Validator, recipients []string) {
+	v.Check(len(recipients) > 0, "recipients", "At least one recipient is required")
+	v.Check(len(recipients) <= 50, "recipients", "At most 50 recipients are allowed")
+	for _, r := range recipients {
+		if !emailRX.MatchString(strings.TrimSpace(r)) {
+			v.AddError("recipients", "Invalid email address: "+r)
+			break
+		}
+	}
+}
+
+func validateReportParameters(v *Validator, p *ReportParameters) {
+	v.Check(p.DaysToExpiry >= 0 && p.DaysToExpiry <= 365, "parameters.days_to_expiry", "Days to expiry must be between 0 and 365")
+	if p.DaysBack != nil {
+		v.Check(*p.DaysBack >= 0 && *p.DaysBack <= 31, "parameters.days_back", "Days back must be between 0 and 31")
+	}
+	v.Check(p.MinBalance >= 0, "parameters.min_balance", "Minimum balance cannot be negative")
+}
+
+// ParseReportParameters decodes stored JSONB parameters, treating NULL as empty.
+func ParseReportParameters(raw []byte) (ReportParameters, error) {
+	var p ReportParameters
+	if len(raw) == 0 {
+		return p, nil
+	}
+	err := json.Unmarshal(raw, &p)
+	return p, err
+}
@@ -0,0 +1,261 @@
+package models
+
+import "time"
+
+// ============================================
+// Purchase Requisition Models
+// ============================================
+
+type RequisitionStatus string
+
+const (
+	RequisitionDraft      RequisitionStatus = "DRAFT"
+	RequisitionSubmitted  RequisitionStatus = "SUBMITTED"
+	RequisitionChecked    RequisitionStatus = "CHECKED"
+	RequisitionAuthorized RequisitionStatus = "AUTHORIZED"
+	RequisitionConverted  RequisitionStatus = "CONVERTED" // Purchase order raised
+	RequisitionCancelled  RequisitionStatus = "CANCELLED"
+)
+
+type Priority string
+
+const (
+	PriorityLow      Priority = "LOW"
+	PriorityNormal   Priority = "NORMAL"
+	PriorityHigh     Priority = "HIGH"
+	PriorityUrgent   Priority = "URGENT"
+	PriorityCritical Priority = "CRITICAL"
+)
+
+// PurchaseRequisition asks purchasing to buy goods. It is checked, then
+// authorized, then converted into a purchase order. A rejected requisition
+// goes back to DRAFT with the reason so the requester can revise it.
+type PurchaseRequisition struct {
+	ID                int               `json:"id"`
+	RequisitionNumber string            `json:"requisition_number"`
+	RequesterID       *int              `json:"requester_id,omitempty"`
+	DepartmentID      *int              `json:"department_id,omitempty"`
+	SupplierID        *int              `json:"supplier_id,omitempty"`
+	WarehouseID       *int              `json:"warehouse_id,omitempty"`
+	Reason            string            `json:"reason,omitempty"`
+	Remark            string            `json:"remark,omitempty"`
+	Priority          Priority          `json:"priority"`
+	DocumentDate      CustomDate        `json:"document_date"`
+	RequiredDate      CustomDate        `json:"required_date"`
+	TotalAmount       float64           `json:"total_amount"`
+	Status            RequisitionStatus `json:"status"`
+	CheckedBy         *int              `json:"checked_by,omitempty"`
+	CheckedDate       CustomDate        `json:"checked_date"`
+	AuthorizedBy      *int              `json:"authorized_by,omitempty"`
+	AuthorizedDate    CustomDate        `json:"authorized_date"`
+	RejectedBy        *int              `json:"rejected_by,omitempty"`
+	RejectedDate      CustomDate        `json:"rejected_date"`
+	RejectionReason   string            `json:"rejection_reason,omitempty"`
+	ConvertedPOID     *int              `json:"converted_po_id,omitempty"`
+	CreatedBy         *int              `json:"created_by,omitempty"`
+	CreatedAt         CustomDateTime    `json:"created_at"`
+	UpdatedAt         CustomDateTime    `json:"updated_at"`
+}
+
+// RequisitionLine is tied to a sales order line when it is a special
+// order; the goods received for it are allocated to that order.
+type RequisitionLine struct {
+	ID                int      `json:"id"`
+	RequisitionID     int      `json:"requisition_id"`
+	LineNumber        int      `json:"line_number"`
+	ProductID         int      `json:"product_id"`
+	ProductSKU        string   `json:"product_sku"`
+	ProductName       string   `json:"product_name"`
+	Description       string   `json:"description,omitempty"`
+	QuantityRequested float64  `json:"quantity_requested"`
+	QuantityApproved  *float64 `json:"quantity_approved,omitempty"`
+	UnitOfMeasure     string   `json:"unit_of_measure,omitempty"`
+	UnitPrice         float64  `json:"unit_price"`
+	Amount            float64  `json:"amount"`
+	PreferredVendorID *int     `json:"preferred_vendor_id,omitempty"`
+	OrderLineID       *int     `json:"order_line_id,omitempty"`
+	OrderID           *int     `json:"order_id,omitempty"`
+	OrderNumber       string   `json:"order_number,omitempty"`
+	Notes             string   `json:"notes,omitempty"`
+}
+
+type RequisitionWithDetails struct {
+	PurchaseRequisition
+	RequesterName     string            `json:"requester_name,omitempty"`
+	SupplierName      string            `json:"supplier_name,omitempty"`
+	WarehouseName     string            `json:"warehouse_name,omitempty"`
+	ConvertedPONumber string            `json:"converted_po_number,omitempty"`
+	Lines             []RequisitionLine `json:"lines,omitempty"`
+}
+
+// SpecialOrderLine is a sales order line put on backorder to wait for a
+// special order.
+type SpecialOrderLine struct {
+	OrderID       int        `json:"order_id"`
+	OrderNumber   string     `json:"order_number"`
+	OrderLineID   int        `json:"order_line_id"`
+	ProductID     int        `json:"product_id"`
+	WarehouseID   int        `json:"warehouse_id"`
+	Quantity      float64    `json:"quantity"` // Backordered for the special order
+	UnitOfMeasure string     `json:"unit_of_measure,omitempty"`
+	RequiredDate  *time.Time `json:"-"`
+}
+
+type SpecialOrderResult struct {
+	RequisitionID       int     `json:"requisition_id"`
+	RequisitionNumber   string  `json:"requisition_number"`
+	QuantityRequested   float64 `json:"quantity_requested"`
+	QuantityBackordered float64 `json:"quantity_backordered"`
+}
+
+// ============================================
+// Request DTOs
+// ============================================
+
+type CreateRequisitionRequest struct {
+	DepartmentID *int                     `json:"department_id,omitempty"`
+	SupplierID   *int                     `json:"supplier_id,omitempty"`
+	WarehouseID  *int                     `json:"warehouse_id,omitempty"`
+	RequiredDate string                   `json:"required_date,omitempty"`
+	Priority     Priority                 `json:"priority,omitempty"` // Defaults to NORMAL
+	Reason       string                   `json:"reason,omitempty"`
+	Remark       string                   `json:"remark,omitempty"`
+	Lines        []RequisitionLineRequest `json:"lines"`
+}
+
+// UpdateRequisitionRequest changes a draft. Lines, when given, replace the
+// requisition's lines.
+type UpdateRequisitionRequest struct {
+	DepartmentID *int                     `json:"department_id,omitempty"`
+	SupplierID   *int                     `json:"supplier_id,omitempty"`
+	WarehouseID  *int                     `json:"warehouse_id,omitempty"`
+	RequiredDate *string                  `json:"required_date,omitempty"`
+	Priority     *Priority                `json:"priority,omitempty"`
+	Reason       *string                  `json:"reason,omitempty"`
+	Remark       *string                  `json:"remark,omitempty"`
+	Lines        []RequisitionLineRequest `json:"lines,omitempty"`
+}
+
+type RequisitionLineRequest struct {
+	ProductID         int     `json:"product_id"`
+	Description       string  `json:"description,omitempty"`
+	Quantity          float64 `json:"quantity"`
+	UnitOfMeasure     string  `json:"unit_of_measure,omitempty"`
+	UnitPrice         float64 `json:"unit_price,omitempty"`
+	PreferredVendorID *int    `json:"preferred_vendor_id,omitempty"`
+	Notes             string  `json:"notes,omitempty"`
+}
+
+// AuthorizeRequisitionRequest may approve less than was requested on
+// some lines; the others are approved in full.
+type AuthorizeRequisitionRequest struct {
+	Lines []RequisitionApprovalLine `json:"lines,omitempty"`
+}
+
+type RequisitionApprovalLine struct {
+	LineID           int     `json:"line_id"`
+	QuantityApproved float64 `json:"quantity_approved"`
+}
+
+type RejectRequisitionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ConvertRequisitionRequest raises the purchase order. Vendor and
+// warehouse default to the requisition's.
+type ConvertRequisitionRequest struct {
+	VendorID     *int   `json:"vendor_id,omitempty"`
+	WarehouseID  *int   `json:"warehouse_id,omitempty"`
+	ExpectedDate string `json:"expected_date,omitempty"`
+	BuyerID      *int   `json:"buyer_id,omitempty"`
+}
+
+// SpecialOrderRequest asks purchasing for an item the warehouse does not
+// stock. Quantity defaults to what the order line still needs; vendor and
+// cost default to the product's primary vendor.
+type SpecialOrderRequest struct {
+	Quantity     *float64 `json:"quantity,omitempty"`
+	VendorID     *int     `json:"vendor_id,omitempty"`
+	UnitCost     *float64 `json:"unit_cost,omitempty"`
+	RequiredDate string   `json:"required_date,omitempty"` // Defaults to the order's ship date
+	Priority     Priority `json:"priority,omitempty"`
+	Notes        string   `json:"notes,omitempty"`
+}
+
+type RequisitionFilters struct {
+	Status      *RequisitionStatus
+	RequesterID *int
+	OrderID     *int
+}
+
+// ============================================
+// Validation
+// ============================================
+
+func ValidateRequisition(v *Validator, req *CreateRequisitionRequest) {
+	validateRequisitionHeader(v, req.RequiredDate, req.Priority)
+	v.Check(len(req.Lines) > 0, "lines", "At least one line is required")
+	validateRequisitionLines(v, req.Lines)
+}
+
+func ValidateUpdateRequisition(v *Validator, req *UpdateRequisitionRequest) {
+	var requiredDate string
+	if req.RequiredDate != nil {
+		requiredDate = *req.RequiredDate
+	}
+	var priority Priority
+	if req.Priority != nil {
+		priority = *req.Priority
+		v.Check(priority != "", "priority", "Priority must be LOW, NORMAL, HIGH, URGENT or CRITICAL")
+	}
+	validateRequisitionHeader(v, requiredDate, priority)
+	validateRequisitionLines(v, req.Lines)
+}
+
+func validateRequisitionHeader(v *Validator, requiredDate string, priority Priority) {
+	if requiredDate != "" {
+		_, err := time.Parse("2006-01-02", requiredDate)
+		v.Check(err == nil, "required_date", "Required date must be YYYY-MM-DD")
+	}
+	v.Check(priority == "" || ValidPriority(priority), "priority", "Priority must be LOW, NORMAL, HIGH, URGENT or CRITICAL")
+}
+
+func validateRequisitionLines(v *Validator, lines []RequisitionLineRequest) {
+	for _, line := range lines {
+		v.Check(line.ProductID > 0, "lines", "Product ID is required for all lines")
+		v.Check(line.Quantity > 0, "lines", "Quantity must be positive for all lines")
+		v.Check(line.UnitPrice >= 0, "lines", "Unit price cannot be negative")
+	}
+}
+
+func ValidateAuthorizeRequisition(v *Validator, req *AuthorizeRequisitionRequest) {
+	for _, line := range req.Lines {
+		v.Check(line.LineID > 0, "lines", "Line is required for all lines")
+		v.Check(line.QuantityApproved >= 0, "lines", "Quantity approved cannot be negative")
+	}
+}
+
+func ValidateRejectRequisition(v *Validator, req *RejectRequisitionRequest) {
+	v.Check(req.Reason != "", "reason", "Reason is required")
+}
+
+func ValidateConvertRequisition(v *Validator, req *ConvertRequisitionRequest) {
+	if req.ExpectedDate != "" {
+		_, err := time.Parse("2006-01-02", req.ExpectedDate)
+		v.Check(err == nil, "expected_date", "Expected date must be YYYY-MM-DD")
+	}
+}
+
+func ValidateSpecialOrder(v *Validator, req *SpecialOrderRequest) {
+	v.Check(req.Quantity == nil || *req.Quantity > 0, "quantity", "Quantity must be positive")
+	v.Check(req.UnitCost == nil || *req.UnitCost >= 0, "unit_cost", "Unit cost cannot be negative")
+	validateRequisitionHeader(v, req.RequiredDate, req.Priority)
+}
+
+func ValidPriority(p Priority) bool {
+	switch p {
+	case PriorityLow, PriorityNormal, PriorityHigh, PriorityUrgent, PriorityCritical:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package models
+
+import "time"
+
+// ============================================
+// Reservation Enums
+// ============================================
+
+type ReservedForType string
+
+const (
+	ReservedForCustomer      ReservedForType = "CUSTOMER"
+	ReservedForCustomerGroup ReservedForType = "CUSTOMER_GROUP" // Every customer in the group
+	ReservedForSalesRep      ReservedForType = "SALES_REP"      // Orders taken by or for the rep's customers
+	ReservedForSalesOrder    ReservedForType = "SALES_ORDER"
+)
+
+// ReservationStatus is worked out from the reservation rather than stored.
+type ReservationStatus string
+
+const (
+	ReservationActive   ReservationStatus = "ACTIVE"
+	ReservationConsumed ReservationStatus = "CONSUMED" // Used up by the party's orders
+	ReservationExpired  ReservationStatus = "EXPIRED"
+	ReservationReleased ReservationStatus = "RELEASED" // Given up before it expired
+)
+
+// ============================================
+// Reservation Models
+// ============================================
+
+// ProductReservation sets stock of a product aside in a warehouse for one
+// party until the expiry date. Orders for the party use it up first; no
+// one else may allocate it while it is active.
+type ProductReservation struct {
+	ID                int               `json:"id"`
+	ProductID         int               `json:"product_id"`
+	ProductSKU        string            `json:"product_sku"`
+	ProductName       string            `json:"product_name"`
+	WarehouseID       int               `json:"warehouse_id"`
+	WarehouseName     string            `json:"warehouse_name"`
+	ReservedForType   ReservedForType   `json:"reserved_for_type"`
+	ReservedForID     int               `json:"reserved_for_id"`
+	ReservedForName   string            `json:"reserved_for_name"`
+	Quantity          float64           `json:"quantity"`
+	QuantityConsumed  float64           `json:"quantity_consumed"`
+	QuantityRemaining float64           `json:"quantity_remaining"`
+	ExpiryDate        CustomDate        `json:"expiry_date"`
+	Status            ReservationStatus `json:"status"`
+	Notes             string            `json:"notes,omitempty"`
+	CreatedBy         *int              `json:"created_by,omitempty"`
+	CreatedAt         time.Time         `json:"created_at"`
+	ReleasedBy        *int              `json:"released_by,omitempty"`
+	ReleasedAt        *time.Time        `json:"released_at,omitempty"`
+}
+
+// ExpiringReservation is an active reservation running out before its
+// party has used all of it.
+type ExpiringReservation struct {
+	ProductReservation
+	DaysToExpiry int `json:"days_to_expiry"`
+}
+
+// StockAvailability is what a party may allocate of a product in a
+// warehouse: the available stock less what is reserved for others.
+type StockAvailability struct {
+	ProductID         int     `json:"product_id"`
+	WarehouseID       int     `json:"warehouse_id"`
+	CustomerID        *int    `json:"customer_id,omitempty"`
+	SalesRepID        *int    `json:"sales_rep_id,omitempty"`
+	OnHand            float64 `json:"on_hand"`
+	Allocated         float64 `json:"allocated"`
+	Available         float64 `json:"available"`
+	ReservedForParty  float64 `json:"reserved_for_party"`
+	ReservedForOthers float64 `json:"reserved_for_others"`
+	AvailableToParty  float64 `json:"available_to_party"`
+}
+
+// ============================================
+// Request DTOs
+// ============================================
+
+type CreateReservationRequest struct {
+	ProductID       int             `json:"product_id"`
+	WarehouseID     int             `json:"warehouse_id"`
+	ReservedForType ReservedForType `json:"reserved_for_type"`
+	ReservedForID   int             `json:"reserved_for_id"`
+	Quantity        float64         `json:"quantity"`
+	ExpiryDate      string          `json:"expiry_date"`
+	Notes           string          `json:"notes,omitempty"`
+}
+
+type UpdateReservationRequest struct {
+	Quantity   *float64 `json:"quantity,omitempty"`
+	ExpiryDate *string  `json:"expiry_date,omitempty"`
+	Notes      *string  `json:"notes,omitempty"`
+}
+
+type ReservationFilters struct {
+	ProductID       *int
+	WarehouseID     *int
+	ReservedForType ReservedForType
+	ReservedForID   *int
+	Status          ReservationStatus
+	Page            int
+	PageSize        int
+}
+
+// AvailabilityRequest asks what a customer, or a sales rep's customers,
+// may order. Without either every reservation counts as someone else's.
+type AvailabilityRequest struct {
+	ProductID   int
+	WarehouseID int
+	CustomerID  *int
+	SalesRepID  *int
+}
+
+// ============================================
+// Validation
+// ============================================
+
+func ValidateCreateReservation(v *Validator, req *CreateReservationRequest) {
+	v.Check(req.ProductID > 0, "product_id", "Product ID is required")
+	v.Check(req.WarehouseID > 0, "warehouse_id", "Warehouse ID is required")
+	switch req.ReservedForType {
+	case ReservedForCustomer, ReservedForCustomerGroup, ReservedForSalesRep:
+	default:
+		v.AddError("reserved_for_type", "Reserved for type must be CUSTOMER, CUSTOMER_GROUP or SALES_REP")
+	}
+	v.Check(req.ReservedForID > 0, "reserved_for_id", "Reserved for ID is required")
+	v.Check(req.Quantity > 0, "quantity", "Quantity must be positive")
+	validateReservationExpiry(v, req.ExpiryDate)
+	v.Check(len(req.Notes) <= 1000, "notes", "Notes must not be more than 1000 characters")
+}
+
+func ValidateUpdateReservation(v *Validator, req *UpdateReservationRequest) {
+	v.Check(req.Quantity == nil || *req.Quantity > 0, "quantity", "Quantity must be positive")
+	if req.ExpiryDate != nil {
+		validateReservationExpiry(v, *req.ExpiryDate)
+	}
+	v.Check(req.Notes == nil || len(*req.Notes) <= 1000, "notes", "Notes must not be more than 1000 characters")
+}
+
+func validateReservationExpiry(v *Validator, expiryDate string) {
+	expiry, err := time.Parse("2006-01-02", expiryDate)
+	v.Check(err == nil, "expiry_date", "Expiry date must be YYYY-MM-DD")
+	v.Check(err != nil || !expiry.Before(time.Now().Truncate(24*time.Hour)), "expiry_date", "Expiry date cannot be in the past")
+}
+
+func ValidReservationStatus(s ReservationStatus) bool {
+	switch s {
+	case ReservationActive, ReservationConsumed, ReservationExpired, ReservationReleased:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,238 @@
+package models
+
+// ============================================
+// Customer Return (RMA) Models
+// ============================================
+
+type RMAStatus string
+
+const (
+	RMAStatusRequested RMAStatus = "REQUESTED"
+	RMAStatusReceived  RMAStatus = "RECEIVED"
+	RMAStatusApproved  RMAStatus = "APPROVED" // Credit memo issued
+	RMAStatusClosed    RMAStatus = "CLOSED"   // Approved and every unit dispositioned
+	RMAStatusRejected  RMAStatus = "REJECTED"
+	RMAStatusCancelled RMAStatus = "CANCELLED"
+)
+
+type ReturnReason string
+
+const (
+	ReturnReasonSpoiled       ReturnReason = "SPOILED"
+	ReturnReasonDamaged       ReturnReason = "DAMAGED"
+	ReturnReasonWrongProduct  ReturnReason = "WRONG_PRODUCT"
+	ReturnReasonWrongQuantity ReturnReason = "WRONG_QUANTITY"
+	ReturnReasonQuality       ReturnReason = "QUALITY"
+	ReturnReasonShortDated    ReturnReason = "SHORT_DATED"
+	ReturnReasonCustomerError ReturnReason = "CUSTOMER_ERROR"
+	ReturnReasonOther         ReturnReason = "OTHER"
+)
+
+// ReturnDisposition is what QA decides to do with returned goods.
+// Quarantined goods stay in the QUARANTINE location until released to
+// stock or destroyed.
+type ReturnDisposition string
+
+const (
+	DispositionRestock    ReturnDisposition = "RESTOCK"
+	DispositionQuarantine ReturnDisposition = "QUARANTINE"
+	DispositionDestroy    ReturnDisposition = "DESTROY"
+)
+
+type RMA struct {
+	ID              int            `json:"id"`
+	RMANumber       string         `json:"rma_number"`
+	CustomerID      int            `json:"customer_id"`
+	OrderID         int            `json:"order_id"`
+	InvoiceID       *int           `json:"invoice_id,omitempty"`
+	WarehouseID     int            `json:"warehouse_id"`
+	Status          RMAStatus      `json:"status"`
+	ReasonCode      ReturnReason   `json:"reason_code"`
+	Notes           string         `json:"notes,omitempty"`
+	ReceivedBy      *int           `json:"received_by,omitempty"`
+	ReceivedAt      CustomDateTime `json:"received_at"`
+	ApprovedBy      *int           `json:"approved_by,omitempty"`
+	ApprovedAt      CustomDateTime `json:"approved_at"`
+	CreditInvoiceID *int           `json:"credit_invoice_id,omitempty"`
+	GLJournalID     *int           `json:"gl_journal_id,omitempty"`
+	RejectReason    string         `json:"reject_reason,omitempty"`
+	CreatedBy       *int           `json:"created_by,omitempty"`
+	CreatedAt       CustomDateTime `json:"created_at"`
+	UpdatedAt       CustomDateTime `json:"updated_at"`
+}
+
+// RMALine credits the received quantity at the net price the customer
+// paid; catch weight lines credit the received weight instead.
+type RMALine struct {
+	ID                    int              `json:"id"`
+	RMAID                 int              `json:"rma_id"`
+	LineNumber            int              `json:"line_number"`
+	OrderLineID           int              `json:"order_line_id"`
+	ProductID             int              `json:"product_id"`
+	ProductSKU            string           `json:"product_sku"`
+	ProductName           string           `json:"product_name"`
+	QuantityAuthorized    float64          `json:"quantity_authorized"`
+	QuantityReceived      float64          `json:"quantity_received"`
+	QuantityDispositioned float64          `json:"quantity_dispositioned"`
+	CatchWeightReceived   *float64         `json:"catch_weight_received,omitempty"`
+	LotNumber             string           `json:"lot_number,omitempty"`
+	UnitPrice             float64          `json:"unit_price"`
+	ReasonCode            ReturnReason     `json:"reason_code"`
+	Notes                 string           `json:"notes,omitempty"`
+	CreditAmount          float64          `json:"credit_amount"`
+	Dispositions          []RMADisposition `json:"dispositions"`
+}
+
+// RMADisposition is a QA decision on some of a line's received quantity.
+// Quarantine dispositions are released later to RESTOCK or DESTROY.
+type RMADisposition struct {
+	ID           int                `json:"id"`
+	RMALineID    int                `json:"rma_line_id"`
+	Disposition  ReturnDisposition  `json:"disposition"`
+	Quantity     float64            `json:"quantity"`
+	LocationCode string             `json:"location_code,omitempty"`
+	ReleasedTo   *ReturnDisposition `json:"released_to,omitempty"`
+	ReleasedAt   CustomDateTime     `json:"released_at"`
+	ReleasedBy   *int               `json:"released_by,omitempty"`
+	Notes        string             `json:"notes,omitempty"`
+	CreatedBy    *int               `json:"created_by,omitempty"`
+	CreatedAt    CustomDateTime     `json:"created_at"`
+}
+
+type RMAWithDetails struct {
+	RMA
+	CustomerName  string    `json:"customer_name"`
+	OrderNumber   string    `json:"order_number"`
+	InvoiceNumber string    `json:"invoice_number,omitempty"`
+	WarehouseName string    `json:"warehouse_name"`
+	CreditAmount  float64   `json:"credit_amount"`
+	Lines         []RMALine `json:"lines,omitempty"`
+}
+
+// ============================================
+// Request DTOs
+// ============================================
+
+// CreateRMARequest authorizes a return against a sales order, or against
+// an invoice, in which case the invoice's order is used.
+type CreateRMARequest struct {
+	OrderID     *int                   `json:"order_id,omitempty"`
+	InvoiceID   *int                   `json:"invoice_id,omitempty"`
+	WarehouseID *int                   `json:"warehouse_id,omitempty"` // Defaults to the order's warehouse
+	ReasonCode  ReturnReason           `json:"reason_code"`
+	Notes       string                 `json:"notes,omitempty"`
+	Lines       []CreateRMALineRequest `json:"lines"`
+}
+
+type CreateRMALineRequest struct {
+	OrderLineID int          `json:"order_line_id"`
+	Quantity    float64      `json:"quantity"`
+	ReasonCode  ReturnReason `json:"reason_code,omitempty"` // Defaults to the RMA's reason
+	Notes       string       `json:"notes,omitempty"`
+}
+
+type ReceiveRMARequest struct {
+	Lines []ReceiveRMALineRequest `json:"lines"`
+	Notes string                  `json:"notes,omitempty"`
+}
+
+// ReceiveRMALineRequest records what came back for a line. Lines left out
+// of the receipt are taken as not returned.
+type ReceiveRMALineRequest struct {
+	LineID      int      `json:"line_id"`
+	Quantity    float64  `json:"quantity"`
+	LotNumber   string   `json:"lot_number,omitempty"` // Defaults to the shipped lot
+	CatchWeight *float64 `json:"catch_weight,omitempty"`
+	PieceCount  int      `json:"piece_count,omitempty"`
+}
+
+type RejectRMARequest struct {
+	Reason string `json:"reason"`
+}
+
+type DispositionRMARequest struct {
+	Lines []RMADispositionLineRequest `json:"lines"`
+}
+
+type RMADispositionLineRequest struct {
+	LineID       int               `json:"line_id"`
+	Disposition  ReturnDisposition `json:"disposition"`
+	Quantity     float64           `json:"quantity"`
+	LocationCode string            `json:"location_code,omitempty"` // Restock location
+	Notes        string            `json:"notes,omitempty"`
+}
+
+// ReleaseQuarantineRequest resolves a quarantine disposition.
+type ReleaseQuarantineRequest struct {
+	Disposition  ReturnDisposition `json:"disposition"`
+	LocationCode string            `json:"location_code,omitempty"`
+	Notes        string            `json:"notes,omitempty"`
+}
+
+type RMAFilters struct {
+	CustomerID *int
+	OrderID    *int
+	Status     *RMAStatus
+}
+
+// ============================================
+// Validation
+// ============================================
+
+func ValidateRMA(v *Validator, req *CreateRMARequest) {
+	v.Check(req.OrderID != nil || req.InvoiceID != nil, "order_id", "A sales order or invoice is required")
+	v.Check(ValidReturnReason(req.ReasonCode), "reason_code", "Reason code is not valid")
+	v.Check(len(req.Lines) > 0, "lines", "At least one line is required")
+	seen := make(map[int]bool)
+	for _, line := range req.Lines {
+		v.Check(line.OrderLineID > 0, "lines", "Order line is required for all lines")
+		v.Check(!seen[line.OrderLineID], "lines", "Each order line can only be returned once per RMA")
+		v.Check(line.Quantity > 0, "lines", "Quantity must be positive for all lines")
+		v.Check(line.ReasonCode == "" || ValidReturnReason(line.ReasonCode), "lines", "Reason code is not valid")
+		seen[line.OrderLineID] = true
+	}
+}
+
+func ValidateReceiveRMA(v *Validator, req *ReceiveRMARequest) {
+	v.Check(len(req.Lines) > 0, "lines", "At least one line is required")
+	for _, line := range req.Lines {
+		v.Check(line.LineID > 0, "lines", "Line is required for all lines")
+		v.Check(line.Quantity >= 0, "lines", "Quantity cannot be negative")
+		v.Check(line.CatchWeight == nil || *line.CatchWeight > 0, "lines", "Catch weight must be positive")
+		v.Check(line.PieceCount >= 0, "lines", "Piece count cannot be negative")
+	}
+}
+
+func ValidateRejectRMA(v *Validator, req *RejectRMARequest) {
+	v.Check(req.Reason != "", "reason", "Reason is required")
+}
+
+func ValidateDispositionRMA(v *Validator, req *DispositionRMARequest) {
+	v.Check(len(req.Lines) > 0, "lines", "At least one line is required")
+	for _, line := range req.Lines {
+		v.Check(line.LineID > 0, "lines", "Line is required for all lines")
+		v.Check(ValidReturnDisposition(line.Disposition), "lines", "Disposition must be RESTOCK, QUARANTINE or DESTROY")
+		v.Check(line.Quantity > 0, "lines", "Quantity must be positive for all lines")
+	}
+}
+
+func ValidateReleaseQuarantine(v *Validator, req *ReleaseQuarantineRequest) {
+	v.Check(req.Disposition == DispositionRestock || req.Disposition == DispositionDestroy, "disposition", "Quarantined goods can only be restocked or destroyed")
+}
+
+func ValidReturnReason(r ReturnReason) bool {
+	switch r {
+	case ReturnReasonSpoiled, ReturnReasonDamaged, ReturnReasonWrongProduct, ReturnReasonWrongQuantity,
+		ReturnReasonQuality, ReturnReasonShortDated, ReturnReasonCustomerError, ReturnReasonOther:
+		return true
+	}
+	return false
+}
+
+func ValidReturnDisposition(d ReturnDisposition) bool {
+	switch d {
+	case DispositionRestock, DispositionQuarantine, DispositionDestroy:
+		return true
+	}
+	return false
+}
@@ -1,5 +1,11 @@
 package models
 
+import (
+	"time"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/query"
+)
+
 // ============================================
 // Sales Order Enums
 // ============================================
@@ -26,6 +32,22 @@ const (
 	OrderStatusDelivered OrderStatus = "DELIVERED"
 	OrderStatusInvoiced  OrderStatus = "INVOICED"
 	OrderStatusCancelled OrderStatus = "CANCELLED"
+	OrderStatusConverted OrderStatus = "CONVERTED" // Quote turned into an order
+	OrderStatusExpired   OrderStatus = "EXPIRED"   // Quote past its expiry date
+)
+
+// OrderAction is a lifecycle step applied to a sales order. Which actions
+// an order accepts depends on its type and status.
+type OrderAction string
+
+const (
+	OrderActionConfirm OrderAction = "confirm"
+	OrderActionCancel  OrderAction = "cancel"
+	OrderActionShip    OrderAction = "ship"
+	OrderActionConvert OrderAction = "convert"
+	OrderActionRelease OrderAction = "release"
+	OrderActionCredit  OrderAction = "credit"
+	OrderActionDeliver OrderAction = "deliver" // Only through a proof of delivery
 )
 
 // ============================================
@@ -47,6 +69,7 @@ type SalesOrder struct {
 	Subtotal          float64     `json:"subtotal"`
 	TaxAmount         float64     `json:"tax_amount"`
 	FreightAmount     float64     `json:"freight_amount"`
+	FreightOverridden bool        `json:"freight_overridden"` // Set by hand rather than by the freight rules
 	DiscountAmount    float64     `json:"discount_amount"`
 	TotalAmount       float64     `json:"total_amount"`
 	Notes             string      `json:"notes,omitempty"`
@@ -55,24 +78,51 @@ type SalesOrder struct {
 	CreatedBy         int         `json:"created_by"`
 	CreatedAt         CustomDate  `json:"created_at"`
 	UpdatedAt         CustomDate  `json:"updated_at"`
+
+	// Type-specific lifecycle fields
+	QuoteExpiryDate   CustomDate     `json:"quote_expiry_date,omitempty"`
+	SourceQuoteID     *int           `json:"source_quote_id,omitempty"`
+	HoldReason        string         `json:"hold_reason,omitempty"`
+	HoldReleasedAt    CustomDateTime `json:"hold_released_at,omitempty"`
+	HoldReleasedBy    *int           `json:"hold_released_by,omitempty"`
+	HoldReleaseReason string         `json:"hold_release_reason,omitempty"`
+
+	// Credit hold, placed at entry or confirmation and released by AR
+	CreditHold          bool           `json:"credit_hold"`
+	CreditHoldReason    string         `json:"credit_hold_reason,omitempty"`
+	CreditHeldAt        CustomDateTime `json:"credit_held_at,omitempty"`
+	CreditReleasedAt    CustomDateTime `json:"credit_released_at,omitempty"`
+	CreditReleasedBy    *int           `json:"credit_released_by,omitempty"`
+	CreditReleaseReason string         `json:"credit_release_reason,omitempty"`
+
+	// Set on a backorder order split from another at shipment
+	BackorderOfID *int `json:"backorder_of_id,omitempty"`
 }
 
 type SalesOrderLine struct {
-	ID              int        `json:"id"`
-	OrderID         int        `json:"order_id"`
-	LineNumber      int        `json:"line_number"`
-	ProductID       int        `json:"product_id"`
-	Description     string     `json:"description,omitempty"`
-	QuantityOrdered float64    `json:"quantity_ordered"`
-	QuantityShipped float64    `json:"quantity_shipped"`
-	UnitOfMeasure   string     `json:"unit_of_measure"`
-	UnitPrice       float64    `json:"unit_price"`
-	DiscountPercent float64    `json:"discount_percent"`
-	LineTotal       float64    `json:"line_total"`
-	LotNumber       string     `json:"lot_number,omitempty"`
-	ExpiryDate      CustomDate `json:"expiry_date,omitempty"`
-	CatchWeight     float64    `json:"catch_weight,omitempty"`
-	Cost            float64    `json:"cost"`
+	ID                  int        `json:"id"`
+	OrderID             int        `json:"order_id"`
+	LineNumber          int        `json:"line_number"`
+	ProductID           int        `json:"product_id"`
+	Description         string     `json:"description,omitempty"`
+	QuantityOrdered     float64    `json:"quantity_ordered"`
+	QuantityShipped     float64    `json:"quantity_shipped"`
+	QuantityAllocated   float64    `json:"quantity_allocated"`
+	QuantityPicked      float64    `json:"quantity_picked"`      // Picked, not yet shipped
+	QuantityBackordered float64    `json:"quantity_backordered"` // Waiting for stock
+	QuantityInvoiced    float64    `json:"quantity_invoiced"`
+	UnitOfMeasure       string     `json:"unit_of_measure"`
+	UnitPrice           float64    `json:"unit_price"`
+	DiscountPercent     float64    `json:"discount_percent"`
+	LineTotal           float64    `json:"line_total"`
+	LotNumber           string     `json:"lot_number,omitempty"`
+	ExpiryDate          CustomDate `json:"expiry_date,omitempty"`
+	CatchWeight         float64    `json:"catch_weight,omitempty"`
+	Cost                float64    `json:"cost"`
+	Margin              float64    `json:"margin"`
+	MarginPercent       float64    `json:"margin_percent"`
+	CustomerItemCode    string     `json:"customer_item_code,omitempty"` // The customer's code for the product
+	CustomerUOM         string     `json:"customer_uom,omitempty"`       // The unit the customer ordered in
 }
 
 type SalesOrderWithDetails struct {
@@ -85,6 +135,14 @@ type SalesOrderWithDetails struct {
 	WarehouseName string           `json:"warehouse_name"`
 	SalesRepName  string           `json:"sales_rep_name,omitempty"`
 	RouteName     string           `json:"route_name,omitempty"`
+
+	// Actions the order's type and status allow next
+	AllowedActions []OrderAction `json:"allowed_actions,omitempty"`
+
+	// Margin over the lines' captured cost
+	TotalCost     float64 `json:"total_cost"`
+	Margin        float64 `json:"margin"`
+	MarginPercent float64 `json:"margin_percent"`
 }
 
 // ============================================
@@ -100,6 +158,8 @@ type CreateSalesOrderRequest struct {
 	RouteID           *int                          `json:"route_id,omitempty"`
 	Notes             string                        `json:"notes,omitempty"`
 	PONumber          string                        `json:"po_number,omitempty"`
+	QuoteExpiryDate   string                        `json:"quote_expiry_date,omitempty"` // Quotes only; defaults to 30 days out
+	HoldReason        string                        `json:"hold_reason,omitempty"`       // On-hold orders only
 	Lines             []CreateSalesOrderLineRequest `json:"lines"`
 }
 
@@ -111,6 +171,11 @@ type CreateSalesOrderLineRequest struct {
 	DiscountPercent float64 `json:"discount_percent,omitempty"`
 	LotNumber       string  `json:"lot_number,omitempty"`
 	Notes           string  `json:"notes,omitempty"`
+
+	// The customer's own item code, in place of or as well as the product;
+	// the unit of measure and quantity are then in the customer's unit
+	CustomerItemCode string `json:"customer_item_code,omitempty"`
+	CustomerUOM      string `json:"-"`
 }
 
 type UpdateSalesOrderRequest struct {
@@ -122,17 +187,59 @@ type UpdateSalesOrderRequest struct {
 	Status            *OrderStatus `json:"status,omitempty"`
 }
 
+// SalesOrderLineResult reports a line's cost and margin, and the order's
+// margin, after the line was added or changed.
+type SalesOrderLineResult struct {
+	LineID             int           `json:"line_id"`
+	UnitCost           float64       `json:"unit_cost"`
+	CostingMethod      CostingMethod `json:"costing_method"`
+	LineMargin         float64       `json:"line_margin"`
+	LineMarginPercent  float64       `json:"line_margin_percent"`
+	OrderMargin        float64       `json:"order_margin"`
+	OrderMarginPercent float64       `json:"order_margin_percent"`
+	IsBelowCost        bool          `json:"is_below_cost"`
+	Warnings           []string      `json:"warnings,omitempty"`
+}
+
+type ConvertQuoteRequest struct {
+	OrderType         OrderType `json:"order_type,omitempty"` // Defaults to STANDARD
+	RequestedShipDate string    `json:"requested_ship_date,omitempty"`
+	PONumber          string    `json:"po_number,omitempty"`
+	HoldReason        string    `json:"hold_reason,omitempty"`
+}
+
+type ReleaseHoldRequest struct {
+	Reason string `json:"reason"`
+}
+
+type ProcessCreditMemoRequest struct {
+	ReturnToStock bool   `json:"return_to_stock"` // Put the credited goods back into inventory
+	LocationCode  string `json:"location_code,omitempty"`
+}
+
+// CreditMemoResult reports what processing a credit memo created.
+type CreditMemoResult struct {
+	OrderID         int  `json:"order_id"`
+	ARCreditID      int  `json:"ar_credit_id"`
+	ReturnedToStock bool `json:"returned_to_stock"`
+	LinesReturned   int  `json:"lines_returned"`
+}
+
 type SalesOrderListFilters struct {
-	CustomerID  *int         `json:"customer_id,omitempty"`
-	Status      *OrderStatus `json:"status,omitempty"`
-	OrderType   *OrderType   `json:"order_type,omitempty"`
-	WarehouseID *int         `json:"warehouse_id,omitempty"`
-	RouteID     *int         `json:"route_id,omitempty"`
-	SalesRepID  *int         `json:"sales_rep_id,omitempty"`
-	DateFrom    string       `json:"date_from,omitempty"`
-	DateTo      string       `json:"date_to,omitempty"`
-	Page        int          `json:"page"`
-	PageSize    int          `json:"page_size"`
+	CustomerID  *int          `json:"customer_id,omitempty"`
+	Status      *OrderStatus  `json:"status,omitempty"`
+	OrderType   *OrderType    `json:"order_type,omitempty"`
+	WarehouseID *int          `json:"warehouse_id,omitempty"`
+	RouteID     *int          `json:"route_id,omitempty"`
+	SalesRepID  *int          `json:"sales_rep_id,omitempty"`
+	CreditHold  *bool         `json:"credit_hold,omitempty"`
+	DateFrom    string        `json:"date_from,omitempty"`
+	DateTo      string        `json:"date_to,omitempty"`
+	Page        int           `json:"page"`
+	PageSize    int           `json:"page_size"`
+	Query       *query.Params `json:"-"`
+
+	CustomerItemCode string `json:"customer_item_code,omitempty"` // Orders with a line for this customer code
 }
 
 // ============================================
@@ -154,19 +261,224 @@ type OrderGuideEntry struct {
 	IsPushItem      bool    `json:"is_push_item"`
 }
 
+// OrderGuideLine is an order guide entry proposed as an order line.
+type OrderGuideLine struct {
+	ProductID       int        `json:"product_id"`
+	ProductSKU      string     `json:"product_sku"`
+	ProductName     string     `json:"product_name"`
+	UnitOfMeasure   string     `json:"unit_of_measure"`
+	IsPushItem      bool       `json:"is_push_item"`
+	OnGuide         bool       `json:"on_guide"` // False for products the client added
+	AvgWeeklyQty    float64    `json:"avg_weekly_qty"`
+	LastOrderedQty  float64    `json:"last_ordered_qty"`
+	SuggestedQty    float64    `json:"suggested_qty"`
+	Quantity        float64    `json:"quantity"` // The suggestion unless edited; zero lines are left off the order
+	UnitPrice       float64    `json:"unit_price"`
+	PriceLevel      PriceLevel `json:"price_level,omitempty"`
+	PriceSource     string     `json:"price_source"`
+	DiscountPercent float64    `json:"discount_percent,omitempty"`
+	LineTotal       float64    `json:"line_total"`
+	Available       float64    `json:"available"`
+	Shortage        bool       `json:"shortage"`
+	ShortQty        float64    `json:"short_qty,omitempty"`
+	Edited          bool       `json:"edited"`
+}
+
+// OrderGuideDraft is an order proposed from a customer's order guide, and
+// the order created from it once accepted.
+type OrderGuideDraft struct {
+	OrderID           *int             `json:"order_id,omitempty"`
+	CustomerID        int              `json:"customer_id"`
+	WarehouseID       int              `json:"warehouse_id"`
+	LastDeliveryDate  CustomDate       `json:"last_delivery_date,omitempty"`
+	DaysSinceDelivery int              `json:"days_since_delivery"`
+	CoverDays         int              `json:"cover_days"` // Days of usage the suggestions cover
+	Lines             []OrderGuideLine `json:"lines"`
+	TotalAmount       float64          `json:"total_amount"`
+	ShortageCount     int              `json:"shortage_count"`
+}
+
+// OrderGuideLineEdit changes one proposed line. A zero quantity drops the
+// line; a product that is not on the guide is added.
+type OrderGuideLineEdit struct {
+	ProductID       int      `json:"product_id"`
+	Quantity        *float64 `json:"quantity,omitempty"`
+	UnitPrice       *float64 `json:"unit_price,omitempty"`
+	DiscountPercent *float64 `json:"discount_percent,omitempty"`
+}
+
+// OrderGuideDraftRequest proposes, or creates, an order from a customer's
+// order guide. Lines without an edit take the suggested quantity.
+type OrderGuideDraftRequest struct {
+	CustomerID        int                  `json:"customer_id"`
+	WarehouseID       int                  `json:"warehouse_id"`
+	ShipToID          *int                 `json:"ship_to_id,omitempty"`
+	OrderType         OrderType            `json:"order_type,omitempty"`
+	RequestedShipDate string               `json:"requested_ship_date,omitempty"`
+	RouteID           *int                 `json:"route_id,omitempty"`
+	PONumber          string               `json:"po_number,omitempty"`
+	Notes             string               `json:"notes,omitempty"`
+	Lines             []OrderGuideLineEdit `json:"lines,omitempty"`
+}
+
+// ============================================
+// Backorders
+// ============================================
+
+// BackorderLine is a line waiting for stock, as listed on the backorder fill
+// report. CanFill is true when the warehouse's available stock covers it and
+// every older backorder for the product.
+type BackorderLine struct {
+	OrderLineID         int        `json:"order_line_id"`
+	OrderID             int        `json:"order_id"`
+	OrderNumber         string     `json:"order_number"`
+	OrderDate           CustomDate `json:"order_date"`
+	BackorderOfID       *int       `json:"backorder_of_id,omitempty"`
+	CustomerID          int        `json:"customer_id"`
+	CustomerName        string     `json:"customer_name"`
+	WarehouseID         int        `json:"warehouse_id"`
+	ProductID           int        `json:"product_id"`
+	ProductSKU          string     `json:"product_sku"`
+	ProductName         string     `json:"product_name"`
+	QuantityBackordered float64    `json:"quantity_backordered"`
+	Available           float64    `json:"available"`
+	CanFill             bool       `json:"can_fill"`
+}
+
+type BackorderFilters struct {
+	WarehouseID *int `json:"warehouse_id,omitempty"`
+	ProductID   *int `json:"product_id,omitempty"`
+	CustomerID  *int `json:"customer_id,omitempty"`
+}
+
+// ReleaseBackordersRequest allocates arrived stock to backorders in a
+// warehouse, oldest first. All backordered products are tried when none
+// are given.
+type ReleaseBackordersRequest struct {
+	WarehouseID int   `json:"warehouse_id"`
+	ProductIDs  []int `json:"product_ids,omitempty"`
+}
+
+type BackorderRelease struct {
+	Released []BackorderLine `json:"released"`
+	Waiting  int             `json:"waiting"` // Backorders the stock did not cover
+}
+
+func ValidateReleaseBackorders(v *Validator, r *ReleaseBackordersRequest) {
+	v.Check(r.WarehouseID > 0, "warehouse_id", "Warehouse is required")
+}
+
 // ============================================
 // Lost Sales
 // ============================================
 
+type LostSaleReason string
+
+const (
+	LostSaleOutOfStock         LostSaleReason = "OUT_OF_STOCK" // The warehouse could not allocate the line
+	LostSaleShortPick          LostSaleReason = "SHORT_PICK"
+	LostSaleBackorderCancelled LostSaleReason = "BACKORDER_CANCELLED"
+	LostSaleOther              LostSaleReason = "OTHER"
+)
+
+// LostSale is demand the warehouse could not fill. The quantity lost is
+// what was requested less what was available.
 type LostSale struct {
-	ID                int        `json:"id"`
-	OrderID           int        `json:"order_id"`
-	ProductID         int        `json:"product_id"`
-	ProductName       string     `json:"product_name"`
-	QuantityRequested float64    `json:"quantity_requested"`
-	QuantityAvailable float64    `json:"quantity_available"`
-	Reason            string     `json:"reason"`
-	CreatedAt         CustomDate `json:"created_at"`
+	ID                int            `json:"id"`
+	OrderID           int            `json:"order_id"`
+	OrderLineID       *int           `json:"order_line_id,omitempty"`
+	ProductID         int            `json:"product_id"`
+	ProductName       string         `json:"product_name"`
+	CustomerID        *int           `json:"customer_id,omitempty"`
+	CustomerName      string         `json:"customer_name,omitempty"`
+	SalesRepID        *int           `json:"sales_rep_id,omitempty"`
+	WarehouseID       *int           `json:"warehouse_id,omitempty"`
+	QuantityRequested float64        `json:"quantity_requested"`
+	QuantityAvailable float64        `json:"quantity_available"`
+	UnitPrice         float64        `json:"unit_price"`
+	LostRevenue       float64        `json:"lost_revenue"`
+	ReasonCode        LostSaleReason `json:"reason_code"`
+	Reason            string         `json:"reason"`
+	CreatedAt         CustomDate     `json:"created_at"`
+}
+
+// RecordLostSaleRequest records a lost sale by hand. The customer, rep,
+// warehouse and price come from the order and line.
+type RecordLostSaleRequest struct {
+	OrderID           int            `json:"order_id"`
+	OrderLineID       *int           `json:"order_line_id,omitempty"`
+	ProductID         int            `json:"product_id"`
+	QuantityRequested float64        `json:"quantity_requested"`
+	QuantityAvailable float64        `json:"quantity_available"`
+	ReasonCode        LostSaleReason `json:"reason_code,omitempty"` // Defaults to OTHER
+	Reason            string         `json:"reason"`
+}
+
+type LostSalesGroup string
+
+const (
+	LostSalesByProduct   LostSalesGroup = "product"
+	LostSalesByCustomer  LostSalesGroup = "customer"
+	LostSalesByRep       LostSalesGroup = "rep"
+	LostSalesByWarehouse LostSalesGroup = "warehouse"
+	LostSalesByWeek      LostSalesGroup = "week"
+)
+
+// LostSalesReportFilters aggregates lost sales between DateFrom and DateTo,
+// the last twelve weeks by default.
+type LostSalesReportFilters struct {
+	GroupBy     LostSalesGroup
+	DateFrom    string
+	DateTo      string
+	WarehouseID *int
+	ProductID   *int
+	CustomerID  *int
+	ReasonCode  *LostSaleReason
+}
+
+// LostSalesSummary is one row of the lost sales report. GroupID is the
+// product, customer, rep or warehouse; weeks are labelled by their Monday.
+type LostSalesSummary struct {
+	GroupID      *int    `json:"group_id,omitempty"`
+	Label        string  `json:"label"`
+	Occurrences  int     `json:"occurrences"`
+	QuantityLost float64 `json:"quantity_lost"`
+	LostRevenue  float64 `json:"lost_revenue"`
+}
+
+func ValidateLostSale(v *Validator, req *RecordLostSaleRequest) {
+	v.Check(req.OrderID > 0, "order_id", "Order ID is required")
+	v.Check(req.ProductID > 0, "product_id", "Product ID is required")
+	v.Check(req.QuantityRequested > 0, "quantity_requested", "Quantity requested must be positive")
+	v.Check(req.QuantityAvailable >= 0, "quantity_available", "Quantity available cannot be negative")
+	v.Check(req.ReasonCode == "" || ValidLostSaleReason(req.ReasonCode), "reason_code",
+		"Reason code must be OUT_OF_STOCK, SHORT_PICK, BACKORDER_CANCELLED or OTHER")
+}
+
+func ValidateLostSalesReport(v *Validator, f *LostSalesReportFilters) {
+	switch f.GroupBy {
+	case LostSalesByProduct, LostSalesByCustomer, LostSalesByRep, LostSalesByWarehouse, LostSalesByWeek:
+	default:
+		v.AddError("group_by", "Group by must be product, customer, rep, warehouse or week")
+	}
+	if f.DateFrom != "" {
+		_, err := time.Parse("2006-01-02", f.DateFrom)
+		v.Check(err == nil, "date_from", "Date must be YYYY-MM-DD")
+	}
+	if f.DateTo != "" {
+		_, err := time.Parse("2006-01-02", f.DateTo)
+		v.Check(err == nil, "date_to", "Date must be YYYY-MM-DD")
+	}
+	v.Check(f.ReasonCode == nil || ValidLostSaleReason(*f.ReasonCode), "reason_code",
+		"Reason code must be OUT_OF_STOCK, SHORT_PICK, BACKORDER_CANCELLED or OTHER")
+}
+
+func ValidLostSaleReason(r LostSaleReason) bool {
+	switch r {
+	case LostSaleOutOfStock, LostSaleShortPick, LostSaleBackorderCancelled, LostSaleOther:
+		return true
+	}
+	return false
 }
 
 // ============================================
@@ -179,9 +491,68 @@ func ValidateSalesOrder(v *Validator, req *CreateSalesOrderRequest) {
 	v.Check(len(req.Lines) > 0, "lines", "At least one line is required")
 
 	for i, line := range req.Lines {
-		v.Check(line.ProductID > 0, "lines", "Product ID is required for all lines")
+		v.Check(line.ProductID > 0 || line.CustomerItemCode != "", "lines", "Product ID or customer item code is required for all lines")
+		v.Check(len(line.CustomerItemCode) <= 50, "lines", "Customer code must not be more than 50 characters")
 		v.Check(line.Quantity > 0, "lines", "Quantity must be positive for all lines")
 		v.Check(line.UnitOfMeasure != "", "lines", "Unit of measure is required for all lines")
 		_ = i // Avoid unused variable
 	}
+
+	ValidateOrderType(v, req.OrderType, req.RequestedShipDate, req.RouteID)
+
+	if req.QuoteExpiryDate != "" {
+		v.Check(req.OrderType == OrderTypeQuote, "quote_expiry_date", "Only quotes have an expiry date")
+		expiry, err := time.Parse("2006-01-02", req.QuoteExpiryDate)
+		v.Check(err == nil, "quote_expiry_date", "Quote expiry date must be YYYY-MM-DD")
+		v.Check(err != nil || !expiry.Before(today()), "quote_expiry_date", "Quote expiry date cannot be in the past")
+	}
+}
+
+func ValidateOrderGuideDraft(v *Validator, req *OrderGuideDraftRequest) {
+	v.Check(req.CustomerID > 0, "customer_id", "Customer is required")
+	v.Check(req.WarehouseID > 0, "warehouse_id", "Warehouse is required")
+	v.Check(req.OrderType != OrderTypeCreditMemo, "order_type", "Credit memos cannot be drafted from an order guide")
+
+	seen := make(map[int]bool)
+	for _, line := range req.Lines {
+		v.Check(line.ProductID > 0, "lines", "Product ID is required for all lines")
+		v.Check(!seen[line.ProductID], "lines", "Each product can only be edited once")
+		v.Check(line.Quantity == nil || *line.Quantity >= 0, "lines", "Quantity cannot be negative")
+		v.Check(line.UnitPrice == nil || *line.UnitPrice >= 0, "lines", "Unit price cannot be negative")
+		v.Check(line.DiscountPercent == nil || (*line.DiscountPercent >= 0 && *line.DiscountPercent <= 100),
+			"lines", "Discount percent must be between 0 and 100")
+		seen[line.ProductID] = true
+	}
+
+	if req.RequestedShipDate != "" {
+		_, err := time.Parse("2006-01-02", req.RequestedShipDate)
+		v.Check(err == nil, "requested_ship_date", "Requested ship date must be YYYY-MM-DD")
+	}
+
+	ValidateOrderType(v, req.OrderType, req.RequestedShipDate, req.RouteID)
+}
+
+// ValidateOrderType checks the header fields a given order type depends on.
+func ValidateOrderType(v *Validator, orderType OrderType, requestedShipDate string, routeID *int) {
+	switch orderType {
+	case "", OrderTypeStandard, OrderTypePrePaid, OrderTypeOnHold, OrderTypeQuote, OrderTypeCreditMemo:
+	case OrderTypeAdvance:
+		shipDate, err := time.Parse("2006-01-02", requestedShipDate)
+		v.Check(err == nil && shipDate.After(today()), "requested_ship_date", "Advance orders must be scheduled for a future date")
+	case OrderTypePickUp:
+		v.Check(routeID == nil, "route_id", "Pick-up orders are not routed")
+	default:
+		v.AddError("order_type", "Invalid order type")
+	}
+}
+
+func ValidateConvertQuote(v *Validator, req *ConvertQuoteRequest) {
+	v.Check(req.OrderType != OrderTypeQuote && req.OrderType != OrderTypeCreditMemo,
+		"order_type", "A quote can only be converted into a sales order")
+	ValidateOrderType(v, req.OrderType, req.RequestedShipDate, nil)
+}
+
+func today() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
 }
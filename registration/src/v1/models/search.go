@@ -0,0 +1,69 @@
+package models
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// ============================================
+// Search Models
+// ============================================
+
+type SearchResultType string
+
+const (
+	SearchResultProduct  SearchResultType = "product"
+	SearchResultCustomer SearchResultType = "customer"
+	SearchResultVendor   SearchResultType = "vendor"
+	SearchResultShipTo   SearchResultType = "ship_to"
+)
+
+// SearchResultTypes lists every searchable type in the order results are
+// grouped when scores tie.
+var SearchResultTypes = []SearchResultType{
+	SearchResultProduct, SearchResultCustomer, SearchResultVendor, SearchResultShipTo,
+}
+
+type SearchResult struct {
+	Type       SearchResultType `json:"type"`
+	ID         int              `json:"id"`
+	Code       string           `json:"code"`
+	Name       string           `json:"name"`
+	Detail     string           `json:"detail,omitempty"`
+	CustomerID *int             `json:"customer_id,omitempty"` // ship_to only
+	IsActive   bool             `json:"is_active"`
+	Score      float64          `json:"score"`
+}
+
+type SearchRequest struct {
+	Query           string             `json:"q"`
+	Types           []SearchResultType `json:"types,omitempty"`
+	Limit           int                `json:"limit"`
+	IncludeInactive bool               `json:"include_inactive,omitempty"`
+}
+
+// ============================================
+// Validation
+// ============================================
+
+func ValidateSearch(v *Validator, req *SearchRequest) {
+	req.Query = strings.TrimSpace(req.Query)
+	n := utf8.RuneCountInString(req.Query)
+	v.Check(n >= 2, "q", "Search text must be at least 2 characters")
+	v.Check(n <= 100, "q", "Search text must not exceed 100 characters")
+
+	if req.Limit == 0 {
+		req.Limit = 20
+	}
+	v.Check(req.Limit >= 1 && req.Limit <= 50, "limit", "Limit must be between 1 and 50")
+
+	if len(req.Types) == 0 {
+		req.Types = SearchResultTypes
+	}
+	for _, t := range req.Types {
+		if t != SearchResultProduct && t != SearchResultCustomer && t != SearchResultVendor && t != SearchResultShipTo {
+			v.AddError("types", "Types must be product, customer, vendor or ship_to")
+			break
+		}
+	}
+}
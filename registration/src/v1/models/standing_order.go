@@ -0,0 +1,228 @@
+package models
+
+import "time"
+
+// ============================================
+// Standing Order Models
+// ============================================
+
+// StandingOrder is a customer's repeating order: the same lines delivered
+// to a ship-to on the same weekdays. Draft sales orders are generated from
+// it DaysAhead days before each delivery the customer's route makes.
+type StandingOrder struct {
+	ID            int            `json:"id"`
+	CustomerID    int            `json:"customer_id"`
+	ShipToID      *int           `json:"ship_to_id,omitempty"`
+	WarehouseID   int            `json:"warehouse_id"`
+	Name          string         `json:"name"`
+	DeliveryDays  []int          `json:"delivery_days"` // 0 = Sunday
+	EffectiveFrom CustomDate     `json:"effective_from"`
+	EffectiveTo   CustomDate     `json:"effective_to"`
+	DaysAhead     *int           `json:"days_ahead,omitempty"` // Company setting when unset
+	PausedAt      CustomDateTime `json:"paused_at"`
+	PausedUntil   CustomDate     `json:"paused_until"` // Paused without end when unset
+	PauseReason   string         `json:"pause_reason,omitempty"`
+	PONumber      string         `json:"po_number,omitempty"`
+	Notes         string         `json:"notes,omitempty"`
+	IsActive      bool           `json:"is_active"`
+	CreatedBy     *int           `json:"created_by,omitempty"`
+	CreatedAt     CustomDateTime `json:"created_at"`
+	UpdatedAt     CustomDateTime `json:"updated_at"`
+}
+
+// StandingOrderLine is priced at the customer's price when the order is
+// generated unless UnitPrice fixes it.
+type StandingOrderLine struct {
+	ID              int      `json:"id"`
+	StandingOrderID int      `json:"standing_order_id"`
+	LineNumber      int      `json:"line_number"`
+	ProductID       int      `json:"product_id"`
+	ProductSKU      string   `json:"product_sku"`
+	ProductName     string   `json:"product_name"`
+	Quantity        float64  `json:"quantity"`
+	UnitOfMeasure   string   `json:"unit_of_measure"`
+	UnitPrice       *float64 `json:"unit_price,omitempty"`
+	Notes           string   `json:"notes,omitempty"`
+}
+
+// StandingOrderSkip is a single delivery the customer does not want.
+type StandingOrderSkip struct {
+	ID              int            `json:"id"`
+	StandingOrderID int            `json:"standing_order_id"`
+	DeliveryDate    CustomDate     `json:"delivery_date"`
+	Reason          string         `json:"reason,omitempty"`
+	CreatedBy       *int           `json:"created_by,omitempty"`
+	CreatedAt       CustomDateTime `json:"created_at"`
+}
+
+type StandingOrderWithDetails struct {
+	StandingOrder
+	CustomerName  string              `json:"customer_name"`
+	ShipToName    string              `json:"ship_to_name,omitempty"`
+	WarehouseName string              `json:"warehouse_name"`
+	Lines         []StandingOrderLine `json:"lines"`
+	Skips         []StandingOrderSkip `json:"skips"` // Today onwards
+}
+
+// StandingOrderRunStatus is what the generator did for a delivery.
+type StandingOrderRunStatus string
+
+const (
+	StandingOrderScheduled StandingOrderRunStatus = "SCHEDULED" // Not generated yet
+	StandingOrderGenerated StandingOrderRunStatus = "GENERATED"
+	StandingOrderSkipped   StandingOrderRunStatus = "SKIPPED"
+	StandingOrderFailed    StandingOrderRunStatus = "FAILED"
+)
+
+// StandingOrderDelivery is one delivery of a standing order, generated or
+// still to come.
+type StandingOrderDelivery struct {
+	StandingOrderID int                    `json:"standing_order_id"`
+	DeliveryDate    CustomDate             `json:"delivery_date"`
+	Status          StandingOrderRunStatus `json:"status"`
+	SalesOrderID    *int                   `json:"sales_order_id,omitempty"`
+	Message         string                 `json:"message,omitempty"`
+}
+
+type GenerateStandingOrdersResult struct {
+	Generated  int                     `json:"generated"`
+	Skipped    int                     `json:"skipped"`
+	Failed     int                     `json:"failed"`
+	Deliveries []StandingOrderDelivery `json:"deliveries"`
+}
+
+// ============================================
+// Request DTOs
+// ============================================
+
+type CreateStandingOrderRequest struct {
+	CustomerID    int                        `json:"customer_id"`
+	ShipToID      *int                       `json:"ship_to_id,omitempty"`
+	WarehouseID   int                        `json:"warehouse_id"`
+	Name          string                     `json:"name"`
+	DeliveryDays  []int                      `json:"delivery_days"`
+	EffectiveFrom string                     `json:"effective_from,omitempty"` // Defaults to today
+	EffectiveTo   string                     `json:"effective_to,omitempty"`
+	DaysAhead     *int                       `json:"days_ahead,omitempty"`
+	PONumber      string                     `json:"po_number,omitempty"`
+	Notes         string                     `json:"notes,omitempty"`
+	Lines         []StandingOrderLineRequest `json:"lines"`
+}
+
+// UpdateStandingOrderRequest changes the fields given. Lines, when given,
+// replace the order's lines; deliveries already generated keep theirs.
+type UpdateStandingOrderRequest struct {
+	ShipToID      *int                       `json:"ship_to_id,omitempty"`
+	WarehouseID   *int                       `json:"warehouse_id,omitempty"`
+	Name          *string                    `json:"name,omitempty"`
+	DeliveryDays  []int                      `json:"delivery_days,omitempty"`
+	EffectiveFrom *string                    `json:"effective_from,omitempty"`
+	EffectiveTo   *string                    `json:"effective_to,omitempty"` // Empty clears it
+	DaysAhead     *int                       `json:"days_ahead,omitempty"`
+	PONumber      *string                    `json:"po_number,omitempty"`
+	Notes         *string                    `json:"notes,omitempty"`
+	IsActive      *bool                      `json:"is_active,omitempty"`
+	Lines         []StandingOrderLineRequest `json:"lines,omitempty"`
+}
+
+type StandingOrderLineRequest struct {
+	ProductID     int      `json:"product_id"`
+	Quantity      float64  `json:"quantity"`
+	UnitOfMeasure string   `json:"unit_of_measure,omitempty"`
+	UnitPrice     *float64 `json:"unit_price,omitempty"`
+	Notes         string   `json:"notes,omitempty"`
+}
+
+// PauseStandingOrderRequest stops deliveries until a date, or until the
+// order is resumed when none is given.
+type PauseStandingOrderRequest struct {
+	Until  string `json:"until,omitempty"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type SkipDeliveryRequest struct {
+	DeliveryDate string `json:"delivery_date"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+type StandingOrderFilters struct {
+	CustomerID *int
+	ActiveOnly bool
+}
+
+// ============================================
+// Validation
+// ============================================
+
+func ValidateStandingOrder(v *Validator, req *CreateStandingOrderRequest) {
+	v.Check(req.CustomerID > 0, "customer_id", "Customer is required")
+	v.Check(req.WarehouseID > 0, "warehouse_id", "Warehouse is required")
+	v.Check(req.Name != "", "name", "Name is required")
+	v.Check(len(req.Name) <= 100, "name", "Name must be 100 characters or less")
+	v.Check(len(req.DeliveryDays) > 0, "delivery_days", "At least one delivery day is required")
+	validateStandingOrderSchedule(v, req.DeliveryDays, req.EffectiveFrom, req.EffectiveTo, req.DaysAhead)
+	v.Check(len(req.Lines) > 0, "lines", "At least one line is required")
+	validateStandingOrderLines(v, req.Lines)
+}
+
+func ValidateUpdateStandingOrder(v *Validator, req *UpdateStandingOrderRequest) {
+	if req.Name != nil {
+		v.Check(*req.Name != "", "name", "Name is required")
+		v.Check(len(*req.Name) <= 100, "name", "Name must be 100 characters or less")
+	}
+	if req.DeliveryDays != nil {
+		v.Check(len(req.DeliveryDays) > 0, "delivery_days", "At least one delivery day is required")
+	}
+	var from, to string
+	if req.EffectiveFrom != nil {
+		from = *req.EffectiveFrom
+		v.Check(from != "", "effective_from", "Effective from must be YYYY-MM-DD")
+	}
+	if req.EffectiveTo != nil {
+		to = *req.EffectiveTo
+	}
+	validateStandingOrderSchedule(v, req.DeliveryDays, from, to, req.DaysAhead)
+	validateStandingOrderLines(v, req.Lines)
+}
+
+func validateStandingOrderSchedule(v *Validator, days []int, from, to string, daysAhead *int) {
+	seen := make(map[int]bool)
+	for _, d := range days {
+		v.Check(d >= 0 && d <= 6, "delivery_days", "Day of week must be 0 (Sunday) to 6 (Saturday)")
+		v.Check(!seen[d], "delivery_days", "Each day of week can only be listed once")
+		seen[d] = true
+	}
+
+	var fromDate time.Time
+	if from != "" {
+		var err error
+		fromDate, err = time.Parse("2006-01-02", from)
+		v.Check(err == nil, "effective_from", "Effective from must be YYYY-MM-DD")
+	}
+	if to != "" {
+		toDate, err := time.Parse("2006-01-02", to)
+		v.Check(err == nil, "effective_to", "Effective to must be YYYY-MM-DD")
+		v.Check(err != nil || fromDate.IsZero() || !toDate.Before(fromDate), "effective_to", "Effective to cannot be before effective from")
+	}
+	v.Check(daysAhead == nil || (*daysAhead >= 0 && *daysAhead <= 30), "days_ahead", "Days ahead must be between 0 and 30")
+}
+
+func validateStandingOrderLines(v *Validator, lines []StandingOrderLineRequest) {
+	for _, line := range lines {
+		v.Check(line.ProductID > 0, "lines", "Product ID is required for all lines")
+		v.Check(line.Quantity > 0, "lines", "Quantity must be positive for all lines")
+		v.Check(line.UnitPrice == nil || *line.UnitPrice >= 0, "lines", "Unit price cannot be negative")
+	}
+}
+
+func ValidatePauseStandingOrder(v *Validator, req *PauseStandingOrderRequest) {
+	if req.Until != "" {
+		_, err := time.Parse("2006-01-02", req.Until)
+		v.Check(err == nil, "until", "Pause end date must be YYYY-MM-DD")
+	}
+}
+
+func ValidateSkipDelivery(v *Validator, req *SkipDeliveryRequest) {
+	_, err := time.Parse("2006-01-02", req.DeliveryDate)
+	v.Check(err == nil, "delivery_date", "Delivery date must be YYYY-MM-DD")
+}
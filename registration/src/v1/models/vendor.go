@@ -1,5 +1,7 @@
 package models
 
+import "github.com/anas-dev-92/FoodHive/registration/src/v1/utils/query"
+
 // ============================================
 // Vendor Models
 // ============================================
@@ -21,6 +23,7 @@ type Vendor struct {
 	LeadTimeDays     int        `json:"lead_time_days"`
 	MinimumOrder     float64    `json:"minimum_order,omitempty"`
 	BuyerID          *int       `json:"buyer_id,omitempty"`
+	DocumentLanguage string     `json:"document_language"`
 	IsActive         bool       `json:"is_active"`
 	CreatedAt        CustomDate `json:"created_at"`
 	UpdatedAt        CustomDate `json:"updated_at"`
@@ -73,6 +76,7 @@ type CreateVendorRequest struct {
 	LeadTimeDays     int     `json:"lead_time_days"`
 	MinimumOrder     float64 `json:"minimum_order,omitempty"`
 	BuyerID          *int    `json:"buyer_id,omitempty"`
+	DocumentLanguage string  `json:"document_language,omitempty"`
 }
 
 type UpdateVendorRequest struct {
@@ -90,15 +94,17 @@ type UpdateVendorRequest struct {
 	LeadTimeDays     *int     `json:"lead_time_days,omitempty"`
 	MinimumOrder     *float64 `json:"minimum_order,omitempty"`
 	BuyerID          *int     `json:"buyer_id,omitempty"`
+	DocumentLanguage *string  `json:"document_language,omitempty"`
 	IsActive         *bool    `json:"is_active,omitempty"`
 }
 
 type VendorListFilters struct {
-	Search   string `json:"search,omitempty"`
-	BuyerID  *int   `json:"buyer_id,omitempty"`
-	IsActive *bool  `json:"is_active,omitempty"`
-	Page     int    `json:"page"`
-	PageSize int    `json:"page_size"`
+	Search   string        `json:"search,omitempty"`
+	BuyerID  *int          `json:"buyer_id,omitempty"`
+	IsActive *bool         `json:"is_active,omitempty"`
+	Page     int           `json:"page"`
+	PageSize int           `json:"page_size"`
+	Query    *query.Params `json:"-"`
 }
 
 // ============================================
@@ -117,6 +123,7 @@ func ValidateVendor(v *Validator, req *CreateVendorRequest) {
 	}
 	v.Check(req.PaymentTermsDays >= 0, "payment_terms_days", "Payment terms must be 0 or greater")
 	v.Check(req.LeadTimeDays >= 0, "lead_time_days", "Lead time must be 0 or greater")
+	ValidateDocumentLanguage(v, req.DocumentLanguage)
 }
 
 func ValidateVendorProduct(v *Validator, req *VendorProduct) {
@@ -0,0 +1,139 @@
+package analytics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/anas-dev-92/FoodHive/core/auth"
+	"github.com/anas-dev-92/FoodHive/core/jwt"
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	analyticsMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/analytics"
+	authMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/auth"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
+	"github.com/go-chi/chi/v5"
+)
+
+func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.AuthService) chi.Router {
+	app := chi.NewRouter()
+
+	// Inject analytics service
+	app.Use(analyticsMiddleware.New(db))
+
+	// Apply authentication
+	app.Use(authMiddleware.Authenticate(jwtService))
+
+	// ===========================================
+	// Sales Routes
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Get("/sales", handleSales())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/sales/documents", handleSalesDocuments())
+
+	return app
+}
+
+// ===========================================
+// Sales Handlers
+// ===========================================
+
+func handleSales() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := analyticsMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		filters := readSalesFilters(r)
+
+		v := models.NewValidator()
+		models.ValidateSalesAnalytics(v, filters)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		analytics, err := svc.GetSales(r.Context(), filters)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, analytics)
+	}
+}
+
+func handleSalesDocuments() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := analyticsMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		filters := readSalesFilters(r)
+
+		v := models.NewValidator()
+		models.ValidateSalesAnalytics(v, filters)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		documents, total, err := svc.ListSalesDocuments(r.Context(), filters)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		totalPages := int(total) / filters.PageSize
+		if int(total)%filters.PageSize > 0 {
+			totalPages++
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{
+			"data": documents,
+			"pagination": helper.Envelope{
+				"page":        filters.Page,
+				"page_size":   filters.PageSize,
+				"total_items": total,
+				"total_pages": totalPages,
+			},
+		})
+	}
+}
+
+// readSalesFilters reads the grouping, dates and a filter for any of the
+// groups from the query string.
+func readSalesFilters(r *http.Request) *models.SalesAnalyticsFilters {
+	query := r.URL.Query()
+	filters := &models.SalesAnalyticsFilters{
+		GroupBy:  models.SalesGroup(query.Get("group_by")),
+		DateFrom: query.Get("date_from"),
+		DateTo:   query.Get("date_to"),
+		Page:     1,
+		PageSize: 20,
+	}
+	if page, err := strconv.Atoi(query.Get("page")); err == nil {
+		filters.Page = page
+	}
+	if pageSize, err := strconv.Atoi(query.Get("page_size")); err == nil {
+		filters.PageSize = pageSize
+	}
+
+	for param, dst := range map[string]**int{
+		"product_id":        &filters.ProductID,
+		"category_id":       &filters.CategoryID,
+		"customer_id":       &filters.CustomerID,
+		"customer_group_id": &filters.CustomerGroupID,
+		"sales_rep_id":      &filters.SalesRepID,
+		"route_id":          &filters.RouteID,
+		"warehouse_id":      &filters.WarehouseID,
+	} {
+		if id, err := strconv.Atoi(query.Get(param)); err == nil {
+			*dst = &id
+		}
+	}
+	return filters
+}
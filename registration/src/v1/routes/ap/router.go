@@ -10,8 +10,12 @@ import (
 	"github.com/anas-dev-92/FoodHive/core/postgres"
 	apMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/ap"
 	authMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/auth"
+	documentMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/document"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	apService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/ap"
+	documentService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/document"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/query"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -55,6 +59,11 @@ func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.Au
 	app.With(authMiddleware.Authorize(jwtService)).Get("/due", handleGetDueInvoices())
 	app.With(authMiddleware.Authorize(jwtService)).Get("/overdue", handleGetOverdueInvoices())
 
+	// ===========================================
+	// Printing
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Get("/payments/{id}/pdf", handlePaymentVoucherPDF())
+
 	return app
 }
 
@@ -86,6 +95,10 @@ func handleCreateInvoice() http.HandlerFunc {
 		createdBy := 1 // TODO: Get from auth context
 
 		id, err := svc.CreateInvoice(r.Context(), &req, createdBy)
+		if errors.Is(err, apService.ErrVendorNotFound) {
+			helper.NotFoundResponse(w, r)
+			return
+		}
 		if err != nil {
 			helper.ServerErrorResponse(w, r, err)
 			return
@@ -149,26 +162,20 @@ func handleListInvoices() http.HandlerFunc {
 			filters.Status = &s
 		}
 
-		invoices, total, err := svc.ListInvoices(r.Context(), &filters)
+		params, err := query.Parse(r.URL.Query())
 		if err != nil {
-			helper.ServerErrorResponse(w, r, err)
+			helper.BadRequestResponse(w, r, err)
 			return
 		}
+		filters.Query = params
 
-		totalPages := int(total) / filters.PageSize
-		if int(total)%filters.PageSize > 0 {
-			totalPages++
+		invoices, total, err := svc.ListInvoices(r.Context(), &filters)
+		if err != nil {
+			helper.ListErrorResponse(w, r, err)
+			return
 		}
 
-		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{
-			"data": invoices,
-			"pagination": helper.Envelope{
-				"page":        filters.Page,
-				"page_size":   filters.PageSize,
-				"total_items": total,
-				"total_pages": totalPages,
-			},
-		})
+		helper.ListResponse(w, r, invoices, total, params)
 	}
 }
 
@@ -276,6 +283,10 @@ func handleCreatePayment() http.HandlerFunc {
 		preparedBy := 1 // TODO: Get from auth context
 
 		id, err := svc.CreatePayment(r.Context(), &req, preparedBy)
+		if errors.Is(err, apService.ErrVendorNotFound) || errors.Is(err, apService.ErrInvoiceNotFound) {
+			helper.NotFoundResponse(w, r)
+			return
+		}
 		if err != nil {
 			helper.ServerErrorResponse(w, r, err)
 			return
@@ -480,3 +491,37 @@ func handleGetOverdueInvoices() http.HandlerFunc {
 		helper.SuccessResponse(w, r, http.StatusOK, invoices)
 	}
 }
+
+// ===========================================
+// PDF Handlers
+// ===========================================
+
+func handlePaymentVoucherPDF() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := documentMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid payment ID"))
+			return
+		}
+
+		printedBy, _ := authMiddleware.GetUserID(r.Context())
+
+		doc, err := svc.RenderPaymentVoucher(r.Context(), id, printedBy)
+		if err != nil {
+			if errors.Is(err, documentService.ErrNotFound) {
+				helper.NotFoundResponse(w, r)
+				return
+			}
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.FileResponse(w, r, doc.FileName, doc.ContentType, doc.Content)
+	}
+}
@@ -4,14 +4,23 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/anas-dev-92/FoodHive/core/auth"
 	"github.com/anas-dev-92/FoodHive/core/jwt"
 	"github.com/anas-dev-92/FoodHive/core/postgres"
 	arMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/ar"
 	authMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/auth"
+	deliveryMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/delivery"
+	documentMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/document"
+	soMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/sales_order"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	arService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/ar"
+	deliveryService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/delivery"
+	documentService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/document"
+	soService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/sales_order"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/query"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -21,6 +30,9 @@ func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.Au
 	// Inject AR service
 	app.Use(arMiddleware.New(db))
 
+	// Credit holds are released from AR on sales orders
+	app.Use(soMiddleware.New(db))
+
 	// Apply authentication
 	app.Use(authMiddleware.Authenticate(jwtService))
 
@@ -34,6 +46,7 @@ func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.Au
 	app.With(authMiddleware.Authorize(jwtService)).Post("/invoices/{id}/post", handlePostInvoice())
 	app.With(authMiddleware.Authorize(jwtService)).Post("/invoices/{id}/void", handleVoidInvoice())
 	app.With(authMiddleware.Authorize(jwtService)).Post("/invoices/from-order/{orderId}", handleCreateFromOrder())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/invoices/{id}/proof-of-delivery", handleGetProofOfDelivery())
 
 	// ===========================================
 	// Payments
@@ -48,6 +61,7 @@ func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.Au
 	app.With(authMiddleware.Authorize(jwtService)).Get("/credit/{customerId}", handleGetCustomerCredit())
 	app.With(authMiddleware.Authorize(jwtService)).Get("/credit/{customerId}/check", handleCheckCredit())
 	app.With(authMiddleware.Authorize(jwtService)).Put("/credit/{customerId}/limit", handleUpdateCreditLimit())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/credit-holds/release/{orderId}", handleReleaseCreditHold())
 
 	// ===========================================
 	// Aging & Statements
@@ -57,6 +71,12 @@ func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.Au
 	app.With(authMiddleware.Authorize(jwtService)).Get("/statement/{customerId}", handleGetStatement())
 	app.With(authMiddleware.Authorize(jwtService)).Get("/overdue", handleGetOverdueInvoices())
 
+	// ===========================================
+	// Printing
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Get("/invoices/{id}/pdf", handleInvoicePDF())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/customers/{id}/statement.pdf", handleStatementPDF())
+
 	return app
 }
 
@@ -88,6 +108,10 @@ func handleCreateInvoice() http.HandlerFunc {
 		createdBy := 1 // TODO: Get from auth context
 
 		id, err := svc.CreateInvoice(r.Context(), &req, createdBy)
+		if errors.Is(err, arService.ErrCustomerNotFound) {
+			helper.NotFoundResponse(w, r)
+			return
+		}
 		if err != nil {
 			helper.ServerErrorResponse(w, r, err)
 			return
@@ -170,26 +194,20 @@ func handleListInvoices() http.HandlerFunc {
 			filters.Status = &s
 		}
 
-		invoices, total, err := svc.ListInvoices(r.Context(), &filters)
+		params, err := query.Parse(r.URL.Query())
 		if err != nil {
-			helper.ServerErrorResponse(w, r, err)
+			helper.BadRequestResponse(w, r, err)
 			return
 		}
+		filters.Query = params
 
-		totalPages := int(total) / filters.PageSize
-		if int(total)%filters.PageSize > 0 {
-			totalPages++
+		invoices, total, err := svc.ListInvoices(r.Context(), &filters)
+		if err != nil {
+			helper.ListErrorResponse(w, r, err)
+			return
 		}
 
-		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{
-			"data": invoices,
-			"pagination": helper.Envelope{
-				"page":        filters.Page,
-				"page_size":   filters.PageSize,
-				"total_items": total,
-				"total_pages": totalPages,
-			},
-		})
+		helper.ListResponse(w, r, invoices, total, params)
 	}
 }
 
@@ -297,6 +315,10 @@ func handleCreatePayment() http.HandlerFunc {
 		receivedBy := 1 // TODO: Get from auth context
 
 		id, err := svc.CreatePayment(r.Context(), &req, receivedBy)
+		if errors.Is(err, arService.ErrCustomerNotFound) || errors.Is(err, arService.ErrInvoiceNotFound) {
+			helper.NotFoundResponse(w, r)
+			return
+		}
 		if err != nil {
 			helper.ServerErrorResponse(w, r, err)
 			return
@@ -443,6 +465,10 @@ func handleUpdateCreditLimit() http.HandlerFunc {
 		}
 
 		err = svc.UpdateCreditLimit(r.Context(), customerID, req.CreditLimit)
+		if errors.Is(err, arService.ErrCustomerNotFound) {
+			helper.NotFoundResponse(w, r)
+			return
+		}
 		if err != nil {
 			helper.ServerErrorResponse(w, r, err)
 			return
@@ -452,6 +478,53 @@ func handleUpdateCreditLimit() http.HandlerFunc {
 	}
 }
 
+// handleReleaseCreditHold approves a sales order held for the customer's
+// credit. It sits under AR so only users with AR rights can release.
+func handleReleaseCreditHold() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := soMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		orderID, err := strconv.Atoi(chi.URLParam(r, "orderId"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid sales order ID"))
+			return
+		}
+
+		var req models.ReleaseHoldRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		v.Check(strings.TrimSpace(req.Reason) != "", "reason", "Release reason is required")
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		releasedBy, _ := authMiddleware.GetUserID(r.Context())
+		err = svc.ReleaseCreditHold(r.Context(), orderID, strings.TrimSpace(req.Reason), releasedBy)
+		if err != nil {
+			switch {
+			case errors.Is(err, soService.ErrOrderNotFound):
+				helper.NotFoundResponse(w, r)
+			case errors.Is(err, soService.ErrIllegalTransition):
+				helper.ErrorResponse(w, r, http.StatusConflict, err.Error())
+			default:
+				helper.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Credit hold released successfully"})
+	}
+}
+
 // ===========================================
 // Aging & Statement Handlers
 // ===========================================
@@ -520,6 +593,10 @@ func handleGetStatement() http.HandlerFunc {
 		}
 
 		statement, err := svc.GetStatement(r.Context(), customerID, fromDate, toDate)
+		if errors.Is(err, arService.ErrCustomerNotFound) {
+			helper.NotFoundResponse(w, r)
+			return
+		}
 		if err != nil {
 			helper.ServerErrorResponse(w, r, err)
 			return
@@ -551,3 +628,101 @@ func handleGetOverdueInvoices() http.HandlerFunc {
 		helper.SuccessResponse(w, r, http.StatusOK, invoices)
 	}
 }
+
+// ===========================================
+// PDF Handlers
+// ===========================================
+
+// handleGetProofOfDelivery returns what the customer signed for on the
+// invoice's order. The delivery service is injected globally.
+func handleGetProofOfDelivery() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := deliveryMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid invoice ID"))
+			return
+		}
+
+		pod, err := svc.GetByInvoice(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, deliveryService.ErrNotFound) {
+				helper.NotFoundResponse(w, r)
+				return
+			}
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, pod)
+	}
+}
+
+func handleInvoicePDF() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := documentMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid invoice ID"))
+			return
+		}
+
+		printedBy, _ := authMiddleware.GetUserID(r.Context())
+
+		doc, err := svc.RenderInvoice(r.Context(), id, printedBy)
+		if err != nil {
+			if errors.Is(err, documentService.ErrNotFound) {
+				helper.NotFoundResponse(w, r)
+				return
+			}
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.FileResponse(w, r, doc.FileName, doc.ContentType, doc.Content)
+	}
+}
+
+func handleStatementPDF() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := documentMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid customer ID"))
+			return
+		}
+
+		printedBy, _ := authMiddleware.GetUserID(r.Context())
+
+		doc, err := svc.RenderStatement(r.Context(), id, r.URL.Query().Get("from_date"), r.URL.Query().Get("to_date"), printedBy)
+		if err != nil {
+			if errors.Is(err, documentService.ErrInvalidPeriod) {
+				helper.BadRequestResponse(w, r, err)
+				return
+			}
+			if errors.Is(err, documentService.ErrNotFound) {
+				helper.NotFoundResponse(w, r)
+				return
+			}
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.FileResponse(w, r, doc.FileName, doc.ContentType, doc.Content)
+	}
+}
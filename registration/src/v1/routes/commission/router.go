@@ -0,0 +1,521 @@
+package commission
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/anas-dev-92/FoodHive/core/auth"
+	"github.com/anas-dev-92/FoodHive/core/jwt"
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	authMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/auth"
+	commissionMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/commission"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	commissionService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/commission"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
+	"github.com/go-chi/chi/v5"
+)
+
+func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.AuthService) chi.Router {
+	app := chi.NewRouter()
+
+	// Inject commission service
+	app.Use(commissionMiddleware.New(db))
+
+	// Apply authentication
+	app.Use(authMiddleware.Authenticate(jwtService))
+
+	// ===========================================
+	// Plan Routes
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Post("/plans", handleCreatePlan())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/plans", handleListPlans())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/plans/{id}", handleGetPlan())
+	app.With(authMiddleware.Authorize(jwtService)).Put("/plans/{id}", handleUpdatePlan())
+	app.With(authMiddleware.Authorize(jwtService)).Delete("/plans/{id}", handleDeletePlan())
+
+	// ===========================================
+	// Assignment Routes
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Post("/assignments", handleAssignPlan())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/assignments", handleListAssignments())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/assignments/{id}/end", handleEndAssignment())
+	app.With(authMiddleware.Authorize(jwtService)).Delete("/assignments/{id}", handleDeleteAssignment())
+
+	// ===========================================
+	// Statement Routes
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Post("/statements/generate", handleGenerateStatements())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/statements/pay", handlePayStatements())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/statements", handleListStatements())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/statements/{id}", handleGetStatement())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/statements/{id}/approve", handleApproveStatement())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/payroll-totals", handlePayrollTotals())
+
+	return app
+}
+
+// ===========================================
+// Plan Handlers
+// ===========================================
+
+func handleCreatePlan() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := commissionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.CreateCommissionPlanRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateCommissionPlan(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		createdBy, _ := authMiddleware.GetUserID(r.Context())
+
+		id, err := svc.CreatePlan(r.Context(), &req, createdBy)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.CreatedResponse(w, r, id, "Commission plan created successfully")
+	}
+}
+
+func handleListPlans() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := commissionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		activeOnly := r.URL.Query().Get("active") == "true"
+
+		plans, err := svc.ListPlans(r.Context(), activeOnly)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, plans)
+	}
+}
+
+func handleGetPlan() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := commissionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid plan ID"))
+			return
+		}
+
+		plan, err := svc.GetPlan(r.Context(), id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, plan)
+	}
+}
+
+func handleUpdatePlan() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := commissionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid plan ID"))
+			return
+		}
+
+		var req models.UpdateCommissionPlanRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateUpdateCommissionPlan(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		if err := svc.UpdatePlan(r.Context(), id, &req); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Commission plan updated successfully"})
+	}
+}
+
+func handleDeletePlan() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := commissionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid plan ID"))
+			return
+		}
+
+		if err := svc.DeletePlan(r.Context(), id); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Commission plan deleted successfully"})
+	}
+}
+
+// ===========================================
+// Assignment Handlers
+// ===========================================
+
+func handleAssignPlan() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := commissionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.AssignCommissionPlanRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateAssignCommissionPlan(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		createdBy, _ := authMiddleware.GetUserID(r.Context())
+
+		id, err := svc.AssignPlan(r.Context(), &req, createdBy)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.CreatedResponse(w, r, id, "Commission plan assigned successfully")
+	}
+}
+
+func handleListAssignments() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := commissionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var filters models.CommissionAssignmentFilters
+		if rid, err := strconv.Atoi(r.URL.Query().Get("sales_rep_id")); err == nil {
+			filters.SalesRepID = &rid
+		}
+		if pid, err := strconv.Atoi(r.URL.Query().Get("plan_id")); err == nil {
+			filters.PlanID = &pid
+		}
+
+		assignments, err := svc.ListAssignments(r.Context(), &filters)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, assignments)
+	}
+}
+
+func handleEndAssignment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := commissionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid assignment ID"))
+			return
+		}
+
+		var req models.EndCommissionAssignmentRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateEndCommissionAssignment(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		if err := svc.EndAssignment(r.Context(), id, &req); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Commission assignment ended"})
+	}
+}
+
+func handleDeleteAssignment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := commissionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid assignment ID"))
+			return
+		}
+
+		if err := svc.DeleteAssignment(r.Context(), id); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Commission assignment deleted successfully"})
+	}
+}
+
+// ===========================================
+// Statement Handlers
+// ===========================================
+
+func handleGenerateStatements() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := commissionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.GenerateCommissionStatementsRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateGenerateCommissionStatements(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		statements, err := svc.GenerateStatements(r.Context(), &req)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, statements)
+	}
+}
+
+func handleListStatements() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := commissionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		filters, err := readStatementFilters(r)
+		if err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		statements, err := svc.ListStatements(r.Context(), filters)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, statements)
+	}
+}
+
+func handleGetStatement() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := commissionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid statement ID"))
+			return
+		}
+
+		statement, err := svc.GetStatement(r.Context(), id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, statement)
+	}
+}
+
+func handleApproveStatement() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := commissionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid statement ID"))
+			return
+		}
+
+		approvedBy, _ := authMiddleware.GetUserID(r.Context())
+
+		if err := svc.ApproveStatement(r.Context(), id, approvedBy); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Commission statement approved"})
+	}
+}
+
+func handlePayStatements() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := commissionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.PayCommissionStatementsRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidatePayCommissionStatements(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		if err := svc.PayStatements(r.Context(), &req); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Commission statements marked paid"})
+	}
+}
+
+func handlePayrollTotals() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := commissionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		filters, err := readStatementFilters(r)
+		if err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		totals, err := svc.GetPayrollTotals(r.Context(), filters)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, totals)
+	}
+}
+
+func readStatementFilters(r *http.Request) (*models.CommissionStatementFilters, error) {
+	query := r.URL.Query()
+	filters := &models.CommissionStatementFilters{
+		DateFrom: query.Get("date_from"),
+		DateTo:   query.Get("date_to"),
+	}
+	if rid, err := strconv.Atoi(query.Get("sales_rep_id")); err == nil {
+		filters.SalesRepID = &rid
+	}
+	if status := query.Get("status"); status != "" {
+		s := models.CommissionStatementStatus(status)
+		if !models.ValidCommissionStatementStatus(s) {
+			return nil, errors.New("status must be DRAFT, APPROVED or PAID")
+		}
+		filters.Status = &s
+	}
+	return filters, nil
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, commissionService.ErrPlanNotFound),
+		errors.Is(err, commissionService.ErrAssignmentNotFound),
+		errors.Is(err, commissionService.ErrStatementNotFound):
+		helper.NotFoundResponse(w, r)
+	case errors.Is(err, commissionService.ErrDuplicatePlan),
+		errors.Is(err, commissionService.ErrPlanInUse),
+		errors.Is(err, commissionService.ErrAssignmentOverlap),
+		errors.Is(err, commissionService.ErrStatementLocked),
+		errors.Is(err, commissionService.ErrIllegalTransition):
+		helper.ErrorResponse(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, commissionService.ErrPlanInactive),
+		errors.Is(err, commissionService.ErrAssignmentEndDate),
+		errors.Is(err, commissionService.ErrSalesRepNotFound),
+		errors.Is(err, commissionService.ErrNoCommissionForRep):
+		helper.BadRequestResponse(w, r, err)
+	default:
+		helper.ServerErrorResponse(w, r, err)
+	}
+}
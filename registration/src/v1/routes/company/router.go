@@ -0,0 +1,380 @@
+package company
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/anas-dev-92/FoodHive/core/auth"
+	"github.com/anas-dev-92/FoodHive/core/jwt"
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	authMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/auth"
+	companyMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/company"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	companyService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/company"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
+	"github.com/go-chi/chi/v5"
+)
+
+func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.AuthService) chi.Router {
+	app := chi.NewRouter()
+
+	// Inject company service
+	app.Use(companyMiddleware.New(db))
+
+	// Apply authentication
+	app.Use(authMiddleware.Authenticate(jwtService))
+
+	// ===========================================
+	// Company Routes
+	// ===========================================
+	// Every user may see the companies they can switch to
+	app.Get("/mine", handleListMine())
+
+	app.With(authMiddleware.Authorize(jwtService)).Post("/", handleCreate())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/", handleList())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/{id}", handleGetByID())
+	app.With(authMiddleware.Authorize(jwtService)).Put("/{id}", handleUpdate())
+	app.With(authMiddleware.Authorize(jwtService)).Put("/employees/{employeeId}", handleSetEmployeeCompanies())
+
+	// ===========================================
+	// Intercompany Routes
+	// ===========================================
+	app.Route("/intercompany", func(r chi.Router) {
+		r.With(authMiddleware.Authorize(jwtService)).Post("/partners", handleCreatePartner())
+		r.With(authMiddleware.Authorize(jwtService)).Get("/partners", handleListPartners())
+		r.With(authMiddleware.Authorize(jwtService)).Get("/orders", handleListIntercompanyOrders())
+		r.With(authMiddleware.Authorize(jwtService)).Post("/sales-orders/{id}/pair", handlePairSalesOrder())
+		r.With(authMiddleware.Authorize(jwtService)).Post("/purchase-orders/{id}/pair", handlePairPurchaseOrder())
+	})
+
+	return app
+}
+
+// ===========================================
+// Company Handlers
+// ===========================================
+
+func handleCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := companyMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.CreateCompanyRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateCompany(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		id, err := svc.Create(r.Context(), &req)
+		if err != nil {
+			if errors.Is(err, companyService.ErrDuplicateCode) {
+				helper.BadRequestResponse(w, r, err)
+			} else {
+				helper.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		helper.CreatedResponse(w, r, id, "Company created successfully")
+	}
+}
+
+func handleList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := companyMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		companies, err := svc.List(r.Context())
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, companies)
+	}
+}
+
+func handleListMine() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := companyMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		userID, _ := authMiddleware.GetUserID(r.Context())
+		companies, err := svc.ListForEmployee(r.Context(), userID)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{
+			"current_company_id": authMiddleware.GetCompanyID(r.Context()),
+			"companies":          companies,
+		})
+	}
+}
+
+func handleGetByID() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := companyMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid company ID"))
+			return
+		}
+
+		company, err := svc.GetByID(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, companyService.ErrNotFound) {
+				helper.NotFoundResponse(w, r)
+			} else {
+				helper.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, company)
+	}
+}
+
+func handleUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := companyMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid company ID"))
+			return
+		}
+
+		var req models.UpdateCompanyRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		if req.BaseCurrency != nil {
+			v.Check(len(*req.BaseCurrency) == 3, "base_currency", "Currency must be a 3-letter code")
+		}
+		if req.CreditHoldOverdueDays != nil {
+			v.Check(*req.CreditHoldOverdueDays >= 0, "credit_hold_overdue_days", "Overdue days cannot be negative")
+		}
+		if req.StandingOrderDaysAhead != nil {
+			v.Check(*req.StandingOrderDaysAhead >= 0 && *req.StandingOrderDaysAhead <= 30, "standing_order_days_ahead", "Days ahead must be between 0 and 30")
+		}
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = svc.Update(r.Context(), id, &req)
+		if err != nil {
+			if errors.Is(err, companyService.ErrNotFound) {
+				helper.NotFoundResponse(w, r)
+			} else if errors.Is(err, companyService.ErrFreightAccount) {
+				helper.BadRequestResponse(w, r, err)
+			} else {
+				helper.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Company updated successfully"})
+	}
+}
+
+func handleSetEmployeeCompanies() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := companyMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		employeeID, err := strconv.Atoi(chi.URLParam(r, "employeeId"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid employee ID"))
+			return
+		}
+
+		var req models.SetEmployeeCompaniesRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateEmployeeCompanies(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		if err := svc.SetEmployeeCompanies(r.Context(), employeeID, &req); err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Employee companies updated successfully"})
+	}
+}
+
+// ===========================================
+// Intercompany Handlers
+// ===========================================
+
+func handleCreatePartner() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := companyMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.CreateIntercompanyPartnerRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateIntercompanyPartner(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		id, err := svc.CreatePartner(r.Context(), &req)
+		if err != nil {
+			writeIntercompanyError(w, r, err)
+			return
+		}
+
+		helper.CreatedResponse(w, r, id, "Intercompany partner saved successfully")
+	}
+}
+
+func handleListPartners() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := companyMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		partners, err := svc.ListPartners(r.Context())
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, partners)
+	}
+}
+
+func handleListIntercompanyOrders() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := companyMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		orders, err := svc.ListIntercompanyOrders(r.Context())
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, orders)
+	}
+}
+
+func handlePairSalesOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := companyMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid sales order ID"))
+			return
+		}
+
+		userID, _ := authMiddleware.GetUserID(r.Context())
+		pairID, err := svc.PairSalesOrder(r.Context(), id, userID)
+		if err != nil {
+			writeIntercompanyError(w, r, err)
+			return
+		}
+
+		helper.CreatedResponse(w, r, pairID, "Intercompany purchase order created successfully")
+	}
+}
+
+func handlePairPurchaseOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := companyMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid purchase order ID"))
+			return
+		}
+
+		userID, _ := authMiddleware.GetUserID(r.Context())
+		pairID, err := svc.PairPurchaseOrder(r.Context(), id, userID)
+		if err != nil {
+			writeIntercompanyError(w, r, err)
+			return
+		}
+
+		helper.CreatedResponse(w, r, pairID, "Intercompany sales order created successfully")
+	}
+}
+
+func writeIntercompanyError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, companyService.ErrNotFound), errors.Is(err, companyService.ErrOrderNotFound):
+		helper.NotFoundResponse(w, r)
+	case errors.Is(err, companyService.ErrAlreadyPaired):
+		helper.ErrorResponse(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, companyService.ErrNotIntercompany), errors.Is(err, companyService.ErrPartnerNotConfigured):
+		helper.BadRequestResponse(w, r, err)
+	default:
+		helper.ServerErrorResponse(w, r, err)
+	}
+}
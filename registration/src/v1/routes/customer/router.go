@@ -11,7 +11,10 @@ import (
 	authMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/auth"
 	customerMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/customer"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	customerService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/customer"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/i18n"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/query"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -59,6 +62,8 @@ func handleCreate() http.HandlerFunc {
 		v := helper.New()
 		v.Check(req.CustomerCode != "", "customer_code", "Customer code is required")
 		v.Check(req.Name != "", "name", "Name is required")
+		v.Check(req.DocumentLanguage == "" || i18n.Valid(req.DocumentLanguage), "document_language", "Document language must be en, lo or th")
+		v.Check(req.BackorderPolicy == "" || models.ValidBackorderPolicy(req.BackorderPolicy), "backorder_policy", "Backorder policy must be SAME_ORDER, NEW_ORDER or NONE")
 		if !v.Valid() {
 			helper.FailedValidationResponse(w, r, v.Errors)
 			return
@@ -145,6 +150,18 @@ func handleUpdate() http.HandlerFunc {
 			return
 		}
 
+		v := models.NewValidator()
+		if req.DocumentLanguage != nil {
+			models.ValidateDocumentLanguage(v, *req.DocumentLanguage)
+		}
+		if req.BackorderPolicy != nil {
+			v.Check(models.ValidBackorderPolicy(*req.BackorderPolicy), "backorder_policy", "Backorder policy must be SAME_ORDER, NEW_ORDER or NONE")
+		}
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
 		err = svc.Update(r.Context(), id, req)
 		if err != nil {
 			helper.ServerErrorResponse(w, r, err)
@@ -208,26 +225,20 @@ func handleList() http.HandlerFunc {
 			filters.IsActive = &active
 		}
 
-		customers, total, err := svc.List(r.Context(), filters)
+		params, err := query.Parse(r.URL.Query())
 		if err != nil {
-			helper.ServerErrorResponse(w, r, err)
+			helper.BadRequestResponse(w, r, err)
 			return
 		}
+		filters.Query = params
 
-		totalPages := int(total) / filters.PageSize
-		if int(total)%filters.PageSize > 0 {
-			totalPages++
+		customers, total, err := svc.List(r.Context(), filters)
+		if err != nil {
+			helper.ListErrorResponse(w, r, err)
+			return
 		}
 
-		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{
-			"data": customers,
-			"pagination": helper.Envelope{
-				"page":        filters.Page,
-				"page_size":   filters.PageSize,
-				"total_items": total,
-				"total_pages": totalPages,
-			},
-		})
+		helper.ListResponse(w, r, customers, total, params)
 	}
 }
 
@@ -246,6 +257,10 @@ func handleGetOrderGuide() http.HandlerFunc {
 		}
 
 		guides, err := svc.GetOrderGuide(r.Context(), customerID)
+		if errors.Is(err, customerService.ErrNotFound) {
+			helper.NotFoundResponse(w, r)
+			return
+		}
 		if err != nil {
 			helper.ServerErrorResponse(w, r, err)
 			return
@@ -285,6 +300,10 @@ func handleAddShipTo() http.HandlerFunc {
 		}
 
 		id, err := svc.AddShipTo(r.Context(), customerID, shipTo)
+		if errors.Is(err, customerService.ErrNotFound) {
+			helper.NotFoundResponse(w, r)
+			return
+		}
 		if err != nil {
 			helper.ServerErrorResponse(w, r, err)
 			return
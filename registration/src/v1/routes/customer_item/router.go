@@ -0,0 +1,346 @@
+package customer_item
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/anas-dev-92/FoodHive/core/auth"
+	"github.com/anas-dev-92/FoodHive/core/jwt"
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	authMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/auth"
+	customerItemMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/customer_item"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	customerItemService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/customer_item"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
+	"github.com/go-chi/chi/v5"
+)
+
+func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.AuthService) chi.Router {
+	app := chi.NewRouter()
+
+	// Inject customer item service
+	app.Use(customerItemMiddleware.New(db))
+
+	// Apply authentication
+	app.Use(authMiddleware.Authenticate(jwtService))
+
+	// ===========================================
+	// Mapping Queue Routes
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Get("/unmapped", handleListUnmapped())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/unmapped/{id}/map", handleMapUnmapped())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/unmapped/{id}/ignore", handleIgnoreUnmapped())
+
+	// ===========================================
+	// Customer Item Code Routes
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Post("/", handleCreate())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/", handleList())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/resolve", handleResolve())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/{id}", handleGet())
+	app.With(authMiddleware.Authorize(jwtService)).Put("/{id}", handleUpdate())
+	app.With(authMiddleware.Authorize(jwtService)).Delete("/{id}", handleDelete())
+
+	return app
+}
+
+// ===========================================
+// Customer Item Code Handlers
+// ===========================================
+
+func handleCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := customerItemMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.CreateCustomerItemCodeRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateCustomerItemCode(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		createdBy, _ := authMiddleware.GetUserID(r.Context())
+
+		id, err := svc.Create(r.Context(), &req, createdBy)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.CreatedResponse(w, r, id, "Customer item code created successfully")
+	}
+}
+
+func handleList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := customerItemMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		query := r.URL.Query()
+		filters := models.CustomerItemCodeFilters{Search: query.Get("search")}
+		if cid, err := strconv.Atoi(query.Get("customer_id")); err == nil {
+			filters.CustomerID = &cid
+		}
+		if gid, err := strconv.Atoi(query.Get("customer_group_id")); err == nil {
+			filters.CustomerGroupID = &gid
+		}
+		if pid, err := strconv.Atoi(query.Get("product_id")); err == nil {
+			filters.ProductID = &pid
+		}
+
+		codes, err := svc.List(r.Context(), &filters)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, codes)
+	}
+}
+
+// handleResolve shows which product a customer's code resolves to, the
+// same way order entry would.
+func handleResolve() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := customerItemMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		query := r.URL.Query()
+		customerID, err := strconv.Atoi(query.Get("customer_id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("customer_id is required"))
+			return
+		}
+		code := query.Get("code")
+		if code == "" {
+			helper.BadRequestResponse(w, r, errors.New("code is required"))
+			return
+		}
+
+		mapping, err := svc.Resolve(r.Context(), customerID, code, query.Get("uom"))
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, mapping)
+	}
+}
+
+func handleGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := customerItemMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid customer item code ID"))
+			return
+		}
+
+		mapping, err := svc.GetByID(r.Context(), id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, mapping)
+	}
+}
+
+func handleUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := customerItemMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid customer item code ID"))
+			return
+		}
+
+		var req models.UpdateCustomerItemCodeRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateUpdateCustomerItemCode(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		if err := svc.Update(r.Context(), id, &req); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Customer item code updated successfully"})
+	}
+}
+
+func handleDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := customerItemMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid customer item code ID"))
+			return
+		}
+
+		if err := svc.Delete(r.Context(), id); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Customer item code deleted successfully"})
+	}
+}
+
+// ===========================================
+// Mapping Queue Handlers
+// ===========================================
+
+func handleListUnmapped() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := customerItemMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		query := r.URL.Query()
+		var filters models.UnmappedCodeFilters
+		if cid, err := strconv.Atoi(query.Get("customer_id")); err == nil {
+			filters.CustomerID = &cid
+		}
+		if status := query.Get("status"); status != "" {
+			s := models.UnmappedCodeStatus(status)
+			if !models.ValidUnmappedCodeStatus(s) {
+				helper.BadRequestResponse(w, r, errors.New("status must be OPEN, MAPPED or IGNORED"))
+				return
+			}
+			filters.Status = &s
+		}
+
+		queue, err := svc.ListUnmapped(r.Context(), &filters)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, queue)
+	}
+}
+
+func handleMapUnmapped() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := customerItemMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid unmapped code ID"))
+			return
+		}
+
+		var req models.MapCustomerCodeRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateMapCustomerCode(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		userID, _ := authMiddleware.GetUserID(r.Context())
+
+		mappingID, err := svc.MapUnmapped(r.Context(), id, &req, userID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.CreatedResponse(w, r, mappingID, "Customer item code mapped successfully")
+	}
+}
+
+func handleIgnoreUnmapped() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := customerItemMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid unmapped code ID"))
+			return
+		}
+
+		userID, _ := authMiddleware.GetUserID(r.Context())
+
+		if err := svc.IgnoreUnmapped(r.Context(), id, userID); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Customer item code ignored"})
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, customerItemService.ErrNotFound),
+		errors.Is(err, customerItemService.ErrUnknownCode),
+		errors.Is(err, customerItemService.ErrQueueItemNotFound):
+		helper.NotFoundResponse(w, r)
+	case errors.Is(err, customerItemService.ErrDuplicateCode),
+		errors.Is(err, customerItemService.ErrQueueItemResolved):
+		helper.ErrorResponse(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, customerItemService.ErrCustomerNotFound),
+		errors.Is(err, customerItemService.ErrProductNotFound),
+		errors.Is(err, customerItemService.ErrNoCustomerGroup):
+		helper.BadRequestResponse(w, r, err)
+	default:
+		helper.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,316 @@
+package delivery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+
+	"github.com/anas-dev-92/FoodHive/core/auth"
+	"github.com/anas-dev-92/FoodHive/core/jwt"
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	authMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/auth"
+	deliveryMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/delivery"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	catchWeightService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/catch_weight"
+	deliveryService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/delivery"
+	glService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/gl"
+	rmaService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/rma"
+	soService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/sales_order"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxDeliverySize limits a delivery upload, signature and photos included,
+// to 25MB.
+const maxDeliverySize = 25 << 20
+
+// Router records proof of delivery for shipped orders. The delivery service
+// is injected globally because it needs the storage config.
+func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.AuthService) chi.Router {
+	app := chi.NewRouter()
+
+	// Apply authentication
+	app.Use(authMiddleware.Authenticate(jwtService))
+
+	// ===========================================
+	// Confirmation
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Post("/orders/{orderId}/confirm", handleConfirmOrder())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/stops/{stopId}/confirm", handleConfirmStop())
+
+	// ===========================================
+	// Retrieval
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Get("/orders/{orderId}", handleGetByOrder())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/{id}", handleGet())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/{id}/signature", handleSignature())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/{id}/photos/{photoId}", handlePhoto())
+
+	return app
+}
+
+// ===========================================
+// Confirmation Handlers
+// ===========================================
+
+func handleConfirmOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := deliveryMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		orderID, err := strconv.Atoi(chi.URLParam(r, "orderId"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid order ID"))
+			return
+		}
+
+		var req models.ConfirmDeliveryRequest
+		if err := readDelivery(w, r, &req, &req.DeliveryEvidence); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateConfirmDelivery(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		confirmedBy, _ := authMiddleware.GetUserID(r.Context())
+
+		pod, err := svc.ConfirmOrder(r.Context(), orderID, &req, confirmedBy)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusCreated, pod)
+	}
+}
+
+func handleConfirmStop() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := deliveryMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		stopID, err := strconv.Atoi(chi.URLParam(r, "stopId"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid stop ID"))
+			return
+		}
+
+		var req models.ConfirmStopDeliveryRequest
+		if err := readDelivery(w, r, &req, &req.DeliveryEvidence); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateConfirmStopDelivery(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		confirmedBy, _ := authMiddleware.GetUserID(r.Context())
+
+		pods, err := svc.ConfirmStop(r.Context(), stopID, &req, confirmedBy)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusCreated, pods)
+	}
+}
+
+// readDelivery reads a multipart delivery: the request as JSON in the
+// 'data' field, the signature in the 'signature' file and any number of
+// 'photos' files.
+func readDelivery(w http.ResponseWriter, r *http.Request, dst any, evidence *models.DeliveryEvidence) error {
+	r.Body = http.MaxBytesReader(w, r.Body, maxDeliverySize+1024)
+	if err := r.ParseMultipartForm(maxDeliverySize); err != nil {
+		return errors.New("delivery must be sent as a multipart form with a 'data' field, a 'signature' file and 'photos' files, at most 25MB")
+	}
+
+	if data := r.FormValue("data"); data != "" {
+		if err := json.Unmarshal([]byte(data), dst); err != nil {
+			return fmt.Errorf("data contains badly-formed JSON: %v", err)
+		}
+	}
+
+	var err error
+	if files := r.MultipartForm.File["signature"]; len(files) > 0 {
+		if evidence.Signature, err = readFile(files[0]); err != nil {
+			return err
+		}
+	}
+	for _, header := range r.MultipartForm.File["photos"] {
+		photo, err := readFile(header)
+		if err != nil {
+			return err
+		}
+		evidence.Photos = append(evidence.Photos, photo)
+	}
+	return nil
+}
+
+func readFile(header *multipart.FileHeader) ([]byte, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// ===========================================
+// Retrieval Handlers
+// ===========================================
+
+func handleGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := deliveryMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid proof of delivery ID"))
+			return
+		}
+
+		pod, err := svc.GetByID(r.Context(), id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, pod)
+	}
+}
+
+func handleGetByOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := deliveryMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		orderID, err := strconv.Atoi(chi.URLParam(r, "orderId"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid order ID"))
+			return
+		}
+
+		pod, err := svc.GetByOrder(r.Context(), orderID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, pod)
+	}
+}
+
+func handleSignature() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := deliveryMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid proof of delivery ID"))
+			return
+		}
+
+		content, err := svc.Signature(r.Context(), id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		writeImage(w, r, fmt.Sprintf("pod-%d-signature", id), content)
+	}
+}
+
+func handlePhoto() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := deliveryMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid proof of delivery ID"))
+			return
+		}
+		photoID, err := strconv.Atoi(chi.URLParam(r, "photoId"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid photo ID"))
+			return
+		}
+
+		content, err := svc.Photo(r.Context(), id, photoID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		writeImage(w, r, fmt.Sprintf("pod-%d-photo-%d", id, photoID), content)
+	}
+}
+
+func writeImage(w http.ResponseWriter, r *http.Request, name string, content []byte) {
+	contentType := http.DetectContentType(content)
+	ext := ".png"
+	if contentType == "image/jpeg" {
+		ext = ".jpg"
+	}
+	helper.FileResponse(w, r, name+ext, contentType, content)
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, deliveryService.ErrNotFound),
+		errors.Is(err, deliveryService.ErrPhotoNotFound),
+		errors.Is(err, deliveryService.ErrStopNotFound),
+		errors.Is(err, soService.ErrOrderNotFound):
+		helper.NotFoundResponse(w, r)
+	case errors.Is(err, soService.ErrIllegalTransition),
+		errors.Is(err, deliveryService.ErrNothingAtStop),
+		errors.Is(err, rmaService.ErrExceedsReturnable):
+		helper.ErrorResponse(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, deliveryService.ErrOrderNotAtStop),
+		errors.Is(err, deliveryService.ErrOrderLineNotFound),
+		errors.Is(err, deliveryService.ErrExceedsShipped),
+		errors.Is(err, deliveryService.ErrInvalidImage),
+		errors.Is(err, catchWeightService.ErrNotCatchWeight),
+		errors.Is(err, glService.ErrAccountNotFound),
+		errors.Is(err, glService.ErrPeriodClosed):
+		helper.BadRequestResponse(w, r, err)
+	case errors.Is(err, deliveryService.ErrNoStorage):
+		helper.ErrorResponse(w, r, http.StatusServiceUnavailable, err.Error())
+	default:
+		helper.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,187 @@
+package document
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/anas-dev-92/FoodHive/core/auth"
+	"github.com/anas-dev-92/FoodHive/core/jwt"
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	authMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/auth"
+	documentMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/document"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	documentService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/document"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxLogoSize limits logo uploads to 2MB.
+const maxLogoSize = 2 << 20
+
+// Router exposes document branding and print history. The document service
+// itself is injected globally because it needs the storage and font config.
+func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.AuthService) chi.Router {
+	app := chi.NewRouter()
+
+	// Apply authentication
+	app.Use(authMiddleware.Authenticate(jwtService))
+
+	// ===========================================
+	// Company Branding
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Get("/company-profile", handleGetCompanyProfile())
+	app.With(authMiddleware.Authorize(jwtService)).Put("/company-profile", handleUpdateCompanyProfile())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/company-profile/logo", handleUploadLogo())
+
+	// ===========================================
+	// Print History
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Get("/prints/{type}/{id}", handleListPrints())
+
+	return app
+}
+
+// ===========================================
+// Company Branding Handlers
+// ===========================================
+
+func handleGetCompanyProfile() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := documentMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		profile, err := svc.GetCompanyProfile(r.Context())
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, profile)
+	}
+}
+
+func handleUpdateCompanyProfile() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := documentMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.UpdateCompanyProfileRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateCompanyProfile(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		userID, _ := authMiddleware.GetUserID(r.Context())
+
+		if err := svc.UpdateCompanyProfile(r.Context(), &req, userID); err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Company profile updated successfully"})
+	}
+}
+
+func handleUploadLogo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := documentMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxLogoSize+1024)
+		if err := r.ParseMultipartForm(maxLogoSize); err != nil {
+			helper.BadRequestResponse(w, r, errors.New("logo must be sent as multipart form field 'file' and be at most 2MB"))
+			return
+		}
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("file is required"))
+			return
+		}
+		defer file.Close()
+
+		content, err := io.ReadAll(file)
+		if err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		userID, _ := authMiddleware.GetUserID(r.Context())
+		fileName := strings.ReplaceAll(filepath.Base(header.Filename), " ", "_")
+
+		err = svc.UploadLogo(r.Context(), fileName, content, userID)
+		if err != nil {
+			switch {
+			case errors.Is(err, documentService.ErrInvalidLogo):
+				helper.BadRequestResponse(w, r, err)
+			case errors.Is(err, documentService.ErrNoStorage):
+				helper.ErrorResponse(w, r, http.StatusServiceUnavailable, err.Error())
+			default:
+				helper.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Logo uploaded successfully"})
+	}
+}
+
+// ===========================================
+// Print History Handlers
+// ===========================================
+
+func handleListPrints() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := documentMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		docType := models.DocumentType(strings.ToUpper(chi.URLParam(r, "type")))
+		v := helper.New()
+		v.Check(helper.PermittedValue(docType,
+			models.DocumentTypeInvoice, models.DocumentTypeDeliveryNote, models.DocumentTypePurchaseOrder,
+			models.DocumentTypePickList, models.DocumentTypeStatement, models.DocumentTypePaymentVoucher,
+			models.DocumentTypePayslip,
+		), "type", "unknown document type")
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid document ID"))
+			return
+		}
+
+		prints, err := svc.ListPrints(r.Context(), docType, id)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, prints)
+	}
+}
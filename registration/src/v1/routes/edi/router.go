@@ -0,0 +1,510 @@
+package edi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/anas-dev-92/FoodHive/core/auth"
+	"github.com/anas-dev-92/FoodHive/core/jwt"
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	authMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/auth"
+	ediMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/edi"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	ediService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/edi"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
+	"github.com/go-chi/chi/v5"
+)
+
+func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.AuthService) chi.Router {
+	app := chi.NewRouter()
+
+	// Apply authentication
+	app.Use(authMiddleware.Authenticate(jwtService))
+
+	// ===========================================
+	// Partner Routes
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Post("/partners", handleCreatePartner())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/partners", handleListPartners())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/partners/{id}", handleGetPartner())
+	app.With(authMiddleware.Authorize(jwtService)).Put("/partners/{id}", handleUpdatePartner())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/partners/{id}/ship-to", handleAddShipToMapping())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/partners/{id}/ship-to", handleListShipToMappings())
+	app.With(authMiddleware.Authorize(jwtService)).Delete("/ship-to/{id}", handleDeleteShipToMapping())
+
+	// ===========================================
+	// Inbound Routes
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Post("/inbound", handleReceive())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/inbound/poll", handlePoll())
+
+	// ===========================================
+	// Interchange Log Routes
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Get("/interchanges", handleListInterchanges())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/interchanges/{id}", handleGetInterchange())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/interchanges/{id}/reprocess", handleReprocess())
+
+	// ===========================================
+	// Outbound Routes
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Post("/orders/{id}/acknowledgement", handleSendAcknowledgement())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/orders/{id}/ship-notice", handleSendShipNotice())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/invoices/{id}", handleSendInvoice())
+
+	return app
+}
+
+// ===========================================
+// Partner Handlers
+// ===========================================
+
+func handleCreatePartner() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := ediMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.CreateEDIPartnerRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateEDIPartner(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		createdBy, _ := authMiddleware.GetUserID(r.Context())
+
+		id, err := svc.CreatePartner(r.Context(), &req, createdBy)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.CreatedResponse(w, r, id, "EDI partner created successfully")
+	}
+}
+
+func handleListPartners() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := ediMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		partners, err := svc.ListPartners(r.Context())
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, partners)
+	}
+}
+
+func handleGetPartner() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := ediMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid EDI partner ID"))
+			return
+		}
+
+		partner, err := svc.GetPartner(r.Context(), id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, partner)
+	}
+}
+
+func handleUpdatePartner() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := ediMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid EDI partner ID"))
+			return
+		}
+
+		var req models.UpdateEDIPartnerRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateUpdateEDIPartner(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		if err := svc.UpdatePartner(r.Context(), id, &req); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "EDI partner updated successfully"})
+	}
+}
+
+func handleAddShipToMapping() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := ediMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		partnerID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid EDI partner ID"))
+			return
+		}
+
+		var req models.CreateEDIShipToMappingRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateEDIShipToMapping(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		id, err := svc.AddShipToMapping(r.Context(), partnerID, &req)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.CreatedResponse(w, r, id, "Ship-to mapping created successfully")
+	}
+}
+
+func handleListShipToMappings() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := ediMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		partnerID, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid EDI partner ID"))
+			return
+		}
+
+		mappings, err := svc.ListShipToMappings(r.Context(), partnerID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, mappings)
+	}
+}
+
+func handleDeleteShipToMapping() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := ediMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid ship-to mapping ID"))
+			return
+		}
+
+		if err := svc.DeleteShipToMapping(r.Context(), id); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Ship-to mapping deleted successfully"})
+	}
+}
+
+// ===========================================
+// Inbound Handlers
+// ===========================================
+
+// handleReceive processes a file uploaded by hand, for partners that
+// cannot reach the drop directory or to retry a file received by email.
+func handleReceive() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := ediMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.ReceiveEDIRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateReceiveEDI(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		interchange, err := svc.Receive(r.Context(), &req)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, interchange)
+	}
+}
+
+// handlePoll runs the scheduler's pass over the drop directory now.
+func handlePoll() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := ediMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		result, err := svc.Poll(r.Context())
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, result)
+	}
+}
+
+// ===========================================
+// Interchange Log Handlers
+// ===========================================
+
+func handleListInterchanges() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := ediMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		query := r.URL.Query()
+		filters := models.EDIInterchangeFilters{
+			DateFrom: query.Get("date_from"),
+			DateTo:   query.Get("date_to"),
+		}
+		if pid, err := strconv.Atoi(query.Get("partner_id")); err == nil {
+			filters.PartnerID = &pid
+		}
+		if d := models.EDIDirection(query.Get("direction")); d != "" {
+			if d != models.EDIInbound && d != models.EDIOutbound {
+				helper.BadRequestResponse(w, r, errors.New("direction must be IN or OUT"))
+				return
+			}
+			filters.Direction = &d
+		}
+		if s := models.EDIInterchangeStatus(query.Get("status")); s != "" {
+			if !models.ValidEDIInterchangeStatus(s) {
+				helper.BadRequestResponse(w, r, errors.New("invalid interchange status"))
+				return
+			}
+			filters.Status = &s
+		}
+		if limit, err := strconv.Atoi(query.Get("limit")); err == nil {
+			filters.Limit = limit
+		}
+
+		interchanges, err := svc.ListInterchanges(r.Context(), &filters)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, interchanges)
+	}
+}
+
+func handleGetInterchange() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := ediMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid interchange ID"))
+			return
+		}
+
+		interchange, err := svc.GetInterchange(r.Context(), id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, interchange)
+	}
+}
+
+func handleReprocess() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := ediMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid interchange ID"))
+			return
+		}
+
+		interchange, err := svc.Reprocess(r.Context(), id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, interchange)
+	}
+}
+
+// ===========================================
+// Outbound Handlers
+// ===========================================
+
+func handleSendAcknowledgement() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := ediMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid order ID"))
+			return
+		}
+
+		interchange, err := svc.SendAcknowledgement(r.Context(), id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, interchange)
+	}
+}
+
+func handleSendShipNotice() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := ediMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid order ID"))
+			return
+		}
+
+		interchange, err := svc.SendShipNotice(r.Context(), id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, interchange)
+	}
+}
+
+func handleSendInvoice() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := ediMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid invoice ID"))
+			return
+		}
+
+		interchange, err := svc.SendInvoice(r.Context(), id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, interchange)
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, ediService.ErrPartnerNotFound),
+		errors.Is(err, ediService.ErrMappingNotFound),
+		errors.Is(err, ediService.ErrInterchangeNotFound),
+		errors.Is(err, ediService.ErrOrderNotFound),
+		errors.Is(err, ediService.ErrInvoiceNotFound):
+		helper.NotFoundResponse(w, r)
+	case errors.Is(err, ediService.ErrDuplicatePartner),
+		errors.Is(err, ediService.ErrDuplicateMapping),
+		errors.Is(err, ediService.ErrNotReprocessable),
+		errors.Is(err, ediService.ErrNotShipped),
+		errors.Is(err, ediService.ErrInvoiceNotSendable),
+		errors.Is(err, ediService.ErrNothingToSend):
+		helper.ErrorResponse(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, ediService.ErrCustomerNotFound),
+		errors.Is(err, ediService.ErrX12EnvelopeTooLong),
+		errors.Is(err, ediService.ErrShipToNotFound),
+		errors.Is(err, ediService.ErrNotEDIPartner),
+		errors.Is(err, ediService.ErrNotConfigured):
+		helper.BadRequestResponse(w, r, err)
+	default:
+		helper.ServerErrorResponse(w, r, err)
+	}
+}
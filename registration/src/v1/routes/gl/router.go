@@ -5,12 +5,14 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/anas-dev-92/FoodHive/core/auth"
 	"github.com/anas-dev-92/FoodHive/core/jwt"
 	"github.com/anas-dev-92/FoodHive/core/postgres"
 	authMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/auth"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	companyService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/company"
 	glService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/gl"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
 	"github.com/go-chi/chi/v5"
@@ -20,6 +22,7 @@ func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.Au
 	r := chi.NewRouter()
 
 	service := glService.New(db.(postgres.Connection))
+	companies := companyService.New(db.(postgres.Connection))
 
 	r.Use(authMiddleware.Authenticate(jwtService))
 
@@ -62,6 +65,16 @@ func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.Au
 	r.With(authMiddleware.Authorize(jwtService)).Get("/reports/balance-sheet", handleGetBalanceSheet(service))
 	r.With(authMiddleware.Authorize(jwtService)).Get("/reports/account-activity/{accountId}", handleGetAccountActivity(service))
 
+	// ===== Consolidation =====
+	r.With(authMiddleware.Authorize(jwtService)).Get("/consolidation/trial-balance", handleGetConsolidatedTrialBalance(service, companies))
+	r.With(authMiddleware.Authorize(jwtService)).Get("/consolidation/income-statement", handleGetConsolidatedIncomeStatement(service, companies))
+	r.With(authMiddleware.Authorize(jwtService)).Get("/consolidation/balance-sheet", handleGetConsolidatedBalanceSheet(service, companies))
+	r.With(authMiddleware.Authorize(jwtService)).Post("/consolidation/eliminations", handleCreateEliminationEntry(service, companies))
+	r.With(authMiddleware.Authorize(jwtService)).Post("/consolidation/eliminations/generate", handleGenerateEliminations(service, companies))
+	r.With(authMiddleware.Authorize(jwtService)).Get("/consolidation/eliminations", handleListEliminationEntries(service, companies))
+	r.With(authMiddleware.Authorize(jwtService)).Get("/consolidation/eliminations/{id}", handleGetEliminationEntryByID(service))
+	r.With(authMiddleware.Authorize(jwtService)).Post("/consolidation/eliminations/{id}/void", handleVoidEliminationEntry(service))
+
 	return r
 }
 
@@ -798,3 +811,252 @@ func handleGetAccountActivity(service glService.GLService) http.HandlerFunc {
 		helper.WriteJSON(w, http.StatusOK, helper.Envelope{"account_activity": report}, nil)
 	}
 }
+
+// ============================================
+// Consolidation Handlers
+// ============================================
+
+// consolidationGroup checks that the user may see every requested company.
+// With no companies requested the group is every company the user can access.
+func consolidationGroup(r *http.Request, companies companyService.CompanyService, requested []int) ([]int, error) {
+	userID, _ := authMiddleware.GetUserID(r.Context())
+	available, err := companies.ListForEmployee(r.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := map[int]bool{}
+	var all []int
+	for _, c := range available {
+		allowed[c.CompanyID] = true
+		all = append(all, c.CompanyID)
+	}
+	if len(all) == 0 {
+		// Employees without assignments work in their token company only
+		current := authMiddleware.GetCompanyID(r.Context())
+		allowed[current] = true
+		all = []int{current}
+	}
+
+	if len(requested) == 0 {
+		return all, nil
+	}
+	for _, id := range requested {
+		if !allowed[id] {
+			return nil, companyService.ErrNoCompanyAccess
+		}
+	}
+	return requested, nil
+}
+
+// consolidationFilters reads company_ids (comma separated), date_from and
+// date_to from the query string.
+func consolidationFilters(r *http.Request, companies companyService.CompanyService) (models.ConsolidationFilters, error) {
+	filters := models.ConsolidationFilters{
+		DateFrom: r.URL.Query().Get("date_from"),
+		DateTo:   r.URL.Query().Get("date_to"),
+	}
+	if ids := r.URL.Query().Get("company_ids"); ids != "" {
+		for _, part := range strings.Split(ids, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return filters, errors.New("invalid company_ids")
+			}
+			filters.CompanyIDs = append(filters.CompanyIDs, id)
+		}
+	}
+
+	group, err := consolidationGroup(r, companies, filters.CompanyIDs)
+	if err != nil {
+		return filters, err
+	}
+	filters.CompanyIDs = group
+	return filters, nil
+}
+
+func writeConsolidationError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, companyService.ErrNoCompanyAccess):
+		helper.ForbiddenResponse(w, r)
+	case errors.Is(err, glService.ErrEliminationNotFound):
+		helper.NotFoundResponse(w, r)
+	case errors.Is(err, glService.ErrAccountNotFound),
+		errors.Is(err, glService.ErrUnbalancedEntry),
+		errors.Is(err, glService.ErrNothingToEliminate),
+		errors.Is(err, glService.ErrIntercompanyOutOfBalance):
+		helper.BadRequestResponse(w, r, err)
+	default:
+		helper.ServerErrorResponse(w, r, err)
+	}
+}
+
+func handleGetConsolidatedTrialBalance(service glService.GLService, companies companyService.CompanyService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filters, err := consolidationFilters(r, companies)
+		if err != nil {
+			writeConsolidationError(w, r, err)
+			return
+		}
+
+		report, err := service.GetConsolidatedTrialBalance(r.Context(), filters)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.WriteJSON(w, http.StatusOK, helper.Envelope{"trial_balance": report}, nil)
+	}
+}
+
+func handleGetConsolidatedIncomeStatement(service glService.GLService, companies companyService.CompanyService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filters, err := consolidationFilters(r, companies)
+		if err != nil {
+			writeConsolidationError(w, r, err)
+			return
+		}
+
+		report, err := service.GetConsolidatedIncomeStatement(r.Context(), filters)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.WriteJSON(w, http.StatusOK, helper.Envelope{"income_statement": report}, nil)
+	}
+}
+
+func handleGetConsolidatedBalanceSheet(service glService.GLService, companies companyService.CompanyService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filters, err := consolidationFilters(r, companies)
+		if err != nil {
+			writeConsolidationError(w, r, err)
+			return
+		}
+		if asOf := r.URL.Query().Get("as_of_date"); asOf != "" {
+			filters.DateTo = asOf
+		}
+
+		report, err := service.GetConsolidatedBalanceSheet(r.Context(), filters)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.WriteJSON(w, http.StatusOK, helper.Envelope{"balance_sheet": report}, nil)
+	}
+}
+
+func handleCreateEliminationEntry(service glService.GLService, companies companyService.CompanyService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.CreateEliminationEntryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateEliminationEntry(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		var lineCompanies []int
+		for _, line := range req.Lines {
+			lineCompanies = append(lineCompanies, line.CompanyID)
+		}
+		if _, err := consolidationGroup(r, companies, lineCompanies); err != nil {
+			writeConsolidationError(w, r, err)
+			return
+		}
+
+		userID, _ := authMiddleware.GetUserID(r.Context())
+		id, err := service.CreateEliminationEntry(r.Context(), req, userID)
+		if err != nil {
+			writeConsolidationError(w, r, err)
+			return
+		}
+
+		helper.CreatedResponse(w, r, id, "elimination entry created")
+	}
+}
+
+func handleGenerateEliminations(service glService.GLService, companies companyService.CompanyService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.GenerateEliminationsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		group, err := consolidationGroup(r, companies, req.CompanyIDs)
+		if err != nil {
+			writeConsolidationError(w, r, err)
+			return
+		}
+		req.CompanyIDs = group
+
+		userID, _ := authMiddleware.GetUserID(r.Context())
+		id, err := service.GenerateEliminations(r.Context(), req, userID)
+		if err != nil {
+			writeConsolidationError(w, r, err)
+			return
+		}
+
+		helper.CreatedResponse(w, r, id, "elimination entry generated")
+	}
+}
+
+func handleListEliminationEntries(service glService.GLService, companies companyService.CompanyService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		filters, err := consolidationFilters(r, companies)
+		if err != nil {
+			writeConsolidationError(w, r, err)
+			return
+		}
+
+		entries, err := service.ListEliminationEntries(r.Context(), filters)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.WriteJSON(w, http.StatusOK, helper.Envelope{"elimination_entries": entries}, nil)
+	}
+}
+
+func handleGetEliminationEntryByID(service glService.GLService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid elimination entry ID"))
+			return
+		}
+
+		entry, err := service.GetEliminationEntryByID(r.Context(), id)
+		if err != nil {
+			writeConsolidationError(w, r, err)
+			return
+		}
+
+		helper.WriteJSON(w, http.StatusOK, helper.Envelope{"elimination_entry": entry}, nil)
+	}
+}
+
+func handleVoidEliminationEntry(service glService.GLService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid elimination entry ID"))
+			return
+		}
+
+		if err := service.VoidEliminationEntry(r.Context(), id); err != nil {
+			writeConsolidationError(w, r, err)
+			return
+		}
+
+		helper.WriteJSON(w, http.StatusOK, helper.Envelope{"message": "elimination entry voided"}, nil)
+	}
+}
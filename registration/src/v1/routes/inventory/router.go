@@ -13,6 +13,7 @@ import (
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
 	inventoryService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/inventory"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/query"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -39,6 +40,7 @@ func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.Au
 	// ===========================================
 	app.With(authMiddleware.Authorize(jwtService)).Get("/summary/product/{productId}", handleGetProductSummary())
 	app.With(authMiddleware.Authorize(jwtService)).Get("/expiring", handleGetExpiring())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/availability", handleGetAvailability())
 
 	// ===========================================
 	// Inventory Operations
@@ -47,6 +49,16 @@ func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.Au
 	app.With(authMiddleware.Authorize(jwtService)).Post("/adjust", handleAdjust())
 	app.With(authMiddleware.Authorize(jwtService)).Post("/transfer", handleTransfer())
 
+	// ===========================================
+	// Reservation Routes
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Post("/reservations/create", handleCreateReservation())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/reservations/list", handleListReservations())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/reservations/expiring", handleGetExpiringReservations())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/reservations/get/{id}", handleGetReservation())
+	app.With(authMiddleware.Authorize(jwtService)).Put("/reservations/update/{id}", handleUpdateReservation())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/reservations/{id}/release", handleReleaseReservation())
+
 	// ===========================================
 	// Transaction History
 	// ===========================================
@@ -264,6 +276,45 @@ func handleGetExpiring() http.HandlerFunc {
 	}
 }
 
+// handleGetAvailability shows what a customer, or a sales rep's
+// customers, can order of a product in a warehouse.
+func handleGetAvailability() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := inventoryMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		params := r.URL.Query()
+		var req models.AvailabilityRequest
+		req.ProductID, _ = strconv.Atoi(params.Get("product_id"))
+		req.WarehouseID, _ = strconv.Atoi(params.Get("warehouse_id"))
+		if id, err := strconv.Atoi(params.Get("customer_id")); err == nil {
+			req.CustomerID = &id
+		}
+		if id, err := strconv.Atoi(params.Get("sales_rep_id")); err == nil {
+			req.SalesRepID = &id
+		}
+
+		v := models.NewValidator()
+		v.Check(req.ProductID > 0, "product_id", "Product ID is required")
+		v.Check(req.WarehouseID > 0, "warehouse_id", "Warehouse ID is required")
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		availability, err := svc.GetAvailability(r.Context(), &req)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, availability)
+	}
+}
+
 // ===========================================
 // Operation Handlers
 // ===========================================
@@ -392,17 +443,244 @@ func handleGetTransactions() http.HandlerFunc {
 			warehouseID = &wid
 		}
 
-		limit := 100
-		if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
-			limit = l
+		params, err := query.Parse(r.URL.Query())
+		if err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		transactions, err := svc.GetTransactions(r.Context(), productID, warehouseID, params)
+		if err != nil {
+			helper.ListErrorResponse(w, r, err)
+			return
+		}
+
+		helper.ListResponse(w, r, transactions, 0, params)
+	}
+}
+
+// ===========================================
+// Reservation Handlers
+// ===========================================
+
+func handleCreateReservation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := inventoryMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.CreateReservationRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateCreateReservation(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		userID, _ := authMiddleware.GetUserID(r.Context())
+		id, err := svc.CreateReservation(r.Context(), &req, userID)
+		if err != nil {
+			writeReservationError(w, r, err)
+			return
+		}
+
+		helper.CreatedResponse(w, r, id, "Reservation created successfully")
+	}
+}
+
+func handleListReservations() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := inventoryMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		params := r.URL.Query()
+		filters := &models.ReservationFilters{
+			ReservedForType: models.ReservedForType(params.Get("reserved_for_type")),
+			Status:          models.ReservationStatus(params.Get("status")),
+			Page:            1,
+			PageSize:        20,
+		}
+		if id, err := strconv.Atoi(params.Get("product_id")); err == nil {
+			filters.ProductID = &id
+		}
+		if id, err := strconv.Atoi(params.Get("warehouse_id")); err == nil {
+			filters.WarehouseID = &id
+		}
+		if id, err := strconv.Atoi(params.Get("reserved_for_id")); err == nil {
+			filters.ReservedForID = &id
+		}
+		if page, err := strconv.Atoi(params.Get("page")); err == nil {
+			filters.Page = page
+		}
+		if pageSize, err := strconv.Atoi(params.Get("page_size")); err == nil {
+			filters.PageSize = pageSize
+		}
+
+		if filters.Status != "" && !models.ValidReservationStatus(filters.Status) {
+			helper.BadRequestResponse(w, r, errors.New("invalid reservation status"))
+			return
 		}
 
-		transactions, err := svc.GetTransactions(r.Context(), productID, warehouseID, limit)
+		reservations, total, err := svc.ListReservations(r.Context(), filters)
 		if err != nil {
 			helper.ServerErrorResponse(w, r, err)
 			return
 		}
 
-		helper.SuccessResponse(w, r, http.StatusOK, transactions)
+		totalPages := int(total) / filters.PageSize
+		if int(total)%filters.PageSize > 0 {
+			totalPages++
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{
+			"data": reservations,
+			"pagination": helper.Envelope{
+				"page":        filters.Page,
+				"page_size":   filters.PageSize,
+				"total_items": total,
+				"total_pages": totalPages,
+			},
+		})
+	}
+}
+
+// handleGetExpiringReservations reports reservations about to expire with
+// stock their party has not ordered yet.
+func handleGetExpiringReservations() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := inventoryMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		daysToExpiry := 7
+		if days, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil && days >= 0 {
+			daysToExpiry = days
+		}
+
+		var warehouseID *int
+		if wid, err := strconv.Atoi(r.URL.Query().Get("warehouse_id")); err == nil {
+			warehouseID = &wid
+		}
+
+		reservations, err := svc.GetExpiringReservations(r.Context(), daysToExpiry, warehouseID)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, reservations)
+	}
+}
+
+func handleGetReservation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := inventoryMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid reservation ID"))
+			return
+		}
+
+		reservation, err := svc.GetReservation(r.Context(), id)
+		if err != nil {
+			writeReservationError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, reservation)
+	}
+}
+
+func handleUpdateReservation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := inventoryMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid reservation ID"))
+			return
+		}
+
+		var req models.UpdateReservationRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateUpdateReservation(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		if err := svc.UpdateReservation(r.Context(), id, &req); err != nil {
+			writeReservationError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Reservation updated successfully"})
+	}
+}
+
+func handleReleaseReservation() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := inventoryMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid reservation ID"))
+			return
+		}
+
+		userID, _ := authMiddleware.GetUserID(r.Context())
+		if err := svc.ReleaseReservation(r.Context(), id, userID); err != nil {
+			writeReservationError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Reservation released successfully"})
+	}
+}
+
+func writeReservationError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, inventoryService.ErrReservationNotFound):
+		helper.NotFoundResponse(w, r)
+	case errors.Is(err, inventoryService.ErrReservationClosed),
+		errors.Is(err, inventoryService.ErrBelowConsumed),
+		errors.Is(err, inventoryService.ErrInsufficientStock):
+		helper.ErrorResponse(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, inventoryService.ErrReservedForNotFound),
+		errors.Is(err, inventoryService.ErrProductNotFound),
+		errors.Is(err, inventoryService.ErrWarehouseNotFound):
+		helper.BadRequestResponse(w, r, err)
+	default:
+		helper.ServerErrorResponse(w, r, err)
 	}
 }
@@ -1,23 +1,34 @@
 package login
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 
 	"github.com/anas-dev-92/FoodHive/core/jwt"
 	"github.com/anas-dev-92/FoodHive/core/postgres"
+	authMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/auth"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	companyService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/company"
 	helper "github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
 )
 
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// CompanyID selects the company to work in. The employee's default
+	// company is used when it is omitted.
+	CompanyID *int `json:"company_id,omitempty"`
 }
 
 type LoginResponse struct {
-	Token string      `json:"token"`
-	User  UserDetails `json:"user"`
+	Token     string                   `json:"token"`
+	User      UserDetails              `json:"user"`
+	CompanyID int                      `json:"company_id"`
+	Companies []models.EmployeeCompany `json:"companies"`
 }
 
 type UserDetails struct {
@@ -80,41 +91,31 @@ func Handler(service jwt.JWTService, db postgres.Executor) http.HandlerFunc {
 			return
 		}
 
-		// Fetch routes with permissions
-		rows := db.Query(r.Context(), `
-			SELECT p.route_name, ep.can_create, ep.can_update, ep.can_delete, ep.can_view
-			FROM pages p
-			JOIN emp_page ep ON p.id = ep.page_id
-			WHERE ep.user_id = $1
-		`, id)
-		defer rows.Close()
-
-		var routes []map[string]interface{}
-		for rows.Next() {
-			var routeName string
-			var canCreate, canUpdate, canDelete, canView bool
-			if err := rows.Scan(&routeName, &canCreate, &canUpdate, &canDelete, &canView); err != nil {
-				log.Printf("Error scanning route: %v", err)
+		companies := companyService.New(db.(postgres.Connection))
+		companyID, err := companies.ResolveCompany(r.Context(), id, req.CompanyID)
+		if err != nil {
+			if errors.Is(err, companyService.ErrNoCompanyAccess) {
+				helper.ForbiddenResponse(w, r)
+			} else {
 				helper.ServerErrorResponse(w, r, err)
-				return
 			}
-			routes = append(routes, map[string]interface{}{
-				"route_name": routeName,
-				"can_create": canCreate,
-				"can_update": canUpdate,
-				"can_delete": canDelete,
-				"can_view":   canView,
-			})
+			return
+		}
+		available, err := companies.ListForEmployee(r.Context(), id)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
 		}
 
-		if err := rows.Err(); err != nil {
+		routes, err := loadRoutes(r.Context(), db, id)
+		if err != nil {
 			log.Printf("Error reading routes: %v", err)
 			helper.ServerErrorResponse(w, r, err)
 			return
 		}
 
-		// Generate token with ID, email, role, and routes with permissions
-		token, err := service.GenerateToken(id, email, roleName, routes)
+		// Generate token with ID, email, role, company, and routes with permissions
+		token, err := service.GenerateToken(id, email, roleName, companyID, routes)
 		if err != nil {
 			log.Printf("Error generating token: %v", err)
 			helper.ServerErrorResponse(w, r, err)
@@ -130,9 +131,118 @@ func Handler(service jwt.JWTService, db postgres.Executor) http.HandlerFunc {
 				Name:  englishName,
 				Role:  roleName,
 			},
+			CompanyID: companyID,
+			Companies: available,
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(response)
 	}
 }
+
+// SwitchCompanyHandler issues a new token for another company the user has
+// access to. Permissions are reloaded so the new token is current.
+// @Summary Switch company
+// @Description Re-issues the JWT token for another company of the user
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body models.SwitchCompanyRequest true "Company to switch to"
+// @Success 200 {object} LoginResponse "Company switched"
+// @Failure 403 {string} string "No access to company"
+// @Router /switch-company [post]
+func SwitchCompanyHandler(service jwt.JWTService, db postgres.Executor) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req models.SwitchCompanyRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := helper.New()
+		v.Check(req.CompanyID > 0, "company_id", "must be provided")
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		id, _ := authMiddleware.GetUserID(r.Context())
+		email, _ := authMiddleware.GetEmail(r.Context())
+		roleName, _ := authMiddleware.GetRole(r.Context())
+
+		companies := companyService.New(db.(postgres.Connection))
+		companyID, err := companies.ResolveCompany(r.Context(), id, &req.CompanyID)
+		if err != nil {
+			if errors.Is(err, companyService.ErrNoCompanyAccess) {
+				helper.ForbiddenResponse(w, r)
+			} else {
+				helper.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+		available, err := companies.ListForEmployee(r.Context(), id)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		var englishName string
+		err = db.QueryRow(r.Context(), `SELECT COALESCE(english_name, '') FROM employees WHERE id = $1`, id).Scan(&englishName)
+		if err != nil {
+			helper.UnauthorizedResponse(w, r)
+			return
+		}
+
+		routes, err := loadRoutes(r.Context(), db, id)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		token, err := service.GenerateToken(id, email, roleName, companyID, routes)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.WriteJSON(w, http.StatusOK, helper.Envelope{
+			"token":      token,
+			"user":       UserDetails{ID: id, Email: email, Name: englishName, Role: roleName},
+			"company_id": companyID,
+			"companies":  available,
+		}, nil)
+	}
+}
+
+// loadRoutes reads the pages an employee may use with their permissions, in
+// the form carried by the token.
+func loadRoutes(ctx context.Context, db postgres.Executor, employeeID int) ([]map[string]interface{}, error) {
+	rows := db.Query(ctx, `
+		SELECT p.route_name, ep.can_create, ep.can_update, ep.can_delete, ep.can_view
+		FROM pages p
+		JOIN emp_page ep ON p.id = ep.page_id
+		WHERE ep.user_id = $1
+	`, employeeID)
+	defer rows.Close()
+
+	var routes []map[string]interface{}
+	for rows.Next() {
+		var routeName string
+		var canCreate, canUpdate, canDelete, canView bool
+		if err := rows.Scan(&routeName, &canCreate, &canUpdate, &canDelete, &canView); err != nil {
+			return nil, fmt.Errorf("scanning route: %w", err)
+		}
+		routes = append(routes, map[string]interface{}{
+			"route_name": routeName,
+			"can_create": canCreate,
+			"can_update": canUpdate,
+			"can_delete": canDelete,
+			"can_view":   canView,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading routes: %w", err)
+	}
+
+	return routes, nil
+}
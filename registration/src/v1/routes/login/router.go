@@ -3,15 +3,20 @@ package login
 import (
 	"github.com/anas-dev-92/FoodHive/core/jwt"
 	"github.com/anas-dev-92/FoodHive/core/postgres"
+	authMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/auth"
 	"github.com/go-chi/chi/v5"
 )
 
-// Router creates the login routes (public - no auth middleware)
+// Router creates the login routes (public - no auth middleware, except for
+// switching company which needs a signed-in user)
 func Router(db postgres.Executor, jwtService jwt.JWTService) chi.Router {
 	r := chi.NewRouter()
 
 	// Login endpoint - no authentication required (public)
 	r.Post("/login", Handler(jwtService, db))
 
+	// Switching company needs a valid token but no page permission
+	r.With(authMiddleware.Authenticate(jwtService)).Post("/switch-company", SwitchCompanyHandler(jwtService, db))
+
 	return r
 }
@@ -1,6 +1,7 @@
 package payroll
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -8,7 +9,9 @@ import (
 	"github.com/anas-dev-92/FoodHive/core/jwt"
 	"github.com/anas-dev-92/FoodHive/core/postgres"
 	authMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/auth"
+	documentMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/document"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	documentService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/document"
 	payrollService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/payroll"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
 	"github.com/go-chi/chi/v5"
@@ -34,6 +37,10 @@ func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.Au
 	r.With(authMiddleware.Authorize(jwtService)).Post("/{id}/calculate", handleCalculate(service))
 	r.With(authMiddleware.Authorize(jwtService)).Post("/{id}/approve", handleApprove(service))
 
+	// Payslips
+	r.With(authMiddleware.Authorize(jwtService)).Get("/{id}/payslips/pdf", handlePayslipsPDF())
+	r.With(authMiddleware.Authorize(jwtService)).Get("/{id}/payslips/{employeeId}/pdf", handlePayslipsPDF())
+
 	return r
 }
 
@@ -305,3 +312,44 @@ func handleApprove(service payrollService.PayrollService) http.HandlerFunc {
 		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "payroll approved successfully"})
 	}
 }
+
+func handlePayslipsPDF() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := documentMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil || id < 1 {
+			helper.NotFoundResponse(w, r)
+			return
+		}
+
+		// Without an employee the whole payroll is printed, one page each.
+		var employeeID *int
+		if param := chi.URLParam(r, "employeeId"); param != "" {
+			eid, err := strconv.Atoi(param)
+			if err != nil || eid < 1 {
+				helper.NotFoundResponse(w, r)
+				return
+			}
+			employeeID = &eid
+		}
+
+		printedBy, _ := authMiddleware.GetUserID(r.Context())
+
+		doc, err := svc.RenderPayslips(r.Context(), id, employeeID, printedBy)
+		if err != nil {
+			if errors.Is(err, documentService.ErrNotFound) {
+				helper.NotFoundResponse(w, r)
+			} else {
+				helper.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		helper.FileResponse(w, r, doc.FileName, doc.ContentType, doc.Content)
+	}
+}
@@ -4,13 +4,17 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/anas-dev-92/FoodHive/core/auth"
 	"github.com/anas-dev-92/FoodHive/core/jwt"
 	"github.com/anas-dev-92/FoodHive/core/postgres"
 	authMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/auth"
+	documentMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/document"
 	pickingMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/picking"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	documentService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/document"
+	pickingService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/picking"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
 	"github.com/go-chi/chi/v5"
 )
@@ -41,6 +45,19 @@ func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.Au
 	app.With(authMiddleware.Authorize(jwtService)).Delete("/routes/stops/{stopId}", handleDeleteRouteStop())
 	app.With(authMiddleware.Authorize(jwtService)).Post("/routes/{routeId}/reorder", handleReorderStops())
 
+	// ===========================================
+	// Route Calendars
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Get("/routes/{routeId}/calendar", handleGetRouteCalendar())
+	app.With(authMiddleware.Authorize(jwtService)).Put("/routes/{routeId}/calendar", handleSetRouteCalendar())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/routes/{routeId}/exceptions", handleAddRouteException())
+	app.With(authMiddleware.Authorize(jwtService)).Delete("/routes/exceptions/{id}", handleDeleteRouteException())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/routes/{routeId}/next-run", handleNextRouteRun())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/routes/holidays/create", handleCreateDeliveryHoliday())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/routes/holidays/list", handleListDeliveryHolidays())
+	app.With(authMiddleware.Authorize(jwtService)).Delete("/routes/holidays/{id}", handleDeleteDeliveryHoliday())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/routes/reroute", handleRerouteOrders())
+
 	// ===========================================
 	// Pick Lists
 	// ===========================================
@@ -64,6 +81,11 @@ func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.Au
 	app.With(authMiddleware.Authorize(jwtService)).Get("/master-pick", handleMasterPickReport())
 	app.With(authMiddleware.Authorize(jwtService)).Get("/suggested-picking", handleSuggestedPicking())
 
+	// ===========================================
+	// Printing
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Get("/pick-lists/{id}/pdf", handlePickListPDF())
+
 	return app
 }
 
@@ -332,6 +354,285 @@ func handleReorderStops() http.HandlerFunc {
 	}
 }
 
+// ===========================================
+// Route Calendar Handlers
+// ===========================================
+
+func handleGetRouteCalendar() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := pickingMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		routeID, err := strconv.Atoi(chi.URLParam(r, "routeId"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid route ID"))
+			return
+		}
+
+		calendar, err := svc.GetRouteCalendar(r.Context(), routeID)
+		if err != nil {
+			writeCalendarError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, calendar)
+	}
+}
+
+func handleSetRouteCalendar() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := pickingMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		routeID, err := strconv.Atoi(chi.URLParam(r, "routeId"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid route ID"))
+			return
+		}
+
+		var req models.SetRouteCalendarRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateRouteCalendar(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		if err := svc.SetRouteCalendar(r.Context(), routeID, &req); err != nil {
+			writeCalendarError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Route calendar updated successfully"})
+	}
+}
+
+func handleAddRouteException() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := pickingMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		routeID, err := strconv.Atoi(chi.URLParam(r, "routeId"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid route ID"))
+			return
+		}
+
+		var req models.CreateRouteExceptionRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateRouteException(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		createdBy, _ := authMiddleware.GetUserID(r.Context())
+
+		id, err := svc.AddRouteException(r.Context(), routeID, &req, createdBy)
+		if err != nil {
+			writeCalendarError(w, r, err)
+			return
+		}
+
+		helper.CreatedResponse(w, r, id, "Route exception added successfully")
+	}
+}
+
+func handleDeleteRouteException() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := pickingMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid exception ID"))
+			return
+		}
+
+		if err := svc.DeleteRouteException(r.Context(), id); err != nil {
+			writeCalendarError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Route exception deleted successfully"})
+	}
+}
+
+func handleNextRouteRun() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := pickingMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		routeID, err := strconv.Atoi(chi.URLParam(r, "routeId"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid route ID"))
+			return
+		}
+
+		run, err := svc.NextRouteRun(r.Context(), routeID, time.Now())
+		if err != nil {
+			writeCalendarError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, run)
+	}
+}
+
+func handleCreateDeliveryHoliday() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := pickingMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.CreateDeliveryHolidayRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateDeliveryHoliday(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		id, err := svc.CreateDeliveryHoliday(r.Context(), &req)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.CreatedResponse(w, r, id, "Delivery holiday created successfully")
+	}
+}
+
+func handleListDeliveryHolidays() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := pickingMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var year int
+		if y := r.URL.Query().Get("year"); y != "" {
+			var err error
+			if year, err = strconv.Atoi(y); err != nil {
+				helper.BadRequestResponse(w, r, errors.New("invalid year"))
+				return
+			}
+		}
+
+		holidays, err := svc.ListDeliveryHolidays(r.Context(), year)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, holidays)
+	}
+}
+
+func handleDeleteDeliveryHoliday() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := pickingMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid holiday ID"))
+			return
+		}
+
+		if err := svc.DeleteDeliveryHoliday(r.Context(), id); err != nil {
+			writeCalendarError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Delivery holiday deleted successfully"})
+	}
+}
+
+// handleRerouteOrders moves a route run's open orders in bulk, typically
+// after the run was cancelled.
+func handleRerouteOrders() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := pickingMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.RerouteOrdersRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateRerouteOrders(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		result, err := svc.RerouteOrders(r.Context(), &req)
+		if err != nil {
+			writeCalendarError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, result)
+	}
+}
+
+// writeCalendarError maps route calendar errors to responses.
+func writeCalendarError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, pickingService.ErrRouteNotFound),
+		errors.Is(err, pickingService.ErrExceptionNotFound),
+		errors.Is(err, pickingService.ErrHolidayNotFound):
+		helper.NotFoundResponse(w, r)
+	case errors.Is(err, pickingService.ErrNoRouteRun),
+		errors.Is(err, pickingService.ErrSameRun):
+		helper.ErrorResponse(w, r, http.StatusConflict, err.Error())
+	default:
+		helper.ServerErrorResponse(w, r, err)
+	}
+}
+
 // ===========================================
 // Pick List Handlers
 // ===========================================
@@ -693,3 +994,37 @@ func handleSuggestedPicking() http.HandlerFunc {
 		helper.SuccessResponse(w, r, http.StatusOK, suggestions)
 	}
 }
+
+// ===========================================
+// PDF Handlers
+// ===========================================
+
+func handlePickListPDF() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := documentMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid pick list ID"))
+			return
+		}
+
+		printedBy, _ := authMiddleware.GetUserID(r.Context())
+
+		doc, err := svc.RenderPickList(r.Context(), id, printedBy)
+		if err != nil {
+			if errors.Is(err, documentService.ErrNotFound) {
+				helper.NotFoundResponse(w, r)
+				return
+			}
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.FileResponse(w, r, doc.FileName, doc.ContentType, doc.Content)
+	}
+}
@@ -0,0 +1,505 @@
+package portal
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/anas-dev-92/FoodHive/core/jwt"
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	portalMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/portal"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	portalService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/portal"
+	salesOrderService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/sales_order"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/query"
+	"github.com/go-chi/chi/v5"
+)
+
+// Router creates the customer portal API. Customers sign in with their own
+// accounts; employee tokens are not accepted here, nor portal tokens
+// anywhere else.
+func Router(db postgres.Executor, jwtService jwt.JWTService) chi.Router {
+	app := chi.NewRouter()
+
+	// Inject portal service
+	app.Use(portalMiddleware.New(db))
+
+	// Sign-in (public)
+	app.Post("/auth/login", handleLogin(jwtService))
+
+	app.Group(func(app chi.Router) {
+		app.Use(portalMiddleware.Authenticate(jwtService))
+
+		app.Get("/me", handleMe())
+		app.Get("/ship-tos", handleShipTos())
+
+		// ===========================================
+		// Ordering
+		// ===========================================
+		app.Get("/catalog", handleCatalog())
+		app.Post("/order-guide/suggest", handleSuggestFromGuide())
+		app.Post("/order-guide/submit", handleSubmitFromGuide())
+		app.Post("/orders", handleSubmitOrder())
+		app.Get("/orders", handleListOrders())
+		app.Get("/orders/{id}", handleGetOrder())
+
+		// ===========================================
+		// Account
+		// ===========================================
+		app.Get("/invoices", handleListInvoices())
+		app.Get("/invoices/{id}", handleGetInvoice())
+		app.Get("/statement", handleGetStatement())
+		app.Get("/aging", handleGetAging())
+	})
+
+	return app
+}
+
+// ===========================================
+// Sign-in Handlers
+// ===========================================
+
+func handleLogin(jwtService jwt.JWTService) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := portalMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.PortalLoginRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidatePortalLogin(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		user, err := svc.Login(r.Context(), &req)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		token, err := jwtService.GenerateToken(user.ID, user.Email, models.PortalRole, user.CompanyID, nil)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, models.PortalLoginResponse{Token: token, User: *user})
+	}
+}
+
+func handleMe() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, user, ok := instance(w, r)
+		if !ok {
+			return
+		}
+
+		me, err := svc.GetUser(r.Context(), user.UserID)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, me)
+	}
+}
+
+func handleShipTos() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, user, ok := instance(w, r)
+		if !ok {
+			return
+		}
+
+		shipTos, err := svc.ShipTos(r.Context(), user)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, shipTos)
+	}
+}
+
+// ===========================================
+// Ordering Handlers
+// ===========================================
+
+func handleCatalog() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, user, ok := instance(w, r)
+		if !ok {
+			return
+		}
+
+		q := r.URL.Query()
+		filters := models.PortalCatalogFilters{
+			Search:   q.Get("search"),
+			InStock:  q.Get("in_stock") == "true",
+			Page:     1,
+			PageSize: 50,
+		}
+		if page, err := strconv.Atoi(q.Get("page")); err == nil {
+			filters.Page = page
+		}
+		if pageSize, err := strconv.Atoi(q.Get("page_size")); err == nil {
+			filters.PageSize = pageSize
+		}
+		if shipToID, err := strconv.Atoi(q.Get("ship_to_id")); err == nil {
+			filters.ShipToID = &shipToID
+		}
+		if categoryID, err := strconv.Atoi(q.Get("category_id")); err == nil {
+			filters.CategoryID = &categoryID
+		}
+
+		items, total, err := svc.Catalog(r.Context(), user, &filters)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		totalPages := int(total) / filters.PageSize
+		if int(total)%filters.PageSize > 0 {
+			totalPages++
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{
+			"data": items,
+			"pagination": helper.Envelope{
+				"page":        filters.Page,
+				"page_size":   filters.PageSize,
+				"total_items": total,
+				"total_pages": totalPages,
+			},
+		})
+	}
+}
+
+// handleSuggestFromGuide proposes an order from the customer's order
+// guide without creating it.
+func handleSuggestFromGuide() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, user, ok := instance(w, r)
+		if !ok {
+			return
+		}
+
+		var req models.PortalOrderGuideRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidatePortalOrderGuide(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		draft, err := svc.SuggestFromGuide(r.Context(), user, &req)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, draft)
+	}
+}
+
+func handleSubmitFromGuide() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, user, ok := instance(w, r)
+		if !ok {
+			return
+		}
+
+		var req models.PortalOrderGuideRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidatePortalOrderGuide(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		draft, err := svc.SubmitFromGuide(r.Context(), user, &req)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusCreated, draft)
+	}
+}
+
+func handleSubmitOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, user, ok := instance(w, r)
+		if !ok {
+			return
+		}
+
+		var req models.PortalOrderRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidatePortalOrder(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		id, err := svc.SubmitOrder(r.Context(), user, &req)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.CreatedResponse(w, r, id, "Order submitted for review")
+	}
+}
+
+func handleListOrders() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, user, ok := instance(w, r)
+		if !ok {
+			return
+		}
+
+		q := r.URL.Query()
+		filters := models.PortalOrderFilters{
+			DateFrom: q.Get("date_from"),
+			DateTo:   q.Get("date_to"),
+			Page:     1,
+			PageSize: 20,
+		}
+		if page, err := strconv.Atoi(q.Get("page")); err == nil {
+			filters.Page = page
+		}
+		if pageSize, err := strconv.Atoi(q.Get("page_size")); err == nil {
+			filters.PageSize = pageSize
+		}
+		if shipToID, err := strconv.Atoi(q.Get("ship_to_id")); err == nil {
+			filters.ShipToID = &shipToID
+		}
+		if status := q.Get("status"); status != "" {
+			s := models.OrderStatus(status)
+			filters.Status = &s
+		}
+
+		orders, total, err := svc.ListOrders(r.Context(), user, &filters)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		totalPages := int(total) / filters.PageSize
+		if int(total)%filters.PageSize > 0 {
+			totalPages++
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{
+			"data": orders,
+			"pagination": helper.Envelope{
+				"page":        filters.Page,
+				"page_size":   filters.PageSize,
+				"total_items": total,
+				"total_pages": totalPages,
+			},
+		})
+	}
+}
+
+func handleGetOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, user, ok := instance(w, r)
+		if !ok {
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid order ID"))
+			return
+		}
+
+		order, err := svc.GetOrder(r.Context(), user, id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, order)
+	}
+}
+
+// ===========================================
+// Account Handlers
+// ===========================================
+
+func handleListInvoices() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, user, ok := instance(w, r)
+		if !ok {
+			return
+		}
+
+		filters := models.ARInvoiceListFilters{
+			DateFrom: r.URL.Query().Get("date_from"),
+			DateTo:   r.URL.Query().Get("date_to"),
+			Overdue:  r.URL.Query().Get("overdue") == "true",
+			Page:     1,
+			PageSize: 20,
+		}
+		if page, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil {
+			filters.Page = page
+		}
+		if pageSize, err := strconv.Atoi(r.URL.Query().Get("page_size")); err == nil {
+			filters.PageSize = pageSize
+		}
+		if status := r.URL.Query().Get("status"); status != "" {
+			s := models.ARInvoiceStatus(status)
+			filters.Status = &s
+		}
+
+		params, err := query.Parse(r.URL.Query())
+		if err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+		filters.Query = params
+
+		invoices, total, err := svc.ListInvoices(r.Context(), user, &filters)
+		if err != nil {
+			helper.ListErrorResponse(w, r, err)
+			return
+		}
+
+		helper.ListResponse(w, r, invoices, total, params)
+	}
+}
+
+func handleGetInvoice() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, user, ok := instance(w, r)
+		if !ok {
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid invoice ID"))
+			return
+		}
+
+		invoice, err := svc.GetInvoice(r.Context(), user, id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, invoice)
+	}
+}
+
+func handleGetStatement() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, user, ok := instance(w, r)
+		if !ok {
+			return
+		}
+
+		fromDate := r.URL.Query().Get("from_date")
+		toDate := r.URL.Query().Get("to_date")
+		if fromDate == "" || toDate == "" {
+			helper.BadRequestResponse(w, r, errors.New("from_date and to_date are required"))
+			return
+		}
+
+		statement, err := svc.GetStatement(r.Context(), user, fromDate, toDate)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, statement)
+	}
+}
+
+func handleGetAging() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, user, ok := instance(w, r)
+		if !ok {
+			return
+		}
+
+		aging, err := svc.GetAging(r.Context(), user)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, aging)
+	}
+}
+
+// ===========================================
+// Helpers
+// ===========================================
+
+// instance returns the portal service and the signed-in user, answering
+// the request itself when either is missing.
+func instance(w http.ResponseWriter, r *http.Request) (portalService.PortalService, *models.PortalIdentity, bool) {
+	svc, ok := portalMiddleware.Instance(r.Context())
+	if !ok {
+		helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+		return nil, nil, false
+	}
+	user, ok := portalMiddleware.User(r.Context())
+	if !ok {
+		helper.UnauthorizedResponse(w, r)
+		return nil, nil, false
+	}
+	return svc, user, true
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, portalService.ErrInvalidCredentials):
+		helper.UnauthorizedResponse(w, r)
+	case errors.Is(err, portalService.ErrUserNotFound),
+		errors.Is(err, portalService.ErrOrderNotFound),
+		errors.Is(err, portalService.ErrInvoiceNotFound):
+		helper.NotFoundResponse(w, r)
+	case errors.Is(err, portalService.ErrAccountOnly):
+		helper.ErrorResponse(w, r, http.StatusForbidden, err.Error())
+	case errors.Is(err, portalService.ErrNotOrderable),
+		errors.Is(err, salesOrderService.ErrEmptyDraft):
+		helper.ErrorResponse(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, portalService.ErrShipToNotFound),
+		errors.Is(err, portalService.ErrShipToRequired),
+		errors.Is(err, portalService.ErrNoWarehouse),
+		errors.Is(err, portalService.ErrProductNotFound):
+		helper.BadRequestResponse(w, r, err)
+	default:
+		helper.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,212 @@
+package portal_user
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/anas-dev-92/FoodHive/core/auth"
+	"github.com/anas-dev-92/FoodHive/core/jwt"
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	authMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/auth"
+	portalMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/portal"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	portalService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/portal"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
+	"github.com/go-chi/chi/v5"
+)
+
+// Router creates the routes employees use to manage customers' portal
+// users and review the orders they submit.
+func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.AuthService) chi.Router {
+	app := chi.NewRouter()
+
+	// Inject portal service
+	app.Use(portalMiddleware.New(db))
+
+	// Apply authentication
+	app.Use(authMiddleware.Authenticate(jwtService))
+
+	// ===========================================
+	// Submitted Orders
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Get("/orders", handleListSubmittedOrders())
+
+	// ===========================================
+	// Portal User Routes
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Post("/", handleCreate())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/", handleList())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/{id}", handleGet())
+	app.With(authMiddleware.Authorize(jwtService)).Put("/{id}", handleUpdate())
+
+	return app
+}
+
+// ===========================================
+// Portal User Handlers
+// ===========================================
+
+func handleCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := portalMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.CreatePortalUserRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidatePortalUser(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		createdBy, _ := authMiddleware.GetUserID(r.Context())
+
+		id, err := svc.CreateUser(r.Context(), &req, createdBy)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.CreatedResponse(w, r, id, "Portal user created successfully")
+	}
+}
+
+func handleList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := portalMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		query := r.URL.Query()
+		filters := models.PortalUserFilters{
+			Search:          query.Get("search"),
+			IncludeInactive: query.Get("include_inactive") == "true",
+		}
+		if cid, err := strconv.Atoi(query.Get("customer_id")); err == nil {
+			filters.CustomerID = &cid
+		}
+
+		users, err := svc.ListUsers(r.Context(), &filters)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, users)
+	}
+}
+
+func handleGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := portalMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid portal user ID"))
+			return
+		}
+
+		user, err := svc.GetUser(r.Context(), id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, user)
+	}
+}
+
+func handleUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := portalMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid portal user ID"))
+			return
+		}
+
+		var req models.UpdatePortalUserRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateUpdatePortalUser(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		if err := svc.UpdateUser(r.Context(), id, &req); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Portal user updated successfully"})
+	}
+}
+
+// handleListSubmittedOrders lists orders customers submitted through the
+// portal; by default the drafts waiting for review.
+func handleListSubmittedOrders() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := portalMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		query := r.URL.Query()
+		var filters models.PortalSubmittedOrderFilters
+		if cid, err := strconv.Atoi(query.Get("customer_id")); err == nil {
+			filters.CustomerID = &cid
+		}
+		if status := query.Get("status"); status != "" {
+			s := models.OrderStatus(status)
+			filters.Status = &s
+		}
+
+		orders, err := svc.ListSubmittedOrders(r.Context(), &filters)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, orders)
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, portalService.ErrUserNotFound):
+		helper.NotFoundResponse(w, r)
+	case errors.Is(err, portalService.ErrDuplicateEmail):
+		helper.ErrorResponse(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, portalService.ErrCustomerNotFound),
+		errors.Is(err, portalService.ErrShipToNotFound):
+		helper.BadRequestResponse(w, r, err)
+	default:
+		helper.ServerErrorResponse(w, r, err)
+	}
+}
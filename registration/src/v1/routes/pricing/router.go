@@ -11,6 +11,7 @@ import (
 	authMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/auth"
 	pricingMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/pricing"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	pricingService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/pricing"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
 	"github.com/go-chi/chi/v5"
 )
@@ -77,6 +78,23 @@ func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.Au
 	// ===========================================
 	app.With(authMiddleware.Authorize(jwtService)).Get("/list", handleGetPriceList())
 
+	// ===========================================
+	// Margin Guard
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Post("/margin/check", handleCheckLineMargin())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/margin-rules/create", handleCreateMarginRule())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/margin-rules/list", handleListMarginRules())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/margin-rules/{id}/deactivate", handleDeactivateMarginRule())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/margin-exceptions", handleGetMarginExceptions())
+
+	// ===========================================
+	// Freight Rules
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Post("/freight/calculate", handleCalculateFreight())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/freight-rules/create", handleCreateFreightRule())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/freight-rules/list", handleListFreightRules())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/freight-rules/{id}/deactivate", handleDeactivateFreightRule())
+
 	return app
 }
 
@@ -589,6 +607,7 @@ func handleUpdateProductCost() http.HandlerFunc {
 		v := models.NewValidator()
 		v.Check(req.ProductID > 0, "product_id", "Product is required")
 		v.Check(req.Cost >= 0, "cost", "Cost must be non-negative")
+		v.Check(models.ValidCostingMethod(req.CostingMethod), "costing_method", "Unknown costing method")
 		if !v.Valid() {
 			helper.FailedValidationResponse(w, r, v.Errors)
 			return
@@ -721,3 +740,288 @@ func handleGetPriceList() http.HandlerFunc {
 		})
 	}
 }
+
+// ===========================================
+// Margin Guard Handlers
+// ===========================================
+
+// handleCheckLineMargin runs the same check order entry applies to a line.
+func handleCheckLineMargin() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := pricingMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.MarginCheckRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		if req.ProductID <= 0 {
+			helper.BadRequestResponse(w, r, errors.New("product_id is required"))
+			return
+		}
+
+		check, err := svc.CheckMargin(r.Context(), &req)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, check)
+	}
+}
+
+func handleCreateMarginRule() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := pricingMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.CreateMarginRuleRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateMarginRule(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		createdBy, _ := authMiddleware.GetUserID(r.Context())
+
+		id, err := svc.CreateMarginRule(r.Context(), &req, createdBy)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.CreatedResponse(w, r, id, "Margin rule created successfully")
+	}
+}
+
+func handleListMarginRules() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := pricingMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		activeOnly := r.URL.Query().Get("active_only") == "true"
+
+		rules, err := svc.ListMarginRules(r.Context(), activeOnly)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, rules)
+	}
+}
+
+func handleDeactivateMarginRule() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := pricingMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid margin rule ID"))
+			return
+		}
+
+		err = svc.DeactivateMarginRule(r.Context(), id)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Margin rule deactivated"})
+	}
+}
+
+// handleGetMarginExceptions is the report of lines sold below cost.
+func handleGetMarginExceptions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := pricingMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		filters := models.MarginExceptionFilters{
+			DateFrom: r.URL.Query().Get("date_from"),
+			DateTo:   r.URL.Query().Get("date_to"),
+			Page:     1,
+			PageSize: 50,
+		}
+
+		if page, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil {
+			filters.Page = page
+		}
+		if pageSize, err := strconv.Atoi(r.URL.Query().Get("page_size")); err == nil {
+			filters.PageSize = pageSize
+		}
+		if customerID, err := strconv.Atoi(r.URL.Query().Get("customer_id")); err == nil {
+			filters.CustomerID = &customerID
+		}
+		if salesRepID, err := strconv.Atoi(r.URL.Query().Get("sales_rep_id")); err == nil {
+			filters.SalesRepID = &salesRepID
+		}
+
+		exceptions, total, err := svc.GetMarginExceptions(r.Context(), &filters)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		totalPages := int(total) / filters.PageSize
+		if int(total)%filters.PageSize > 0 {
+			totalPages++
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{
+			"data": exceptions,
+			"pagination": helper.Envelope{
+				"page":        filters.Page,
+				"page_size":   filters.PageSize,
+				"total_items": total,
+				"total_pages": totalPages,
+			},
+		})
+	}
+}
+
+// ===========================================
+// Freight Rule Handlers
+// ===========================================
+
+// handleCalculateFreight prices delivery for an order that is not yet
+// entered, such as a quote over the phone.
+func handleCalculateFreight() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := pricingMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.FreightRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateFreightRequest(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		calc, err := svc.CalculateFreight(r.Context(), &req)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, calc)
+	}
+}
+
+func handleCreateFreightRule() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := pricingMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.CreateFreightRuleRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateFreightRule(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		createdBy, _ := authMiddleware.GetUserID(r.Context())
+
+		id, err := svc.CreateFreightRule(r.Context(), &req, createdBy)
+		if err != nil {
+			if errors.Is(err, pricingService.ErrRouteNotFound) {
+				helper.BadRequestResponse(w, r, err)
+			} else {
+				helper.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		helper.CreatedResponse(w, r, id, "Freight rule created successfully")
+	}
+}
+
+func handleListFreightRules() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := pricingMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		activeOnly := r.URL.Query().Get("active_only") == "true"
+
+		rules, err := svc.ListFreightRules(r.Context(), activeOnly)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, rules)
+	}
+}
+
+func handleDeactivateFreightRule() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := pricingMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid freight rule ID"))
+			return
+		}
+
+		err = svc.DeactivateFreightRule(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, pricingService.ErrFreightRuleNotFound) {
+				helper.NotFoundResponse(w, r)
+			} else {
+				helper.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Freight rule deactivated"})
+	}
+}
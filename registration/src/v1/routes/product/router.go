@@ -13,6 +13,7 @@ import (
 	productMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/product"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/query"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -178,6 +179,16 @@ func handleUpdate() http.HandlerFunc {
 			return
 		}
 
+		v := models.NewValidator()
+		models.ValidateTranslations(v, "name_translations", req.NameTranslations)
+		if req.CostingMethod != nil {
+			v.Check(models.ValidCostingMethod(*req.CostingMethod), "costing_method", "Unknown costing method")
+		}
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
 		log.Printf("Product update request for ID %d: Name=%v, Description=%v, QCRequired=%v, IsActive=%v",
 			id, req.Name, req.Description, req.QCRequired, req.IsActive)
 
@@ -246,26 +257,20 @@ func handleList() http.HandlerFunc {
 			filters.IsActive = &active
 		}
 
-		products, total, err := svc.List(r.Context(), &filters)
+		params, err := query.Parse(r.URL.Query())
 		if err != nil {
-			helper.ServerErrorResponse(w, r, err)
+			helper.BadRequestResponse(w, r, err)
 			return
 		}
+		filters.Query = params
 
-		totalPages := int(total) / filters.PageSize
-		if int(total)%filters.PageSize > 0 {
-			totalPages++
+		products, total, err := svc.List(r.Context(), &filters)
+		if err != nil {
+			helper.ListErrorResponse(w, r, err)
+			return
 		}
 
-		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{
-			"data": products,
-			"pagination": helper.Envelope{
-				"page":        filters.Page,
-				"page_size":   filters.PageSize,
-				"total_items": total,
-				"total_pages": totalPages,
-			},
-		})
+		helper.ListResponse(w, r, products, total, params)
 	}
 }
 
@@ -290,6 +295,7 @@ func handleCreateCategory() http.HandlerFunc {
 		// Validate
 		v := models.NewValidator()
 		v.Check(req.Name != "", "name", "Category name is required")
+		models.ValidateTranslations(v, "name_translations", req.NameTranslations)
 		if !v.Valid() {
 			helper.FailedValidationResponse(w, r, v.Errors)
 			return
@@ -349,6 +355,13 @@ func handleUpdateCategory() http.HandlerFunc {
 			return
 		}
 
+		v := models.NewValidator()
+		models.ValidateTranslations(v, "name_translations", req.NameTranslations)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
 		err = svc.UpdateCategory(r.Context(), id, &req)
 		if err != nil {
 			helper.ServerErrorResponse(w, r, err)
@@ -9,9 +9,13 @@ import (
 	"github.com/anas-dev-92/FoodHive/core/jwt"
 	"github.com/anas-dev-92/FoodHive/core/postgres"
 	authMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/auth"
+	documentMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/document"
 	poMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/purchase_order"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	documentService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/document"
+	poService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/purchase_order"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/query"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -50,6 +54,16 @@ func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.Au
 	app.With(authMiddleware.Authorize(jwtService)).Get("/receiving/{id}", handleGetReceiving())
 	app.With(authMiddleware.Authorize(jwtService)).Get("/receivings", handleListReceivings())
 
+	// ===========================================
+	// Replenishment Routes
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Get("/replenishment", handleReplenishment())
+
+	// ===========================================
+	// Printing
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Get("/{id}/pdf", handlePurchaseOrderPDF())
+
 	return app
 }
 
@@ -81,6 +95,10 @@ func handleCreate() http.HandlerFunc {
 		createdBy := 1 // TODO: Get from auth context
 
 		id, err := svc.Create(r.Context(), &req, createdBy)
+		if errors.Is(err, poService.ErrVendorNotFound) {
+			helper.NotFoundResponse(w, r)
+			return
+		}
 		if err != nil {
 			helper.ServerErrorResponse(w, r, err)
 			return
@@ -222,26 +240,20 @@ func handleList() http.HandlerFunc {
 			filters.BuyerID = &buyerID
 		}
 
-		orders, total, err := svc.List(r.Context(), &filters)
+		params, err := query.Parse(r.URL.Query())
 		if err != nil {
-			helper.ServerErrorResponse(w, r, err)
+			helper.BadRequestResponse(w, r, err)
 			return
 		}
+		filters.Query = params
 
-		totalPages := int(total) / filters.PageSize
-		if int(total)%filters.PageSize > 0 {
-			totalPages++
+		orders, total, err := svc.List(r.Context(), &filters)
+		if err != nil {
+			helper.ListErrorResponse(w, r, err)
+			return
 		}
 
-		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{
-			"data": orders,
-			"pagination": helper.Envelope{
-				"page":        filters.Page,
-				"page_size":   filters.PageSize,
-				"total_items": total,
-				"total_pages": totalPages,
-			},
-		})
+		helper.ListResponse(w, r, orders, total, params)
 	}
 }
 
@@ -327,6 +339,10 @@ func handleAddLine() http.HandlerFunc {
 		}
 
 		id, err := svc.AddLine(r.Context(), poID, &req)
+		if errors.Is(err, poService.ErrPurchaseOrderNotFound) {
+			helper.NotFoundResponse(w, r)
+			return
+		}
 		if err != nil {
 			helper.ServerErrorResponse(w, r, err)
 			return
@@ -357,6 +373,10 @@ func handleUpdateLine() http.HandlerFunc {
 		}
 
 		err = svc.UpdateLine(r.Context(), lineID, &req)
+		if errors.Is(err, poService.ErrLineNotFound) {
+			helper.NotFoundResponse(w, r)
+			return
+		}
 		if err != nil {
 			helper.ServerErrorResponse(w, r, err)
 			return
@@ -381,6 +401,10 @@ func handleDeleteLine() http.HandlerFunc {
 		}
 
 		err = svc.DeleteLine(r.Context(), lineID)
+		if errors.Is(err, poService.ErrLineNotFound) {
+			helper.NotFoundResponse(w, r)
+			return
+		}
 		if err != nil {
 			helper.ServerErrorResponse(w, r, err)
 			return
@@ -418,6 +442,10 @@ func handleCreateReceiving() http.HandlerFunc {
 		receivedBy := 1 // TODO: Get from auth context
 
 		id, err := svc.CreateReceiving(r.Context(), &req, receivedBy)
+		if errors.Is(err, poService.ErrVendorNotFound) || errors.Is(err, poService.ErrPurchaseOrderNotFound) || errors.Is(err, poService.ErrLineNotFound) {
+			helper.NotFoundResponse(w, r)
+			return
+		}
 		if err != nil {
 			helper.ServerErrorResponse(w, r, err)
 			return
@@ -481,3 +509,77 @@ func handleListReceivings() http.HandlerFunc {
 		helper.SuccessResponse(w, r, http.StatusOK, receivings)
 	}
 }
+
+// ===========================================
+// Replenishment Handlers
+// ===========================================
+
+// handleReplenishment lists what a warehouse should reorder, with the
+// lost sales trend for each product.
+func handleReplenishment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := poMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var filters models.ReplenishmentFilters
+		filters.WarehouseID, _ = strconv.Atoi(r.URL.Query().Get("warehouse_id"))
+		if vid, err := strconv.Atoi(r.URL.Query().Get("vendor_id")); err == nil {
+			filters.VendorID = &vid
+		}
+		if weeks, err := strconv.Atoi(r.URL.Query().Get("weeks")); err == nil {
+			filters.Weeks = weeks
+		}
+
+		v := models.NewValidator()
+		models.ValidateReplenishment(v, &filters)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		suggestions, err := svc.GetReplenishmentSuggestions(r.Context(), &filters)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, suggestions)
+	}
+}
+
+// ===========================================
+// PDF Handlers
+// ===========================================
+
+func handlePurchaseOrderPDF() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := documentMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid purchase order ID"))
+			return
+		}
+
+		printedBy, _ := authMiddleware.GetUserID(r.Context())
+
+		doc, err := svc.RenderPurchaseOrder(r.Context(), id, printedBy)
+		if err != nil {
+			if errors.Is(err, documentService.ErrNotFound) {
+				helper.NotFoundResponse(w, r)
+				return
+			}
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.FileResponse(w, r, doc.FileName, doc.ContentType, doc.Content)
+	}
+}
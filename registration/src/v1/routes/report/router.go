@@ -0,0 +1,395 @@
+package report
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/anas-dev-92/FoodHive/core/auth"
+	"github.com/anas-dev-92/FoodHive/core/jwt"
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	authMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/auth"
+	reportMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/report"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	reportService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/report"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
+	"github.com/go-chi/chi/v5"
+)
+
+// Router exposes scheduled report subscriptions and their delivery log.
+func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.AuthService) chi.Router {
+	app := chi.NewRouter()
+
+	// Apply authentication
+	app.Use(authMiddleware.Authenticate(jwtService))
+
+	// ===========================================
+	// Subscriptions
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Get("/subscriptions", handleListSubscriptions())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/subscriptions", handleCreateSubscription())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/subscriptions/{id}", handleGetSubscription())
+	app.With(authMiddleware.Authorize(jwtService)).Put("/subscriptions/{id}", handleUpdateSubscription())
+	app.With(authMiddleware.Authorize(jwtService)).Delete("/subscriptions/{id}", handleDeleteSubscription())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/subscriptions/{id}/preview", handlePreview())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/subscriptions/{id}/send", handleSendNow())
+
+	// ===========================================
+	// Delivery Log
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Get("/deliveries", handleListDeliveries())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/deliveries/{id}", handleGetDelivery())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/deliveries/{id}/retry", handleRetryDelivery())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/process-due", handleProcessDue())
+
+	return app
+}
+
+// ===========================================
+// Subscription Handlers
+// ===========================================
+
+func handleListSubscriptions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := reportMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		subs, err := svc.ListSubscriptions(r.Context())
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, subs)
+	}
+}
+
+func handleCreateSubscription() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := reportMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.CreateReportSubscriptionRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateReportSubscription(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		userID, _ := authMiddleware.GetUserID(r.Context())
+
+		id, err := svc.CreateSubscription(r.Context(), &req, userID)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.CreatedResponse(w, r, id, "Report subscription created successfully")
+	}
+}
+
+func handleGetSubscription() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := reportMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid subscription ID"))
+			return
+		}
+
+		sub, err := svc.GetSubscription(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, reportService.ErrNotFound) {
+				helper.NotFoundResponse(w, r)
+				return
+			}
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, sub)
+	}
+}
+
+func handleUpdateSubscription() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := reportMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid subscription ID"))
+			return
+		}
+
+		var req models.UpdateReportSubscriptionRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		current, err := svc.GetSubscription(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, reportService.ErrNotFound) {
+				helper.NotFoundResponse(w, r)
+				return
+			}
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateReportSubscriptionUpdate(v, &req, current)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		if err := svc.UpdateSubscription(r.Context(), id, &req); err != nil {
+			if errors.Is(err, reportService.ErrNotFound) {
+				helper.NotFoundResponse(w, r)
+				return
+			}
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Report subscription updated successfully"})
+	}
+}
+
+func handleDeleteSubscription() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := reportMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid subscription ID"))
+			return
+		}
+
+		if err := svc.DeleteSubscription(r.Context(), id); err != nil {
+			if errors.Is(err, reportService.ErrNotFound) {
+				helper.NotFoundResponse(w, r)
+				return
+			}
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Report subscription deleted successfully"})
+	}
+}
+
+// handlePreview renders the report without emailing it.
+func handlePreview() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := reportMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid subscription ID"))
+			return
+		}
+
+		file, err := svc.Preview(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, reportService.ErrNotFound) {
+				helper.NotFoundResponse(w, r)
+				return
+			}
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.FileResponse(w, r, file.FileName, file.ContentType, file.Content)
+	}
+}
+
+// handleSendNow emails the report immediately. A failed send is reported in
+// the returned delivery and retried by the scheduler.
+func handleSendNow() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := reportMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid subscription ID"))
+			return
+		}
+
+		delivery, err := svc.SendNow(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, reportService.ErrNotFound) {
+				helper.NotFoundResponse(w, r)
+				return
+			}
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, delivery)
+	}
+}
+
+// ===========================================
+// Delivery Log Handlers
+// ===========================================
+
+func handleListDeliveries() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := reportMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		filters := models.ReportDeliveryFilters{
+			Status:   models.ReportDeliveryStatus(strings.ToUpper(r.URL.Query().Get("status"))),
+			Page:     1,
+			PageSize: 20,
+		}
+		if page, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil {
+			filters.Page = page
+		}
+		if pageSize, err := strconv.Atoi(r.URL.Query().Get("page_size")); err == nil {
+			filters.PageSize = pageSize
+		}
+		if subscriptionID, err := strconv.Atoi(r.URL.Query().Get("subscription_id")); err == nil {
+			filters.SubscriptionID = &subscriptionID
+		}
+
+		deliveries, total, err := svc.ListDeliveries(r.Context(), &filters)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		totalPages := int(total) / filters.PageSize
+		if int(total)%filters.PageSize > 0 {
+			totalPages++
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{
+			"data": deliveries,
+			"pagination": helper.Envelope{
+				"page":        filters.Page,
+				"page_size":   filters.PageSize,
+				"total_items": total,
+				"total_pages": totalPages,
+			},
+		})
+	}
+}
+
+func handleGetDelivery() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := reportMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid delivery ID"))
+			return
+		}
+
+		delivery, err := svc.GetDelivery(r.Context(), id)
+		if err != nil {
+			if errors.Is(err, reportService.ErrDeliveryNotFound) {
+				helper.NotFoundResponse(w, r)
+				return
+			}
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, delivery)
+	}
+}
+
+func handleRetryDelivery() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := reportMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid delivery ID"))
+			return
+		}
+
+		delivery, err := svc.RetryDelivery(r.Context(), id)
+		if err != nil {
+			switch {
+			case errors.Is(err, reportService.ErrDeliveryNotFound):
+				helper.NotFoundResponse(w, r)
+			case errors.Is(err, reportService.ErrNotRetryable):
+				helper.BadRequestResponse(w, r, err)
+			default:
+				helper.ServerErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, delivery)
+	}
+}
+
+// handleProcessDue runs one scheduler pass on demand, e.g. after fixing the
+// SMTP settings.
+func handleProcessDue() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := reportMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		processed, err := svc.ProcessDue(r.Context())
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"processed": processed})
+	}
+}
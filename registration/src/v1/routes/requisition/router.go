@@ -0,0 +1,405 @@
+package requisition
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/anas-dev-92/FoodHive/core/auth"
+	"github.com/anas-dev-92/FoodHive/core/jwt"
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	authMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/auth"
+	requisitionMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/requisition"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	requisitionService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/requisition"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
+	"github.com/go-chi/chi/v5"
+)
+
+func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.AuthService) chi.Router {
+	app := chi.NewRouter()
+
+	// Inject requisition service
+	app.Use(requisitionMiddleware.New(db))
+
+	// Apply authentication
+	app.Use(authMiddleware.Authenticate(jwtService))
+
+	// ===========================================
+	// Requisition Routes
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Post("/create", handleCreate())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/get/{id}", handleGetByID())
+	app.With(authMiddleware.Authorize(jwtService)).Put("/update/{id}", handleUpdate())
+	app.With(authMiddleware.Authorize(jwtService)).Delete("/delete/{id}", handleDelete())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/list", handleList())
+
+	// ===========================================
+	// Approval Routes
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Post("/{id}/submit", handleSubmit())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/{id}/check", handleCheck())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/{id}/authorize", handleAuthorize())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/{id}/reject", handleReject())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/{id}/cancel", handleCancel())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/{id}/convert", handleConvert())
+
+	return app
+}
+
+// ===========================================
+// Requisition Handlers
+// ===========================================
+
+func handleCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := requisitionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.CreateRequisitionRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateRequisition(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		createdBy, _ := authMiddleware.GetUserID(r.Context())
+
+		id, err := svc.Create(r.Context(), &req, createdBy)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.CreatedResponse(w, r, id, "Purchase requisition created successfully")
+	}
+}
+
+func handleGetByID() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := requisitionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid requisition ID"))
+			return
+		}
+
+		requisition, err := svc.GetByID(r.Context(), id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, requisition)
+	}
+}
+
+func handleUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := requisitionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid requisition ID"))
+			return
+		}
+
+		var req models.UpdateRequisitionRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateUpdateRequisition(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		if err := svc.Update(r.Context(), id, &req); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Purchase requisition updated successfully"})
+	}
+}
+
+func handleDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := requisitionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid requisition ID"))
+			return
+		}
+
+		if err := svc.Delete(r.Context(), id); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Purchase requisition deleted successfully"})
+	}
+}
+
+func handleList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := requisitionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var filters models.RequisitionFilters
+		if status := r.URL.Query().Get("status"); status != "" {
+			s := models.RequisitionStatus(status)
+			filters.Status = &s
+		}
+		if rid, err := strconv.Atoi(r.URL.Query().Get("requester_id")); err == nil {
+			filters.RequesterID = &rid
+		}
+		if oid, err := strconv.Atoi(r.URL.Query().Get("order_id")); err == nil {
+			filters.OrderID = &oid
+		}
+
+		requisitions, err := svc.List(r.Context(), &filters)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, requisitions)
+	}
+}
+
+// ===========================================
+// Approval Handlers
+// ===========================================
+
+func handleSubmit() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := requisitionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid requisition ID"))
+			return
+		}
+
+		if err := svc.Submit(r.Context(), id); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Purchase requisition submitted"})
+	}
+}
+
+func handleCheck() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := requisitionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid requisition ID"))
+			return
+		}
+
+		checkedBy, _ := authMiddleware.GetUserID(r.Context())
+
+		if err := svc.Check(r.Context(), id, checkedBy); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Purchase requisition checked"})
+	}
+}
+
+func handleAuthorize() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := requisitionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid requisition ID"))
+			return
+		}
+
+		var req models.AuthorizeRequisitionRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateAuthorizeRequisition(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		authorizedBy, _ := authMiddleware.GetUserID(r.Context())
+
+		if err := svc.Authorize(r.Context(), id, &req, authorizedBy); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Purchase requisition authorized"})
+	}
+}
+
+func handleReject() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := requisitionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid requisition ID"))
+			return
+		}
+
+		var req models.RejectRequisitionRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateRejectRequisition(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		rejectedBy, _ := authMiddleware.GetUserID(r.Context())
+
+		if err := svc.Reject(r.Context(), id, &req, rejectedBy); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Purchase requisition returned to draft"})
+	}
+}
+
+func handleCancel() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := requisitionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid requisition ID"))
+			return
+		}
+
+		if err := svc.Cancel(r.Context(), id); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Purchase requisition cancelled"})
+	}
+}
+
+// handleConvert raises the purchase order and returns its ID.
+func handleConvert() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := requisitionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid requisition ID"))
+			return
+		}
+
+		var req models.ConvertRequisitionRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateConvertRequisition(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		convertedBy, _ := authMiddleware.GetUserID(r.Context())
+
+		poID, err := svc.Convert(r.Context(), id, &req, convertedBy)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.CreatedResponse(w, r, poID, "Purchase order created from requisition")
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, requisitionService.ErrRequisitionNotFound):
+		helper.NotFoundResponse(w, r)
+	case errors.Is(err, requisitionService.ErrIllegalTransition):
+		helper.ErrorResponse(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, requisitionService.ErrEmptyRequisition),
+		errors.Is(err, requisitionService.ErrNoVendor),
+		errors.Is(err, requisitionService.ErrNoWarehouse),
+		errors.Is(err, requisitionService.ErrNothingApproved):
+		helper.BadRequestResponse(w, r, err)
+	default:
+		helper.ServerErrorResponse(w, r, err)
+	}
+}
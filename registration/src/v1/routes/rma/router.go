@@ -0,0 +1,390 @@
+package rma
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/anas-dev-92/FoodHive/core/auth"
+	"github.com/anas-dev-92/FoodHive/core/jwt"
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	authMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/auth"
+	rmaMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/rma"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	catchWeightService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/catch_weight"
+	glService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/gl"
+	inventoryService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/inventory"
+	rmaService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/rma"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
+	"github.com/go-chi/chi/v5"
+)
+
+func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.AuthService) chi.Router {
+	app := chi.NewRouter()
+
+	// Inject RMA service
+	app.Use(rmaMiddleware.New(db))
+
+	// Apply authentication
+	app.Use(authMiddleware.Authenticate(jwtService))
+
+	// ===========================================
+	// Return Authorization Routes
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Post("/create", handleCreate())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/get/{id}", handleGetByID())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/list", handleList())
+
+	// ===========================================
+	// Workflow Routes
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Post("/{id}/receive", handleReceive())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/{id}/approve", handleApprove())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/{id}/reject", handleReject())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/{id}/cancel", handleCancel())
+
+	// ===========================================
+	// QA Disposition Routes
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Post("/{id}/disposition", handleDisposition())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/{id}/quarantine/{dispositionId}/release", handleReleaseQuarantine())
+
+	return app
+}
+
+// ===========================================
+// Return Authorization Handlers
+// ===========================================
+
+func handleCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := rmaMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.CreateRMARequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateRMA(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		createdBy, _ := authMiddleware.GetUserID(r.Context())
+
+		id, err := svc.Create(r.Context(), &req, createdBy)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.CreatedResponse(w, r, id, "Return authorization created successfully")
+	}
+}
+
+func handleGetByID() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := rmaMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid RMA ID"))
+			return
+		}
+
+		rma, err := svc.GetByID(r.Context(), id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, rma)
+	}
+}
+
+func handleList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := rmaMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var filters models.RMAFilters
+		if cid, err := strconv.Atoi(r.URL.Query().Get("customer_id")); err == nil {
+			filters.CustomerID = &cid
+		}
+		if oid, err := strconv.Atoi(r.URL.Query().Get("order_id")); err == nil {
+			filters.OrderID = &oid
+		}
+		if status := r.URL.Query().Get("status"); status != "" {
+			s := models.RMAStatus(status)
+			filters.Status = &s
+		}
+
+		rmas, err := svc.List(r.Context(), &filters)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, rmas)
+	}
+}
+
+// ===========================================
+// Workflow Handlers
+// ===========================================
+
+func handleReceive() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := rmaMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid RMA ID"))
+			return
+		}
+
+		var req models.ReceiveRMARequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateReceiveRMA(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		receivedBy, _ := authMiddleware.GetUserID(r.Context())
+
+		if err := svc.Receive(r.Context(), id, &req, receivedBy); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Return received"})
+	}
+}
+
+// handleApprove issues the credit memo and returns the updated RMA so the
+// caller sees the credit and journal entry.
+func handleApprove() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := rmaMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid RMA ID"))
+			return
+		}
+
+		approvedBy, _ := authMiddleware.GetUserID(r.Context())
+
+		if err := svc.Approve(r.Context(), id, approvedBy); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		rma, err := svc.GetByID(r.Context(), id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, rma)
+	}
+}
+
+func handleReject() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := rmaMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid RMA ID"))
+			return
+		}
+
+		var req models.RejectRMARequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateRejectRMA(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		if err := svc.Reject(r.Context(), id, &req); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Return rejected"})
+	}
+}
+
+func handleCancel() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := rmaMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid RMA ID"))
+			return
+		}
+
+		if err := svc.Cancel(r.Context(), id); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Return cancelled"})
+	}
+}
+
+// ===========================================
+// QA Disposition Handlers
+// ===========================================
+
+func handleDisposition() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := rmaMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid RMA ID"))
+			return
+		}
+
+		var req models.DispositionRMARequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateDispositionRMA(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		createdBy, _ := authMiddleware.GetUserID(r.Context())
+
+		if err := svc.Disposition(r.Context(), id, &req, createdBy); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Disposition recorded"})
+	}
+}
+
+func handleReleaseQuarantine() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := rmaMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid RMA ID"))
+			return
+		}
+		dispositionID, err := strconv.Atoi(chi.URLParam(r, "dispositionId"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid disposition ID"))
+			return
+		}
+
+		var req models.ReleaseQuarantineRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateReleaseQuarantine(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		releasedBy, _ := authMiddleware.GetUserID(r.Context())
+
+		if err := svc.ReleaseQuarantine(r.Context(), id, dispositionID, &req, releasedBy); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Quarantined goods released"})
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, rmaService.ErrRMANotFound),
+		errors.Is(err, rmaService.ErrDispositionNotFound):
+		helper.NotFoundResponse(w, r)
+	case errors.Is(err, rmaService.ErrIllegalTransition),
+		errors.Is(err, rmaService.ErrExceedsReturnable),
+		errors.Is(err, rmaService.ErrExceedsReceived),
+		errors.Is(err, inventoryService.ErrInsufficientStock):
+		helper.ErrorResponse(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, rmaService.ErrOrderNotFound),
+		errors.Is(err, rmaService.ErrInvoiceNotFound),
+		errors.Is(err, rmaService.ErrInvoiceHasNoOrder),
+		errors.Is(err, rmaService.ErrInvoiceMismatch),
+		errors.Is(err, rmaService.ErrOrderLineNotFound),
+		errors.Is(err, rmaService.ErrLineNotFound),
+		errors.Is(err, rmaService.ErrExceedsAuthorized),
+		errors.Is(err, rmaService.ErrNothingReceived),
+		errors.Is(err, catchWeightService.ErrNotCatchWeight),
+		errors.Is(err, glService.ErrAccountNotFound),
+		errors.Is(err, glService.ErrPeriodClosed):
+		helper.BadRequestResponse(w, r, err)
+	default:
+		helper.ServerErrorResponse(w, r, err)
+	}
+}
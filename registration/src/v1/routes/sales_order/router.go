@@ -4,14 +4,21 @@ import (
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/anas-dev-92/FoodHive/core/auth"
 	"github.com/anas-dev-92/FoodHive/core/jwt"
 	"github.com/anas-dev-92/FoodHive/core/postgres"
 	authMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/auth"
+	documentMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/document"
+	requisitionMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/requisition"
 	soMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/sales_order"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	documentService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/document"
+	inventoryService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/inventory"
+	soService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/sales_order"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/query"
 	"github.com/go-chi/chi/v5"
 )
 
@@ -37,23 +44,52 @@ func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.Au
 	app.With(authMiddleware.Authorize(jwtService)).Post("/cancel/{id}", handleCancel())
 	app.With(authMiddleware.Authorize(jwtService)).Post("/ship/{id}", handleShip())
 
+	// ===========================================
+	// Order Type Lifecycle Routes
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Post("/convert/{id}", handleConvertQuote())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/release/{id}", handleReleaseHold())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/credit/{id}", handleProcessCreditMemo())
+
 	// ===========================================
 	// Sales Order Lines Routes
 	// ===========================================
 	app.With(authMiddleware.Authorize(jwtService)).Post("/{orderId}/lines", handleAddLine())
 	app.With(authMiddleware.Authorize(jwtService)).Put("/lines/{lineId}", handleUpdateLine())
 	app.With(authMiddleware.Authorize(jwtService)).Delete("/lines/{lineId}", handleDeleteLine())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/lines/{lineId}/special-order", handleSpecialOrder())
 
 	// ===========================================
 	// Order Guide Routes
 	// ===========================================
 	app.With(authMiddleware.Authorize(jwtService)).Get("/order-guide/{customerId}", handleGetOrderGuide())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/order-guide/suggest", handleSuggestFromGuide())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/order-guide/create", handleCreateFromGuide())
+
+	// ===========================================
+	// Backorder Routes
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Get("/backorders", handleGetBackorders())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/backorders/release", handleReleaseBackorders())
 
 	// ===========================================
 	// Lost Sales Routes
 	// ===========================================
 	app.With(authMiddleware.Authorize(jwtService)).Post("/lost-sale", handleRecordLostSale())
 	app.With(authMiddleware.Authorize(jwtService)).Get("/lost-sales", handleGetLostSales())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/lost-sales/report", handleLostSalesReport())
+
+	// ===========================================
+	// Freight Routes
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Get("/{id}/freight", handleGetFreight())
+	app.With(authMiddleware.AuthorizePage(jwtService, freightOverridePage)).Put("/{id}/freight", handleOverrideFreight())
+	app.With(authMiddleware.AuthorizePage(jwtService, freightOverridePage)).Delete("/{id}/freight", handleClearFreightOverride())
+
+	// ===========================================
+	// Printing
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Get("/{id}/delivery-note/pdf", handleDeliveryNotePDF())
 
 	return app
 }
@@ -87,7 +123,18 @@ func handleCreate() http.HandlerFunc {
 
 		id, err := svc.Create(r.Context(), &req, createdBy)
 		if err != nil {
-			helper.ServerErrorResponse(w, r, err)
+			writeLifecycleError(w, r, err)
+			return
+		}
+
+		// Orders over the customer's credit are created on credit hold
+		if order, err := svc.GetByID(r.Context(), id); err == nil && order.Order.CreditHold {
+			helper.SuccessResponse(w, r, http.StatusCreated, helper.Envelope{
+				"id":                 id,
+				"message":            "Sales order created on credit hold",
+				"credit_hold":        true,
+				"credit_hold_reason": order.Order.CreditHoldReason,
+			})
 			return
 		}
 
@@ -160,7 +207,7 @@ func handleUpdate() http.HandlerFunc {
 
 		err = svc.Update(r.Context(), id, &req)
 		if err != nil {
-			helper.ServerErrorResponse(w, r, err)
+			writeLifecycleError(w, r, err)
 			return
 		}
 
@@ -216,6 +263,7 @@ func handleList() http.HandlerFunc {
 		if customerID, err := strconv.Atoi(r.URL.Query().Get("customer_id")); err == nil {
 			filters.CustomerID = &customerID
 		}
+		filters.CustomerItemCode = r.URL.Query().Get("customer_item_code")
 		if warehouseID, err := strconv.Atoi(r.URL.Query().Get("warehouse_id")); err == nil {
 			filters.WarehouseID = &warehouseID
 		}
@@ -225,6 +273,9 @@ func handleList() http.HandlerFunc {
 		if salesRepID, err := strconv.Atoi(r.URL.Query().Get("sales_rep_id")); err == nil {
 			filters.SalesRepID = &salesRepID
 		}
+		if creditHold, err := strconv.ParseBool(r.URL.Query().Get("credit_hold")); err == nil {
+			filters.CreditHold = &creditHold
+		}
 		if status := r.URL.Query().Get("status"); status != "" {
 			s := models.OrderStatus(status)
 			filters.Status = &s
@@ -234,26 +285,20 @@ func handleList() http.HandlerFunc {
 			filters.OrderType = &t
 		}
 
-		orders, total, err := svc.List(r.Context(), &filters)
+		params, err := query.Parse(r.URL.Query())
 		if err != nil {
-			helper.ServerErrorResponse(w, r, err)
+			helper.BadRequestResponse(w, r, err)
 			return
 		}
+		filters.Query = params
 
-		totalPages := int(total) / filters.PageSize
-		if int(total)%filters.PageSize > 0 {
-			totalPages++
+		orders, total, err := svc.List(r.Context(), &filters)
+		if err != nil {
+			helper.ListErrorResponse(w, r, err)
+			return
 		}
 
-		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{
-			"data": orders,
-			"pagination": helper.Envelope{
-				"page":        filters.Page,
-				"page_size":   filters.PageSize,
-				"total_items": total,
-				"total_pages": totalPages,
-			},
-		})
+		helper.ListResponse(w, r, orders, total, params)
 	}
 }
 
@@ -271,9 +316,10 @@ func handleConfirm() http.HandlerFunc {
 			return
 		}
 
-		err = svc.Confirm(r.Context(), id)
+		confirmedBy, _ := authMiddleware.GetUserID(r.Context())
+		err = svc.Confirm(r.Context(), id, confirmedBy)
 		if err != nil {
-			helper.ServerErrorResponse(w, r, err)
+			writeLifecycleError(w, r, err)
 			return
 		}
 
@@ -295,9 +341,10 @@ func handleCancel() http.HandlerFunc {
 			return
 		}
 
-		err = svc.Cancel(r.Context(), id)
+		cancelledBy, _ := authMiddleware.GetUserID(r.Context())
+		err = svc.Cancel(r.Context(), id, cancelledBy)
 		if err != nil {
-			helper.ServerErrorResponse(w, r, err)
+			writeLifecycleError(w, r, err)
 			return
 		}
 
@@ -319,9 +366,10 @@ func handleShip() http.HandlerFunc {
 			return
 		}
 
-		err = svc.Ship(r.Context(), id)
+		shippedBy, _ := authMiddleware.GetUserID(r.Context())
+		err = svc.Ship(r.Context(), id, shippedBy)
 		if err != nil {
-			helper.ServerErrorResponse(w, r, err)
+			writeLifecycleError(w, r, err)
 			return
 		}
 
@@ -329,6 +377,148 @@ func handleShip() http.HandlerFunc {
 	}
 }
 
+// ===========================================
+// Order Type Lifecycle Handlers
+// ===========================================
+
+func handleConvertQuote() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := soMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid sales order ID"))
+			return
+		}
+
+		var req models.ConvertQuoteRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateConvertQuote(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		createdBy, _ := authMiddleware.GetUserID(r.Context())
+		orderID, err := svc.ConvertQuote(r.Context(), id, &req, createdBy)
+		if err != nil {
+			writeLifecycleError(w, r, err)
+			return
+		}
+
+		helper.CreatedResponse(w, r, orderID, "Quote converted to sales order successfully")
+	}
+}
+
+func handleReleaseHold() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := soMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid sales order ID"))
+			return
+		}
+
+		var req models.ReleaseHoldRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		v.Check(strings.TrimSpace(req.Reason) != "", "reason", "Release reason is required")
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		releasedBy, _ := authMiddleware.GetUserID(r.Context())
+		if err := svc.ReleaseHold(r.Context(), id, strings.TrimSpace(req.Reason), releasedBy); err != nil {
+			writeLifecycleError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Order hold released successfully"})
+	}
+}
+
+func handleProcessCreditMemo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := soMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid sales order ID"))
+			return
+		}
+
+		var req models.ProcessCreditMemoRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		processedBy, _ := authMiddleware.GetUserID(r.Context())
+		result, err := svc.ProcessCreditMemo(r.Context(), id, &req, processedBy)
+		if err != nil {
+			writeLifecycleError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, result)
+	}
+}
+
+// writeLifecycleError maps order state errors to 409 so clients can tell a
+// refused transition from a failure.
+func writeLifecycleError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, soService.ErrOrderNotFound),
+		errors.Is(err, soService.ErrLineNotFound),
+		errors.Is(err, soService.ErrProductNotFound),
+		errors.Is(err, soService.ErrCustomerNotFound):
+		helper.NotFoundResponse(w, r)
+	case errors.Is(err, soService.ErrIllegalTransition),
+		errors.Is(err, soService.ErrQuoteExpired),
+		errors.Is(err, soService.ErrPaymentRequired),
+		errors.Is(err, soService.ErrOrderOnHold),
+		errors.Is(err, soService.ErrNotYetScheduled),
+		errors.Is(err, soService.ErrCreditHold),
+		errors.Is(err, soService.ErrNothingToShip),
+		errors.Is(err, soService.ErrLineCovered),
+		errors.Is(err, soService.ErrFreightBilled),
+		errors.Is(err, inventoryService.ErrInsufficientStock):
+		helper.ErrorResponse(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, soService.ErrMarginTooLow),
+		errors.Is(err, soService.ErrUnknownCustomerCode),
+		errors.Is(err, soService.ErrCustomerCodeMismatch):
+		helper.ErrorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, soService.ErrPickUpNotRouted),
+		errors.Is(err, soService.ErrEmptyDraft):
+		helper.BadRequestResponse(w, r, err)
+	default:
+		helper.ServerErrorResponse(w, r, err)
+	}
+}
+
 // ===========================================
 // Line Handlers
 // ===========================================
@@ -354,7 +544,7 @@ func handleAddLine() http.HandlerFunc {
 		}
 
 		v := models.NewValidator()
-		v.Check(req.ProductID > 0, "product_id", "Product ID is required")
+		v.Check(req.ProductID > 0 || req.CustomerItemCode != "", "product_id", "Product ID or customer item code is required")
 		v.Check(req.Quantity > 0, "quantity", "Quantity must be positive")
 		v.Check(req.UnitOfMeasure != "", "unit_of_measure", "Unit of measure is required")
 		if !v.Valid() {
@@ -362,13 +552,18 @@ func handleAddLine() http.HandlerFunc {
 			return
 		}
 
-		id, err := svc.AddLine(r.Context(), orderID, &req)
+		userID, _ := authMiddleware.GetUserID(r.Context())
+		result, err := svc.AddLine(r.Context(), orderID, &req, userID)
 		if err != nil {
-			helper.ServerErrorResponse(w, r, err)
+			writeLifecycleError(w, r, err)
 			return
 		}
 
-		helper.CreatedResponse(w, r, id, "Line added successfully")
+		helper.SuccessResponse(w, r, http.StatusCreated, helper.Envelope{
+			"id":      result.LineID,
+			"message": "Line added successfully",
+			"margin":  result,
+		})
 	}
 }
 
@@ -392,13 +587,17 @@ func handleUpdateLine() http.HandlerFunc {
 			return
 		}
 
-		err = svc.UpdateLine(r.Context(), lineID, &req)
+		userID, _ := authMiddleware.GetUserID(r.Context())
+		result, err := svc.UpdateLine(r.Context(), lineID, &req, userID)
 		if err != nil {
-			helper.ServerErrorResponse(w, r, err)
+			writeLifecycleError(w, r, err)
 			return
 		}
 
-		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Line updated successfully"})
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{
+			"message": "Line updated successfully",
+			"margin":  result,
+		})
 	}
 }
 
@@ -416,9 +615,19 @@ func handleDeleteLine() http.HandlerFunc {
 			return
 		}
 
-		err = svc.DeleteLine(r.Context(), lineID)
+		// Lines dropped for want of stock say why and count as lost sales
+		reasonCode := models.LostSaleReason(r.URL.Query().Get("lost_sale_reason"))
+		v := models.NewValidator()
+		v.Check(reasonCode == "" || models.ValidLostSaleReason(reasonCode), "lost_sale_reason",
+			"Reason code must be OUT_OF_STOCK, SHORT_PICK, BACKORDER_CANCELLED or OTHER")
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		err = svc.DeleteLine(r.Context(), lineID, reasonCode)
 		if err != nil {
-			helper.ServerErrorResponse(w, r, err)
+			writeLifecycleError(w, r, err)
 			return
 		}
 
@@ -426,6 +635,47 @@ func handleDeleteLine() http.HandlerFunc {
 	}
 }
 
+// handleSpecialOrder backorders the line and submits a purchase requisition
+// for it; the goods are allocated to the order when they are received.
+func handleSpecialOrder() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := requisitionMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		lineID, err := strconv.Atoi(chi.URLParam(r, "lineId"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid line ID"))
+			return
+		}
+
+		var req models.SpecialOrderRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateSpecialOrder(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		createdBy, _ := authMiddleware.GetUserID(r.Context())
+
+		result, err := svc.CreateSpecialOrder(r.Context(), lineID, &req, createdBy)
+		if err != nil {
+			writeLifecycleError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusCreated, result)
+	}
+}
+
 // ===========================================
 // Order Guide Handler
 // ===========================================
@@ -460,6 +710,73 @@ func handleGetOrderGuide() http.HandlerFunc {
 	}
 }
 
+// handleSuggestFromGuide proposes an order from the customer's order guide
+// with the client's edits applied, without creating it.
+func handleSuggestFromGuide() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := soMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.OrderGuideDraftRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateOrderGuideDraft(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		draft, err := svc.SuggestFromGuide(r.Context(), &req)
+		if err != nil {
+			writeLifecycleError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, draft)
+	}
+}
+
+// handleCreateFromGuide accepts the proposal as a DRAFT order.
+func handleCreateFromGuide() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := soMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.OrderGuideDraftRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateOrderGuideDraft(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		createdBy, _ := authMiddleware.GetUserID(r.Context())
+
+		draft, err := svc.CreateFromGuide(r.Context(), &req, createdBy)
+		if err != nil {
+			writeLifecycleError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusCreated, draft)
+	}
+}
+
 // ===========================================
 // Lost Sales Handlers
 // ===========================================
@@ -472,31 +789,23 @@ func handleRecordLostSale() http.HandlerFunc {
 			return
 		}
 
-		var req struct {
-			OrderID           int     `json:"order_id"`
-			ProductID         int     `json:"product_id"`
-			QuantityRequested float64 `json:"quantity_requested"`
-			QuantityAvailable float64 `json:"quantity_available"`
-			Reason            string  `json:"reason"`
-		}
-
+		var req models.RecordLostSaleRequest
 		if err := helper.ReadJSON(w, r, &req); err != nil {
 			helper.BadRequestResponse(w, r, err)
 			return
 		}
 
 		v := models.NewValidator()
-		v.Check(req.OrderID > 0, "order_id", "Order ID is required")
-		v.Check(req.ProductID > 0, "product_id", "Product ID is required")
-		v.Check(req.QuantityRequested > 0, "quantity_requested", "Quantity requested must be positive")
+		models.ValidateLostSale(v, &req)
 		if !v.Valid() {
 			helper.FailedValidationResponse(w, r, v.Errors)
 			return
 		}
 
-		err := svc.RecordLostSale(r.Context(), req.OrderID, req.ProductID, req.QuantityRequested, req.QuantityAvailable, req.Reason)
-		if err != nil {
-			helper.ServerErrorResponse(w, r, err)
+		recordedBy, _ := authMiddleware.GetUserID(r.Context())
+
+		if err := svc.RecordLostSale(r.Context(), &req, recordedBy); err != nil {
+			writeLifecycleError(w, r, err)
 			return
 		}
 
@@ -531,3 +840,246 @@ func handleGetLostSales() http.HandlerFunc {
 		helper.SuccessResponse(w, r, http.StatusOK, lostSales)
 	}
 }
+
+// handleLostSalesReport totals lost sales by product, customer, rep,
+// warehouse or week.
+func handleLostSalesReport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := soMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		filters := models.LostSalesReportFilters{
+			GroupBy:  models.LostSalesGroup(r.URL.Query().Get("group_by")),
+			DateFrom: r.URL.Query().Get("date_from"),
+			DateTo:   r.URL.Query().Get("date_to"),
+		}
+		if filters.GroupBy == "" {
+			filters.GroupBy = models.LostSalesByProduct
+		}
+		if wid, err := strconv.Atoi(r.URL.Query().Get("warehouse_id")); err == nil {
+			filters.WarehouseID = &wid
+		}
+		if pid, err := strconv.Atoi(r.URL.Query().Get("product_id")); err == nil {
+			filters.ProductID = &pid
+		}
+		if cid, err := strconv.Atoi(r.URL.Query().Get("customer_id")); err == nil {
+			filters.CustomerID = &cid
+		}
+		if code := r.URL.Query().Get("reason_code"); code != "" {
+			c := models.LostSaleReason(code)
+			filters.ReasonCode = &c
+		}
+
+		v := models.NewValidator()
+		models.ValidateLostSalesReport(v, &filters)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		report, err := svc.GetLostSalesReport(r.Context(), &filters)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, report)
+	}
+}
+
+// ===========================================
+// Backorder Handlers
+// ===========================================
+
+// handleGetBackorders is the backorder fill report.
+func handleGetBackorders() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := soMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var filters models.BackorderFilters
+		if id, err := strconv.Atoi(r.URL.Query().Get("warehouse_id")); err == nil {
+			filters.WarehouseID = &id
+		}
+		if id, err := strconv.Atoi(r.URL.Query().Get("product_id")); err == nil {
+			filters.ProductID = &id
+		}
+		if id, err := strconv.Atoi(r.URL.Query().Get("customer_id")); err == nil {
+			filters.CustomerID = &id
+		}
+
+		backorders, err := svc.GetBackorders(r.Context(), &filters)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, backorders)
+	}
+}
+
+func handleReleaseBackorders() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := soMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.ReleaseBackordersRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateReleaseBackorders(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		result, err := svc.ReleaseBackorders(r.Context(), &req)
+		if err != nil {
+			writeLifecycleError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, result)
+	}
+}
+
+// ===========================================
+// Freight Handlers
+// ===========================================
+
+func handleGetFreight() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := soMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid sales order ID"))
+			return
+		}
+
+		freight, err := svc.GetFreight(r.Context(), id)
+		if err != nil {
+			writeLifecycleError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, freight)
+	}
+}
+
+// freightOverridePage is the page permission that allows overriding the
+// freight rules: update to set the freight, delete to clear it. Access to
+// sales orders alone is not enough.
+const freightOverridePage = "/sales-orders/freight-override"
+
+// handleOverrideFreight sets the order's freight by hand.
+func handleOverrideFreight() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := soMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid sales order ID"))
+			return
+		}
+
+		var req models.OverrideFreightRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+		req.Reason = strings.TrimSpace(req.Reason)
+
+		v := models.NewValidator()
+		models.ValidateOverrideFreight(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		userID, _ := authMiddleware.GetUserID(r.Context())
+		if err := svc.OverrideFreight(r.Context(), id, &req, userID); err != nil {
+			writeLifecycleError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Freight overridden successfully"})
+	}
+}
+
+func handleClearFreightOverride() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := soMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid sales order ID"))
+			return
+		}
+
+		if err := svc.ClearFreightOverride(r.Context(), id); err != nil {
+			writeLifecycleError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Freight returned to the freight rules"})
+	}
+}
+
+// ===========================================
+// PDF Handlers
+// ===========================================
+
+func handleDeliveryNotePDF() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := documentMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid sales order ID"))
+			return
+		}
+
+		printedBy, _ := authMiddleware.GetUserID(r.Context())
+
+		doc, err := svc.RenderDeliveryNote(r.Context(), id, printedBy)
+		if err != nil {
+			if errors.Is(err, documentService.ErrNotFound) {
+				helper.NotFoundResponse(w, r)
+				return
+			}
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.FileResponse(w, r, doc.FileName, doc.ContentType, doc.Content)
+	}
+}
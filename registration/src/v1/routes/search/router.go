@@ -0,0 +1,80 @@
+package search
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/anas-dev-92/FoodHive/core/auth"
+	"github.com/anas-dev-92/FoodHive/core/jwt"
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	authMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/auth"
+	searchMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/search"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
+	"github.com/go-chi/chi/v5"
+)
+
+// Router exposes the unified search used for type-ahead lookups.
+func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.AuthService) chi.Router {
+	app := chi.NewRouter()
+
+	// Inject search service
+	app.Use(searchMiddleware.New(db))
+
+	// Apply authentication
+	app.Use(authMiddleware.Authenticate(jwtService))
+
+	app.With(authMiddleware.Authorize(jwtService)).Get("/", handleSearch())
+
+	return app
+}
+
+// handleSearch answers GET /search?q=...&types=product,customer&limit=20
+// with results of all requested types ranked together.
+func handleSearch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := searchMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		req := models.SearchRequest{
+			Query:           r.URL.Query().Get("q"),
+			IncludeInactive: r.URL.Query().Get("include_inactive") == "true",
+		}
+		if types := r.URL.Query().Get("types"); types != "" {
+			for _, t := range strings.Split(types, ",") {
+				req.Types = append(req.Types, models.SearchResultType(strings.TrimSpace(t)))
+			}
+		}
+		if limit := r.URL.Query().Get("limit"); limit != "" {
+			n, err := strconv.Atoi(limit)
+			if err != nil {
+				helper.BadRequestResponse(w, r, errors.New("invalid limit"))
+				return
+			}
+			req.Limit = n
+		}
+
+		v := models.NewValidator()
+		models.ValidateSearch(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		results, err := svc.Search(r.Context(), &req)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{
+			"query":   req.Query,
+			"results": results,
+		})
+	}
+}
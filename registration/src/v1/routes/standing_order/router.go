@@ -0,0 +1,405 @@
+package standing_order
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/anas-dev-92/FoodHive/core/auth"
+	"github.com/anas-dev-92/FoodHive/core/jwt"
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	authMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/auth"
+	standingOrderMiddleware "github.com/anas-dev-92/FoodHive/registration/src/v1/middlewares/standing_order"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	standingOrderService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/standing_order"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/helper"
+	"github.com/go-chi/chi/v5"
+)
+
+// defaultScheduleDays is how far ahead the schedule looks when not asked.
+const defaultScheduleDays = 14
+
+func Router(db postgres.Executor, jwtService jwt.JWTService, authService auth.AuthService) chi.Router {
+	app := chi.NewRouter()
+
+	// Inject standing order service
+	app.Use(standingOrderMiddleware.New(db))
+
+	// Apply authentication
+	app.Use(authMiddleware.Authenticate(jwtService))
+
+	// ===========================================
+	// Standing Order Routes
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Post("/create", handleCreate())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/get/{id}", handleGetByID())
+	app.With(authMiddleware.Authorize(jwtService)).Put("/update/{id}", handleUpdate())
+	app.With(authMiddleware.Authorize(jwtService)).Delete("/delete/{id}", handleDelete())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/list", handleList())
+
+	// ===========================================
+	// Pause and Skip Routes
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Post("/{id}/pause", handlePause())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/{id}/resume", handleResume())
+	app.With(authMiddleware.Authorize(jwtService)).Post("/{id}/skip", handleSkipDelivery())
+	app.With(authMiddleware.Authorize(jwtService)).Delete("/{id}/skip/{date}", handleUnskipDelivery())
+	app.With(authMiddleware.Authorize(jwtService)).Get("/{id}/schedule", handleGetSchedule())
+
+	// ===========================================
+	// Generation Routes
+	// ===========================================
+	app.With(authMiddleware.Authorize(jwtService)).Post("/generate", handleGenerate())
+
+	return app
+}
+
+// ===========================================
+// Standing Order Handlers
+// ===========================================
+
+func handleCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := standingOrderMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		var req models.CreateStandingOrderRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateStandingOrder(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		createdBy, _ := authMiddleware.GetUserID(r.Context())
+
+		id, err := svc.Create(r.Context(), &req, createdBy)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.CreatedResponse(w, r, id, "Standing order created successfully")
+	}
+}
+
+func handleGetByID() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := standingOrderMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid standing order ID"))
+			return
+		}
+
+		order, err := svc.GetByID(r.Context(), id)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, order)
+	}
+}
+
+func handleUpdate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := standingOrderMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid standing order ID"))
+			return
+		}
+
+		var req models.UpdateStandingOrderRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateUpdateStandingOrder(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		if err := svc.Update(r.Context(), id, &req); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Standing order updated successfully"})
+	}
+}
+
+func handleDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := standingOrderMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid standing order ID"))
+			return
+		}
+
+		if err := svc.Delete(r.Context(), id); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Standing order deleted successfully"})
+	}
+}
+
+func handleList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := standingOrderMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		filters := models.StandingOrderFilters{
+			ActiveOnly: r.URL.Query().Get("active_only") == "true",
+		}
+		if cid, err := strconv.Atoi(r.URL.Query().Get("customer_id")); err == nil {
+			filters.CustomerID = &cid
+		}
+
+		orders, err := svc.List(r.Context(), &filters)
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, orders)
+	}
+}
+
+// ===========================================
+// Pause and Skip Handlers
+// ===========================================
+
+func handlePause() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := standingOrderMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid standing order ID"))
+			return
+		}
+
+		var req models.PauseStandingOrderRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidatePauseStandingOrder(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		if err := svc.Pause(r.Context(), id, &req); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Standing order paused"})
+	}
+}
+
+func handleResume() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := standingOrderMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid standing order ID"))
+			return
+		}
+
+		if err := svc.Resume(r.Context(), id); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Standing order resumed"})
+	}
+}
+
+func handleSkipDelivery() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := standingOrderMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid standing order ID"))
+			return
+		}
+
+		var req models.SkipDeliveryRequest
+		if err := helper.ReadJSON(w, r, &req); err != nil {
+			helper.BadRequestResponse(w, r, err)
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateSkipDelivery(v, &req)
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		createdBy, _ := authMiddleware.GetUserID(r.Context())
+
+		if err := svc.SkipDelivery(r.Context(), id, &req, createdBy); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Delivery skipped"})
+	}
+}
+
+func handleUnskipDelivery() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := standingOrderMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid standing order ID"))
+			return
+		}
+
+		v := models.NewValidator()
+		models.ValidateSkipDelivery(v, &models.SkipDeliveryRequest{DeliveryDate: chi.URLParam(r, "date")})
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		if err := svc.UnskipDelivery(r.Context(), id, chi.URLParam(r, "date")); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, helper.Envelope{"message": "Delivery restored"})
+	}
+}
+
+func handleGetSchedule() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := standingOrderMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		id, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil {
+			helper.BadRequestResponse(w, r, errors.New("invalid standing order ID"))
+			return
+		}
+
+		days := defaultScheduleDays
+		if d, err := strconv.Atoi(r.URL.Query().Get("days")); err == nil {
+			days = d
+		}
+
+		v := models.NewValidator()
+		v.Check(days >= 1 && days <= 90, "days", "Days must be between 1 and 90")
+		if !v.Valid() {
+			helper.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		schedule, err := svc.GetSchedule(r.Context(), id, days)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, schedule)
+	}
+}
+
+// ===========================================
+// Generation Handlers
+// ===========================================
+
+// handleGenerate runs the generator for the company now rather than
+// waiting for the scheduler.
+func handleGenerate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		svc, ok := standingOrderMiddleware.Instance(r.Context())
+		if !ok {
+			helper.ServerErrorResponse(w, r, errors.New("service unavailable"))
+			return
+		}
+
+		result, err := svc.Generate(r.Context())
+		if err != nil {
+			helper.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		helper.SuccessResponse(w, r, http.StatusOK, result)
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, standingOrderService.ErrStandingOrderNotFound),
+		errors.Is(err, standingOrderService.ErrSkipNotFound):
+		helper.NotFoundResponse(w, r)
+	case errors.Is(err, standingOrderService.ErrDeliveryInProgress):
+		helper.ErrorResponse(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, standingOrderService.ErrNotADeliveryDay):
+		helper.BadRequestResponse(w, r, err)
+	default:
+		helper.ServerErrorResponse(w, r, err)
+	}
+}
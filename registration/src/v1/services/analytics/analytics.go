@@ -0,0 +1,327 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+)
+
+// ============================================
+// Service Interface
+// ============================================
+
+type AnalyticsService interface {
+	GetSales(ctx context.Context, filters *models.SalesAnalyticsFilters) (*models.SalesAnalytics, error)
+	ListSalesDocuments(ctx context.Context, filters *models.SalesAnalyticsFilters) ([]models.SalesDocument, int64, error)
+}
+
+// ============================================
+// Service Implementation
+// ============================================
+
+type analyticsServiceImpl struct {
+	db postgres.Executor
+}
+
+func New(db postgres.Executor) AnalyticsService {
+	return &analyticsServiceImpl{db: db}
+}
+
+// ============================================
+// Sales Lines
+// ============================================
+//
+// Sales are what was billed: every line of a posted invoice or credit memo,
+// credit memo lines carrying negative quantities. Lines billed from an order
+// take its rep, route and warehouse through the order line, or through the
+// invoice's order; the rep falls back to the customer's.
+
+const salesFrom = `
+	FROM ar_invoices i
+	JOIN ar_invoice_lines l ON l.invoice_id = i.id
+	JOIN customers c ON c.id = i.customer_id
+	LEFT JOIN products p ON p.id = l.product_id
+	LEFT JOIN sales_order_lines sol ON sol.id = l.order_line_id
+	LEFT JOIN sales_orders lso ON lso.id = sol.order_id
+	LEFT JOIN sales_orders iso ON iso.id = i.order_id`
+
+// Cost is what the order line shipped at, or the product's average cost
+// for lines billed without an order. Weight is the caught weight of catch
+// weight lines in kilograms, or the product's unit weight.
+const (
+	salesRevenue = `l.quantity * l.unit_price`
+	salesCost    = `l.quantity * COALESCE(sol.cost, p.average_cost, p.standard_cost, 0)`
+	salesWeight  = `CASE WHEN p.is_catch_weight AND sol.catch_weight IS NOT NULL AND sol.quantity_shipped > 0
+		THEN sol.catch_weight * l.quantity / sol.quantity_shipped *
+			 CASE COALESCE(p.catch_weight_unit, 'KG')
+				 WHEN 'LB' THEN 0.45359237 WHEN 'GR' THEN 0.001 WHEN 'OZ' THEN 0.028349523125 ELSE 1 END
+		ELSE l.quantity * COALESCE(p.weight_kg, 0) END`
+	salesOrder = `COALESCE(sol.order_id, i.order_id)`
+)
+
+// salesGroups maps each grouping to its key, label and join. The key is
+// also what the grouping's filter matches.
+var salesGroups = map[models.SalesGroup]struct {
+	key, label, join string
+}{
+	models.SalesByProduct:       {"l.product_id", "COALESCE(p.sku || ' ' || p.name, '')", ""},
+	models.SalesByCategory:      {"p.category_id", "COALESCE(pc.name, '')", "LEFT JOIN product_categories pc ON pc.id = p.category_id"},
+	models.SalesByCustomer:      {"i.customer_id", "c.name", ""},
+	models.SalesByCustomerGroup: {"c.customer_group_id", "COALESCE(cg.name, '')", "LEFT JOIN customer_groups cg ON cg.id = c.customer_group_id"},
+	models.SalesByRep:           {"COALESCE(lso.sales_rep_id, iso.sales_rep_id, c.sales_rep_id)", "COALESCE(e.english_name, '')", "LEFT JOIN employees e ON e.id = COALESCE(lso.sales_rep_id, iso.sales_rep_id, c.sales_rep_id)"},
+	models.SalesByRoute:         {"COALESCE(lso.route_id, iso.route_id)", "COALESCE(r.name, '')", "LEFT JOIN routes r ON r.id = COALESCE(lso.route_id, iso.route_id)"},
+	models.SalesByWarehouse:     {"COALESCE(lso.warehouse_id, iso.warehouse_id)", "COALESCE(w.name, '')", "LEFT JOIN warehouses w ON w.id = COALESCE(lso.warehouse_id, iso.warehouse_id)"},
+}
+
+// salesWhere narrows sales to the company and the filters, leaving the
+// dates to the caller.
+func salesWhere(ctx context.Context, filters *models.SalesAnalyticsFilters) (string, []interface{}) {
+	whereClause := "WHERE i.company_id = $1 AND i.status NOT IN ('DRAFT', 'VOID')"
+	args := []interface{}{tenant.Company(ctx)}
+
+	for _, f := range []struct {
+		group models.SalesGroup
+		id    *int
+	}{
+		{models.SalesByProduct, filters.ProductID},
+		{models.SalesByCategory, filters.CategoryID},
+		{models.SalesByCustomer, filters.CustomerID},
+		{models.SalesByCustomerGroup, filters.CustomerGroupID},
+		{models.SalesByRep, filters.SalesRepID},
+		{models.SalesByRoute, filters.RouteID},
+		{models.SalesByWarehouse, filters.WarehouseID},
+	} {
+		if f.id != nil {
+			args = append(args, *f.id)
+			whereClause += fmt.Sprintf(" AND %s = $%d", salesGroups[f.group].key, len(args))
+		}
+	}
+	return whereClause, args
+}
+
+// salesPeriods resolves the filter dates, the month to date by default, and
+// the periods compared with them.
+func salesPeriods(filters *models.SalesAnalyticsFilters) (current, prior, lastYear models.SalesPeriod, err error) {
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if filters.DateTo != "" {
+		if to, err = time.Parse("2006-01-02", filters.DateTo); err != nil {
+			return
+		}
+	}
+	from := time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if filters.DateFrom != "" {
+		if from, err = time.Parse("2006-01-02", filters.DateFrom); err != nil {
+			return
+		}
+	}
+	if to.Before(from) {
+		to = from
+	}
+
+	days := int(to.Sub(from).Hours()/24) + 1
+	priorTo := from.AddDate(0, 0, -1)
+
+	current = models.SalesPeriod{From: from.Format("2006-01-02"), To: to.Format("2006-01-02")}
+	prior = models.SalesPeriod{From: priorTo.AddDate(0, 0, 1-days).Format("2006-01-02"), To: priorTo.Format("2006-01-02")}
+	lastYear = models.SalesPeriod{From: from.AddDate(-1, 0, 0).Format("2006-01-02"), To: to.AddDate(-1, 0, 0).Format("2006-01-02")}
+	return
+}
+
+// ============================================
+// Analytics
+// ============================================
+
+// GetSales totals sales by the chosen group for the period, the period
+// before it and the same period last year, largest current revenue first.
+// The three can overlap when the period is longer than a year, so each is
+// summed on its own dates.
+func (s *analyticsServiceImpl) GetSales(ctx context.Context, filters *models.SalesAnalyticsFilters) (*models.SalesAnalytics, error) {
+	if filters.GroupBy == "" {
+		filters.GroupBy = models.SalesByProduct
+	}
+	group, ok := salesGroups[filters.GroupBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown sales grouping %q", filters.GroupBy)
+	}
+	current, prior, lastYear, err := salesPeriods(filters)
+	if err != nil {
+		return nil, err
+	}
+
+	whereClause, args := salesWhere(ctx, filters)
+	var columns, dates string
+	for _, period := range []models.SalesPeriod{current, prior, lastYear} {
+		args = append(args, period.From, period.To)
+		in := fmt.Sprintf("invoice_date BETWEEN $%d::date AND $%d::date", len(args)-1, len(args))
+		columns += fmt.Sprintf(`,
+			   COALESCE(SUM(revenue) FILTER (WHERE %[1]s), 0), COALESCE(SUM(cost) FILTER (WHERE %[1]s), 0),
+			   COALESCE(SUM(quantity) FILTER (WHERE %[1]s), 0), COALESCE(SUM(weight) FILTER (WHERE %[1]s), 0),
+			   COUNT(DISTINCT order_id) FILTER (WHERE %[1]s AND invoice_type = 'INVOICE')`, in)
+		if dates != "" {
+			dates += " OR "
+		}
+		dates += "i." + in
+	}
+
+	// The grouping set without a key is the totals row
+	query := fmt.Sprintf(`
+		WITH lines AS (
+			SELECT %[1]s AS group_id, %[2]s AS label, i.invoice_date, i.invoice_type,
+				   %[3]s AS revenue, %[4]s AS cost, l.quantity, %[5]s AS weight, %[6]s AS order_id
+			%[7]s
+			%[8]s
+			%[9]s AND (%[10]s)
+		)
+		SELECT GROUPING(group_id) = 1, group_id, COALESCE(label, '')%[11]s
+		FROM lines
+		GROUP BY GROUPING SETS ((group_id, label), ())
+		ORDER BY GROUPING(group_id), 4 DESC, 3`,
+		group.key, group.label, salesRevenue, salesCost, salesWeight, salesOrder,
+		salesFrom, group.join, whereClause, dates, columns)
+
+	rows := s.db.Query(ctx, query, args...)
+	defer rows.Close()
+
+	analytics := &models.SalesAnalytics{
+		GroupBy:     filters.GroupBy,
+		Period:      current,
+		PriorPeriod: prior,
+		LastYear:    lastYear,
+		Rows:        []models.SalesAnalyticsRow{},
+	}
+	for rows.Next() {
+		var total bool
+		var row models.SalesAnalyticsRow
+		err := rows.Scan(&total, &row.GroupID, &row.Label,
+			&row.Current.Revenue, &row.Current.Cost, &row.Current.Quantity, &row.Current.Weight, &row.Current.OrderCount,
+			&row.PriorPeriod.Revenue, &row.PriorPeriod.Cost, &row.PriorPeriod.Quantity, &row.PriorPeriod.Weight, &row.PriorPeriod.OrderCount,
+			&row.LastYear.Revenue, &row.LastYear.Cost, &row.LastYear.Quantity, &row.LastYear.Weight, &row.LastYear.OrderCount,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sales analytics: %w", err)
+		}
+		compare(&row)
+		if total {
+			row.Label = ""
+			analytics.Totals = row
+			continue
+		}
+		analytics.Rows = append(analytics.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get sales analytics: %w", err)
+	}
+	return analytics, nil
+}
+
+// compare rounds the row's figures and works out its margins and changes.
+func compare(row *models.SalesAnalyticsRow) {
+	margins(&row.Current)
+	margins(&row.PriorPeriod.SalesMetrics)
+	margins(&row.LastYear.SalesMetrics)
+	for _, c := range []*models.SalesComparison{&row.PriorPeriod, &row.LastYear} {
+		c.RevenueChange = change(row.Current.Revenue, c.Revenue)
+		c.GrossMarginChange = change(row.Current.GrossMargin, c.GrossMargin)
+		c.QuantityChange = change(row.Current.Quantity, c.Quantity)
+		c.OrderCountChange = change(float64(row.Current.OrderCount), float64(c.OrderCount))
+	}
+}
+
+func margins(m *models.SalesMetrics) {
+	m.Revenue = round(m.Revenue)
+	m.Cost = round(m.Cost)
+	m.GrossMargin = round(m.Revenue - m.Cost)
+	if m.Revenue != 0 {
+		percent := round(m.GrossMargin / m.Revenue * 100)
+		m.MarginPercent = &percent
+	}
+}
+
+// change is the percentage change from an earlier value, not set when the
+// earlier value was zero.
+func change(current, earlier float64) *float64 {
+	if earlier == 0 {
+		return nil
+	}
+	percent := round((current - earlier) / math.Abs(earlier) * 100)
+	return &percent
+}
+
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// ============================================
+// Drill-through
+// ============================================
+
+// ListSalesDocuments lists the invoices and credit memos behind the period's
+// analytics, newest first. With a filter the totals are of the matching
+// lines only.
+func (s *analyticsServiceImpl) ListSalesDocuments(ctx context.Context, filters *models.SalesAnalyticsFilters) ([]models.SalesDocument, int64, error) {
+	if filters.Page < 1 {
+		filters.Page = 1
+	}
+	if filters.PageSize < 1 || filters.PageSize > 100 {
+		filters.PageSize = 20
+	}
+	current, _, _, err := salesPeriods(filters)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	whereClause, args := salesWhere(ctx, filters)
+	args = append(args, current.From, current.To)
+	whereClause += fmt.Sprintf(" AND i.invoice_date BETWEEN $%d::date AND $%d::date", len(args)-1, len(args))
+	argNum := len(args) + 1
+
+	var total int64
+	countQuery := fmt.Sprintf(`SELECT COUNT(DISTINCT i.id) %s %s`, salesFrom, whereClause)
+	if err := s.db.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count sales documents: %w", err)
+	}
+
+	offset := (filters.Page - 1) * filters.PageSize
+	query := fmt.Sprintf(`
+		SELECT i.id, i.invoice_number, i.invoice_type, i.invoice_date, i.order_id, COALESCE(iso.order_number, ''),
+			   i.customer_id, c.name,
+			   COALESCE(SUM(%[1]s), 0), COALESCE(SUM(%[2]s), 0), COALESCE(SUM(l.quantity), 0), COALESCE(SUM(%[3]s), 0)
+		%[4]s
+		%[5]s
+		GROUP BY i.id, iso.order_number, c.name
+		ORDER BY i.invoice_date DESC, i.id DESC
+		LIMIT $%[6]d OFFSET $%[7]d`,
+		salesRevenue, salesCost, salesWeight, salesFrom, whereClause, argNum, argNum+1)
+	args = append(args, filters.PageSize, offset)
+
+	rows := s.db.Query(ctx, query, args...)
+	defer rows.Close()
+
+	var documents []models.SalesDocument
+	for rows.Next() {
+		var d models.SalesDocument
+		err := rows.Scan(
+			&d.InvoiceID, &d.InvoiceNumber, &d.InvoiceType, &d.InvoiceDate, &d.OrderID, &d.OrderNumber,
+			&d.CustomerID, &d.CustomerName,
+			&d.Revenue, &d.Cost, &d.Quantity, &d.Weight,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan sales document: %w", err)
+		}
+		d.Revenue = round(d.Revenue)
+		d.Cost = round(d.Cost)
+		d.GrossMargin = round(d.Revenue - d.Cost)
+		documents = append(documents, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list sales documents: %w", err)
+	}
+
+	return documents, total, nil
+}
@@ -2,14 +2,22 @@ package ap
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/anas-dev-92/FoodHive/core/postgres"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/query"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
 	"github.com/jackc/pgx/v5"
 )
 
+var (
+	ErrVendorNotFound  = errors.New("vendor not found")
+	ErrInvoiceNotFound = errors.New("invoice not found")
+)
+
 // ============================================
 // Service Interface
 // ============================================
@@ -59,6 +67,10 @@ func New(db postgres.Executor) APService {
 // ============================================
 
 func (s *apServiceImpl) CreateInvoice(ctx context.Context, req *models.CreateAPInvoiceRequest, createdBy int) (int, error) {
+	if err := s.checkVendor(ctx, req.VendorID); err != nil {
+		return 0, err
+	}
+
 	invDate, _ := time.Parse("2006-01-02", req.InvoiceDate)
 
 	// Get vendor payment terms for due date
@@ -83,14 +95,14 @@ func (s *apServiceImpl) CreateInvoice(ctx context.Context, req *models.CreateAPI
 		INSERT INTO ap_invoices (
 			invoice_number, vendor_id, po_id, receiving_id, invoice_date, due_date, status,
 			subtotal, tax_amount, freight_amount, total_amount, balance_due,
-			currency, notes, created_by
-		) VALUES ($1, $2, $3, $4, $5, $6, 'PENDING', $7, $8, $9, $10, $10, 'USD', $11, $12)
+			currency, notes, created_by, company_id
+		) VALUES ($1, $2, $3, $4, $5, $6, 'PENDING', $7, $8, $9, $10, $10, 'USD', $11, $12, $13)
 		RETURNING id`
 
 	var id int
 	err := s.db.QueryRow(ctx, query,
 		req.InvoiceNumber, req.VendorID, req.POID, req.ReceivingID, invDate, dueDate,
-		subtotal, req.TaxAmount, req.FreightAmount, totalAmount, req.Notes, createdBy,
+		subtotal, req.TaxAmount, req.FreightAmount, totalAmount, req.Notes, createdBy, tenant.Company(ctx),
 	).Scan(&id)
 
 	if err != nil {
@@ -120,11 +132,11 @@ func (s *apServiceImpl) CreateInvoice(ctx context.Context, req *models.CreateAPI
 }
 
 func (s *apServiceImpl) GetInvoice(ctx context.Context, id int) (*models.APInvoiceWithDetails, error) {
-	return s.getInvoice(ctx, "i.id = $1", id)
+	return s.getInvoice(ctx, "i.id = $1 AND i.company_id = $2", id, tenant.Company(ctx))
 }
 
 func (s *apServiceImpl) GetInvoiceByNumber(ctx context.Context, vendorID int, number string) (*models.APInvoiceWithDetails, error) {
-	return s.getInvoice(ctx, "i.vendor_id = $1 AND i.invoice_number = $2", vendorID, number)
+	return s.getInvoice(ctx, "i.vendor_id = $1 AND i.invoice_number = $2 AND i.company_id = $3", vendorID, number, tenant.Company(ctx))
 }
 
 func (s *apServiceImpl) getInvoice(ctx context.Context, whereClause string, args ...interface{}) (*models.APInvoiceWithDetails, error) {
@@ -191,17 +203,33 @@ func (s *apServiceImpl) getInvoice(ctx context.Context, whereClause string, args
 	return &inv, nil
 }
 
-func (s *apServiceImpl) ListInvoices(ctx context.Context, filters *models.APInvoiceListFilters) ([]models.APInvoiceWithDetails, int64, error) {
-	if filters.Page < 1 {
-		filters.Page = 1
-	}
-	if filters.PageSize < 1 || filters.PageSize > 100 {
-		filters.PageSize = 20
-	}
+// apInvoiceListSchema whitelists the fields clients may filter and sort on.
+var apInvoiceListSchema = query.Schema{
+	Fields: map[string]query.Field{
+		"id":             {Column: "i.id", Type: query.Int, Sortable: true},
+		"invoice_number": {Column: "i.invoice_number", Type: query.String, Sortable: true},
+		"vendor_id":      {Column: "i.vendor_id", Type: query.Int},
+		"po_id":          {Column: "i.po_id", Type: query.Int},
+		"invoice_date":   {Column: "i.invoice_date", Type: query.Date, Sortable: true},
+		"due_date":       {Column: "i.due_date", Type: query.Date, Sortable: true},
+		"status":         {Column: "i.status::text", Type: query.String},
+		"total_amount":   {Column: "i.total_amount", Type: query.Number, Sortable: true},
+		"amount_paid":    {Column: "i.amount_paid", Type: query.Number, Sortable: true},
+		"balance_due":    {Column: "i.balance_due", Type: query.Number, Sortable: true},
+		"currency":       {Column: "i.currency", Type: query.String},
+		"vendor_name":    {Column: "v.name", Type: query.String, Sortable: true},
+		"vendor_code":    {Column: "v.vendor_code", Type: query.String},
+		"days_overdue":   {Column: "GREATEST(0, CURRENT_DATE - i.due_date)", Type: query.Int, Sortable: true},
+		"created_at":     {Column: "i.created_at", Type: query.Time, Sortable: true},
+	},
+	Default: []query.Sort{{Field: "due_date"}, {Field: "id", Desc: true}},
+	Key:     "id",
+}
 
-	whereClause := "WHERE 1=1"
-	args := []interface{}{}
-	argNum := 1
+func (s *apServiceImpl) ListInvoices(ctx context.Context, filters *models.APInvoiceListFilters) ([]models.APInvoiceWithDetails, int64, error) {
+	whereClause := "WHERE i.company_id = $1"
+	args := []interface{}{tenant.Company(ctx)}
+	argNum := 2
 
 	if filters.VendorID != nil {
 		whereClause += fmt.Sprintf(" AND i.vendor_id = $%d", argNum)
@@ -227,36 +255,52 @@ func (s *apServiceImpl) ListInvoices(ctx context.Context, filters *models.APInvo
 		whereClause += " AND i.due_date < CURRENT_DATE AND i.balance_due > 0"
 	}
 
-	// Count
-	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM ap_invoices i %s`, whereClause)
+	params := query.Default(filters.Query, filters.Page, filters.PageSize)
+	q, err := query.Build(apInvoiceListSchema, params, argNum)
+	if err != nil {
+		return nil, 0, err
+	}
+	whereClause += q.Where
+	args = append(args, q.Args...)
+
+	// Count; keyset pages follow next_cursor instead
 	var total int64
-	s.db.QueryRow(ctx, countQuery, args...).Scan(&total)
+	if !params.Keyset() {
+		// Filters and the schema can refer to the joined vendor columns
+		countQuery := fmt.Sprintf(`
+			SELECT COUNT(*)
+			FROM ap_invoices i
+			JOIN vendors v ON i.vendor_id = v.id
+			%s`, whereClause)
+		if err := s.db.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+			return nil, 0, fmt.Errorf("failed to count invoices: %w", err)
+		}
+	}
 
 	// Query
-	offset := (filters.Page - 1) * filters.PageSize
-	query := fmt.Sprintf(`
+	listQuery := fmt.Sprintf(`
 		SELECT i.id, i.invoice_number, i.vendor_id, i.po_id, i.receiving_id, i.invoice_date, i.due_date,
 			   i.status, i.subtotal, i.tax_amount, i.freight_amount, i.discount_amount,
 			   i.total_amount, i.amount_paid, i.balance_due, i.currency, i.notes,
 			   i.approved_by, i.approved_at, i.created_by, i.created_at, i.updated_at,
 			   v.name as vendor_name, v.vendor_code,
 			   COALESCE(po.po_number, '') as po_number,
-			   GREATEST(0, CURRENT_DATE - i.due_date) as days_overdue
+			   GREATEST(0, CURRENT_DATE - i.due_date) as days_overdue, %s
 		FROM ap_invoices i
 		JOIN vendors v ON i.vendor_id = v.id
 		LEFT JOIN purchase_orders po ON i.po_id = po.id
+		%s%s
 		%s
-		ORDER BY i.due_date ASC, i.id DESC
-		LIMIT $%d OFFSET $%d`, whereClause, argNum, argNum+1)
+		%s`, q.Cursor, whereClause, q.Keyset, q.OrderBy, q.Limit)
 
-	args = append(args, filters.PageSize, offset)
-
-	rows := s.db.Query(ctx, query, args...)
+	rows := s.db.Query(ctx, listQuery, append(args, q.PageArgs...)...)
 	defer rows.Close()
 
 	var invoices []models.APInvoiceWithDetails
+	var cursors []string
 	for rows.Next() {
 		var inv models.APInvoiceWithDetails
+		var cursor string
 		var notes *string
 		var approvedAt *time.Time
 
@@ -267,7 +311,7 @@ func (s *apServiceImpl) ListInvoices(ctx context.Context, filters *models.APInvo
 			&inv.Invoice.DiscountAmount, &inv.Invoice.TotalAmount, &inv.Invoice.AmountPaid,
 			&inv.Invoice.BalanceDue, &inv.Invoice.Currency, &notes, &inv.Invoice.ApprovedBy,
 			&approvedAt, &inv.Invoice.CreatedBy, &inv.Invoice.CreatedAt, &inv.Invoice.UpdatedAt,
-			&inv.VendorName, &inv.VendorCode, &inv.PONumber, &inv.DaysOverdue,
+			&inv.VendorName, &inv.VendorCode, &inv.PONumber, &inv.DaysOverdue, &cursor,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan invoice: %w", err)
@@ -276,15 +320,19 @@ func (s *apServiceImpl) ListInvoices(ctx context.Context, filters *models.APInvo
 			inv.Invoice.Notes = *notes
 		}
 		invoices = append(invoices, inv)
+		cursors = append(cursors, cursor)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list invoices: %w", err)
 	}
 
-	return invoices, total, nil
+	return invoices[:params.Trim(cursors)], total, nil
 }
 
 func (s *apServiceImpl) ApproveInvoice(ctx context.Context, id int, approvedBy int) error {
 	result, err := s.db.Exec(ctx, `
 		UPDATE ap_invoices SET status = 'APPROVED', approved_by = $1, approved_at = NOW(), updated_at = NOW()
-		WHERE id = $2 AND status = 'PENDING'`, approvedBy, id)
+		WHERE id = $2 AND company_id = $3 AND status = 'PENDING'`, approvedBy, id, tenant.Company(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to approve invoice: %w", err)
 	}
@@ -297,7 +345,7 @@ func (s *apServiceImpl) ApproveInvoice(ctx context.Context, id int, approvedBy i
 func (s *apServiceImpl) VoidInvoice(ctx context.Context, id int) error {
 	result, err := s.db.Exec(ctx, `
 		UPDATE ap_invoices SET status = 'VOID', updated_at = NOW()
-		WHERE id = $1 AND status NOT IN ('PAID', 'VOID')`, id)
+		WHERE id = $1 AND company_id = $2 AND status NOT IN ('PAID', 'VOID')`, id, tenant.Company(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to void invoice: %w", err)
 	}
@@ -353,20 +401,38 @@ func (s *apServiceImpl) CreateFromReceiving(ctx context.Context, receivingID int
 // ============================================
 
 func (s *apServiceImpl) CreatePayment(ctx context.Context, req *models.CreateAPPaymentRequest, preparedBy int) (int, error) {
+	if err := s.checkVendor(ctx, req.VendorID); err != nil {
+		return 0, err
+	}
+
+	// Payments only settle the vendor's own invoices
+	for _, app := range req.Applications {
+		var exists bool
+		err := s.db.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM ap_invoices WHERE id = $1 AND vendor_id = $2 AND company_id = $3)`,
+			app.InvoiceID, req.VendorID, tenant.Company(ctx)).Scan(&exists)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get invoice: %w", err)
+		}
+		if !exists {
+			return 0, ErrInvoiceNotFound
+		}
+	}
+
 	paymentNumber := s.generatePaymentNumber(ctx)
 	paymentDate, _ := time.Parse("2006-01-02", req.PaymentDate)
 
 	query := `
 		INSERT INTO ap_payments (
 			payment_number, vendor_id, payment_date, payment_method, amount,
-			currency, check_number, bank_account_id, reference_no, notes, prepared_by
-		) VALUES ($1, $2, $3, $4, $5, 'USD', $6, $7, $8, $9, $10)
+			currency, check_number, bank_account_id, reference_no, notes, prepared_by, company_id
+		) VALUES ($1, $2, $3, $4, $5, 'USD', $6, $7, $8, $9, $10, $11)
 		RETURNING id`
 
 	var id int
 	err := s.db.QueryRow(ctx, query,
 		paymentNumber, req.VendorID, paymentDate, req.PaymentMethod, req.Amount,
-		req.CheckNumber, req.BankAccountID, req.ReferenceNo, req.Notes, preparedBy,
+		req.CheckNumber, req.BankAccountID, req.ReferenceNo, req.Notes, preparedBy, tenant.Company(ctx),
 	).Scan(&id)
 
 	if err != nil {
@@ -383,7 +449,7 @@ func (s *apServiceImpl) CreatePayment(ctx context.Context, req *models.CreateAPP
 		}
 
 		// Update invoice
-		s.db.Exec(ctx, `
+		_, err = s.db.Exec(ctx, `
 			UPDATE ap_invoices SET
 				amount_paid = amount_paid + $1,
 				balance_due = balance_due - $1,
@@ -392,7 +458,10 @@ func (s *apServiceImpl) CreatePayment(ctx context.Context, req *models.CreateAPP
 					ELSE 'PARTIAL'::ap_invoice_status
 				END,
 				updated_at = NOW()
-			WHERE id = $2`, app.Amount, app.InvoiceID)
+			WHERE id = $2 AND vendor_id = $3 AND company_id = $4`, app.Amount, app.InvoiceID, req.VendorID, tenant.Company(ctx))
+		if err != nil {
+			return 0, fmt.Errorf("failed to update invoice: %w", err)
+		}
 	}
 
 	return id, nil
@@ -408,12 +477,12 @@ func (s *apServiceImpl) GetPayment(ctx context.Context, id int) (*models.APPayme
 		FROM ap_payments p
 		JOIN vendors v ON p.vendor_id = v.id
 		LEFT JOIN employees e ON p.prepared_by = e.id
-		WHERE p.id = $1`
+		WHERE p.id = $1 AND p.company_id = $2`
 
 	var pay models.APPaymentWithDetails
 	var checkNo, refNo, notes *string
 
-	err := s.db.QueryRow(ctx, query, id).Scan(
+	err := s.db.QueryRow(ctx, query, id, tenant.Company(ctx)).Scan(
 		&pay.Payment.ID, &pay.Payment.PaymentNumber, &pay.Payment.VendorID, &pay.Payment.PaymentDate,
 		&pay.Payment.PaymentMethod, &pay.Payment.Amount, &pay.Payment.Currency,
 		&checkNo, &pay.Payment.BankAccountID, &refNo, &notes, &pay.Payment.PreparedBy,
@@ -455,9 +524,9 @@ func (s *apServiceImpl) ListPayments(ctx context.Context, vendorID *int, limit i
 		limit = 100
 	}
 
-	whereClause := "WHERE p.is_voided = false"
-	args := []interface{}{}
-	argNum := 1
+	whereClause := "WHERE p.is_voided = false AND p.company_id = $1"
+	args := []interface{}{tenant.Company(ctx)}
+	argNum := 2
 
 	if vendorID != nil {
 		whereClause += fmt.Sprintf(" AND p.vendor_id = $%d", argNum)
@@ -511,35 +580,37 @@ func (s *apServiceImpl) ListPayments(ctx context.Context, vendorID *int, limit i
 }
 
 func (s *apServiceImpl) VoidPayment(ctx context.Context, id int) error {
-	// Get payment applications first to reverse
-	appRows := s.db.Query(ctx, `SELECT invoice_id, amount FROM ap_payment_applications WHERE payment_id = $1`, id)
-	defer appRows.Close()
-
-	for appRows.Next() {
-		var invoiceID int
-		var amount float64
-		appRows.Scan(&invoiceID, &amount)
-
-		// Reverse invoice payment
-		s.db.Exec(ctx, `
-			UPDATE ap_invoices SET
-				amount_paid = amount_paid - $1,
-				balance_due = balance_due + $1,
-				status = CASE 
-					WHEN amount_paid - $1 <= 0 THEN 'APPROVED'::ap_invoice_status
-					ELSE 'PARTIAL'::ap_invoice_status
-				END,
-				updated_at = NOW()
-			WHERE id = $2`, amount, invoiceID)
-	}
-
-	result, err := s.db.Exec(ctx, `UPDATE ap_payments SET is_voided = true WHERE id = $1`, id)
+	// Void first, so a payment of another company or one already voided is
+	// not reversed
+	result, err := s.db.Exec(ctx, `
+		UPDATE ap_payments SET is_voided = true
+		WHERE id = $1 AND company_id = $2 AND is_voided = false`, id, tenant.Company(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to void payment: %w", err)
 	}
 	if result.RowsAffected() == 0 {
 		return fmt.Errorf("payment not found")
 	}
+
+	// Reverse the payment's applications
+	_, err = s.db.Exec(ctx, `
+		UPDATE ap_invoices i SET
+			amount_paid = i.amount_paid - a.amount,
+			balance_due = i.balance_due + a.amount,
+			status = CASE 
+				WHEN i.amount_paid - a.amount <= 0 THEN 'APPROVED'::ap_invoice_status
+				ELSE 'PARTIAL'::ap_invoice_status
+			END,
+			updated_at = NOW()
+		FROM (
+			SELECT invoice_id, SUM(amount) AS amount
+			FROM ap_payment_applications WHERE payment_id = $1
+			GROUP BY invoice_id
+		) a
+		WHERE a.invoice_id = i.id`, id)
+	if err != nil {
+		return fmt.Errorf("failed to reverse payment: %w", err)
+	}
 	return nil
 }
 
@@ -616,11 +687,12 @@ func (s *apServiceImpl) GetAgingReport(ctx context.Context) ([]models.VendorAgin
 			   COALESCE(SUM(i.balance_due), 0) as total
 		FROM vendors v
 		LEFT JOIN ap_invoices i ON v.id = i.vendor_id AND i.status NOT IN ('VOID', 'PAID')
+		WHERE v.company_id = $1
 		GROUP BY v.id, v.name, v.vendor_code
 		HAVING COALESCE(SUM(i.balance_due), 0) > 0
 		ORDER BY total DESC`
 
-	rows := s.db.Query(ctx, query)
+	rows := s.db.Query(ctx, query, tenant.Company(ctx))
 	defer rows.Close()
 
 	var report []models.VendorAging
@@ -697,3 +769,18 @@ func (s *apServiceImpl) generatePaymentNumber(ctx context.Context) string {
 	s.db.QueryRow(ctx, `SELECT COUNT(*) FROM ap_payments WHERE DATE(created_at) = CURRENT_DATE`).Scan(&count)
 	return fmt.Sprintf("PMT%s%04d", time.Now().Format("20060102"), count+1)
 }
+
+// checkVendor makes sure a document is entered for a vendor of the company
+// it is entered in.
+func (s *apServiceImpl) checkVendor(ctx context.Context, vendorID int) error {
+	var exists bool
+	err := s.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM vendors WHERE id = $1 AND company_id = $2)`,
+		vendorID, tenant.Company(ctx)).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to get vendor: %w", err)
+	}
+	if !exists {
+		return ErrVendorNotFound
+	}
+	return nil
+}
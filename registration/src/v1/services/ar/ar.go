@@ -2,14 +2,23 @@ package ar
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/anas-dev-92/FoodHive/core/postgres"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/query"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
 	"github.com/jackc/pgx/v5"
 )
 
+var (
+	ErrCustomerNotFound = errors.New("customer not found")
+	ErrInvoiceNotFound  = errors.New("invoice not found")
+)
+
 // ============================================
 // Service Interface
 // ============================================
@@ -23,6 +32,8 @@ type ARService interface {
 	PostInvoice(ctx context.Context, id int, postedBy int) error
 	VoidInvoice(ctx context.Context, id int) error
 	CreateFromOrder(ctx context.Context, orderID int, createdBy int) (int, error)
+	CreateCreditFromOrder(ctx context.Context, orderID int, createdBy int) (int, error)
+	CreateCreditMemo(ctx context.Context, req *models.CreateARCreditMemoRequest, createdBy int) (int, error)
 
 	// Payments
 	CreatePayment(ctx context.Context, req *models.CreateARPaymentRequest, receivedBy int) (int, error)
@@ -62,6 +73,10 @@ func New(db postgres.Executor) ARService {
 // ============================================
 
 func (s *arServiceImpl) CreateInvoice(ctx context.Context, req *models.CreateARInvoiceRequest, createdBy int) (int, error) {
+	if err := s.checkCustomer(ctx, req.CustomerID); err != nil {
+		return 0, err
+	}
+
 	invoiceNumber := s.generateInvoiceNumber(ctx)
 
 	invDate, _ := time.Parse("2006-01-02", req.InvoiceDate)
@@ -88,14 +103,14 @@ func (s *arServiceImpl) CreateInvoice(ctx context.Context, req *models.CreateARI
 		INSERT INTO ar_invoices (
 			invoice_number, customer_id, order_id, invoice_date, due_date, status,
 			subtotal, tax_amount, freight_amount, total_amount, balance_due,
-			currency, notes, created_by
-		) VALUES ($1, $2, $3, $4, $5, 'DRAFT', $6, $7, $8, $9, $9, 'USD', $10, $11)
+			currency, notes, created_by, company_id
+		) VALUES ($1, $2, $3, $4, $5, 'DRAFT', $6, $7, $8, $9, $9, 'USD', $10, $11, $12)
 		RETURNING id`
 
 	var id int
 	err := s.db.QueryRow(ctx, query,
 		invoiceNumber, req.CustomerID, req.OrderID, invDate, dueDate,
-		subtotal, req.TaxAmount, req.FreightAmount, totalAmount, req.Notes, createdBy,
+		subtotal, req.TaxAmount, req.FreightAmount, totalAmount, req.Notes, createdBy, tenant.Company(ctx),
 	).Scan(&id)
 
 	if err != nil {
@@ -109,12 +124,12 @@ func (s *arServiceImpl) CreateInvoice(ctx context.Context, req *models.CreateARI
 		lineQuery := `
 			INSERT INTO ar_invoice_lines (
 				invoice_id, line_number, product_id, description, quantity,
-				unit_price, tax_percent, line_total
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+				unit_price, tax_percent, line_total, order_line_id
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
 
 		_, err := s.db.Exec(ctx, lineQuery,
 			id, i+1, line.ProductID, line.Description, line.Quantity,
-			line.UnitPrice, line.TaxPercent, lineTotal,
+			line.UnitPrice, line.TaxPercent, lineTotal, line.OrderLineID,
 		)
 		if err != nil {
 			return 0, fmt.Errorf("failed to create invoice line: %w", err)
@@ -144,13 +159,13 @@ func (s *arServiceImpl) getInvoice(ctx context.Context, whereClause string, arg
 		FROM ar_invoices i
 		JOIN customers c ON i.customer_id = c.id
 		LEFT JOIN sales_orders so ON i.order_id = so.id
-		WHERE %s`, whereClause)
+		WHERE %s AND i.company_id = $2`, whereClause)
 
 	var inv models.ARInvoiceWithDetails
 	var notes *string
 	var postedAt *time.Time
 
-	err := s.db.QueryRow(ctx, query, arg).Scan(
+	err := s.db.QueryRow(ctx, query, arg, tenant.Company(ctx)).Scan(
 		&inv.Invoice.ID, &inv.Invoice.InvoiceNumber, &inv.Invoice.CustomerID, &inv.Invoice.OrderID,
 		&inv.Invoice.InvoiceDate, &inv.Invoice.DueDate, &inv.Invoice.Status,
 		&inv.Invoice.Subtotal, &inv.Invoice.TaxAmount, &inv.Invoice.FreightAmount,
@@ -173,11 +188,13 @@ func (s *arServiceImpl) getInvoice(ctx context.Context, whereClause string, arg
 
 	// Get lines
 	linesQuery := `
-		SELECT id, invoice_id, line_number, product_id, description, quantity,
-			   unit_price, tax_percent, line_total, order_line_id
-		FROM ar_invoice_lines
-		WHERE invoice_id = $1
-		ORDER BY line_number`
+		SELECT l.id, l.invoice_id, l.line_number, l.product_id, l.description, l.quantity,
+			   l.unit_price, l.tax_percent, l.line_total, l.order_line_id,
+			   COALESCE(sol.customer_item_code, '')
+		FROM ar_invoice_lines l
+		LEFT JOIN sales_order_lines sol ON sol.id = l.order_line_id
+		WHERE l.invoice_id = $1
+		ORDER BY l.line_number`
 
 	rows := s.db.Query(ctx, linesQuery, inv.Invoice.ID)
 	defer rows.Close()
@@ -186,7 +203,7 @@ func (s *arServiceImpl) getInvoice(ctx context.Context, whereClause string, arg
 		var line models.ARInvoiceLine
 		err := rows.Scan(&line.ID, &line.InvoiceID, &line.LineNumber, &line.ProductID,
 			&line.Description, &line.Quantity, &line.UnitPrice, &line.TaxPercent,
-			&line.LineTotal, &line.OrderLineID)
+			&line.LineTotal, &line.OrderLineID, &line.CustomerItemCode)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan invoice line: %w", err)
 		}
@@ -196,17 +213,33 @@ func (s *arServiceImpl) getInvoice(ctx context.Context, whereClause string, arg
 	return &inv, nil
 }
 
-func (s *arServiceImpl) ListInvoices(ctx context.Context, filters *models.ARInvoiceListFilters) ([]models.ARInvoiceWithDetails, int64, error) {
-	if filters.Page < 1 {
-		filters.Page = 1
-	}
-	if filters.PageSize < 1 || filters.PageSize > 100 {
-		filters.PageSize = 20
-	}
+// arInvoiceListSchema whitelists the fields clients may filter and sort on.
+var arInvoiceListSchema = query.Schema{
+	Fields: map[string]query.Field{
+		"id":             {Column: "i.id", Type: query.Int, Sortable: true},
+		"invoice_number": {Column: "i.invoice_number", Type: query.String, Sortable: true},
+		"customer_id":    {Column: "i.customer_id", Type: query.Int},
+		"order_id":       {Column: "i.order_id", Type: query.Int},
+		"invoice_date":   {Column: "i.invoice_date", Type: query.Date, Sortable: true},
+		"due_date":       {Column: "i.due_date", Type: query.Date, Sortable: true},
+		"status":         {Column: "i.status::text", Type: query.String},
+		"total_amount":   {Column: "i.total_amount", Type: query.Number, Sortable: true},
+		"amount_paid":    {Column: "i.amount_paid", Type: query.Number, Sortable: true},
+		"balance_due":    {Column: "i.balance_due", Type: query.Number, Sortable: true},
+		"currency":       {Column: "i.currency", Type: query.String},
+		"customer_name":  {Column: "c.name", Type: query.String, Sortable: true},
+		"customer_code":  {Column: "c.customer_code", Type: query.String},
+		"days_overdue":   {Column: "GREATEST(0, CURRENT_DATE - i.due_date)", Type: query.Int, Sortable: true},
+		"created_at":     {Column: "i.created_at", Type: query.Time, Sortable: true},
+	},
+	Default: []query.Sort{{Field: "invoice_date", Desc: true}},
+	Key:     "id",
+}
 
-	whereClause := "WHERE 1=1"
-	args := []interface{}{}
-	argNum := 1
+func (s *arServiceImpl) ListInvoices(ctx context.Context, filters *models.ARInvoiceListFilters) ([]models.ARInvoiceWithDetails, int64, error) {
+	whereClause := "WHERE i.company_id = $1"
+	args := []interface{}{tenant.Company(ctx)}
+	argNum := 2
 
 	if filters.CustomerID != nil {
 		whereClause += fmt.Sprintf(" AND i.customer_id = $%d", argNum)
@@ -231,37 +264,61 @@ func (s *arServiceImpl) ListInvoices(ctx context.Context, filters *models.ARInvo
 	if filters.Overdue {
 		whereClause += " AND i.due_date < CURRENT_DATE AND i.balance_due > 0"
 	}
+	if filters.ShipToIDs != nil {
+		whereClause += fmt.Sprintf(" AND i.order_id IN (SELECT id FROM sales_orders WHERE ship_to_id = ANY($%d))", argNum)
+		args = append(args, filters.ShipToIDs)
+		argNum++
+	}
+	if filters.PostedOnly {
+		whereClause += " AND i.status <> 'DRAFT'"
+	}
 
-	// Count
-	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM ar_invoices i %s`, whereClause)
+	params := query.Default(filters.Query, filters.Page, filters.PageSize)
+	q, err := query.Build(arInvoiceListSchema, params, argNum)
+	if err != nil {
+		return nil, 0, err
+	}
+	whereClause += q.Where
+	args = append(args, q.Args...)
+
+	// Count; keyset pages follow next_cursor instead
 	var total int64
-	s.db.QueryRow(ctx, countQuery, args...).Scan(&total)
+	if !params.Keyset() {
+		// Filters and the schema can refer to the joined customer columns
+		countQuery := fmt.Sprintf(`
+			SELECT COUNT(*)
+			FROM ar_invoices i
+			JOIN customers c ON i.customer_id = c.id
+			%s`, whereClause)
+		if err := s.db.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+			return nil, 0, fmt.Errorf("failed to count invoices: %w", err)
+		}
+	}
 
 	// Query
-	offset := (filters.Page - 1) * filters.PageSize
-	query := fmt.Sprintf(`
+	listQuery := fmt.Sprintf(`
 		SELECT i.id, i.invoice_number, i.customer_id, i.order_id, i.invoice_date, i.due_date,
 			   i.status, i.subtotal, i.tax_amount, i.freight_amount, i.discount_amount,
 			   i.total_amount, i.amount_paid, i.balance_due, i.currency, i.notes,
 			   i.posted_by, i.posted_at, i.created_by, i.created_at, i.updated_at,
 			   c.name as customer_name, c.customer_code,
 			   COALESCE(so.order_number, '') as order_number,
-			   GREATEST(0, CURRENT_DATE - i.due_date) as days_overdue
+			   GREATEST(0, CURRENT_DATE - i.due_date) as days_overdue, %s
 		FROM ar_invoices i
 		JOIN customers c ON i.customer_id = c.id
 		LEFT JOIN sales_orders so ON i.order_id = so.id
+		%s%s
 		%s
-		ORDER BY i.invoice_date DESC, i.id DESC
-		LIMIT $%d OFFSET $%d`, whereClause, argNum, argNum+1)
-
-	args = append(args, filters.PageSize, offset)
+		%s`, q.Cursor, whereClause, q.Keyset, q.OrderBy, q.Limit)
 
-	rows := s.db.Query(ctx, query, args...)
+	rows := s.db.Query(ctx, listQuery, append(args, q.PageArgs...)...)
 	defer rows.Close()
 
 	var invoices []models.ARInvoiceWithDetails
+	var cursors []string
 	for rows.Next() {
 		var inv models.ARInvoiceWithDetails
+		var cursor string
 		var notes *string
 		var postedAt *time.Time
 
@@ -272,7 +329,7 @@ func (s *arServiceImpl) ListInvoices(ctx context.Context, filters *models.ARInvo
 			&inv.Invoice.DiscountAmount, &inv.Invoice.TotalAmount, &inv.Invoice.AmountPaid,
 			&inv.Invoice.BalanceDue, &inv.Invoice.Currency, &notes, &inv.Invoice.PostedBy,
 			&postedAt, &inv.Invoice.CreatedBy, &inv.Invoice.CreatedAt, &inv.Invoice.UpdatedAt,
-			&inv.CustomerName, &inv.CustomerCode, &inv.OrderNumber, &inv.DaysOverdue,
+			&inv.CustomerName, &inv.CustomerCode, &inv.OrderNumber, &inv.DaysOverdue, &cursor,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan invoice: %w", err)
@@ -281,15 +338,19 @@ func (s *arServiceImpl) ListInvoices(ctx context.Context, filters *models.ARInvo
 			inv.Invoice.Notes = *notes
 		}
 		invoices = append(invoices, inv)
+		cursors = append(cursors, cursor)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list invoices: %w", err)
 	}
 
-	return invoices, total, nil
+	return invoices[:params.Trim(cursors)], total, nil
 }
 
 func (s *arServiceImpl) PostInvoice(ctx context.Context, id int, postedBy int) error {
 	result, err := s.db.Exec(ctx, `
 		UPDATE ar_invoices SET status = 'POSTED', posted_by = $1, posted_at = NOW(), updated_at = NOW()
-		WHERE id = $2 AND status = 'DRAFT'`, postedBy, id)
+		WHERE id = $2 AND company_id = $3 AND status = 'DRAFT'`, postedBy, id, tenant.Company(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to post invoice: %w", err)
 	}
@@ -306,7 +367,8 @@ func (s *arServiceImpl) VoidInvoice(ctx context.Context, id int) error {
 	// Get invoice info first
 	var status string
 	var customerID int
-	s.db.QueryRow(ctx, `SELECT status, customer_id FROM ar_invoices WHERE id = $1`, id).Scan(&status, &customerID)
+	s.db.QueryRow(ctx, `SELECT status, customer_id FROM ar_invoices WHERE id = $1 AND company_id = $2`,
+		id, tenant.Company(ctx)).Scan(&status, &customerID)
 
 	if status == "VOID" {
 		return fmt.Errorf("invoice already voided")
@@ -314,7 +376,7 @@ func (s *arServiceImpl) VoidInvoice(ctx context.Context, id int) error {
 
 	result, err := s.db.Exec(ctx, `
 		UPDATE ar_invoices SET status = 'VOID', updated_at = NOW()
-		WHERE id = $1 AND status != 'VOID'`, id)
+		WHERE id = $1 AND company_id = $2 AND status != 'VOID'`, id, tenant.Company(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to void invoice: %w", err)
 	}
@@ -326,43 +388,252 @@ func (s *arServiceImpl) VoidInvoice(ctx context.Context, id int) error {
 	if status == "POSTED" || status == "PARTIAL" {
 		s.updateCustomerBalance(ctx, id, false)
 	}
+
+	// What the invoice billed on its order can be invoiced again
+	_, err = s.db.Exec(ctx, `
+		UPDATE sales_order_lines sol SET quantity_invoiced = GREATEST(sol.quantity_invoiced - l.quantity, 0)
+		FROM ar_invoice_lines l
+		WHERE l.invoice_id = $1 AND l.order_line_id = sol.id`, id)
+	if err != nil {
+		return fmt.Errorf("failed to unbill order lines: %w", err)
+	}
 	return nil
 }
 
 func (s *arServiceImpl) CreateFromOrder(ctx context.Context, orderID int, createdBy int) (int, error) {
-	// Get order details
+	// Get order details. Pre-paid orders are invoiced once confirmed so the
+	// customer can pay before anything ships. Other orders bill what has
+	// shipped since their last invoice, so each partial shipment is billed
+	// as it goes out.
 	var customerID int
 	var subtotal, taxAmount, freightAmount float64
+	var prePaid, billedBefore bool
 	err := s.db.QueryRow(ctx, `
-		SELECT customer_id, subtotal, tax_amount, freight_amount
-		FROM sales_orders WHERE id = $1 AND status IN ('SHIPPED', 'DELIVERED')`, orderID).Scan(
-		&customerID, &subtotal, &taxAmount, &freightAmount)
+		SELECT customer_id, subtotal, tax_amount, freight_amount,
+			   order_type = 'PRE_PAID' AND status = 'CONFIRMED',
+			   EXISTS (
+				   SELECT 1 FROM ar_invoices i
+				   WHERE i.order_id = so.id AND i.status <> 'VOID' AND i.invoice_type = 'INVOICE'
+			   )
+		FROM sales_orders so WHERE id = $1 AND company_id = $2
+		AND (status IN ('SHIPPED', 'DELIVERED')
+			 OR (status = 'CONFIRMED' AND (order_type = 'PRE_PAID' OR actual_ship_date IS NOT NULL)))`,
+		orderID, tenant.Company(ctx)).Scan(
+		&customerID, &subtotal, &taxAmount, &freightAmount, &prePaid, &billedBefore)
 	if err != nil {
 		return 0, fmt.Errorf("order not found or not ready for invoicing")
 	}
 
 	// Create invoice request
 	req := &models.CreateARInvoiceRequest{
-		CustomerID:    customerID,
-		OrderID:       &orderID,
-		InvoiceDate:   time.Now().Format("2006-01-02"),
-		TaxAmount:     taxAmount,
-		FreightAmount: freightAmount,
+		CustomerID:  customerID,
+		OrderID:     &orderID,
+		InvoiceDate: time.Now().Format("2006-01-02"),
 	}
 
-	// Get order lines
+	// Get the order lines' unbilled quantities at their discounted price
 	rows := s.db.Query(ctx, `
-		SELECT product_id, description, quantity_shipped, unit_price, 0 as tax_percent
-		FROM sales_order_lines WHERE order_id = $1`, orderID)
+		SELECT id, product_id, COALESCE(description, ''),
+			   CASE WHEN $2 THEN quantity_ordered ELSE quantity_shipped END - quantity_invoiced,
+			   unit_price * (1 - COALESCE(discount_percent, 0) / 100), 0 as tax_percent
+		FROM sales_order_lines
+		WHERE order_id = $1 AND CASE WHEN $2 THEN quantity_ordered ELSE quantity_shipped END > quantity_invoiced
+		ORDER BY line_number`, orderID, prePaid)
 	defer rows.Close()
 
+	var billed float64
 	for rows.Next() {
 		var line models.CreateARInvoiceLineReq
-		rows.Scan(&line.ProductID, &line.Description, &line.Quantity, &line.UnitPrice, &line.TaxPercent)
+		var lineID int
+		err := rows.Scan(&lineID, &line.ProductID, &line.Description, &line.Quantity, &line.UnitPrice, &line.TaxPercent)
+		if err != nil {
+			return 0, fmt.Errorf("failed to scan order line: %w", err)
+		}
+		line.OrderLineID = &lineID
+		billed += line.Quantity * line.UnitPrice
 		req.Lines = append(req.Lines, line)
 	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to get order lines: %w", err)
+	}
+	if len(req.Lines) == 0 {
+		return 0, fmt.Errorf("order has nothing shipped that is not yet invoiced")
+	}
+
+	// Tax follows the share of the order billed; freight is billed once
+	if subtotal > 0 {
+		req.TaxAmount = math.Round(taxAmount*billed/subtotal*100) / 100
+	}
+	if !billedBefore {
+		req.FreightAmount = freightAmount
+	}
+
+	id, err := s.CreateInvoice(ctx, req, createdBy)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = s.db.Exec(ctx, `
+		UPDATE sales_order_lines sol SET quantity_invoiced = sol.quantity_invoiced + l.quantity
+		FROM ar_invoice_lines l
+		WHERE l.invoice_id = $1 AND l.order_line_id = sol.id`, id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record invoiced quantities: %w", err)
+	}
+	return id, nil
+}
+
+// CreateCreditFromOrder posts the AR credit for a credit memo order, or for
+// what a cancelled pre-paid order was billed and will not ship. Credits are
+// stored as invoices with negative amounts, so the customer balance, aging
+// and statements net them against open invoices.
+func (s *arServiceImpl) CreateCreditFromOrder(ctx context.Context, orderID int, createdBy int) (int, error) {
+	var customerID int
+	var orderNumber string
+	var subtotal, taxAmount, freightAmount float64
+	var prePaid, shipped bool
+	err := s.db.QueryRow(ctx, `
+		SELECT customer_id, order_number, subtotal, tax_amount, freight_amount,
+			   order_type = 'PRE_PAID',
+			   EXISTS (SELECT 1 FROM sales_order_lines WHERE order_id = so.id AND quantity_shipped > 0)
+		FROM sales_orders so WHERE id = $1 AND company_id = $2
+		AND (order_type = 'CREDIT_MEMO' OR (order_type = 'PRE_PAID' AND status IN ('CANCELLED', 'SHIPPED')))`,
+		orderID, tenant.Company(ctx)).Scan(
+		&customerID, &orderNumber, &subtotal, &taxAmount, &freightAmount, &prePaid, &shipped)
+	if err != nil {
+		return 0, fmt.Errorf("credit memo order not found")
+	}
+
+	notes := "Credit memo " + orderNumber
+	if prePaid {
+		// Only the billed quantities that did not ship are credited. Tax
+		// follows their share of the order; freight is credited only when
+		// nothing went out.
+		var credited float64
+		err := s.db.QueryRow(ctx, `
+			SELECT COALESCE(SUM((quantity_invoiced - quantity_shipped) * unit_price * (1 - COALESCE(discount_percent, 0) / 100)), 0)
+			FROM sales_order_lines WHERE order_id = $1 AND quantity_invoiced > quantity_shipped`,
+			orderID).Scan(&credited)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get order lines: %w", err)
+		}
+		if credited <= 0 {
+			return 0, fmt.Errorf("order has nothing billed that is not shipped")
+		}
+		if subtotal > 0 {
+			taxAmount = math.Round(taxAmount*credited/subtotal*100) / 100
+		}
+		subtotal = math.Round(credited*100) / 100
+		if shipped {
+			freightAmount = 0
+		}
+		notes = "Cancelled order " + orderNumber
+	}
+
+	total := subtotal + taxAmount + freightAmount
+
+	var id int
+	err = s.db.QueryRow(ctx, `
+		INSERT INTO ar_invoices (
+			invoice_number, invoice_type, customer_id, order_id, invoice_date, due_date, status,
+			subtotal, tax_amount, freight_amount, total_amount, balance_due,
+			currency, notes, posted_by, posted_at, created_by, company_id
+		) VALUES ($1, 'CREDIT_MEMO', $2, $3, CURRENT_DATE, CURRENT_DATE, 'POSTED',
+			$4, $5, $6, $7, $7, 'USD', $8, NULLIF($9, 0), NOW(), NULLIF($9, 0), $10)
+		RETURNING id`,
+		s.generateCreditNumber(ctx), customerID, orderID,
+		-subtotal, -taxAmount, -freightAmount, -total,
+		notes, createdBy, tenant.Company(ctx),
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create AR credit: %w", err)
+	}
+
+	if prePaid {
+		_, err = s.db.Exec(ctx, `
+			INSERT INTO ar_invoice_lines (
+				invoice_id, line_number, product_id, description, quantity,
+				unit_price, tax_percent, line_total, order_line_id
+			)
+			SELECT $1, line_number, product_id, COALESCE(description, ''), -(quantity_invoiced - quantity_shipped),
+				   unit_price * (1 - COALESCE(discount_percent, 0) / 100), 0,
+				   -ROUND((quantity_invoiced - quantity_shipped) * unit_price * (1 - COALESCE(discount_percent, 0) / 100), 2), id
+			FROM sales_order_lines WHERE order_id = $2 AND quantity_invoiced > quantity_shipped`, id, orderID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create AR credit lines: %w", err)
+		}
+
+		// The credited quantities are no longer billed
+		_, err = s.db.Exec(ctx, `
+			UPDATE sales_order_lines SET quantity_invoiced = quantity_shipped
+			WHERE order_id = $1 AND quantity_invoiced > quantity_shipped`, orderID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to unbill order lines: %w", err)
+		}
+	} else {
+		_, err = s.db.Exec(ctx, `
+			INSERT INTO ar_invoice_lines (
+				invoice_id, line_number, product_id, description, quantity,
+				unit_price, tax_percent, line_total
+			)
+			SELECT $1, line_number, product_id, COALESCE(description, ''), -quantity_ordered,
+				   unit_price, 0, -line_total
+			FROM sales_order_lines WHERE order_id = $2`, id, orderID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create AR credit lines: %w", err)
+		}
+	}
 
-	return s.CreateInvoice(ctx, req, createdBy)
+	// Posted straight away; the negative total lowers the customer balance
+	s.updateCustomerBalance(ctx, id, true)
+	return id, nil
+}
+
+// CreateCreditMemo posts a credit memo for the lines given, such as the
+// goods on an approved return.
+func (s *arServiceImpl) CreateCreditMemo(ctx context.Context, req *models.CreateARCreditMemoRequest, createdBy int) (int, error) {
+	if err := s.checkCustomer(ctx, req.CustomerID); err != nil {
+		return 0, err
+	}
+
+	var subtotal float64
+	for _, line := range req.Lines {
+		subtotal += line.Quantity * line.UnitPrice
+	}
+	total := subtotal + req.TaxAmount
+
+	var id int
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO ar_invoices (
+			invoice_number, invoice_type, customer_id, order_id, invoice_date, due_date, status,
+			subtotal, tax_amount, freight_amount, total_amount, balance_due,
+			currency, notes, posted_by, posted_at, created_by, company_id
+		) VALUES ($1, 'CREDIT_MEMO', $2, $3, CURRENT_DATE, CURRENT_DATE, 'POSTED',
+			$4, $5, 0, $6, $6, 'USD', $7, $8, NOW(), $8, $9)
+		RETURNING id`,
+		s.generateCreditNumber(ctx), req.CustomerID, req.OrderID,
+		-subtotal, -req.TaxAmount, -total, req.Notes, createdBy, tenant.Company(ctx),
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create AR credit: %w", err)
+	}
+
+	for i, line := range req.Lines {
+		_, err := s.db.Exec(ctx, `
+			INSERT INTO ar_invoice_lines (
+				invoice_id, line_number, product_id, description, quantity,
+				unit_price, tax_percent, line_total, order_line_id
+			) VALUES ($1, $2, $3, $4, $5, $6, 0, $7, $8)`,
+			id, i+1, line.ProductID, line.Description, -line.Quantity,
+			line.UnitPrice, -line.Quantity*line.UnitPrice, line.OrderLineID,
+		)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create AR credit lines: %w", err)
+		}
+	}
+
+	s.updateCustomerBalance(ctx, id, true)
+	return id, nil
 }
 
 // ============================================
@@ -370,20 +641,38 @@ func (s *arServiceImpl) CreateFromOrder(ctx context.Context, orderID int, create
 // ============================================
 
 func (s *arServiceImpl) CreatePayment(ctx context.Context, req *models.CreateARPaymentRequest, receivedBy int) (int, error) {
+	if err := s.checkCustomer(ctx, req.CustomerID); err != nil {
+		return 0, err
+	}
+
+	// Payments only settle the customer's own invoices
+	for _, app := range req.Applications {
+		var exists bool
+		err := s.db.QueryRow(ctx, `
+			SELECT EXISTS (SELECT 1 FROM ar_invoices WHERE id = $1 AND customer_id = $2 AND company_id = $3)`,
+			app.InvoiceID, req.CustomerID, tenant.Company(ctx)).Scan(&exists)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get invoice: %w", err)
+		}
+		if !exists {
+			return 0, ErrInvoiceNotFound
+		}
+	}
+
 	receiptNumber := s.generateReceiptNumber(ctx)
 	paymentDate, _ := time.Parse("2006-01-02", req.PaymentDate)
 
 	query := `
 		INSERT INTO ar_payments (
 			receipt_number, customer_id, payment_date, payment_method, amount,
-			currency, reference_no, check_number, notes, received_by
-		) VALUES ($1, $2, $3, $4, $5, 'USD', $6, $7, $8, $9)
+			currency, reference_no, check_number, notes, received_by, company_id
+		) VALUES ($1, $2, $3, $4, $5, 'USD', $6, $7, $8, $9, $10)
 		RETURNING id`
 
 	var id int
 	err := s.db.QueryRow(ctx, query,
 		receiptNumber, req.CustomerID, paymentDate, req.PaymentMethod, req.Amount,
-		req.ReferenceNo, req.CheckNumber, req.Notes, receivedBy,
+		req.ReferenceNo, req.CheckNumber, req.Notes, receivedBy, tenant.Company(ctx),
 	).Scan(&id)
 
 	if err != nil {
@@ -400,7 +689,7 @@ func (s *arServiceImpl) CreatePayment(ctx context.Context, req *models.CreateARP
 		}
 
 		// Update invoice
-		s.db.Exec(ctx, `
+		_, err = s.db.Exec(ctx, `
 			UPDATE ar_invoices SET
 				amount_paid = amount_paid + $1,
 				balance_due = balance_due - $1,
@@ -409,12 +698,18 @@ func (s *arServiceImpl) CreatePayment(ctx context.Context, req *models.CreateARP
 					ELSE 'PARTIAL'::ar_invoice_status
 				END,
 				updated_at = NOW()
-			WHERE id = $2`, app.Amount, app.InvoiceID)
+			WHERE id = $2 AND customer_id = $3 AND company_id = $4`, app.Amount, app.InvoiceID, req.CustomerID, tenant.Company(ctx))
+		if err != nil {
+			return 0, fmt.Errorf("failed to update invoice: %w", err)
+		}
 	}
 
 	// Update customer balance
-	s.db.Exec(ctx, `UPDATE customers SET current_balance = current_balance - $1 WHERE id = $2`,
+	_, err = s.db.Exec(ctx, `UPDATE customers SET current_balance = current_balance - $1 WHERE id = $2`,
 		req.Amount, req.CustomerID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update customer balance: %w", err)
+	}
 
 	return id, nil
 }
@@ -429,13 +724,13 @@ func (s *arServiceImpl) GetPayment(ctx context.Context, id int) (*models.ARPayme
 		FROM ar_payments p
 		JOIN customers c ON p.customer_id = c.id
 		LEFT JOIN employees e ON p.received_by = e.id
-		WHERE p.id = $1`
+		WHERE p.id = $1 AND p.company_id = $2`
 
 	var pay models.ARPaymentWithDetails
 	var refNo, checkNo, bankAcc, notes *string
 	var postedAt *time.Time
 
-	err := s.db.QueryRow(ctx, query, id).Scan(
+	err := s.db.QueryRow(ctx, query, id, tenant.Company(ctx)).Scan(
 		&pay.Payment.ID, &pay.Payment.ReceiptNumber, &pay.Payment.CustomerID, &pay.Payment.PaymentDate,
 		&pay.Payment.PaymentMethod, &pay.Payment.Amount, &pay.Payment.Currency,
 		&refNo, &checkNo, &bankAcc, &notes, &pay.Payment.ReceivedBy, &postedAt, &pay.Payment.CreatedAt,
@@ -479,9 +774,9 @@ func (s *arServiceImpl) ListPayments(ctx context.Context, customerID *int, limit
 		limit = 100
 	}
 
-	whereClause := "WHERE 1=1"
-	args := []interface{}{}
-	argNum := 1
+	whereClause := "WHERE p.company_id = $1"
+	args := []interface{}{tenant.Company(ctx)}
+	argNum := 2
 
 	if customerID != nil {
 		whereClause += fmt.Sprintf(" AND p.customer_id = $%d", argNum)
@@ -548,16 +843,16 @@ func (s *arServiceImpl) GetCustomerCredit(ctx context.Context, customerID int) (
 			   COALESCE((SELECT SUM(balance_due) FROM ar_invoices WHERE customer_id = c.id AND due_date < CURRENT_DATE AND balance_due > 0), 0) as total_overdue,
 			   COALESCE((SELECT MAX(CURRENT_DATE - due_date) FROM ar_invoices WHERE customer_id = c.id AND due_date < CURRENT_DATE AND balance_due > 0), 0) as oldest_overdue
 		FROM customers c
-		WHERE c.id = $1`
+		WHERE c.id = $1 AND c.company_id = $2`
 
 	var credit models.CustomerCredit
-	err := s.db.QueryRow(ctx, query, customerID).Scan(
+	err := s.db.QueryRow(ctx, query, customerID, tenant.Company(ctx)).Scan(
 		&credit.CustomerID, &credit.CustomerName, &credit.CreditLimit, &credit.CurrentBalance,
 		&credit.PaymentTermsDays, &credit.AvailableCredit, &credit.TotalOverdue, &credit.OldestOverdue,
 	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("customer not found")
+			return nil, ErrCustomerNotFound
 		}
 		return nil, fmt.Errorf("failed to get customer credit: %w", err)
 	}
@@ -585,12 +880,13 @@ func (s *arServiceImpl) CheckCreditAvailable(ctx context.Context, customerID int
 }
 
 func (s *arServiceImpl) UpdateCreditLimit(ctx context.Context, customerID int, newLimit float64) error {
-	result, err := s.db.Exec(ctx, `UPDATE customers SET credit_limit = $1 WHERE id = $2`, newLimit, customerID)
+	result, err := s.db.Exec(ctx, `UPDATE customers SET credit_limit = $1 WHERE id = $2 AND company_id = $3`,
+		newLimit, customerID, tenant.Company(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to update credit limit: %w", err)
 	}
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("customer not found")
+		return ErrCustomerNotFound
 	}
 	return nil
 }
@@ -610,18 +906,18 @@ func (s *arServiceImpl) GetCustomerAging(ctx context.Context, customerID int) (*
 			   COALESCE(SUM(i.balance_due), 0) as total
 		FROM customers c
 		LEFT JOIN ar_invoices i ON c.id = i.customer_id AND i.status NOT IN ('DRAFT', 'VOID', 'PAID')
-		WHERE c.id = $1
+		WHERE c.id = $1 AND c.company_id = $2
 		GROUP BY c.id, c.name, c.customer_code`
 
 	var aging models.CustomerAging
-	err := s.db.QueryRow(ctx, query, customerID).Scan(
+	err := s.db.QueryRow(ctx, query, customerID, tenant.Company(ctx)).Scan(
 		&aging.CustomerID, &aging.CustomerName, &aging.CustomerCode,
 		&aging.Aging.Current, &aging.Aging.Days1_30, &aging.Aging.Days31_60,
 		&aging.Aging.Days61_90, &aging.Aging.Over90, &aging.Aging.Total,
 	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("customer not found")
+			return nil, ErrCustomerNotFound
 		}
 		return nil, fmt.Errorf("failed to get customer aging: %w", err)
 	}
@@ -640,11 +936,12 @@ func (s *arServiceImpl) GetAgingReport(ctx context.Context) ([]models.CustomerAg
 			   COALESCE(SUM(i.balance_due), 0) as total
 		FROM customers c
 		LEFT JOIN ar_invoices i ON c.id = i.customer_id AND i.status NOT IN ('DRAFT', 'VOID', 'PAID')
+		WHERE c.company_id = $1
 		GROUP BY c.id, c.name, c.customer_code
 		HAVING COALESCE(SUM(i.balance_due), 0) > 0
 		ORDER BY total DESC`
 
-	rows := s.db.Query(ctx, query)
+	rows := s.db.Query(ctx, query, tenant.Company(ctx))
 	defer rows.Close()
 
 	var report []models.CustomerAging
@@ -671,11 +968,15 @@ func (s *arServiceImpl) GetAgingReport(ctx context.Context) ([]models.CustomerAg
 func (s *arServiceImpl) GetStatement(ctx context.Context, customerID int, fromDate, toDate string) (*models.CustomerStatement, error) {
 	// Get customer info
 	var stmt models.CustomerStatement
-	err := s.db.QueryRow(ctx, `SELECT id, name, customer_code FROM customers WHERE id = $1`, customerID).Scan(
+	err := s.db.QueryRow(ctx, `SELECT id, name, customer_code FROM customers WHERE id = $1 AND company_id = $2`,
+		customerID, tenant.Company(ctx)).Scan(
 		&stmt.CustomerID, &stmt.CustomerName, &stmt.CustomerCode,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("customer not found")
+		if err == pgx.ErrNoRows {
+			return nil, ErrCustomerNotFound
+		}
+		return nil, fmt.Errorf("failed to get customer: %w", err)
 	}
 
 	// Get opening balance
@@ -757,6 +1058,12 @@ func (s *arServiceImpl) generateInvoiceNumber(ctx context.Context) string {
 	return fmt.Sprintf("INV%s%04d", time.Now().Format("20060102"), count+1)
 }
 
+func (s *arServiceImpl) generateCreditNumber(ctx context.Context) string {
+	var count int64
+	s.db.QueryRow(ctx, `SELECT COUNT(*) FROM ar_invoices WHERE DATE(created_at) = CURRENT_DATE AND invoice_type = 'CREDIT_MEMO'`).Scan(&count)
+	return fmt.Sprintf("CM%s%04d", time.Now().Format("20060102"), count+1)
+}
+
 func (s *arServiceImpl) generateReceiptNumber(ctx context.Context) string {
 	var count int64
 	s.db.QueryRow(ctx, `SELECT COUNT(*) FROM ar_payments WHERE DATE(created_at) = CURRENT_DATE`).Scan(&count)
@@ -774,3 +1081,18 @@ func (s *arServiceImpl) updateCustomerBalance(ctx context.Context, invoiceID int
 		s.db.Exec(ctx, `UPDATE customers SET current_balance = current_balance - $1 WHERE id = $2`, amount, customerID)
 	}
 }
+
+// checkCustomer makes sure a document is raised for a customer of the
+// company it is entered in.
+func (s *arServiceImpl) checkCustomer(ctx context.Context, customerID int) error {
+	var exists bool
+	err := s.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM customers WHERE id = $1 AND company_id = $2)`,
+		customerID, tenant.Company(ctx)).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to get customer: %w", err)
+	}
+	if !exists {
+		return ErrCustomerNotFound
+	}
+	return nil
+}
@@ -51,10 +51,10 @@ type CatchWeightService interface {
 }
 
 type catchWeightServiceImpl struct {
-	db postgres.Connection
+	db postgres.Executor
 }
 
-func New(db postgres.Connection) CatchWeightService {
+func New(db postgres.Executor) CatchWeightService {
 	return &catchWeightServiceImpl{db: db}
 }
 
@@ -0,0 +1,504 @@
+package commission
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrPlanNotFound       = errors.New("commission plan not found")
+	ErrDuplicatePlan      = errors.New("a commission plan with this name already exists")
+	ErrPlanInUse          = errors.New("commission plan is assigned to sales reps")
+	ErrPlanInactive       = errors.New("commission plan is not active")
+	ErrAssignmentNotFound = errors.New("commission assignment not found")
+	ErrAssignmentOverlap  = errors.New("sales rep already has a commission plan for these dates")
+	ErrAssignmentEndDate  = errors.New("commission assignment cannot end before it starts")
+	ErrSalesRepNotFound   = errors.New("sales rep not found")
+	ErrStatementNotFound  = errors.New("commission statement not found")
+	ErrStatementLocked    = errors.New("commission statement is approved and cannot be recalculated")
+	ErrIllegalTransition  = errors.New("commission statement cannot move to that status")
+	ErrNoCommissionForRep = errors.New("sales rep has no commission plan in the period")
+)
+
+// ============================================
+// Service Interface
+// ============================================
+
+type CommissionService interface {
+	// Plans
+	CreatePlan(ctx context.Context, req *models.CreateCommissionPlanRequest, createdBy int) (int, error)
+	GetPlan(ctx context.Context, id int) (*models.CommissionPlan, error)
+	UpdatePlan(ctx context.Context, id int, req *models.UpdateCommissionPlanRequest) error
+	DeletePlan(ctx context.Context, id int) error
+	ListPlans(ctx context.Context, activeOnly bool) ([]models.CommissionPlan, error)
+
+	// Assignments
+	AssignPlan(ctx context.Context, req *models.AssignCommissionPlanRequest, createdBy int) (int, error)
+	EndAssignment(ctx context.Context, id int, req *models.EndCommissionAssignmentRequest) error
+	DeleteAssignment(ctx context.Context, id int) error
+	ListAssignments(ctx context.Context, filters *models.CommissionAssignmentFilters) ([]models.CommissionAssignment, error)
+
+	// Statements
+	GenerateStatements(ctx context.Context, req *models.GenerateCommissionStatementsRequest) ([]models.CommissionStatement, error)
+	GetStatement(ctx context.Context, id int) (*models.CommissionStatement, error)
+	ListStatements(ctx context.Context, filters *models.CommissionStatementFilters) ([]models.CommissionStatement, error)
+	ApproveStatement(ctx context.Context, id int, approvedBy int) error
+	PayStatements(ctx context.Context, req *models.PayCommissionStatementsRequest) error
+	GetPayrollTotals(ctx context.Context, filters *models.CommissionStatementFilters) ([]models.CommissionPayrollTotal, error)
+}
+
+// ============================================
+// Service Implementation
+// ============================================
+
+type commissionServiceImpl struct {
+	db postgres.Executor
+}
+
+func New(db postgres.Executor) CommissionService {
+	return &commissionServiceImpl{db: db}
+}
+
+// inTx runs fn in a transaction. When the service was built on a caller's
+// transaction, fn runs in that one.
+func (s *commissionServiceImpl) inTx(ctx context.Context, fn func(tx *commissionServiceImpl) error) error {
+	conn, ok := s.db.(postgres.Connection)
+	if !ok {
+		return fn(s)
+	}
+
+	tx, err := conn.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&commissionServiceImpl{db: tx}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ============================================
+// Plans
+// ============================================
+
+func (s *commissionServiceImpl) CreatePlan(ctx context.Context, req *models.CreateCommissionPlanRequest, createdBy int) (int, error) {
+	basis := req.Basis
+	if basis == "" {
+		basis = models.CommissionOnRevenue
+	}
+	earnOn := req.EarnOn
+	if earnOn == "" {
+		earnOn = models.CommissionEarnedInvoiced
+	}
+	newCustomerDays := 365
+	if req.NewCustomerDays != nil {
+		newCustomerDays = *req.NewCustomerDays
+	}
+
+	var id int
+	err := s.inTx(ctx, func(tx *commissionServiceImpl) error {
+		if err := tx.checkPlanName(ctx, req.Name, 0); err != nil {
+			return err
+		}
+
+		err := tx.db.QueryRow(ctx, `
+			INSERT INTO commission_plans (
+				company_id, name, description, basis, earn_on, base_rate,
+				new_customer_bonus_rate, new_customer_days, created_by
+			) VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8, NULLIF($9, 0))
+			RETURNING id`,
+			tenant.Company(ctx), req.Name, req.Description, basis, earnOn, req.BaseRate,
+			req.NewCustomerBonusRate, newCustomerDays, createdBy,
+		).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("failed to create commission plan: %w", err)
+		}
+		return tx.replaceRates(ctx, id, req.Tiers, req.CategoryRates)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+const planSelect = `
+	SELECT id, name, COALESCE(description, ''), basis, earn_on, base_rate,
+		   new_customer_bonus_rate, new_customer_days, is_active, created_by, created_at, updated_at
+	FROM commission_plans`
+
+func scanPlan(row pgx.Row, p *models.CommissionPlan) error {
+	return row.Scan(
+		&p.ID, &p.Name, &p.Description, &p.Basis, &p.EarnOn, &p.BaseRate,
+		&p.NewCustomerBonusRate, &p.NewCustomerDays, &p.IsActive, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt,
+	)
+}
+
+func (s *commissionServiceImpl) GetPlan(ctx context.Context, id int) (*models.CommissionPlan, error) {
+	var p models.CommissionPlan
+	err := scanPlan(s.db.QueryRow(ctx, planSelect+` WHERE id = $1 AND company_id = $2`, id, tenant.Company(ctx)), &p)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrPlanNotFound
+		}
+		return nil, fmt.Errorf("failed to get commission plan: %w", err)
+	}
+
+	if err := s.loadRates(ctx, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *commissionServiceImpl) UpdatePlan(ctx context.Context, id int, req *models.UpdateCommissionPlanRequest) error {
+	return s.inTx(ctx, func(tx *commissionServiceImpl) error {
+		if req.Name != nil {
+			if err := tx.checkPlanName(ctx, *req.Name, id); err != nil {
+				return err
+			}
+		}
+
+		tag, err := tx.db.Exec(ctx, `
+			UPDATE commission_plans SET
+				name = COALESCE($3, name),
+				description = COALESCE($4, description),
+				basis = COALESCE($5, basis),
+				earn_on = COALESCE($6, earn_on),
+				base_rate = COALESCE($7, base_rate),
+				new_customer_bonus_rate = COALESCE($8, new_customer_bonus_rate),
+				new_customer_days = COALESCE($9, new_customer_days),
+				is_active = COALESCE($10, is_active),
+				updated_at = NOW()
+			WHERE id = $1 AND company_id = $2`,
+			id, tenant.Company(ctx), req.Name, req.Description, req.Basis, req.EarnOn, req.BaseRate,
+			req.NewCustomerBonusRate, req.NewCustomerDays, req.IsActive)
+		if err != nil {
+			return fmt.Errorf("failed to update commission plan: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrPlanNotFound
+		}
+
+		if req.Tiers != nil {
+			if _, err := tx.db.Exec(ctx, `DELETE FROM commission_plan_tiers WHERE plan_id = $1`, id); err != nil {
+				return fmt.Errorf("failed to clear commission tiers: %w", err)
+			}
+		}
+		if req.CategoryRates != nil {
+			if _, err := tx.db.Exec(ctx, `DELETE FROM commission_plan_category_rates WHERE plan_id = $1`, id); err != nil {
+				return fmt.Errorf("failed to clear category rates: %w", err)
+			}
+		}
+		return tx.replaceRates(ctx, id, req.Tiers, req.CategoryRates)
+	})
+}
+
+// DeletePlan removes a plan that has never been assigned. Plans that have
+// been are deactivated instead, so their statements keep their history.
+func (s *commissionServiceImpl) DeletePlan(ctx context.Context, id int) error {
+	var assigned bool
+	err := s.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM commission_assignments WHERE plan_id = $1)
+		FROM commission_plans WHERE id = $1 AND company_id = $2`,
+		id, tenant.Company(ctx)).Scan(&assigned)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrPlanNotFound
+		}
+		return fmt.Errorf("failed to get commission plan: %w", err)
+	}
+	if assigned {
+		return ErrPlanInUse
+	}
+
+	_, err = s.db.Exec(ctx, `DELETE FROM commission_plans WHERE id = $1 AND company_id = $2`, id, tenant.Company(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to delete commission plan: %w", err)
+	}
+	return nil
+}
+
+func (s *commissionServiceImpl) ListPlans(ctx context.Context, activeOnly bool) ([]models.CommissionPlan, error) {
+	rows := s.db.Query(ctx, planSelect+` WHERE company_id = $1 AND (is_active OR NOT $2) ORDER BY name`,
+		tenant.Company(ctx), activeOnly)
+
+	plans := []models.CommissionPlan{}
+	for rows.Next() {
+		var p models.CommissionPlan
+		if err := scanPlan(rows, &p); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan commission plan: %w", err)
+		}
+		plans = append(plans, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list commission plans: %w", err)
+	}
+
+	for i := range plans {
+		if err := s.loadRates(ctx, &plans[i]); err != nil {
+			return nil, err
+		}
+	}
+	return plans, nil
+}
+
+// loadRates reads a plan's tiers, lowest first, and its category rates.
+func (s *commissionServiceImpl) loadRates(ctx context.Context, p *models.CommissionPlan) error {
+	p.Tiers = []models.CommissionTier{}
+	rows := s.db.Query(ctx, `
+		SELECT threshold, rate FROM commission_plan_tiers WHERE plan_id = $1 ORDER BY threshold`, p.ID)
+	for rows.Next() {
+		var t models.CommissionTier
+		if err := rows.Scan(&t.Threshold, &t.Rate); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan commission tier: %w", err)
+		}
+		p.Tiers = append(p.Tiers, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get commission tiers: %w", err)
+	}
+
+	p.CategoryRates = []models.CommissionCategoryRate{}
+	rows = s.db.Query(ctx, `
+		SELECT cr.category_id, COALESCE(pc.name, ''), cr.rate
+		FROM commission_plan_category_rates cr
+		LEFT JOIN product_categories pc ON pc.id = cr.category_id
+		WHERE cr.plan_id = $1
+		ORDER BY pc.name`, p.ID)
+	defer rows.Close()
+	for rows.Next() {
+		var c models.CommissionCategoryRate
+		if err := rows.Scan(&c.CategoryID, &c.CategoryName, &c.Rate); err != nil {
+			return fmt.Errorf("failed to scan category rate: %w", err)
+		}
+		p.CategoryRates = append(p.CategoryRates, c)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get category rates: %w", err)
+	}
+	return nil
+}
+
+// replaceRates adds the plan's tiers and category rates. Callers clear
+// the ones being replaced first.
+func (s *commissionServiceImpl) replaceRates(ctx context.Context, planID int, tiers []models.CommissionTier, categoryRates []models.CommissionCategoryRate) error {
+	for _, t := range tiers {
+		_, err := s.db.Exec(ctx, `
+			INSERT INTO commission_plan_tiers (plan_id, threshold, rate) VALUES ($1, $2, $3)`,
+			planID, t.Threshold, t.Rate)
+		if err != nil {
+			return fmt.Errorf("failed to add commission tier: %w", err)
+		}
+	}
+	for _, c := range categoryRates {
+		_, err := s.db.Exec(ctx, `
+			INSERT INTO commission_plan_category_rates (plan_id, category_id, rate) VALUES ($1, $2, $3)`,
+			planID, c.CategoryID, c.Rate)
+		if err != nil {
+			return fmt.Errorf("failed to add category rate: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *commissionServiceImpl) checkPlanName(ctx context.Context, name string, exceptID int) error {
+	var exists bool
+	err := s.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM commission_plans WHERE company_id = $1 AND name = $2 AND id <> $3)`,
+		tenant.Company(ctx), name, exceptID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check commission plan name: %w", err)
+	}
+	if exists {
+		return ErrDuplicatePlan
+	}
+	return nil
+}
+
+// ============================================
+// Assignments
+// ============================================
+
+// AssignPlan puts a rep on a plan from a date. A rep is on one plan at a
+// time, so the dates may not overlap another of the rep's assignments.
+func (s *commissionServiceImpl) AssignPlan(ctx context.Context, req *models.AssignCommissionPlanRequest, createdBy int) (int, error) {
+	var id int
+	err := s.inTx(ctx, func(tx *commissionServiceImpl) error {
+		var active bool
+		err := tx.db.QueryRow(ctx, `
+			SELECT is_active FROM commission_plans WHERE id = $1 AND company_id = $2`,
+			req.PlanID, tenant.Company(ctx)).Scan(&active)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrPlanNotFound
+			}
+			return fmt.Errorf("failed to get commission plan: %w", err)
+		}
+		if !active {
+			return ErrPlanInactive
+		}
+
+		// Serialize assignments for the rep while checking the dates
+		var repID int
+		err = tx.db.QueryRow(ctx, `SELECT id FROM employees WHERE id = $1 FOR UPDATE`, req.SalesRepID).Scan(&repID)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrSalesRepNotFound
+			}
+			return fmt.Errorf("failed to get sales rep: %w", err)
+		}
+
+		to := parseDate(req.EffectiveTo)
+		if err := tx.checkOverlap(ctx, req.SalesRepID, 0, parseDate(req.EffectiveFrom), to); err != nil {
+			return err
+		}
+
+		err = tx.db.QueryRow(ctx, `
+			INSERT INTO commission_assignments (
+				company_id, sales_rep_id, plan_id, effective_from, effective_to, created_by
+			) VALUES ($1, $2, $3, $4, $5, NULLIF($6, 0))
+			RETURNING id`,
+			tenant.Company(ctx), req.SalesRepID, req.PlanID, parseDate(req.EffectiveFrom), to, createdBy,
+		).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("failed to assign commission plan: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// EndAssignment closes an open assignment, or moves its end date, so the
+// rep can be put on another plan from the day after.
+func (s *commissionServiceImpl) EndAssignment(ctx context.Context, id int, req *models.EndCommissionAssignmentRequest) error {
+	return s.inTx(ctx, func(tx *commissionServiceImpl) error {
+		var repID int
+		var from time.Time
+		err := tx.db.QueryRow(ctx, `
+			SELECT sales_rep_id, effective_from FROM commission_assignments
+			WHERE id = $1 AND company_id = $2 FOR UPDATE`,
+			id, tenant.Company(ctx)).Scan(&repID, &from)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrAssignmentNotFound
+			}
+			return fmt.Errorf("failed to get commission assignment: %w", err)
+		}
+
+		to := parseDate(req.EffectiveTo)
+		if to.Before(from) {
+			return ErrAssignmentEndDate
+		}
+		if err := tx.checkOverlap(ctx, repID, id, &from, to); err != nil {
+			return err
+		}
+
+		_, err = tx.db.Exec(ctx, `UPDATE commission_assignments SET effective_to = $2 WHERE id = $1`, id, to)
+		if err != nil {
+			return fmt.Errorf("failed to end commission assignment: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *commissionServiceImpl) DeleteAssignment(ctx context.Context, id int) error {
+	tag, err := s.db.Exec(ctx, `
+		DELETE FROM commission_assignments WHERE id = $1 AND company_id = $2`, id, tenant.Company(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to delete commission assignment: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAssignmentNotFound
+	}
+	return nil
+}
+
+func (s *commissionServiceImpl) ListAssignments(ctx context.Context, filters *models.CommissionAssignmentFilters) ([]models.CommissionAssignment, error) {
+	whereClause := "WHERE ca.company_id = $1"
+	args := []interface{}{tenant.Company(ctx)}
+	argNum := 2
+
+	if filters.SalesRepID != nil {
+		whereClause += fmt.Sprintf(" AND ca.sales_rep_id = $%d", argNum)
+		args = append(args, *filters.SalesRepID)
+		argNum++
+	}
+	if filters.PlanID != nil {
+		whereClause += fmt.Sprintf(" AND ca.plan_id = $%d", argNum)
+		args = append(args, *filters.PlanID)
+		argNum++
+	}
+
+	rows := s.db.Query(ctx, fmt.Sprintf(`
+		SELECT ca.id, ca.sales_rep_id, COALESCE(e.english_name, ''), ca.plan_id, cp.name,
+			   ca.effective_from, ca.effective_to, ca.created_by, ca.created_at
+		FROM commission_assignments ca
+		JOIN commission_plans cp ON cp.id = ca.plan_id
+		LEFT JOIN employees e ON e.id = ca.sales_rep_id
+		%s
+		ORDER BY e.english_name, ca.effective_from DESC`, whereClause), args...)
+	defer rows.Close()
+
+	assignments := []models.CommissionAssignment{}
+	for rows.Next() {
+		var a models.CommissionAssignment
+		err := rows.Scan(
+			&a.ID, &a.SalesRepID, &a.SalesRepName, &a.PlanID, &a.PlanName,
+			&a.EffectiveFrom, &a.EffectiveTo, &a.CreatedBy, &a.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan commission assignment: %w", err)
+		}
+		assignments = append(assignments, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list commission assignments: %w", err)
+	}
+	return assignments, nil
+}
+
+// checkOverlap returns ErrAssignmentOverlap when the rep has another
+// assignment in the dates given. An open end runs forever.
+func (s *commissionServiceImpl) checkOverlap(ctx context.Context, repID, exceptID int, from, to *time.Time) error {
+	var overlaps bool
+	err := s.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM commission_assignments
+			WHERE sales_rep_id = $1 AND company_id = $2 AND id <> $3
+			  AND effective_from <= COALESCE($5::date, 'infinity'::date)
+			  AND COALESCE(effective_to, 'infinity'::date) >= $4::date
+		)`, repID, tenant.Company(ctx), exceptID, from, to).Scan(&overlaps)
+	if err != nil {
+		return fmt.Errorf("failed to check commission assignments: %w", err)
+	}
+	if overlaps {
+		return ErrAssignmentOverlap
+	}
+	return nil
+}
+
+func parseDate(s string) *time.Time {
+	if s == "" {
+		return nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
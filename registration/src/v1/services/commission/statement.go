@@ -0,0 +1,425 @@
+package commission
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+// ============================================
+// Statements
+// ============================================
+//
+// A statement gathers what a rep earned in a period from posted AR. Each
+// invoice line counts for the rep on its sales order, or the customer's
+// rep when it has none, under the plan the rep was on that day. Plans
+// earning on invoices count the line on the invoice date; plans earning
+// on payment count each payment's share of the line on the payment date.
+// Credit memos claw back on their own date under either kind of plan. An
+// invoice line, or its share of a payment, is earned on one statement
+// only, so overlapping periods never pay twice.
+
+// GenerateStatements calculates the period's statement for the rep asked
+// for, or for every rep on a plan in the period. Drafts are recalculated;
+// approved statements are left as they are.
+func (s *commissionServiceImpl) GenerateStatements(ctx context.Context, req *models.GenerateCommissionStatementsRequest) ([]models.CommissionStatement, error) {
+	var ids []int
+	err := s.inTx(ctx, func(tx *commissionServiceImpl) error {
+		reps, err := tx.repsInPeriod(ctx, req)
+		if err != nil {
+			return err
+		}
+		if req.SalesRepID != nil && len(reps) == 0 {
+			return ErrNoCommissionForRep
+		}
+
+		for _, repID := range reps {
+			id, err := tx.calculate(ctx, repID, req.PeriodStart, req.PeriodEnd)
+			if err == ErrStatementLocked && req.SalesRepID == nil {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.listStatements(ctx, "WHERE cs.company_id = $1 AND cs.id = ANY($2)", tenant.Company(ctx), ids)
+}
+
+// repsInPeriod returns the reps with a plan on any day of the period.
+func (s *commissionServiceImpl) repsInPeriod(ctx context.Context, req *models.GenerateCommissionStatementsRequest) ([]int, error) {
+	rows := s.db.Query(ctx, `
+		SELECT DISTINCT sales_rep_id FROM commission_assignments
+		WHERE company_id = $1 AND effective_from <= $3::date
+		  AND (effective_to IS NULL OR effective_to >= $2::date)
+		  AND ($4::int IS NULL OR sales_rep_id = $4)
+		ORDER BY sales_rep_id`,
+		tenant.Company(ctx), req.PeriodStart, req.PeriodEnd, req.SalesRepID)
+	defer rows.Close()
+
+	var reps []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan sales rep: %w", err)
+		}
+		reps = append(reps, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get sales reps: %w", err)
+	}
+	return reps, nil
+}
+
+// calculate rebuilds a rep's draft statement for the period.
+func (s *commissionServiceImpl) calculate(ctx context.Context, repID int, periodStart, periodEnd string) (int, error) {
+	var id int
+	var status models.CommissionStatementStatus
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO commission_statements (company_id, sales_rep_id, period_start, period_end)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (company_id, sales_rep_id, period_start, period_end) DO UPDATE SET updated_at = NOW()
+		RETURNING id, status`,
+		tenant.Company(ctx), repID, periodStart, periodEnd).Scan(&id, &status)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create commission statement: %w", err)
+	}
+	if status != models.CommissionStatementDraft {
+		return 0, ErrStatementLocked
+	}
+
+	if _, err := s.db.Exec(ctx, `DELETE FROM commission_entries WHERE statement_id = $1`, id); err != nil {
+		return 0, fmt.Errorf("failed to clear commission entries: %w", err)
+	}
+
+	// Margin takes the cost the order line shipped at, or the product's
+	// average cost for lines billed without an order. A customer is new
+	// when first invoiced within the plan's days of the sale.
+	_, err = s.db.Exec(ctx, `
+		WITH lines AS (
+			SELECT i.id AS invoice_id, i.invoice_type, i.invoice_date, i.total_amount, i.customer_id,
+				   l.id AS line_id, l.product_id, p.category_id,
+				   l.quantity * l.unit_price AS revenue,
+				   l.quantity * COALESCE(sol.cost, p.average_cost, p.standard_cost, 0) AS cost
+			FROM ar_invoices i
+			JOIN ar_invoice_lines l ON l.invoice_id = i.id
+			JOIN customers c ON c.id = i.customer_id
+			LEFT JOIN products p ON p.id = l.product_id
+			LEFT JOIN sales_order_lines sol ON sol.id = l.order_line_id
+			LEFT JOIN sales_orders lso ON lso.id = sol.order_id
+			LEFT JOIN sales_orders iso ON iso.id = i.order_id
+			WHERE i.company_id = $5 AND i.status NOT IN ('DRAFT', 'VOID')
+			  AND COALESCE(lso.sales_rep_id, iso.sales_rep_id, c.sales_rep_id) = $2
+		),
+		events AS (
+			SELECT CASE WHEN l.invoice_type = 'CREDIT_MEMO' THEN 'CREDIT_MEMO' ELSE 'INVOICE' END AS source_type,
+				   l.invoice_date AS source_date, NULL::int AS payment_id, 1::numeric AS share, l.*
+			FROM lines l
+			WHERE l.invoice_date BETWEEN $3::date AND $4::date
+			UNION ALL
+			SELECT 'PAYMENT', ap.payment_date, ap.id, a.amount / l.total_amount, l.*
+			FROM lines l
+			JOIN ar_payment_applications a ON a.invoice_id = l.invoice_id
+			JOIN ar_payments ap ON ap.id = a.payment_id
+			WHERE l.invoice_type = 'INVOICE' AND l.total_amount > 0
+			  AND ap.payment_date BETWEEN $3::date AND $4::date
+		)
+		INSERT INTO commission_entries (
+			statement_id, plan_id, source_type, source_date, invoice_id, invoice_line_id, payment_id,
+			customer_id, product_id, category_id, revenue, cost, basis_amount, new_customer, bonus_rate
+		)
+		SELECT $1, cp.id, e.source_type, e.source_date, e.invoice_id, e.line_id, e.payment_id,
+			   e.customer_id, e.product_id, e.category_id,
+			   ROUND(e.revenue * e.share, 2), ROUND(e.cost * e.share, 2),
+			   ROUND(CASE WHEN cp.basis = 'MARGIN' THEN e.revenue - e.cost ELSE e.revenue END * e.share, 2),
+			   nc.is_new, CASE WHEN nc.is_new THEN cp.new_customer_bonus_rate ELSE 0 END
+		FROM events e
+		JOIN commission_assignments ca ON ca.sales_rep_id = $2 AND ca.company_id = $5
+			AND e.source_date >= ca.effective_from
+			AND (ca.effective_to IS NULL OR e.source_date <= ca.effective_to)
+		JOIN commission_plans cp ON cp.id = ca.plan_id
+		CROSS JOIN LATERAL (
+			SELECT cp.new_customer_bonus_rate > 0 AND COALESCE(MIN(fi.invoice_date) >= e.invoice_date - cp.new_customer_days, false) AS is_new
+			FROM ar_invoices fi
+			WHERE fi.customer_id = e.customer_id AND fi.invoice_type = 'INVOICE' AND fi.status <> 'VOID'
+		) nc
+		WHERE (e.source_type = 'CREDIT_MEMO'
+			   OR (e.source_type = 'INVOICE' AND cp.earn_on = 'INVOICED')
+			   OR (e.source_type = 'PAYMENT' AND cp.earn_on = 'PAID'))
+		  AND NOT EXISTS (
+			  SELECT 1 FROM commission_entries x
+			  WHERE x.invoice_line_id = e.line_id AND COALESCE(x.payment_id, 0) = COALESCE(e.payment_id, 0)
+		  )`,
+		id, repID, periodStart, periodEnd, tenant.Company(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("failed to gather commission entries: %w", err)
+	}
+
+	// The highest tier the plan's basis reaches on the statement pays on
+	// all of it; a category rate overrides the tier.
+	_, err = s.db.Exec(ctx, `
+		UPDATE commission_entries ce SET
+			rate = r.rate,
+			commission = ROUND(ce.basis_amount * r.rate / 100, 2),
+			bonus = ROUND(ce.basis_amount * ce.bonus_rate / 100, 2)
+		FROM (
+			SELECT e.id, COALESCE(cr.rate, tier.rate, cp.base_rate) AS rate
+			FROM commission_entries e
+			JOIN commission_plans cp ON cp.id = e.plan_id
+			JOIN (
+				SELECT plan_id, SUM(basis_amount) AS basis
+				FROM commission_entries WHERE statement_id = $1
+				GROUP BY plan_id
+			) pt ON pt.plan_id = e.plan_id
+			LEFT JOIN commission_plan_category_rates cr ON cr.plan_id = e.plan_id AND cr.category_id = e.category_id
+			LEFT JOIN LATERAL (
+				SELECT t.rate FROM commission_plan_tiers t
+				WHERE t.plan_id = e.plan_id AND t.threshold <= pt.basis
+				ORDER BY t.threshold DESC
+				LIMIT 1
+			) tier ON true
+			WHERE e.statement_id = $1
+		) r
+		WHERE ce.id = r.id`, id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to rate commission entries: %w", err)
+	}
+
+	_, err = s.db.Exec(ctx, `
+		UPDATE commission_statements cs SET
+			basis_total = t.basis,
+			commission_total = t.commission,
+			bonus_total = t.bonus,
+			clawback_total = t.clawback,
+			total_payable = t.commission + t.bonus,
+			updated_at = NOW()
+		FROM (
+			SELECT COALESCE(SUM(basis_amount), 0) AS basis,
+				   COALESCE(SUM(commission), 0) AS commission,
+				   COALESCE(SUM(bonus), 0) AS bonus,
+				   COALESCE(SUM(commission + bonus) FILTER (WHERE source_type = 'CREDIT_MEMO'), 0) AS clawback
+			FROM commission_entries WHERE statement_id = $1
+		) t
+		WHERE cs.id = $1`, id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to total commission statement: %w", err)
+	}
+	return id, nil
+}
+
+const statementSelect = `
+	SELECT cs.id, cs.sales_rep_id, COALESCE(e.english_name, ''), cs.period_start, cs.period_end, cs.status,
+		   cs.basis_total, cs.commission_total, cs.bonus_total, cs.clawback_total, cs.total_payable,
+		   cs.approved_by, cs.approved_at, cs.paid_at, COALESCE(cs.payroll_reference, ''),
+		   cs.created_at, cs.updated_at
+	FROM commission_statements cs
+	LEFT JOIN employees e ON e.id = cs.sales_rep_id`
+
+func scanStatement(row pgx.Row, st *models.CommissionStatement) error {
+	return row.Scan(
+		&st.ID, &st.SalesRepID, &st.SalesRepName, &st.PeriodStart, &st.PeriodEnd, &st.Status,
+		&st.BasisTotal, &st.CommissionTotal, &st.BonusTotal, &st.ClawbackTotal, &st.TotalPayable,
+		&st.ApprovedBy, &st.ApprovedAt, &st.PaidAt, &st.PayrollReference,
+		&st.CreatedAt, &st.UpdatedAt,
+	)
+}
+
+func (s *commissionServiceImpl) GetStatement(ctx context.Context, id int) (*models.CommissionStatement, error) {
+	var st models.CommissionStatement
+	err := scanStatement(s.db.QueryRow(ctx, statementSelect+` WHERE cs.id = $1 AND cs.company_id = $2`,
+		id, tenant.Company(ctx)), &st)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrStatementNotFound
+		}
+		return nil, fmt.Errorf("failed to get commission statement: %w", err)
+	}
+
+	rows := s.db.Query(ctx, `
+		SELECT ce.id, ce.plan_id, cp.name, ce.source_type, ce.source_date,
+			   ce.invoice_id, i.invoice_number, ce.payment_id, ce.customer_id, COALESCE(c.name, ''),
+			   ce.product_id, COALESCE(p.name, ''), ce.revenue, ce.cost, ce.basis_amount,
+			   ce.rate, ce.commission, ce.new_customer, ce.bonus_rate, ce.bonus
+		FROM commission_entries ce
+		JOIN commission_plans cp ON cp.id = ce.plan_id
+		JOIN ar_invoices i ON i.id = ce.invoice_id
+		LEFT JOIN customers c ON c.id = ce.customer_id
+		LEFT JOIN products p ON p.id = ce.product_id
+		WHERE ce.statement_id = $1
+		ORDER BY ce.source_date, i.invoice_number, ce.id`, id)
+	defer rows.Close()
+
+	st.Entries = []models.CommissionEntry{}
+	for rows.Next() {
+		var e models.CommissionEntry
+		err := rows.Scan(
+			&e.ID, &e.PlanID, &e.PlanName, &e.SourceType, &e.SourceDate,
+			&e.InvoiceID, &e.InvoiceNumber, &e.PaymentID, &e.CustomerID, &e.CustomerName,
+			&e.ProductID, &e.ProductName, &e.Revenue, &e.Cost, &e.BasisAmount,
+			&e.Rate, &e.Commission, &e.NewCustomer, &e.BonusRate, &e.Bonus,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan commission entry: %w", err)
+		}
+		st.Entries = append(st.Entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get commission entries: %w", err)
+	}
+	return &st, nil
+}
+
+func (s *commissionServiceImpl) ListStatements(ctx context.Context, filters *models.CommissionStatementFilters) ([]models.CommissionStatement, error) {
+	whereClause, args := statementFilters(ctx, filters)
+	return s.listStatements(ctx, whereClause, args...)
+}
+
+func (s *commissionServiceImpl) listStatements(ctx context.Context, whereClause string, args ...interface{}) ([]models.CommissionStatement, error) {
+	rows := s.db.Query(ctx, statementSelect+" "+whereClause+" ORDER BY cs.period_end DESC, e.english_name", args...)
+	defer rows.Close()
+
+	statements := []models.CommissionStatement{}
+	for rows.Next() {
+		var st models.CommissionStatement
+		if err := scanStatement(rows, &st); err != nil {
+			return nil, fmt.Errorf("failed to scan commission statement: %w", err)
+		}
+		statements = append(statements, st)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list commission statements: %w", err)
+	}
+	return statements, nil
+}
+
+func statementFilters(ctx context.Context, filters *models.CommissionStatementFilters) (string, []interface{}) {
+	whereClause := "WHERE cs.company_id = $1"
+	args := []interface{}{tenant.Company(ctx)}
+	argNum := 2
+
+	if filters.SalesRepID != nil {
+		whereClause += fmt.Sprintf(" AND cs.sales_rep_id = $%d", argNum)
+		args = append(args, *filters.SalesRepID)
+		argNum++
+	}
+	if filters.Status != nil {
+		whereClause += fmt.Sprintf(" AND cs.status = $%d", argNum)
+		args = append(args, *filters.Status)
+		argNum++
+	}
+	if filters.DateFrom != "" {
+		whereClause += fmt.Sprintf(" AND cs.period_end >= $%d::date", argNum)
+		args = append(args, filters.DateFrom)
+		argNum++
+	}
+	if filters.DateTo != "" {
+		whereClause += fmt.Sprintf(" AND cs.period_end <= $%d::date", argNum)
+		args = append(args, filters.DateTo)
+		argNum++
+	}
+	return whereClause, args
+}
+
+// ApproveStatement fixes a draft statement for payroll.
+func (s *commissionServiceImpl) ApproveStatement(ctx context.Context, id int, approvedBy int) error {
+	tag, err := s.db.Exec(ctx, `
+		UPDATE commission_statements SET
+			status = 'APPROVED', approved_by = NULLIF($3, 0), approved_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND company_id = $2 AND status = 'DRAFT'`,
+		id, tenant.Company(ctx), approvedBy)
+	if err != nil {
+		return fmt.Errorf("failed to approve commission statement: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return s.transitionError(ctx, id)
+	}
+	return nil
+}
+
+// PayStatements marks approved statements paid by the payroll run given.
+// Either all of them are paid or none is.
+func (s *commissionServiceImpl) PayStatements(ctx context.Context, req *models.PayCommissionStatementsRequest) error {
+	return s.inTx(ctx, func(tx *commissionServiceImpl) error {
+		for _, id := range req.StatementIDs {
+			tag, err := tx.db.Exec(ctx, `
+				UPDATE commission_statements SET
+					status = 'PAID', paid_at = NOW(), payroll_reference = NULLIF($3, ''), updated_at = NOW()
+				WHERE id = $1 AND company_id = $2 AND status = 'APPROVED'`,
+				id, tenant.Company(ctx), req.PayrollReference)
+			if err != nil {
+				return fmt.Errorf("failed to pay commission statement: %w", err)
+			}
+			if tag.RowsAffected() == 0 {
+				return tx.transitionError(ctx, id)
+			}
+		}
+		return nil
+	})
+}
+
+func (s *commissionServiceImpl) transitionError(ctx context.Context, id int) error {
+	var exists bool
+	err := s.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM commission_statements WHERE id = $1 AND company_id = $2)`,
+		id, tenant.Company(ctx)).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to get commission statement: %w", err)
+	}
+	if !exists {
+		return ErrStatementNotFound
+	}
+	return ErrIllegalTransition
+}
+
+// ============================================
+// Payroll
+// ============================================
+
+// GetPayrollTotals sums each rep's statements ending in the dates given,
+// approved ones unless another status is asked for. A negative total is
+// a clawback to deduct from the rep's pay.
+func (s *commissionServiceImpl) GetPayrollTotals(ctx context.Context, filters *models.CommissionStatementFilters) ([]models.CommissionPayrollTotal, error) {
+	if filters.Status == nil {
+		approved := models.CommissionStatementApproved
+		filters.Status = &approved
+	}
+	whereClause, args := statementFilters(ctx, filters)
+
+	rows := s.db.Query(ctx, fmt.Sprintf(`
+		SELECT cs.sales_rep_id, COALESCE(e.english_name, ''), COALESCE(e.email, ''),
+			   COUNT(*), ARRAY_AGG(cs.id ORDER BY cs.period_end),
+			   SUM(cs.commission_total), SUM(cs.bonus_total), SUM(cs.clawback_total), SUM(cs.total_payable)
+		FROM commission_statements cs
+		LEFT JOIN employees e ON e.id = cs.sales_rep_id
+		%s
+		GROUP BY cs.sales_rep_id, e.english_name, e.email
+		ORDER BY e.english_name`, whereClause), args...)
+	defer rows.Close()
+
+	totals := []models.CommissionPayrollTotal{}
+	for rows.Next() {
+		var t models.CommissionPayrollTotal
+		err := rows.Scan(
+			&t.SalesRepID, &t.SalesRepName, &t.Email,
+			&t.Statements, &t.StatementIDs,
+			&t.CommissionTotal, &t.BonusTotal, &t.ClawbackTotal, &t.TotalPayable,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan commission payroll total: %w", err)
+		}
+		totals = append(totals, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get commission payroll totals: %w", err)
+	}
+	return totals, nil
+}
@@ -0,0 +1,622 @@
+package company
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	poService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/purchase_order"
+	salesOrderService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/sales_order"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrNotFound             = errors.New("company not found")
+	ErrDuplicateCode        = errors.New("company code already exists")
+	ErrNoCompanyAccess      = errors.New("no access to company")
+	ErrOrderNotFound        = errors.New("order not found")
+	ErrNotIntercompany      = errors.New("order is not with a sister company")
+	ErrPartnerNotConfigured = errors.New("intercompany partner not configured")
+	ErrAlreadyPaired        = errors.New("order is already paired")
+	ErrFreightAccount       = errors.New("freight income account must be an active postable revenue account of the company")
+)
+
+// CompanyService manages the legal entities, which companies each employee
+// may work in, and the pairing of orders between sister companies.
+type CompanyService interface {
+	Create(ctx context.Context, req *models.CreateCompanyRequest) (int, error)
+	GetByID(ctx context.Context, id int) (*models.Company, error)
+	Update(ctx context.Context, id int, req *models.UpdateCompanyRequest) error
+	List(ctx context.Context) ([]models.Company, error)
+
+	// Employee access
+	ListForEmployee(ctx context.Context, employeeID int) ([]models.EmployeeCompany, error)
+	ResolveCompany(ctx context.Context, employeeID int, requested *int) (int, error)
+	SetEmployeeCompanies(ctx context.Context, employeeID int, req *models.SetEmployeeCompaniesRequest) error
+
+	// Intercompany
+	CreatePartner(ctx context.Context, req *models.CreateIntercompanyPartnerRequest) (int, error)
+	ListPartners(ctx context.Context) ([]models.IntercompanyPartner, error)
+	PairSalesOrder(ctx context.Context, salesOrderID int, createdBy int) (int, error)
+	PairPurchaseOrder(ctx context.Context, purchaseOrderID int, createdBy int) (int, error)
+	ListIntercompanyOrders(ctx context.Context) ([]models.IntercompanyOrder, error)
+}
+
+type companyServiceImpl struct {
+	db postgres.Connection
+}
+
+func New(db postgres.Connection) CompanyService {
+	return &companyServiceImpl{db: db}
+}
+
+// ============================================
+// Company CRUD
+// ============================================
+
+func (s *companyServiceImpl) Create(ctx context.Context, req *models.CreateCompanyRequest) (int, error) {
+	var exists bool
+	err := s.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM companies WHERE company_code = $1)`, req.CompanyCode).Scan(&exists)
+	if err != nil {
+		return 0, fmt.Errorf("checking company code: %w", err)
+	}
+	if exists {
+		return 0, ErrDuplicateCode
+	}
+
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var id int
+	err = tx.QueryRow(ctx, `
+		INSERT INTO companies (company_code, company_name, legal_name, tax_id, base_currency, credit_hold_overdue_days, standing_order_days_ahead)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, COALESCE($6, 30), COALESCE($7, 3))
+		RETURNING id
+	`, req.CompanyCode, req.CompanyName, req.LegalName, req.TaxID, req.BaseCurrency, req.CreditHoldOverdueDays,
+		req.StandingOrderDaysAhead).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("creating company: %w", err)
+	}
+
+	// Printed documents read the branding from the profile row with the same id
+	_, err = tx.Exec(ctx, `
+		INSERT INTO company_profile (id, company_name, legal_name, tax_id)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+		ON CONFLICT (id) DO NOTHING
+	`, id, req.CompanyName, req.LegalName, req.TaxID)
+	if err != nil {
+		return 0, fmt.Errorf("creating company profile: %w", err)
+	}
+
+	if req.CopyChartFrom != nil {
+		if err := copyChartOfAccounts(ctx, tx, *req.CopyChartFrom, id); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("committing company: %w", err)
+	}
+
+	return id, nil
+}
+
+// copyChartOfAccounts copies the active accounts of one company to another
+// without balances, keeping the parent structure by account code.
+func copyChartOfAccounts(ctx context.Context, tx postgres.Transaction, fromCompanyID, toCompanyID int) error {
+	_, err := tx.Exec(ctx, `
+		INSERT INTO gl_accounts (
+			company_id, account_code, account_name, account_type, account_sub_type, description,
+			currency, is_postable, normal_balance, consolidation_code
+		)
+		SELECT $2, account_code, account_name, account_type, account_sub_type, description,
+		       currency, is_postable, normal_balance, consolidation_code
+		FROM gl_accounts
+		WHERE company_id = $1 AND is_active = true
+	`, fromCompanyID, toCompanyID)
+	if err != nil {
+		return fmt.Errorf("copying chart of accounts: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE gl_accounts a SET parent_id = np.id
+		FROM gl_accounts src
+		JOIN gl_accounts sp ON sp.id = src.parent_id
+		JOIN gl_accounts np ON np.company_id = $2 AND np.account_code = sp.account_code
+		WHERE src.company_id = $1 AND a.company_id = $2 AND a.account_code = src.account_code
+	`, fromCompanyID, toCompanyID)
+	if err != nil {
+		return fmt.Errorf("copying account hierarchy: %w", err)
+	}
+
+	return nil
+}
+
+func (s *companyServiceImpl) GetByID(ctx context.Context, id int) (*models.Company, error) {
+	var c models.Company
+	err := s.db.QueryRow(ctx, `
+		SELECT id, company_code, company_name, COALESCE(legal_name, ''), COALESCE(tax_id, ''),
+		       base_currency, is_active, credit_hold_overdue_days, standing_order_days_ahead, freight_income_account_id,
+		       created_at, updated_at
+		FROM companies WHERE id = $1
+	`, id).Scan(
+		&c.ID, &c.CompanyCode, &c.CompanyName, &c.LegalName, &c.TaxID,
+		&c.BaseCurrency, &c.IsActive, &c.CreditHoldOverdueDays, &c.StandingOrderDaysAhead, &c.FreightIncomeAccountID,
+		&c.CreatedAt, &c.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("getting company: %w", err)
+	}
+
+	return &c, nil
+}
+
+func (s *companyServiceImpl) Update(ctx context.Context, id int, req *models.UpdateCompanyRequest) error {
+	if req.FreightIncomeAccountID != nil && *req.FreightIncomeAccountID != 0 {
+		var valid bool
+		err := s.db.QueryRow(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM gl_accounts
+				WHERE id = $1 AND company_id = $2 AND account_type = 'REVENUE' AND is_postable AND is_active
+			)`, *req.FreightIncomeAccountID, id).Scan(&valid)
+		if err != nil {
+			return fmt.Errorf("checking freight income account: %w", err)
+		}
+		if !valid {
+			return ErrFreightAccount
+		}
+	}
+
+	result, err := s.db.Exec(ctx, `
+		UPDATE companies SET
+			company_name = COALESCE($1, company_name),
+			legal_name = COALESCE($2, legal_name),
+			tax_id = COALESCE($3, tax_id),
+			base_currency = COALESCE($4, base_currency),
+			is_active = COALESCE($5, is_active),
+			credit_hold_overdue_days = COALESCE($6, credit_hold_overdue_days),
+			standing_order_days_ahead = COALESCE($7, standing_order_days_ahead),
+			freight_income_account_id = CASE WHEN $9::int IS NULL THEN freight_income_account_id ELSE NULLIF($9, 0) END,
+			updated_at = NOW()
+		WHERE id = $8
+	`, req.CompanyName, req.LegalName, req.TaxID, req.BaseCurrency, req.IsActive, req.CreditHoldOverdueDays,
+		req.StandingOrderDaysAhead, id, req.FreightIncomeAccountID)
+	if err != nil {
+		return fmt.Errorf("updating company: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *companyServiceImpl) List(ctx context.Context) ([]models.Company, error) {
+	rows := s.db.Query(ctx, `
+		SELECT id, company_code, company_name, COALESCE(legal_name, ''), COALESCE(tax_id, ''),
+		       base_currency, is_active, credit_hold_overdue_days, standing_order_days_ahead, freight_income_account_id,
+		       created_at, updated_at
+		FROM companies ORDER BY company_code
+	`)
+	defer rows.Close()
+
+	companies := []models.Company{}
+	for rows.Next() {
+		var c models.Company
+		if err := rows.Scan(
+			&c.ID, &c.CompanyCode, &c.CompanyName, &c.LegalName, &c.TaxID,
+			&c.BaseCurrency, &c.IsActive, &c.CreditHoldOverdueDays, &c.StandingOrderDaysAhead, &c.FreightIncomeAccountID,
+			&c.CreatedAt, &c.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scanning company: %w", err)
+		}
+		companies = append(companies, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing companies: %w", err)
+	}
+
+	return companies, nil
+}
+
+// ============================================
+// Employee Access
+// ============================================
+
+func (s *companyServiceImpl) ListForEmployee(ctx context.Context, employeeID int) ([]models.EmployeeCompany, error) {
+	rows := s.db.Query(ctx, `
+		SELECT c.id, c.company_code, c.company_name, ec.is_default
+		FROM employee_companies ec
+		JOIN companies c ON c.id = ec.company_id
+		WHERE ec.employee_id = $1 AND c.is_active = true
+		ORDER BY ec.is_default DESC, c.company_code
+	`, employeeID)
+	defer rows.Close()
+
+	companies := []models.EmployeeCompany{}
+	for rows.Next() {
+		var c models.EmployeeCompany
+		if err := rows.Scan(&c.CompanyID, &c.CompanyCode, &c.CompanyName, &c.IsDefault); err != nil {
+			return nil, fmt.Errorf("scanning employee company: %w", err)
+		}
+		companies = append(companies, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing employee companies: %w", err)
+	}
+
+	return companies, nil
+}
+
+// ResolveCompany returns the company an employee works in: the requested one
+// if they have access to it, otherwise their default. Employees created
+// before any companies were assigned to them work in the default company.
+func (s *companyServiceImpl) ResolveCompany(ctx context.Context, employeeID int, requested *int) (int, error) {
+	companies, err := s.ListForEmployee(ctx, employeeID)
+	if err != nil {
+		return 0, err
+	}
+	if len(companies) == 0 {
+		if requested != nil && *requested != tenant.DefaultCompany {
+			return 0, ErrNoCompanyAccess
+		}
+		return tenant.DefaultCompany, nil
+	}
+
+	if requested == nil {
+		// The list is ordered with the default company first
+		return companies[0].CompanyID, nil
+	}
+	for _, c := range companies {
+		if c.CompanyID == *requested {
+			return c.CompanyID, nil
+		}
+	}
+
+	return 0, ErrNoCompanyAccess
+}
+
+func (s *companyServiceImpl) SetEmployeeCompanies(ctx context.Context, employeeID int, req *models.SetEmployeeCompaniesRequest) error {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `DELETE FROM employee_companies WHERE employee_id = $1`, employeeID); err != nil {
+		return fmt.Errorf("clearing employee companies: %w", err)
+	}
+
+	for _, companyID := range req.CompanyIDs {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO employee_companies (employee_id, company_id, is_default)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (employee_id, company_id) DO NOTHING
+		`, employeeID, companyID, companyID == req.DefaultCompanyID)
+		if err != nil {
+			return fmt.Errorf("assigning company %d: %w", companyID, err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ============================================
+// Intercompany
+// ============================================
+
+func (s *companyServiceImpl) CreatePartner(ctx context.Context, req *models.CreateIntercompanyPartnerRequest) (int, error) {
+	companyID := tenant.Company(ctx)
+	if req.PartnerCompanyID == companyID {
+		return 0, ErrNotIntercompany
+	}
+	if _, err := s.GetByID(ctx, req.PartnerCompanyID); err != nil {
+		return 0, err
+	}
+
+	// The customer and vendor must belong to the current company
+	if req.CustomerID != nil {
+		var exists bool
+		err := s.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM customers WHERE id = $1 AND company_id = $2)`,
+			*req.CustomerID, companyID).Scan(&exists)
+		if err != nil {
+			return 0, fmt.Errorf("checking customer: %w", err)
+		}
+		if !exists {
+			return 0, fmt.Errorf("%w: customer %d", ErrPartnerNotConfigured, *req.CustomerID)
+		}
+	}
+	if req.VendorID != nil {
+		var exists bool
+		err := s.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM vendors WHERE id = $1 AND company_id = $2)`,
+			*req.VendorID, companyID).Scan(&exists)
+		if err != nil {
+			return 0, fmt.Errorf("checking vendor: %w", err)
+		}
+		if !exists {
+			return 0, fmt.Errorf("%w: vendor %d", ErrPartnerNotConfigured, *req.VendorID)
+		}
+	}
+
+	var id int
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO intercompany_partners (company_id, partner_company_id, customer_id, vendor_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (company_id, partner_company_id) DO UPDATE SET
+			customer_id = COALESCE(EXCLUDED.customer_id, intercompany_partners.customer_id),
+			vendor_id = COALESCE(EXCLUDED.vendor_id, intercompany_partners.vendor_id)
+		RETURNING id
+	`, companyID, req.PartnerCompanyID, req.CustomerID, req.VendorID).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("creating intercompany partner: %w", err)
+	}
+
+	return id, nil
+}
+
+func (s *companyServiceImpl) ListPartners(ctx context.Context) ([]models.IntercompanyPartner, error) {
+	rows := s.db.Query(ctx, `
+		SELECT ip.id, ip.company_id, ip.partner_company_id, c.company_name,
+		       ip.customer_id, ip.vendor_id, ip.created_at
+		FROM intercompany_partners ip
+		JOIN companies c ON c.id = ip.partner_company_id
+		WHERE ip.company_id = $1
+		ORDER BY c.company_code
+	`, tenant.Company(ctx))
+	defer rows.Close()
+
+	partners := []models.IntercompanyPartner{}
+	for rows.Next() {
+		var p models.IntercompanyPartner
+		if err := rows.Scan(
+			&p.ID, &p.CompanyID, &p.PartnerCompanyID, &p.PartnerCompanyName,
+			&p.CustomerID, &p.VendorID, &p.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scanning intercompany partner: %w", err)
+		}
+		partners = append(partners, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing intercompany partners: %w", err)
+	}
+
+	return partners, nil
+}
+
+// PairSalesOrder creates the purchase order in the buying sister company for
+// a sales order of the current company, priced at the sales order prices.
+func (s *companyServiceImpl) PairSalesOrder(ctx context.Context, salesOrderID int, createdBy int) (int, error) {
+	sellerID := tenant.Company(ctx)
+
+	order, err := salesOrderService.New(s.db).GetByID(ctx, salesOrderID)
+	if err != nil {
+		return 0, ErrOrderNotFound
+	}
+	if err := s.checkNotPaired(ctx, "sales_order_id", salesOrderID); err != nil {
+		return 0, err
+	}
+
+	// The customer on the order must be set up as a sister company
+	var buyerID int
+	err = s.db.QueryRow(ctx, `
+		SELECT partner_company_id FROM intercompany_partners
+		WHERE company_id = $1 AND customer_id = $2
+	`, sellerID, order.Order.CustomerID).Scan(&buyerID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrNotIntercompany
+		}
+		return 0, fmt.Errorf("finding buying company: %w", err)
+	}
+
+	// ...and the buyer must buy from the seller through one of its vendors
+	vendorID, err := s.partnerAccount(ctx, buyerID, sellerID, "vendor_id")
+	if err != nil {
+		return 0, err
+	}
+
+	req := &models.CreatePurchaseOrderRequest{
+		VendorID:    vendorID,
+		WarehouseID: order.Order.WarehouseID,
+		Notes:       "Intercompany order for " + order.Order.OrderNumber,
+	}
+	if !order.Order.RequestedShipDate.IsZero() {
+		req.ExpectedDate = time.Time(order.Order.RequestedShipDate).Format("2006-01-02")
+	}
+	for _, line := range order.Lines {
+		req.Lines = append(req.Lines, models.CreatePurchaseOrderLineRequest{
+			ProductID:     line.ProductID,
+			Quantity:      line.QuantityOrdered,
+			UnitOfMeasure: line.UnitOfMeasure,
+			UnitCost:      line.UnitPrice * (1 - line.DiscountPercent/100),
+			Description:   line.Description,
+		})
+	}
+
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	poID, err := poService.New(tx).Create(tenant.WithCompany(ctx, buyerID), req, createdBy)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := recordPair(ctx, tx, salesOrderID, poID, sellerID, buyerID, createdBy)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("committing intercompany order: %w", err)
+	}
+
+	return id, nil
+}
+
+// PairPurchaseOrder creates the sales order in the selling sister company for
+// a purchase order of the current company, priced at the purchase order costs.
+func (s *companyServiceImpl) PairPurchaseOrder(ctx context.Context, purchaseOrderID int, createdBy int) (int, error) {
+	buyerID := tenant.Company(ctx)
+
+	order, err := poService.New(s.db).GetByID(ctx, purchaseOrderID)
+	if err != nil {
+		return 0, ErrOrderNotFound
+	}
+	if err := s.checkNotPaired(ctx, "purchase_order_id", purchaseOrderID); err != nil {
+		return 0, err
+	}
+
+	var sellerID int
+	err = s.db.QueryRow(ctx, `
+		SELECT partner_company_id FROM intercompany_partners
+		WHERE company_id = $1 AND vendor_id = $2
+	`, buyerID, order.Order.VendorID).Scan(&sellerID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrNotIntercompany
+		}
+		return 0, fmt.Errorf("finding selling company: %w", err)
+	}
+
+	customerID, err := s.partnerAccount(ctx, sellerID, buyerID, "customer_id")
+	if err != nil {
+		return 0, err
+	}
+
+	req := &models.CreateSalesOrderRequest{
+		CustomerID:  customerID,
+		OrderType:   models.OrderTypeStandard,
+		WarehouseID: order.Order.WarehouseID,
+		PONumber:    order.Order.PONumber,
+		Notes:       "Intercompany order for " + order.Order.PONumber,
+	}
+	if !order.Order.ExpectedDate.IsZero() {
+		req.RequestedShipDate = time.Time(order.Order.ExpectedDate).Format("2006-01-02")
+	}
+	for _, line := range order.Lines {
+		req.Lines = append(req.Lines, models.CreateSalesOrderLineRequest{
+			ProductID:     line.ProductID,
+			Quantity:      line.QuantityOrdered,
+			UnitOfMeasure: line.UnitOfMeasure,
+			UnitPrice:     line.UnitCost,
+			Notes:         line.Description,
+		})
+	}
+
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	soID, err := salesOrderService.New(tx).Create(tenant.WithCompany(ctx, sellerID), req, createdBy)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := recordPair(ctx, tx, soID, purchaseOrderID, sellerID, buyerID, createdBy)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("committing intercompany order: %w", err)
+	}
+
+	return id, nil
+}
+
+func (s *companyServiceImpl) checkNotPaired(ctx context.Context, column string, orderID int) error {
+	var paired bool
+	query := fmt.Sprintf(`SELECT EXISTS(SELECT 1 FROM intercompany_orders WHERE %s = $1)`, column)
+	if err := s.db.QueryRow(ctx, query, orderID).Scan(&paired); err != nil {
+		return fmt.Errorf("checking intercompany order: %w", err)
+	}
+	if paired {
+		return ErrAlreadyPaired
+	}
+	return nil
+}
+
+// partnerAccount returns the customer or vendor that companyID uses for
+// partnerID.
+func (s *companyServiceImpl) partnerAccount(ctx context.Context, companyID, partnerID int, column string) (int, error) {
+	var id *int
+	query := fmt.Sprintf(`SELECT %s FROM intercompany_partners WHERE company_id = $1 AND partner_company_id = $2`, column)
+	err := s.db.QueryRow(ctx, query, companyID, partnerID).Scan(&id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("finding intercompany partner: %w", err)
+	}
+	if id == nil {
+		return 0, fmt.Errorf("%w: company %d has no %s for company %d",
+			ErrPartnerNotConfigured, companyID, strings.TrimSuffix(column, "_id"), partnerID)
+	}
+	return *id, nil
+}
+
+func recordPair(ctx context.Context, tx postgres.Transaction, salesOrderID, purchaseOrderID, sellerID, buyerID, createdBy int) (int, error) {
+	var by *int
+	if createdBy > 0 {
+		by = &createdBy
+	}
+
+	var id int
+	err := tx.QueryRow(ctx, `
+		INSERT INTO intercompany_orders (sales_order_id, purchase_order_id, seller_company_id, buyer_company_id, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, salesOrderID, purchaseOrderID, sellerID, buyerID, by).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("recording intercompany order: %w", err)
+	}
+	return id, nil
+}
+
+// ListIntercompanyOrders lists the pairs the current company sells or buys in.
+func (s *companyServiceImpl) ListIntercompanyOrders(ctx context.Context) ([]models.IntercompanyOrder, error) {
+	rows := s.db.Query(ctx, `
+		SELECT io.id, io.sales_order_id, so.order_number, io.purchase_order_id, po.po_number,
+		       io.seller_company_id, sc.company_name, io.buyer_company_id, bc.company_name,
+		       COALESCE(io.created_by, 0), io.created_at
+		FROM intercompany_orders io
+		JOIN sales_orders so ON so.id = io.sales_order_id
+		JOIN purchase_orders po ON po.id = io.purchase_order_id
+		JOIN companies sc ON sc.id = io.seller_company_id
+		JOIN companies bc ON bc.id = io.buyer_company_id
+		WHERE io.seller_company_id = $1 OR io.buyer_company_id = $1
+		ORDER BY io.created_at DESC
+	`, tenant.Company(ctx))
+	defer rows.Close()
+
+	orders := []models.IntercompanyOrder{}
+	for rows.Next() {
+		var o models.IntercompanyOrder
+		if err := rows.Scan(
+			&o.ID, &o.SalesOrderID, &o.SalesOrderNumber, &o.PurchaseOrderID, &o.PurchaseOrderNum,
+			&o.SellerCompanyID, &o.SellerCompanyName, &o.BuyerCompanyID, &o.BuyerCompanyName,
+			&o.CreatedBy, &o.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scanning intercompany order: %w", err)
+		}
+		orders = append(orders, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing intercompany orders: %w", err)
+	}
+
+	return orders, nil
+}
@@ -7,6 +7,8 @@ import (
 
 	"github.com/anas-dev-92/FoodHive/core/postgres"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/query"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
 	"github.com/jackc/pgx/v5"
 )
 
@@ -42,12 +44,19 @@ func (s *customerServiceImpl) Create(ctx context.Context, req models.CreateCusto
 	if req.PaymentTermsDays == 0 {
 		req.PaymentTermsDays = 30
 	}
+	if req.DocumentLanguage == "" {
+		req.DocumentLanguage = "en"
+	}
+	if req.BackorderPolicy == "" {
+		req.BackorderPolicy = models.BackorderSameOrder
+	}
 
 	query := `
 		INSERT INTO customers (
 			customer_code, name, credit_limit, payment_terms_days, 
-			currency, sales_rep_id, default_warehouse_id, tax_exempt, created_by
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			currency, sales_rep_id, default_warehouse_id, tax_exempt, created_by,
+			document_language, backorder_policy, company_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id`
 
 	var id int
@@ -61,6 +70,9 @@ func (s *customerServiceImpl) Create(ctx context.Context, req models.CreateCusto
 		req.DefaultWarehouseID,
 		req.TaxExempt,
 		createdBy,
+		req.DocumentLanguage,
+		req.BackorderPolicy,
+		tenant.Company(ctx),
 	).Scan(&id)
 
 	if err != nil {
@@ -81,17 +93,18 @@ func (s *customerServiceImpl) GetByID(ctx context.Context, id int) (*models.Cust
 			c.credit_limit, c.current_balance, c.payment_terms_days,
 			c.currency, c.sales_rep_id, c.default_route_id, c.default_warehouse_id,
 			c.tax_exempt, c.is_active, c.created_by, c.created_at, c.updated_at,
+			c.document_language, c.backorder_policy,
 			COALESCE(e.english_name, '') as sales_rep_name,
 			COALESCE(w.name, '') as warehouse_name
 		FROM customers c
 		LEFT JOIN employees e ON c.sales_rep_id = e.id
 		LEFT JOIN warehouses w ON c.default_warehouse_id = w.id
-		WHERE c.id = $1`
+		WHERE c.id = $1 AND c.company_id = $2`
 
 	var result models.CustomerWithDetails
 	var cust models.Customer
 
-	err := s.db.QueryRow(ctx, query, id).Scan(
+	err := s.db.QueryRow(ctx, query, id, tenant.Company(ctx)).Scan(
 		&cust.ID,
 		&cust.CustomerCode,
 		&cust.Name,
@@ -108,6 +121,8 @@ func (s *customerServiceImpl) GetByID(ctx context.Context, id int) (*models.Cust
 		&cust.CreatedBy,
 		&cust.CreatedAt,
 		&cust.UpdatedAt,
+		&cust.DocumentLanguage,
+		&cust.BackorderPolicy,
 		&result.SalesRepName,
 		&result.WarehouseName,
 	)
@@ -154,17 +169,18 @@ func (s *customerServiceImpl) GetByCode(ctx context.Context, code string) (*mode
 		SELECT id, customer_code, name, billing_address_id, credit_limit, 
 			current_balance, payment_terms_days, currency, sales_rep_id,
 			default_route_id, default_warehouse_id, tax_exempt, is_active,
-			created_by, created_at, updated_at
+			created_by, created_at, updated_at, document_language, backorder_policy
 		FROM customers
-		WHERE customer_code = $1`
+		WHERE customer_code = $1 AND company_id = $2`
 
 	var cust models.Customer
-	err := s.db.QueryRow(ctx, query, code).Scan(
+	err := s.db.QueryRow(ctx, query, code, tenant.Company(ctx)).Scan(
 		&cust.ID, &cust.CustomerCode, &cust.Name, &cust.BillingAddressID,
 		&cust.CreditLimit, &cust.CurrentBalance, &cust.PaymentTermsDays,
 		&cust.Currency, &cust.SalesRepID, &cust.DefaultRouteID,
 		&cust.DefaultWarehouseID, &cust.TaxExempt, &cust.IsActive,
-		&cust.CreatedBy, &cust.CreatedAt, &cust.UpdatedAt,
+		&cust.CreatedBy, &cust.CreatedAt, &cust.UpdatedAt, &cust.DocumentLanguage,
+		&cust.BackorderPolicy,
 	)
 
 	if err != nil {
@@ -188,8 +204,10 @@ func (s *customerServiceImpl) Update(ctx context.Context, id int, req models.Upd
 			default_warehouse_id = COALESCE($7, default_warehouse_id),
 			tax_exempt = COALESCE($8, tax_exempt),
 			is_active = COALESCE($9, is_active),
+			document_language = COALESCE($10, document_language),
+			backorder_policy = COALESCE($11, backorder_policy),
 			updated_at = NOW()
-		WHERE id = $1`
+		WHERE id = $1 AND company_id = $12`
 
 	result, err := s.db.Exec(ctx, query,
 		id,
@@ -201,6 +219,9 @@ func (s *customerServiceImpl) Update(ctx context.Context, id int, req models.Upd
 		req.DefaultWarehouseID,
 		req.TaxExempt,
 		req.IsActive,
+		req.DocumentLanguage,
+		req.BackorderPolicy,
+		tenant.Company(ctx),
 	)
 
 	if err != nil {
@@ -216,8 +237,8 @@ func (s *customerServiceImpl) Update(ctx context.Context, id int, req models.Upd
 
 func (s *customerServiceImpl) Delete(ctx context.Context, id int) error {
 	// Soft delete - just mark as inactive
-	query := `UPDATE customers SET is_active = false, updated_at = NOW() WHERE id = $1`
-	result, err := s.db.Exec(ctx, query, id)
+	query := `UPDATE customers SET is_active = false, updated_at = NOW() WHERE id = $1 AND company_id = $2`
+	result, err := s.db.Exec(ctx, query, id, tenant.Company(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to delete customer: %w", err)
 	}
@@ -229,96 +250,105 @@ func (s *customerServiceImpl) Delete(ctx context.Context, id int) error {
 	return nil
 }
 
-func (s *customerServiceImpl) List(ctx context.Context, filters models.CustomerListFilters) ([]models.Customer, int64, error) {
-	// Set defaults
-	if filters.Page < 1 {
-		filters.Page = 1
-	}
-	if filters.PageSize < 1 {
-		filters.PageSize = 20
-	}
+// customerListSchema whitelists the fields clients may filter and sort on.
+var customerListSchema = query.Schema{
+	Fields: map[string]query.Field{
+		"id":                 {Column: "id", Type: query.Int, Sortable: true},
+		"customer_code":      {Column: "customer_code", Type: query.String, Sortable: true},
+		"name":               {Column: "name", Type: query.String, Sortable: true},
+		"credit_limit":       {Column: "credit_limit", Type: query.Number, Sortable: true},
+		"current_balance":    {Column: "current_balance", Type: query.Number, Sortable: true},
+		"payment_terms_days": {Column: "payment_terms_days", Type: query.Int, Sortable: true},
+		"currency":           {Column: "currency", Type: query.String},
+		"sales_rep_id":       {Column: "sales_rep_id", Type: query.Int},
+		"default_route_id":   {Column: "default_route_id", Type: query.Int},
+		"tax_exempt":         {Column: "tax_exempt", Type: query.Bool},
+		"is_active":          {Column: "is_active", Type: query.Bool},
+		"created_at":         {Column: "created_at", Type: query.Time, Sortable: true},
+	},
+	Default: []query.Sort{{Field: "name"}},
+	Key:     "id",
+}
 
-	// Count total
-	countQuery := `SELECT COUNT(*) FROM customers WHERE 1=1`
-	args := []interface{}{}
-	argIndex := 1
+func (s *customerServiceImpl) List(ctx context.Context, filters models.CustomerListFilters) ([]models.Customer, int64, error) {
+	where := " AND company_id = $1"
+	args := []interface{}{tenant.Company(ctx)}
+	argIndex := 2
 
 	if filters.Search != "" {
-		countQuery += fmt.Sprintf(" AND (name ILIKE $%d OR customer_code ILIKE $%d)", argIndex, argIndex)
+		where += fmt.Sprintf(" AND (name ILIKE $%d OR customer_code ILIKE $%d)", argIndex, argIndex)
 		args = append(args, "%"+filters.Search+"%")
 		argIndex++
 	}
 	if filters.SalesRepID != nil {
-		countQuery += fmt.Sprintf(" AND sales_rep_id = $%d", argIndex)
+		where += fmt.Sprintf(" AND sales_rep_id = $%d", argIndex)
 		args = append(args, *filters.SalesRepID)
 		argIndex++
 	}
 	if filters.IsActive != nil {
-		countQuery += fmt.Sprintf(" AND is_active = $%d", argIndex)
+		where += fmt.Sprintf(" AND is_active = $%d", argIndex)
 		args = append(args, *filters.IsActive)
 		argIndex++
 	}
 
-	var total int64
-	err := s.db.QueryRow(ctx, countQuery, args...).Scan(&total)
+	p := query.Default(filters.Query, filters.Page, filters.PageSize)
+	q, err := query.Build(customerListSchema, p, argIndex)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to count customers: %w", err)
+		return nil, 0, err
+	}
+	where += q.Where
+	args = append(args, q.Args...)
+
+	// Keyset pages skip the count; clients follow next_cursor instead.
+	var total int64
+	if !p.Keyset() {
+		err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM customers WHERE 1=1`+where, args...).Scan(&total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to count customers: %w", err)
+		}
 	}
 
 	// Get page
-	query := `
+	sql := `
 		SELECT id, customer_code, name, billing_address_id, credit_limit,
 			current_balance, payment_terms_days, currency, sales_rep_id,
 			default_route_id, default_warehouse_id, tax_exempt, is_active,
-			created_by, created_at, updated_at
-		FROM customers WHERE 1=1`
-
-	args = []interface{}{}
-	argIndex = 1
-
-	if filters.Search != "" {
-		query += fmt.Sprintf(" AND (name ILIKE $%d OR customer_code ILIKE $%d)", argIndex, argIndex)
-		args = append(args, "%"+filters.Search+"%")
-		argIndex++
-	}
-	if filters.SalesRepID != nil {
-		query += fmt.Sprintf(" AND sales_rep_id = $%d", argIndex)
-		args = append(args, *filters.SalesRepID)
-		argIndex++
-	}
-	if filters.IsActive != nil {
-		query += fmt.Sprintf(" AND is_active = $%d", argIndex)
-		args = append(args, *filters.IsActive)
-		argIndex++
-	}
-
-	query += " ORDER BY name"
-	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argIndex, argIndex+1)
-	args = append(args, filters.PageSize, (filters.Page-1)*filters.PageSize)
+			created_by, created_at, updated_at, document_language, backorder_policy, ` + q.Cursor + `
+		FROM customers WHERE 1=1` + where + q.Keyset + " " + q.OrderBy + " " + q.Limit
 
-	rows := s.db.Query(ctx, query, args...)
+	rows := s.db.Query(ctx, sql, append(args, q.PageArgs...)...)
 	defer rows.Close()
 
 	var customers []models.Customer
+	var cursors []string
 	for rows.Next() {
 		var c models.Customer
+		var cursor string
 		err := rows.Scan(
 			&c.ID, &c.CustomerCode, &c.Name, &c.BillingAddressID,
 			&c.CreditLimit, &c.CurrentBalance, &c.PaymentTermsDays,
 			&c.Currency, &c.SalesRepID, &c.DefaultRouteID,
 			&c.DefaultWarehouseID, &c.TaxExempt, &c.IsActive,
-			&c.CreatedBy, &c.CreatedAt, &c.UpdatedAt,
+			&c.CreatedBy, &c.CreatedAt, &c.UpdatedAt, &c.DocumentLanguage, &c.BackorderPolicy, &cursor,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan customer: %w", err)
 		}
 		customers = append(customers, c)
+		cursors = append(cursors, cursor)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list customers: %w", err)
 	}
 
-	return customers, total, nil
+	return customers[:p.Trim(cursors)], total, nil
 }
 
 func (s *customerServiceImpl) GetOrderGuide(ctx context.Context, customerID int) ([]models.CustomerOrderGuide, error) {
+	if err := s.checkCustomer(ctx, customerID); err != nil {
+		return nil, err
+	}
+
 	query := `
 		SELECT 
 			cog.id, cog.customer_id, cog.product_id,
@@ -352,6 +382,10 @@ func (s *customerServiceImpl) GetOrderGuide(ctx context.Context, customerID int)
 }
 
 func (s *customerServiceImpl) AddShipTo(ctx context.Context, customerID int, shipTo models.CustomerShipTo) (int, error) {
+	if err := s.checkCustomer(ctx, customerID); err != nil {
+		return 0, err
+	}
+
 	query := `
 		INSERT INTO customer_ship_to (
 			customer_id, ship_to_code, name, address_line1, address_line2,
@@ -382,3 +416,18 @@ func (s *customerServiceImpl) AddShipTo(ctx context.Context, customerID int, shi
 
 	return id, nil
 }
+
+// checkCustomer returns ErrNotFound unless the customer belongs to the
+// caller's company
+func (s *customerServiceImpl) checkCustomer(ctx context.Context, customerID int) error {
+	var exists bool
+	err := s.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM customers WHERE id = $1 AND company_id = $2)`,
+		customerID, tenant.Company(ctx)).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to get customer: %w", err)
+	}
+	if !exists {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,542 @@
+package customer_item
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrNotFound          = errors.New("customer item code not found")
+	ErrDuplicateCode     = errors.New("customer item code is already mapped for this customer and unit")
+	ErrUnknownCode       = errors.New("customer item code is not mapped")
+	ErrCustomerNotFound  = errors.New("customer not found")
+	ErrProductNotFound   = errors.New("product not found")
+	ErrNoCustomerGroup   = errors.New("customer does not belong to a customer group")
+	ErrQueueItemNotFound = errors.New("unmapped customer code not found")
+	ErrQueueItemResolved = errors.New("unmapped customer code has already been resolved")
+)
+
+// ============================================
+// Service Interface
+// ============================================
+
+type CustomerItemService interface {
+	Create(ctx context.Context, req *models.CreateCustomerItemCodeRequest, createdBy int) (int, error)
+	GetByID(ctx context.Context, id int) (*models.CustomerItemCode, error)
+	Update(ctx context.Context, id int, req *models.UpdateCustomerItemCodeRequest) error
+	Delete(ctx context.Context, id int) error
+	List(ctx context.Context, filters *models.CustomerItemCodeFilters) ([]models.CustomerItemCode, error)
+
+	// Lookup
+	Resolve(ctx context.Context, customerID int, code, uom string) (*models.CustomerItemCode, error)
+	CodeFor(ctx context.Context, customerID, productID int, uom string) (*models.CustomerItemCode, error)
+
+	// Mapping queue
+	QueueUnknown(ctx context.Context, req *models.QueueCustomerCodeRequest) error
+	ListUnmapped(ctx context.Context, filters *models.UnmappedCodeFilters) ([]models.UnmappedCustomerCode, error)
+	MapUnmapped(ctx context.Context, id int, req *models.MapCustomerCodeRequest, userID int) (int, error)
+	IgnoreUnmapped(ctx context.Context, id int, userID int) error
+}
+
+// ============================================
+// Service Implementation
+// ============================================
+
+type customerItemServiceImpl struct {
+	db postgres.Executor
+}
+
+func New(db postgres.Executor) CustomerItemService {
+	return &customerItemServiceImpl{db: db}
+}
+
+// inTx runs fn in a transaction. When the service was built on a caller's
+// transaction, fn runs in that one.
+func (s *customerItemServiceImpl) inTx(ctx context.Context, fn func(tx *customerItemServiceImpl) error) error {
+	conn, ok := s.db.(postgres.Connection)
+	if !ok {
+		return fn(s)
+	}
+
+	tx, err := conn.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&customerItemServiceImpl{db: tx}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ============================================
+// Customer Item Code CRUD
+// ============================================
+
+// Create adds a mapping and clears the queue of the codes it now covers.
+func (s *customerItemServiceImpl) Create(ctx context.Context, req *models.CreateCustomerItemCodeRequest, createdBy int) (int, error) {
+	var id int
+	err := s.inTx(ctx, func(tx *customerItemServiceImpl) error {
+		var err error
+		id, err = tx.create(ctx, req, createdBy)
+		if err != nil {
+			return err
+		}
+		return tx.resolveQueue(ctx, id, createdBy)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (s *customerItemServiceImpl) create(ctx context.Context, req *models.CreateCustomerItemCodeRequest, createdBy int) (int, error) {
+	if err := s.checkOwner(ctx, req.CustomerID, req.CustomerGroupID); err != nil {
+		return 0, err
+	}
+
+	// Unit defaults to the product's base unit
+	var unit string
+	err := s.db.QueryRow(ctx, `
+		SELECT COALESCE(NULLIF($2, ''), base_unit, 'EA') FROM products WHERE id = $1`,
+		req.ProductID, req.UnitOfMeasure).Scan(&unit)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrProductNotFound
+		}
+		return 0, fmt.Errorf("failed to get product: %w", err)
+	}
+
+	if err := s.checkDuplicate(ctx, 0, req.CustomerID, req.CustomerGroupID, req.CustomerCode, req.CustomerUOM); err != nil {
+		return 0, err
+	}
+
+	factor := req.ConversionFactor
+	if factor == 0 {
+		factor = 1
+	}
+
+	var id int
+	err = s.db.QueryRow(ctx, `
+		INSERT INTO customer_item_codes (
+			company_id, customer_id, customer_group_id, customer_code, customer_uom,
+			product_id, unit_of_measure, conversion_factor, description, created_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), NULLIF($10, 0))
+		RETURNING id`,
+		tenant.Company(ctx), req.CustomerID, req.CustomerGroupID, req.CustomerCode, req.CustomerUOM,
+		req.ProductID, unit, factor, req.Description, createdBy,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create customer item code: %w", err)
+	}
+	return id, nil
+}
+
+const itemCodeSelect = `
+	SELECT m.id, m.customer_id, COALESCE(c.name, ''), m.customer_group_id, COALESCE(cg.name, ''),
+		   m.customer_code, m.customer_uom, m.product_id, p.sku, p.name,
+		   m.unit_of_measure, m.conversion_factor, COALESCE(m.description, ''), m.is_active,
+		   m.created_by, m.created_at, m.updated_at
+	FROM customer_item_codes m
+	JOIN products p ON p.id = m.product_id
+	LEFT JOIN customers c ON c.id = m.customer_id
+	LEFT JOIN customer_groups cg ON cg.id = m.customer_group_id`
+
+func scanItemCode(row pgx.Row, m *models.CustomerItemCode) error {
+	return row.Scan(
+		&m.ID, &m.CustomerID, &m.CustomerName, &m.CustomerGroupID, &m.CustomerGroupName,
+		&m.CustomerCode, &m.CustomerUOM, &m.ProductID, &m.ProductSKU, &m.ProductName,
+		&m.UnitOfMeasure, &m.ConversionFactor, &m.Description, &m.IsActive,
+		&m.CreatedBy, &m.CreatedAt, &m.UpdatedAt,
+	)
+}
+
+func (s *customerItemServiceImpl) GetByID(ctx context.Context, id int) (*models.CustomerItemCode, error) {
+	var m models.CustomerItemCode
+	err := scanItemCode(s.db.QueryRow(ctx, itemCodeSelect+` WHERE m.id = $1 AND m.company_id = $2`,
+		id, tenant.Company(ctx)), &m)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get customer item code: %w", err)
+	}
+	return &m, nil
+}
+
+func (s *customerItemServiceImpl) Update(ctx context.Context, id int, req *models.UpdateCustomerItemCodeRequest) error {
+	return s.inTx(ctx, func(tx *customerItemServiceImpl) error {
+		current, err := tx.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if req.CustomerCode != nil || req.CustomerUOM != nil {
+			code, uom := current.CustomerCode, current.CustomerUOM
+			if req.CustomerCode != nil {
+				code = *req.CustomerCode
+			}
+			if req.CustomerUOM != nil {
+				uom = *req.CustomerUOM
+			}
+			if err := tx.checkDuplicate(ctx, id, current.CustomerID, current.CustomerGroupID, code, uom); err != nil {
+				return err
+			}
+		}
+		if req.ProductID != nil {
+			var exists bool
+			tx.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM products WHERE id = $1)`, *req.ProductID).Scan(&exists)
+			if !exists {
+				return ErrProductNotFound
+			}
+		}
+
+		_, err = tx.db.Exec(ctx, `
+			UPDATE customer_item_codes SET
+				customer_code = COALESCE($2, customer_code),
+				customer_uom = COALESCE($3, customer_uom),
+				product_id = COALESCE($4, product_id),
+				unit_of_measure = COALESCE($5, unit_of_measure),
+				conversion_factor = COALESCE($6, conversion_factor),
+				description = COALESCE($7, description),
+				is_active = COALESCE($8, is_active),
+				updated_at = NOW()
+			WHERE id = $1`,
+			id, req.CustomerCode, req.CustomerUOM, req.ProductID, req.UnitOfMeasure,
+			req.ConversionFactor, req.Description, req.IsActive)
+		if err != nil {
+			return fmt.Errorf("failed to update customer item code: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *customerItemServiceImpl) Delete(ctx context.Context, id int) error {
+	tag, err := s.db.Exec(ctx, `DELETE FROM customer_item_codes WHERE id = $1 AND company_id = $2`,
+		id, tenant.Company(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to delete customer item code: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *customerItemServiceImpl) List(ctx context.Context, filters *models.CustomerItemCodeFilters) ([]models.CustomerItemCode, error) {
+	whereClause := "WHERE m.company_id = $1"
+	args := []interface{}{tenant.Company(ctx)}
+	argNum := 2
+
+	// A customer's list includes its group's codes, which apply to it too
+	if filters.CustomerID != nil {
+		whereClause += fmt.Sprintf(` AND (m.customer_id = $%[1]d OR m.customer_group_id =
+			(SELECT customer_group_id FROM customers WHERE id = $%[1]d))`, argNum)
+		args = append(args, *filters.CustomerID)
+		argNum++
+	}
+	if filters.CustomerGroupID != nil {
+		whereClause += fmt.Sprintf(" AND m.customer_group_id = $%d", argNum)
+		args = append(args, *filters.CustomerGroupID)
+		argNum++
+	}
+	if filters.ProductID != nil {
+		whereClause += fmt.Sprintf(" AND m.product_id = $%d", argNum)
+		args = append(args, *filters.ProductID)
+		argNum++
+	}
+	if filters.Search != "" {
+		whereClause += fmt.Sprintf(" AND (m.customer_code ILIKE $%[1]d OR p.sku ILIKE $%[1]d OR p.name ILIKE $%[1]d)", argNum)
+		args = append(args, "%"+filters.Search+"%")
+		argNum++
+	}
+
+	rows := s.db.Query(ctx, itemCodeSelect+" "+whereClause+
+		" ORDER BY COALESCE(c.name, cg.name), m.customer_code, m.customer_uom", args...)
+	defer rows.Close()
+
+	codes := []models.CustomerItemCode{}
+	for rows.Next() {
+		var m models.CustomerItemCode
+		if err := scanItemCode(rows, &m); err != nil {
+			return nil, fmt.Errorf("failed to scan customer item code: %w", err)
+		}
+		codes = append(codes, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list customer item codes: %w", err)
+	}
+	return codes, nil
+}
+
+// ============================================
+// Lookup
+// ============================================
+
+// Resolve finds the product a customer means by its code in the unit it
+// ordered in. The customer's own mappings win over its group's, and a
+// mapping for that unit over one for any unit. Codes are matched without
+// regard to case.
+func (s *customerItemServiceImpl) Resolve(ctx context.Context, customerID int, code, uom string) (*models.CustomerItemCode, error) {
+	var m models.CustomerItemCode
+	err := scanItemCode(s.db.QueryRow(ctx, itemCodeSelect+`
+		JOIN customers oc ON oc.id = $1
+		WHERE m.company_id = $4 AND m.is_active
+		  AND UPPER(m.customer_code) = UPPER($2)
+		  AND (m.customer_uom = '' OR UPPER(m.customer_uom) = UPPER($3))
+		  AND (m.customer_id = oc.id OR m.customer_group_id = oc.customer_group_id)
+		ORDER BY m.customer_id IS NULL, m.customer_uom = ''
+		LIMIT 1`, customerID, code, uom, tenant.Company(ctx)), &m)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrUnknownCode
+		}
+		return nil, fmt.Errorf("failed to resolve customer item code: %w", err)
+	}
+	return &m, nil
+}
+
+// CodeFor finds the customer's code for one of our products, preferring a
+// mapping in the unit given. It returns nil when the customer has none.
+func (s *customerItemServiceImpl) CodeFor(ctx context.Context, customerID, productID int, uom string) (*models.CustomerItemCode, error) {
+	var m models.CustomerItemCode
+	err := scanItemCode(s.db.QueryRow(ctx, itemCodeSelect+`
+		JOIN customers oc ON oc.id = $1
+		WHERE m.company_id = $4 AND m.is_active AND m.product_id = $2
+		  AND (m.customer_id = oc.id OR m.customer_group_id = oc.customer_group_id)
+		ORDER BY m.customer_id IS NULL, m.unit_of_measure <> $3, m.conversion_factor <> 1, m.id
+		LIMIT 1`, customerID, productID, uom, tenant.Company(ctx)), &m)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get customer item code: %w", err)
+	}
+	return &m, nil
+}
+
+// ============================================
+// Mapping Queue
+// ============================================
+
+// QueueUnknown records a code that could not be resolved, or counts it
+// again if it is already waiting.
+func (s *customerItemServiceImpl) QueueUnknown(ctx context.Context, req *models.QueueCustomerCodeRequest) error {
+	source := req.Source
+	if source == "" {
+		source = models.CustomerCodeFromOrder
+	}
+
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO unmapped_customer_codes (
+			company_id, customer_id, customer_code, customer_uom, description, source, order_id
+		) VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7)
+		ON CONFLICT (customer_id, UPPER(customer_code), UPPER(customer_uom)) WHERE status = 'OPEN'
+		DO UPDATE SET
+			occurrences = unmapped_customer_codes.occurrences + 1,
+			last_seen_at = NOW(),
+			order_id = COALESCE(EXCLUDED.order_id, unmapped_customer_codes.order_id),
+			description = COALESCE(EXCLUDED.description, unmapped_customer_codes.description)`,
+		tenant.Company(ctx), req.CustomerID, req.CustomerCode, req.CustomerUOM, req.Description, source, req.OrderID)
+	if err != nil {
+		return fmt.Errorf("failed to queue customer item code: %w", err)
+	}
+	return nil
+}
+
+func (s *customerItemServiceImpl) ListUnmapped(ctx context.Context, filters *models.UnmappedCodeFilters) ([]models.UnmappedCustomerCode, error) {
+	status := models.UnmappedCodeOpen
+	if filters.Status != nil {
+		status = *filters.Status
+	}
+
+	whereClause := "WHERE u.company_id = $1 AND u.status = $2"
+	args := []interface{}{tenant.Company(ctx), status}
+	argNum := 3
+
+	if filters.CustomerID != nil {
+		whereClause += fmt.Sprintf(" AND u.customer_id = $%d", argNum)
+		args = append(args, *filters.CustomerID)
+		argNum++
+	}
+
+	rows := s.db.Query(ctx, `
+		SELECT u.id, u.customer_id, c.name, u.customer_code, u.customer_uom, COALESCE(u.description, ''),
+			   u.source, u.order_id, COALESCE(so.order_number, ''), u.occurrences,
+			   u.first_seen_at, u.last_seen_at, u.status, u.mapping_id, u.resolved_by, u.resolved_at
+		FROM unmapped_customer_codes u
+		JOIN customers c ON c.id = u.customer_id
+		LEFT JOIN sales_orders so ON so.id = u.order_id
+		`+whereClause+`
+		ORDER BY u.occurrences DESC, u.last_seen_at DESC`, args...)
+	defer rows.Close()
+
+	queue := []models.UnmappedCustomerCode{}
+	for rows.Next() {
+		var u models.UnmappedCustomerCode
+		err := rows.Scan(
+			&u.ID, &u.CustomerID, &u.CustomerName, &u.CustomerCode, &u.CustomerUOM, &u.Description,
+			&u.Source, &u.OrderID, &u.OrderNumber, &u.Occurrences,
+			&u.FirstSeenAt, &u.LastSeenAt, &u.Status, &u.MappingID, &u.ResolvedBy, &u.ResolvedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan unmapped customer code: %w", err)
+		}
+		queue = append(queue, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list unmapped customer codes: %w", err)
+	}
+	return queue, nil
+}
+
+// MapUnmapped creates the mapping for a queued code, for the customer or
+// its group, in the unit it was ordered in unless any unit is asked for.
+func (s *customerItemServiceImpl) MapUnmapped(ctx context.Context, id int, req *models.MapCustomerCodeRequest, userID int) (int, error) {
+	var mappingID int
+	err := s.inTx(ctx, func(tx *customerItemServiceImpl) error {
+		var customerID int
+		var groupID *int
+		var code, uom, description string
+		var status models.UnmappedCodeStatus
+		err := tx.db.QueryRow(ctx, `
+			SELECT u.customer_id, c.customer_group_id, u.customer_code, u.customer_uom,
+				   COALESCE(u.description, ''), u.status
+			FROM unmapped_customer_codes u
+			JOIN customers c ON c.id = u.customer_id
+			WHERE u.id = $1 AND u.company_id = $2
+			FOR UPDATE OF u`,
+			id, tenant.Company(ctx)).Scan(&customerID, &groupID, &code, &uom, &description, &status)
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return ErrQueueItemNotFound
+			}
+			return fmt.Errorf("failed to get unmapped customer code: %w", err)
+		}
+		if status != models.UnmappedCodeOpen {
+			return ErrQueueItemResolved
+		}
+
+		create := &models.CreateCustomerItemCodeRequest{
+			CustomerCode:     code,
+			CustomerUOM:      uom,
+			ProductID:        req.ProductID,
+			UnitOfMeasure:    req.UnitOfMeasure,
+			ConversionFactor: req.ConversionFactor,
+			Description:      req.Description,
+		}
+		if create.Description == "" {
+			create.Description = description
+		}
+		if req.AnyUnit {
+			create.CustomerUOM = ""
+		}
+		if req.ForGroup {
+			if groupID == nil {
+				return ErrNoCustomerGroup
+			}
+			create.CustomerGroupID = groupID
+		} else {
+			create.CustomerID = &customerID
+		}
+
+		mappingID, err = tx.create(ctx, create, userID)
+		if err != nil {
+			return err
+		}
+		return tx.resolveQueue(ctx, mappingID, userID)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return mappingID, nil
+}
+
+func (s *customerItemServiceImpl) IgnoreUnmapped(ctx context.Context, id int, userID int) error {
+	tag, err := s.db.Exec(ctx, `
+		UPDATE unmapped_customer_codes SET
+			status = 'IGNORED', resolved_by = NULLIF($3, 0), resolved_at = NOW()
+		WHERE id = $1 AND company_id = $2 AND status = 'OPEN'`,
+		id, tenant.Company(ctx), userID)
+	if err != nil {
+		return fmt.Errorf("failed to ignore unmapped customer code: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		var exists bool
+		s.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM unmapped_customer_codes WHERE id = $1 AND company_id = $2)`,
+			id, tenant.Company(ctx)).Scan(&exists)
+		if !exists {
+			return ErrQueueItemNotFound
+		}
+		return ErrQueueItemResolved
+	}
+	return nil
+}
+
+// resolveQueue marks the open codes a new mapping covers as mapped: the
+// customer's, or those of every customer in the group.
+func (s *customerItemServiceImpl) resolveQueue(ctx context.Context, mappingID int, userID int) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE unmapped_customer_codes u SET
+			status = 'MAPPED', mapping_id = m.id, resolved_by = NULLIF($2, 0), resolved_at = NOW()
+		FROM customer_item_codes m, customers c
+		WHERE m.id = $1 AND c.id = u.customer_id AND u.status = 'OPEN'
+		  AND UPPER(u.customer_code) = UPPER(m.customer_code)
+		  AND (m.customer_uom = '' OR UPPER(u.customer_uom) = UPPER(m.customer_uom))
+		  AND (m.customer_id = u.customer_id OR m.customer_group_id = c.customer_group_id)`,
+		mappingID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to clear unmapped customer codes: %w", err)
+	}
+	return nil
+}
+
+// checkOwner confirms the customer or group belongs to the company.
+func (s *customerItemServiceImpl) checkOwner(ctx context.Context, customerID, groupID *int) error {
+	if customerID != nil {
+		var exists bool
+		err := s.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM customers WHERE id = $1 AND company_id = $2)`,
+			*customerID, tenant.Company(ctx)).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to get customer: %w", err)
+		}
+		if !exists {
+			return ErrCustomerNotFound
+		}
+	}
+	if groupID != nil {
+		var exists bool
+		err := s.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM customer_groups WHERE id = $1)`, *groupID).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to get customer group: %w", err)
+		}
+		if !exists {
+			return ErrCustomerNotFound
+		}
+	}
+	return nil
+}
+
+func (s *customerItemServiceImpl) checkDuplicate(ctx context.Context, exceptID int, customerID, groupID *int, code, uom string) error {
+	var exists bool
+	err := s.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM customer_item_codes
+			WHERE id <> $1 AND customer_id IS NOT DISTINCT FROM $2 AND customer_group_id IS NOT DISTINCT FROM $3
+			  AND UPPER(customer_code) = UPPER($4) AND UPPER(customer_uom) = UPPER($5)
+		)`, exceptID, customerID, groupID, code, uom).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check customer item code: %w", err)
+	}
+	if exists {
+		return ErrDuplicateCode
+	}
+	return nil
+}
@@ -0,0 +1,556 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	"github.com/anas-dev-92/FoodHive/core/storage"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	rmaService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/rma"
+	soService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/sales_order"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrNotFound          = errors.New("proof of delivery not found")
+	ErrPhotoNotFound     = errors.New("delivery photo not found")
+	ErrStopNotFound      = errors.New("route stop not found")
+	ErrNothingAtStop     = errors.New("no shipped orders to deliver at the stop")
+	ErrOrderNotAtStop    = errors.New("order is not shipped to the stop")
+	ErrOrderLineNotFound = errors.New("order line not found on the shipped order")
+	ErrExceedsShipped    = errors.New("refused quantity exceeds quantity shipped")
+	ErrInvalidImage      = errors.New("signature and photos must be PNG or JPEG images")
+	ErrNoStorage         = errors.New("file storage is not configured")
+)
+
+// bucket holds signatures and photos, under a folder per company.
+const bucket = "deliveries"
+
+// quantityTolerance absorbs rounding in DECIMAL(10,3) quantities.
+const quantityTolerance = 0.0005
+
+// ============================================
+// Service Interface
+// ============================================
+
+type DeliveryService interface {
+	// Confirmation
+	ConfirmOrder(ctx context.Context, orderID int, req *models.ConfirmDeliveryRequest, confirmedBy int) (*models.ProofOfDelivery, error)
+	ConfirmStop(ctx context.Context, stopID int, req *models.ConfirmStopDeliveryRequest, confirmedBy int) ([]models.ProofOfDelivery, error)
+
+	// Retrieval
+	GetByID(ctx context.Context, id int) (*models.ProofOfDelivery, error)
+	GetByOrder(ctx context.Context, orderID int) (*models.ProofOfDelivery, error)
+	GetByInvoice(ctx context.Context, invoiceID int) (*models.ProofOfDelivery, error)
+	Signature(ctx context.Context, id int) ([]byte, error)
+	Photo(ctx context.Context, id, photoID int) ([]byte, error)
+}
+
+// ============================================
+// Service Implementation
+// ============================================
+
+type deliveryServiceImpl struct {
+	db      postgres.Executor
+	storage storage.StorageService
+}
+
+// New creates the delivery service. storageService may be nil, in which
+// case deliveries cannot be confirmed and images cannot be downloaded.
+func New(db postgres.Executor, storageService storage.StorageService) DeliveryService {
+	return &deliveryServiceImpl{db: db, storage: storageService}
+}
+
+// inTx runs fn in a transaction. When the service was built on a caller's
+// transaction, fn runs in that one.
+func (s *deliveryServiceImpl) inTx(ctx context.Context, fn func(tx *deliveryServiceImpl) error) error {
+	conn, ok := s.db.(postgres.Connection)
+	if !ok {
+		return fn(s)
+	}
+
+	tx, err := conn.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&deliveryServiceImpl{db: tx, storage: s.storage}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ============================================
+// Confirmation
+// ============================================
+
+// storedEvidence is the evidence with its images saved to storage.
+type storedEvidence struct {
+	*models.DeliveryEvidence
+	signaturePath string
+	photoPaths    []string
+}
+
+// ConfirmOrder delivers a shipped order. Refused goods go back on an RMA
+// that is received and approved at once, crediting the customer.
+func (s *deliveryServiceImpl) ConfirmOrder(ctx context.Context, orderID int, req *models.ConfirmDeliveryRequest, confirmedBy int) (*models.ProofOfDelivery, error) {
+	evidence, err := s.storeEvidence(ctx, &req.DeliveryEvidence)
+	if err != nil {
+		return nil, err
+	}
+
+	var id int
+	err = s.inTx(ctx, func(tx *deliveryServiceImpl) error {
+		id, err = tx.confirm(ctx, orderID, nil, evidence, req.Lines, confirmedBy)
+		return err
+	})
+	if err != nil {
+		s.discard(evidence)
+		return nil, err
+	}
+	return s.GetByID(ctx, id)
+}
+
+// ConfirmStop delivers every order shipped to a route stop under one
+// signature. Orders not listed in the request were accepted in full.
+func (s *deliveryServiceImpl) ConfirmStop(ctx context.Context, stopID int, req *models.ConfirmStopDeliveryRequest, confirmedBy int) ([]models.ProofOfDelivery, error) {
+	orderIDs, err := s.stopOrders(ctx, stopID, req.RunDate)
+	if err != nil {
+		return nil, err
+	}
+	refusals := make(map[int][]models.DeliveryRefusalRequest)
+	for _, order := range req.Orders {
+		refusals[order.OrderID] = order.Lines
+	}
+	for orderID := range refusals {
+		found := false
+		for _, id := range orderIDs {
+			found = found || id == orderID
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: order %d", ErrOrderNotAtStop, orderID)
+		}
+	}
+
+	evidence, err := s.storeEvidence(ctx, &req.DeliveryEvidence)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int
+	err = s.inTx(ctx, func(tx *deliveryServiceImpl) error {
+		for _, orderID := range orderIDs {
+			id, err := tx.confirm(ctx, orderID, &stopID, evidence, refusals[orderID], confirmedBy)
+			if err != nil {
+				return err
+			}
+			ids = append(ids, id)
+		}
+		return nil
+	})
+	if err != nil {
+		s.discard(evidence)
+		return nil, err
+	}
+
+	pods := make([]models.ProofOfDelivery, 0, len(ids))
+	for _, id := range ids {
+		pod, err := s.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		pods = append(pods, *pod)
+	}
+	return pods, nil
+}
+
+// stopOrders finds the shipped orders on the stop's route for its customer
+// and, when the stop has one, its ship-to.
+func (s *deliveryServiceImpl) stopOrders(ctx context.Context, stopID int, runDate string) ([]int, error) {
+	var exists bool
+	err := s.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM route_stops rs JOIN customers c ON c.id = rs.customer_id
+			WHERE rs.id = $1 AND c.company_id = $2
+		)`, stopID, tenant.Company(ctx)).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get route stop: %w", err)
+	}
+	if !exists {
+		return nil, ErrStopNotFound
+	}
+
+	rows := s.db.Query(ctx, `
+		SELECT so.id
+		FROM route_stops rs
+		JOIN sales_orders so ON so.route_id = rs.route_id AND so.customer_id = rs.customer_id
+		WHERE rs.id = $1 AND so.company_id = $2 AND so.status = 'SHIPPED'
+		  AND (rs.ship_to_id IS NULL OR so.ship_to_id = rs.ship_to_id)
+		  AND (NULLIF($3, '')::date IS NULL OR so.requested_ship_date = NULLIF($3, '')::date)
+		ORDER BY so.id`, stopID, tenant.Company(ctx), runDate)
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan stop order: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get stop orders: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, ErrNothingAtStop
+	}
+	return ids, nil
+}
+
+type shippedLine struct {
+	id        int
+	productID int
+	shipped   float64
+}
+
+// confirm records the proof of delivery for one order and moves it to
+// DELIVERED. Lines not refused were accepted in full.
+func (s *deliveryServiceImpl) confirm(ctx context.Context, orderID int, stopID *int, evidence *storedEvidence, refusals []models.DeliveryRefusalRequest, confirmedBy int) (int, error) {
+	// Locks the order and checks it was shipped
+	if err := soService.New(s.db).Deliver(ctx, orderID, confirmedBy); err != nil {
+		return 0, err
+	}
+
+	rows := s.db.Query(ctx, `
+		SELECT id, product_id, quantity_shipped
+		FROM sales_order_lines
+		WHERE order_id = $1 AND quantity_shipped > 0
+		ORDER BY line_number`, orderID)
+	var lines []shippedLine
+	for rows.Next() {
+		var l shippedLine
+		if err := rows.Scan(&l.id, &l.productID, &l.shipped); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan order line: %w", err)
+		}
+		lines = append(lines, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to get order lines: %w", err)
+	}
+
+	refused := make(map[int]models.DeliveryRefusalRequest)
+	for _, r := range refusals {
+		var line *shippedLine
+		for i := range lines {
+			if lines[i].id == r.OrderLineID {
+				line = &lines[i]
+			}
+		}
+		if line == nil {
+			return 0, fmt.Errorf("%w: line %d", ErrOrderLineNotFound, r.OrderLineID)
+		}
+		if r.QuantityRefused > line.shipped+quantityTolerance {
+			return 0, fmt.Errorf("%w: line %d shipped %.3f", ErrExceedsShipped, r.OrderLineID, line.shipped)
+		}
+		refused[r.OrderLineID] = r
+	}
+
+	var id int
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO delivery_confirmations (
+			company_id, order_id, route_stop_id, recipient_name, signature_path,
+			delivered_at, latitude, longitude, notes, confirmed_by
+		) VALUES ($1, $2, $3, $4, $5, COALESCE($6, NOW()), $7, $8, NULLIF($9, ''), NULLIF($10, 0))
+		RETURNING id`,
+		tenant.Company(ctx), orderID, stopID, evidence.RecipientName, evidence.signaturePath,
+		evidence.DeliveredAt, evidence.Latitude, evidence.Longitude, evidence.Notes, confirmedBy,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to record delivery: %w", err)
+	}
+
+	for _, line := range lines {
+		r := refused[line.id]
+		var reason *models.ReturnReason
+		if r.QuantityRefused > 0 {
+			reason = &r.RefusalReason
+		}
+		_, err := s.db.Exec(ctx, `
+			INSERT INTO delivery_confirmation_lines (
+				confirmation_id, order_line_id, product_id, quantity_shipped,
+				quantity_accepted, quantity_refused, refusal_reason, notes
+			) VALUES ($1, $2, $3, $4, GREATEST($4 - $5, 0), $5, $6, NULLIF($7, ''))`,
+			id, line.id, line.productID, line.shipped, r.QuantityRefused, reason, r.Notes)
+		if err != nil {
+			return 0, fmt.Errorf("failed to record delivery line: %w", err)
+		}
+	}
+
+	for _, path := range evidence.photoPaths {
+		_, err := s.db.Exec(ctx, `
+			INSERT INTO delivery_photos (confirmation_id, file_path) VALUES ($1, $2)`, id, path)
+		if err != nil {
+			return 0, fmt.Errorf("failed to record delivery photo: %w", err)
+		}
+	}
+
+	if len(refusals) > 0 {
+		if err := s.returnRefused(ctx, id, orderID, evidence.RecipientName, refusals, confirmedBy); err != nil {
+			return 0, err
+		}
+	}
+	return id, nil
+}
+
+// returnRefused puts the refused goods on an RMA. The driver brings them
+// back, so the return is received and approved with the delivery; the
+// approval issues the credit memo and QA dispositions the goods as usual.
+func (s *deliveryServiceImpl) returnRefused(ctx context.Context, id, orderID int, recipient string, refusals []models.DeliveryRefusalRequest, confirmedBy int) error {
+	var invoiceID *int
+	err := s.db.QueryRow(ctx, `
+		SELECT id FROM ar_invoices
+		WHERE order_id = $1 AND invoice_type = 'INVOICE' AND status <> 'VOID'
+		ORDER BY id DESC LIMIT 1`, orderID).Scan(&invoiceID)
+	if err != nil && err != pgx.ErrNoRows {
+		return fmt.Errorf("failed to get order invoice: %w", err)
+	}
+
+	create := &models.CreateRMARequest{
+		OrderID:    &orderID,
+		InvoiceID:  invoiceID,
+		ReasonCode: refusals[0].RefusalReason,
+		Notes:      fmt.Sprintf("Refused on delivery, signed by %s", recipient),
+	}
+	for _, r := range refusals {
+		create.Lines = append(create.Lines, models.CreateRMALineRequest{
+			OrderLineID: r.OrderLineID,
+			Quantity:    r.QuantityRefused,
+			ReasonCode:  r.RefusalReason,
+			Notes:       r.Notes,
+		})
+	}
+
+	rmas := rmaService.New(s.db)
+	rmaID, err := rmas.Create(ctx, create, confirmedBy)
+	if err != nil {
+		return err
+	}
+	rma, err := rmas.GetByID(ctx, rmaID)
+	if err != nil {
+		return err
+	}
+
+	receive := &models.ReceiveRMARequest{Notes: "Returned by the driver"}
+	for _, line := range rma.Lines {
+		received := models.ReceiveRMALineRequest{LineID: line.ID, Quantity: line.QuantityAuthorized}
+		for _, r := range refusals {
+			if r.OrderLineID == line.OrderLineID {
+				received.CatchWeight = r.CatchWeight
+			}
+		}
+		receive.Lines = append(receive.Lines, received)
+	}
+	if err := rmas.Receive(ctx, rmaID, receive, confirmedBy); err != nil {
+		return err
+	}
+	if err := rmas.Approve(ctx, rmaID, confirmedBy); err != nil {
+		return err
+	}
+
+	if _, err := s.db.Exec(ctx, `UPDATE delivery_confirmations SET rma_id = $1 WHERE id = $2`, rmaID, id); err != nil {
+		return fmt.Errorf("failed to link return: %w", err)
+	}
+	return nil
+}
+
+// storeEvidence checks and uploads the signature and photos before the
+// delivery is recorded.
+func (s *deliveryServiceImpl) storeEvidence(ctx context.Context, evidence *models.DeliveryEvidence) (*storedEvidence, error) {
+	if s.storage == nil {
+		return nil, ErrNoStorage
+	}
+
+	images := append([][]byte{evidence.Signature}, evidence.Photos...)
+	exts := make([]string, len(images))
+	for i, image := range images {
+		switch http.DetectContentType(image) {
+		case "image/png":
+			exts[i] = ".png"
+		case "image/jpeg":
+			exts[i] = ".jpg"
+		default:
+			return nil, ErrInvalidImage
+		}
+	}
+
+	stored := &storedEvidence{DeliveryEvidence: evidence}
+	prefix := fmt.Sprintf("%d/%d", tenant.Company(ctx), time.Now().UnixNano())
+	for i, content := range images {
+		name := fmt.Sprintf("%s_signature%s", prefix, exts[i])
+		if i > 0 {
+			name = fmt.Sprintf("%s_photo%d%s", prefix, i, exts[i])
+		}
+		if _, err := s.storage.UploadFile(bucket, name, content); err != nil {
+			s.discard(stored)
+			return nil, fmt.Errorf("uploading delivery image: %w", err)
+		}
+		if i == 0 {
+			stored.signaturePath = name
+		} else {
+			stored.photoPaths = append(stored.photoPaths, name)
+		}
+	}
+	return stored, nil
+}
+
+// discard removes images uploaded for a delivery that was not recorded.
+func (s *deliveryServiceImpl) discard(evidence *storedEvidence) {
+	if evidence.signaturePath != "" {
+		s.storage.DeleteFile(bucket, evidence.signaturePath)
+	}
+	for _, path := range evidence.photoPaths {
+		s.storage.DeleteFile(bucket, path)
+	}
+}
+
+// ============================================
+// Retrieval
+// ============================================
+
+const podSelect = `
+	SELECT dc.id, dc.order_id, so.order_number, so.customer_id, c.name, dc.route_stop_id,
+		   dc.recipient_name, dc.signature_path, dc.delivered_at, dc.latitude, dc.longitude,
+		   COALESCE(dc.notes, ''), dc.rma_id, COALESCE(r.rma_number, ''), r.credit_invoice_id,
+		   dc.confirmed_by, COALESCE(e.english_name, ''), dc.created_at
+	FROM delivery_confirmations dc
+	JOIN sales_orders so ON so.id = dc.order_id
+	JOIN customers c ON c.id = so.customer_id
+	LEFT JOIN rmas r ON r.id = dc.rma_id
+	LEFT JOIN employees e ON e.id = dc.confirmed_by`
+
+func (s *deliveryServiceImpl) get(ctx context.Context, where string, arg int) (*models.ProofOfDelivery, error) {
+	var p models.ProofOfDelivery
+	err := s.db.QueryRow(ctx, podSelect+`
+		WHERE dc.company_id = $1 AND `+where, tenant.Company(ctx), arg).Scan(
+		&p.ID, &p.OrderID, &p.OrderNumber, &p.CustomerID, &p.CustomerName, &p.RouteStopID,
+		&p.RecipientName, &p.SignaturePath, &p.DeliveredAt, &p.Latitude, &p.Longitude,
+		&p.Notes, &p.RMAID, &p.RMANumber, &p.CreditInvoiceID,
+		&p.ConfirmedBy, &p.ConfirmedByName, &p.CreatedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get proof of delivery: %w", err)
+	}
+
+	if p.Lines, err = s.getLines(ctx, p.ID); err != nil {
+		return nil, err
+	}
+	if p.Photos, err = s.getPhotos(ctx, p.ID); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (s *deliveryServiceImpl) GetByID(ctx context.Context, id int) (*models.ProofOfDelivery, error) {
+	return s.get(ctx, "dc.id = $2", id)
+}
+
+func (s *deliveryServiceImpl) GetByOrder(ctx context.Context, orderID int) (*models.ProofOfDelivery, error) {
+	return s.get(ctx, "dc.order_id = $2", orderID)
+}
+
+// GetByInvoice finds the proof of delivery for the order an invoice or
+// credit memo was raised from.
+func (s *deliveryServiceImpl) GetByInvoice(ctx context.Context, invoiceID int) (*models.ProofOfDelivery, error) {
+	return s.get(ctx, "dc.order_id = (SELECT order_id FROM ar_invoices WHERE id = $2 AND company_id = dc.company_id)", invoiceID)
+}
+
+func (s *deliveryServiceImpl) getLines(ctx context.Context, id int) ([]models.ProofOfDeliveryLine, error) {
+	rows := s.db.Query(ctx, `
+		SELECT l.id, l.order_line_id, l.product_id, p.sku, p.name,
+			   l.quantity_shipped, l.quantity_accepted, l.quantity_refused,
+			   COALESCE(l.refusal_reason, ''), COALESCE(l.notes, '')
+		FROM delivery_confirmation_lines l
+		JOIN sales_order_lines sol ON sol.id = l.order_line_id
+		JOIN products p ON p.id = l.product_id
+		WHERE l.confirmation_id = $1
+		ORDER BY sol.line_number`, id)
+	defer rows.Close()
+
+	lines := []models.ProofOfDeliveryLine{}
+	for rows.Next() {
+		var l models.ProofOfDeliveryLine
+		if err := rows.Scan(&l.ID, &l.OrderLineID, &l.ProductID, &l.ProductSKU, &l.ProductName,
+			&l.QuantityShipped, &l.QuantityAccepted, &l.QuantityRefused,
+			&l.RefusalReason, &l.Notes); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery line: %w", err)
+		}
+		lines = append(lines, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get delivery lines: %w", err)
+	}
+	return lines, nil
+}
+
+func (s *deliveryServiceImpl) getPhotos(ctx context.Context, id int) ([]models.DeliveryPhoto, error) {
+	rows := s.db.Query(ctx, `
+		SELECT id, file_path, created_at FROM delivery_photos
+		WHERE confirmation_id = $1 ORDER BY id`, id)
+	defer rows.Close()
+
+	photos := []models.DeliveryPhoto{}
+	for rows.Next() {
+		var p models.DeliveryPhoto
+		if err := rows.Scan(&p.ID, &p.FilePath, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery photo: %w", err)
+		}
+		photos = append(photos, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get delivery photos: %w", err)
+	}
+	return photos, nil
+}
+
+func (s *deliveryServiceImpl) Signature(ctx context.Context, id int) ([]byte, error) {
+	pod, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.download(pod.SignaturePath)
+}
+
+func (s *deliveryServiceImpl) Photo(ctx context.Context, id, photoID int) ([]byte, error) {
+	pod, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, photo := range pod.Photos {
+		if photo.ID == photoID {
+			return s.download(photo.FilePath)
+		}
+	}
+	return nil, ErrPhotoNotFound
+}
+
+func (s *deliveryServiceImpl) download(path string) ([]byte, error) {
+	if s.storage == nil {
+		return nil, ErrNoStorage
+	}
+	content, err := s.storage.DownloadFile(bucket, path)
+	if err != nil {
+		return nil, fmt.Errorf("downloading delivery image: %w", err)
+	}
+	return content, nil
+}
@@ -0,0 +1,1244 @@
+package document
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	"github.com/anas-dev-92/FoodHive/core/storage"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	apService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/ap"
+	arService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/ar"
+	payrollService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/payroll"
+	pickingService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/picking"
+	poService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/purchase_order"
+	salesOrderService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/sales_order"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/i18n"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/pdf"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrNotFound      = errors.New("document not found")
+	ErrInvalidPeriod = errors.New("invalid statement period, dates must be YYYY-MM-DD and from must not be after to")
+	ErrNoStorage     = errors.New("file storage is not configured")
+	ErrInvalidLogo   = errors.New("logo must be a JPEG, PNG or GIF image")
+)
+
+// DocumentService renders printable PDF documents and keeps the company
+// branding and print history used on them.
+type DocumentService interface {
+	// Branding
+	GetCompanyProfile(ctx context.Context) (*models.CompanyProfile, error)
+	UpdateCompanyProfile(ctx context.Context, req *models.UpdateCompanyProfileRequest, updatedBy int) error
+	UploadLogo(ctx context.Context, fileName string, content []byte, updatedBy int) error
+
+	// Print history
+	ListPrints(ctx context.Context, docType models.DocumentType, documentID int) ([]models.DocumentPrint, error)
+
+	// Rendering
+	RenderInvoice(ctx context.Context, invoiceID, printedBy int) (*models.RenderedDocument, error)
+	RenderDeliveryNote(ctx context.Context, orderID, printedBy int) (*models.RenderedDocument, error)
+	RenderPurchaseOrder(ctx context.Context, poID, printedBy int) (*models.RenderedDocument, error)
+	RenderPickList(ctx context.Context, pickListID, printedBy int) (*models.RenderedDocument, error)
+	RenderStatement(ctx context.Context, customerID int, fromDate, toDate string, printedBy int) (*models.RenderedDocument, error)
+	RenderPaymentVoucher(ctx context.Context, paymentID, printedBy int) (*models.RenderedDocument, error)
+	RenderPayslips(ctx context.Context, payrollID int, employeeID *int, printedBy int) (*models.RenderedDocument, error)
+	RenderReport(ctx context.Context, report *models.ReportTable) (*models.RenderedDocument, error)
+}
+
+type documentServiceImpl struct {
+	db      postgres.Connection
+	storage storage.StorageService
+	regular []*pdf.TrueTypeFont
+	bold    []*pdf.TrueTypeFont
+
+	ar         arService.ARService
+	ap         apService.APService
+	po         poService.PurchaseOrderService
+	picking    pickingService.PickingService
+	payroll    payrollService.PayrollService
+	salesOrder salesOrderService.SalesOrderService
+}
+
+// New creates the document service. storageService may be nil, in which case
+// documents are printed without a logo. The TrueType fonts are tried in order
+// for each character so Lao and Thai text can be mixed with Latin text.
+func New(db postgres.Connection, storageService storage.StorageService, regular, bold []*pdf.TrueTypeFont) DocumentService {
+	return &documentServiceImpl{
+		db:         db,
+		storage:    storageService,
+		regular:    regular,
+		bold:       bold,
+		ar:         arService.New(db),
+		ap:         apService.New(db),
+		po:         poService.New(db),
+		picking:    pickingService.New(db),
+		payroll:    payrollService.New(db),
+		salesOrder: salesOrderService.New(db),
+	}
+}
+
+// logoBucket is where uploaded logos are stored.
+const logoBucket = "branding"
+
+// ============================================
+// Company Profile
+// ============================================
+
+func (s *documentServiceImpl) GetCompanyProfile(ctx context.Context) (*models.CompanyProfile, error) {
+	query := `
+		SELECT id, company_name, COALESCE(legal_name, ''), COALESCE(tax_id, ''), COALESCE(address, ''),
+			   COALESCE(phone, ''), COALESCE(email, ''), COALESCE(website, ''), COALESCE(bank_details, ''),
+			   COALESCE(footer_text, ''), COALESCE(logo_bucket, ''), COALESCE(logo_path, ''),
+			   COALESCE(primary_color, '#1F4E79'), updated_by, updated_at
+		FROM company_profile
+		WHERE id = $1`
+
+	// Each company keeps its profile in the row with its own id.
+	var p models.CompanyProfile
+	err := s.db.QueryRow(ctx, query, tenant.Company(ctx)).Scan(
+		&p.ID, &p.CompanyName, &p.LegalName, &p.TaxID, &p.Address,
+		&p.Phone, &p.Email, &p.Website, &p.BankDetails,
+		&p.FooterText, &p.LogoBucket, &p.LogoPath,
+		&p.PrimaryColor, &p.UpdatedBy, &p.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Documents must still print before the profile is set up.
+			return &models.CompanyProfile{CompanyName: "FoodHive", PrimaryColor: "#1F4E79"}, nil
+		}
+		return nil, fmt.Errorf("getting company profile: %w", err)
+	}
+
+	return &p, nil
+}
+
+func (s *documentServiceImpl) UpdateCompanyProfile(ctx context.Context, req *models.UpdateCompanyProfileRequest, updatedBy int) error {
+	var by *int
+	if updatedBy > 0 {
+		by = &updatedBy
+	}
+
+	setClauses := []string{"updated_by = $1", "updated_at = NOW()"}
+	args := []interface{}{by}
+	argNum := 2
+
+	fields := []struct {
+		column string
+		value  *string
+	}{
+		{"company_name", req.CompanyName},
+		{"legal_name", req.LegalName},
+		{"tax_id", req.TaxID},
+		{"address", req.Address},
+		{"phone", req.Phone},
+		{"email", req.Email},
+		{"website", req.Website},
+		{"bank_details", req.BankDetails},
+		{"footer_text", req.FooterText},
+		{"logo_bucket", req.LogoBucket},
+		{"logo_path", req.LogoPath},
+		{"primary_color", req.PrimaryColor},
+	}
+	for _, f := range fields {
+		if f.value == nil {
+			continue
+		}
+		setClauses = append(setClauses, fmt.Sprintf("%s = $%d", f.column, argNum))
+		args = append(args, *f.value)
+		argNum++
+	}
+
+	companyID := tenant.Company(ctx)
+	args = append(args, companyID)
+	query := fmt.Sprintf(`UPDATE company_profile SET %s WHERE id = $%d`, strings.Join(setClauses, ", "), argNum)
+	result, err := s.db.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("updating company profile: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		name := "FoodHive"
+		if req.CompanyName != nil {
+			name = *req.CompanyName
+		}
+		if _, err := s.db.Exec(ctx, `INSERT INTO company_profile (id, company_name) VALUES ($1, $2)`, companyID, name); err != nil {
+			return fmt.Errorf("creating company profile: %w", err)
+		}
+		return s.UpdateCompanyProfile(ctx, req, updatedBy)
+	}
+
+	return nil
+}
+
+func (s *documentServiceImpl) UploadLogo(ctx context.Context, fileName string, content []byte, updatedBy int) error {
+	if s.storage == nil {
+		return ErrNoStorage
+	}
+
+	// Reject files the PDF writer cannot embed before storing them.
+	if _, err := pdf.New(nil, nil).LoadImage(content); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidLogo, err)
+	}
+
+	path := fmt.Sprintf("logo/%d_%s", time.Now().Unix(), fileName)
+	if _, err := s.storage.UploadFile(logoBucket, path, content); err != nil {
+		return fmt.Errorf("uploading logo: %w", err)
+	}
+
+	bucket := logoBucket
+	return s.UpdateCompanyProfile(ctx, &models.UpdateCompanyProfileRequest{
+		LogoBucket: &bucket,
+		LogoPath:   &path,
+	}, updatedBy)
+}
+
+// ============================================
+// Print History
+// ============================================
+
+func (s *documentServiceImpl) ListPrints(ctx context.Context, docType models.DocumentType, documentID int) ([]models.DocumentPrint, error) {
+	query := `
+		SELECT p.id, p.document_type, p.document_id, COALESCE(p.document_number, ''), p.copy_number,
+			   p.printed_by, COALESCE(e.english_name, ''), p.printed_at
+		FROM document_prints p
+		LEFT JOIN employees e ON p.printed_by = e.id
+		WHERE p.document_type = $1 AND p.document_id = $2
+		ORDER BY p.copy_number`
+
+	rows := s.db.Query(ctx, query, docType, documentID)
+	defer rows.Close()
+
+	var prints []models.DocumentPrint
+	for rows.Next() {
+		var p models.DocumentPrint
+		if err := rows.Scan(&p.ID, &p.DocumentType, &p.DocumentID, &p.DocumentNumber, &p.CopyNumber,
+			&p.PrintedBy, &p.PrintedByName, &p.PrintedAt); err != nil {
+			return nil, fmt.Errorf("scanning document print: %w", err)
+		}
+		prints = append(prints, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing document prints: %w", err)
+	}
+
+	return prints, nil
+}
+
+// print records a print in the log and renders the document inside the same
+// transaction, so a failed render does not count as a copy. The copy number
+// decides whether the reprint watermark is applied.
+func (s *documentServiceImpl) print(
+	ctx context.Context,
+	docType models.DocumentType,
+	documentID int,
+	number string,
+	printedBy int,
+	render func(copyNumber int) ([]byte, error),
+) ([]byte, error) {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var by *int
+	if printedBy > 0 {
+		by = &printedBy
+	}
+
+	// Serialise concurrent prints of the same document so copy numbers stay
+	// unique.
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1), $2)`, string(docType), documentID); err != nil {
+		return nil, fmt.Errorf("locking print log: %w", err)
+	}
+
+	var copyNumber int
+	err = tx.QueryRow(ctx, `
+		INSERT INTO document_prints (document_type, document_id, document_number, copy_number, printed_by)
+		SELECT $1, $2, $3, COALESCE(MAX(copy_number), 0) + 1, $4
+		FROM document_prints WHERE document_type = $1 AND document_id = $2
+		RETURNING copy_number`,
+		docType, documentID, number, by,
+	).Scan(&copyNumber)
+	if err != nil {
+		return nil, fmt.Errorf("recording print: %w", err)
+	}
+
+	content, err := render(copyNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing print: %w", err)
+	}
+
+	return content, nil
+}
+
+// ============================================
+// Shared Rendering
+// ============================================
+
+// start loads the branding and creates the page layout for a document
+// printed in lang.
+func (s *documentServiceImpl) start(ctx context.Context, lang i18n.Lang, title, number, watermark string) (*layout, error) {
+	profile, err := s.GetCompanyProfile(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var logo []byte
+	if s.storage != nil && profile.LogoBucket != "" && profile.LogoPath != "" {
+		// The logo is decoration; print without it if storage is down.
+		logo, _ = s.storage.DownloadFile(profile.LogoBucket, profile.LogoPath)
+	}
+
+	return newLayout(pdf.New(s.regular, s.bold), profile, logo, lang, title, number, watermark), nil
+}
+
+// watermarkFor picks the status watermark, falling back to the reprint mark.
+func watermarkFor(lang i18n.Lang, status string, copyNumber int) string {
+	if status != "" {
+		return i18n.Label(lang, status)
+	}
+	if copyNumber > 1 {
+		return fmt.Sprintf(i18n.Label(lang, "REPRINT - COPY %d"), copyNumber)
+	}
+	return ""
+}
+
+func rendered(name string, content []byte) *models.RenderedDocument {
+	return &models.RenderedDocument{
+		FileName:    name + ".pdf",
+		ContentType: "application/pdf",
+		Content:     content,
+	}
+}
+
+type partyInfo struct {
+	Name     string
+	Code     string
+	Address  string
+	Phone    string
+	Email    string
+	TaxID    string
+	Language i18n.Lang // document language of a customer or vendor
+}
+
+func (p partyInfo) lines(lang i18n.Lang) []string {
+	return []string{
+		p.Name,
+		p.Code,
+		p.Address,
+		joinNonEmpty("  ", prefixed(i18n.Label(lang, "Tel "), p.Phone), p.Email),
+		prefixed(i18n.Label(lang, "Tax ID: "), p.TaxID),
+	}
+}
+
+func (s *documentServiceImpl) customerInfo(ctx context.Context, customerID int) (partyInfo, error) {
+	var p partyInfo
+	err := s.db.QueryRow(ctx, `
+		SELECT name, customer_code, COALESCE(full_address, ''), COALESCE(tel, mobile, ''),
+			   COALESCE(email, ''), COALESCE(tax_code, ''), document_language
+		FROM customers WHERE id = $1 AND company_id = $2`, customerID, tenant.Company(ctx),
+	).Scan(&p.Name, &p.Code, &p.Address, &p.Phone, &p.Email, &p.TaxID, &p.Language)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return p, ErrNotFound
+		}
+		return p, fmt.Errorf("getting customer: %w", err)
+	}
+	return p, nil
+}
+
+func (s *documentServiceImpl) vendorInfo(ctx context.Context, vendorID int) (partyInfo, string, error) {
+	var p partyInfo
+	var line1, line2, city, country, bankName, bankAccount, bankAccountName string
+	err := s.db.QueryRow(ctx, `
+		SELECT name, vendor_code, COALESCE(address_line1, ''), COALESCE(address_line2, ''),
+			   COALESCE(city, ''), COALESCE(country, ''), COALESCE(phone, mobile, ''),
+			   COALESCE(email, ''), COALESCE(tax_code, ''), COALESCE(bank_name, ''),
+			   COALESCE(bank_account_number, ''), COALESCE(bank_account_name, ''), document_language
+		FROM vendors WHERE id = $1 AND company_id = $2`, vendorID, tenant.Company(ctx),
+	).Scan(&p.Name, &p.Code, &line1, &line2, &city, &country, &p.Phone,
+		&p.Email, &p.TaxID, &bankName, &bankAccount, &bankAccountName, &p.Language)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return p, "", ErrNotFound
+		}
+		return p, "", fmt.Errorf("getting vendor: %w", err)
+	}
+	p.Address = joinNonEmpty(", ", line1, line2, city, country)
+	bank := joinNonEmpty(" / ", bankName, bankAccount, bankAccountName)
+	return p, bank, nil
+}
+
+func (s *documentServiceImpl) warehouseInfo(ctx context.Context, warehouseID int) (partyInfo, error) {
+	var p partyInfo
+	var line1, line2, city, country string
+	err := s.db.QueryRow(ctx, `
+		SELECT name, warehouse_code, COALESCE(address_line1, ''), COALESCE(address_line2, ''),
+			   COALESCE(city, ''), COALESCE(country, ''), COALESCE(phone, ''), COALESCE(email, '')
+		FROM warehouses WHERE id = $1`, warehouseID,
+	).Scan(&p.Name, &p.Code, &line1, &line2, &city, &country, &p.Phone, &p.Email)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return p, nil
+		}
+		return p, fmt.Errorf("getting warehouse: %w", err)
+	}
+	p.Address = joinNonEmpty(", ", line1, line2, city, country)
+	return p, nil
+}
+
+type productInfo struct {
+	SKU  string
+	Name string
+}
+
+// products loads SKU and name for the given product IDs in one query,
+// using the translated name for lang where one exists.
+func (s *documentServiceImpl) products(ctx context.Context, lang i18n.Lang, ids []int) (map[int]productInfo, error) {
+	result := make(map[int]productInfo)
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	rows := s.db.Query(ctx, `SELECT id, sku, name, name_translations FROM products WHERE id = ANY($1)`, ids)
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var p productInfo
+		var translations models.Translations
+		if err := rows.Scan(&id, &p.SKU, &p.Name, &translations); err != nil {
+			return nil, fmt.Errorf("scanning product: %w", err)
+		}
+		p.Name = i18n.Pick(lang, p.Name, translations)
+		result[id] = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("loading products: %w", err)
+	}
+
+	return result, nil
+}
+
+// ============================================
+// AR Invoice
+// ============================================
+
+func (s *documentServiceImpl) RenderInvoice(ctx context.Context, invoiceID, printedBy int) (*models.RenderedDocument, error) {
+	inv, err := s.ar.GetInvoice(ctx, invoiceID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	customer, err := s.customerInfo(ctx, inv.Invoice.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+
+	var productIDs []int
+	for _, line := range inv.Lines {
+		if line.ProductID != nil {
+			productIDs = append(productIDs, *line.ProductID)
+		}
+	}
+	lang := customer.Language
+	products, err := s.products(ctx, lang, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	status := ""
+	switch inv.Invoice.Status {
+	case models.ARInvoiceStatusDraft:
+		status = "DRAFT"
+	case models.ARInvoiceStatusVoid:
+		status = "VOID"
+	}
+
+	number := inv.Invoice.InvoiceNumber
+	content, err := s.print(ctx, models.DocumentTypeInvoice, invoiceID, number, printedBy, func(copyNumber int) ([]byte, error) {
+		l, err := s.start(ctx, lang, "Invoice", number, watermarkFor(lang, status, copyNumber))
+		if err != nil {
+			return nil, err
+		}
+
+		l.begin([][2]string{
+			{"Invoice Date", formatDate(inv.Invoice.InvoiceDate)},
+			{"Due Date", formatDate(inv.Invoice.DueDate)},
+			{"Order No.", inv.OrderNumber},
+			{"Currency", inv.Invoice.Currency},
+		})
+		l.parties(party{Title: "Bill To", Lines: customer.lines(lang)})
+
+		cols := []column{
+			{Title: "#", Width: 0.05, Align: pdf.AlignCenter},
+			{Title: "Item", Width: 0.14},
+			{Title: "Description", Width: 0.37, Wrap: true},
+			{Title: "Qty", Width: 0.10, Align: pdf.AlignRight},
+			{Title: "Unit Price", Width: 0.13, Align: pdf.AlignRight},
+			{Title: "Tax %", Width: 0.07, Align: pdf.AlignRight},
+			{Title: "Amount", Width: 0.14, Align: pdf.AlignRight},
+		}
+		// Customers who order by their own codes see them beside our SKU
+		customerCodes := false
+		for _, line := range inv.Lines {
+			customerCodes = customerCodes || line.CustomerItemCode != ""
+		}
+		if customerCodes {
+			cols[2].Width = 0.25
+			cols = slices.Insert(cols, 2, column{Title: "Cust. Item", Width: 0.12})
+		}
+		var rows [][]string
+		for _, line := range inv.Lines {
+			sku, desc := "", line.Description
+			if line.ProductID != nil {
+				p := products[*line.ProductID]
+				sku = p.SKU
+				if desc == "" {
+					desc = p.Name
+				}
+			}
+			row := []string{fmt.Sprint(line.LineNumber), sku}
+			if customerCodes {
+				row = append(row, line.CustomerItemCode)
+			}
+			rows = append(rows, append(row, desc, formatQty(line.Quantity),
+				formatAmount(line.UnitPrice), formatQty(line.TaxPercent), formatAmount(line.LineTotal)))
+		}
+		l.table(cols, rows)
+
+		cur := inv.Invoice.Currency
+		totals := [][2]string{{"Subtotal", formatMoney(inv.Invoice.Subtotal, cur)}}
+		if inv.Invoice.DiscountAmount != 0 {
+			totals = append(totals, [2]string{"Discount", formatMoney(-inv.Invoice.DiscountAmount, cur)})
+		}
+		totals = append(totals, [2]string{"Tax", formatMoney(inv.Invoice.TaxAmount, cur)})
+		if inv.Invoice.FreightAmount != 0 {
+			totals = append(totals, [2]string{"Freight", formatMoney(inv.Invoice.FreightAmount, cur)})
+		}
+		totals = append(totals, [2]string{"Total", formatMoney(inv.Invoice.TotalAmount, cur)})
+		if inv.Invoice.AmountPaid != 0 {
+			totals = append(totals, [2]string{"Paid", formatMoney(-inv.Invoice.AmountPaid, cur)})
+		}
+		totals = append(totals, [2]string{"Balance Due", formatMoney(inv.Invoice.BalanceDue, cur)})
+		l.totals(totals)
+
+		l.paragraph("Notes", inv.Invoice.Notes)
+		l.paragraph("Payment Details", l.profile.BankDetails)
+		l.signatures("Received by", "Authorized signature")
+
+		return l.bytes()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rendered("invoice_"+number, content), nil
+}
+
+// ============================================
+// Delivery Note
+// ============================================
+
+func (s *documentServiceImpl) RenderDeliveryNote(ctx context.Context, orderID, printedBy int) (*models.RenderedDocument, error) {
+	order, err := s.salesOrder.GetByID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	customer, err := s.customerInfo(ctx, order.Order.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+
+	var productIDs []int
+	for _, line := range order.Lines {
+		productIDs = append(productIDs, line.ProductID)
+	}
+	lang := customer.Language
+	products, err := s.products(ctx, lang, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	status := ""
+	switch order.Order.Status {
+	case models.OrderStatusDraft:
+		status = "DRAFT"
+	case models.OrderStatusCancelled:
+		status = "CANCELLED"
+	}
+
+	number := order.Order.OrderNumber
+	content, err := s.print(ctx, models.DocumentTypeDeliveryNote, orderID, number, printedBy, func(copyNumber int) ([]byte, error) {
+		l, err := s.start(ctx, lang, "Delivery Note", number, watermarkFor(lang, status, copyNumber))
+		if err != nil {
+			return nil, err
+		}
+
+		shipDate := order.Order.ActualShipDate
+		if time.Time(shipDate).IsZero() {
+			shipDate = order.Order.RequestedShipDate
+		}
+		l.begin([][2]string{
+			{"Order Date", formatDate(order.Order.OrderDate)},
+			{"Ship Date", formatDate(shipDate)},
+			{"Customer PO", order.Order.PONumber},
+			{"Route", order.RouteName},
+			{"Warehouse", order.WarehouseName},
+			{"Sales Rep", order.SalesRepName},
+		})
+
+		shipTo := party{Title: "Ship To", Lines: customer.lines(lang)}
+		if order.ShipToName != "" || order.ShipToAddress != "" {
+			shipTo.Lines = []string{order.ShipToName, order.ShipToAddress}
+		}
+		l.parties(party{Title: "Customer", Lines: customer.lines(lang)}, shipTo)
+
+		cols := []column{
+			{Title: "#", Width: 0.05, Align: pdf.AlignCenter},
+			{Title: "Item", Width: 0.13},
+			{Title: "Description", Width: 0.32, Wrap: true},
+			{Title: "Ordered", Width: 0.10, Align: pdf.AlignRight},
+			{Title: "Shipped", Width: 0.10, Align: pdf.AlignRight},
+			{Title: "UOM", Width: 0.07, Align: pdf.AlignCenter},
+			{Title: "Lot", Width: 0.12},
+			{Title: "Weight", Width: 0.11, Align: pdf.AlignRight},
+		}
+		var rows [][]string
+		var totalWeight float64
+		for _, line := range order.Lines {
+			p := products[line.ProductID]
+			desc := line.Description
+			if desc == "" {
+				desc = p.Name
+			}
+			weight := ""
+			if line.CatchWeight > 0 {
+				weight = formatQty(line.CatchWeight)
+				totalWeight += line.CatchWeight
+			}
+			rows = append(rows, []string{
+				fmt.Sprint(line.LineNumber), p.SKU, desc, formatQty(line.QuantityOrdered),
+				formatQty(line.QuantityShipped), line.UnitOfMeasure, line.LotNumber, weight,
+			})
+		}
+		l.table(cols, rows)
+
+		summary := [][2]string{{"Lines", fmt.Sprint(len(order.Lines))}}
+		if totalWeight > 0 {
+			summary = append(summary, [2]string{"Total Weight", formatQty(totalWeight)})
+		}
+		l.totals(summary)
+
+		l.paragraph("Notes", order.Order.Notes)
+		l.signatures("Delivered by", "Received by (name & signature)", "Date / Time")
+
+		return l.bytes()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rendered("delivery_note_"+number, content), nil
+}
+
+// ============================================
+// Purchase Order
+// ============================================
+
+func (s *documentServiceImpl) RenderPurchaseOrder(ctx context.Context, poID, printedBy int) (*models.RenderedDocument, error) {
+	po, err := s.po.GetByID(ctx, poID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	vendor, _, err := s.vendorInfo(ctx, po.Order.VendorID)
+	if err != nil {
+		return nil, err
+	}
+	warehouse, err := s.warehouseInfo(ctx, po.Order.WarehouseID)
+	if err != nil {
+		return nil, err
+	}
+
+	var productIDs []int
+	for _, line := range po.Lines {
+		productIDs = append(productIDs, line.ProductID)
+	}
+	lang := vendor.Language
+	products, err := s.products(ctx, lang, productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	status := ""
+	switch po.Order.Status {
+	case models.POStatusDraft:
+		status = "DRAFT"
+	case models.POStatusCancelled:
+		status = "CANCELLED"
+	}
+
+	number := po.Order.PONumber
+	content, err := s.print(ctx, models.DocumentTypePurchaseOrder, poID, number, printedBy, func(copyNumber int) ([]byte, error) {
+		l, err := s.start(ctx, lang, "Purchase Order", number, watermarkFor(lang, status, copyNumber))
+		if err != nil {
+			return nil, err
+		}
+
+		l.begin([][2]string{
+			{"Order Date", formatDate(po.Order.OrderDate)},
+			{"Expected", formatDate(po.Order.ExpectedDate)},
+			{"Buyer", po.BuyerName},
+		})
+		l.parties(
+			party{Title: "Vendor", Lines: vendor.lines(lang)},
+			party{Title: "Deliver To", Lines: warehouse.lines(lang)},
+		)
+
+		cols := []column{
+			{Title: "#", Width: 0.05, Align: pdf.AlignCenter},
+			{Title: "Item", Width: 0.13},
+			{Title: "Description", Width: 0.33, Wrap: true},
+			{Title: "Qty", Width: 0.09, Align: pdf.AlignRight},
+			{Title: "UOM", Width: 0.07, Align: pdf.AlignCenter},
+			{Title: "Unit Cost", Width: 0.12, Align: pdf.AlignRight},
+			{Title: "Expected", Width: 0.09, Align: pdf.AlignCenter},
+			{Title: "Amount", Width: 0.12, Align: pdf.AlignRight},
+		}
+		var rows [][]string
+		for _, line := range po.Lines {
+			p := products[line.ProductID]
+			desc := line.Description
+			if desc == "" {
+				desc = p.Name
+			}
+			rows = append(rows, []string{
+				fmt.Sprint(line.LineNumber), p.SKU, desc, formatQty(line.QuantityOrdered), line.UnitOfMeasure,
+				formatAmount(line.UnitCost), formatDate(line.ExpectedDate), formatAmount(line.LineTotal),
+			})
+		}
+		l.table(cols, rows)
+
+		totals := [][2]string{
+			{"Subtotal", formatAmount(po.Order.Subtotal)},
+			{"Tax", formatAmount(po.Order.TaxAmount)},
+		}
+		if po.Order.FreightAmount != 0 {
+			totals = append(totals, [2]string{"Freight", formatAmount(po.Order.FreightAmount)})
+		}
+		totals = append(totals, [2]string{"Total", formatAmount(po.Order.TotalAmount)})
+		l.totals(totals)
+
+		l.paragraph("Notes", po.Order.Notes)
+		l.signatures("Prepared by", "Approved by", "Vendor acknowledgement")
+
+		return l.bytes()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rendered("purchase_order_"+number, content), nil
+}
+
+// ============================================
+// Pick List
+// ============================================
+
+func (s *documentServiceImpl) RenderPickList(ctx context.Context, pickListID, printedBy int) (*models.RenderedDocument, error) {
+	pl, err := s.picking.GetPickList(ctx, pickListID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	lines, err := s.picking.GetPickLines(ctx, pickListID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Walk the warehouse in location order.
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].PickListLine.LocationCode < lines[j].PickListLine.LocationCode
+	})
+
+	status := ""
+	if pl.PickList.Status == models.PickListStatusCancelled {
+		status = "CANCELLED"
+	}
+
+	// Pick lists are for our own staff, so they print in the language of
+	// whoever prints them.
+	lang := i18n.FromContext(ctx)
+	number := pl.PickList.PickNumber
+	content, err := s.print(ctx, models.DocumentTypePickList, pickListID, number, printedBy, func(copyNumber int) ([]byte, error) {
+		l, err := s.start(ctx, lang, "Pick List", number, watermarkFor(lang, status, copyNumber))
+		if err != nil {
+			return nil, err
+		}
+
+		l.begin([][2]string{
+			{"Pick Date", formatDate(pl.PickList.PickDate)},
+			{"Warehouse", pl.WarehouseName},
+			{"Route", pl.RouteName},
+			{"Picker", pl.PickerName},
+			{"Orders", fmt.Sprint(pl.TotalOrders)},
+		})
+
+		cols := []column{
+			{Title: "Location", Width: 0.10},
+			{Title: "Item", Width: 0.11},
+			{Title: "Product", Width: 0.23, Wrap: true},
+			{Title: "Order", Width: 0.12},
+			{Title: "Customer", Width: 0.14, Wrap: true},
+			{Title: "Qty", Width: 0.07, Align: pdf.AlignRight},
+			{Title: "UOM", Width: 0.06, Align: pdf.AlignCenter},
+			{Title: "Lot", Width: 0.09},
+			{Title: "Picked", Width: 0.08, Align: pdf.AlignRight},
+		}
+		var rows [][]string
+		for _, line := range lines {
+			picked := "______"
+			if line.PickListLine.QuantityPicked > 0 {
+				picked = formatQty(line.PickListLine.QuantityPicked)
+			}
+			rows = append(rows, []string{
+				line.PickListLine.LocationCode, line.ProductSKU, line.ProductName, line.OrderNumber,
+				line.CustomerName, formatQty(line.PickListLine.QuantityOrdered), line.UnitOfMeasure,
+				line.PickListLine.LotNumber, picked,
+			})
+		}
+		l.table(cols, rows)
+
+		l.totals([][2]string{{"Total Lines", fmt.Sprint(len(lines))}})
+		l.signatures("Picked by", "Checked by", "Loaded by")
+
+		return l.bytes()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rendered("pick_list_"+number, content), nil
+}
+
+// ============================================
+// Customer Statement
+// ============================================
+
+func (s *documentServiceImpl) RenderStatement(ctx context.Context, customerID int, fromDate, toDate string, printedBy int) (*models.RenderedDocument, error) {
+	customer, err := s.customerInfo(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if fromDate == "" {
+		fromDate = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).Format("2006-01-02")
+	}
+	if toDate == "" {
+		toDate = now.Format("2006-01-02")
+	}
+	from, errFrom := time.Parse("2006-01-02", fromDate)
+	to, errTo := time.Parse("2006-01-02", toDate)
+	if errFrom != nil || errTo != nil || from.After(to) {
+		return nil, ErrInvalidPeriod
+	}
+
+	stmt, err := s.ar.GetStatement(ctx, customerID, fromDate, toDate)
+	if err != nil {
+		return nil, err
+	}
+	aging, err := s.ar.GetCustomerAging(ctx, customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	number := fmt.Sprintf("%s-%s", customer.Code, to.Format("20060102"))
+	content, err := s.print(ctx, models.DocumentTypeStatement, customerID, number, printedBy, func(copyNumber int) ([]byte, error) {
+		l, err := s.start(ctx, customer.Language, "Statement", number, watermarkFor(customer.Language, "", copyNumber))
+		if err != nil {
+			return nil, err
+		}
+
+		l.begin([][2]string{
+			{"Statement Date", to.Format("02 Jan 2006")},
+			{"Period", from.Format("02 Jan 2006") + " - " + to.Format("02 Jan 2006")},
+		})
+		l.parties(party{Title: "Customer", Lines: customer.lines(l.lang)})
+
+		cols := []column{
+			{Title: "Date", Width: 0.12},
+			{Title: "Type", Width: 0.10},
+			{Title: "Reference", Width: 0.16},
+			{Title: "Description", Width: 0.20, Wrap: true},
+			{Title: "Debit", Width: 0.14, Align: pdf.AlignRight},
+			{Title: "Credit", Width: 0.14, Align: pdf.AlignRight},
+			{Title: "Balance", Width: 0.14, Align: pdf.AlignRight},
+		}
+		rows := [][]string{{from.Format("02 Jan 2006"), "", "", l.t("Opening balance"), "", "", formatAmount(stmt.OpeningBalance)}}
+		for _, line := range stmt.Lines {
+			debit, credit := "", ""
+			if line.Debit != 0 {
+				debit = formatAmount(line.Debit)
+			}
+			if line.Credit != 0 {
+				credit = formatAmount(line.Credit)
+			}
+			rows = append(rows, []string{
+				formatDate(line.Date), line.Type, line.Reference, line.Description,
+				debit, credit, formatAmount(line.Balance),
+			})
+		}
+		l.table(cols, rows)
+		l.totals([][2]string{{"Closing Balance", formatAmount(stmt.ClosingBalance)}})
+
+		l.heading("Aging Summary")
+		l.table([]column{
+			{Title: "Current", Width: 1.0 / 6, Align: pdf.AlignRight},
+			{Title: "1-30 Days", Width: 1.0 / 6, Align: pdf.AlignRight},
+			{Title: "31-60 Days", Width: 1.0 / 6, Align: pdf.AlignRight},
+			{Title: "61-90 Days", Width: 1.0 / 6, Align: pdf.AlignRight},
+			{Title: "Over 90", Width: 1.0 / 6, Align: pdf.AlignRight},
+			{Title: "Total Due", Width: 1.0 / 6, Align: pdf.AlignRight},
+		}, [][]string{{
+			formatAmount(aging.Aging.Current), formatAmount(aging.Aging.Days1_30), formatAmount(aging.Aging.Days31_60),
+			formatAmount(aging.Aging.Days61_90), formatAmount(aging.Aging.Over90), formatAmount(aging.Aging.Total),
+		}})
+
+		l.paragraph("Payment Details", l.profile.BankDetails)
+
+		return l.bytes()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rendered("statement_"+number, content), nil
+}
+
+// ============================================
+// AP Payment Voucher
+// ============================================
+
+func (s *documentServiceImpl) RenderPaymentVoucher(ctx context.Context, paymentID, printedBy int) (*models.RenderedDocument, error) {
+	pay, err := s.ap.GetPayment(ctx, paymentID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotFound, err)
+	}
+	vendor, bank, err := s.vendorInfo(ctx, pay.Payment.VendorID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Resolve the invoice numbers the payment was applied to.
+	type invoiceRef struct {
+		Number string
+		Date   models.CustomDate
+		Total  float64
+	}
+	invoices := make(map[int]invoiceRef)
+	var invoiceIDs []int
+	for _, app := range pay.Applications {
+		invoiceIDs = append(invoiceIDs, app.InvoiceID)
+	}
+	if len(invoiceIDs) > 0 {
+		rows := s.db.Query(ctx, `SELECT id, invoice_number, invoice_date, total_amount FROM ap_invoices WHERE id = ANY($1)`, invoiceIDs)
+		for rows.Next() {
+			var id int
+			var ref invoiceRef
+			if err := rows.Scan(&id, &ref.Number, &ref.Date, &ref.Total); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("scanning invoice: %w", err)
+			}
+			invoices[id] = ref
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("loading invoices: %w", err)
+		}
+	}
+
+	status := ""
+	if pay.Payment.IsVoided {
+		status = "VOID"
+	}
+
+	number := pay.Payment.PaymentNumber
+	content, err := s.print(ctx, models.DocumentTypePaymentVoucher, paymentID, number, printedBy, func(copyNumber int) ([]byte, error) {
+		l, err := s.start(ctx, vendor.Language, "Payment Voucher", number, watermarkFor(vendor.Language, status, copyNumber))
+		if err != nil {
+			return nil, err
+		}
+
+		l.begin([][2]string{
+			{"Payment Date", formatDate(pay.Payment.PaymentDate)},
+			{"Method", string(pay.Payment.PaymentMethod)},
+			{"Check No.", pay.Payment.CheckNumber},
+			{"Reference", pay.Payment.ReferenceNo},
+			{"Currency", pay.Payment.Currency},
+		})
+		l.parties(party{Title: "Pay To", Lines: append(vendor.lines(l.lang), prefixed(l.t("Bank: "), bank))})
+
+		cols := []column{
+			{Title: "#", Width: 0.06, Align: pdf.AlignCenter},
+			{Title: "Invoice", Width: 0.28},
+			{Title: "Invoice Date", Width: 0.20, Align: pdf.AlignCenter},
+			{Title: "Invoice Total", Width: 0.23, Align: pdf.AlignRight},
+			{Title: "Amount Paid", Width: 0.23, Align: pdf.AlignRight},
+		}
+		var rows [][]string
+		for i, app := range pay.Applications {
+			ref := invoices[app.InvoiceID]
+			rows = append(rows, []string{
+				fmt.Sprint(i + 1), ref.Number, formatDate(ref.Date), formatAmount(ref.Total), formatAmount(app.Amount),
+			})
+		}
+		l.table(cols, rows)
+		l.totals([][2]string{{"Total Paid", formatMoney(pay.Payment.Amount, pay.Payment.Currency)}})
+
+		l.paragraph("Notes", pay.Payment.Notes)
+		l.signatures(l.t("Prepared by")+" "+pay.PreparedByName, "Approved by", "Received by")
+
+		return l.bytes()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rendered("payment_voucher_"+number, content), nil
+}
+
+// ============================================
+// Payslips
+// ============================================
+
+type employeeInfo struct {
+	Name        string
+	Department  string
+	JobTitle    string
+	BankName    string
+	BankAccount string
+}
+
+func (s *documentServiceImpl) RenderPayslips(ctx context.Context, payrollID int, employeeID *int, printedBy int) (*models.RenderedDocument, error) {
+	payroll, err := s.payroll.GetByID(ctx, payrollID)
+	if err != nil {
+		if errors.Is(err, payrollService.ErrNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	var lines []models.PayrollLine
+	for _, line := range payroll.Lines {
+		if employeeID == nil || line.EmployeeID == *employeeID {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return nil, ErrNotFound
+	}
+
+	employees := make(map[int]employeeInfo)
+	var ids []int
+	for _, line := range lines {
+		ids = append(ids, line.EmployeeID)
+	}
+	rows := s.db.Query(ctx, `
+		SELECT e.id, COALESCE(e.english_name, e.email), COALESCE(d.name, ''), COALESCE(ed.job_title, ''),
+			   COALESCE(f.bank_name, ''), COALESCE(f.bank_account_number, '')
+		FROM employees e
+		LEFT JOIN departments d ON e.department_id = d.id
+		LEFT JOIN employee_details ed ON ed.employee_id = e.id
+		LEFT JOIN employee_finances f ON f.employee_id = e.id
+		WHERE e.id = ANY($1)`, ids)
+	for rows.Next() {
+		var id int
+		var e employeeInfo
+		if err := rows.Scan(&id, &e.Name, &e.Department, &e.JobTitle, &e.BankName, &e.BankAccount); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("scanning employee: %w", err)
+		}
+		employees[id] = e
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("loading employees: %w", err)
+	}
+
+	p := payroll.Payroll
+	number := "PAY-" + p.PayrollPeriod
+	if employeeID != nil {
+		number = fmt.Sprintf("%s-%d", number, *employeeID)
+	}
+
+	lang := i18n.FromContext(ctx)
+
+	// A single payslip is logged against its payroll line so each employee's
+	// copy is tracked separately.
+	logID := payrollID
+	docType := models.DocumentTypePayslip
+	if employeeID != nil {
+		logID = lines[0].ID
+	}
+
+	content, err := s.print(ctx, docType, logID, number, printedBy, func(copyNumber int) ([]byte, error) {
+		status := ""
+		if p.Status == models.PayrollStatusDraft || p.Status == models.PayrollStatusPending {
+			status = "DRAFT"
+		}
+
+		var l *layout
+		for i, line := range lines {
+			e := employees[line.EmployeeID]
+			slip := fmt.Sprintf("%s-%d", p.PayrollPeriod, line.EmployeeID)
+			meta := [][2]string{
+				{"Pay Period", p.PayrollPeriod},
+				{"Pay Date", formatDate(p.PayDate)},
+			}
+			if i == 0 {
+				var err error
+				l, err = s.start(ctx, lang, "Payslip", slip, watermarkFor(lang, status, copyNumber))
+				if err != nil {
+					return nil, err
+				}
+				l.begin(meta)
+			} else {
+				// Every employee starts on a fresh page with their own header.
+				l.number = slip
+				l.meta = meta
+				l.newPage(true)
+			}
+
+			l.parties(
+				party{Title: "Employee", Lines: []string{e.Name, fmt.Sprintf(i18n.Label(lang, "Employee No. %d"), line.EmployeeID), e.JobTitle, e.Department}},
+				party{Title: "Payment", Lines: []string{e.BankName, e.BankAccount}},
+			)
+
+			earnings := [][]string{
+				{"Base salary", formatAmount(line.BaseSalary)},
+				{"Allowances", formatAmount(line.Allowances)},
+			}
+			if line.Bonuses != 0 {
+				earnings = append(earnings, []string{"Bonuses", formatAmount(line.Bonuses)})
+			}
+			if line.OvertimePay != 0 {
+				earnings = append(earnings, []string{fmt.Sprintf(l.t("Overtime (%s h)"), formatQty(line.OvertimeHours)), formatAmount(line.OvertimePay)})
+			}
+			l.heading("Earnings")
+			l.table([]column{{Title: "Description", Width: 0.7}, {Title: "Amount", Width: 0.3, Align: pdf.AlignRight}}, earnings)
+			l.totals([][2]string{{"Gross Pay", formatAmount(line.GrossPay)}})
+
+			deductions := [][]string{
+				{"Income tax", formatAmount(line.TaxDeduction)},
+				{"Social insurance", formatAmount(line.InsuranceDeduction)},
+			}
+			if line.OtherDeductions != 0 {
+				deductions = append(deductions, []string{"Other deductions", formatAmount(line.OtherDeductions)})
+			}
+			l.heading("Deductions")
+			l.table([]column{{Title: "Description", Width: 0.7}, {Title: "Amount", Width: 0.3, Align: pdf.AlignRight}}, deductions)
+			l.totals([][2]string{
+				{"Total Deductions", formatAmount(line.TaxDeduction + line.InsuranceDeduction + line.OtherDeductions)},
+				{"Net Pay", formatAmount(line.NetPay)},
+			})
+
+			l.paragraph("Notes", line.Notes)
+			l.signatures("Employer", "Employee")
+		}
+
+		return l.bytes()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rendered("payslip_"+number, content), nil
+}
+
+// ============================================
+// Tabular Reports
+// ============================================
+
+// RenderReport prints a generic report table on the company letterhead.
+// Reports are not numbered documents, so nothing is written to the print log.
+func (s *documentServiceImpl) RenderReport(ctx context.Context, report *models.ReportTable) (*models.RenderedDocument, error) {
+	// Report tables are assembled in English by the report service.
+	l, err := s.start(ctx, i18n.English, report.Title, "", "")
+	if err != nil {
+		return nil, err
+	}
+
+	l.begin(report.Meta)
+
+	cols := make([]column, len(report.Columns))
+	for i, c := range report.Columns {
+		cols[i] = column{Title: c.Title, Width: c.Width, Wrap: !c.Numeric}
+		if c.Numeric {
+			cols[i].Align = pdf.AlignRight
+		}
+	}
+	rows := make([][]string, len(report.Rows))
+	for i, row := range report.Rows {
+		rows[i] = make([]string, len(row))
+		for j, cell := range row {
+			if j < len(report.Columns) && report.Columns[j].Numeric {
+				cell = groupThousands(cell)
+			}
+			rows[i][j] = cell
+		}
+	}
+	l.table(cols, rows)
+
+	if len(report.Totals) > 0 {
+		totals := make([][2]string, len(report.Totals))
+		for i, kv := range report.Totals {
+			totals[i] = [2]string{kv[0], groupThousands(kv[1])}
+		}
+		l.totals(totals)
+	}
+
+	content, err := l.bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.ToLower(strings.ReplaceAll(report.Title, " ", "_"))
+	return rendered(name+"_"+time.Now().Format("20060102"), content), nil
+}
+
+// groupThousands adds separators to a plain number such as "1234567.50".
+// Anything that is not a number is returned unchanged.
+func groupThousands(s string) string {
+	if _, err := strconv.ParseFloat(s, 64); err != nil || strings.ContainsAny(s, "eE") {
+		return s
+	}
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	intPart, frac := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, frac = s[:i], s[i:]
+	}
+
+	var b strings.Builder
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	return sign + b.String() + frac
+}
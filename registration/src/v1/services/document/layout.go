@@ -0,0 +1,510 @@
+package document
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/i18n"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/pdf"
+)
+
+// ============================================
+// Page Geometry
+// ============================================
+
+const (
+	marginX      = 40.0
+	marginTop    = 40.0
+	marginBottom = 60.0
+	rowHeight    = 16.0
+)
+
+// column describes one column of a line-item table.
+type column struct {
+	Title string
+	Width float64 // fraction of the printable width
+	Align pdf.Align
+	Wrap  bool // wrap long text instead of truncating it
+}
+
+// party is an address block such as "Bill To" or "Vendor".
+type party struct {
+	Title string
+	Lines []string
+}
+
+// layout draws the company-branded frame shared by every document: header
+// with logo, title block, line-item tables with page breaks and the footer.
+// Titles and labels are passed in English and printed in the layout's
+// language.
+type layout struct {
+	d         *pdf.Document
+	profile   *models.CompanyProfile
+	logo      *pdf.Image
+	color     [3]int
+	lang      i18n.Lang
+	title     string
+	number    string
+	watermark string
+	meta      [][2]string
+	y         float64
+}
+
+func newLayout(d *pdf.Document, profile *models.CompanyProfile, logo []byte, lang i18n.Lang, title, number, watermark string) *layout {
+	title = i18n.Label(lang, title)
+	l := &layout{
+		d:         d,
+		profile:   profile,
+		color:     parseColor(profile.PrimaryColor),
+		lang:      lang,
+		title:     title,
+		number:    number,
+		watermark: watermark,
+	}
+	d.Title = title + " " + number
+	d.Author = profile.CompanyName
+	d.Subject = title
+
+	if len(logo) > 0 {
+		// A broken logo should not prevent printing.
+		if img, err := d.LoadImage(logo); err == nil {
+			l.logo = img
+		}
+	}
+
+	d.SetFooter(l.footer)
+	return l
+}
+
+func (l *layout) contentWidth() float64 {
+	return l.d.Width() - 2*marginX
+}
+
+// t translates a printed label into the document language.
+func (l *layout) t(label string) string {
+	return i18n.Label(l.lang, label)
+}
+
+// ============================================
+// Header & Footer
+// ============================================
+
+// begin starts the first page with the full header and the meta block
+// (document date, terms, ...) printed under the title.
+func (l *layout) begin(meta [][2]string) {
+	l.meta = meta
+	l.newPage(true)
+}
+
+func (l *layout) newPage(first bool) {
+	d := l.d
+	d.AddPage()
+	if l.watermark != "" {
+		d.Watermark(l.watermark)
+	}
+
+	right := d.Width() - marginX
+	y := marginTop
+
+	if !first {
+		d.SetFont(pdf.Bold, 10)
+		d.SetTextColor(l.color[0], l.color[1], l.color[2])
+		d.Text(marginX, y+10, l.profile.CompanyName)
+		d.TextIn(right-250, y+10, 250, l.title+"  "+l.number, pdf.AlignRight)
+		d.SetTextColor(0, 0, 0)
+		d.SetStrokeColor(l.color[0], l.color[1], l.color[2])
+		d.Line(marginX, y+18, right, y+18)
+		d.SetStrokeColor(0, 0, 0)
+		l.y = y + 32
+		return
+	}
+
+	// Company block on the left, logo first.
+	textX := marginX
+	if l.logo != nil {
+		w, _ := d.FitImage(l.logo, marginX, y, 110, 55)
+		textX += w + 12
+	}
+	d.SetFont(pdf.Bold, 13)
+	d.SetTextColor(l.color[0], l.color[1], l.color[2])
+	d.TextIn(textX, y+12, 250, l.profile.CompanyName, pdf.AlignLeft)
+	d.SetTextColor(60, 60, 60)
+	d.SetFont(pdf.Regular, 8)
+	cy := y + 24
+	var info []string
+	if l.profile.LegalName != "" {
+		info = append(info, l.profile.LegalName)
+	}
+	info = append(info, d.Wrap(l.profile.Address, 250)...)
+	if contact := joinNonEmpty("  ", prefixed(l.t("Tel "), l.profile.Phone), l.profile.Email); contact != "" {
+		info = append(info, contact)
+	}
+	if l.profile.Website != "" {
+		info = append(info, l.profile.Website)
+	}
+	if l.profile.TaxID != "" {
+		info = append(info, l.t("Tax ID: ")+l.profile.TaxID)
+	}
+	for _, line := range info {
+		if line == "" {
+			continue
+		}
+		d.Text(textX, cy, line)
+		cy += 10
+	}
+
+	// Title block on the right with a scannable document number.
+	d.SetFont(pdf.Bold, 18)
+	d.SetTextColor(l.color[0], l.color[1], l.color[2])
+	d.TextIn(right-200, y+14, 200, strings.ToUpper(l.title), pdf.AlignRight)
+	d.SetTextColor(0, 0, 0)
+	d.SetFont(pdf.Bold, 10)
+	d.TextIn(right-200, y+30, 200, l.number, pdf.AlignRight)
+	by := y + 36
+	if l.number != "" {
+		if err := d.Barcode(right-170, by, 170, 26, l.number); err == nil {
+			by += 32
+		}
+	}
+
+	d.SetFont(pdf.Regular, 8.5)
+	for _, kv := range l.meta {
+		if kv[1] == "" {
+			continue
+		}
+		d.SetTextColor(100, 100, 100)
+		d.TextIn(right-200, by+8, 95, l.t(kv[0]), pdf.AlignRight)
+		d.SetTextColor(0, 0, 0)
+		d.TextIn(right-100, by+8, 100, kv[1], pdf.AlignRight)
+		by += 11
+	}
+
+	l.y = math.Max(cy, by) + 10
+	d.SetStrokeColor(l.color[0], l.color[1], l.color[2])
+	d.SetLineWidth(1.2)
+	d.Line(marginX, l.y, right, l.y)
+	d.SetLineWidth(0.5)
+	d.SetStrokeColor(0, 0, 0)
+	l.y += 14
+}
+
+func (l *layout) footer(d *pdf.Document, pageNum, pageCount int) {
+	y := d.Height() - marginBottom + 18
+	right := d.Width() - marginX
+
+	d.SetStrokeColor(200, 200, 200)
+	d.SetLineWidth(0.5)
+	d.Line(marginX, y, right, y)
+	d.SetStrokeColor(0, 0, 0)
+
+	d.SetFont(pdf.Regular, 7.5)
+	d.SetTextColor(110, 110, 110)
+	left := joinNonEmpty(" | ", l.profile.FooterText, l.profile.BankDetails)
+	d.TextIn(marginX, y+11, l.contentWidth()-90, left, pdf.AlignLeft)
+	d.TextIn(right-90, y+11, 90, fmt.Sprintf(l.t("Page %d of %d"), pageNum, pageCount), pdf.AlignRight)
+	d.TextIn(marginX, y+21, l.contentWidth(), l.t("Printed")+" "+time.Now().Format("02 Jan 2006 15:04"), pdf.AlignLeft)
+	d.SetTextColor(0, 0, 0)
+}
+
+// ensure starts a new page when fewer than h points are left.
+func (l *layout) ensure(h float64) bool {
+	if l.y+h <= l.d.Height()-marginBottom {
+		return false
+	}
+	l.newPage(false)
+	return true
+}
+
+// ============================================
+// Blocks
+// ============================================
+
+// parties draws up to three address boxes side by side.
+func (l *layout) parties(blocks ...party) {
+	if len(blocks) == 0 {
+		return
+	}
+	d := l.d
+	gap := 12.0
+	w := (l.contentWidth() - gap*float64(len(blocks)-1)) / float64(len(blocks))
+
+	// Measure the tallest block first so the boxes line up.
+	d.SetFont(pdf.Regular, 9)
+	wrapped := make([][]string, len(blocks))
+	maxLines := 0
+	for i, b := range blocks {
+		for _, line := range b.Lines {
+			if line == "" {
+				continue
+			}
+			wrapped[i] = append(wrapped[i], d.Wrap(line, w-12)...)
+		}
+		if len(wrapped[i]) > maxLines {
+			maxLines = len(wrapped[i])
+		}
+	}
+	h := 22 + float64(maxLines)*11
+	l.ensure(h)
+
+	for i, b := range blocks {
+		x := marginX + float64(i)*(w+gap)
+		d.SetFillColor(245, 245, 245)
+		d.FillRect(x, l.y, w, h)
+		d.SetStrokeColor(210, 210, 210)
+		d.Rect(x, l.y, w, h)
+		d.SetStrokeColor(0, 0, 0)
+
+		d.SetFont(pdf.Bold, 8)
+		d.SetTextColor(l.color[0], l.color[1], l.color[2])
+		d.Text(x+6, l.y+11, strings.ToUpper(l.t(b.Title)))
+		d.SetTextColor(0, 0, 0)
+		d.SetFont(pdf.Regular, 9)
+		for j, line := range wrapped[i] {
+			if j == 0 {
+				d.SetFont(pdf.Bold, 9)
+			}
+			d.Text(x+6, l.y+23+float64(j)*11, line)
+			if j == 0 {
+				d.SetFont(pdf.Regular, 9)
+			}
+		}
+	}
+	l.y += h + 14
+}
+
+// table draws a line-item table, repeating the header on every page.
+func (l *layout) table(cols []column, rows [][]string) {
+	d := l.d
+	total := l.contentWidth()
+
+	widths := make([]float64, len(cols))
+	for i, c := range cols {
+		widths[i] = c.Width * total
+	}
+
+	drawHeader := func() {
+		d.SetFillColor(l.color[0], l.color[1], l.color[2])
+		d.FillRect(marginX, l.y, total, rowHeight+2)
+		d.SetFont(pdf.Bold, 8)
+		d.SetTextColor(255, 255, 255)
+		x := marginX
+		for i, c := range cols {
+			d.TextIn(x+3, l.y+11.5, widths[i]-6, l.t(c.Title), c.Align)
+			x += widths[i]
+		}
+		d.SetTextColor(0, 0, 0)
+		l.y += rowHeight + 2
+	}
+
+	l.ensure(rowHeight * 3)
+	drawHeader()
+
+	d.SetFont(pdf.Regular, 8.5)
+	for n, row := range rows {
+		// Wrapped cells make the row taller.
+		cells := make([][]string, len(cols))
+		lines := 1
+		for i, c := range cols {
+			text := ""
+			if i < len(row) {
+				text = row[i]
+			}
+			if c.Wrap {
+				cells[i] = d.Wrap(text, widths[i]-6)
+			} else {
+				cells[i] = []string{text}
+			}
+			if len(cells[i]) > lines {
+				lines = len(cells[i])
+			}
+		}
+		h := rowHeight + float64(lines-1)*10
+
+		if l.ensure(h) {
+			drawHeader()
+			d.SetFont(pdf.Regular, 8.5)
+		}
+
+		if n%2 == 1 {
+			d.SetFillColor(247, 247, 247)
+			d.FillRect(marginX, l.y, total, h)
+		}
+		x := marginX
+		for i, c := range cols {
+			for j, line := range cells[i] {
+				d.TextIn(x+3, l.y+11+float64(j)*10, widths[i]-6, line, c.Align)
+			}
+			x += widths[i]
+		}
+		l.y += h
+	}
+
+	d.SetStrokeColor(200, 200, 200)
+	d.Line(marginX, l.y, marginX+total, l.y)
+	d.SetStrokeColor(0, 0, 0)
+	l.y += 10
+}
+
+// totals draws label/value pairs right-aligned; the last one is emphasised.
+func (l *layout) totals(pairs [][2]string) {
+	d := l.d
+	right := d.Width() - marginX
+	l.ensure(float64(len(pairs))*14 + 10)
+
+	for i, kv := range pairs {
+		last := i == len(pairs)-1
+		if last {
+			d.SetFillColor(l.color[0], l.color[1], l.color[2])
+			d.FillRect(right-220, l.y, 220, 16)
+			d.SetTextColor(255, 255, 255)
+			d.SetFont(pdf.Bold, 9.5)
+		} else {
+			d.SetFont(pdf.Regular, 9)
+		}
+		d.TextIn(right-215, l.y+11.5, 110, l.t(kv[0]), pdf.AlignLeft)
+		d.TextIn(right-110, l.y+11.5, 105, kv[1], pdf.AlignRight)
+		d.SetTextColor(0, 0, 0)
+		l.y += 15
+	}
+	l.y += 10
+}
+
+// paragraph prints a labelled block of wrapped text.
+func (l *layout) paragraph(label, text string) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return
+	}
+	d := l.d
+	d.SetFont(pdf.Regular, 8.5)
+	lines := d.Wrap(text, l.contentWidth())
+	l.ensure(14 + float64(len(lines))*10)
+
+	d.SetFont(pdf.Bold, 8.5)
+	d.Text(marginX, l.y+8, l.t(label))
+	d.SetFont(pdf.Regular, 8.5)
+	for i, line := range lines {
+		d.Text(marginX, l.y+19+float64(i)*10, line)
+	}
+	l.y += 20 + float64(len(lines))*10
+}
+
+// signatures draws labelled signature lines across the page.
+func (l *layout) signatures(labels ...string) {
+	d := l.d
+	l.ensure(60)
+	l.y += 30
+	gap := 20.0
+	w := (l.contentWidth() - gap*float64(len(labels)-1)) / float64(len(labels))
+	d.SetFont(pdf.Regular, 8)
+	for i, label := range labels {
+		x := marginX + float64(i)*(w+gap)
+		d.Line(x, l.y, x+w, l.y)
+		d.TextIn(x, l.y+10, w, l.t(label), pdf.AlignCenter)
+	}
+	l.y += 24
+}
+
+// heading prints a small section heading.
+func (l *layout) heading(text string) {
+	d := l.d
+	l.ensure(40)
+	d.SetFont(pdf.Bold, 10)
+	d.SetTextColor(l.color[0], l.color[1], l.color[2])
+	d.Text(marginX, l.y+10, l.t(text))
+	d.SetTextColor(0, 0, 0)
+	l.y += 16
+}
+
+func (l *layout) bytes() ([]byte, error) {
+	return l.d.Bytes()
+}
+
+// ============================================
+// Formatting Helpers
+// ============================================
+
+// formatAmount formats money with thousands separators and two decimals.
+func formatAmount(v float64) string {
+	neg := v < 0
+	s := strconv.FormatFloat(math.Abs(v), 'f', 2, 64)
+	intPart, frac := s[:len(s)-3], s[len(s)-2:]
+
+	var b strings.Builder
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	out := b.String() + "." + frac
+	if neg {
+		return "-" + out
+	}
+	return out
+}
+
+// formatMoney prefixes an amount with its currency code.
+func formatMoney(v float64, currency string) string {
+	if currency == "" {
+		return formatAmount(v)
+	}
+	return currency + " " + formatAmount(v)
+}
+
+// formatQty prints quantities without trailing zeros.
+func formatQty(v float64) string {
+	return strconv.FormatFloat(math.Round(v*1000)/1000, 'f', -1, 64)
+}
+
+func formatDate(d models.CustomDate) string {
+	t := time.Time(d)
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("02 Jan 2006")
+}
+
+func formatDateTime(d models.CustomDateTime) string {
+	t := time.Time(d)
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("02 Jan 2006 15:04")
+}
+
+func parseColor(hex string) [3]int {
+	c := [3]int{31, 78, 121}
+	if len(hex) != 7 || hex[0] != '#' {
+		return c
+	}
+	for i := 0; i < 3; i++ {
+		v, err := strconv.ParseUint(hex[1+i*2:3+i*2], 16, 8)
+		if err != nil {
+			return [3]int{31, 78, 121}
+		}
+		c[i] = int(v)
+	}
+	return c
+}
+
+func joinNonEmpty(sep string, parts ...string) string {
+	var out []string
+	for _, p := range parts {
+		if p = strings.TrimSpace(p); p != "" {
+			out = append(out, p)
+		}
+	}
+	return strings.Join(out, sep)
+}
+
+func prefixed(prefix, s string) string {
+	if s == "" {
+		return ""
+	}
+	return prefix + s
+}
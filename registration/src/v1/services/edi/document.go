@@ -0,0 +1,183 @@
+package edi
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+)
+
+// ============================================
+// Documents
+// ============================================
+//
+// Both standards are parsed into the same segments and messages, and the
+// documents we read and write are kept apart from either syntax.
+
+type segment struct {
+	tag      string
+	elements [][]string // Components of each element
+}
+
+// el returns the first component of element i, counting from 1.
+func (s segment) el(i int) string {
+	return s.component(i, 0)
+}
+
+// component returns component j, counting from 0, of element i.
+func (s segment) component(i, j int) string {
+	if i < 1 || i > len(s.elements) || j >= len(s.elements[i-1]) {
+		return ""
+	}
+	return s.elements[i-1][j]
+}
+
+// message is an X12 transaction set or an EDIFACT message. err is set
+// when its trailer does not match it; the rest of the interchange can
+// still be read.
+type message struct {
+	messageType   string
+	controlNumber string
+	segments      []segment
+	err           string
+}
+
+type interchange struct {
+	standard          models.EDIStandard
+	senderQualifier   string
+	senderID          string
+	receiverQualifier string
+	receiverID        string
+	controlNumber     string
+	test              bool
+	closed            bool
+	messages          []*message
+}
+
+// parse reads an interchange in whichever standard it is written in.
+func parse(content string) (*interchange, error) {
+	content = strings.TrimSpace(strings.TrimPrefix(content, "\ufeff"))
+	switch {
+	case strings.HasPrefix(content, "ISA"):
+		return parseX12(content)
+	case strings.HasPrefix(content, "UNA"), strings.HasPrefix(content, "UNB"):
+		return parseEDIFACT(content)
+	}
+	return nil, fmt.Errorf("%w: not an X12 or EDIFACT interchange", ErrMalformed)
+}
+
+func (ic *interchange) purchaseOrder(msg *message) (*purchaseOrder, error) {
+	if ic.standard == models.EDIStandardX12 {
+		return purchaseOrderFromX12(msg)
+	}
+	return purchaseOrderFromEDIFACT(msg)
+}
+
+// isPurchaseOrder reports whether a message is an 850 or ORDERS.
+func (msg *message) isPurchaseOrder() bool {
+	return msg.messageType == "850" || msg.messageType == edifactMessageTypes[models.EDIPurchaseOrder]
+}
+
+type purchaseOrder struct {
+	number     string
+	date       time.Time
+	shipDate   time.Time
+	shipToCode string
+	shipToName string
+	notes      string
+	lines      []purchaseOrderLine
+}
+
+type purchaseOrderLine struct {
+	buyerLine    string
+	customerCode string // The buyer's part number
+	sku          string // Our part number
+	upc          string
+	description  string
+	uom          string
+	quantity     float64
+	unitPrice    float64
+}
+
+// setID records a product ID by its X12 or EDIFACT qualifier.
+func (l *purchaseOrderLine) setID(qualifier, value string) {
+	if value == "" {
+		return
+	}
+	switch qualifier {
+	case "BP", "IN":
+		l.customerCode = value
+	case "VP", "VN", "SA", "SK":
+		l.sku = value
+	case "UP", "UK", "EN", "UPC":
+		l.upc = value
+	}
+}
+
+// envelope addresses an outbound interchange.
+type envelope struct {
+	senderQualifier   string
+	senderID          string
+	receiverQualifier string
+	receiverID        string
+	interchangeNumber int
+	groupNumber       int
+	test              bool
+	at                time.Time
+}
+
+// outboundDocument is an acknowledgement, ship notice or invoice to send.
+// controlNumber is assigned as it is encoded.
+type outboundDocument struct {
+	documentType  models.EDIDocumentType
+	messageType   string
+	controlNumber string
+	number        string // Our order or invoice number
+	poNumber      string
+	date          time.Time
+	poDate        time.Time
+	shipDate      time.Time
+	shipToCode    string
+	shipToName    string
+	currency      string
+	lines         []outboundLine
+	subtotal      float64
+	tax           float64
+	freight       float64
+	total         float64
+	orderID       *int
+	invoiceID     *int
+}
+
+type outboundLine struct {
+	buyerLine    string
+	customerCode string
+	sku          string
+	description  string
+	uom          string
+	quantity     float64
+	unitPrice    float64
+	amount       float64
+}
+
+var x12MessageTypes = map[models.EDIDocumentType]string{
+	models.EDIPurchaseOrder:   "850",
+	models.EDIAcknowledgement: "855",
+	models.EDIShipNotice:      "856",
+	models.EDIInvoice:         "810",
+}
+
+func messageType(standard models.EDIStandard, documentType models.EDIDocumentType) string {
+	if standard == models.EDIStandardX12 {
+		return x12MessageTypes[documentType]
+	}
+	return edifactMessageTypes[documentType]
+}
+
+func encode(standard models.EDIStandard, env *envelope, docs []*outboundDocument) []byte {
+	if standard == models.EDIStandardX12 {
+		return encodeX12(env, docs)
+	}
+	return encodeEDIFACT(env, docs)
+}
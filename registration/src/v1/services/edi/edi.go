@@ -0,0 +1,509 @@
+package edi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrPartnerNotFound     = errors.New("EDI partner not found")
+	ErrDuplicatePartner    = errors.New("an EDI partner already exists for this customer or these interchange IDs")
+	ErrCustomerNotFound    = errors.New("customer not found")
+	ErrShipToNotFound      = errors.New("ship-to not found for the partner's customer")
+	ErrMappingNotFound     = errors.New("ship-to mapping not found")
+	ErrDuplicateMapping    = errors.New("location code is already mapped for this partner")
+	ErrInterchangeNotFound = errors.New("EDI interchange not found")
+	ErrNotReprocessable    = errors.New("only failed or partly failed inbound interchanges can be reprocessed")
+	ErrMalformed           = errors.New("malformed EDI file")
+	ErrUnknownPartner      = errors.New("no EDI partner for the interchange's sender and receiver")
+	ErrNotConfigured       = errors.New("EDI outbound directory is not configured")
+	ErrNotEDIPartner       = errors.New("customer is not an active EDI partner")
+	ErrNothingToSend       = errors.New("document has no lines to send")
+	ErrOrderNotFound       = errors.New("sales order not found")
+	ErrInvoiceNotFound     = errors.New("invoice not found")
+	ErrX12EnvelopeTooLong  = errors.New("X12 qualifiers must not be more than 2 characters and interchange IDs not more than 15")
+)
+
+// ============================================
+// Service Interface
+// ============================================
+
+type EDIService interface {
+	// Partners
+	CreatePartner(ctx context.Context, req *models.CreateEDIPartnerRequest, createdBy int) (int, error)
+	GetPartner(ctx context.Context, id int) (*models.EDIPartner, error)
+	UpdatePartner(ctx context.Context, id int, req *models.UpdateEDIPartnerRequest) error
+	ListPartners(ctx context.Context) ([]models.EDIPartner, error)
+	AddShipToMapping(ctx context.Context, partnerID int, req *models.CreateEDIShipToMappingRequest) (int, error)
+	ListShipToMappings(ctx context.Context, partnerID int) ([]models.EDIShipToMapping, error)
+	DeleteShipToMapping(ctx context.Context, id int) error
+
+	// Inbound
+	Receive(ctx context.Context, req *models.ReceiveEDIRequest) (*models.EDIInterchangeWithDetails, error)
+	Reprocess(ctx context.Context, interchangeID int) (*models.EDIInterchangeWithDetails, error)
+	Poll(ctx context.Context) (*models.EDIPollResult, error)
+
+	// Outbound
+	SendAcknowledgement(ctx context.Context, orderID int) (*models.EDIInterchangeWithDetails, error)
+	SendShipNotice(ctx context.Context, orderID int) (*models.EDIInterchangeWithDetails, error)
+	SendInvoice(ctx context.Context, invoiceID int) (*models.EDIInterchangeWithDetails, error)
+	SendPending(ctx context.Context) (int, error)
+
+	// Interchange Log
+	ListInterchanges(ctx context.Context, filters *models.EDIInterchangeFilters) ([]models.EDIInterchange, error)
+	GetInterchange(ctx context.Context, id int) (*models.EDIInterchangeWithDetails, error)
+
+	// Scheduler
+	RunScheduler(ctx context.Context, interval time.Duration)
+}
+
+// ============================================
+// Service Implementation
+// ============================================
+
+type ediServiceImpl struct {
+	db          postgres.Executor
+	inboundDir  string
+	outboundDir string
+}
+
+// New creates the EDI service. Files are read from inboundDir and written
+// to outboundDir; either may be empty, in which case files can still be
+// submitted through the API but nothing is sent.
+func New(db postgres.Executor, inboundDir, outboundDir string) EDIService {
+	return &ediServiceImpl{db: db, inboundDir: inboundDir, outboundDir: outboundDir}
+}
+
+// inTx runs fn in a transaction. When the service was built on a caller's
+// transaction, fn runs in that one.
+func (s *ediServiceImpl) inTx(ctx context.Context, fn func(tx *ediServiceImpl) error) error {
+	conn, ok := s.db.(postgres.Connection)
+	if !ok {
+		return fn(s)
+	}
+
+	tx, err := conn.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&ediServiceImpl{db: tx, inboundDir: s.inboundDir, outboundDir: s.outboundDir}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ============================================
+// Partners
+// ============================================
+
+func (s *ediServiceImpl) CreatePartner(ctx context.Context, req *models.CreateEDIPartnerRequest, createdBy int) (int, error) {
+	var exists bool
+	err := s.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM customers WHERE id = $1 AND company_id = $2)`,
+		req.CustomerID, tenant.Company(ctx)).Scan(&exists)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get customer: %w", err)
+	}
+	if !exists {
+		return 0, ErrCustomerNotFound
+	}
+	if err := s.checkDuplicatePartner(ctx, 0, req.CustomerID, req.PartnerID, req.OurID); err != nil {
+		return 0, err
+	}
+
+	orTrue := func(b *bool) bool { return b == nil || *b }
+
+	var id int
+	err = s.db.QueryRow(ctx, `
+		INSERT INTO edi_partners (
+			company_id, customer_id, name, standard, partner_qualifier, partner_id,
+			our_qualifier, our_id, warehouse_id, send_acknowledgement, send_ship_notice,
+			send_invoice, test_mode, created_by
+		) VALUES ($1, $2, $3, $4, COALESCE(NULLIF($5, ''), 'ZZ'), $6, COALESCE(NULLIF($7, ''), 'ZZ'), $8,
+			$9, $10, $11, $12, $13, NULLIF($14, 0))
+		RETURNING id`,
+		tenant.Company(ctx), req.CustomerID, req.Name, req.Standard, req.PartnerQualifier, req.PartnerID,
+		req.OurQualifier, req.OurID, req.WarehouseID, orTrue(req.SendAcknowledgement), orTrue(req.SendShipNotice),
+		orTrue(req.SendInvoice), req.TestMode, createdBy,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create EDI partner: %w", err)
+	}
+	return id, nil
+}
+
+const partnerSelect = `
+	SELECT p.id, p.customer_id, c.name, p.name, p.standard, p.partner_qualifier, p.partner_id,
+		   p.our_qualifier, p.our_id, p.warehouse_id, p.send_acknowledgement, p.send_ship_notice,
+		   p.send_invoice, p.test_mode, p.interchange_control_number, p.group_control_number,
+		   p.is_active, p.created_by, p.created_at, p.updated_at
+	FROM edi_partners p
+	JOIN customers c ON c.id = p.customer_id`
+
+func scanPartner(row pgx.Row, p *models.EDIPartner) error {
+	return row.Scan(
+		&p.ID, &p.CustomerID, &p.CustomerName, &p.Name, &p.Standard, &p.PartnerQualifier, &p.PartnerID,
+		&p.OurQualifier, &p.OurID, &p.WarehouseID, &p.SendAcknowledgement, &p.SendShipNotice,
+		&p.SendInvoice, &p.TestMode, &p.InterchangeControlNumber, &p.GroupControlNumber,
+		&p.IsActive, &p.CreatedBy, &p.CreatedAt, &p.UpdatedAt,
+	)
+}
+
+func (s *ediServiceImpl) GetPartner(ctx context.Context, id int) (*models.EDIPartner, error) {
+	var p models.EDIPartner
+	err := scanPartner(s.db.QueryRow(ctx, partnerSelect+` WHERE p.id = $1 AND p.company_id = $2`,
+		id, tenant.Company(ctx)), &p)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrPartnerNotFound
+		}
+		return nil, fmt.Errorf("failed to get EDI partner: %w", err)
+	}
+	return &p, nil
+}
+
+// customerPartner finds the active partner trading as a customer.
+func (s *ediServiceImpl) customerPartner(ctx context.Context, customerID int) (*models.EDIPartner, error) {
+	var p models.EDIPartner
+	err := scanPartner(s.db.QueryRow(ctx, partnerSelect+`
+		WHERE p.customer_id = $1 AND p.company_id = $2 AND p.is_active`,
+		customerID, tenant.Company(ctx)), &p)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrNotEDIPartner
+		}
+		return nil, fmt.Errorf("failed to get EDI partner: %w", err)
+	}
+	return &p, nil
+}
+
+func (s *ediServiceImpl) UpdatePartner(ctx context.Context, id int, req *models.UpdateEDIPartnerRequest) error {
+	current, err := s.GetPartner(ctx, id)
+	if err != nil {
+		return err
+	}
+	// The request does not carry the standard, so X12's fixed-width ISA
+	// fields are checked against the partner as it is
+	longer := func(value *string, n int) bool { return value != nil && len(*value) > n }
+	if current.Standard == models.EDIStandardX12 &&
+		(longer(req.PartnerQualifier, 2) || longer(req.OurQualifier, 2) || longer(req.PartnerID, 15) || longer(req.OurID, 15)) {
+		return ErrX12EnvelopeTooLong
+	}
+	if req.PartnerID != nil || req.OurID != nil {
+		partnerID, ourID := current.PartnerID, current.OurID
+		if req.PartnerID != nil {
+			partnerID = *req.PartnerID
+		}
+		if req.OurID != nil {
+			ourID = *req.OurID
+		}
+		if err := s.checkDuplicatePartner(ctx, id, current.CustomerID, partnerID, ourID); err != nil {
+			return err
+		}
+	}
+
+	_, err = s.db.Exec(ctx, `
+		UPDATE edi_partners SET
+			name = COALESCE($2, name),
+			partner_qualifier = COALESCE($3, partner_qualifier),
+			partner_id = COALESCE($4, partner_id),
+			our_qualifier = COALESCE($5, our_qualifier),
+			our_id = COALESCE($6, our_id),
+			warehouse_id = COALESCE($7, warehouse_id),
+			send_acknowledgement = COALESCE($8, send_acknowledgement),
+			send_ship_notice = COALESCE($9, send_ship_notice),
+			send_invoice = COALESCE($10, send_invoice),
+			test_mode = COALESCE($11, test_mode),
+			is_active = COALESCE($12, is_active),
+			updated_at = NOW()
+		WHERE id = $1`,
+		id, req.Name, req.PartnerQualifier, req.PartnerID, req.OurQualifier, req.OurID, req.WarehouseID,
+		req.SendAcknowledgement, req.SendShipNotice, req.SendInvoice, req.TestMode, req.IsActive)
+	if err != nil {
+		return fmt.Errorf("failed to update EDI partner: %w", err)
+	}
+	return nil
+}
+
+func (s *ediServiceImpl) ListPartners(ctx context.Context) ([]models.EDIPartner, error) {
+	rows := s.db.Query(ctx, partnerSelect+` WHERE p.company_id = $1 ORDER BY p.name`, tenant.Company(ctx))
+	defer rows.Close()
+
+	partners := []models.EDIPartner{}
+	for rows.Next() {
+		var p models.EDIPartner
+		if err := scanPartner(rows, &p); err != nil {
+			return nil, fmt.Errorf("failed to scan EDI partner: %w", err)
+		}
+		partners = append(partners, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list EDI partners: %w", err)
+	}
+	return partners, nil
+}
+
+// checkDuplicatePartner enforces one partner per customer, and interchange
+// IDs that route inbound files to a single partner in any company.
+func (s *ediServiceImpl) checkDuplicatePartner(ctx context.Context, exceptID, customerID int, partnerID, ourID string) error {
+	var exists bool
+	err := s.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM edi_partners
+			WHERE id <> $1 AND ((company_id = $2 AND customer_id = $3)
+				OR (UPPER(partner_id) = UPPER($4) AND UPPER(our_id) = UPPER($5)))
+		)`, exceptID, tenant.Company(ctx), customerID, partnerID, ourID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check EDI partner: %w", err)
+	}
+	if exists {
+		return ErrDuplicatePartner
+	}
+	return nil
+}
+
+// ============================================
+// Ship-To Mappings
+// ============================================
+
+func (s *ediServiceImpl) AddShipToMapping(ctx context.Context, partnerID int, req *models.CreateEDIShipToMappingRequest) (int, error) {
+	partner, err := s.GetPartner(ctx, partnerID)
+	if err != nil {
+		return 0, err
+	}
+
+	var exists bool
+	err = s.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM customer_ship_to WHERE id = $1 AND customer_id = $2)`,
+		req.ShipToID, partner.CustomerID).Scan(&exists)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get ship-to: %w", err)
+	}
+	if !exists {
+		return 0, ErrShipToNotFound
+	}
+
+	var id int
+	err = s.db.QueryRow(ctx, `
+		INSERT INTO edi_ship_to_mappings (partner_id, location_code, ship_to_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (partner_id, UPPER(location_code)) DO NOTHING
+		RETURNING id`, partnerID, req.LocationCode, req.ShipToID).Scan(&id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrDuplicateMapping
+		}
+		return 0, fmt.Errorf("failed to create ship-to mapping: %w", err)
+	}
+	return id, nil
+}
+
+func (s *ediServiceImpl) ListShipToMappings(ctx context.Context, partnerID int) ([]models.EDIShipToMapping, error) {
+	if _, err := s.GetPartner(ctx, partnerID); err != nil {
+		return nil, err
+	}
+
+	rows := s.db.Query(ctx, `
+		SELECT m.id, m.partner_id, m.location_code, m.ship_to_id, COALESCE(st.ship_to_code, ''), COALESCE(st.name, '')
+		FROM edi_ship_to_mappings m
+		JOIN customer_ship_to st ON st.id = m.ship_to_id
+		WHERE m.partner_id = $1
+		ORDER BY m.location_code`, partnerID)
+	defer rows.Close()
+
+	mappings := []models.EDIShipToMapping{}
+	for rows.Next() {
+		var m models.EDIShipToMapping
+		if err := rows.Scan(&m.ID, &m.PartnerID, &m.LocationCode, &m.ShipToID, &m.ShipToCode, &m.ShipToName); err != nil {
+			return nil, fmt.Errorf("failed to scan ship-to mapping: %w", err)
+		}
+		mappings = append(mappings, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list ship-to mappings: %w", err)
+	}
+	return mappings, nil
+}
+
+func (s *ediServiceImpl) DeleteShipToMapping(ctx context.Context, id int) error {
+	tag, err := s.db.Exec(ctx, `
+		DELETE FROM edi_ship_to_mappings m USING edi_partners p
+		WHERE m.id = $1 AND p.id = m.partner_id AND p.company_id = $2`, id, tenant.Company(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to delete ship-to mapping: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrMappingNotFound
+	}
+	return nil
+}
+
+// ============================================
+// Interchange Log
+// ============================================
+
+func (s *ediServiceImpl) ListInterchanges(ctx context.Context, filters *models.EDIInterchangeFilters) ([]models.EDIInterchange, error) {
+	whereClause := "WHERE i.company_id = $1"
+	args := []interface{}{tenant.Company(ctx)}
+	argNum := 2
+
+	if filters.PartnerID != nil {
+		whereClause += fmt.Sprintf(" AND i.partner_id = $%d", argNum)
+		args = append(args, *filters.PartnerID)
+		argNum++
+	}
+	if filters.Direction != nil {
+		whereClause += fmt.Sprintf(" AND i.direction = $%d", argNum)
+		args = append(args, *filters.Direction)
+		argNum++
+	}
+	if filters.Status != nil {
+		whereClause += fmt.Sprintf(" AND i.status = $%d", argNum)
+		args = append(args, *filters.Status)
+		argNum++
+	}
+	if filters.DateFrom != "" {
+		whereClause += fmt.Sprintf(" AND i.created_at >= $%d::date", argNum)
+		args = append(args, filters.DateFrom)
+		argNum++
+	}
+	if filters.DateTo != "" {
+		whereClause += fmt.Sprintf(" AND i.created_at < $%d::date + 1", argNum)
+		args = append(args, filters.DateTo)
+		argNum++
+	}
+
+	limit := filters.Limit
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+	args = append(args, limit)
+
+	rows := s.db.Query(ctx, interchangeSelect+" "+whereClause+
+		fmt.Sprintf(" ORDER BY i.created_at DESC, i.id DESC LIMIT $%d", argNum), args...)
+	defer rows.Close()
+
+	interchanges := []models.EDIInterchange{}
+	for rows.Next() {
+		var ic models.EDIInterchange
+		if err := scanInterchange(rows, &ic); err != nil {
+			return nil, fmt.Errorf("failed to scan EDI interchange: %w", err)
+		}
+		interchanges = append(interchanges, ic)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list EDI interchanges: %w", err)
+	}
+	return interchanges, nil
+}
+
+const interchangeSelect = `
+	SELECT i.id, i.partner_id, COALESCE(p.name, ''), i.direction, COALESCE(i.standard, ''),
+		   COALESCE(i.sender_id, ''), COALESCE(i.receiver_id, ''), COALESCE(i.control_number, ''),
+		   i.file_name, i.status, COALESCE(i.error_message, ''), i.transaction_count,
+		   i.created_at, i.processed_at
+	FROM edi_interchanges i
+	LEFT JOIN edi_partners p ON p.id = i.partner_id`
+
+func scanInterchange(row pgx.Row, ic *models.EDIInterchange) error {
+	return row.Scan(
+		&ic.ID, &ic.PartnerID, &ic.PartnerName, &ic.Direction, &ic.Standard,
+		&ic.SenderID, &ic.ReceiverID, &ic.ControlNumber,
+		&ic.FileName, &ic.Status, &ic.ErrorMessage, &ic.TransactionCount,
+		&ic.CreatedAt, &ic.ProcessedAt,
+	)
+}
+
+func (s *ediServiceImpl) GetInterchange(ctx context.Context, id int) (*models.EDIInterchangeWithDetails, error) {
+	var ic models.EDIInterchangeWithDetails
+	err := s.db.QueryRow(ctx, `SELECT content FROM edi_interchanges WHERE id = $1 AND company_id = $2`,
+		id, tenant.Company(ctx)).Scan(&ic.Content)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrInterchangeNotFound
+		}
+		return nil, fmt.Errorf("failed to get EDI interchange: %w", err)
+	}
+	if err := scanInterchange(s.db.QueryRow(ctx, interchangeSelect+` WHERE i.id = $1`, id), &ic.EDIInterchange); err != nil {
+		return nil, fmt.Errorf("failed to get EDI interchange: %w", err)
+	}
+
+	rows := s.db.Query(ctx, `
+		SELECT id, interchange_id, document_type, message_type, control_number, COALESCE(reference, ''),
+			   order_id, invoice_id, status, COALESCE(error_message, ''), created_at
+		FROM edi_transactions
+		WHERE interchange_id = $1
+		ORDER BY id`, id)
+	defer rows.Close()
+
+	ic.Transactions = []models.EDITransaction{}
+	for rows.Next() {
+		var t models.EDITransaction
+		err := rows.Scan(&t.ID, &t.InterchangeID, &t.DocumentType, &t.MessageType, &t.ControlNumber, &t.Reference,
+			&t.OrderID, &t.InvoiceID, &t.Status, &t.ErrorMessage, &t.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan EDI transaction: %w", err)
+		}
+		ic.Transactions = append(ic.Transactions, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get EDI transactions: %w", err)
+	}
+	return &ic, nil
+}
+
+// ============================================
+// Helpers
+// ============================================
+
+// parseDate reads CCYYMMDD or YYMMDD, ignoring any time that follows.
+func parseDate(s string) time.Time {
+	var t time.Time
+	var err error
+	switch {
+	case len(s) >= 8:
+		t, err = time.Parse("20060102", s[:8])
+	case len(s) == 6:
+		t, err = time.Parse("060102", s)
+	default:
+		return time.Time{}
+	}
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+func parseNumber(s string) float64 {
+	f, _ := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return f
+}
+
+// formatNumber writes a number without trailing zeros, as EDI expects.
+func formatNumber(f float64) string {
+	return strconv.FormatFloat(math.Round(f*10000)/10000, 'f', -1, 64)
+}
+
+func cents(f float64) int64 {
+	return int64(math.Round(f * 100))
+}
+
+func appendNote(notes, text string) string {
+	if text == "" {
+		return notes
+	}
+	if notes == "" {
+		return text
+	}
+	return notes + "\n" + text
+}
@@ -0,0 +1,356 @@
+package edi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+)
+
+// ============================================
+// EDIFACT
+// ============================================
+//
+// An EDIFACT interchange may open with a UNA service string giving its
+// separators; the defaults are : components, + elements, ? release and
+// ' terminators. Messages (UNH/UNT) sit directly in the interchange
+// (UNB/UNZ). We write directory D.96A with the UNOA syntax.
+
+type edifactSyntax struct {
+	component, element, release, terminator byte
+}
+
+var edifactDefault = edifactSyntax{component: ':', element: '+', release: '?', terminator: '\''}
+
+var edifactMessageTypes = map[models.EDIDocumentType]string{
+	models.EDIPurchaseOrder:   "ORDERS",
+	models.EDIAcknowledgement: "ORDRSP",
+	models.EDIShipNotice:      "DESADV",
+	models.EDIInvoice:         "INVOIC",
+}
+
+func parseEDIFACT(content string) (*interchange, error) {
+	syntax := edifactDefault
+	if strings.HasPrefix(content, "UNA") {
+		if len(content) < 9 {
+			return nil, fmt.Errorf("%w: UNA service string is too short", ErrMalformed)
+		}
+		syntax = edifactSyntax{component: content[3], element: content[4], release: content[6], terminator: content[8]}
+		content = content[9:]
+	}
+
+	ic := &interchange{standard: models.EDIStandardEDIFACT}
+	var msg *message
+	for _, seg := range syntax.split(content) {
+		switch seg.tag {
+		case "UNB":
+			ic.senderID = seg.el(2)
+			ic.senderQualifier = seg.component(2, 1)
+			ic.receiverID = seg.el(3)
+			ic.receiverQualifier = seg.component(3, 1)
+			ic.controlNumber = seg.el(5)
+			ic.test = seg.el(11) == "1"
+		case "UNH":
+			msg = &message{messageType: seg.el(2), controlNumber: seg.el(1)}
+			msg.segments = append(msg.segments, seg)
+		case "UNT":
+			if msg == nil {
+				return nil, fmt.Errorf("%w: UNT without UNH", ErrMalformed)
+			}
+			msg.segments = append(msg.segments, seg)
+			if n, _ := strconv.Atoi(seg.el(1)); n != len(msg.segments) {
+				msg.err = fmt.Sprintf("UNT counts %s segments but the message has %d", seg.el(1), len(msg.segments))
+			} else if seg.el(2) != msg.controlNumber {
+				msg.err = fmt.Sprintf("UNT reference %s does not match UNH %s", seg.el(2), msg.controlNumber)
+			}
+			ic.messages = append(ic.messages, msg)
+			msg = nil
+		case "UNZ":
+			if seg.el(2) != ic.controlNumber {
+				return nil, fmt.Errorf("%w: UNZ reference %s does not match UNB %s", ErrMalformed, seg.el(2), ic.controlNumber)
+			}
+			ic.closed = true
+		case "UNG", "UNE":
+			// Functional groups are optional and carry nothing we need
+		default:
+			if msg == nil {
+				return nil, fmt.Errorf("%w: %s segment outside a message", ErrMalformed, seg.tag)
+			}
+			msg.segments = append(msg.segments, seg)
+		}
+	}
+
+	switch {
+	case ic.senderID == "":
+		return nil, fmt.Errorf("%w: file has no UNB segment", ErrMalformed)
+	case msg != nil:
+		return nil, fmt.Errorf("%w: message %s has no UNT", ErrMalformed, msg.controlNumber)
+	case !ic.closed:
+		return nil, fmt.Errorf("%w: interchange has no UNZ", ErrMalformed)
+	}
+	return ic, nil
+}
+
+// split breaks content into segments, honouring the release character.
+func (syn edifactSyntax) split(content string) []segment {
+	var segments []segment
+	var seg segment
+	var el []string
+	var value strings.Builder
+
+	endComponent := func() {
+		el = append(el, value.String())
+		value.Reset()
+	}
+	endElement := func() {
+		endComponent()
+		if seg.tag == "" {
+			seg.tag = el[0]
+		} else {
+			seg.elements = append(seg.elements, el)
+		}
+		el = nil
+	}
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == syn.release && i+1 < len(content):
+			i++
+			value.WriteByte(content[i])
+		case c == syn.component:
+			endComponent()
+		case c == syn.element:
+			endElement()
+		case c == syn.terminator:
+			endElement()
+			segments = append(segments, seg)
+			seg = segment{}
+		case c == '\r' || c == '\n':
+			// Line breaks between segments are not data
+		default:
+			value.WriteByte(c)
+		}
+	}
+	return segments
+}
+
+// purchaseOrderFromEDIFACT reads an ORDERS message.
+func purchaseOrderFromEDIFACT(msg *message) (*purchaseOrder, error) {
+	po := &purchaseOrder{}
+	var line *purchaseOrderLine
+	for _, seg := range msg.segments {
+		switch seg.tag {
+		case "BGM":
+			po.number = seg.el(2)
+		case "DTM":
+			// 137 document date, 2 delivery requested, 10 shipment requested
+			switch seg.el(1) {
+			case "137":
+				po.date = parseDate(seg.component(1, 1))
+			case "2", "10":
+				po.shipDate = parseDate(seg.component(1, 1))
+			}
+		case "NAD":
+			if q := seg.el(1); q == "DP" || q == "ST" {
+				po.shipToCode = seg.el(2)
+				po.shipToName = seg.el(4)
+			}
+		case "FTX":
+			po.notes = appendNote(po.notes, seg.el(4))
+		case "LIN":
+			po.lines = append(po.lines, purchaseOrderLine{buyerLine: seg.el(1)})
+			line = &po.lines[len(po.lines)-1]
+			line.setID(seg.component(3, 1), seg.el(3))
+		case "PIA":
+			if line != nil {
+				for i := 2; i <= len(seg.elements); i++ {
+					line.setID(seg.component(i, 1), seg.el(i))
+				}
+			}
+		case "IMD":
+			if line != nil {
+				line.description = seg.component(3, 3)
+			}
+		case "QTY":
+			// 21 ordered quantity
+			if line != nil && seg.el(1) == "21" {
+				line.quantity = parseNumber(seg.component(1, 1))
+				line.uom = seg.component(1, 2)
+			}
+		case "PRI":
+			// AAA net price, AAB gross price
+			if line != nil && line.unitPrice == 0 {
+				line.unitPrice = parseNumber(seg.component(1, 1))
+			}
+		}
+	}
+
+	if po.number == "" {
+		return nil, fmt.Errorf("%w: ORDERS has no BGM order number", ErrMalformed)
+	}
+	if len(po.lines) == 0 {
+		return nil, fmt.Errorf("%w: ORDERS has no LIN lines", ErrMalformed)
+	}
+	return po, nil
+}
+
+// ============================================
+// EDIFACT Writer
+// ============================================
+
+type edifactWriter struct {
+	b        strings.Builder
+	segments int // In the current message
+}
+
+// segment writes a segment from elements given as their components,
+// escaping any separators in the values.
+func (w *edifactWriter) segment(tag string, elements ...[]string) {
+	for len(elements) > 0 && len(strings.Join(elements[len(elements)-1], "")) == 0 {
+		elements = elements[:len(elements)-1]
+	}
+	w.b.WriteString(tag)
+	for _, el := range elements {
+		w.b.WriteByte('+')
+		for len(el) > 0 && el[len(el)-1] == "" {
+			el = el[:len(el)-1]
+		}
+		for i, c := range el {
+			if i > 0 {
+				w.b.WriteByte(':')
+			}
+			w.b.WriteString(edifactEscape(c))
+		}
+	}
+	w.b.WriteString("'\n")
+	w.segments++
+}
+
+func edifactEscape(s string) string {
+	return strings.NewReplacer("?", "??", ":", "?:", "+", "?+", "'", "?'", "\n", " ").Replace(s)
+}
+
+// elem builds an element from its components.
+func elem(components ...string) []string {
+	return components
+}
+
+// encodeEDIFACT writes an interchange with a message per document.
+func encodeEDIFACT(env *envelope, docs []*outboundDocument) []byte {
+	w := &edifactWriter{}
+	now := env.at
+	control := strconv.Itoa(env.interchangeNumber)
+
+	w.b.WriteString("UNA:+.? '\n")
+	test := ""
+	if env.test {
+		test = "1"
+	}
+	w.segment("UNB", elem("UNOA", "3"), elem(env.senderID, env.senderQualifier), elem(env.receiverID, env.receiverQualifier),
+		elem(now.Format("060102"), now.Format("1504")), elem(control), elem(), elem(), elem(), elem(), elem(), elem(test))
+
+	for i, doc := range docs {
+		doc.controlNumber = strconv.Itoa(i + 1)
+		w.segments = 0
+		w.segment("UNH", elem(doc.controlNumber), elem(doc.messageType, "D", "96A", "UN"))
+		switch doc.documentType {
+		case models.EDIAcknowledgement:
+			w.acknowledgement(doc)
+		case models.EDIShipNotice:
+			w.shipNotice(doc)
+		case models.EDIInvoice:
+			w.invoice(doc)
+		}
+		w.segment("UNT", elem(strconv.Itoa(w.segments+1)), elem(doc.controlNumber))
+	}
+
+	w.segment("UNZ", elem(strconv.Itoa(len(docs))), elem(control))
+	return []byte(w.b.String())
+}
+
+// acknowledgement writes an ORDRSP accepting the order without change.
+func (w *edifactWriter) acknowledgement(doc *outboundDocument) {
+	w.segment("BGM", elem("231"), elem(doc.number), elem("29"))
+	w.segment("DTM", elem("137", edifactDate(doc.date), "102"))
+	w.segment("RFF", elem("ON", doc.poNumber))
+	w.shipTo(doc)
+	for i, l := range doc.lines {
+		w.line(i, l, "5")
+		w.segment("QTY", elem("113", formatNumber(l.quantity), l.uom))
+		w.segment("PRI", elem("AAA", formatNumber(l.unitPrice)))
+	}
+	w.segment("UNS", elem("S"))
+}
+
+// shipNotice writes a DESADV with one consignment.
+func (w *edifactWriter) shipNotice(doc *outboundDocument) {
+	w.segment("BGM", elem("351"), elem(doc.number), elem("9"))
+	w.segment("DTM", elem("137", edifactDate(doc.date), "102"))
+	w.segment("DTM", elem("11", edifactDate(doc.shipDate), "102"))
+	w.segment("RFF", elem("ON", doc.poNumber))
+	w.shipTo(doc)
+	w.segment("CPS", elem("1"))
+	for i, l := range doc.lines {
+		w.line(i, l, "")
+		w.segment("QTY", elem("12", formatNumber(l.quantity), l.uom))
+	}
+}
+
+// invoice writes an INVOIC with line amounts and the invoice totals.
+func (w *edifactWriter) invoice(doc *outboundDocument) {
+	w.segment("BGM", elem("380"), elem(doc.number), elem("9"))
+	w.segment("DTM", elem("137", edifactDate(doc.date), "102"))
+	w.segment("RFF", elem("ON", doc.poNumber))
+	w.shipTo(doc)
+	w.segment("CUX", elem("2", doc.currency, "4"))
+	for i, l := range doc.lines {
+		w.line(i, l, "")
+		if l.description != "" {
+			w.segment("IMD", elem("F"), elem(), elem("", "", "", l.description))
+		}
+		w.segment("QTY", elem("47", formatNumber(l.quantity), l.uom))
+		w.segment("MOA", elem("203", formatNumber(l.amount)))
+		w.segment("PRI", elem("AAA", formatNumber(l.unitPrice)))
+	}
+	w.segment("UNS", elem("S"))
+	w.segment("MOA", elem("79", formatNumber(doc.subtotal)))
+	if doc.tax != 0 {
+		w.segment("MOA", elem("124", formatNumber(doc.tax)))
+	}
+	if doc.freight != 0 {
+		w.segment("MOA", elem("64", formatNumber(doc.freight)))
+	}
+	w.segment("MOA", elem("77", formatNumber(doc.total)))
+}
+
+// line writes LIN with our SKU, PIA with the buyer's item number and the
+// buyer's line number.
+func (w *edifactWriter) line(i int, l outboundLine, action string) {
+	w.segment("LIN", elem(strconv.Itoa(i+1)), elem(action), elem(l.sku, "SA"))
+	if l.customerCode != "" {
+		w.segment("PIA", elem("5"), elem(l.customerCode, "IN"))
+	}
+	if l.buyerLine != "" {
+		w.segment("RFF", elem("LI", l.buyerLine))
+	}
+}
+
+func (w *edifactWriter) shipTo(doc *outboundDocument) {
+	if doc.shipToCode != "" || doc.shipToName != "" {
+		agency := ""
+		if doc.shipToCode != "" {
+			agency = "92"
+		}
+		w.segment("NAD", elem("DP"), elem(doc.shipToCode, "", agency), elem(), elem(doc.shipToName))
+	}
+}
+
+func edifactDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("20060102")
+}
@@ -0,0 +1,627 @@
+package edi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	customerItemService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/customer_item"
+	salesOrderService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/sales_order"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrDuplicatePO     = errors.New("purchase order has already been received from this partner")
+	ErrUnknownLocation = errors.New("ship-to location code is not mapped")
+	ErrNoWarehouse     = errors.New("no warehouse set for the partner, ship-to or customer")
+	ErrUnknownItem     = errors.New("items could not be matched to products")
+	ErrNoQuantity      = errors.New("line has no quantity")
+)
+
+// ============================================
+// Inbound
+// ============================================
+//
+// Each file is logged as an interchange before anything else is done with
+// it, so a file that fails can be read and reprocessed later. Files are
+// routed to a partner by their sender and receiver IDs, which are unique
+// across companies; the partner's company is the one the orders are
+// created in. Every 850 or ORDERS becomes a draft sales order on its own
+// transaction, so one bad purchase order does not hold up the rest.
+
+// fileSettleTime is how long a file must go unmodified before it is
+// picked up, so files still being written are left alone.
+const fileSettleTime = 5 * time.Second
+
+// receipt is the outcome of processing an inbound interchange.
+type receipt struct {
+	interchangeID int
+	status        models.EDIInterchangeStatus
+	orders        int
+	sent          int
+}
+
+// Receive processes a file submitted through the API. Files from partners
+// of other companies are refused as if the partner were unknown.
+func (s *ediServiceImpl) Receive(ctx context.Context, req *models.ReceiveEDIRequest) (*models.EDIInterchangeWithDetails, error) {
+	fileName := req.FileName
+	if fileName == "" {
+		fileName = "upload_" + time.Now().Format("20060102150405") + ".edi"
+	}
+	r, err := s.receive(ctx, fileName, req.Content, false)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetInterchange(ctx, r.interchangeID)
+}
+
+// receive logs and processes one file. anyCompany lets the file's partner
+// belong to any company, as it does for files from the drop directory.
+func (s *ediServiceImpl) receive(ctx context.Context, fileName, content string, anyCompany bool) (*receipt, error) {
+	ic, err := parse(content)
+	if err != nil {
+		return s.reject(ctx, fileName, content, nil, err)
+	}
+
+	companyID, partner, err := s.partnerFor(ctx, ic)
+	if err == nil && !anyCompany && companyID != tenant.Company(ctx) {
+		err = ErrUnknownPartner
+	}
+	if err != nil {
+		if err == ErrUnknownPartner {
+			return s.reject(ctx, fileName, content, ic, fmt.Errorf("%w: %s to %s", err, ic.senderID, ic.receiverID))
+		}
+		return nil, err
+	}
+	ctx = tenant.WithCompany(ctx, companyID)
+
+	// A resent interchange we have already processed is logged but not
+	// processed again
+	var duplicate bool
+	err = s.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM edi_interchanges
+			WHERE partner_id = $1 AND direction = 'IN' AND control_number = $2 AND status = 'PROCESSED'
+		)`, partner.ID, ic.controlNumber).Scan(&duplicate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check EDI interchange: %w", err)
+	}
+	status := models.EDIInterchangeReceived
+	if duplicate {
+		status = models.EDIInterchangeDuplicate
+	}
+
+	id, err := s.insertInterchange(ctx, &partner.ID, models.EDIInbound, ic, fileName, content, status)
+	if err != nil {
+		return nil, err
+	}
+	if duplicate {
+		_, err := s.db.Exec(ctx, `
+			UPDATE edi_interchanges SET error_message = $2, transaction_count = $3, processed_at = NOW()
+			WHERE id = $1`, id, "interchange "+ic.controlNumber+" has already been processed", len(ic.messages))
+		if err != nil {
+			return nil, fmt.Errorf("failed to update EDI interchange: %w", err)
+		}
+		return &receipt{interchangeID: id, status: status}, nil
+	}
+	return s.process(ctx, id, partner, ic)
+}
+
+// reject logs a file that cannot be processed at all.
+func (s *ediServiceImpl) reject(ctx context.Context, fileName, content string, ic *interchange, reason error) (*receipt, error) {
+	id, err := s.insertInterchange(ctx, nil, models.EDIInbound, ic, fileName, content, models.EDIInterchangeFailed)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.db.Exec(ctx, `UPDATE edi_interchanges SET error_message = $2, processed_at = NOW() WHERE id = $1`,
+		id, reason.Error())
+	if err != nil {
+		return nil, fmt.Errorf("failed to update EDI interchange: %w", err)
+	}
+	return &receipt{interchangeID: id, status: models.EDIInterchangeFailed}, nil
+}
+
+// partnerFor finds the active partner an interchange is from and the
+// company it belongs to.
+func (s *ediServiceImpl) partnerFor(ctx context.Context, ic *interchange) (int, *models.EDIPartner, error) {
+	var id, companyID int
+	err := s.db.QueryRow(ctx, `
+		SELECT id, company_id FROM edi_partners
+		WHERE UPPER(partner_id) = UPPER($1) AND UPPER(our_id) = UPPER($2) AND is_active`,
+		ic.senderID, ic.receiverID).Scan(&id, &companyID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, nil, ErrUnknownPartner
+		}
+		return 0, nil, fmt.Errorf("failed to get EDI partner: %w", err)
+	}
+	partner, err := s.GetPartner(tenant.WithCompany(ctx, companyID), id)
+	if err != nil {
+		return 0, nil, err
+	}
+	return companyID, partner, nil
+}
+
+func (s *ediServiceImpl) insertInterchange(ctx context.Context, partnerID *int, direction models.EDIDirection, ic *interchange,
+	fileName, content string, status models.EDIInterchangeStatus) (int, error) {
+	if ic == nil {
+		ic = &interchange{}
+	}
+	var id int
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO edi_interchanges (
+			company_id, partner_id, direction, standard, sender_id, receiver_id, control_number,
+			file_name, status, content
+		) VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), $8, $9, $10)
+		RETURNING id`,
+		tenant.Company(ctx), partnerID, direction, string(ic.standard), ic.senderID, ic.receiverID, ic.controlNumber,
+		fileName, status, content,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to log EDI interchange: %w", err)
+	}
+	return id, nil
+}
+
+// process turns an interchange's purchase orders into sales orders.
+// Messages already processed, when an interchange is reprocessed, are
+// skipped.
+func (s *ediServiceImpl) process(ctx context.Context, interchangeID int, partner *models.EDIPartner, ic *interchange) (*receipt, error) {
+	r := &receipt{interchangeID: interchangeID}
+	var orderIDs []int
+	var unsupported []string
+	for _, msg := range ic.messages {
+		if !msg.isPurchaseOrder() {
+			unsupported = append(unsupported, msg.messageType)
+			continue
+		}
+
+		var done bool
+		err := s.db.QueryRow(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM edi_transactions
+				WHERE interchange_id = $1 AND control_number = $2 AND status = 'PROCESSED'
+			)`, interchangeID, msg.controlNumber).Scan(&done)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check EDI transaction: %w", err)
+		}
+		if done {
+			continue
+		}
+
+		orderID, reference, err := s.receiveOrder(ctx, interchangeID, partner, ic, msg)
+		if err != nil {
+			_, err := s.db.Exec(ctx, `
+				INSERT INTO edi_transactions (
+					interchange_id, document_type, message_type, control_number, reference, status, error_message
+				) VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7)`,
+				interchangeID, models.EDIPurchaseOrder, msg.messageType, msg.controlNumber, reference,
+				models.EDITransactionFailed, err.Error())
+			if err != nil {
+				return nil, fmt.Errorf("failed to log EDI transaction: %w", err)
+			}
+			continue
+		}
+		orderIDs = append(orderIDs, orderID)
+	}
+
+	var processed, failed int
+	err := s.db.QueryRow(ctx, `
+		SELECT COUNT(*) FILTER (WHERE status = 'PROCESSED'), COUNT(*) FILTER (WHERE status = 'FAILED')
+		FROM edi_transactions WHERE interchange_id = $1`, interchangeID).Scan(&processed, &failed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count EDI transactions: %w", err)
+	}
+
+	var problems []string
+	switch {
+	case processed+failed == 0:
+		r.status = models.EDIInterchangeFailed
+		problems = append(problems, "interchange has no purchase orders")
+	case failed == 0:
+		r.status = models.EDIInterchangeProcessed
+	case processed == 0:
+		r.status = models.EDIInterchangeFailed
+		problems = append(problems, fmt.Sprintf("%d of %d purchase orders failed", failed, processed+failed))
+	default:
+		r.status = models.EDIInterchangePartial
+		problems = append(problems, fmt.Sprintf("%d of %d purchase orders failed", failed, processed+failed))
+	}
+	if len(unsupported) > 0 {
+		problems = append(problems, "unsupported messages skipped: "+strings.Join(unsupported, ", "))
+	}
+
+	_, err = s.db.Exec(ctx, `
+		UPDATE edi_interchanges
+		SET partner_id = $2, status = $3, error_message = NULLIF($4, ''), transaction_count = $5, processed_at = NOW()
+		WHERE id = $1`, interchangeID, partner.ID, r.status, strings.Join(problems, "; "), len(ic.messages))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update EDI interchange: %w", err)
+	}
+
+	// Orders are acknowledged as soon as they are created; any that cannot
+	// be sent now are picked up by SendPending
+	r.orders = len(orderIDs)
+	if partner.SendAcknowledgement && s.outboundDir != "" {
+		for _, orderID := range orderIDs {
+			if _, err := s.SendAcknowledgement(ctx, orderID); err != nil {
+				log.Printf("⚠ EDI acknowledgement for order %d: %v", orderID, err)
+				continue
+			}
+			r.sent++
+		}
+	}
+	return r, nil
+}
+
+// receiveOrder creates the sales order for one purchase order and logs
+// its transaction. The purchase order number is returned even when the
+// order fails so the failure can be traced.
+func (s *ediServiceImpl) receiveOrder(ctx context.Context, interchangeID int, partner *models.EDIPartner, ic *interchange, msg *message) (int, string, error) {
+	if msg.err != "" {
+		return 0, "", fmt.Errorf("%w: %s", ErrMalformed, msg.err)
+	}
+	po, err := ic.purchaseOrder(msg)
+	if err != nil {
+		return 0, "", err
+	}
+
+	var duplicate bool
+	err = s.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM edi_transactions t
+			JOIN edi_interchanges i ON i.id = t.interchange_id
+			WHERE i.partner_id = $1 AND i.direction = 'IN' AND t.document_type = 'PURCHASE_ORDER'
+			  AND t.status = 'PROCESSED' AND t.reference = $2
+		)`, partner.ID, po.number).Scan(&duplicate)
+	if err != nil {
+		return 0, po.number, fmt.Errorf("failed to check purchase order: %w", err)
+	}
+	if duplicate {
+		return 0, po.number, ErrDuplicatePO
+	}
+
+	shipToID, warehouseID, err := s.shipTo(ctx, partner, po)
+	if err != nil {
+		return 0, po.number, err
+	}
+	lines, err := s.orderLines(ctx, partner, po)
+	if err != nil {
+		return 0, po.number, err
+	}
+
+	req := &models.CreateSalesOrderRequest{
+		CustomerID:  partner.CustomerID,
+		ShipToID:    shipToID,
+		WarehouseID: warehouseID,
+		Notes:       po.notes,
+		PONumber:    po.number,
+		Lines:       lines,
+	}
+	if !po.shipDate.IsZero() {
+		req.RequestedShipDate = po.shipDate.Format("2006-01-02")
+	}
+	createdBy := 0
+	if partner.CreatedBy != nil {
+		createdBy = *partner.CreatedBy
+	}
+
+	var orderID int
+	err = s.inTx(ctx, func(tx *ediServiceImpl) error {
+		var err error
+		orderID, err = salesOrderService.New(tx.db).Create(ctx, req, createdBy)
+		if err != nil {
+			return err
+		}
+
+		// Keep the buyer's line numbers to echo back on what we send
+		for i, l := range po.lines {
+			if l.buyerLine == "" {
+				continue
+			}
+			_, err := tx.db.Exec(ctx, `
+				INSERT INTO edi_order_lines (order_id, line_number, buyer_line_number) VALUES ($1, $2, $3)`,
+				orderID, i+1, l.buyerLine)
+			if err != nil {
+				return fmt.Errorf("failed to save buyer line numbers: %w", err)
+			}
+		}
+
+		_, err = tx.db.Exec(ctx, `
+			INSERT INTO edi_transactions (
+				interchange_id, document_type, message_type, control_number, reference, order_id, status
+			) VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+			interchangeID, models.EDIPurchaseOrder, msg.messageType, msg.controlNumber, po.number, orderID,
+			models.EDITransactionProcessed)
+		if err != nil {
+			return fmt.Errorf("failed to log EDI transaction: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, po.number, err
+	}
+	return orderID, po.number, nil
+}
+
+// shipTo resolves the purchase order's ship-to location, through the
+// partner's mappings first and then the customer's own ship-to codes, and
+// picks the warehouse to ship from.
+func (s *ediServiceImpl) shipTo(ctx context.Context, partner *models.EDIPartner, po *purchaseOrder) (*int, int, error) {
+	var shipToID, shipToWarehouse *int
+	if po.shipToCode != "" {
+		var id int
+		err := s.db.QueryRow(ctx, `
+			SELECT st.id, st.warehouse_id
+			FROM edi_ship_to_mappings m
+			JOIN customer_ship_to st ON st.id = m.ship_to_id
+			WHERE m.partner_id = $1 AND UPPER(m.location_code) = UPPER($2)`,
+			partner.ID, po.shipToCode).Scan(&id, &shipToWarehouse)
+		if err == pgx.ErrNoRows {
+			err = s.db.QueryRow(ctx, `
+				SELECT id, warehouse_id FROM customer_ship_to
+				WHERE customer_id = $1 AND UPPER(ship_to_code) = UPPER($2) AND COALESCE(is_active, true)`,
+				partner.CustomerID, po.shipToCode).Scan(&id, &shipToWarehouse)
+		}
+		if err != nil {
+			if err == pgx.ErrNoRows {
+				return nil, 0, fmt.Errorf("%w: %s", ErrUnknownLocation, po.shipToCode)
+			}
+			return nil, 0, fmt.Errorf("failed to get ship-to: %w", err)
+		}
+		shipToID = &id
+	}
+
+	switch {
+	case partner.WarehouseID != nil:
+		return shipToID, *partner.WarehouseID, nil
+	case shipToWarehouse != nil:
+		return shipToID, *shipToWarehouse, nil
+	}
+	var warehouseID *int
+	err := s.db.QueryRow(ctx, `SELECT default_warehouse_id FROM customers WHERE id = $1`, partner.CustomerID).Scan(&warehouseID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get customer: %w", err)
+	}
+	if warehouseID == nil {
+		return nil, 0, ErrNoWarehouse
+	}
+	return shipToID, *warehouseID, nil
+}
+
+// orderLines matches the purchase order's lines to products. The buyer's
+// part number is used when it is mapped, and the sales order converts the
+// quantity from the buyer's unit. Unmapped part numbers are queued for the
+// sales desk and the line falls back to our SKU or UPC, taken in the
+// product's base unit. If any line matches nothing the order is refused.
+func (s *ediServiceImpl) orderLines(ctx context.Context, partner *models.EDIPartner, po *purchaseOrder) ([]models.CreateSalesOrderLineRequest, error) {
+	items := customerItemService.New(s.db)
+
+	var lines []models.CreateSalesOrderLineRequest
+	var unknown []string
+	for _, l := range po.lines {
+		if l.quantity <= 0 {
+			return nil, fmt.Errorf("%w: %s", ErrNoQuantity, l.buyerLine)
+		}
+		line := models.CreateSalesOrderLineRequest{Quantity: l.quantity, UnitPrice: l.unitPrice}
+
+		if l.customerCode != "" {
+			_, err := items.Resolve(ctx, partner.CustomerID, l.customerCode, l.uom)
+			if err == nil {
+				line.CustomerItemCode = l.customerCode
+				line.UnitOfMeasure = l.uom
+				lines = append(lines, line)
+				continue
+			}
+			if !errors.Is(err, customerItemService.ErrUnknownCode) {
+				return nil, err
+			}
+			err = items.QueueUnknown(ctx, &models.QueueCustomerCodeRequest{
+				CustomerID:   partner.CustomerID,
+				CustomerCode: l.customerCode,
+				CustomerUOM:  l.uom,
+				Description:  l.description,
+				Source:       models.CustomerCodeFromEDI,
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var productID int
+		var unit string
+		err := s.db.QueryRow(ctx, `
+			SELECT id, COALESCE(base_unit, 'EA') FROM products
+			WHERE is_active AND ((UPPER(sku) = UPPER($1) AND $1 <> '') OR (upc = $2 AND $2 <> '') OR (barcode = $2 AND $2 <> ''))
+			ORDER BY UPPER(sku) = UPPER($1) DESC
+			LIMIT 1`, l.sku, l.upc).Scan(&productID, &unit)
+		if err == pgx.ErrNoRows {
+			unknown = append(unknown, l.itemName())
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get product: %w", err)
+		}
+		line.ProductID = productID
+		line.UnitOfMeasure = unit
+		lines = append(lines, line)
+	}
+
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownItem, strings.Join(unknown, ", "))
+	}
+	return lines, nil
+}
+
+// itemName names a line by whichever ID the buyer sent.
+func (l purchaseOrderLine) itemName() string {
+	for _, id := range []string{l.customerCode, l.sku, l.upc} {
+		if id != "" {
+			return id
+		}
+	}
+	return "line " + l.buyerLine
+}
+
+// ============================================
+// Reprocessing
+// ============================================
+
+// Reprocess runs a failed or partly failed inbound interchange again, for
+// instance once its item codes or ship-to locations have been mapped.
+// Purchase orders that already became sales orders are left alone.
+func (s *ediServiceImpl) Reprocess(ctx context.Context, interchangeID int) (*models.EDIInterchangeWithDetails, error) {
+	var direction models.EDIDirection
+	var status models.EDIInterchangeStatus
+	var content, fileName string
+	err := s.db.QueryRow(ctx, `
+		SELECT direction, status, content, file_name FROM edi_interchanges WHERE id = $1 AND company_id = $2`,
+		interchangeID, tenant.Company(ctx)).Scan(&direction, &status, &content, &fileName)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrInterchangeNotFound
+		}
+		return nil, fmt.Errorf("failed to get EDI interchange: %w", err)
+	}
+	if direction != models.EDIInbound ||
+		(status != models.EDIInterchangeFailed && status != models.EDIInterchangePartial) {
+		return nil, ErrNotReprocessable
+	}
+
+	ic, err := parse(content)
+	var partner *models.EDIPartner
+	if err == nil {
+		var companyID int
+		companyID, partner, err = s.partnerFor(ctx, ic)
+		if err == nil && companyID != tenant.Company(ctx) {
+			err = ErrUnknownPartner
+		}
+		if err != nil && err != ErrUnknownPartner {
+			return nil, err
+		}
+	}
+	if err != nil {
+		_, err := s.db.Exec(ctx, `UPDATE edi_interchanges SET error_message = $2, processed_at = NOW() WHERE id = $1`,
+			interchangeID, err.Error())
+		if err != nil {
+			return nil, fmt.Errorf("failed to update EDI interchange: %w", err)
+		}
+		return s.GetInterchange(ctx, interchangeID)
+	}
+
+	_, err = s.db.Exec(ctx, `DELETE FROM edi_transactions WHERE interchange_id = $1 AND status = 'FAILED'`, interchangeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clear EDI transactions: %w", err)
+	}
+	_, err = s.db.Exec(ctx, `
+		UPDATE edi_interchanges
+		SET standard = $2, sender_id = $3, receiver_id = $4, control_number = $5
+		WHERE id = $1`, interchangeID, ic.standard, ic.senderID, ic.receiverID, ic.controlNumber)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update EDI interchange: %w", err)
+	}
+	if _, err := s.process(ctx, interchangeID, partner, ic); err != nil {
+		return nil, err
+	}
+	return s.GetInterchange(ctx, interchangeID)
+}
+
+// ============================================
+// Drop Directory
+// ============================================
+
+// Poll processes the files waiting in the inbound directory, then sends
+// whatever acknowledgements, ship notices and invoices are due. Each file
+// is moved to processed/ or failed/ once it has been logged.
+func (s *ediServiceImpl) Poll(ctx context.Context) (*models.EDIPollResult, error) {
+	result := &models.EDIPollResult{}
+
+	if s.inboundDir != "" {
+		entries, err := os.ReadDir(s.inboundDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read EDI inbound directory: %w", err)
+		}
+		for _, entry := range entries {
+			if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil || time.Since(info.ModTime()) < fileSettleTime {
+				continue
+			}
+
+			path := filepath.Join(s.inboundDir, entry.Name())
+			content, err := os.ReadFile(path)
+			if err != nil {
+				log.Printf("⚠ EDI file %s: %v", entry.Name(), err)
+				continue
+			}
+			r, err := s.receive(ctx, entry.Name(), string(content), true)
+			if err != nil {
+				// Left in place to be tried again
+				log.Printf("⚠ EDI file %s: %v", entry.Name(), err)
+				continue
+			}
+
+			result.Files++
+			result.OrdersCreated += r.orders
+			result.DocumentsSent += r.sent
+			dir := "processed"
+			if r.status == models.EDIInterchangeFailed {
+				dir = "failed"
+				result.Failed++
+			} else {
+				result.Processed++
+			}
+			if err := archive(s.inboundDir, dir, entry.Name()); err != nil {
+				log.Printf("⚠ EDI file %s: %v", entry.Name(), err)
+			}
+		}
+	}
+
+	sent, err := s.SendPending(ctx)
+	result.DocumentsSent += sent
+	if err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// archive moves a file into a subdirectory of dir, prefixed with the time
+// so files of the same name do not collide.
+func archive(dir, subdir, name string) error {
+	target := filepath.Join(dir, subdir)
+	if err := os.MkdirAll(target, 0o755); err != nil {
+		return err
+	}
+	return os.Rename(filepath.Join(dir, name), filepath.Join(target, time.Now().Format("20060102150405")+"_"+name))
+}
+
+// RunScheduler polls the inbound directory and sends due documents every
+// interval until ctx is cancelled.
+func (s *ediServiceImpl) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if result, err := s.Poll(ctx); err != nil {
+			log.Printf("⚠ EDI scheduler: %v", err)
+		} else if result.Files > 0 || result.DocumentsSent > 0 {
+			log.Printf("✓ EDI scheduler received %d files (%d orders) and sent %d documents",
+				result.Files, result.OrdersCreated, result.DocumentsSent)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,479 @@
+package edi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	customerItemService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/customer_item"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrNotShipped         = errors.New("order has not shipped")
+	ErrInvoiceNotSendable = errors.New("only posted invoices can be sent")
+)
+
+// ============================================
+// Outbound
+// ============================================
+//
+// Acknowledgements answer the purchase orders we received; ship notices
+// and invoices go to partners whatever way the order came in. Each
+// document is sent in an interchange of its own. The partner's control
+// numbers are taken, the interchange logged and the file written in one
+// transaction, so a file that cannot be written leaves no gap in the
+// numbers. Quantities on lines ordered by the customer's item code are
+// given back in the customer's unit.
+
+// SendAcknowledgement sends an 855 or ORDRSP accepting an order as
+// entered.
+func (s *ediServiceImpl) SendAcknowledgement(ctx context.Context, orderID int) (*models.EDIInterchangeWithDetails, error) {
+	doc, partner, err := s.orderDocument(ctx, orderID, models.EDIAcknowledgement)
+	if err != nil {
+		return nil, err
+	}
+	return s.send(ctx, partner, doc)
+}
+
+// SendShipNotice sends an 856 or DESADV for what an order shipped.
+func (s *ediServiceImpl) SendShipNotice(ctx context.Context, orderID int) (*models.EDIInterchangeWithDetails, error) {
+	doc, partner, err := s.orderDocument(ctx, orderID, models.EDIShipNotice)
+	if err != nil {
+		return nil, err
+	}
+	return s.send(ctx, partner, doc)
+}
+
+// SendInvoice sends an 810 or INVOIC for a posted invoice.
+func (s *ediServiceImpl) SendInvoice(ctx context.Context, invoiceID int) (*models.EDIInterchangeWithDetails, error) {
+	doc, partner, err := s.invoiceDocument(ctx, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	return s.send(ctx, partner, doc)
+}
+
+// send writes one document in an interchange to the partner.
+func (s *ediServiceImpl) send(ctx context.Context, partner *models.EDIPartner, doc *outboundDocument) (*models.EDIInterchangeWithDetails, error) {
+	if s.outboundDir == "" {
+		return nil, ErrNotConfigured
+	}
+
+	var id int
+	err := s.inTx(ctx, func(tx *ediServiceImpl) error {
+		env := &envelope{
+			senderQualifier:   partner.OurQualifier,
+			senderID:          partner.OurID,
+			receiverQualifier: partner.PartnerQualifier,
+			receiverID:        partner.PartnerID,
+			test:              partner.TestMode,
+			at:                time.Now(),
+		}
+		// Control numbers wrap at nine digits, the most an ISA can hold
+		err := tx.db.QueryRow(ctx, `
+			UPDATE edi_partners
+			SET interchange_control_number = interchange_control_number % 999999999 + 1,
+				group_control_number = group_control_number % 999999999 + 1
+			WHERE id = $1
+			RETURNING interchange_control_number, group_control_number`,
+			partner.ID).Scan(&env.interchangeNumber, &env.groupNumber)
+		if err != nil {
+			return fmt.Errorf("failed to take EDI control number: %w", err)
+		}
+
+		content := encode(partner.Standard, env, []*outboundDocument{doc})
+		control, extension := strconv.Itoa(env.interchangeNumber), "edi"
+		if partner.Standard == models.EDIStandardX12 {
+			control, extension = fmt.Sprintf("%09d", env.interchangeNumber), "x12"
+		}
+		fileName := fmt.Sprintf("%s_%s_%s.%s", fileSafe(partner.PartnerID), doc.messageType, control, extension)
+
+		ic := &interchange{
+			standard:      partner.Standard,
+			senderID:      partner.OurID,
+			receiverID:    partner.PartnerID,
+			controlNumber: control,
+		}
+		id, err = tx.insertInterchange(ctx, &partner.ID, models.EDIOutbound, ic, fileName, string(content), models.EDIInterchangeSent)
+		if err != nil {
+			return err
+		}
+		_, err = tx.db.Exec(ctx, `UPDATE edi_interchanges SET transaction_count = 1, processed_at = NOW() WHERE id = $1`, id)
+		if err != nil {
+			return fmt.Errorf("failed to update EDI interchange: %w", err)
+		}
+		_, err = tx.db.Exec(ctx, `
+			INSERT INTO edi_transactions (
+				interchange_id, document_type, message_type, control_number, reference, order_id, invoice_id, status
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+			id, doc.documentType, doc.messageType, doc.controlNumber, doc.number, doc.orderID, doc.invoiceID,
+			models.EDITransactionSent)
+		if err != nil {
+			return fmt.Errorf("failed to log EDI transaction: %w", err)
+		}
+
+		// Written under a temporary name so the file is complete when
+		// whatever collects it sees it
+		path := filepath.Join(s.outboundDir, fileName)
+		if err := os.WriteFile(path+".tmp", content, 0o644); err != nil {
+			return fmt.Errorf("failed to write EDI file: %w", err)
+		}
+		if err := os.Rename(path+".tmp", path); err != nil {
+			os.Remove(path + ".tmp")
+			return fmt.Errorf("failed to write EDI file: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetInterchange(ctx, id)
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+func fileSafe(s string) string {
+	return unsafeFileChars.ReplaceAllString(s, "_")
+}
+
+// ============================================
+// Documents
+// ============================================
+
+// orderDocument builds an acknowledgement or ship notice for an order.
+func (s *ediServiceImpl) orderDocument(ctx context.Context, orderID int, documentType models.EDIDocumentType) (*outboundDocument, *models.EDIPartner, error) {
+	var customerID int
+	var shipToID *int
+	var status models.OrderStatus
+	var orderDate, shipDate *time.Time
+	doc := &outboundDocument{documentType: documentType, date: time.Now(), orderID: &orderID}
+	err := s.db.QueryRow(ctx, `
+		SELECT so.order_number, so.customer_id, so.ship_to_id, so.status, so.order_date, so.actual_ship_date,
+			   COALESCE(so.po_number, ''), COALESCE(so.currency, '')
+		FROM sales_orders so
+		WHERE so.id = $1 AND so.company_id = $2`, orderID, tenant.Company(ctx)).Scan(
+		&doc.number, &customerID, &shipToID, &status, &orderDate, &shipDate, &doc.poNumber, &doc.currency)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil, ErrOrderNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to get sales order: %w", err)
+	}
+
+	shipped := status == models.OrderStatusShipped || status == models.OrderStatusDelivered || status == models.OrderStatusInvoiced
+	if documentType == models.EDIShipNotice && (!shipped || shipDate == nil) {
+		return nil, nil, ErrNotShipped
+	}
+
+	partner, err := s.customerPartner(ctx, customerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	doc.messageType = messageType(partner.Standard, documentType)
+	if orderDate != nil {
+		doc.poDate = *orderDate
+	}
+	if shipDate != nil {
+		doc.shipDate = *shipDate
+	}
+	if err := s.fillShipTo(ctx, partner, shipToID, doc); err != nil {
+		return nil, nil, err
+	}
+
+	rows := s.db.Query(ctx, `
+		SELECT l.line_number, COALESCE(p.sku, ''), COALESCE(NULLIF(l.description, ''), p.name, ''),
+			   CASE WHEN $2::boolean THEN l.quantity_shipped ELSE l.quantity_ordered END,
+			   COALESCE(l.unit_price, 0), COALESCE(l.unit_of_measure, ''),
+			   COALESCE(l.customer_item_code, ''), COALESCE(l.customer_uom, ''), eol.buyer_line_number
+		FROM sales_order_lines l
+		LEFT JOIN products p ON p.id = l.product_id
+		LEFT JOIN edi_order_lines eol ON eol.order_id = l.order_id AND eol.line_number = l.line_number
+		WHERE l.order_id = $1
+		ORDER BY l.line_number`, orderID, documentType == models.EDIShipNotice)
+	lines, err := scanLines(rows, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.addLines(ctx, partner, doc, lines); err != nil {
+		return nil, nil, err
+	}
+	return doc, partner, nil
+}
+
+// invoiceDocument builds an invoice. The purchase order it answers comes
+// from the sales order the invoice was raised for.
+func (s *ediServiceImpl) invoiceDocument(ctx context.Context, invoiceID int) (*outboundDocument, *models.EDIPartner, error) {
+	var customerID int
+	var shipToID *int
+	var invoiceType string
+	var status models.ARInvoiceStatus
+	var invoiceDate, poDate, shipDate *time.Time
+	doc := &outboundDocument{documentType: models.EDIInvoice, invoiceID: &invoiceID}
+	err := s.db.QueryRow(ctx, `
+		SELECT i.invoice_number, i.customer_id, i.invoice_type, i.status, i.invoice_date,
+			   COALESCE(i.currency, ''), COALESCE(i.subtotal, 0), COALESCE(i.tax_amount, 0),
+			   COALESCE(i.freight_amount, 0), COALESCE(i.total_amount, 0),
+			   i.order_id, so.ship_to_id, COALESCE(so.po_number, ''), so.order_date, so.actual_ship_date
+		FROM ar_invoices i
+		LEFT JOIN sales_orders so ON so.id = i.order_id
+		WHERE i.id = $1 AND i.company_id = $2`, invoiceID, tenant.Company(ctx)).Scan(
+		&doc.number, &customerID, &invoiceType, &status, &invoiceDate,
+		&doc.currency, &doc.subtotal, &doc.tax, &doc.freight, &doc.total,
+		&doc.orderID, &shipToID, &doc.poNumber, &poDate, &shipDate)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil, ErrInvoiceNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to get invoice: %w", err)
+	}
+	if invoiceType != "INVOICE" || status == models.ARInvoiceStatusDraft || status == models.ARInvoiceStatusVoid {
+		return nil, nil, ErrInvoiceNotSendable
+	}
+
+	partner, err := s.customerPartner(ctx, customerID)
+	if err != nil {
+		return nil, nil, err
+	}
+	doc.messageType = messageType(partner.Standard, models.EDIInvoice)
+	if invoiceDate != nil {
+		doc.date = *invoiceDate
+	}
+	if poDate != nil {
+		doc.poDate = *poDate
+	}
+	if shipDate != nil {
+		doc.shipDate = *shipDate
+	}
+	if err := s.fillShipTo(ctx, partner, shipToID, doc); err != nil {
+		return nil, nil, err
+	}
+
+	rows := s.db.Query(ctx, `
+		SELECT l.line_number, COALESCE(p.sku, ''), COALESCE(l.description, ''), l.quantity,
+			   l.unit_price, COALESCE(sol.unit_of_measure, p.base_unit, 'EA'),
+			   COALESCE(sol.customer_item_code, ''), COALESCE(sol.customer_uom, ''), eol.buyer_line_number,
+			   l.line_total
+		FROM ar_invoice_lines l
+		LEFT JOIN products p ON p.id = l.product_id
+		LEFT JOIN sales_order_lines sol ON sol.id = l.order_line_id
+		LEFT JOIN edi_order_lines eol ON eol.order_id = sol.order_id AND eol.line_number = sol.line_number
+		WHERE l.invoice_id = $1
+		ORDER BY l.line_number`, invoiceID)
+	lines, err := scanLines(rows, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.addLines(ctx, partner, doc, lines); err != nil {
+		return nil, nil, err
+	}
+	return doc, partner, nil
+}
+
+// fillShipTo names the ship-to by the partner's location code when it is
+// mapped, and by our ship-to code otherwise.
+func (s *ediServiceImpl) fillShipTo(ctx context.Context, partner *models.EDIPartner, shipToID *int, doc *outboundDocument) error {
+	if shipToID == nil {
+		return nil
+	}
+	err := s.db.QueryRow(ctx, `
+		SELECT COALESCE((SELECT location_code FROM edi_ship_to_mappings
+						 WHERE partner_id = $1 AND ship_to_id = st.id ORDER BY id LIMIT 1), st.ship_to_code, ''),
+			   COALESCE(st.name, '')
+		FROM customer_ship_to st
+		WHERE st.id = $2`, partner.ID, *shipToID).Scan(&doc.shipToCode, &doc.shipToName)
+	if err != nil && err != pgx.ErrNoRows {
+		return fmt.Errorf("failed to get ship-to: %w", err)
+	}
+	return nil
+}
+
+// documentLine is a line as stored, before it is put in the customer's
+// units.
+type documentLine struct {
+	outboundLine
+	lineNumber  int
+	customerUOM string
+}
+
+func scanLines(rows pgx.Rows, withAmount bool) ([]documentLine, error) {
+	defer rows.Close()
+
+	var lines []documentLine
+	for rows.Next() {
+		var l documentLine
+		var buyerLine *string
+		dest := []interface{}{&l.lineNumber, &l.sku, &l.description, &l.quantity, &l.unitPrice, &l.uom,
+			&l.customerCode, &l.customerUOM, &buyerLine}
+		if withAmount {
+			dest = append(dest, &l.amount)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan document line: %w", err)
+		}
+		l.buyerLine = strconv.Itoa(l.lineNumber)
+		if buyerLine != nil {
+			l.buyerLine = *buyerLine
+		}
+		lines = append(lines, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get document lines: %w", err)
+	}
+	return lines, nil
+}
+
+// addLines puts lines with a quantity on the document, converting those
+// the customer ordered by their own code back to the customer's unit.
+func (s *ediServiceImpl) addLines(ctx context.Context, partner *models.EDIPartner, doc *outboundDocument, lines []documentLine) error {
+	items := customerItemService.New(s.db)
+	for _, l := range lines {
+		if l.quantity <= 0 {
+			continue
+		}
+		if l.amount == 0 {
+			l.amount = l.quantity * l.unitPrice
+		}
+		if l.customerCode != "" && l.customerUOM != "" {
+			mapping, err := items.Resolve(ctx, partner.CustomerID, l.customerCode, l.customerUOM)
+			switch {
+			case err == nil:
+				if mapping.ConversionFactor > 0 {
+					l.quantity /= mapping.ConversionFactor
+					l.unitPrice *= mapping.ConversionFactor
+				}
+				l.uom = l.customerUOM
+			case errors.Is(err, customerItemService.ErrUnknownCode):
+				// Unmapped since the order was taken; send it in our unit
+			default:
+				return err
+			}
+		}
+		doc.lines = append(doc.lines, l.outboundLine)
+	}
+	if len(doc.lines) == 0 {
+		return ErrNothingToSend
+	}
+	return nil
+}
+
+// ============================================
+// Pending Documents
+// ============================================
+
+// SendPending sends, for every company, the acknowledgements, ship notices
+// and invoices active partners are set up to receive and have not had.
+// Only orders shipped and invoices raised since the partner was set up
+// are sent.
+func (s *ediServiceImpl) SendPending(ctx context.Context) (int, error) {
+	if s.outboundDir == "" {
+		return 0, nil
+	}
+
+	rows := s.db.Query(ctx, `SELECT DISTINCT company_id FROM edi_partners WHERE is_active`)
+	var companies []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scanning EDI partner company: %w", err)
+		}
+		companies = append(companies, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("finding EDI partner companies: %w", err)
+	}
+
+	sent := 0
+	for _, companyID := range companies {
+		n, err := s.sendPending(tenant.WithCompany(ctx, companyID))
+		sent += n
+		if err != nil {
+			log.Printf("⚠ EDI documents for company %d: %v", companyID, err)
+		}
+	}
+	return sent, nil
+}
+
+var pendingQueries = []struct {
+	documentType models.EDIDocumentType
+	query        string
+}{
+	{models.EDIAcknowledgement, `
+		SELECT DISTINCT t.order_id
+		FROM edi_transactions t
+		JOIN edi_interchanges i ON i.id = t.interchange_id
+		JOIN edi_partners p ON p.id = i.partner_id
+		WHERE i.company_id = $1 AND p.is_active AND p.send_acknowledgement
+		  AND t.document_type = 'PURCHASE_ORDER' AND t.status = 'PROCESSED' AND t.order_id IS NOT NULL
+		  AND NOT EXISTS (SELECT 1 FROM edi_transactions a
+						  WHERE a.order_id = t.order_id AND a.document_type = 'ACKNOWLEDGEMENT')
+		ORDER BY t.order_id`},
+	{models.EDIShipNotice, `
+		SELECT so.id
+		FROM sales_orders so
+		JOIN edi_partners p ON p.customer_id = so.customer_id AND p.company_id = so.company_id
+		WHERE so.company_id = $1 AND p.is_active AND p.send_ship_notice
+		  AND so.status IN ('SHIPPED', 'DELIVERED', 'INVOICED') AND so.actual_ship_date >= p.created_at::date
+		  AND EXISTS (SELECT 1 FROM sales_order_lines l WHERE l.order_id = so.id AND l.quantity_shipped > 0)
+		  AND NOT EXISTS (SELECT 1 FROM edi_transactions t
+						  WHERE t.order_id = so.id AND t.document_type = 'SHIP_NOTICE')
+		ORDER BY so.id`},
+	{models.EDIInvoice, `
+		SELECT i.id
+		FROM ar_invoices i
+		JOIN edi_partners p ON p.customer_id = i.customer_id AND p.company_id = i.company_id
+		WHERE i.company_id = $1 AND p.is_active AND p.send_invoice
+		  AND i.invoice_type = 'INVOICE' AND i.status IN ('POSTED', 'PARTIAL', 'PAID', 'OVERDUE')
+		  AND i.invoice_date >= p.created_at::date
+		  AND NOT EXISTS (SELECT 1 FROM edi_transactions t
+						  WHERE t.invoice_id = i.id AND t.document_type = 'INVOICE')
+		ORDER BY i.id`},
+}
+
+func (s *ediServiceImpl) sendPending(ctx context.Context) (int, error) {
+	sent := 0
+	for _, pending := range pendingQueries {
+		rows := s.db.Query(ctx, pending.query, tenant.Company(ctx))
+		var ids []int
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return sent, fmt.Errorf("scanning pending EDI document: %w", err)
+			}
+			ids = append(ids, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return sent, fmt.Errorf("finding pending EDI documents: %w", err)
+		}
+
+		for _, id := range ids {
+			var err error
+			switch pending.documentType {
+			case models.EDIAcknowledgement:
+				_, err = s.SendAcknowledgement(ctx, id)
+			case models.EDIShipNotice:
+				_, err = s.SendShipNotice(ctx, id)
+			case models.EDIInvoice:
+				_, err = s.SendInvoice(ctx, id)
+			}
+			if err != nil {
+				log.Printf("⚠ EDI %s %d: %v", pending.documentType, id, err)
+				continue
+			}
+			sent++
+		}
+	}
+	return sent, nil
+}
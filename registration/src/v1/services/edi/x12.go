@@ -0,0 +1,297 @@
+package edi
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+)
+
+// ============================================
+// X12
+// ============================================
+//
+// An X12 interchange opens with a fixed-width ISA segment whose fourth
+// character is the element separator and whose last two characters are the
+// component separator and segment terminator. Functional groups (GS/GE)
+// hold transaction sets (ST/SE). We read any separators and write version
+// 004010 with * elements, > components and ~ terminators.
+
+const x12ISALength = 106
+
+var x12FunctionalIDs = map[string]string{
+	"850": "PO",
+	"855": "PR",
+	"856": "SH",
+	"810": "IN",
+}
+
+func parseX12(content string) (*interchange, error) {
+	if len(content) < x12ISALength || !strings.HasPrefix(content, "ISA") {
+		return nil, fmt.Errorf("%w: file does not start with an ISA segment", ErrMalformed)
+	}
+	elementSep := content[3]
+	componentSep := content[104]
+	terminator := content[105]
+
+	ic := &interchange{standard: models.EDIStandardX12}
+	var msg *message
+	var groups, closed int
+	for _, raw := range strings.Split(content, string(terminator)) {
+		raw = strings.Trim(raw, "\r\n\t ")
+		if raw == "" {
+			continue
+		}
+		seg := segment{}
+		for i, el := range strings.Split(raw, string(elementSep)) {
+			if i == 0 {
+				seg.tag = el
+				continue
+			}
+			seg.elements = append(seg.elements, strings.Split(el, string(componentSep)))
+		}
+
+		switch seg.tag {
+		case "ISA":
+			ic.senderQualifier = strings.TrimSpace(seg.el(5))
+			ic.senderID = strings.TrimSpace(seg.el(6))
+			ic.receiverQualifier = strings.TrimSpace(seg.el(7))
+			ic.receiverID = strings.TrimSpace(seg.el(8))
+			ic.controlNumber = strings.TrimSpace(seg.el(13))
+			ic.test = seg.el(15) == "T"
+		case "GS":
+			groups++
+		case "GE":
+			closed++
+		case "ST":
+			msg = &message{messageType: seg.el(1), controlNumber: seg.el(2)}
+			msg.segments = append(msg.segments, seg)
+		case "SE":
+			if msg == nil {
+				return nil, fmt.Errorf("%w: SE without ST", ErrMalformed)
+			}
+			msg.segments = append(msg.segments, seg)
+			if n, _ := strconv.Atoi(seg.el(1)); n != len(msg.segments) {
+				msg.err = fmt.Sprintf("SE counts %s segments but the transaction set has %d", seg.el(1), len(msg.segments))
+			} else if seg.el(2) != msg.controlNumber {
+				msg.err = fmt.Sprintf("SE control number %s does not match ST %s", seg.el(2), msg.controlNumber)
+			}
+			ic.messages = append(ic.messages, msg)
+			msg = nil
+		case "IEA":
+			if strings.TrimSpace(seg.el(2)) != ic.controlNumber {
+				return nil, fmt.Errorf("%w: IEA control number %s does not match ISA %s", ErrMalformed, seg.el(2), ic.controlNumber)
+			}
+			ic.closed = true
+		default:
+			if msg == nil {
+				return nil, fmt.Errorf("%w: %s segment outside a transaction set", ErrMalformed, seg.tag)
+			}
+			msg.segments = append(msg.segments, seg)
+		}
+	}
+
+	switch {
+	case msg != nil:
+		return nil, fmt.Errorf("%w: transaction set %s has no SE", ErrMalformed, msg.controlNumber)
+	case groups != closed:
+		return nil, fmt.Errorf("%w: functional group has no GE", ErrMalformed)
+	case !ic.closed:
+		return nil, fmt.Errorf("%w: interchange has no IEA", ErrMalformed)
+	}
+	return ic, nil
+}
+
+// purchaseOrderFromX12 reads an 850.
+func purchaseOrderFromX12(msg *message) (*purchaseOrder, error) {
+	po := &purchaseOrder{}
+	var line *purchaseOrderLine
+	for _, seg := range msg.segments {
+		switch seg.tag {
+		case "BEG":
+			po.number = seg.el(3)
+			po.date = parseDate(seg.el(5))
+		case "DTM":
+			// 002 delivery requested, 010 ship requested
+			if q := seg.el(1); q == "002" || q == "010" {
+				po.shipDate = parseDate(seg.el(2))
+			}
+		case "N1":
+			if seg.el(1) == "ST" {
+				po.shipToName = seg.el(2)
+				po.shipToCode = seg.el(4)
+			}
+		case "MSG":
+			po.notes = appendNote(po.notes, seg.el(1))
+		case "PO1":
+			po.lines = append(po.lines, purchaseOrderLine{
+				buyerLine: seg.el(1),
+				quantity:  parseNumber(seg.el(2)),
+				uom:       seg.el(3),
+				unitPrice: parseNumber(seg.el(4)),
+			})
+			line = &po.lines[len(po.lines)-1]
+			// Product IDs come in qualifier/value pairs from PO106
+			for i := 6; i+1 <= len(seg.elements); i += 2 {
+				line.setID(seg.el(i), seg.el(i+1))
+			}
+		case "PID":
+			if line != nil && seg.el(1) == "F" {
+				line.description = seg.el(5)
+			}
+		}
+	}
+
+	if po.number == "" {
+		return nil, fmt.Errorf("%w: 850 has no BEG purchase order number", ErrMalformed)
+	}
+	if len(po.lines) == 0 {
+		return nil, fmt.Errorf("%w: 850 has no PO1 lines", ErrMalformed)
+	}
+	return po, nil
+}
+
+// ============================================
+// X12 Writer
+// ============================================
+
+type x12Writer struct {
+	b        strings.Builder
+	segments int // In the current transaction set
+}
+
+func (w *x12Writer) segment(tag string, elements ...string) {
+	// Trailing empty elements are dropped
+	for len(elements) > 0 && elements[len(elements)-1] == "" {
+		elements = elements[:len(elements)-1]
+	}
+	w.b.WriteString(tag)
+	for _, el := range elements {
+		w.b.WriteByte('*')
+		w.b.WriteString(x12Escape(el))
+	}
+	w.b.WriteString("~\n")
+	w.segments++
+}
+
+// x12Escape drops separators from free text; X12 has no release character.
+func x12Escape(s string) string {
+	return strings.NewReplacer("*", " ", "~", " ", ">", " ", "\n", " ").Replace(s)
+}
+
+// encodeX12 writes an interchange with one functional group holding a
+// transaction set per document.
+func encodeX12(env *envelope, docs []*outboundDocument) []byte {
+	w := &x12Writer{}
+	now := env.at
+
+	usage := "P"
+	if env.test {
+		usage = "T"
+	}
+	// ISA is fixed width: qualifiers are padded to 2, IDs to 15 and the
+	// control number to 9. Partners are validated to fit, but a longer value
+	// is cut rather than shifting every field after it.
+	senderID, receiverID := x12Escape(env.senderID), x12Escape(env.receiverID)
+	fmt.Fprintf(&w.b, "ISA*00*%-10s*00*%-10s*%-2.2s*%-15.15s*%-2.2s*%-15.15s*%s*%s*U*00401*%09d*0*%s*>~\n",
+		"", "", x12Escape(env.senderQualifier), senderID, x12Escape(env.receiverQualifier), receiverID,
+		now.Format("060102"), now.Format("1504"), env.interchangeNumber, usage)
+	fmt.Fprintf(&w.b, "GS*%s*%.15s*%.15s*%s*%s*%d*X*004010~\n",
+		x12FunctionalIDs[docs[0].messageType], senderID, receiverID,
+		now.Format("20060102"), now.Format("1504"), env.groupNumber)
+
+	for i, doc := range docs {
+		doc.controlNumber = fmt.Sprintf("%04d", i+1)
+		w.segments = 0
+		w.segment("ST", doc.messageType, doc.controlNumber)
+		switch doc.documentType {
+		case models.EDIAcknowledgement:
+			w.acknowledgement(doc)
+		case models.EDIShipNotice:
+			w.shipNotice(doc)
+		case models.EDIInvoice:
+			w.invoice(doc)
+		}
+		w.segment("SE", strconv.Itoa(w.segments+1), doc.controlNumber)
+	}
+
+	fmt.Fprintf(&w.b, "GE*%d*%d~\n", len(docs), env.groupNumber)
+	fmt.Fprintf(&w.b, "IEA*1*%09d~\n", env.interchangeNumber)
+	return []byte(w.b.String())
+}
+
+// acknowledgement writes an 855 accepting every line as ordered.
+func (w *x12Writer) acknowledgement(doc *outboundDocument) {
+	w.segment("BAK", "00", "AC", doc.poNumber, x12Date(doc.poDate), "", "", "", doc.number, x12Date(doc.date))
+	w.shipTo(doc)
+	for _, l := range doc.lines {
+		w.segment("PO1", append([]string{l.buyerLine, formatNumber(l.quantity), l.uom, formatNumber(l.unitPrice), ""}, w.ids(l)...)...)
+		w.segment("ACK", "IA", formatNumber(l.quantity), l.uom)
+	}
+	w.segment("CTT", strconv.Itoa(len(doc.lines)))
+}
+
+// shipNotice writes an 856 with shipment, order and item levels.
+func (w *x12Writer) shipNotice(doc *outboundDocument) {
+	w.segment("BSN", "00", doc.number, x12Date(doc.date), doc.date.Format("1504"))
+	w.segment("HL", "1", "", "S")
+	w.segment("DTM", "011", x12Date(doc.shipDate))
+	w.shipTo(doc)
+	w.segment("HL", "2", "1", "O")
+	w.segment("PRF", doc.poNumber)
+	for i, l := range doc.lines {
+		w.segment("HL", strconv.Itoa(i+3), "2", "I")
+		w.segment("LIN", append([]string{l.buyerLine}, w.ids(l)...)...)
+		w.segment("SN1", "", formatNumber(l.quantity), l.uom)
+	}
+	w.segment("CTT", strconv.Itoa(len(doc.lines)))
+}
+
+// invoice writes an 810. TDS carries the total in cents.
+func (w *x12Writer) invoice(doc *outboundDocument) {
+	w.segment("BIG", x12Date(doc.date), doc.number, x12Date(doc.poDate), doc.poNumber)
+	w.segment("CUR", "SE", doc.currency)
+	w.shipTo(doc)
+	for _, l := range doc.lines {
+		w.segment("IT1", append([]string{l.buyerLine, formatNumber(l.quantity), l.uom, formatNumber(l.unitPrice), ""}, w.ids(l)...)...)
+		if l.description != "" {
+			w.segment("PID", "F", "", "", "", l.description)
+		}
+	}
+	w.segment("TDS", strconv.FormatInt(cents(doc.total), 10))
+	if doc.tax != 0 {
+		w.segment("TXI", "TX", formatNumber(doc.tax))
+	}
+	if doc.freight != 0 {
+		w.segment("SAC", "C", "G830", "", "", strconv.FormatInt(cents(doc.freight), 10))
+	}
+	w.segment("CTT", strconv.Itoa(len(doc.lines)))
+}
+
+func (w *x12Writer) shipTo(doc *outboundDocument) {
+	if doc.shipToCode != "" || doc.shipToName != "" {
+		qualifier := ""
+		if doc.shipToCode != "" {
+			qualifier = "92"
+		}
+		w.segment("N1", "ST", doc.shipToName, qualifier, doc.shipToCode)
+	}
+}
+
+// ids lists the line's product IDs: the buyer's part number then our SKU.
+func (w *x12Writer) ids(l outboundLine) []string {
+	var ids []string
+	if l.customerCode != "" {
+		ids = append(ids, "BP", l.customerCode)
+	}
+	return append(ids, "VP", l.sku)
+}
+
+func x12Date(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("20060102")
+}
@@ -0,0 +1,564 @@
+package gl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrEliminationNotFound      = errors.New("elimination entry not found")
+	ErrNothingToEliminate       = errors.New("no intercompany balances to eliminate")
+	ErrIntercompanyOutOfBalance = errors.New("intercompany balances do not net to zero")
+)
+
+// ============================================
+// Consolidated Reports
+// ============================================
+
+// accountBalance is the debit-positive balance of one company's account.
+type accountBalance struct {
+	companyID   int
+	accountID   int
+	code        string
+	name        string
+	accountType models.GLAccountType
+	balance     float64
+}
+
+// consolidatedLine adds up the accounts sharing a consolidation code.
+type consolidatedLine struct {
+	code         string
+	name         string
+	accountType  models.GLAccountType
+	companies    map[int]float64
+	eliminations float64
+}
+
+func (l *consolidatedLine) total() float64 {
+	total := l.eliminations
+	for _, amount := range l.companies {
+		total += amount
+	}
+	return total
+}
+
+func (s *glServiceImpl) GetConsolidatedTrialBalance(ctx context.Context, filters models.ConsolidationFilters) (*models.ConsolidatedTrialBalanceReport, error) {
+	companies, err := s.consolidationCompanies(ctx, filters.CompanyIDs)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := s.consolidate(ctx, companies, filters.DateFrom, filters.DateTo, true)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ConsolidatedTrialBalanceReport{
+		DateFrom:  filters.DateFrom,
+		DateTo:    filters.DateTo,
+		Companies: companies,
+		Rows:      []models.ConsolidatedTrialBalanceRow{},
+	}
+	for _, line := range lines {
+		row := models.ConsolidatedTrialBalanceRow{
+			ConsolidationCode: line.code,
+			AccountName:       line.name,
+			AccountType:       line.accountType,
+			Companies:         companyAmounts(companies, line.companies, 1),
+			Eliminations:      line.eliminations,
+		}
+		if net := line.total(); net >= 0 {
+			row.ClosingDebit = net
+		} else {
+			row.ClosingCredit = -net
+		}
+
+		report.Rows = append(report.Rows, row)
+		report.TotalDebit += row.ClosingDebit
+		report.TotalCredit += row.ClosingCredit
+		report.TotalEliminations += line.eliminations
+	}
+
+	return report, nil
+}
+
+func (s *glServiceImpl) GetConsolidatedIncomeStatement(ctx context.Context, filters models.ConsolidationFilters) (*models.ConsolidatedIncomeStatement, error) {
+	companies, err := s.consolidationCompanies(ctx, filters.CompanyIDs)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := s.consolidate(ctx, companies, filters.DateFrom, filters.DateTo, false)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ConsolidatedIncomeStatement{
+		DateFrom:  filters.DateFrom,
+		DateTo:    filters.DateTo,
+		Companies: companies,
+		Revenue:   []models.ConsolidatedStatementRow{},
+		Expenses:  []models.ConsolidatedStatementRow{},
+	}
+	for _, line := range lines {
+		switch line.accountType {
+		case models.GLAccountTypeRevenue:
+			row := statementRow(companies, line, -1)
+			report.Revenue = append(report.Revenue, row)
+			report.TotalRevenue += row.Amount
+		case models.GLAccountTypeExpense:
+			row := statementRow(companies, line, 1)
+			report.Expenses = append(report.Expenses, row)
+			report.TotalExpenses += row.Amount
+		}
+	}
+	report.NetIncome = report.TotalRevenue - report.TotalExpenses
+
+	return report, nil
+}
+
+func (s *glServiceImpl) GetConsolidatedBalanceSheet(ctx context.Context, filters models.ConsolidationFilters) (*models.ConsolidatedBalanceSheet, error) {
+	companies, err := s.consolidationCompanies(ctx, filters.CompanyIDs)
+	if err != nil {
+		return nil, err
+	}
+	// The balance sheet is cumulative up to the report date
+	lines, err := s.consolidate(ctx, companies, "", filters.DateTo, true)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ConsolidatedBalanceSheet{
+		AsOfDate:    filters.DateTo,
+		Companies:   companies,
+		Assets:      []models.ConsolidatedStatementRow{},
+		Liabilities: []models.ConsolidatedStatementRow{},
+		Equity:      []models.ConsolidatedStatementRow{},
+	}
+	for _, line := range lines {
+		switch line.accountType {
+		case models.GLAccountTypeAsset:
+			row := statementRow(companies, line, 1)
+			report.Assets = append(report.Assets, row)
+			report.TotalAssets += row.Amount
+		case models.GLAccountTypeLiability:
+			row := statementRow(companies, line, -1)
+			report.Liabilities = append(report.Liabilities, row)
+			report.TotalLiabilities += row.Amount
+		case models.GLAccountTypeEquity:
+			row := statementRow(companies, line, -1)
+			report.Equity = append(report.Equity, row)
+			report.TotalEquity += row.Amount
+		case models.GLAccountTypeRevenue, models.GLAccountTypeExpense:
+			report.CurrentEarnings -= line.total()
+		}
+	}
+	report.TotalEquity += report.CurrentEarnings
+
+	return report, nil
+}
+
+// consolidationCompanies returns the companies to consolidate, defaulting to
+// the current company when none are given.
+func (s *glServiceImpl) consolidationCompanies(ctx context.Context, companyIDs []int) ([]models.ConsolidatedCompany, error) {
+	if len(companyIDs) == 0 {
+		companyIDs = []int{tenant.Company(ctx)}
+	}
+
+	rows := s.db.Query(ctx, `
+		SELECT id, company_code, company_name FROM companies
+		WHERE id = ANY($1)
+		ORDER BY company_code
+	`, companyIDs)
+	defer rows.Close()
+
+	companies := []models.ConsolidatedCompany{}
+	for rows.Next() {
+		var c models.ConsolidatedCompany
+		if err := rows.Scan(&c.CompanyID, &c.CompanyCode, &c.CompanyName); err != nil {
+			return nil, fmt.Errorf("scanning company: %w", err)
+		}
+		companies = append(companies, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing companies: %w", err)
+	}
+
+	return companies, nil
+}
+
+// consolidate adds up account balances and eliminations by consolidation code.
+func (s *glServiceImpl) consolidate(ctx context.Context, companies []models.ConsolidatedCompany, dateFrom, dateTo string, withOpening bool) ([]*consolidatedLine, error) {
+	companyIDs := make([]int, len(companies))
+	for i, c := range companies {
+		companyIDs[i] = c.CompanyID
+	}
+
+	balances, err := s.accountBalances(ctx, companyIDs, dateFrom, dateTo, withOpening, false)
+	if err != nil {
+		return nil, err
+	}
+	eliminations, err := s.eliminationAmounts(ctx, companyIDs, dateFrom, dateTo)
+	if err != nil {
+		return nil, err
+	}
+
+	// Balances come ordered by consolidation code
+	var lines []*consolidatedLine
+	byCode := map[string]*consolidatedLine{}
+	for _, b := range balances {
+		line, ok := byCode[b.code]
+		if !ok {
+			line = &consolidatedLine{code: b.code, name: b.name, accountType: b.accountType, companies: map[int]float64{}}
+			byCode[b.code] = line
+			lines = append(lines, line)
+		}
+		line.companies[b.companyID] += b.balance
+		line.eliminations += eliminations[b.accountID]
+	}
+
+	return lines, nil
+}
+
+// accountBalances returns debit-positive balances of the companies' active
+// accounts from posted entries in the date range. With intercompanyOnly only
+// intercompany accounts whose partner is one of the companies are returned.
+func (s *glServiceImpl) accountBalances(ctx context.Context, companyIDs []int, dateFrom, dateTo string, withOpening, intercompanyOnly bool) ([]accountBalance, error) {
+	rows := s.db.Query(ctx, `
+		SELECT a.company_id, a.id, COALESCE(NULLIF(a.consolidation_code, ''), a.account_code) AS code,
+		       a.account_name, a.account_type,
+		       CASE WHEN $4 THEN
+		           CASE WHEN a.normal_balance = 'DEBIT' THEN COALESCE(a.opening_balance, 0)
+		                ELSE -COALESCE(a.opening_balance, 0) END
+		       ELSE 0 END + COALESCE(act.amount, 0) AS balance
+		FROM gl_accounts a
+		LEFT JOIN (
+			SELECT jl.account_id, SUM(jl.debit_amount - jl.credit_amount) AS amount
+			FROM gl_journal_lines jl
+			JOIN gl_journal_entries je ON je.id = jl.journal_id
+			WHERE je.status = 'POSTED' AND je.company_id = ANY($1)
+			  AND ($2::date IS NULL OR je.posting_date >= $2::date)
+			  AND ($3::date IS NULL OR je.posting_date <= $3::date)
+			GROUP BY jl.account_id
+		) act ON act.account_id = a.id
+		WHERE a.company_id = ANY($1) AND a.is_active = true
+		  AND (NOT $5 OR (a.is_intercompany AND a.partner_company_id = ANY($1)))
+		ORDER BY code, a.company_id
+	`, companyIDs, nullDate(dateFrom), nullDate(dateTo), withOpening, intercompanyOnly)
+	defer rows.Close()
+
+	var balances []accountBalance
+	for rows.Next() {
+		var b accountBalance
+		if err := rows.Scan(&b.companyID, &b.accountID, &b.code, &b.name, &b.accountType, &b.balance); err != nil {
+			return nil, fmt.Errorf("scanning account balance: %w", err)
+		}
+		balances = append(balances, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("getting account balances: %w", err)
+	}
+
+	return balances, nil
+}
+
+// eliminationAmounts returns debit-positive elimination amounts by account
+// from active elimination entries dated in the range.
+func (s *glServiceImpl) eliminationAmounts(ctx context.Context, companyIDs []int, dateFrom, dateTo string) (map[int]float64, error) {
+	rows := s.db.Query(ctx, `
+		SELECT l.account_id, SUM(l.debit_amount - l.credit_amount)
+		FROM gl_elimination_lines l
+		JOIN gl_elimination_entries e ON e.id = l.elimination_id
+		WHERE e.status = 'ACTIVE' AND l.company_id = ANY($1)
+		  AND ($2::date IS NULL OR e.entry_date >= $2::date)
+		  AND ($3::date IS NULL OR e.entry_date <= $3::date)
+		GROUP BY l.account_id
+	`, companyIDs, nullDate(dateFrom), nullDate(dateTo))
+	defer rows.Close()
+
+	amounts := map[int]float64{}
+	for rows.Next() {
+		var accountID int
+		var amount float64
+		if err := rows.Scan(&accountID, &amount); err != nil {
+			return nil, fmt.Errorf("scanning elimination amount: %w", err)
+		}
+		amounts[accountID] = amount
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("getting elimination amounts: %w", err)
+	}
+
+	return amounts, nil
+}
+
+// statementRow converts a line to a statement row. sign is -1 for sections
+// shown credit-positive.
+func statementRow(companies []models.ConsolidatedCompany, line *consolidatedLine, sign float64) models.ConsolidatedStatementRow {
+	return models.ConsolidatedStatementRow{
+		ConsolidationCode: line.code,
+		AccountName:       line.name,
+		AccountType:       line.accountType,
+		Companies:         companyAmounts(companies, line.companies, sign),
+		Eliminations:      sign * line.eliminations,
+		Amount:            sign * line.total(),
+	}
+}
+
+func companyAmounts(companies []models.ConsolidatedCompany, amounts map[int]float64, sign float64) []models.ConsolidatedAmount {
+	result := make([]models.ConsolidatedAmount, len(companies))
+	for i, c := range companies {
+		result[i] = models.ConsolidatedAmount{CompanyID: c.CompanyID, Amount: sign * amounts[c.CompanyID]}
+	}
+	return result
+}
+
+func nullDate(date string) *string {
+	if date == "" {
+		return nil
+	}
+	return &date
+}
+
+// ============================================
+// Elimination Entries
+// ============================================
+
+func (s *glServiceImpl) CreateEliminationEntry(ctx context.Context, req models.CreateEliminationEntryRequest, createdBy int) (int, error) {
+	for _, line := range req.Lines {
+		var exists bool
+		err := s.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM gl_accounts WHERE id = $1 AND company_id = $2)`,
+			line.AccountID, line.CompanyID).Scan(&exists)
+		if err != nil {
+			return 0, fmt.Errorf("checking account: %w", err)
+		}
+		if !exists {
+			return 0, ErrAccountNotFound
+		}
+	}
+
+	return s.insertElimination(ctx, req, false, createdBy)
+}
+
+// GenerateEliminations creates an elimination entry reversing what remains on
+// the intercompany accounts between the companies as of a date. Receivables
+// net against payables and sales against purchases, so the remaining balances
+// must add up to zero.
+func (s *glServiceImpl) GenerateEliminations(ctx context.Context, req models.GenerateEliminationsRequest, createdBy int) (int, error) {
+	companyIDs := req.CompanyIDs
+	if len(companyIDs) == 0 {
+		companyIDs = []int{tenant.Company(ctx)}
+	}
+	asOfDate := req.AsOfDate
+	if asOfDate == "" {
+		asOfDate = time.Now().Format("2006-01-02")
+	}
+
+	balances, err := s.accountBalances(ctx, companyIDs, "", asOfDate, true, true)
+	if err != nil {
+		return 0, err
+	}
+	eliminated, err := s.eliminationAmounts(ctx, companyIDs, "", asOfDate)
+	if err != nil {
+		return 0, err
+	}
+
+	entry := models.CreateEliminationEntryRequest{
+		EntryDate:   asOfDate,
+		Description: "Intercompany eliminations as of " + asOfDate,
+	}
+	var totalDebit, totalCredit float64
+	for _, b := range balances {
+		remaining := math.Round((b.balance+eliminated[b.accountID])*100) / 100
+		if remaining == 0 {
+			continue
+		}
+
+		line := models.CreateEliminationLineRequest{
+			CompanyID:   b.companyID,
+			AccountID:   b.accountID,
+			Description: "Eliminate " + b.code + " " + b.name,
+		}
+		if remaining > 0 {
+			line.CreditAmount = remaining
+		} else {
+			line.DebitAmount = -remaining
+		}
+		totalDebit += line.DebitAmount
+		totalCredit += line.CreditAmount
+		entry.Lines = append(entry.Lines, line)
+	}
+
+	if len(entry.Lines) == 0 {
+		return 0, ErrNothingToEliminate
+	}
+	if diff := math.Abs(totalDebit - totalCredit); diff >= 0.01 {
+		return 0, fmt.Errorf("%w: difference %.2f", ErrIntercompanyOutOfBalance, diff)
+	}
+
+	return s.insertElimination(ctx, entry, true, createdBy)
+}
+
+func (s *glServiceImpl) insertElimination(ctx context.Context, req models.CreateEliminationEntryRequest, isGenerated bool, createdBy int) (int, error) {
+	entryDate, err := time.Parse("2006-01-02", req.EntryDate)
+	if err != nil {
+		return 0, fmt.Errorf("parsing entry date: %w", err)
+	}
+
+	var totalDebit, totalCredit float64
+	for _, line := range req.Lines {
+		totalDebit += line.DebitAmount
+		totalCredit += line.CreditAmount
+	}
+	if math.Abs(totalDebit-totalCredit) >= 0.01 {
+		return 0, ErrUnbalancedEntry
+	}
+
+	var by *int
+	if createdBy > 0 {
+		by = &createdBy
+	}
+
+	var entryID int
+	err = s.inTx(ctx, func(tx *glServiceImpl) error {
+		err := tx.db.QueryRow(ctx, `
+			INSERT INTO gl_elimination_entries (
+				entry_number, entry_date, description, is_generated, total_debit, total_credit, created_by
+			)
+			SELECT 'EL-' || TO_CHAR(NOW(), 'YYYYMMDD') || '-' || LPAD((COALESCE(MAX(id), 0) + 1)::TEXT, 4, '0'),
+			       $1, $2, $3, $4, $5, $6
+			FROM gl_elimination_entries
+			RETURNING id
+		`, entryDate, req.Description, isGenerated, totalDebit, totalCredit, by).Scan(&entryID)
+		if err != nil {
+			return fmt.Errorf("creating elimination entry: %w", err)
+		}
+
+		for i, line := range req.Lines {
+			_, err := tx.db.Exec(ctx, `
+				INSERT INTO gl_elimination_lines (
+					elimination_id, line_number, company_id, account_id, description, debit_amount, credit_amount
+				) VALUES ($1, $2, $3, $4, $5, $6, $7)
+			`, entryID, i+1, line.CompanyID, line.AccountID, line.Description, line.DebitAmount, line.CreditAmount)
+			if err != nil {
+				return fmt.Errorf("creating elimination line: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return entryID, nil
+}
+
+// GetEliminationEntryByID returns an elimination entry touching the current
+// company.
+func (s *glServiceImpl) GetEliminationEntryByID(ctx context.Context, id int) (*models.GLEliminationEntryWithLines, error) {
+	var result models.GLEliminationEntryWithLines
+	e := &result.Entry
+	err := s.db.QueryRow(ctx, `
+		SELECT id, entry_number, entry_date, description, status, is_generated,
+		       total_debit, total_credit, created_by, created_at
+		FROM gl_elimination_entries e
+		WHERE id = $1
+		  AND EXISTS (SELECT 1 FROM gl_elimination_lines l WHERE l.elimination_id = e.id AND l.company_id = $2)
+	`, id, tenant.Company(ctx)).Scan(
+		&e.ID, &e.EntryNumber, &e.EntryDate, &e.Description, &e.Status, &e.IsGenerated,
+		&e.TotalDebit, &e.TotalCredit, &e.CreatedBy, &e.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrEliminationNotFound
+		}
+		return nil, fmt.Errorf("getting elimination entry: %w", err)
+	}
+
+	rows := s.db.Query(ctx, `
+		SELECT l.id, l.elimination_id, l.line_number, l.company_id, c.company_name,
+		       l.account_id, a.account_code, a.account_name, COALESCE(l.description, ''),
+		       l.debit_amount, l.credit_amount
+		FROM gl_elimination_lines l
+		JOIN companies c ON c.id = l.company_id
+		JOIN gl_accounts a ON a.id = l.account_id
+		WHERE l.elimination_id = $1
+		ORDER BY l.line_number
+	`, id)
+	defer rows.Close()
+
+	result.Lines = []models.GLEliminationLine{}
+	for rows.Next() {
+		var l models.GLEliminationLine
+		if err := rows.Scan(
+			&l.ID, &l.EliminationID, &l.LineNumber, &l.CompanyID, &l.CompanyName,
+			&l.AccountID, &l.AccountCode, &l.AccountName, &l.Description,
+			&l.DebitAmount, &l.CreditAmount,
+		); err != nil {
+			return nil, fmt.Errorf("scanning elimination line: %w", err)
+		}
+		result.Lines = append(result.Lines, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("getting elimination lines: %w", err)
+	}
+
+	return &result, nil
+}
+
+// ListEliminationEntries lists the elimination entries touching any of the
+// given companies.
+func (s *glServiceImpl) ListEliminationEntries(ctx context.Context, filters models.ConsolidationFilters) ([]models.GLEliminationEntry, error) {
+	companyIDs := filters.CompanyIDs
+	if len(companyIDs) == 0 {
+		companyIDs = []int{tenant.Company(ctx)}
+	}
+
+	rows := s.db.Query(ctx, `
+		SELECT id, entry_number, entry_date, description, status, is_generated,
+		       total_debit, total_credit, created_by, created_at
+		FROM gl_elimination_entries e
+		WHERE EXISTS (SELECT 1 FROM gl_elimination_lines l WHERE l.elimination_id = e.id AND l.company_id = ANY($1))
+		  AND ($2::date IS NULL OR e.entry_date >= $2::date)
+		  AND ($3::date IS NULL OR e.entry_date <= $3::date)
+		ORDER BY e.entry_date DESC, e.id DESC
+	`, companyIDs, nullDate(filters.DateFrom), nullDate(filters.DateTo))
+	defer rows.Close()
+
+	entries := []models.GLEliminationEntry{}
+	for rows.Next() {
+		var e models.GLEliminationEntry
+		if err := rows.Scan(
+			&e.ID, &e.EntryNumber, &e.EntryDate, &e.Description, &e.Status, &e.IsGenerated,
+			&e.TotalDebit, &e.TotalCredit, &e.CreatedBy, &e.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scanning elimination entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing elimination entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+func (s *glServiceImpl) VoidEliminationEntry(ctx context.Context, id int) error {
+	result, err := s.db.Exec(ctx, `
+		UPDATE gl_elimination_entries e SET status = 'VOIDED'
+		WHERE id = $1 AND status = 'ACTIVE'
+		  AND EXISTS (SELECT 1 FROM gl_elimination_lines l WHERE l.elimination_id = e.id AND l.company_id = $2)
+	`, id, tenant.Company(ctx))
+	if err != nil {
+		return fmt.Errorf("voiding elimination entry: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrEliminationNotFound
+	}
+	return nil
+}
@@ -4,10 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"time"
 
 	"github.com/anas-dev-92/FoodHive/core/postgres"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
 	"github.com/jackc/pgx/v5"
 )
 
@@ -68,29 +70,61 @@ type GLService interface {
 	GetBalanceSheet(ctx context.Context, filters models.ReportFilters) (*models.BalanceSheetReport, error)
 	GetAccountActivity(ctx context.Context, accountID int, dateFrom, dateTo string) (*models.AccountActivityReport, error)
 
+	// Consolidation across companies
+	GetConsolidatedTrialBalance(ctx context.Context, filters models.ConsolidationFilters) (*models.ConsolidatedTrialBalanceReport, error)
+	GetConsolidatedIncomeStatement(ctx context.Context, filters models.ConsolidationFilters) (*models.ConsolidatedIncomeStatement, error)
+	GetConsolidatedBalanceSheet(ctx context.Context, filters models.ConsolidationFilters) (*models.ConsolidatedBalanceSheet, error)
+	CreateEliminationEntry(ctx context.Context, req models.CreateEliminationEntryRequest, createdBy int) (int, error)
+	GenerateEliminations(ctx context.Context, req models.GenerateEliminationsRequest, createdBy int) (int, error)
+	GetEliminationEntryByID(ctx context.Context, id int) (*models.GLEliminationEntryWithLines, error)
+	ListEliminationEntries(ctx context.Context, filters models.ConsolidationFilters) ([]models.GLEliminationEntry, error)
+	VoidEliminationEntry(ctx context.Context, id int) error
+
 	// Integration (for posting from other modules)
 	PostFromAR(ctx context.Context, invoiceID int, createdBy int) (int, error)
 	PostFromAP(ctx context.Context, invoiceID int, createdBy int) (int, error)
 }
 
 type glServiceImpl struct {
-	db postgres.Connection
+	db postgres.Executor
 }
 
-func New(db postgres.Connection) GLService {
+func New(db postgres.Executor) GLService {
 	return &glServiceImpl{db: db}
 }
 
+// inTx runs fn in a transaction. When the service was built on a caller's
+// transaction fn joins it instead.
+func (s *glServiceImpl) inTx(ctx context.Context, fn func(tx *glServiceImpl) error) error {
+	conn, ok := s.db.(postgres.Connection)
+	if !ok {
+		return fn(s)
+	}
+
+	tx, err := conn.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&glServiceImpl{db: tx}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
 // ============================================
 // Account Management
 // ============================================
 
 func (s *glServiceImpl) CreateAccount(ctx context.Context, req models.CreateGLAccountRequest) (int, error) {
-	// Check for duplicate code
+	companyID := tenant.Company(ctx)
+
+	// Check for duplicate code; each company keeps its own chart
 	var exists bool
 	err := s.db.QueryRow(ctx, `
-		SELECT EXISTS(SELECT 1 FROM gl_accounts WHERE account_code = $1)
-	`, req.AccountCode).Scan(&exists)
+		SELECT EXISTS(SELECT 1 FROM gl_accounts WHERE company_id = $1 AND account_code = $2)
+	`, companyID, req.AccountCode).Scan(&exists)
 	if err != nil {
 		return 0, fmt.Errorf("checking duplicate: %w", err)
 	}
@@ -104,13 +138,14 @@ func (s *glServiceImpl) CreateAccount(ctx context.Context, req models.CreateGLAc
 			account_code, account_name, account_type, account_sub_type,
 			parent_id, description, currency, is_postable, is_bank_account,
 			bank_account_id, normal_balance, opening_balance, current_balance,
-			department_id
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $12, $13)
+			department_id, company_id, consolidation_code, is_intercompany, partner_company_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $12, $13, $14, NULLIF($15, ''), $16, $17)
 		RETURNING id
 	`,
 		req.AccountCode, req.AccountName, req.AccountType, req.AccountSubType,
 		req.ParentID, req.Description, req.Currency, req.IsPostable, req.IsBankAccount,
 		req.BankAccountID, req.NormalBalance, req.OpeningBalance, req.DepartmentID,
+		companyID, req.ConsolidationCode, req.IsIntercompany, req.PartnerCompanyID,
 	).Scan(&id)
 	if err != nil {
 		return 0, fmt.Errorf("inserting account: %w", err)
@@ -125,16 +160,18 @@ func (s *glServiceImpl) GetAccountByID(ctx context.Context, id int) (*models.GLA
 	var parentID, bankAccountID, deptID *int
 
 	err := s.db.QueryRow(ctx, `
-		SELECT id, account_code, account_name, account_type, account_sub_type,
+		SELECT id, company_id, account_code, account_name, account_type, account_sub_type,
 		       parent_id, description, currency, is_active, is_postable,
 		       is_bank_account, bank_account_id, normal_balance, opening_balance,
-		       current_balance, budget_amount, department_id, created_at, updated_at
-		FROM gl_accounts WHERE id = $1
-	`, id).Scan(
-		&account.ID, &account.AccountCode, &account.AccountName, &account.AccountType, &subType,
+		       current_balance, budget_amount, department_id,
+		       COALESCE(consolidation_code, ''), is_intercompany, partner_company_id, created_at, updated_at
+		FROM gl_accounts WHERE id = $1 AND company_id = $2
+	`, id, tenant.Company(ctx)).Scan(
+		&account.ID, &account.CompanyID, &account.AccountCode, &account.AccountName, &account.AccountType, &subType,
 		&parentID, &description, &account.Currency, &account.IsActive, &account.IsPostable,
 		&account.IsBankAccount, &bankAccountID, &account.NormalBalance, &account.OpeningBalance,
-		&account.CurrentBalance, &account.BudgetAmount, &deptID, &account.CreatedAt, &account.UpdatedAt,
+		&account.CurrentBalance, &account.BudgetAmount, &deptID,
+		&account.ConsolidationCode, &account.IsIntercompany, &account.PartnerCompanyID, &account.CreatedAt, &account.UpdatedAt,
 	)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -164,7 +201,8 @@ func (s *glServiceImpl) GetAccountByID(ctx context.Context, id int) (*models.GLA
 
 func (s *glServiceImpl) GetAccountByCode(ctx context.Context, code string) (*models.GLAccount, error) {
 	var id int
-	err := s.db.QueryRow(ctx, `SELECT id FROM gl_accounts WHERE account_code = $1`, code).Scan(&id)
+	err := s.db.QueryRow(ctx, `SELECT id FROM gl_accounts WHERE company_id = $1 AND account_code = $2`,
+		tenant.Company(ctx), code).Scan(&id)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrAccountNotFound
@@ -185,11 +223,15 @@ func (s *glServiceImpl) UpdateAccount(ctx context.Context, id int, req models.Up
 			is_active = COALESCE($6, is_active),
 			budget_amount = COALESCE($7, budget_amount),
 			department_id = COALESCE($8, department_id),
+			consolidation_code = COALESCE(NULLIF($10, ''), consolidation_code),
+			is_intercompany = COALESCE($11, is_intercompany),
+			partner_company_id = COALESCE($12, partner_company_id),
 			updated_at = NOW()
-		WHERE id = $9
+		WHERE id = $9 AND company_id = $13
 	`,
 		req.AccountName, req.AccountSubType, req.ParentID, req.Description,
 		req.IsPostable, req.IsActive, req.BudgetAmount, req.DepartmentID, id,
+		req.ConsolidationCode, req.IsIntercompany, req.PartnerCompanyID, tenant.Company(ctx),
 	)
 	if err != nil {
 		return fmt.Errorf("updating account: %w", err)
@@ -211,11 +253,11 @@ func (s *glServiceImpl) DeleteAccount(ctx context.Context, id int) error {
 	}
 	if hasTransactions {
 		// Soft delete - just deactivate
-		_, err = s.db.Exec(ctx, `UPDATE gl_accounts SET is_active = false WHERE id = $1`, id)
+		_, err = s.db.Exec(ctx, `UPDATE gl_accounts SET is_active = false WHERE id = $1 AND company_id = $2`, id, tenant.Company(ctx))
 		return err
 	}
 
-	result, err := s.db.Exec(ctx, `DELETE FROM gl_accounts WHERE id = $1`, id)
+	result, err := s.db.Exec(ctx, `DELETE FROM gl_accounts WHERE id = $1 AND company_id = $2`, id, tenant.Company(ctx))
 	if err != nil {
 		return fmt.Errorf("deleting account: %w", err)
 	}
@@ -227,15 +269,16 @@ func (s *glServiceImpl) DeleteAccount(ctx context.Context, id int) error {
 
 func (s *glServiceImpl) ListAccounts(ctx context.Context, filters models.GLAccountListFilters) ([]models.GLAccount, int64, error) {
 	query := `
-		SELECT id, account_code, account_name, account_type, account_sub_type,
+		SELECT id, company_id, account_code, account_name, account_type, account_sub_type,
 		       parent_id, description, currency, is_active, is_postable,
 		       is_bank_account, bank_account_id, normal_balance, opening_balance,
-		       current_balance, budget_amount, department_id, created_at, updated_at
-		FROM gl_accounts WHERE 1=1
+		       current_balance, budget_amount, department_id,
+		       COALESCE(consolidation_code, ''), is_intercompany, partner_company_id, created_at, updated_at
+		FROM gl_accounts WHERE company_id = $1
 	`
-	countQuery := `SELECT COUNT(*) FROM gl_accounts WHERE 1=1`
-	args := []interface{}{}
-	argNum := 1
+	countQuery := `SELECT COUNT(*) FROM gl_accounts WHERE company_id = $1`
+	args := []interface{}{tenant.Company(ctx)}
+	argNum := 2
 
 	if filters.AccountType != nil {
 		query += fmt.Sprintf(" AND account_type = $%d", argNum)
@@ -299,10 +342,11 @@ func (s *glServiceImpl) ListAccounts(ctx context.Context, filters models.GLAccou
 		var parentID, bankAccountID, deptID *int
 
 		err := rows.Scan(
-			&a.ID, &a.AccountCode, &a.AccountName, &a.AccountType, &subType,
+			&a.ID, &a.CompanyID, &a.AccountCode, &a.AccountName, &a.AccountType, &subType,
 			&parentID, &description, &a.Currency, &a.IsActive, &a.IsPostable,
 			&a.IsBankAccount, &bankAccountID, &a.NormalBalance, &a.OpeningBalance,
-			&a.CurrentBalance, &a.BudgetAmount, &deptID, &a.CreatedAt, &a.UpdatedAt,
+			&a.CurrentBalance, &a.BudgetAmount, &deptID,
+			&a.ConsolidationCode, &a.IsIntercompany, &a.PartnerCompanyID, &a.CreatedAt, &a.UpdatedAt,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("scanning account: %w", err)
@@ -333,14 +377,15 @@ func (s *glServiceImpl) ListAccounts(ctx context.Context, filters models.GLAccou
 func (s *glServiceImpl) GetChartOfAccounts(ctx context.Context) ([]models.GLAccountWithChildren, error) {
 	// Get all active accounts ordered by code
 	rows := s.db.Query(ctx, `
-		SELECT id, account_code, account_name, account_type, account_sub_type,
+		SELECT id, company_id, account_code, account_name, account_type, account_sub_type,
 		       parent_id, description, currency, is_active, is_postable,
 		       is_bank_account, bank_account_id, normal_balance, opening_balance,
-		       current_balance, budget_amount, department_id, created_at, updated_at
+		       current_balance, budget_amount, department_id,
+		       COALESCE(consolidation_code, ''), is_intercompany, partner_company_id, created_at, updated_at
 		FROM gl_accounts
-		WHERE is_active = true
+		WHERE is_active = true AND company_id = $1
 		ORDER BY account_code
-	`)
+	`, tenant.Company(ctx))
 	defer rows.Close()
 
 	accountMap := make(map[int]*models.GLAccountWithChildren)
@@ -352,10 +397,11 @@ func (s *glServiceImpl) GetChartOfAccounts(ctx context.Context) ([]models.GLAcco
 		var parentID, bankAccountID, deptID *int
 
 		err := rows.Scan(
-			&a.ID, &a.AccountCode, &a.AccountName, &a.AccountType, &subType,
+			&a.ID, &a.CompanyID, &a.AccountCode, &a.AccountName, &a.AccountType, &subType,
 			&parentID, &description, &a.Currency, &a.IsActive, &a.IsPostable,
 			&a.IsBankAccount, &bankAccountID, &a.NormalBalance, &a.OpeningBalance,
-			&a.CurrentBalance, &a.BudgetAmount, &deptID, &a.CreatedAt, &a.UpdatedAt,
+			&a.CurrentBalance, &a.BudgetAmount, &deptID,
+			&a.ConsolidationCode, &a.IsIntercompany, &a.PartnerCompanyID, &a.CreatedAt, &a.UpdatedAt,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("scanning account: %w", err)
@@ -429,10 +475,10 @@ func (s *glServiceImpl) CreateFiscalYear(ctx context.Context, req models.CreateF
 
 	var fiscalYearID int
 	err = s.db.QueryRow(ctx, `
-		INSERT INTO gl_fiscal_years (year_code, start_date, end_date)
-		VALUES ($1, $2, $3)
+		INSERT INTO gl_fiscal_years (year_code, start_date, end_date, company_id)
+		VALUES ($1, $2, $3, $4)
 		RETURNING id
-	`, req.YearCode, startDate, endDate).Scan(&fiscalYearID)
+	`, req.YearCode, startDate, endDate, tenant.Company(ctx)).Scan(&fiscalYearID)
 	if err != nil {
 		return 0, fmt.Errorf("creating fiscal year: %w", err)
 	}
@@ -481,10 +527,10 @@ func (s *glServiceImpl) GetFiscalYearByID(ctx context.Context, id int) (*models.
 	var closedAt *time.Time
 
 	err := s.db.QueryRow(ctx, `
-		SELECT id, year_code, start_date, end_date, is_current, is_closed, closed_by, closed_at, created_at
-		FROM gl_fiscal_years WHERE id = $1
-	`, id).Scan(
-		&fy.ID, &fy.YearCode, &fy.StartDate, &fy.EndDate, &fy.IsCurrent, &fy.IsClosed,
+		SELECT id, company_id, year_code, start_date, end_date, is_current, is_closed, closed_by, closed_at, created_at
+		FROM gl_fiscal_years WHERE id = $1 AND company_id = $2
+	`, id, tenant.Company(ctx)).Scan(
+		&fy.ID, &fy.CompanyID, &fy.YearCode, &fy.StartDate, &fy.EndDate, &fy.IsCurrent, &fy.IsClosed,
 		&closedBy, &closedAt, &fy.CreatedAt,
 	)
 	if err != nil {
@@ -503,7 +549,8 @@ func (s *glServiceImpl) GetFiscalYearByID(ctx context.Context, id int) (*models.
 
 func (s *glServiceImpl) GetCurrentFiscalYear(ctx context.Context) (*models.GLFiscalYear, error) {
 	var id int
-	err := s.db.QueryRow(ctx, `SELECT id FROM gl_fiscal_years WHERE is_current = true LIMIT 1`).Scan(&id)
+	err := s.db.QueryRow(ctx, `SELECT id FROM gl_fiscal_years WHERE company_id = $1 AND is_current = true LIMIT 1`,
+		tenant.Company(ctx)).Scan(&id)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrFiscalYearNotFound
@@ -515,9 +562,9 @@ func (s *glServiceImpl) GetCurrentFiscalYear(ctx context.Context) (*models.GLFis
 
 func (s *glServiceImpl) ListFiscalYears(ctx context.Context) ([]models.GLFiscalYear, error) {
 	rows := s.db.Query(ctx, `
-		SELECT id, year_code, start_date, end_date, is_current, is_closed, closed_by, closed_at, created_at
-		FROM gl_fiscal_years ORDER BY start_date DESC
-	`)
+		SELECT id, company_id, year_code, start_date, end_date, is_current, is_closed, closed_by, closed_at, created_at
+		FROM gl_fiscal_years WHERE company_id = $1 ORDER BY start_date DESC
+	`, tenant.Company(ctx))
 	defer rows.Close()
 
 	var years []models.GLFiscalYear
@@ -527,7 +574,7 @@ func (s *glServiceImpl) ListFiscalYears(ctx context.Context) ([]models.GLFiscalY
 		var closedAt *time.Time
 
 		err := rows.Scan(
-			&fy.ID, &fy.YearCode, &fy.StartDate, &fy.EndDate, &fy.IsCurrent, &fy.IsClosed,
+			&fy.ID, &fy.CompanyID, &fy.YearCode, &fy.StartDate, &fy.EndDate, &fy.IsCurrent, &fy.IsClosed,
 			&closedBy, &closedAt, &fy.CreatedAt,
 		)
 		if err != nil {
@@ -556,8 +603,8 @@ func (s *glServiceImpl) CloseFiscalYear(ctx context.Context, id int, closedBy in
 	}
 
 	_, err = s.db.Exec(ctx, `
-		UPDATE gl_fiscal_years SET is_closed = true, closed_by = $1, closed_at = NOW() WHERE id = $2
-	`, closedBy, id)
+		UPDATE gl_fiscal_years SET is_closed = true, closed_by = $1, closed_at = NOW() WHERE id = $2 AND company_id = $3
+	`, closedBy, id, tenant.Company(ctx))
 	return err
 }
 
@@ -569,8 +616,9 @@ func (s *glServiceImpl) GetPeriodByID(ctx context.Context, id int) (*models.GLPe
 	err := s.db.QueryRow(ctx, `
 		SELECT id, fiscal_year_id, period_number, period_name, start_date, end_date,
 		       status, is_adjustment, closed_by, closed_at
-		FROM gl_periods WHERE id = $1
-	`, id).Scan(
+		FROM gl_periods
+		WHERE id = $1 AND fiscal_year_id IN (SELECT id FROM gl_fiscal_years WHERE company_id = $2)
+	`, id, tenant.Company(ctx)).Scan(
 		&p.ID, &p.FiscalYearID, &p.PeriodNumber, &p.PeriodName, &p.StartDate, &p.EndDate,
 		&p.Status, &p.IsAdjustment, &closedBy, &closedAt,
 	)
@@ -594,10 +642,10 @@ func (s *glServiceImpl) GetCurrentPeriod(ctx context.Context) (*models.GLPeriod,
 	err := s.db.QueryRow(ctx, `
 		SELECT p.id FROM gl_periods p
 		JOIN gl_fiscal_years fy ON p.fiscal_year_id = fy.id
-		WHERE fy.is_current = true AND p.status = 'OPEN' AND p.is_adjustment = false
+		WHERE fy.company_id = $2 AND fy.is_current = true AND p.status = 'OPEN' AND p.is_adjustment = false
 		  AND $1 BETWEEN p.start_date AND p.end_date
 		LIMIT 1
-	`, now).Scan(&id)
+	`, now, tenant.Company(ctx)).Scan(&id)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrPeriodNotFound
@@ -611,8 +659,11 @@ func (s *glServiceImpl) ListPeriods(ctx context.Context, fiscalYearID int) ([]mo
 	rows := s.db.Query(ctx, `
 		SELECT id, fiscal_year_id, period_number, period_name, start_date, end_date,
 		       status, is_adjustment, closed_by, closed_at
-		FROM gl_periods WHERE fiscal_year_id = $1 ORDER BY period_number
-	`, fiscalYearID)
+		FROM gl_periods
+		WHERE fiscal_year_id = $1
+		  AND fiscal_year_id IN (SELECT id FROM gl_fiscal_years WHERE company_id = $2)
+		ORDER BY period_number
+	`, fiscalYearID, tenant.Company(ctx))
 	defer rows.Close()
 
 	var periods []models.GLPeriod
@@ -639,15 +690,17 @@ func (s *glServiceImpl) ListPeriods(ctx context.Context, fiscalYearID int) ([]mo
 
 func (s *glServiceImpl) ClosePeriod(ctx context.Context, id int, closedBy int) error {
 	_, err := s.db.Exec(ctx, `
-		UPDATE gl_periods SET status = 'CLOSED', closed_by = $1, closed_at = NOW() WHERE id = $2
-	`, closedBy, id)
+		UPDATE gl_periods SET status = 'CLOSED', closed_by = $1, closed_at = NOW()
+		WHERE id = $2 AND fiscal_year_id IN (SELECT id FROM gl_fiscal_years WHERE company_id = $3)
+	`, closedBy, id, tenant.Company(ctx))
 	return err
 }
 
 func (s *glServiceImpl) ReopenPeriod(ctx context.Context, id int) error {
 	_, err := s.db.Exec(ctx, `
-		UPDATE gl_periods SET status = 'OPEN', closed_by = NULL, closed_at = NULL WHERE id = $1
-	`, id)
+		UPDATE gl_periods SET status = 'OPEN', closed_by = NULL, closed_at = NULL
+		WHERE id = $1 AND fiscal_year_id IN (SELECT id FROM gl_fiscal_years WHERE company_id = $2)
+	`, id, tenant.Company(ctx))
 	return err
 }
 
@@ -656,19 +709,22 @@ func (s *glServiceImpl) ReopenPeriod(ctx context.Context, id int) error {
 // ============================================
 
 func (s *glServiceImpl) CreateJournalEntry(ctx context.Context, req models.CreateJournalEntryRequest, createdBy int) (int, error) {
+	companyID := tenant.Company(ctx)
+
 	entryDate, err := time.Parse("2006-01-02", req.EntryDate)
 	if err != nil {
 		return 0, fmt.Errorf("parsing entry date: %w", err)
 	}
 
-	// Find the period for this date
+	// Find the period for this date in the company's own fiscal calendar
 	var periodID int
 	err = s.db.QueryRow(ctx, `
 		SELECT p.id FROM gl_periods p
 		JOIN gl_fiscal_years fy ON p.fiscal_year_id = fy.id
-		WHERE $1 BETWEEN p.start_date AND p.end_date AND p.status = 'OPEN' AND p.is_adjustment = false
+		WHERE fy.company_id = $2
+		  AND $1 BETWEEN p.start_date AND p.end_date AND p.status = 'OPEN' AND p.is_adjustment = false
 		LIMIT 1
-	`, entryDate).Scan(&periodID)
+	`, entryDate, companyID).Scan(&periodID)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return 0, ErrPeriodClosed
@@ -706,13 +762,13 @@ func (s *glServiceImpl) CreateJournalEntry(ctx context.Context, req models.Creat
 		INSERT INTO gl_journal_entries (
 			journal_number, entry_date, posting_date, period_id, entry_type, status,
 			description, reference, total_debit, total_credit, currency, exchange_rate,
-			auto_reverse, auto_reverse_date, created_by
-		) VALUES ($1, $2, $2, $3, $4, 'DRAFT', $5, $6, $7, $8, $9, $10, $11, $12, $13)
+			auto_reverse, auto_reverse_date, created_by, company_id
+		) VALUES ($1, $2, $2, $3, $4, 'DRAFT', $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
 		RETURNING id
 	`,
 		journalNum, entryDate, periodID, req.EntryType, req.Description, req.Reference,
 		totalDebit, totalCredit, req.Currency, req.ExchangeRate,
-		req.AutoReverse, req.AutoReverseDate, createdBy,
+		req.AutoReverse, req.AutoReverseDate, createdBy, companyID,
 	).Scan(&entryID)
 	if err != nil {
 		return 0, fmt.Errorf("inserting journal entry: %w", err)
@@ -720,9 +776,13 @@ func (s *glServiceImpl) CreateJournalEntry(ctx context.Context, req models.Creat
 
 	// Insert lines
 	for i, line := range req.Lines {
-		// Verify account is postable
+		// Verify account is postable and belongs to the company's chart
 		var isPostable bool
-		err = s.db.QueryRow(ctx, `SELECT is_postable FROM gl_accounts WHERE id = $1`, line.AccountID).Scan(&isPostable)
+		err = s.db.QueryRow(ctx, `SELECT is_postable FROM gl_accounts WHERE id = $1 AND company_id = $2`,
+			line.AccountID, companyID).Scan(&isPostable)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrAccountNotFound
+		}
 		if err != nil {
 			return 0, fmt.Errorf("checking account %d: %w", line.AccountID, err)
 		}
@@ -763,8 +823,8 @@ func (s *glServiceImpl) GetJournalEntryByID(ctx context.Context, id int) (*model
 		FROM gl_journal_entries je
 		JOIN gl_periods p ON je.period_id = p.id
 		JOIN employees e ON je.created_by = e.id
-		WHERE je.id = $1
-	`, id).Scan(
+		WHERE je.id = $1 AND je.company_id = $2
+	`, id, tenant.Company(ctx)).Scan(
 		&entry.Entry.ID, &entry.Entry.JournalNumber, &entry.Entry.EntryDate, &entry.Entry.PostingDate,
 		&entry.Entry.PeriodID, &entry.Entry.EntryType, &entry.Entry.Status, &entry.Entry.Description,
 		&ref, &srcDoc, &srcMod, &srcID, &entry.Entry.TotalDebit, &entry.Entry.TotalCredit,
@@ -844,7 +904,8 @@ func (s *glServiceImpl) GetJournalEntryByID(ctx context.Context, id int) (*model
 func (s *glServiceImpl) UpdateJournalEntry(ctx context.Context, id int, req models.CreateJournalEntryRequest) error {
 	// Check if entry is still draft
 	var status models.JournalEntryStatus
-	err := s.db.QueryRow(ctx, `SELECT status FROM gl_journal_entries WHERE id = $1`, id).Scan(&status)
+	err := s.db.QueryRow(ctx, `SELECT status FROM gl_journal_entries WHERE id = $1 AND company_id = $2`,
+		id, tenant.Company(ctx)).Scan(&status)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return ErrJournalNotFound
@@ -901,7 +962,8 @@ func (s *glServiceImpl) UpdateJournalEntry(ctx context.Context, id int, req mode
 
 func (s *glServiceImpl) DeleteJournalEntry(ctx context.Context, id int) error {
 	var status models.JournalEntryStatus
-	err := s.db.QueryRow(ctx, `SELECT status FROM gl_journal_entries WHERE id = $1`, id).Scan(&status)
+	err := s.db.QueryRow(ctx, `SELECT status FROM gl_journal_entries WHERE id = $1 AND company_id = $2`,
+		id, tenant.Company(ctx)).Scan(&status)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return ErrJournalNotFound
@@ -926,11 +988,11 @@ func (s *glServiceImpl) ListJournalEntries(ctx context.Context, filters models.J
 		       description, reference, source_document, source_module, source_id,
 		       total_debit, total_credit, currency, exchange_rate, is_recurring, recurring_id,
 		       reversed_entry_id, auto_reverse, auto_reverse_date, created_by, posted_by, posted_at, created_at
-		FROM gl_journal_entries WHERE 1=1
+		FROM gl_journal_entries WHERE company_id = $1
 	`
-	countQuery := `SELECT COUNT(*) FROM gl_journal_entries WHERE 1=1`
-	args := []interface{}{}
-	argNum := 1
+	countQuery := `SELECT COUNT(*) FROM gl_journal_entries WHERE company_id = $1`
+	args := []interface{}{tenant.Company(ctx)}
+	argNum := 2
 
 	if filters.PeriodID != nil {
 		query += fmt.Sprintf(" AND period_id = $%d", argNum)
@@ -1037,8 +1099,8 @@ func (s *glServiceImpl) PostJournalEntry(ctx context.Context, id int, postedBy i
 	var status models.JournalEntryStatus
 	var totalDebit, totalCredit float64
 	err := s.db.QueryRow(ctx, `
-		SELECT status, total_debit, total_credit FROM gl_journal_entries WHERE id = $1
-	`, id).Scan(&status, &totalDebit, &totalCredit)
+		SELECT status, total_debit, total_credit FROM gl_journal_entries WHERE id = $1 AND company_id = $2
+	`, id, tenant.Company(ctx)).Scan(&status, &totalDebit, &totalCredit)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return ErrJournalNotFound
@@ -1055,38 +1117,49 @@ func (s *glServiceImpl) PostJournalEntry(ctx context.Context, id int, postedBy i
 		return ErrUnbalancedEntry
 	}
 
-	// Update account balances
+	// Update account balances. The totals are read before updating so the
+	// statements can share a transaction's connection.
+	type accountTotal struct {
+		accountID     int
+		debit, credit float64
+	}
 	rows := s.db.Query(ctx, `
 		SELECT account_id, SUM(debit_amount) as debit, SUM(credit_amount) as credit
 		FROM gl_journal_lines WHERE journal_id = $1
 		GROUP BY account_id
 	`, id)
-	defer rows.Close()
-
+	var totals []accountTotal
 	for rows.Next() {
-		var accountID int
-		var debit, credit float64
-		if err := rows.Scan(&accountID, &debit, &credit); err != nil {
+		var t accountTotal
+		if err := rows.Scan(&t.accountID, &t.debit, &t.credit); err != nil {
+			rows.Close()
 			return fmt.Errorf("scanning line: %w", err)
 		}
+		totals = append(totals, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("getting lines: %w", err)
+	}
 
+	for _, t := range totals {
 		// Get account normal balance
 		var normalBalance string
-		err = s.db.QueryRow(ctx, `SELECT normal_balance FROM gl_accounts WHERE id = $1`, accountID).Scan(&normalBalance)
+		err = s.db.QueryRow(ctx, `SELECT normal_balance FROM gl_accounts WHERE id = $1`, t.accountID).Scan(&normalBalance)
 		if err != nil {
 			return fmt.Errorf("getting account: %w", err)
 		}
 
 		var balanceChange float64
 		if normalBalance == "DEBIT" {
-			balanceChange = debit - credit
+			balanceChange = t.debit - t.credit
 		} else {
-			balanceChange = credit - debit
+			balanceChange = t.credit - t.debit
 		}
 
 		_, err = s.db.Exec(ctx, `
 			UPDATE gl_accounts SET current_balance = current_balance + $1 WHERE id = $2
-		`, balanceChange, accountID)
+		`, balanceChange, t.accountID)
 		if err != nil {
 			return fmt.Errorf("updating account balance: %w", err)
 		}
@@ -1152,7 +1225,8 @@ func (s *glServiceImpl) ReverseJournalEntry(ctx context.Context, id int, reversa
 }
 
 func (s *glServiceImpl) VoidJournalEntry(ctx context.Context, id int) error {
-	_, err := s.db.Exec(ctx, `UPDATE gl_journal_entries SET status = 'VOIDED' WHERE id = $1 AND status = 'DRAFT'`, id)
+	_, err := s.db.Exec(ctx, `UPDATE gl_journal_entries SET status = 'VOIDED' WHERE id = $1 AND company_id = $2 AND status = 'DRAFT'`,
+		id, tenant.Company(ctx))
 	return err
 }
 
@@ -1192,10 +1266,10 @@ func (s *glServiceImpl) GetTrialBalance(ctx context.Context, filters models.Repo
 		FROM gl_accounts a
 		LEFT JOIN gl_journal_lines jl ON a.id = jl.account_id
 		LEFT JOIN gl_journal_entries je ON jl.journal_id = je.id AND je.status = 'POSTED'
-		WHERE a.is_active = true
+		WHERE a.is_active = true AND a.company_id = $1
 	`
-	args := []interface{}{}
-	argNum := 1
+	args := []interface{}{tenant.Company(ctx)}
+	argNum := 2
 
 	if filters.DateFrom != "" {
 		query += fmt.Sprintf(" AND (je.posting_date >= $%d OR je.id IS NULL)", argNum)
@@ -1264,10 +1338,10 @@ func (s *glServiceImpl) GetIncomeStatement(ctx context.Context, filters models.R
 		FROM gl_accounts a
 		LEFT JOIN gl_journal_lines jl ON a.id = jl.account_id
 		LEFT JOIN gl_journal_entries je ON jl.journal_id = je.id AND je.status = 'POSTED'
-		WHERE a.is_active = true AND a.account_type IN ('REVENUE', 'EXPENSE')
+		WHERE a.is_active = true AND a.account_type IN ('REVENUE', 'EXPENSE') AND a.company_id = $1
 	`
-	args := []interface{}{}
-	argNum := 1
+	args := []interface{}{tenant.Company(ctx)}
+	argNum := 2
 
 	if filters.DateFrom != "" {
 		query += fmt.Sprintf(" AND (je.posting_date >= $%d OR je.id IS NULL)", argNum)
@@ -1314,11 +1388,11 @@ func (s *glServiceImpl) GetBalanceSheet(ctx context.Context, filters models.Repo
 	query := `
 		SELECT a.id, a.account_code, a.account_name, a.account_type, a.current_balance
 		FROM gl_accounts a
-		WHERE a.is_active = true AND a.account_type IN ('ASSET', 'LIABILITY', 'EQUITY')
+		WHERE a.is_active = true AND a.account_type IN ('ASSET', 'LIABILITY', 'EQUITY') AND a.company_id = $1
 		ORDER BY a.account_type, a.account_code
 	`
 
-	rows := s.db.Query(ctx, query)
+	rows := s.db.Query(ctx, query, tenant.Company(ctx))
 	defer rows.Close()
 
 	for rows.Next() {
@@ -1411,9 +1485,199 @@ func (s *glServiceImpl) GetAccountActivity(ctx context.Context, accountID int, d
 // Integration
 // ============================================
 
+// PostFromAR posts an AR invoice or credit memo to the GL: receivables
+// against sales, freight and tax. Sales are split by the product's sales
+// account when it is in the company's chart, otherwise they go to the first
+// sales account; freight goes to the company's freight income account, or
+// other income when there is such an account, and tax to accrued
+// liabilities. Credit memos carry negative amounts and so post the other
+// way round.
 func (s *glServiceImpl) PostFromAR(ctx context.Context, invoiceID int, createdBy int) (int, error) {
-	// TODO: Implement AR to GL posting
-	return 0, fmt.Errorf("not implemented")
+	companyID := tenant.Company(ctx)
+
+	var number string
+	var invoiceDate time.Time
+	var total, tax, freight float64
+	err := s.db.QueryRow(ctx, `
+		SELECT invoice_number, invoice_date, total_amount, COALESCE(tax_amount, 0), COALESCE(freight_amount, 0)
+		FROM ar_invoices WHERE id = $1 AND company_id = $2 AND status <> 'VOID'
+	`, invoiceID, companyID).Scan(&number, &invoiceDate, &total, &tax, &freight)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("getting AR invoice: %w", err)
+	}
+
+	var posted bool
+	err = s.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM gl_journal_entries
+			WHERE source_module = 'AR' AND source_id = $1 AND company_id = $2
+			  AND status NOT IN ('VOIDED', 'REVERSED'))
+	`, invoiceID, companyID).Scan(&posted)
+	if err != nil {
+		return 0, fmt.Errorf("checking AR posting: %w", err)
+	}
+	if posted {
+		return 0, ErrJournalPosted
+	}
+
+	receivables, err := s.defaultAccount(ctx, models.GLSubTypeReceivables)
+	if err != nil {
+		return 0, err
+	}
+	sales, err := s.defaultAccount(ctx, models.GLSubTypeSales)
+	if err != nil {
+		return 0, err
+	}
+
+	// Line values by sales account; the sales amount is split in proportion
+	type salesLine struct {
+		accountID int
+		value     float64
+	}
+	rows := s.db.Query(ctx, `
+		SELECT COALESCE(a.id, $3), SUM(l.quantity * l.unit_price)
+		FROM ar_invoice_lines l
+		LEFT JOIN products p ON p.id = l.product_id
+		LEFT JOIN gl_accounts a ON a.id = p.gl_sales_account_id AND a.company_id = $2 AND a.is_postable
+		WHERE l.invoice_id = $1
+		GROUP BY COALESCE(a.id, $3)
+		ORDER BY 1
+	`, invoiceID, companyID, sales)
+	var lines []salesLine
+	var linesValue float64
+	for rows.Next() {
+		var l salesLine
+		if err := rows.Scan(&l.accountID, &l.value); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scanning AR invoice line: %w", err)
+		}
+		lines = append(lines, l)
+		linesValue += l.value
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("getting AR invoice lines: %w", err)
+	}
+
+	freightAccount, err := s.freightAccount(ctx, sales)
+	if err != nil {
+		return 0, err
+	}
+	// Work in cents so the entry balances exactly
+	taxCents, freightCents := cents(tax), cents(freight)
+	salesCents := cents(total) - taxCents - freightCents
+
+	req := models.CreateJournalEntryRequest{
+		EntryDate:   invoiceDate.Format("2006-01-02"),
+		EntryType:   models.JournalTypeAR,
+		Description: "AR " + number,
+		Reference:   number,
+	}
+	addLine := func(accountID int, amount int64, description string, debit bool) {
+		if amount == 0 {
+			return
+		}
+		if amount < 0 {
+			amount, debit = -amount, !debit
+		}
+		line := models.CreateJournalLineRequest{AccountID: accountID, Description: description, Reference: number}
+		if debit {
+			line.DebitAmount = float64(amount) / 100
+		} else {
+			line.CreditAmount = float64(amount) / 100
+		}
+		req.Lines = append(req.Lines, line)
+	}
+	addLine(receivables, cents(total), "Receivable", true)
+	remaining := salesCents
+	for i, l := range lines {
+		amount := remaining
+		if i < len(lines)-1 && linesValue != 0 {
+			amount = int64(math.Round(float64(salesCents) * l.value / linesValue))
+		}
+		addLine(l.accountID, amount, "Sales", false)
+		remaining -= amount
+	}
+	if len(lines) == 0 {
+		addLine(sales, salesCents, "Sales", false)
+	}
+	addLine(freightAccount, freightCents, "Freight", false)
+	if taxCents != 0 {
+		taxAccount, err := s.defaultAccount(ctx, models.GLSubTypeAccruedLiab)
+		if err != nil {
+			return 0, err
+		}
+		addLine(taxAccount, taxCents, "Sales tax", false)
+	}
+
+	var entryID int
+	err = s.inTx(ctx, func(tx *glServiceImpl) error {
+		var err error
+		entryID, err = tx.CreateJournalEntry(ctx, req, createdBy)
+		if err != nil {
+			return err
+		}
+		_, err = tx.db.Exec(ctx, `
+			UPDATE gl_journal_entries SET source_module = 'AR', source_id = $1, source_document = $2
+			WHERE id = $3
+		`, invoiceID, number, entryID)
+		if err != nil {
+			return fmt.Errorf("linking journal entry to AR invoice: %w", err)
+		}
+		return tx.PostJournalEntry(ctx, entryID, createdBy)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return entryID, nil
+}
+
+// defaultAccount is the company's first postable account of a sub type.
+func (s *glServiceImpl) defaultAccount(ctx context.Context, subType models.GLAccountSubType) (int, error) {
+	var id int
+	err := s.db.QueryRow(ctx, `
+		SELECT id FROM gl_accounts
+		WHERE company_id = $1 AND account_sub_type = $2 AND is_postable AND is_active
+		ORDER BY account_code
+		LIMIT 1
+	`, tenant.Company(ctx), subType).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("%w: no %s account", ErrAccountNotFound, subType)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("finding %s account: %w", subType, err)
+	}
+	return id, nil
+}
+
+// freightAccount is the company's freight income account, or else its
+// first other income account, or else sales.
+func (s *glServiceImpl) freightAccount(ctx context.Context, sales int) (int, error) {
+	var id *int
+	err := s.db.QueryRow(ctx, `
+		SELECT a.id FROM companies c
+		LEFT JOIN gl_accounts a ON a.id = c.freight_income_account_id AND a.is_postable AND a.is_active
+		WHERE c.id = $1
+	`, tenant.Company(ctx)).Scan(&id)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("finding freight income account: %w", err)
+	}
+	if id != nil {
+		return *id, nil
+	}
+
+	account, err := s.defaultAccount(ctx, models.GLSubTypeOtherIncome)
+	if errors.Is(err, ErrAccountNotFound) {
+		return sales, nil
+	}
+	return account, err
+}
+
+func cents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
 }
 
 func (s *glServiceImpl) PostFromAP(ctx context.Context, invoiceID int, createdBy int) (int, error) {
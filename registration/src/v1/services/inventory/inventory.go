@@ -2,14 +2,22 @@ package inventory
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/anas-dev-92/FoodHive/core/postgres"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/query"
 	"github.com/jackc/pgx/v5"
 )
 
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// QuarantineLocation holds stock that may not be sold until QA releases it,
+// such as customer returns awaiting inspection. Allocation skips it.
+const QuarantineLocation = "QUARANTINE"
+
 // ============================================
 // Service Interface
 // ============================================
@@ -29,11 +37,29 @@ type InventoryService interface {
 
 	// Inventory Operations
 	Receive(ctx context.Context, req *ReceiveRequest, createdBy int) (int, error)
+	Return(ctx context.Context, req *ReceiveRequest, createdBy int) (int, error)
 	Adjust(ctx context.Context, req *models.AdjustInventoryRequest, createdBy int) error
+	Dispose(ctx context.Context, req *DisposeRequest, createdBy int) (float64, error)
 	Transfer(ctx context.Context, req *models.TransferInventoryRequest, createdBy int) error
 
+	// Sales Allocation
+	Allocate(ctx context.Context, req *AllocateRequest) ([]StockAllocation, error)
+	ReleaseAllocation(ctx context.Context, inventoryID int, quantity float64) error
+	ShipAllocation(ctx context.Context, req *ShipAllocationRequest, createdBy int) (float64, error)
+
+	// Product Reservations
+	CreateReservation(ctx context.Context, req *models.CreateReservationRequest, createdBy int) (int, error)
+	GetReservation(ctx context.Context, id int) (*models.ProductReservation, error)
+	ListReservations(ctx context.Context, filters *models.ReservationFilters) ([]models.ProductReservation, int64, error)
+	UpdateReservation(ctx context.Context, id int, req *models.UpdateReservationRequest) error
+	ReleaseReservation(ctx context.Context, id int, releasedBy int) error
+	GetAvailability(ctx context.Context, req *models.AvailabilityRequest) (*models.StockAvailability, error)
+	GetExpiringReservations(ctx context.Context, daysToExpiry int, warehouseID *int) ([]models.ExpiringReservation, error)
+	ConsumeReservations(ctx context.Context, req *ConsumeReservationsRequest) error
+	ReturnReservations(ctx context.Context, orderLineID int, keep float64) error
+
 	// Transaction History
-	GetTransactions(ctx context.Context, productID, warehouseID *int, limit int) ([]models.InventoryTransaction, error)
+	GetTransactions(ctx context.Context, productID, warehouseID *int, params *query.Params) ([]models.InventoryTransaction, error)
 }
 
 // ============================================
@@ -55,6 +81,51 @@ type ReceiveRequest struct {
 	Notes           string     `json:"notes,omitempty"`
 }
 
+// DisposeRequest writes stock off as destroyed.
+type DisposeRequest struct {
+	ProductID       int
+	WarehouseID     int
+	LocationCode    string
+	LotNumber       string
+	Quantity        float64
+	ReferenceType   string
+	ReferenceID     int
+	ReferenceNumber string
+	Notes           string
+}
+
+// AllocateRequest asks for stock of one product in one warehouse.
+type AllocateRequest struct {
+	ProductID   int
+	WarehouseID int
+	Quantity    float64
+	LotNumber   string    // Only this lot when set
+	UsableOn    time.Time // Stock expiring before this date is skipped
+
+	// Stock reserved for the customer, the customer's group, the sales rep
+	// or the order may be used
+	CustomerID int
+	SalesRepID int
+	OrderID    int
+}
+
+// StockAllocation is the part of an allocation taken from one inventory row.
+type StockAllocation struct {
+	InventoryID  int
+	LocationCode string
+	LotNumber    string
+	Quantity     float64
+}
+
+// ShipAllocationRequest takes allocated stock out of an inventory row.
+type ShipAllocationRequest struct {
+	InventoryID     int
+	Quantity        float64
+	ReferenceType   string
+	ReferenceID     int
+	ReferenceNumber string
+}
+
 // ============================================
 // Service Implementation
 // ============================================
@@ -67,6 +138,26 @@ func New(db postgres.Executor) InventoryService {
 	return &inventoryServiceImpl{db: db}
 }
 
+// inTx runs fn in a transaction. When the service was built on a caller's
+// transaction, fn runs in that one.
+func (s *inventoryServiceImpl) inTx(ctx context.Context, fn func(tx *inventoryServiceImpl) error) error {
+	conn, ok := s.db.(postgres.Connection)
+	if !ok {
+		return fn(s)
+	}
+
+	tx, err := conn.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&inventoryServiceImpl{db: tx}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
 // ============================================
 // Inventory Queries
 // ============================================
@@ -305,7 +396,11 @@ func (s *inventoryServiceImpl) GetProductSummary(ctx context.Context, productID
 			   COALESCE(SUM(i.quantity_allocated), 0) as total_allocated,
 			   COALESCE(SUM(i.quantity_on_order), 0) as total_on_order,
 			   COALESCE(SUM(i.quantity_available), 0) as total_available,
-			   COALESCE(AVG(i.average_cost), 0) as avg_cost
+			   COALESCE(AVG(i.average_cost), 0) as avg_cost,
+			   COALESCE((
+				   SELECT SUM(r.quantity - r.quantity_consumed) FROM product_reservations r
+				   WHERE r.product_id = p.id AND ` + openReservation + `
+			   ), 0) as total_reserved
 		FROM products p
 		LEFT JOIN inventory i ON p.id = i.product_id
 		WHERE p.id = $1
@@ -315,7 +410,7 @@ func (s *inventoryServiceImpl) GetProductSummary(ctx context.Context, productID
 	err := s.db.QueryRow(ctx, query, productID).Scan(
 		&summary.ProductID, &summary.ProductSKU, &summary.ProductName,
 		&summary.TotalOnHand, &summary.TotalAllocated, &summary.TotalOnOrder,
-		&summary.TotalAvailable, &summary.AverageCost,
+		&summary.TotalAvailable, &summary.AverageCost, &summary.TotalReserved,
 	)
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -331,7 +426,11 @@ func (s *inventoryServiceImpl) GetProductSummary(ctx context.Context, productID
 		SELECT i.warehouse_id, w.name,
 			   SUM(i.quantity_on_hand) as on_hand,
 			   SUM(i.quantity_allocated) as allocated,
-			   SUM(i.quantity_available) as available
+			   SUM(i.quantity_available) as available,
+			   COALESCE((
+				   SELECT SUM(r.quantity - r.quantity_consumed) FROM product_reservations r
+				   WHERE r.product_id = $1 AND r.warehouse_id = i.warehouse_id AND ` + openReservation + `
+			   ), 0) as reserved
 		FROM inventory i
 		JOIN warehouses w ON i.warehouse_id = w.id
 		WHERE i.product_id = $1
@@ -343,7 +442,7 @@ func (s *inventoryServiceImpl) GetProductSummary(ctx context.Context, productID
 
 	for rows.Next() {
 		var wq models.WarehouseQuantity
-		err := rows.Scan(&wq.WarehouseID, &wq.WarehouseName, &wq.OnHand, &wq.Allocated, &wq.Available)
+		err := rows.Scan(&wq.WarehouseID, &wq.WarehouseName, &wq.OnHand, &wq.Allocated, &wq.Available, &wq.Reserved)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan warehouse quantity: %w", err)
 		}
@@ -418,6 +517,16 @@ func (s *inventoryServiceImpl) GetExpiringInventory(ctx context.Context, daysToE
 // ============================================
 
 func (s *inventoryServiceImpl) Receive(ctx context.Context, req *ReceiveRequest, createdBy int) (int, error) {
+	return s.receive(ctx, req, models.TxReceive, createdBy)
+}
+
+// Return puts goods a customer sent back into stock. It books like a receipt
+// but is logged as a RETURN.
+func (s *inventoryServiceImpl) Return(ctx context.Context, req *ReceiveRequest, createdBy int) (int, error) {
+	return s.receive(ctx, req, models.TxReturn, createdBy)
+}
+
+func (s *inventoryServiceImpl) receive(ctx context.Context, req *ReceiveRequest, txType models.InventoryTransactionType, createdBy int) (int, error) {
 	// Upsert inventory record
 	query := `
 		INSERT INTO inventory (
@@ -446,7 +555,7 @@ func (s *inventoryServiceImpl) Receive(ctx context.Context, req *ReceiveRequest,
 
 	// Log transaction
 	s.logTransaction(ctx, req.ProductID, req.WarehouseID, req.LocationCode,
-		models.TxReceive, req.Quantity, req.LotNumber, req.UnitCost,
+		txType, req.Quantity, req.LotNumber, req.UnitCost,
 		req.ReferenceType, req.ReferenceID, req.ReferenceNumber, req.Notes, createdBy)
 
 	return id, nil
@@ -486,6 +595,37 @@ func (s *inventoryServiceImpl) Adjust(ctx context.Context, req *models.AdjustInv
 	return nil
 }
 
+// Dispose removes destroyed stock from a location and logs the DISPOSE. It
+// returns the row's average cost.
+func (s *inventoryServiceImpl) Dispose(ctx context.Context, req *DisposeRequest, createdBy int) (float64, error) {
+	var unitCost float64
+	err := s.db.QueryRow(ctx, `
+		UPDATE inventory SET
+			quantity_on_hand = quantity_on_hand - $1,
+			last_movement_date = NOW(),
+			updated_at = NOW()
+		WHERE product_id = $2 AND warehouse_id = $3
+			AND COALESCE(location_code, '') = $4
+			AND COALESCE(lot_number, '') = $5
+			AND quantity_on_hand - quantity_allocated >= $1
+		RETURNING COALESCE(average_cost, 0)`,
+		req.Quantity, req.ProductID, req.WarehouseID, req.LocationCode, req.LotNumber,
+	).Scan(&unitCost)
+	if err == pgx.ErrNoRows {
+		return 0, fmt.Errorf("%w: product %d needs %.3f to dispose at %q in warehouse %d",
+			ErrInsufficientStock, req.ProductID, req.Quantity, req.LocationCode, req.WarehouseID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to dispose inventory: %w", err)
+	}
+
+	s.logTransaction(ctx, req.ProductID, req.WarehouseID, req.LocationCode,
+		models.TxDispose, -req.Quantity, req.LotNumber, unitCost,
+		req.ReferenceType, req.ReferenceID, req.ReferenceNumber, req.Notes, createdBy)
+
+	return unitCost, nil
+}
+
 func (s *inventoryServiceImpl) Transfer(ctx context.Context, req *models.TransferInventoryRequest, createdBy int) error {
 	// Deduct from source
 	deductQuery := `
@@ -544,13 +684,165 @@ func (s *inventoryServiceImpl) Transfer(ctx context.Context, req *models.Transfe
 	return nil
 }
 
+// ============================================
+// Sales Allocation
+// ============================================
+
+// Allocate earmarks stock for an order, earliest expiry first. All of the
+// product's rows in the warehouse stay locked until the caller's transaction
+// ends, so concurrent orders for the same product queue instead of both
+// taking the last units. Callers allocating several products must do so in
+// product ID order to avoid deadlocks. Stock reserved for another party is
+// left alone; the caller uses up the party's own reservations with
+// ConsumeReservations.
+func (s *inventoryServiceImpl) Allocate(ctx context.Context, req *AllocateRequest) ([]StockAllocation, error) {
+	_, err := s.db.Exec(ctx, `
+		SELECT id FROM inventory
+		WHERE product_id = $1 AND warehouse_id = $2
+		ORDER BY id
+		FOR UPDATE`, req.ProductID, req.WarehouseID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock inventory: %w", err)
+	}
+
+	// Usable stock of any lot, less what is reserved for someone else
+	var usable, reserved float64
+	err = s.db.QueryRow(ctx, `
+		SELECT COALESCE(SUM(quantity_available), 0)
+		FROM inventory
+		WHERE product_id = $1 AND warehouse_id = $2 AND quantity_available > 0
+			AND (expiry_date IS NULL OR expiry_date >= $3)
+			AND location_code IS DISTINCT FROM $4`,
+		req.ProductID, req.WarehouseID, req.UsableOn, QuarantineLocation).Scan(&usable)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get available stock: %w", err)
+	}
+	err = s.db.QueryRow(ctx, `SELECT `+ReservedForOthers("$1", "$2", "$3", "$4", "$5"),
+		req.ProductID, req.WarehouseID, req.CustomerID, req.SalesRepID, req.OrderID).Scan(&reserved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reserved stock: %w", err)
+	}
+
+	rows := s.db.Query(ctx, `
+		SELECT id, COALESCE(location_code, ''), COALESCE(lot_number, ''), quantity_available
+		FROM inventory
+		WHERE product_id = $1 AND warehouse_id = $2 AND quantity_available > 0
+			AND (expiry_date IS NULL OR expiry_date >= $3)
+			AND ($4 = '' OR lot_number = $4)
+			AND location_code IS DISTINCT FROM $5
+		ORDER BY expiry_date NULLS LAST, production_date, id`,
+		req.ProductID, req.WarehouseID, req.UsableOn, req.LotNumber, QuarantineLocation)
+
+	var candidates []StockAllocation
+	var inLot float64
+	for rows.Next() {
+		var a StockAllocation
+		if err := rows.Scan(&a.InventoryID, &a.LocationCode, &a.LotNumber, &a.Quantity); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan inventory: %w", err)
+		}
+		candidates = append(candidates, a)
+		inLot += a.Quantity
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get inventory: %w", err)
+	}
+
+	allocatable := min(inLot, usable-reserved)
+	if allocatable < req.Quantity-0.0005 {
+		return nil, fmt.Errorf("%w: product %d needs %.3f, %.3f available in warehouse %d",
+			ErrInsufficientStock, req.ProductID, req.Quantity, max(allocatable, 0), req.WarehouseID)
+	}
+
+	var allocations []StockAllocation
+	remaining := req.Quantity
+	for _, c := range candidates {
+		if remaining <= 0 {
+			break
+		}
+		take := min(c.Quantity, remaining)
+		_, err := s.db.Exec(ctx, `
+			UPDATE inventory SET quantity_allocated = quantity_allocated + $1, updated_at = NOW()
+			WHERE id = $2`, take, c.InventoryID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to allocate inventory: %w", err)
+		}
+		c.Quantity = take
+		allocations = append(allocations, c)
+		remaining -= take
+	}
+
+	return allocations, nil
+}
+
+func (s *inventoryServiceImpl) ReleaseAllocation(ctx context.Context, inventoryID int, quantity float64) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE inventory SET quantity_allocated = GREATEST(quantity_allocated - $1, 0), updated_at = NOW()
+		WHERE id = $2`, quantity, inventoryID)
+	if err != nil {
+		return fmt.Errorf("failed to release allocation: %w", err)
+	}
+	return nil
+}
+
+// ShipAllocation relieves on-hand and allocated stock for goods leaving the
+// warehouse and logs the SHIP. It returns the row's average cost.
+func (s *inventoryServiceImpl) ShipAllocation(ctx context.Context, req *ShipAllocationRequest, createdBy int) (float64, error) {
+	var productID, warehouseID int
+	var locationCode, lotNumber string
+	var unitCost float64
+	err := s.db.QueryRow(ctx, `
+		UPDATE inventory SET
+			quantity_on_hand = quantity_on_hand - $1,
+			quantity_allocated = GREATEST(quantity_allocated - $1, 0),
+			last_movement_date = NOW(),
+			updated_at = NOW()
+		WHERE id = $2
+		RETURNING product_id, warehouse_id, COALESCE(location_code, ''), COALESCE(lot_number, ''),
+			COALESCE(average_cost, 0)`,
+		req.Quantity, req.InventoryID,
+	).Scan(&productID, &warehouseID, &locationCode, &lotNumber, &unitCost)
+	if err != nil {
+		return 0, fmt.Errorf("failed to ship inventory: %w", err)
+	}
+
+	s.logTransaction(ctx, productID, warehouseID, locationCode,
+		models.TxShip, -req.Quantity, lotNumber, unitCost,
+		req.ReferenceType, req.ReferenceID, req.ReferenceNumber, "", createdBy)
+
+	return unitCost, nil
+}
+
 // ============================================
 // Transaction History
 // ============================================
 
-func (s *inventoryServiceImpl) GetTransactions(ctx context.Context, productID, warehouseID *int, limit int) ([]models.InventoryTransaction, error) {
-	if limit <= 0 || limit > 500 {
-		limit = 100
+// transactionListSchema whitelists the fields clients may filter and sort on.
+// The history grows without bound, so it is always read with keyset cursors.
+var transactionListSchema = query.Schema{
+	Fields: map[string]query.Field{
+		"id":               {Column: "id", Type: query.Int, Sortable: true},
+		"product_id":       {Column: "product_id", Type: query.Int},
+		"warehouse_id":     {Column: "warehouse_id", Type: query.Int},
+		"location_code":    {Column: "location_code", Type: query.String},
+		"transaction_type": {Column: "transaction_type::text", Type: query.String},
+		"quantity":         {Column: "quantity", Type: query.Number},
+		"lot_number":       {Column: "lot_number", Type: query.String},
+		"reference_type":   {Column: "reference_type", Type: query.String},
+		"reference_number": {Column: "reference_number", Type: query.String},
+		"created_by":       {Column: "created_by", Type: query.Int},
+		"created_at":       {Column: "created_at", Type: query.Time, Sortable: true},
+	},
+	Default: []query.Sort{{Field: "created_at", Desc: true}},
+	Key:     "id",
+}
+
+func (s *inventoryServiceImpl) GetTransactions(ctx context.Context, productID, warehouseID *int, params *query.Params) ([]models.InventoryTransaction, error) {
+	params = query.Default(params, 0, 0)
+	params.Page = 0
+	if params.Limit == 0 {
+		params.Limit = query.MaxLimit
 	}
 
 	whereClause := "WHERE 1=1"
@@ -569,30 +861,36 @@ func (s *inventoryServiceImpl) GetTransactions(ctx context.Context, productID, w
 		argNum++
 	}
 
-	query := fmt.Sprintf(`
+	q, err := query.Build(transactionListSchema, params, argNum)
+	if err != nil {
+		return nil, err
+	}
+
+	listQuery := fmt.Sprintf(`
 		SELECT id, product_id, warehouse_id, location_code, transaction_type,
 			   quantity, lot_number, unit_cost, reference_type, reference_id,
-			   reference_number, notes, created_by, created_at
+			   reference_number, notes, created_by, created_at, %s
 		FROM inventory_transactions
+		%s%s%s
 		%s
-		ORDER BY created_at DESC
-		LIMIT $%d`, whereClause, argNum)
-
-	args = append(args, limit)
+		%s`, q.Cursor, whereClause, q.Where, q.Keyset, q.OrderBy, q.Limit)
 
-	rows := s.db.Query(ctx, query, args...)
+	args = append(args, q.Args...)
+	rows := s.db.Query(ctx, listQuery, append(args, q.PageArgs...)...)
 	defer rows.Close()
 
 	var transactions []models.InventoryTransaction
+	var cursors []string
 	for rows.Next() {
 		var tx models.InventoryTransaction
 		var locCode, lotNum, refType, refNum, notes *string
 		var refID *int
+		var cursor string
 
 		err := rows.Scan(
 			&tx.ID, &tx.ProductID, &tx.WarehouseID, &locCode, &tx.TransactionType,
 			&tx.Quantity, &lotNum, &tx.UnitCost, &refType, &refID,
-			&refNum, &notes, &tx.CreatedBy, &tx.CreatedAt,
+			&refNum, &notes, &tx.CreatedBy, &tx.CreatedAt, &cursor,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan transaction: %w", err)
@@ -618,9 +916,13 @@ func (s *inventoryServiceImpl) GetTransactions(ctx context.Context, productID, w
 		}
 
 		transactions = append(transactions, tx)
+		cursors = append(cursors, cursor)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list transactions: %w", err)
 	}
 
-	return transactions, nil
+	return transactions[:params.Trim(cursors)], nil
 }
 
 // ============================================
@@ -645,7 +947,7 @@ func (s *inventoryServiceImpl) logTransaction(
 			product_id, warehouse_id, location_code, transaction_type,
 			quantity, lot_number, unit_cost, reference_type, reference_id,
 			reference_number, notes, created_by
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, 0))`
 
 	s.db.Exec(ctx, query,
 		productID, warehouseID, locationCode, txType,
@@ -0,0 +1,544 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrReservationNotFound = errors.New("reservation not found")
+	ErrReservationClosed   = errors.New("reservation is no longer active")
+	ErrReservedForNotFound = errors.New("customer, customer group or sales rep to reserve for not found")
+	ErrProductNotFound     = errors.New("product not found")
+	ErrWarehouseNotFound   = errors.New("warehouse not found")
+	ErrBelowConsumed       = errors.New("reservation quantity cannot be less than what orders have used")
+)
+
+// ============================================
+// Product Reservations
+// ============================================
+//
+// A reservation holds stock of a product in a warehouse for a customer, a
+// customer group or a sales rep until its expiry date. While it is open,
+// allocation leaves the rest of it alone for everyone else; orders for the
+// party allocate as usual and then use the reservation up, so the party's
+// orders draw on it before free stock. Stock an order line releases goes
+// back to the reservations it used.
+
+// openReservation matches reservations r still holding stock.
+const openReservation = `r.released_at IS NULL AND (r.expiry_date IS NULL OR r.expiry_date >= CURRENT_DATE)
+	AND r.quantity > r.quantity_consumed`
+
+// heldFor matches reservations r held for the customer or the customer's
+// group, the sales rep (the customer's own rep when none is given) or the
+// order. The arguments are SQL expressions; 0 matches nothing.
+func heldFor(customerID, salesRepID, orderID string) string {
+	return fmt.Sprintf(`COALESCE(
+		(r.reserved_for_type = 'CUSTOMER' AND r.reserved_for_id = %[1]s)
+		OR (r.reserved_for_type = 'CUSTOMER_GROUP'
+			AND r.reserved_for_id = (SELECT customer_group_id FROM customers WHERE id = %[1]s))
+		OR (r.reserved_for_type = 'SALES_REP'
+			AND r.reserved_for_id = COALESCE(NULLIF(%[2]s, 0), (SELECT sales_rep_id FROM customers WHERE id = %[1]s)))
+		OR (r.reserved_for_type = 'SALES_ORDER' AND r.reserved_for_id = %[3]s), false)`,
+		customerID, salesRepID, orderID)
+}
+
+// ReservedForOthers returns SQL for the open reserved quantity of a product
+// in a warehouse held for anyone but the customer, the sales rep and the
+// order given, for queries showing what a party can order. The arguments
+// are SQL expressions, such as columns or placeholders; 0 stands for none.
+func ReservedForOthers(productID, warehouseID, customerID, salesRepID, orderID string) string {
+	return fmt.Sprintf(`COALESCE((
+		SELECT SUM(r.quantity - r.quantity_consumed)
+		FROM product_reservations r
+		WHERE r.product_id = %s AND r.warehouse_id = %s AND %s
+		  AND NOT %s
+	), 0)`, productID, warehouseID, openReservation, heldFor(customerID, salesRepID, orderID))
+}
+
+// reservationStatus works a reservation r's status out.
+const reservationStatus = `CASE WHEN r.released_at IS NOT NULL THEN 'RELEASED'
+	WHEN r.quantity_consumed >= COALESCE(r.quantity, 0) THEN 'CONSUMED'
+	WHEN r.expiry_date < CURRENT_DATE THEN 'EXPIRED'
+	ELSE 'ACTIVE' END`
+
+// reservationSelect reads reservations r with their product, warehouse and
+// party names and the status worked out from them.
+const reservationSelect = `
+	SELECT r.id, r.product_id, p.sku, p.name, r.warehouse_id, w.name,
+		   r.reserved_for_type, r.reserved_for_id,
+		   COALESCE(CASE r.reserved_for_type
+			   WHEN 'CUSTOMER' THEN c.name
+			   WHEN 'CUSTOMER_GROUP' THEN cg.name
+			   WHEN 'SALES_REP' THEN e.english_name
+			   WHEN 'SALES_ORDER' THEN so.order_number
+		   END, ''),
+		   COALESCE(r.quantity, 0), r.quantity_consumed,
+		   GREATEST(COALESCE(r.quantity, 0) - r.quantity_consumed, 0), r.expiry_date,
+		   ` + reservationStatus + `,
+		   COALESCE(r.notes, ''), r.created_by, r.created_at, r.released_by, r.released_at`
+
+const reservationFrom = `
+	FROM product_reservations r
+	JOIN products p ON p.id = r.product_id
+	JOIN warehouses w ON w.id = r.warehouse_id
+	LEFT JOIN customers c ON r.reserved_for_type = 'CUSTOMER' AND c.id = r.reserved_for_id
+	LEFT JOIN customer_groups cg ON r.reserved_for_type = 'CUSTOMER_GROUP' AND cg.id = r.reserved_for_id
+	LEFT JOIN employees e ON r.reserved_for_type = 'SALES_REP' AND e.id = r.reserved_for_id
+	LEFT JOIN sales_orders so ON r.reserved_for_type = 'SALES_ORDER' AND so.id = r.reserved_for_id`
+
+func scanReservation(row pgx.Row, r *models.ProductReservation) error {
+	return row.Scan(
+		&r.ID, &r.ProductID, &r.ProductSKU, &r.ProductName, &r.WarehouseID, &r.WarehouseName,
+		&r.ReservedForType, &r.ReservedForID, &r.ReservedForName,
+		&r.Quantity, &r.QuantityConsumed, &r.QuantityRemaining, &r.ExpiryDate, &r.Status,
+		&r.Notes, &r.CreatedBy, &r.CreatedAt, &r.ReleasedBy, &r.ReleasedAt,
+	)
+}
+
+// CreateReservation sets stock aside for the party. It must come out of
+// stock nobody has allocated or reserved yet.
+func (s *inventoryServiceImpl) CreateReservation(ctx context.Context, req *models.CreateReservationRequest, createdBy int) (int, error) {
+	var id int
+	err := s.inTx(ctx, func(tx *inventoryServiceImpl) error {
+		if err := tx.checkReservationParty(ctx, req); err != nil {
+			return err
+		}
+		if err := tx.checkUnreserved(ctx, req.ProductID, req.WarehouseID, 0, req.Quantity); err != nil {
+			return err
+		}
+
+		err := tx.db.QueryRow(ctx, `
+			INSERT INTO product_reservations (
+				company_id, product_id, warehouse_id, reserved_for_type, reserved_for_id,
+				quantity, expiry_date, notes, created_by
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, 0))
+			RETURNING id`,
+			tenant.Company(ctx), req.ProductID, req.WarehouseID, req.ReservedForType, req.ReservedForID,
+			req.Quantity, req.ExpiryDate, req.Notes, createdBy,
+		).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("failed to create reservation: %w", err)
+		}
+		return nil
+	})
+	return id, err
+}
+
+// checkReservationParty makes sure the product, warehouse and party exist.
+func (s *inventoryServiceImpl) checkReservationParty(ctx context.Context, req *models.CreateReservationRequest) error {
+	var exists bool
+	s.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM products WHERE id = $1)`, req.ProductID).Scan(&exists)
+	if !exists {
+		return ErrProductNotFound
+	}
+	exists = false
+	s.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM warehouses WHERE id = $1)`, req.WarehouseID).Scan(&exists)
+	if !exists {
+		return ErrWarehouseNotFound
+	}
+
+	exists = false
+	switch req.ReservedForType {
+	case models.ReservedForCustomer:
+		s.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM customers WHERE id = $1 AND company_id = $2)`,
+			req.ReservedForID, tenant.Company(ctx)).Scan(&exists)
+	case models.ReservedForCustomerGroup:
+		s.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM customer_groups WHERE id = $1)`, req.ReservedForID).Scan(&exists)
+	case models.ReservedForSalesRep:
+		s.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM employees WHERE id = $1)`, req.ReservedForID).Scan(&exists)
+	}
+	if !exists {
+		return ErrReservedForNotFound
+	}
+	return nil
+}
+
+// checkUnreserved locks the product's stock in the warehouse, as Allocate
+// does, and makes sure quantity more can be reserved on top of every other
+// open reservation but the one given.
+func (s *inventoryServiceImpl) checkUnreserved(ctx context.Context, productID, warehouseID, reservationID int, quantity float64) error {
+	_, err := s.db.Exec(ctx, `
+		SELECT id FROM inventory
+		WHERE product_id = $1 AND warehouse_id = $2
+		ORDER BY id
+		FOR UPDATE`, productID, warehouseID)
+	if err != nil {
+		return fmt.Errorf("failed to lock inventory: %w", err)
+	}
+
+	var unreserved float64
+	err = s.db.QueryRow(ctx, fmt.Sprintf(`
+		SELECT COALESCE((
+				   SELECT SUM(quantity_available) FROM inventory
+				   WHERE product_id = $1 AND warehouse_id = $2 AND quantity_available > 0
+					 AND (expiry_date IS NULL OR expiry_date >= CURRENT_DATE)
+					 AND location_code IS DISTINCT FROM $4
+			   ), 0)
+			 - COALESCE((
+				   SELECT SUM(r.quantity - r.quantity_consumed) FROM product_reservations r
+				   WHERE r.product_id = $1 AND r.warehouse_id = $2 AND r.id <> $3 AND %s
+			   ), 0)`, openReservation),
+		productID, warehouseID, reservationID, QuarantineLocation).Scan(&unreserved)
+	if err != nil {
+		return fmt.Errorf("failed to get unreserved stock: %w", err)
+	}
+	if unreserved < quantity-0.0005 {
+		return fmt.Errorf("%w: product %d needs %.3f, %.3f unreserved in warehouse %d",
+			ErrInsufficientStock, productID, quantity, max(unreserved, 0), warehouseID)
+	}
+	return nil
+}
+
+func (s *inventoryServiceImpl) GetReservation(ctx context.Context, id int) (*models.ProductReservation, error) {
+	var r models.ProductReservation
+	err := scanReservation(s.db.QueryRow(ctx, reservationSelect+reservationFrom+`
+		WHERE r.id = $1 AND r.company_id = $2`, id, tenant.Company(ctx)), &r)
+	if err == pgx.ErrNoRows {
+		return nil, ErrReservationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reservation: %w", err)
+	}
+	return &r, nil
+}
+
+func (s *inventoryServiceImpl) ListReservations(ctx context.Context, filters *models.ReservationFilters) ([]models.ProductReservation, int64, error) {
+	if filters.Page < 1 {
+		filters.Page = 1
+	}
+	if filters.PageSize < 1 || filters.PageSize > 100 {
+		filters.PageSize = 20
+	}
+
+	whereClause := "WHERE r.company_id = $1"
+	args := []interface{}{tenant.Company(ctx)}
+	argNum := 2
+
+	if filters.ProductID != nil {
+		whereClause += fmt.Sprintf(" AND r.product_id = $%d", argNum)
+		args = append(args, *filters.ProductID)
+		argNum++
+	}
+	if filters.WarehouseID != nil {
+		whereClause += fmt.Sprintf(" AND r.warehouse_id = $%d", argNum)
+		args = append(args, *filters.WarehouseID)
+		argNum++
+	}
+	if filters.ReservedForType != "" {
+		whereClause += fmt.Sprintf(" AND r.reserved_for_type = $%d", argNum)
+		args = append(args, filters.ReservedForType)
+		argNum++
+	}
+	if filters.ReservedForID != nil {
+		whereClause += fmt.Sprintf(" AND r.reserved_for_id = $%d", argNum)
+		args = append(args, *filters.ReservedForID)
+		argNum++
+	}
+	if filters.Status != "" {
+		whereClause += fmt.Sprintf(" AND %s = $%d", reservationStatus, argNum)
+		args = append(args, filters.Status)
+		argNum++
+	}
+
+	var total int64
+	err := s.db.QueryRow(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM product_reservations r %s`, whereClause), args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count reservations: %w", err)
+	}
+
+	offset := (filters.Page - 1) * filters.PageSize
+	rows := s.db.Query(ctx, fmt.Sprintf(`%s %s
+		%s
+		ORDER BY r.expiry_date NULLS LAST, p.name, r.id
+		LIMIT $%d OFFSET $%d`, reservationSelect, reservationFrom, whereClause, argNum, argNum+1),
+		append(args, filters.PageSize, offset)...)
+	defer rows.Close()
+
+	reservations := []models.ProductReservation{}
+	for rows.Next() {
+		var r models.ProductReservation
+		if err := scanReservation(rows, &r); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan reservation: %w", err)
+		}
+		reservations = append(reservations, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list reservations: %w", err)
+	}
+	return reservations, total, nil
+}
+
+// UpdateReservation changes an active reservation. More stock must be
+// unreserved to raise it, and it cannot go below what orders have used.
+func (s *inventoryServiceImpl) UpdateReservation(ctx context.Context, id int, req *models.UpdateReservationRequest) error {
+	return s.inTx(ctx, func(tx *inventoryServiceImpl) error {
+		r, err := tx.GetReservation(ctx, id)
+		if err != nil {
+			return err
+		}
+		if r.Status == models.ReservationReleased || r.Status == models.ReservationExpired {
+			return ErrReservationClosed
+		}
+
+		if req.Quantity != nil {
+			if *req.Quantity < r.QuantityConsumed {
+				return fmt.Errorf("%w: %.3f used", ErrBelowConsumed, r.QuantityConsumed)
+			}
+			if *req.Quantity > r.Quantity {
+				if err := tx.checkUnreserved(ctx, r.ProductID, r.WarehouseID, r.ID, *req.Quantity-r.QuantityConsumed); err != nil {
+					return err
+				}
+			}
+		}
+
+		_, err = tx.db.Exec(ctx, `
+			UPDATE product_reservations SET
+				quantity = COALESCE($2, quantity),
+				expiry_date = COALESCE($3::date, expiry_date),
+				notes = CASE WHEN $4::text IS NULL THEN notes ELSE NULLIF($4, '') END,
+				updated_at = NOW()
+			WHERE id = $1`, id, req.Quantity, req.ExpiryDate, req.Notes)
+		if err != nil {
+			return fmt.Errorf("failed to update reservation: %w", err)
+		}
+		return nil
+	})
+}
+
+// ReleaseReservation gives up what is left of a reservation before it
+// expires, freeing the stock for everyone.
+func (s *inventoryServiceImpl) ReleaseReservation(ctx context.Context, id int, releasedBy int) error {
+	result, err := s.db.Exec(ctx, `
+		UPDATE product_reservations SET released_at = NOW(), released_by = NULLIF($3, 0), updated_at = NOW()
+		WHERE id = $1 AND company_id = $2 AND released_at IS NULL`, id, tenant.Company(ctx), releasedBy)
+	if err != nil {
+		return fmt.Errorf("failed to release reservation: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		if _, err := s.GetReservation(ctx, id); err != nil {
+			return err
+		}
+		return ErrReservationClosed
+	}
+	return nil
+}
+
+// ============================================
+// Reservation Availability
+// ============================================
+
+// GetAvailability shows what the customer, or the sales rep's customers,
+// can be sold of a product in a warehouse: usable stock less what is
+// reserved for anyone else.
+func (s *inventoryServiceImpl) GetAvailability(ctx context.Context, req *models.AvailabilityRequest) (*models.StockAvailability, error) {
+	availability := &models.StockAvailability{
+		ProductID:   req.ProductID,
+		WarehouseID: req.WarehouseID,
+		CustomerID:  req.CustomerID,
+		SalesRepID:  req.SalesRepID,
+	}
+	var customerID, salesRepID int
+	if req.CustomerID != nil {
+		customerID = *req.CustomerID
+	}
+	if req.SalesRepID != nil {
+		salesRepID = *req.SalesRepID
+	}
+
+	err := s.db.QueryRow(ctx, fmt.Sprintf(`
+		SELECT COALESCE(SUM(quantity_on_hand), 0), COALESCE(SUM(quantity_allocated), 0),
+			   COALESCE(SUM(quantity_available) FILTER (
+				   WHERE quantity_available > 0
+					 AND (expiry_date IS NULL OR expiry_date >= CURRENT_DATE)
+					 AND location_code IS DISTINCT FROM $5
+			   ), 0),
+			   COALESCE((
+				   SELECT SUM(r.quantity - r.quantity_consumed) FROM product_reservations r
+				   WHERE r.product_id = $1 AND r.warehouse_id = $2 AND %s AND %s
+			   ), 0),
+			   %s
+		FROM inventory
+		WHERE product_id = $1 AND warehouse_id = $2`,
+		openReservation, heldFor("$3", "$4", "0"), ReservedForOthers("$1", "$2", "$3", "$4", "0")),
+		req.ProductID, req.WarehouseID, customerID, salesRepID, QuarantineLocation,
+	).Scan(&availability.OnHand, &availability.Allocated, &availability.Available,
+		&availability.ReservedForParty, &availability.ReservedForOthers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get availability: %w", err)
+	}
+
+	availability.AvailableToParty = max(availability.Available-availability.ReservedForOthers, 0)
+	return availability, nil
+}
+
+// GetExpiringReservations lists active reservations expiring within the
+// days given that their party has not used up, soonest first, so they can
+// be extended, followed up or released.
+func (s *inventoryServiceImpl) GetExpiringReservations(ctx context.Context, daysToExpiry int, warehouseID *int) ([]models.ExpiringReservation, error) {
+	whereClause := fmt.Sprintf(`WHERE r.company_id = $1 AND %s AND r.expiry_date <= CURRENT_DATE + $2`, openReservation)
+	args := []interface{}{tenant.Company(ctx), daysToExpiry}
+
+	if warehouseID != nil {
+		whereClause += " AND r.warehouse_id = $3"
+		args = append(args, *warehouseID)
+	}
+
+	rows := s.db.Query(ctx, fmt.Sprintf(`%s, r.expiry_date - CURRENT_DATE %s
+		%s
+		ORDER BY r.expiry_date, p.name, r.id`, reservationSelect, reservationFrom, whereClause), args...)
+	defer rows.Close()
+
+	reservations := []models.ExpiringReservation{}
+	for rows.Next() {
+		var e models.ExpiringReservation
+		r := &e.ProductReservation
+		err := rows.Scan(
+			&r.ID, &r.ProductID, &r.ProductSKU, &r.ProductName, &r.WarehouseID, &r.WarehouseName,
+			&r.ReservedForType, &r.ReservedForID, &r.ReservedForName,
+			&r.Quantity, &r.QuantityConsumed, &r.QuantityRemaining, &r.ExpiryDate, &r.Status,
+			&r.Notes, &r.CreatedBy, &r.CreatedAt, &r.ReleasedBy, &r.ReleasedAt,
+			&e.DaysToExpiry,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan reservation: %w", err)
+		}
+		reservations = append(reservations, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get expiring reservations: %w", err)
+	}
+	return reservations, nil
+}
+
+// ============================================
+// Reservation Consumption
+// ============================================
+
+// ConsumeReservationsRequest uses up the party's reservations for stock
+// just allocated to an order line.
+type ConsumeReservationsRequest struct {
+	ProductID   int
+	WarehouseID int
+	CustomerID  int
+	SalesRepID  int
+	OrderID     int
+	OrderLineID int
+	Quantity    float64
+}
+
+// ConsumeReservations takes the allocated quantity off the party's open
+// reservations, those expiring soonest first, and records what the line
+// used. Call it in the transaction that allocated the stock, so the
+// product's inventory rows are still locked.
+func (s *inventoryServiceImpl) ConsumeReservations(ctx context.Context, req *ConsumeReservationsRequest) error {
+	rows := s.db.Query(ctx, fmt.Sprintf(`
+		SELECT r.id, r.quantity - r.quantity_consumed
+		FROM product_reservations r
+		WHERE r.product_id = $1 AND r.warehouse_id = $2 AND %s AND %s
+		ORDER BY r.expiry_date NULLS LAST, r.id
+		FOR UPDATE`, openReservation, heldFor("$3", "$4", "$5")),
+		req.ProductID, req.WarehouseID, req.CustomerID, req.SalesRepID, req.OrderID)
+
+	type openQuantity struct {
+		id        int
+		remaining float64
+	}
+	var open []openQuantity
+	for rows.Next() {
+		var o openQuantity
+		if err := rows.Scan(&o.id, &o.remaining); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan reservation: %w", err)
+		}
+		open = append(open, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get reservations: %w", err)
+	}
+
+	remaining := req.Quantity
+	for _, o := range open {
+		if remaining <= 0 {
+			break
+		}
+		take := min(o.remaining, remaining)
+		_, err := s.db.Exec(ctx, `
+			UPDATE product_reservations SET quantity_consumed = quantity_consumed + $2, updated_at = NOW()
+			WHERE id = $1`, o.id, take)
+		if err != nil {
+			return fmt.Errorf("failed to consume reservation: %w", err)
+		}
+		_, err = s.db.Exec(ctx, `
+			INSERT INTO product_reservation_consumptions (reservation_id, order_id, order_line_id, quantity)
+			VALUES ($1, $2, $3, $4)`, o.id, req.OrderID, req.OrderLineID, take)
+		if err != nil {
+			return fmt.Errorf("failed to record reservation use: %w", err)
+		}
+		remaining -= take
+	}
+	return nil
+}
+
+// ReturnReservations gives back what an order line took from reservations
+// beyond the quantity it keeps, newest use first, once the line has
+// released stock. Reservations that have since expired or been released
+// stay closed.
+func (s *inventoryServiceImpl) ReturnReservations(ctx context.Context, orderLineID int, keep float64) error {
+	rows := s.db.Query(ctx, `
+		SELECT id, reservation_id, quantity
+		FROM product_reservation_consumptions
+		WHERE order_line_id = $1
+		ORDER BY id DESC`, orderLineID)
+
+	type consumption struct {
+		id            int
+		reservationID int
+		quantity      float64
+	}
+	var used []consumption
+	var total float64
+	for rows.Next() {
+		var c consumption
+		if err := rows.Scan(&c.id, &c.reservationID, &c.quantity); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan reservation use: %w", err)
+		}
+		used = append(used, c)
+		total += c.quantity
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get reservation uses: %w", err)
+	}
+
+	excess := total - max(keep, 0)
+	for _, c := range used {
+		if excess <= 0.0005 {
+			break
+		}
+		give := min(c.quantity, excess)
+		_, err := s.db.Exec(ctx, `
+			UPDATE product_reservations SET quantity_consumed = GREATEST(quantity_consumed - $2, 0), updated_at = NOW()
+			WHERE id = $1`, c.reservationID, give)
+		if err != nil {
+			return fmt.Errorf("failed to return reservation: %w", err)
+		}
+		if give < c.quantity {
+			_, err = s.db.Exec(ctx, `UPDATE product_reservation_consumptions SET quantity = quantity - $2 WHERE id = $1`, c.id, give)
+		} else {
+			_, err = s.db.Exec(ctx, `DELETE FROM product_reservation_consumptions WHERE id = $1`, c.id)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update reservation use: %w", err)
+		}
+		excess -= give
+	}
+	return nil
+}
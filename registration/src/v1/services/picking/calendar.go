@@ -0,0 +1,482 @@
+package picking
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+// ============================================
+// Route Calendars
+// ============================================
+//
+// A route runs on its delivery weekdays, except on company holidays.
+// Exceptions cancel or add single runs and override both. Orders for a run
+// close at its cutoff, a number of lead days before the run.
+
+// routeRunHorizonDays is how far ahead a route's next run is looked for.
+const routeRunHorizonDays = 60
+
+func (s *pickingServiceImpl) GetRouteCalendar(ctx context.Context, routeID int) (*models.RouteCalendar, error) {
+	if err := s.routeExists(ctx, routeID); err != nil {
+		return nil, err
+	}
+
+	days, err := s.deliveryDays(ctx, routeID)
+	if err != nil {
+		return nil, err
+	}
+	exceptions, err := s.routeExceptions(ctx, routeID, today(), time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	calendar := &models.RouteCalendar{
+		RouteID:      routeID,
+		DeliveryDays: []models.RouteDeliveryDay{},
+		Exceptions:   exceptions,
+	}
+	for dow := 0; dow <= 6; dow++ {
+		if day, ok := days[dow]; ok {
+			calendar.DeliveryDays = append(calendar.DeliveryDays, day)
+		}
+	}
+	return calendar, nil
+}
+
+// SetRouteCalendar replaces the route's delivery weekdays.
+func (s *pickingServiceImpl) SetRouteCalendar(ctx context.Context, routeID int, req *models.SetRouteCalendarRequest) error {
+	if err := s.routeExists(ctx, routeID); err != nil {
+		return err
+	}
+
+	return s.inTx(ctx, func(tx *pickingServiceImpl) error {
+		if _, err := tx.db.Exec(ctx, `DELETE FROM route_delivery_days WHERE route_id = $1`, routeID); err != nil {
+			return fmt.Errorf("failed to clear route calendar: %w", err)
+		}
+		for _, day := range req.DeliveryDays {
+			leadDays := 1
+			if day.LeadDays != nil {
+				leadDays = *day.LeadDays
+			}
+			_, err := tx.db.Exec(ctx, `
+				INSERT INTO route_delivery_days (route_id, day_of_week, cutoff_time, lead_days)
+				VALUES ($1, $2, NULLIF($3, '')::time, $4)`,
+				routeID, day.DayOfWeek, day.CutoffTime, leadDays)
+			if err != nil {
+				return fmt.Errorf("failed to set route calendar: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// AddRouteException cancels or adds the route's run on a date, replacing
+// any earlier exception for that date.
+func (s *pickingServiceImpl) AddRouteException(ctx context.Context, routeID int, req *models.CreateRouteExceptionRequest, createdBy int) (int, error) {
+	if err := s.routeExists(ctx, routeID); err != nil {
+		return 0, err
+	}
+
+	leadDays := 1
+	if req.LeadDays != nil {
+		leadDays = *req.LeadDays
+	}
+
+	var id int
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO route_calendar_exceptions (route_id, run_date, is_running, cutoff_time, lead_days, reason, created_by)
+		VALUES ($1, $2, $3, NULLIF($4, '')::time, $5, NULLIF($6, ''), NULLIF($7, 0))
+		ON CONFLICT (route_id, run_date) DO UPDATE SET
+			is_running = EXCLUDED.is_running, cutoff_time = EXCLUDED.cutoff_time,
+			lead_days = EXCLUDED.lead_days, reason = EXCLUDED.reason,
+			created_by = EXCLUDED.created_by, created_at = NOW()
+		RETURNING id`,
+		routeID, req.RunDate, req.IsRunning, req.CutoffTime, leadDays, req.Reason, createdBy,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add route exception: %w", err)
+	}
+	return id, nil
+}
+
+func (s *pickingServiceImpl) DeleteRouteException(ctx context.Context, id int) error {
+	result, err := s.db.Exec(ctx, `DELETE FROM route_calendar_exceptions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete route exception: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrExceptionNotFound
+	}
+	return nil
+}
+
+func (s *pickingServiceImpl) CreateDeliveryHoliday(ctx context.Context, req *models.CreateDeliveryHolidayRequest) (int, error) {
+	var id int
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO delivery_holidays (company_id, holiday_date, name)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (company_id, holiday_date) DO UPDATE SET name = EXCLUDED.name
+		RETURNING id`,
+		tenant.Company(ctx), req.HolidayDate, req.Name,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create delivery holiday: %w", err)
+	}
+	return id, nil
+}
+
+// ListDeliveryHolidays returns the company's holidays in a year, or from
+// today on when year is zero.
+func (s *pickingServiceImpl) ListDeliveryHolidays(ctx context.Context, year int) ([]models.DeliveryHoliday, error) {
+	rows := s.db.Query(ctx, `
+		SELECT id, holiday_date, name
+		FROM delivery_holidays
+		WHERE company_id = $1
+		  AND CASE WHEN $2 = 0 THEN holiday_date >= CURRENT_DATE
+				   ELSE EXTRACT(YEAR FROM holiday_date) = $2 END
+		ORDER BY holiday_date`, tenant.Company(ctx), year)
+	defer rows.Close()
+
+	holidays := []models.DeliveryHoliday{}
+	for rows.Next() {
+		var h models.DeliveryHoliday
+		if err := rows.Scan(&h.ID, &h.HolidayDate, &h.Name); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery holiday: %w", err)
+		}
+		holidays = append(holidays, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list delivery holidays: %w", err)
+	}
+	return holidays, nil
+}
+
+func (s *pickingServiceImpl) DeleteDeliveryHoliday(ctx context.Context, id int) error {
+	result, err := s.db.Exec(ctx, `DELETE FROM delivery_holidays WHERE id = $1 AND company_id = $2`,
+		id, tenant.Company(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to delete delivery holiday: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrHolidayNotFound
+	}
+	return nil
+}
+
+// NextRouteRun finds the route's first run that an order placed at from
+// can still make.
+func (s *pickingServiceImpl) NextRouteRun(ctx context.Context, routeID int, from time.Time) (*models.RouteRun, error) {
+	return s.nextRun(ctx, routeID, from, true)
+}
+
+// RouteRunsOn reports whether the route makes a run on the date, cutoffs
+// aside.
+func (s *pickingServiceImpl) RouteRunsOn(ctx context.Context, routeID int, date time.Time) (bool, error) {
+	run, err := s.nextRun(ctx, routeID, date, false)
+	if errors.Is(err, ErrNoRouteRun) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return time.Time(run.RunDate).Format("2006-01-02") == date.Format("2006-01-02"), nil
+}
+
+// nextRun walks the route's calendar day by day from the date of from.
+// Without cutoffs every run from that date on is taken.
+func (s *pickingServiceImpl) nextRun(ctx context.Context, routeID int, from time.Time, enforceCutoff bool) (*models.RouteRun, error) {
+	var active bool
+	err := s.db.QueryRow(ctx, `SELECT COALESCE(is_active, true) FROM routes WHERE id = $1`, routeID).Scan(&active)
+	if err == pgx.ErrNoRows {
+		return nil, ErrRouteNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get route: %w", err)
+	}
+	if !active {
+		return nil, fmt.Errorf("%w: route is not active", ErrNoRouteRun)
+	}
+
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.Local)
+	end := start.AddDate(0, 0, routeRunHorizonDays)
+
+	days, err := s.deliveryDays(ctx, routeID)
+	if err != nil {
+		return nil, err
+	}
+	exceptions, err := s.routeExceptions(ctx, routeID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	holidays, err := s.holidayDates(ctx, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	byDate := make(map[string]models.RouteCalendarException, len(exceptions))
+	for _, e := range exceptions {
+		byDate[time.Time(e.RunDate).Format("2006-01-02")] = e
+	}
+
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		key := d.Format("2006-01-02")
+
+		var cutoffTime string
+		var leadDays int
+		if e, ok := byDate[key]; ok {
+			if !e.IsRunning {
+				continue
+			}
+			cutoffTime, leadDays = e.CutoffTime, e.LeadDays
+		} else if day, ok := days[int(d.Weekday())]; ok && !holidays[key] {
+			cutoffTime, leadDays = day.CutoffTime, day.LeadDays
+		} else {
+			continue
+		}
+
+		cutoff := runCutoff(d, leadDays, cutoffTime)
+		if enforceCutoff && from.After(cutoff) {
+			continue
+		}
+		return &models.RouteRun{
+			RouteID: routeID,
+			RunDate: models.CustomDate(time.Date(d.Year(), d.Month(), d.Day(), 0, 0, 0, 0, time.UTC)),
+			Cutoff:  models.CustomDateTime(cutoff),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: none in the next %d days", ErrNoRouteRun, routeRunHorizonDays)
+}
+
+// runCutoff is when orders for a run on date close: at cutoffTime, leadDays
+// before it, or the end of that day when no time is set.
+func runCutoff(date time.Time, leadDays int, cutoffTime string) time.Time {
+	day := date.AddDate(0, 0, -leadDays)
+	t, err := time.Parse("15:04", cutoffTime)
+	if err != nil {
+		return day.AddDate(0, 0, 1).Add(-time.Second)
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), t.Hour(), t.Minute(), 0, 0, time.Local)
+}
+
+// RerouteOrders moves the open orders of a route run, typically one that
+// was cancelled, to another run. Orders already on a pick list stay put and
+// are reported for dispatch to handle.
+func (s *pickingServiceImpl) RerouteOrders(ctx context.Context, req *models.RerouteOrdersRequest) (*models.RerouteResult, error) {
+	runDate, err := time.Parse("2006-01-02", req.RunDate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid run date: %w", err)
+	}
+	if err := s.routeExists(ctx, req.FromRouteID); err != nil {
+		return nil, err
+	}
+
+	toRoute := req.FromRouteID
+	if req.ToRouteID != nil {
+		toRoute = *req.ToRouteID
+	}
+
+	var toDate time.Time
+	if req.ToDate != "" {
+		if err := s.routeExists(ctx, toRoute); err != nil {
+			return nil, err
+		}
+		if toDate, err = time.Parse("2006-01-02", req.ToDate); err != nil {
+			return nil, fmt.Errorf("invalid target date: %w", err)
+		}
+	} else {
+		// Dispatch is moving the orders, so the customer's cutoff is not
+		// what decides the run
+		from := runDate
+		if t := today(); from.Before(t) {
+			from = t
+		}
+		run, err := s.nextRun(ctx, toRoute, from, false)
+		if err != nil {
+			return nil, err
+		}
+		toDate = time.Time(run.RunDate)
+	}
+
+	if toRoute == req.FromRouteID && toDate.Equal(runDate) {
+		return nil, fmt.Errorf("%w: the orders are already on route %d for %s", ErrSameRun, toRoute, req.RunDate)
+	}
+
+	result := &models.RerouteResult{
+		ToRouteID: toRoute,
+		ToDate:    models.CustomDate(toDate),
+		Moved:     []models.ReroutedOrder{},
+		Skipped:   []models.ReroutedOrder{},
+	}
+
+	err = s.inTx(ctx, func(tx *pickingServiceImpl) error {
+		rows := tx.db.Query(ctx, `
+			SELECT id, order_number, status
+			FROM sales_orders
+			WHERE route_id = $1 AND requested_ship_date = $2 AND company_id = $3
+			  AND status IN ('DRAFT', 'CONFIRMED', 'PICKING')
+			  AND (cardinality($4::int[]) = 0 OR id = ANY($4))
+			ORDER BY order_number
+			FOR UPDATE`,
+			req.FromRouteID, runDate, tenant.Company(ctx), orderIDs(req.OrderIDs))
+		for rows.Next() {
+			var o models.ReroutedOrder
+			if err := rows.Scan(&o.OrderID, &o.OrderNumber, &o.Status); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan route order: %w", err)
+			}
+			if o.Status == string(models.OrderStatusPicking) {
+				result.Skipped = append(result.Skipped, o)
+			} else {
+				result.Moved = append(result.Moved, o)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to find route orders: %w", err)
+		}
+
+		for _, o := range result.Moved {
+			_, err := tx.db.Exec(ctx, `
+				UPDATE sales_orders SET route_id = $1, requested_ship_date = $2, updated_at = NOW()
+				WHERE id = $3`, toRoute, toDate, o.OrderID)
+			if err != nil {
+				return fmt.Errorf("failed to reroute order %s: %w", o.OrderNumber, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// orderIDs keeps an empty filter from being sent as NULL.
+func orderIDs(ids []int) []int {
+	if ids == nil {
+		return []int{}
+	}
+	return ids
+}
+
+func (s *pickingServiceImpl) routeExists(ctx context.Context, routeID int) error {
+	var exists bool
+	err := s.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM routes WHERE id = $1)`, routeID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to get route: %w", err)
+	}
+	if !exists {
+		return ErrRouteNotFound
+	}
+	return nil
+}
+
+// deliveryDays returns the route's delivery weekdays keyed by day of week.
+func (s *pickingServiceImpl) deliveryDays(ctx context.Context, routeID int) (map[int]models.RouteDeliveryDay, error) {
+	rows := s.db.Query(ctx, `
+		SELECT route_id, day_of_week, COALESCE(to_char(cutoff_time, 'HH24:MI'), ''), lead_days
+		FROM route_delivery_days
+		WHERE route_id = $1`, routeID)
+	defer rows.Close()
+
+	days := make(map[int]models.RouteDeliveryDay)
+	for rows.Next() {
+		var d models.RouteDeliveryDay
+		if err := rows.Scan(&d.RouteID, &d.DayOfWeek, &d.CutoffTime, &d.LeadDays); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery day: %w", err)
+		}
+		days[d.DayOfWeek] = d
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get route calendar: %w", err)
+	}
+	return days, nil
+}
+
+// routeExceptions returns the route's exceptions from one date, up to
+// another unless it is zero.
+func (s *pickingServiceImpl) routeExceptions(ctx context.Context, routeID int, from, to time.Time) ([]models.RouteCalendarException, error) {
+	var until *time.Time
+	if !to.IsZero() {
+		until = &to
+	}
+
+	rows := s.db.Query(ctx, `
+		SELECT id, route_id, run_date, is_running, COALESCE(to_char(cutoff_time, 'HH24:MI'), ''),
+			   lead_days, COALESCE(reason, ''), created_by, created_at
+		FROM route_calendar_exceptions
+		WHERE route_id = $1 AND run_date >= $2::date AND ($3::date IS NULL OR run_date <= $3::date)
+		ORDER BY run_date`, routeID, from.Format("2006-01-02"), until)
+	defer rows.Close()
+
+	exceptions := []models.RouteCalendarException{}
+	for rows.Next() {
+		var e models.RouteCalendarException
+		err := rows.Scan(&e.ID, &e.RouteID, &e.RunDate, &e.IsRunning, &e.CutoffTime,
+			&e.LeadDays, &e.Reason, &e.CreatedBy, &e.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan route exception: %w", err)
+		}
+		exceptions = append(exceptions, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get route exceptions: %w", err)
+	}
+	return exceptions, nil
+}
+
+// holidayDates returns the company's holidays in a date range.
+func (s *pickingServiceImpl) holidayDates(ctx context.Context, from, to time.Time) (map[string]bool, error) {
+	rows := s.db.Query(ctx, `
+		SELECT holiday_date FROM delivery_holidays
+		WHERE company_id = $1 AND holiday_date BETWEEN $2::date AND $3::date`,
+		tenant.Company(ctx), from.Format("2006-01-02"), to.Format("2006-01-02"))
+	defer rows.Close()
+
+	holidays := make(map[string]bool)
+	for rows.Next() {
+		var d time.Time
+		if err := rows.Scan(&d); err != nil {
+			return nil, fmt.Errorf("failed to scan delivery holiday: %w", err)
+		}
+		holidays[d.Format("2006-01-02")] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get delivery holidays: %w", err)
+	}
+	return holidays, nil
+}
+
+// today is the current date at midnight UTC, as DATE columns are read.
+func today() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// inTx runs fn in a transaction. When the service was built on a caller's
+// transaction, fn joins it instead.
+func (s *pickingServiceImpl) inTx(ctx context.Context, fn func(tx *pickingServiceImpl) error) error {
+	conn, ok := s.db.(postgres.Connection)
+	if !ok {
+		return fn(s)
+	}
+
+	tx, err := conn.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&pickingServiceImpl{db: tx}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
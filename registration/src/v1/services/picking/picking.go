@@ -2,14 +2,24 @@ package picking
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/anas-dev-92/FoodHive/core/postgres"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	inventoryService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/inventory"
 	"github.com/jackc/pgx/v5"
 )
 
+var (
+	ErrRouteNotFound     = errors.New("route not found")
+	ErrExceptionNotFound = errors.New("route exception not found")
+	ErrHolidayNotFound   = errors.New("delivery holiday not found")
+	ErrNoRouteRun        = errors.New("route has no upcoming run")
+	ErrSameRun           = errors.New("orders are already on that route run")
+)
+
 // ============================================
 // Service Interface
 // ============================================
@@ -28,6 +38,18 @@ type PickingService interface {
 	DeleteRouteStop(ctx context.Context, stopID int) error
 	ReorderStops(ctx context.Context, routeID int, stopOrders []int) error // Stop IDs in new order
 
+	// Route Calendars
+	GetRouteCalendar(ctx context.Context, routeID int) (*models.RouteCalendar, error)
+	SetRouteCalendar(ctx context.Context, routeID int, req *models.SetRouteCalendarRequest) error
+	AddRouteException(ctx context.Context, routeID int, req *models.CreateRouteExceptionRequest, createdBy int) (int, error)
+	DeleteRouteException(ctx context.Context, id int) error
+	CreateDeliveryHoliday(ctx context.Context, req *models.CreateDeliveryHolidayRequest) (int, error)
+	ListDeliveryHolidays(ctx context.Context, year int) ([]models.DeliveryHoliday, error)
+	DeleteDeliveryHoliday(ctx context.Context, id int) error
+	NextRouteRun(ctx context.Context, routeID int, from time.Time) (*models.RouteRun, error)
+	RouteRunsOn(ctx context.Context, routeID int, date time.Time) (bool, error)
+	RerouteOrders(ctx context.Context, req *models.RerouteOrdersRequest) (*models.RerouteResult, error)
+
 	// Pick Lists
 	CreatePickList(ctx context.Context, req *models.CreatePickListRequest, createdBy int) (int, error)
 	GeneratePickListForRoute(ctx context.Context, req *models.GeneratePickListRequest, createdBy int) (int, error)
@@ -118,7 +140,7 @@ func (s *pickingServiceImpl) GetRoute(ctx context.Context, id int) (*models.Rout
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("route not found")
+			return nil, ErrRouteNotFound
 		}
 		return nil, fmt.Errorf("failed to get route: %w", err)
 	}
@@ -431,17 +453,31 @@ func (s *pickingServiceImpl) GeneratePickListForRoute(ctx context.Context, req *
 	return s.CreatePickList(ctx, createReq, createdBy)
 }
 
+// addOrderToPickList adds what the order's lines still need, less what is
+// backordered, and moves a confirmed order to PICKING so it ships what is
+// picked.
 func (s *pickingServiceImpl) addOrderToPickList(ctx context.Context, pickListID, orderID int) error {
 	query := `
 		INSERT INTO pick_list_lines (pick_list_id, order_id, order_line_id, product_id, quantity_ordered)
-		SELECT $1, sol.order_id, sol.id, sol.product_id, sol.quantity_ordered - sol.quantity_shipped
+		SELECT $1, sol.order_id, sol.id, sol.product_id,
+			   sol.quantity_ordered - sol.quantity_shipped - sol.quantity_backordered
 		FROM sales_order_lines sol
-		WHERE sol.order_id = $2 AND sol.quantity_ordered > sol.quantity_shipped`
+		WHERE sol.order_id = $2 AND sol.quantity_ordered - sol.quantity_shipped - sol.quantity_backordered > 0`
 
-	_, err := s.db.Exec(ctx, query, pickListID, orderID)
+	result, err := s.db.Exec(ctx, query, pickListID, orderID)
 	if err != nil {
 		return fmt.Errorf("failed to add order to pick list: %w", err)
 	}
+	if result.RowsAffected() == 0 {
+		return nil
+	}
+
+	_, err = s.db.Exec(ctx, `
+		UPDATE sales_orders SET status = 'PICKING', updated_at = NOW()
+		WHERE id = $1 AND status = 'CONFIRMED'`, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to start picking order: %w", err)
+	}
 	return nil
 }
 
@@ -605,12 +641,15 @@ func (s *pickingServiceImpl) CompletePicking(ctx context.Context, pickListID int
 		return fmt.Errorf("pick list not found or not in progress")
 	}
 
-	// Update sales order lines with picked quantities
-	s.db.Exec(ctx, `
+	// Picked quantities are what the orders ship
+	_, err = s.db.Exec(ctx, `
 		UPDATE sales_order_lines sol
-		SET quantity_shipped = quantity_shipped + pll.quantity_picked
+		SET quantity_picked = sol.quantity_picked + pll.quantity_picked
 		FROM pick_list_lines pll
 		WHERE pll.order_line_id = sol.id AND pll.pick_list_id = $1`, pickListID)
+	if err != nil {
+		return fmt.Errorf("failed to record picked quantities: %w", err)
+	}
 
 	return nil
 }
@@ -626,6 +665,23 @@ func (s *pickingServiceImpl) CancelPickList(ctx context.Context, pickListID int)
 	if result.RowsAffected() == 0 {
 		return fmt.Errorf("pick list not found or cannot be cancelled")
 	}
+
+	// Orders with nothing picked and no other pick list go back to CONFIRMED
+	_, err = s.db.Exec(ctx, `
+		UPDATE sales_orders so SET status = 'CONFIRMED', updated_at = NOW()
+		WHERE so.status = 'PICKING'
+		  AND so.id IN (SELECT order_id FROM pick_list_lines WHERE pick_list_id = $1)
+		  AND NOT EXISTS (
+			  SELECT 1 FROM pick_list_lines pll
+			  JOIN pick_lists pl ON pll.pick_list_id = pl.id
+			  WHERE pll.order_id = so.id AND pl.status IN ('PENDING', 'IN_PROGRESS')
+		  )
+		  AND NOT EXISTS (
+			  SELECT 1 FROM sales_order_lines WHERE order_id = so.id AND quantity_picked > 0
+		  )`, pickListID)
+	if err != nil {
+		return fmt.Errorf("failed to release orders from pick list: %w", err)
+	}
 	return nil
 }
 
@@ -769,9 +825,10 @@ func (s *pickingServiceImpl) GetSuggestedPicking(ctx context.Context, productID,
 		SELECT location_code, lot_number, expiry_date, quantity_available
 		FROM inventory
 		WHERE product_id = $1 AND warehouse_id = $2 AND quantity_available > 0
+			AND location_code IS DISTINCT FROM $3
 		ORDER BY expiry_date NULLS LAST, production_date, lot_number`
 
-	rows := s.db.Query(ctx, query, productID, warehouseID)
+	rows := s.db.Query(ctx, query, productID, warehouseID, inventoryService.QuarantineLocation)
 	defer rows.Close()
 
 	var suggestions []SuggestedPickLocation
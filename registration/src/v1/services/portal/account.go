@@ -0,0 +1,69 @@
+package portal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	arService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/ar"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+// ============================================
+// Account
+// ============================================
+//
+// Invoices, statements and aging come from AR for the user's customer.
+// Users limited to some ship-tos see the invoices for orders shipped there;
+// statements and aging cover the whole account, so only users with access
+// to it see them. Draft invoices are not shown.
+
+func (s *portalServiceImpl) ListInvoices(ctx context.Context, user *models.PortalIdentity, filters *models.ARInvoiceListFilters) ([]models.ARInvoiceWithDetails, int64, error) {
+	filters.CustomerID = &user.CustomerID
+	filters.PostedOnly = true
+	filters.ShipToIDs = nil
+	if !user.AllShipTos {
+		filters.ShipToIDs = append([]int{}, user.ShipToIDs...)
+	}
+	return arService.New(s.db).ListInvoices(ctx, filters)
+}
+
+func (s *portalServiceImpl) GetInvoice(ctx context.Context, user *models.PortalIdentity, id int) (*models.ARInvoiceWithDetails, error) {
+	var customerID int
+	var status models.ARInvoiceStatus
+	var shipToID *int
+	err := s.db.QueryRow(ctx, `
+		SELECT i.customer_id, i.status, so.ship_to_id
+		FROM ar_invoices i
+		LEFT JOIN sales_orders so ON so.id = i.order_id
+		WHERE i.id = $1 AND i.company_id = $2`, id, tenant.Company(ctx)).Scan(&customerID, &status, &shipToID)
+	if err == pgx.ErrNoRows {
+		return nil, ErrInvoiceNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invoice: %w", err)
+	}
+	if customerID != user.CustomerID || status == models.ARInvoiceStatusDraft {
+		return nil, ErrInvoiceNotFound
+	}
+	if !user.AllShipTos && (shipToID == nil || !user.CanUseShipTo(*shipToID)) {
+		return nil, ErrInvoiceNotFound
+	}
+
+	return arService.New(s.db).GetInvoice(ctx, id)
+}
+
+func (s *portalServiceImpl) GetStatement(ctx context.Context, user *models.PortalIdentity, fromDate, toDate string) (*models.CustomerStatement, error) {
+	if !user.AllShipTos {
+		return nil, ErrAccountOnly
+	}
+	return arService.New(s.db).GetStatement(ctx, user.CustomerID, fromDate, toDate)
+}
+
+func (s *portalServiceImpl) GetAging(ctx context.Context, user *models.PortalIdentity) (*models.CustomerAging, error) {
+	if !user.AllShipTos {
+		return nil, ErrAccountOnly
+	}
+	return arService.New(s.db).GetCustomerAging(ctx, user.CustomerID)
+}
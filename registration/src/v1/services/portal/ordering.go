@@ -0,0 +1,437 @@
+package portal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	inventoryService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/inventory"
+	pricingService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/pricing"
+	salesOrderService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/sales_order"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+// ============================================
+// Ordering
+// ============================================
+//
+// Customers order at the prices the pricing hierarchy resolves for them
+// and cannot change prices or discounts. What they submit becomes a DRAFT
+// sales order, marked with the portal user, for a rep to review and
+// confirm.
+
+func (s *portalServiceImpl) ShipTos(ctx context.Context, user *models.PortalIdentity) ([]models.PortalShipTo, error) {
+	return s.userShipTos(ctx, &models.PortalUser{ID: user.UserID, CustomerID: user.CustomerID, AllShipTos: user.AllShipTos})
+}
+
+// Catalog lists active products at the customer's price. Availability is
+// at the warehouse serving the ship-to asked for, or the customer's, less
+// stock reserved for anyone else.
+func (s *portalServiceImpl) Catalog(ctx context.Context, user *models.PortalIdentity, filters *models.PortalCatalogFilters) ([]models.PortalCatalogItem, int64, error) {
+	if filters.Page < 1 {
+		filters.Page = 1
+	}
+	if filters.PageSize < 1 || filters.PageSize > 100 {
+		filters.PageSize = 50
+	}
+
+	_, warehouseID, err := s.orderShipTo(ctx, user, filters.ShipToID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	whereClause := "WHERE p.is_active = true"
+	args := []interface{}{user.CustomerID, warehouseID}
+	argNum := 3
+
+	if filters.CategoryID != nil {
+		whereClause += fmt.Sprintf(" AND p.category_id = $%d", argNum)
+		args = append(args, *filters.CategoryID)
+		argNum++
+	}
+	if filters.Search != "" {
+		whereClause += fmt.Sprintf(" AND (p.sku ILIKE $%d OR p.name ILIKE $%d)", argNum, argNum)
+		args = append(args, "%"+filters.Search+"%")
+		argNum++
+	}
+	if filters.InStock {
+		whereClause += " AND COALESCE(i.available, 0) > 0"
+	}
+
+	from := `
+		FROM products p
+		LEFT JOIN product_categories pc ON p.category_id = pc.id
+		LEFT JOIN product_units pu ON p.default_unit_id = pu.id
+		LEFT JOIN customer_order_guides cog ON cog.customer_id = $1 AND cog.product_id = p.id
+		LEFT JOIN LATERAL (
+			SELECT GREATEST(SUM(quantity_available) - ` + inventoryService.ReservedForOthers("p.id", "$2", "$1", "0", "0") + `, 0) as available
+			FROM inventory
+			WHERE product_id = p.id AND warehouse_id = $2
+		) i ON true`
+
+	var total int64
+	err = s.db.QueryRow(ctx, fmt.Sprintf(`SELECT COUNT(*) %s %s`, from, whereClause), args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count catalog: %w", err)
+	}
+
+	offset := (filters.Page - 1) * filters.PageSize
+	rows := s.db.Query(ctx, fmt.Sprintf(`
+		SELECT p.id, p.sku, p.name, COALESCE(p.description, ''), COALESCE(pc.name, ''),
+			   COALESCE(pu.abbreviation, 'EA'), COALESCE(i.available, 0), cog.id IS NOT NULL
+		%s
+		%s
+		ORDER BY p.name
+		LIMIT $%d OFFSET $%d`, from, whereClause, argNum, argNum+1),
+		append(args, filters.PageSize, offset)...)
+	defer rows.Close()
+
+	items := []models.PortalCatalogItem{}
+	for rows.Next() {
+		var item models.PortalCatalogItem
+		err := rows.Scan(&item.ProductID, &item.ProductSKU, &item.ProductName, &item.Description,
+			&item.CategoryName, &item.UnitOfMeasure, &item.Available, &item.OnOrderGuide)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan catalog item: %w", err)
+		}
+		item.InStock = item.Available > 0
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list catalog: %w", err)
+	}
+
+	// Only the price is shown; cost and margin stay with us
+	pricing := pricingService.New(s.db)
+	for i := range items {
+		price, err := pricing.GetPrice(ctx, &models.PriceLookupRequest{
+			ProductID:  items[i].ProductID,
+			CustomerID: &user.CustomerID,
+			Quantity:   1,
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		items[i].Price = price.Price
+		items[i].PriceLevel = price.PriceLevel
+		if price.OriginalPrice > price.Price {
+			items[i].OriginalPrice = price.OriginalPrice
+		}
+	}
+
+	return items, total, nil
+}
+
+// SuggestFromGuide proposes an order from the customer's order guide,
+// with the quantities the user changed.
+func (s *portalServiceImpl) SuggestFromGuide(ctx context.Context, user *models.PortalIdentity, req *models.PortalOrderGuideRequest) (*models.OrderGuideDraft, error) {
+	guideReq, err := s.guideRequest(ctx, user, req)
+	if err != nil {
+		return nil, err
+	}
+	draft, err := salesOrderService.New(s.db).SuggestFromGuide(ctx, guideReq)
+	if err != nil {
+		return nil, orderError(err)
+	}
+	return draft, nil
+}
+
+// SubmitFromGuide submits the proposal from the order guide as a DRAFT
+// order.
+func (s *portalServiceImpl) SubmitFromGuide(ctx context.Context, user *models.PortalIdentity, req *models.PortalOrderGuideRequest) (*models.OrderGuideDraft, error) {
+	guideReq, err := s.guideRequest(ctx, user, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var draft *models.OrderGuideDraft
+	err = s.inTx(ctx, func(tx *portalServiceImpl) error {
+		var err error
+		draft, err = salesOrderService.New(tx.db).CreateFromGuide(ctx, guideReq, 0)
+		if err != nil {
+			return orderError(err)
+		}
+		return tx.markSubmitted(ctx, *draft.OrderID, user.UserID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return draft, nil
+}
+
+// SubmitOrder submits the user's cart as a DRAFT order. Each line is
+// priced for its quantity, as the catalog would show it.
+func (s *portalServiceImpl) SubmitOrder(ctx context.Context, user *models.PortalIdentity, req *models.PortalOrderRequest) (int, error) {
+	shipToID, warehouseID, err := s.orderShipTo(ctx, user, req.ShipToID)
+	if err != nil {
+		return 0, err
+	}
+
+	order := &models.CreateSalesOrderRequest{
+		CustomerID:        user.CustomerID,
+		ShipToID:          shipToID,
+		RequestedShipDate: req.RequestedShipDate,
+		WarehouseID:       warehouseID,
+		PONumber:          req.PONumber,
+		Notes:             req.Notes,
+	}
+	pricing := pricingService.New(s.db)
+	for _, l := range req.Lines {
+		var uom string
+		err := s.db.QueryRow(ctx, `
+			SELECT COALESCE(pu.abbreviation, 'EA')
+			FROM products p
+			LEFT JOIN product_units pu ON p.default_unit_id = pu.id
+			WHERE p.id = $1 AND p.is_active = true`, l.ProductID).Scan(&uom)
+		if err == pgx.ErrNoRows {
+			return 0, fmt.Errorf("%w: %d", ErrProductNotFound, l.ProductID)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to get product: %w", err)
+		}
+
+		price, err := pricing.GetPrice(ctx, &models.PriceLookupRequest{
+			ProductID:  l.ProductID,
+			CustomerID: &user.CustomerID,
+			Quantity:   l.Quantity,
+			AsOfDate:   req.RequestedShipDate,
+		})
+		if err != nil {
+			return 0, err
+		}
+		order.Lines = append(order.Lines, models.CreateSalesOrderLineRequest{
+			ProductID:     l.ProductID,
+			Quantity:      l.Quantity,
+			UnitOfMeasure: uom,
+			UnitPrice:     price.Price,
+		})
+	}
+
+	var id int
+	err = s.inTx(ctx, func(tx *portalServiceImpl) error {
+		var err error
+		id, err = salesOrderService.New(tx.db).Create(ctx, order, 0)
+		if err != nil {
+			return orderError(err)
+		}
+		return tx.markSubmitted(ctx, id, user.UserID)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// guideRequest turns the user's request into an order guide draft for the
+// customer. Only quantities carry over.
+func (s *portalServiceImpl) guideRequest(ctx context.Context, user *models.PortalIdentity, req *models.PortalOrderGuideRequest) (*models.OrderGuideDraftRequest, error) {
+	shipToID, warehouseID, err := s.orderShipTo(ctx, user, req.ShipToID)
+	if err != nil {
+		return nil, err
+	}
+
+	guideReq := &models.OrderGuideDraftRequest{
+		CustomerID:        user.CustomerID,
+		WarehouseID:       warehouseID,
+		ShipToID:          shipToID,
+		RequestedShipDate: req.RequestedShipDate,
+		PONumber:          req.PONumber,
+		Notes:             req.Notes,
+	}
+	for _, l := range req.Lines {
+		qty := l.Quantity
+		guideReq.Lines = append(guideReq.Lines, models.OrderGuideLineEdit{ProductID: l.ProductID, Quantity: &qty})
+	}
+	return guideReq, nil
+}
+
+// orderShipTo checks the ship-to the user asked for and finds the
+// warehouse that serves it: the ship-to's, else the customer's default.
+// Users limited to one ship-to need not name it; users limited to several
+// must.
+func (s *portalServiceImpl) orderShipTo(ctx context.Context, user *models.PortalIdentity, shipToID *int) (*int, int, error) {
+	if shipToID == nil && !user.AllShipTos {
+		if len(user.ShipToIDs) != 1 {
+			return nil, 0, ErrShipToRequired
+		}
+		shipToID = &user.ShipToIDs[0]
+	}
+
+	var warehouseID *int
+	if shipToID != nil {
+		if !user.CanUseShipTo(*shipToID) {
+			return nil, 0, ErrShipToNotFound
+		}
+		err := s.db.QueryRow(ctx, `
+			SELECT COALESCE(st.warehouse_id, c.default_warehouse_id)
+			FROM customer_ship_to st
+			JOIN customers c ON c.id = st.customer_id
+			WHERE st.id = $1 AND st.customer_id = $2 AND COALESCE(st.is_active, true)`,
+			*shipToID, user.CustomerID).Scan(&warehouseID)
+		if err == pgx.ErrNoRows {
+			return nil, 0, ErrShipToNotFound
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get ship-to: %w", err)
+		}
+	} else {
+		err := s.db.QueryRow(ctx, `SELECT default_warehouse_id FROM customers WHERE id = $1`, user.CustomerID).Scan(&warehouseID)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to get customer: %w", err)
+		}
+	}
+
+	if warehouseID == nil {
+		return nil, 0, ErrNoWarehouse
+	}
+	return shipToID, *warehouseID, nil
+}
+
+// markSubmitted records which portal user submitted an order.
+func (s *portalServiceImpl) markSubmitted(ctx context.Context, orderID, userID int) error {
+	_, err := s.db.Exec(ctx, `UPDATE sales_orders SET portal_user_id = $1 WHERE id = $2`, userID, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to mark order as submitted: %w", err)
+	}
+	return nil
+}
+
+// orderError keeps the reasons an order was refused that concern our
+// margins to ourselves.
+func orderError(err error) error {
+	switch {
+	case errors.Is(err, salesOrderService.ErrMarginTooLow):
+		return ErrNotOrderable
+	case errors.Is(err, salesOrderService.ErrProductNotFound):
+		return ErrProductNotFound
+	}
+	return err
+}
+
+// ============================================
+// Orders
+// ============================================
+
+func (s *portalServiceImpl) ListOrders(ctx context.Context, user *models.PortalIdentity, filters *models.PortalOrderFilters) ([]models.PortalOrder, int64, error) {
+	if filters.Page < 1 {
+		filters.Page = 1
+	}
+	if filters.PageSize < 1 || filters.PageSize > 100 {
+		filters.PageSize = 20
+	}
+
+	whereClause := "WHERE so.customer_id = $1 AND so.company_id = $2"
+	args := []interface{}{user.CustomerID, tenant.Company(ctx)}
+	argNum := 3
+
+	if !user.AllShipTos {
+		whereClause += fmt.Sprintf(" AND so.ship_to_id = ANY($%d)", argNum)
+		args = append(args, user.ShipToIDs)
+		argNum++
+	}
+	if filters.ShipToID != nil {
+		whereClause += fmt.Sprintf(" AND so.ship_to_id = $%d", argNum)
+		args = append(args, *filters.ShipToID)
+		argNum++
+	}
+	if filters.Status != nil {
+		whereClause += fmt.Sprintf(" AND so.status = $%d", argNum)
+		args = append(args, *filters.Status)
+		argNum++
+	}
+	if filters.DateFrom != "" {
+		whereClause += fmt.Sprintf(" AND so.order_date >= $%d", argNum)
+		args = append(args, filters.DateFrom)
+		argNum++
+	}
+	if filters.DateTo != "" {
+		whereClause += fmt.Sprintf(" AND so.order_date <= $%d", argNum)
+		args = append(args, filters.DateTo)
+		argNum++
+	}
+
+	var total int64
+	err := s.db.QueryRow(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM sales_orders so %s`, whereClause), args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count orders: %w", err)
+	}
+
+	offset := (filters.Page - 1) * filters.PageSize
+	orders, err := s.orders(ctx, fmt.Sprintf(`%s
+		ORDER BY so.order_date DESC, so.id DESC
+		LIMIT $%d OFFSET $%d`, whereClause, argNum, argNum+1), append(args, filters.PageSize, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return orders, total, nil
+}
+
+func (s *portalServiceImpl) GetOrder(ctx context.Context, user *models.PortalIdentity, id int) (*models.PortalOrder, error) {
+	orders, err := s.orders(ctx, `WHERE so.id = $1 AND so.customer_id = $2 AND so.company_id = $3`, id, user.CustomerID, tenant.Company(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if len(orders) == 0 || (orders[0].ShipToID != nil && !user.CanUseShipTo(*orders[0].ShipToID)) ||
+		(orders[0].ShipToID == nil && !user.AllShipTos) {
+		return nil, ErrOrderNotFound
+	}
+	order := &orders[0]
+
+	rows := s.db.Query(ctx, `
+		SELECT sol.line_number, sol.product_id, p.sku, p.name, sol.quantity_ordered,
+			   COALESCE(sol.quantity_shipped, 0), COALESCE(sol.unit_of_measure, ''),
+			   COALESCE(sol.unit_price, 0), COALESCE(sol.discount_percent, 0), COALESCE(sol.line_total, 0)
+		FROM sales_order_lines sol
+		JOIN products p ON p.id = sol.product_id
+		WHERE sol.order_id = $1
+		ORDER BY sol.line_number`, id)
+	defer rows.Close()
+
+	order.Lines = []models.PortalOrderLine{}
+	for rows.Next() {
+		var l models.PortalOrderLine
+		err := rows.Scan(&l.LineNumber, &l.ProductID, &l.ProductSKU, &l.ProductName, &l.Quantity,
+			&l.QuantityShipped, &l.UnitOfMeasure, &l.UnitPrice, &l.DiscountPercent, &l.LineTotal)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order line: %w", err)
+		}
+		order.Lines = append(order.Lines, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list order lines: %w", err)
+	}
+	return order, nil
+}
+
+// orders reads order headers as the customer sees them.
+func (s *portalServiceImpl) orders(ctx context.Context, where string, args ...interface{}) ([]models.PortalOrder, error) {
+	rows := s.db.Query(ctx, fmt.Sprintf(`
+		SELECT so.id, so.order_number, so.order_type, so.status, so.order_date, so.requested_ship_date,
+			   so.actual_ship_date, so.ship_to_id, COALESCE(st.name, ''), COALESCE(so.po_number, ''),
+			   COALESCE(so.subtotal, 0), COALESCE(so.tax_amount, 0), COALESCE(so.freight_amount, 0),
+			   COALESCE(so.total_amount, 0), COALESCE(so.currency, ''), COALESCE(so.notes, ''),
+			   so.portal_user_id IS NOT NULL
+		FROM sales_orders so
+		LEFT JOIN customer_ship_to st ON st.id = so.ship_to_id
+		%s`, where), args...)
+	defer rows.Close()
+
+	orders := []models.PortalOrder{}
+	for rows.Next() {
+		var o models.PortalOrder
+		err := rows.Scan(&o.ID, &o.OrderNumber, &o.OrderType, &o.Status, &o.OrderDate, &o.RequestedShipDate,
+			&o.ActualShipDate, &o.ShipToID, &o.ShipToName, &o.PONumber,
+			&o.Subtotal, &o.TaxAmount, &o.FreightAmount,
+			&o.TotalAmount, &o.Currency, &o.Notes, &o.FromPortal)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order: %w", err)
+		}
+		orders = append(orders, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list orders: %w", err)
+	}
+	return orders, nil
+}
@@ -0,0 +1,428 @@
+package portal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrUserNotFound       = errors.New("portal user not found")
+	ErrDuplicateEmail     = errors.New("a portal user with this email already exists")
+	ErrCustomerNotFound   = errors.New("customer not found")
+	ErrShipToNotFound     = errors.New("ship-to not found")
+	ErrInvalidCredentials = errors.New("invalid email or password")
+	ErrShipToRequired     = errors.New("choose a ship-to for the order")
+	ErrNoWarehouse        = errors.New("no warehouse serves this ship-to")
+	ErrProductNotFound    = errors.New("product not found")
+	ErrNotOrderable       = errors.New("a product cannot be ordered online; please contact your sales rep")
+	ErrOrderNotFound      = errors.New("order not found")
+	ErrInvoiceNotFound    = errors.New("invoice not found")
+	ErrAccountOnly        = errors.New("statements and aging are only available to users with access to the whole account")
+)
+
+// ============================================
+// Service Interface
+// ============================================
+
+type PortalService interface {
+	// Portal users, managed by employees
+	CreateUser(ctx context.Context, req *models.CreatePortalUserRequest, createdBy int) (int, error)
+	GetUser(ctx context.Context, id int) (*models.PortalUser, error)
+	ListUsers(ctx context.Context, filters *models.PortalUserFilters) ([]models.PortalUser, error)
+	UpdateUser(ctx context.Context, id int, req *models.UpdatePortalUserRequest) error
+	ListSubmittedOrders(ctx context.Context, filters *models.PortalSubmittedOrderFilters) ([]models.PortalSubmittedOrder, error)
+
+	// Sign-in
+	Login(ctx context.Context, req *models.PortalLoginRequest) (*models.PortalUser, error)
+	Identify(ctx context.Context, userID int) (*models.PortalIdentity, error)
+
+	// Ordering
+	ShipTos(ctx context.Context, user *models.PortalIdentity) ([]models.PortalShipTo, error)
+	Catalog(ctx context.Context, user *models.PortalIdentity, filters *models.PortalCatalogFilters) ([]models.PortalCatalogItem, int64, error)
+	SuggestFromGuide(ctx context.Context, user *models.PortalIdentity, req *models.PortalOrderGuideRequest) (*models.OrderGuideDraft, error)
+	SubmitFromGuide(ctx context.Context, user *models.PortalIdentity, req *models.PortalOrderGuideRequest) (*models.OrderGuideDraft, error)
+	SubmitOrder(ctx context.Context, user *models.PortalIdentity, req *models.PortalOrderRequest) (int, error)
+	ListOrders(ctx context.Context, user *models.PortalIdentity, filters *models.PortalOrderFilters) ([]models.PortalOrder, int64, error)
+	GetOrder(ctx context.Context, user *models.PortalIdentity, id int) (*models.PortalOrder, error)
+
+	// Account
+	ListInvoices(ctx context.Context, user *models.PortalIdentity, filters *models.ARInvoiceListFilters) ([]models.ARInvoiceWithDetails, int64, error)
+	GetInvoice(ctx context.Context, user *models.PortalIdentity, id int) (*models.ARInvoiceWithDetails, error)
+	GetStatement(ctx context.Context, user *models.PortalIdentity, fromDate, toDate string) (*models.CustomerStatement, error)
+	GetAging(ctx context.Context, user *models.PortalIdentity) (*models.CustomerAging, error)
+}
+
+// ============================================
+// Service Implementation
+// ============================================
+
+type portalServiceImpl struct {
+	db postgres.Executor
+}
+
+func New(db postgres.Executor) PortalService {
+	return &portalServiceImpl{db: db}
+}
+
+// inTx runs fn in a transaction. When the service was built on a caller's
+// transaction, fn runs in that one.
+func (s *portalServiceImpl) inTx(ctx context.Context, fn func(tx *portalServiceImpl) error) error {
+	conn, ok := s.db.(postgres.Connection)
+	if !ok {
+		return fn(s)
+	}
+
+	tx, err := conn.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&portalServiceImpl{db: tx}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ============================================
+// Portal Users
+// ============================================
+
+func (s *portalServiceImpl) CreateUser(ctx context.Context, req *models.CreatePortalUserRequest, createdBy int) (int, error) {
+	var exists bool
+	err := s.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM customers WHERE id = $1 AND company_id = $2)`,
+		req.CustomerID, tenant.Company(ctx)).Scan(&exists)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check customer: %w", err)
+	}
+	if !exists {
+		return 0, ErrCustomerNotFound
+	}
+	if err := s.checkDuplicateEmail(ctx, req.Email, 0); err != nil {
+		return 0, err
+	}
+
+	hash, err := hashPassword(req.Password)
+	if err != nil {
+		return 0, err
+	}
+
+	var id int
+	err = s.inTx(ctx, func(tx *portalServiceImpl) error {
+		err := tx.db.QueryRow(ctx, `
+			INSERT INTO customer_portal_users (
+				company_id, customer_id, email, password_hash, name, phone, all_ship_tos, created_by
+			) VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, NULLIF($8, 0))
+			RETURNING id`,
+			tenant.Company(ctx), req.CustomerID, strings.TrimSpace(req.Email), hash,
+			strings.TrimSpace(req.Name), req.Phone, len(req.ShipToIDs) == 0, createdBy,
+		).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("failed to create portal user: %w", err)
+		}
+		return tx.setShipTos(ctx, id, req.CustomerID, req.ShipToIDs)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (s *portalServiceImpl) GetUser(ctx context.Context, id int) (*models.PortalUser, error) {
+	users, err := s.listUsers(ctx, "u.id = $2", id)
+	if err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, ErrUserNotFound
+	}
+	return &users[0], nil
+}
+
+func (s *portalServiceImpl) ListUsers(ctx context.Context, filters *models.PortalUserFilters) ([]models.PortalUser, error) {
+	where := []string{}
+	args := []interface{}{}
+	if filters.CustomerID != nil {
+		args = append(args, *filters.CustomerID)
+		where = append(where, fmt.Sprintf("u.customer_id = $%d", len(args)+1))
+	}
+	if filters.Search != "" {
+		args = append(args, "%"+filters.Search+"%")
+		where = append(where, fmt.Sprintf("(u.name ILIKE $%d OR u.email ILIKE $%d)", len(args)+1, len(args)+1))
+	}
+	if !filters.IncludeInactive {
+		where = append(where, "u.is_active = true")
+	}
+	if len(where) == 0 {
+		where = append(where, "true")
+	}
+	return s.listUsers(ctx, strings.Join(where, " AND "), args...)
+}
+
+// listUsers reads the company's portal users matching a condition, whose
+// arguments start at $2, with their ship-tos.
+func (s *portalServiceImpl) listUsers(ctx context.Context, where string, args ...interface{}) ([]models.PortalUser, error) {
+	rows := s.db.Query(ctx, fmt.Sprintf(`
+		SELECT u.id, u.company_id, u.customer_id, c.name, u.email, u.name, COALESCE(u.phone, ''),
+			   u.all_ship_tos, u.is_active, u.last_login_at, u.created_by, u.created_at, u.updated_at
+		FROM customer_portal_users u
+		JOIN customers c ON c.id = u.customer_id
+		WHERE u.company_id = $1 AND %s
+		ORDER BY c.name, u.name`, where), append([]interface{}{tenant.Company(ctx)}, args...)...)
+	defer rows.Close()
+
+	var users []models.PortalUser
+	for rows.Next() {
+		var u models.PortalUser
+		err := rows.Scan(&u.ID, &u.CompanyID, &u.CustomerID, &u.CustomerName, &u.Email, &u.Name, &u.Phone,
+			&u.AllShipTos, &u.IsActive, &u.LastLoginAt, &u.CreatedBy, &u.CreatedAt, &u.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan portal user: %w", err)
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list portal users: %w", err)
+	}
+
+	for i := range users {
+		shipTos, err := s.userShipTos(ctx, &users[i])
+		if err != nil {
+			return nil, err
+		}
+		users[i].ShipTos = shipTos
+	}
+	return users, nil
+}
+
+func (s *portalServiceImpl) UpdateUser(ctx context.Context, id int, req *models.UpdatePortalUserRequest) error {
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	if req.Email != nil {
+		if err := s.checkDuplicateEmail(ctx, *req.Email, id); err != nil {
+			return err
+		}
+		trimmed := strings.TrimSpace(*req.Email)
+		req.Email = &trimmed
+	}
+	var hash *string
+	if req.Password != nil {
+		h, err := hashPassword(*req.Password)
+		if err != nil {
+			return err
+		}
+		hash = &h
+	}
+	var allShipTos *bool
+	if req.ShipToIDs != nil {
+		all := len(*req.ShipToIDs) == 0
+		allShipTos = &all
+	}
+
+	return s.inTx(ctx, func(tx *portalServiceImpl) error {
+		_, err := tx.db.Exec(ctx, `
+			UPDATE customer_portal_users SET
+				email = COALESCE($1, email),
+				password_hash = COALESCE($2, password_hash),
+				name = COALESCE($3, name),
+				phone = CASE WHEN $4::text IS NULL THEN phone ELSE NULLIF($4, '') END,
+				all_ship_tos = COALESCE($5, all_ship_tos),
+				is_active = COALESCE($6, is_active),
+				updated_at = NOW()
+			WHERE id = $7`,
+			req.Email, hash, req.Name, req.Phone, allShipTos, req.IsActive, id)
+		if err != nil {
+			return fmt.Errorf("failed to update portal user: %w", err)
+		}
+		if req.ShipToIDs != nil {
+			return tx.setShipTos(ctx, id, user.CustomerID, *req.ShipToIDs)
+		}
+		return nil
+	})
+}
+
+// checkDuplicateEmail refuses an email another portal user signs in with,
+// in any company.
+func (s *portalServiceImpl) checkDuplicateEmail(ctx context.Context, email string, exceptID int) error {
+	var exists bool
+	err := s.db.QueryRow(ctx, `
+		SELECT EXISTS(SELECT 1 FROM customer_portal_users WHERE LOWER(email) = LOWER($1) AND id <> $2)`,
+		strings.TrimSpace(email), exceptID).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check email: %w", err)
+	}
+	if exists {
+		return ErrDuplicateEmail
+	}
+	return nil
+}
+
+// setShipTos replaces the ship-tos a user is limited to. Every one must
+// be the customer's.
+func (s *portalServiceImpl) setShipTos(ctx context.Context, userID, customerID int, shipToIDs []int) error {
+	if _, err := s.db.Exec(ctx, `DELETE FROM customer_portal_user_ship_tos WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to clear ship-tos: %w", err)
+	}
+	for _, shipToID := range shipToIDs {
+		result, err := s.db.Exec(ctx, `
+			INSERT INTO customer_portal_user_ship_tos (user_id, ship_to_id)
+			SELECT $1, id FROM customer_ship_to WHERE id = $2 AND customer_id = $3`,
+			userID, shipToID, customerID)
+		if err != nil {
+			return fmt.Errorf("failed to assign ship-to: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return fmt.Errorf("%w: %d", ErrShipToNotFound, shipToID)
+		}
+	}
+	return nil
+}
+
+// userShipTos lists the active ship-tos a user can order for.
+func (s *portalServiceImpl) userShipTos(ctx context.Context, u *models.PortalUser) ([]models.PortalShipTo, error) {
+	rows := s.db.Query(ctx, `
+		SELECT st.id, COALESCE(st.ship_to_code, ''), COALESCE(st.name, ''),
+			   TRIM(CONCAT_WS(' ', st.address_line1, st.address_line2)), COALESCE(st.city, ''),
+			   st.warehouse_id, COALESCE(st.is_default, false)
+		FROM customer_ship_to st
+		WHERE st.customer_id = $1 AND COALESCE(st.is_active, true)
+		  AND ($2 OR st.id IN (SELECT ship_to_id FROM customer_portal_user_ship_tos WHERE user_id = $3))
+		ORDER BY st.is_default DESC NULLS LAST, st.name`,
+		u.CustomerID, u.AllShipTos, u.ID)
+	defer rows.Close()
+
+	shipTos := []models.PortalShipTo{}
+	for rows.Next() {
+		var st models.PortalShipTo
+		if err := rows.Scan(&st.ID, &st.ShipToCode, &st.Name, &st.Address, &st.City, &st.WarehouseID, &st.IsDefault); err != nil {
+			return nil, fmt.Errorf("failed to scan ship-to: %w", err)
+		}
+		shipTos = append(shipTos, st)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list ship-tos: %w", err)
+	}
+	return shipTos, nil
+}
+
+// ListSubmittedOrders lists orders customers sent through the portal, by
+// default those still waiting for a rep to review them.
+func (s *portalServiceImpl) ListSubmittedOrders(ctx context.Context, filters *models.PortalSubmittedOrderFilters) ([]models.PortalSubmittedOrder, error) {
+	status := models.OrderStatusDraft
+	if filters.Status != nil {
+		status = *filters.Status
+	}
+
+	rows := s.db.Query(ctx, `
+		SELECT so.id, so.order_number, so.status, so.customer_id, c.name, COALESCE(st.name, ''),
+			   u.id, u.name, so.order_date, so.requested_ship_date, COALESCE(so.po_number, ''),
+			   COALESCE(so.total_amount, 0), so.created_at
+		FROM sales_orders so
+		JOIN customers c ON c.id = so.customer_id
+		JOIN customer_portal_users u ON u.id = so.portal_user_id
+		LEFT JOIN customer_ship_to st ON st.id = so.ship_to_id
+		WHERE so.company_id = $1 AND so.status = $2 AND ($3::int IS NULL OR so.customer_id = $3)
+		ORDER BY so.created_at`,
+		tenant.Company(ctx), status, filters.CustomerID)
+	defer rows.Close()
+
+	orders := []models.PortalSubmittedOrder{}
+	for rows.Next() {
+		var o models.PortalSubmittedOrder
+		err := rows.Scan(&o.OrderID, &o.OrderNumber, &o.Status, &o.CustomerID, &o.CustomerName, &o.ShipToName,
+			&o.PortalUserID, &o.PortalUserName, &o.OrderDate, &o.RequestedShipDate, &o.PONumber,
+			&o.TotalAmount, &o.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan submitted order: %w", err)
+		}
+		orders = append(orders, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list submitted orders: %w", err)
+	}
+	return orders, nil
+}
+
+// ============================================
+// Sign-in
+// ============================================
+
+// Login checks a portal user's password. Emails are unique across
+// companies, so no company is needed to sign in.
+func (s *portalServiceImpl) Login(ctx context.Context, req *models.PortalLoginRequest) (*models.PortalUser, error) {
+	var id, companyID int
+	var hash string
+	err := s.db.QueryRow(ctx, `
+		SELECT u.id, u.company_id, u.password_hash
+		FROM customer_portal_users u
+		JOIN customers c ON c.id = u.customer_id
+		WHERE LOWER(u.email) = LOWER($1) AND u.is_active = true AND COALESCE(c.is_active, true)`,
+		strings.TrimSpace(req.Email)).Scan(&id, &companyID, &hash)
+	if err == pgx.ErrNoRows {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get portal user: %w", err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(req.Password)) != nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if _, err := s.db.Exec(ctx, `UPDATE customer_portal_users SET last_login_at = NOW() WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("failed to record login: %w", err)
+	}
+	return s.GetUser(tenant.WithCompany(ctx, companyID), id)
+}
+
+// Identify loads the signed-in user for a request. Users who have been
+// deactivated, or whose customer has, are not found.
+func (s *portalServiceImpl) Identify(ctx context.Context, userID int) (*models.PortalIdentity, error) {
+	user := &models.PortalIdentity{UserID: userID}
+	err := s.db.QueryRow(ctx, `
+		SELECT u.customer_id, u.company_id, u.email, u.name, u.all_ship_tos
+		FROM customer_portal_users u
+		JOIN customers c ON c.id = u.customer_id
+		WHERE u.id = $1 AND u.is_active = true AND COALESCE(c.is_active, true)`,
+		userID).Scan(&user.CustomerID, &user.CompanyID, &user.Email, &user.Name, &user.AllShipTos)
+	if err == pgx.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get portal user: %w", err)
+	}
+
+	if !user.AllShipTos {
+		rows := s.db.Query(ctx, `SELECT ship_to_id FROM customer_portal_user_ship_tos WHERE user_id = $1`, userID)
+		defer rows.Close()
+		user.ShipToIDs = []int{}
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				return nil, fmt.Errorf("failed to scan ship-to: %w", err)
+			}
+			user.ShipToIDs = append(user.ShipToIDs, id)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to list ship-tos: %w", err)
+		}
+	}
+	return user, nil
+}
+
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return string(hash), nil
+}
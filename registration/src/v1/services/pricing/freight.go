@@ -0,0 +1,196 @@
+package pricing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+)
+
+var (
+	ErrFreightRuleNotFound = errors.New("freight rule not found")
+	ErrRouteNotFound       = errors.New("route not found")
+)
+
+// ============================================
+// Freight Rules
+// ============================================
+//
+// Each rule type is looked up on its own. Rules for the order's route take
+// the place of rules without a route, so a route can be given its own fee,
+// tiers or free delivery threshold while the rest share the defaults.
+
+func (s *pricingServiceImpl) CreateFreightRule(ctx context.Context, req *models.CreateFreightRuleRequest, createdBy int) (int, error) {
+	if req.RouteID != nil {
+		var exists bool
+		err := s.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM routes WHERE id = $1)`, *req.RouteID).Scan(&exists)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get route: %w", err)
+		}
+		if !exists {
+			return 0, ErrRouteNotFound
+		}
+	}
+
+	var id int
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO freight_rules (
+			company_id, name, rule_type, route_id, fulfillment, min_order_value,
+			min_weight_kg, max_weight_kg, amount, rate_per_kg, created_by
+		) VALUES ($1, $2, $3, $4, NULLIF($5, ''), $6, $7, $8, $9, $10, NULLIF($11, 0))
+		RETURNING id`,
+		tenant.Company(ctx), req.Name, req.RuleType, req.RouteID, req.Fulfillment, req.MinOrderValue,
+		req.MinWeightKg, req.MaxWeightKg, req.Amount, req.RatePerKg, createdBy,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create freight rule: %w", err)
+	}
+	return id, nil
+}
+
+func (s *pricingServiceImpl) ListFreightRules(ctx context.Context, activeOnly bool) ([]models.FreightRule, error) {
+	query := `
+		SELECT fr.id, fr.name, fr.rule_type, fr.route_id, COALESCE(r.name, ''), COALESCE(fr.fulfillment, ''),
+			   fr.min_order_value, fr.min_weight_kg, fr.max_weight_kg, fr.amount, fr.rate_per_kg,
+			   fr.is_active, fr.created_at
+		FROM freight_rules fr
+		LEFT JOIN routes r ON r.id = fr.route_id
+		WHERE fr.company_id = $1`
+	if activeOnly {
+		query += ` AND fr.is_active = true`
+	}
+	query += ` ORDER BY fr.rule_type, fr.route_id NULLS FIRST, fr.min_weight_kg NULLS FIRST, fr.name`
+
+	rows := s.db.Query(ctx, query, tenant.Company(ctx))
+	defer rows.Close()
+
+	rules := []models.FreightRule{}
+	for rows.Next() {
+		var r models.FreightRule
+		err := rows.Scan(&r.ID, &r.Name, &r.RuleType, &r.RouteID, &r.RouteName, &r.Fulfillment,
+			&r.MinOrderValue, &r.MinWeightKg, &r.MaxWeightKg, &r.Amount, &r.RatePerKg,
+			&r.IsActive, &r.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan freight rule: %w", err)
+		}
+		rules = append(rules, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list freight rules: %w", err)
+	}
+	return rules, nil
+}
+
+func (s *pricingServiceImpl) DeactivateFreightRule(ctx context.Context, id int) error {
+	result, err := s.db.Exec(ctx, `UPDATE freight_rules SET is_active = false WHERE id = $1 AND company_id = $2`,
+		id, tenant.Company(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to deactivate freight rule: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrFreightRuleNotFound
+	}
+	return nil
+}
+
+// ============================================
+// Freight Calculation
+// ============================================
+
+// CalculateFreight works out the charge for an order of the given value and
+// weight. Deliveries pay the route fee and the fee of the weight tier they
+// fall in, both waived from the free delivery order value; surcharges for
+// the way the order is fulfilled are always added.
+func (s *pricingServiceImpl) CalculateFreight(ctx context.Context, req *models.FreightRequest) (*models.FreightCalculation, error) {
+	calc := &models.FreightCalculation{
+		Fulfillment: req.Fulfillment,
+		RouteID:     req.RouteID,
+		OrderValue:  req.OrderValue,
+		WeightKg:    math.Round(req.WeightKg*1000) / 1000,
+	}
+
+	rows := s.db.Query(ctx, `
+		SELECT name, rule_type, route_id IS NOT NULL, COALESCE(fulfillment, ''), min_order_value,
+			   COALESCE(min_weight_kg, 0), max_weight_kg, amount, rate_per_kg
+		FROM freight_rules
+		WHERE company_id = $1 AND is_active = true AND (route_id IS NULL OR route_id = $2)
+		ORDER BY id`,
+		tenant.Company(ctx), req.RouteID)
+	defer rows.Close()
+
+	// Rules of each type for the route, and for every route
+	type freightRule struct {
+		name          string
+		fulfillment   models.Fulfillment
+		minOrderValue *float64
+		minWeight     float64
+		maxWeight     *float64
+		amount        float64
+		ratePerKg     float64
+	}
+	forRoute := make(map[models.FreightRuleType][]freightRule)
+	forAll := make(map[models.FreightRuleType][]freightRule)
+	for rows.Next() {
+		var r freightRule
+		var ruleType models.FreightRuleType
+		var routeSpecific bool
+		err := rows.Scan(&r.name, &ruleType, &routeSpecific, &r.fulfillment, &r.minOrderValue,
+			&r.minWeight, &r.maxWeight, &r.amount, &r.ratePerKg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan freight rule: %w", err)
+		}
+		if routeSpecific {
+			forRoute[ruleType] = append(forRoute[ruleType], r)
+		} else {
+			forAll[ruleType] = append(forAll[ruleType], r)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get freight rules: %w", err)
+	}
+	rules := func(ruleType models.FreightRuleType) []freightRule {
+		if len(forRoute[ruleType]) > 0 {
+			return forRoute[ruleType]
+		}
+		return forAll[ruleType]
+	}
+
+	if req.Fulfillment == models.FulfillmentDelivery {
+		if fees := rules(models.FreightRuleRouteFee); len(fees) > 0 {
+			calc.RouteFee = fees[0].amount
+			calc.Rules = append(calc.Rules, fees[0].name)
+		}
+		for _, tier := range rules(models.FreightRuleWeightTier) {
+			if calc.WeightKg < tier.minWeight || (tier.maxWeight != nil && calc.WeightKg >= *tier.maxWeight) {
+				continue
+			}
+			calc.WeightFee = tier.amount + tier.ratePerKg*calc.WeightKg
+			calc.Rules = append(calc.Rules, tier.name)
+			break
+		}
+		for _, free := range rules(models.FreightRuleFreeDelivery) {
+			if free.minOrderValue != nil && req.OrderValue >= *free.minOrderValue {
+				calc.FreeDelivery = true
+				calc.RouteFee, calc.WeightFee = 0, 0
+				calc.Rules = append(calc.Rules, free.name)
+				break
+			}
+		}
+	}
+
+	for _, surcharge := range rules(models.FreightRuleSurcharge) {
+		if surcharge.fulfillment == req.Fulfillment {
+			calc.Surcharge += surcharge.amount
+			calc.Rules = append(calc.Rules, surcharge.name)
+		}
+	}
+
+	calc.RouteFee = math.Round(calc.RouteFee*100) / 100
+	calc.WeightFee = math.Round(calc.WeightFee*100) / 100
+	calc.Surcharge = math.Round(calc.Surcharge*100) / 100
+	calc.Amount = calc.RouteFee + calc.WeightFee + calc.Surcharge
+	return calc, nil
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/anas-dev-92/FoodHive/core/postgres"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
 	"github.com/jackc/pgx/v5"
 )
 
@@ -53,6 +54,23 @@ type PricingService interface {
 
 	// Margin Check
 	CheckBelowCost(ctx context.Context, productID int, price float64) (bool, float64, error)
+	UnitCost(ctx context.Context, productID, warehouseID int) (float64, models.CostingMethod, error)
+	CheckMargin(ctx context.Context, req *models.MarginCheckRequest) (*models.MarginCheck, error)
+
+	// Margin Rules
+	CreateMarginRule(ctx context.Context, req *models.CreateMarginRuleRequest, createdBy int) (int, error)
+	ListMarginRules(ctx context.Context, activeOnly bool) ([]models.MarginRule, error)
+	DeactivateMarginRule(ctx context.Context, id int) error
+
+	// Freight Rules
+	CreateFreightRule(ctx context.Context, req *models.CreateFreightRuleRequest, createdBy int) (int, error)
+	ListFreightRules(ctx context.Context, activeOnly bool) ([]models.FreightRule, error)
+	DeactivateFreightRule(ctx context.Context, id int) error
+	CalculateFreight(ctx context.Context, req *models.FreightRequest) (*models.FreightCalculation, error)
+
+	// Below-Cost Exceptions
+	RecordMarginException(ctx context.Context, e *models.MarginException) error
+	GetMarginExceptions(ctx context.Context, filters *models.MarginExceptionFilters) ([]models.MarginException, int64, error)
 }
 
 // ============================================
@@ -744,11 +762,314 @@ func (s *pricingServiceImpl) GetPriceList(ctx context.Context, filters *models.P
 // Margin Check
 // ============================================
 
+// CheckBelowCost compares a price with the product's cost under its costing
+// method, across all warehouses.
 func (s *pricingServiceImpl) CheckBelowCost(ctx context.Context, productID int, price float64) (bool, float64, error) {
-	var cost float64
-	err := s.db.QueryRow(ctx, `SELECT COALESCE(average_cost, 0) FROM inventory WHERE product_id = $1 LIMIT 1`, productID).Scan(&cost)
+	cost, _, err := s.UnitCost(ctx, productID, 0)
 	if err != nil {
-		return false, 0, nil
+		return false, 0, err
 	}
 	return price < cost, cost, nil
 }
+
+// UnitCost returns the product's cost under its costing method. Average
+// cost is taken from the warehouse's stock when a warehouse is given. A
+// method with no cost recorded falls back to the standard cost.
+func (s *pricingServiceImpl) UnitCost(ctx context.Context, productID, warehouseID int) (float64, models.CostingMethod, error) {
+	var method models.CostingMethod
+	var average, last, standard float64
+	var methodCost, landedCost *float64
+	err := s.db.QueryRow(ctx, `
+		SELECT p.costing_method, COALESCE(p.average_cost, 0), COALESCE(p.last_purchase_cost, 0),
+			   COALESCE(p.standard_cost, 0), pc.cost, pc.landed_cost
+		FROM products p
+		LEFT JOIN product_costs pc ON pc.product_id = p.id AND pc.costing_method = p.costing_method
+		WHERE p.id = $1`, productID).Scan(&method, &average, &last, &standard, &methodCost, &landedCost)
+	if err == pgx.ErrNoRows {
+		return 0, "", fmt.Errorf("product not found")
+	}
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to get product cost: %w", err)
+	}
+
+	var cost float64
+	switch method {
+	case models.CostMethodAverage:
+		var stockAverage *float64
+		err := s.db.QueryRow(ctx, `
+			SELECT SUM(quantity_on_hand * average_cost) / NULLIF(SUM(quantity_on_hand), 0)
+			FROM inventory
+			WHERE product_id = $1 AND ($2 = 0 OR warehouse_id = $2)
+			  AND quantity_on_hand > 0 AND average_cost IS NOT NULL`, productID, warehouseID).Scan(&stockAverage)
+		if err != nil {
+			return 0, "", fmt.Errorf("failed to get average cost: %w", err)
+		}
+		cost = average
+		if stockAverage != nil {
+			cost = *stockAverage
+		}
+	case models.CostMethodLast:
+		cost = last
+		if methodCost != nil && *methodCost > 0 {
+			cost = *methodCost
+		}
+	case models.CostMethodLanded:
+		if landedCost != nil {
+			cost = *landedCost
+		}
+	default:
+		if methodCost != nil {
+			cost = *methodCost
+		}
+	}
+
+	if cost <= 0 {
+		cost = standard
+	}
+	return cost, method, nil
+}
+
+// CheckMargin measures a sale price against the product's cost and every
+// active margin rule matching the product's category, the customer's group
+// and the salesperson. A violated BLOCK rule blocks the sale; violated WARN
+// rules and selling below cost only warn.
+func (s *pricingServiceImpl) CheckMargin(ctx context.Context, req *models.MarginCheckRequest) (*models.MarginCheck, error) {
+	cost, method, err := s.UnitCost(ctx, req.ProductID, req.WarehouseID)
+	if err != nil {
+		return nil, err
+	}
+
+	check := &models.MarginCheck{
+		ProductID:     req.ProductID,
+		UnitPrice:     req.UnitPrice,
+		UnitCost:      cost,
+		CostingMethod: method,
+		Margin:        req.UnitPrice - cost,
+		MarginPercent: marginPercent(req.UnitPrice, cost),
+		IsBelowCost:   req.UnitPrice < cost-0.00005,
+	}
+	if check.IsBelowCost {
+		check.Warnings = append(check.Warnings,
+			fmt.Sprintf("price %.4f is below %s cost %.4f", req.UnitPrice, method, cost))
+	}
+	if cost <= 0 {
+		// Nothing to measure a margin against
+		return check, nil
+	}
+
+	var categoryID, customerGroupID, salesRepID *int
+	s.db.QueryRow(ctx, `SELECT category_id FROM products WHERE id = $1`, req.ProductID).Scan(&categoryID)
+	if req.CustomerID > 0 {
+		s.db.QueryRow(ctx, `SELECT customer_group_id, sales_rep_id FROM customers WHERE id = $1`,
+			req.CustomerID).Scan(&customerGroupID, &salesRepID)
+	}
+	if req.SalesRepID > 0 {
+		salesRepID = &req.SalesRepID
+	}
+
+	rows := s.db.Query(ctx, `
+		SELECT name, min_margin_percent, action
+		FROM margin_rules
+		WHERE company_id = $1 AND is_active = true
+		  AND (category_id IS NULL OR category_id = $2)
+		  AND (customer_group_id IS NULL OR customer_group_id = $3)
+		  AND (sales_rep_id IS NULL OR sales_rep_id = $4)
+		ORDER BY min_margin_percent DESC, id`,
+		tenant.Company(ctx), categoryID, customerGroupID, salesRepID)
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		var minMargin float64
+		var action models.MarginAction
+		if err := rows.Scan(&name, &minMargin, &action); err != nil {
+			return nil, fmt.Errorf("failed to scan margin rule: %w", err)
+		}
+		if check.MarginPercent >= minMargin {
+			continue
+		}
+		if action == models.MarginActionBlock {
+			check.Blocked = true
+		}
+		check.Warnings = append(check.Warnings,
+			fmt.Sprintf("margin %.2f%% is below the %.2f%% minimum of rule %s", check.MarginPercent, minMargin, name))
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get margin rules: %w", err)
+	}
+
+	return check, nil
+}
+
+// marginPercent is margin over price. A free line with a cost loses all of
+// it.
+func marginPercent(price, cost float64) float64 {
+	if price > 0 {
+		return (price - cost) / price * 100
+	}
+	if cost > 0 {
+		return -100
+	}
+	return 0
+}
+
+// ============================================
+// Margin Rules
+// ============================================
+
+func (s *pricingServiceImpl) CreateMarginRule(ctx context.Context, req *models.CreateMarginRuleRequest, createdBy int) (int, error) {
+	var id int
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO margin_rules (
+			company_id, name, category_id, customer_group_id, sales_rep_id,
+			min_margin_percent, action, created_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, 0))
+		RETURNING id`,
+		tenant.Company(ctx), req.Name, req.CategoryID, req.CustomerGroupID, req.SalesRepID,
+		req.MinMarginPercent, req.Action, createdBy,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create margin rule: %w", err)
+	}
+	return id, nil
+}
+
+func (s *pricingServiceImpl) ListMarginRules(ctx context.Context, activeOnly bool) ([]models.MarginRule, error) {
+	query := `
+		SELECT id, name, category_id, customer_group_id, sales_rep_id,
+			   min_margin_percent, action, is_active, created_at
+		FROM margin_rules
+		WHERE company_id = $1`
+	if activeOnly {
+		query += ` AND is_active = true`
+	}
+	query += ` ORDER BY name`
+
+	rows := s.db.Query(ctx, query, tenant.Company(ctx))
+	defer rows.Close()
+
+	rules := []models.MarginRule{}
+	for rows.Next() {
+		var r models.MarginRule
+		err := rows.Scan(&r.ID, &r.Name, &r.CategoryID, &r.CustomerGroupID, &r.SalesRepID,
+			&r.MinMarginPercent, &r.Action, &r.IsActive, &r.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan margin rule: %w", err)
+		}
+		rules = append(rules, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list margin rules: %w", err)
+	}
+	return rules, nil
+}
+
+func (s *pricingServiceImpl) DeactivateMarginRule(ctx context.Context, id int) error {
+	result, err := s.db.Exec(ctx, `UPDATE margin_rules SET is_active = false WHERE id = $1 AND company_id = $2`,
+		id, tenant.Company(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to deactivate margin rule: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("margin rule not found")
+	}
+	return nil
+}
+
+// ============================================
+// Below-Cost Exceptions
+// ============================================
+
+// RecordMarginException logs a line that was let through below cost.
+func (s *pricingServiceImpl) RecordMarginException(ctx context.Context, e *models.MarginException) error {
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO margin_exceptions (
+			company_id, order_id, order_line_id, product_id, customer_id, sales_rep_id,
+			quantity, unit_price, unit_cost, costing_method, margin_percent, created_by
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+		tenant.Company(ctx), e.OrderID, e.OrderLineID, e.ProductID, e.CustomerID, e.SalesRepID,
+		e.Quantity, e.UnitPrice, e.UnitCost, e.CostingMethod, e.MarginPercent, e.CreatedBy,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record margin exception: %w", err)
+	}
+	return nil
+}
+
+// GetMarginExceptions is the below-cost exceptions report, newest first.
+func (s *pricingServiceImpl) GetMarginExceptions(ctx context.Context, filters *models.MarginExceptionFilters) ([]models.MarginException, int64, error) {
+	if filters.Page < 1 {
+		filters.Page = 1
+	}
+	if filters.PageSize < 1 || filters.PageSize > 100 {
+		filters.PageSize = 50
+	}
+
+	whereClause := "WHERE me.company_id = $1"
+	args := []interface{}{tenant.Company(ctx)}
+	argNum := 2
+
+	if filters.DateFrom != "" {
+		whereClause += fmt.Sprintf(" AND me.created_at >= $%d::date", argNum)
+		args = append(args, filters.DateFrom)
+		argNum++
+	}
+	if filters.DateTo != "" {
+		whereClause += fmt.Sprintf(" AND me.created_at < $%d::date + 1", argNum)
+		args = append(args, filters.DateTo)
+		argNum++
+	}
+	if filters.CustomerID != nil {
+		whereClause += fmt.Sprintf(" AND me.customer_id = $%d", argNum)
+		args = append(args, *filters.CustomerID)
+		argNum++
+	}
+	if filters.SalesRepID != nil {
+		whereClause += fmt.Sprintf(" AND me.sales_rep_id = $%d", argNum)
+		args = append(args, *filters.SalesRepID)
+		argNum++
+	}
+
+	var total int64
+	err := s.db.QueryRow(ctx, fmt.Sprintf(`SELECT COUNT(*) FROM margin_exceptions me %s`, whereClause), args...).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count margin exceptions: %w", err)
+	}
+
+	offset := (filters.Page - 1) * filters.PageSize
+	query := fmt.Sprintf(`
+		SELECT me.id, me.order_id, so.order_number, me.order_line_id, me.product_id, p.sku, p.name,
+			   me.customer_id, c.name, me.sales_rep_id, COALESCE(e.first_name || ' ' || e.last_name, ''),
+			   me.quantity, me.unit_price, me.unit_cost, me.costing_method, me.margin_percent,
+			   (me.unit_cost - me.unit_price) * me.quantity, me.created_by, me.created_at
+		FROM margin_exceptions me
+		JOIN sales_orders so ON me.order_id = so.id
+		JOIN products p ON me.product_id = p.id
+		JOIN customers c ON me.customer_id = c.id
+		LEFT JOIN employees e ON me.sales_rep_id = e.id
+		%s
+		ORDER BY me.created_at DESC, me.id DESC
+		LIMIT $%d OFFSET $%d`, whereClause, argNum, argNum+1)
+	args = append(args, filters.PageSize, offset)
+
+	rows := s.db.Query(ctx, query, args...)
+	defer rows.Close()
+
+	exceptions := []models.MarginException{}
+	for rows.Next() {
+		var e models.MarginException
+		err := rows.Scan(&e.ID, &e.OrderID, &e.OrderNumber, &e.OrderLineID, &e.ProductID, &e.ProductSKU, &e.ProductName,
+			&e.CustomerID, &e.CustomerName, &e.SalesRepID, &e.SalesRepName,
+			&e.Quantity, &e.UnitPrice, &e.UnitCost, &e.CostingMethod, &e.MarginPercent,
+			&e.LostMargin, &e.CreatedBy, &e.CreatedAt)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan margin exception: %w", err)
+		}
+		exceptions = append(exceptions, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to get margin exceptions: %w", err)
+	}
+
+	return exceptions, total, nil
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/anas-dev-92/FoodHive/core/postgres"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/query"
 	"github.com/jackc/pgx/v5"
 )
 
@@ -59,9 +60,9 @@ func (s *productServiceImpl) Create(ctx context.Context, req *models.CreateProdu
 			sku, barcode, upc, name, description, category_id,
 			base_unit, is_catch_weight, catch_weight_unit, country_of_origin,
 			shelf_life_days, min_shelf_life_days, is_lot_tracked, is_serialized,
-			haccp_category, qc_required, is_active
+			haccp_category, qc_required, name_translations, is_active, costing_method
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, true
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, COALESCE($17, '{}'::jsonb), true, $18
 		) RETURNING id`
 
 	var id int
@@ -82,6 +83,8 @@ func (s *productServiceImpl) Create(ctx context.Context, req *models.CreateProdu
 		req.IsSerialized,
 		req.HACCPCategory,
 		req.QCRequired,
+		req.NameTranslations,
+		req.CostingMethod,
 	).Scan(&id)
 
 	if err != nil {
@@ -139,7 +142,7 @@ func (s *productServiceImpl) getProduct(ctx context.Context, whereClause string,
 		SELECT id, sku, barcode, upc, name, description, category_id,
 			   base_unit, is_catch_weight, catch_weight_unit, country_of_origin,
 			   shelf_life_days, min_shelf_life_days, is_lot_tracked, is_serialized,
-			   haccp_category, qc_required, is_active, created_at, updated_at
+			   haccp_category, qc_required, is_active, created_at, updated_at, name_translations, costing_method
 		FROM products
 		WHERE %s`, whereClause)
 
@@ -151,7 +154,7 @@ func (s *productServiceImpl) getProduct(ctx context.Context, whereClause string,
 		&p.ID, &p.SKU, &barcode, &upc, &p.Name, &description, &p.CategoryID,
 		&p.BaseUnit, &p.IsCatchWeight, &catchWeightUnit, &countryOfOrigin,
 		&shelfLifeDays, &minShelfLifeDays, &p.IsLotTracked, &p.IsSerialized,
-		&haccpCategory, &p.QCRequired, &p.IsActive, &p.CreatedAt, &p.UpdatedAt,
+		&haccpCategory, &p.QCRequired, &p.IsActive, &p.CreatedAt, &p.UpdatedAt, &p.NameTranslations, &p.CostingMethod,
 	)
 
 	if err != nil {
@@ -203,8 +206,10 @@ func (s *productServiceImpl) Update(ctx context.Context, id int, req *models.Upd
 			haccp_category = COALESCE($8, haccp_category),
 			qc_required = COALESCE($9, qc_required),
 			is_active = COALESCE($10, is_active),
+			name_translations = COALESCE($11, name_translations),
+			costing_method = COALESCE($12, costing_method),
 			updated_at = NOW()
-		WHERE id = $11`
+		WHERE id = $13`
 
 	result, err := s.db.Exec(ctx, query,
 		req.Name,
@@ -217,6 +222,8 @@ func (s *productServiceImpl) Update(ctx context.Context, id int, req *models.Upd
 		req.HACCPCategory,
 		req.QCRequired,
 		req.IsActive,
+		req.NameTranslations,
+		req.CostingMethod,
 		id,
 	)
 
@@ -244,15 +251,29 @@ func (s *productServiceImpl) Delete(ctx context.Context, id int) error {
 	return nil
 }
 
-func (s *productServiceImpl) List(ctx context.Context, filters *models.ProductListFilters) ([]models.Product, int64, error) {
-	// Set defaults
-	if filters.Page < 1 {
-		filters.Page = 1
-	}
-	if filters.PageSize < 1 || filters.PageSize > 100 {
-		filters.PageSize = 20
-	}
+// productListSchema whitelists the fields clients may filter and sort on.
+var productListSchema = query.Schema{
+	Fields: map[string]query.Field{
+		"id":                {Column: "id", Type: query.Int, Sortable: true},
+		"sku":               {Column: "sku", Type: query.String, Sortable: true},
+		"name":              {Column: "name", Type: query.String, Sortable: true},
+		"barcode":           {Column: "barcode", Type: query.String},
+		"category_id":       {Column: "category_id", Type: query.Int},
+		"base_unit":         {Column: "base_unit", Type: query.String},
+		"is_catch_weight":   {Column: "is_catch_weight", Type: query.Bool},
+		"country_of_origin": {Column: "country_of_origin", Type: query.String},
+		"shelf_life_days":   {Column: "shelf_life_days", Type: query.Int},
+		"is_lot_tracked":    {Column: "is_lot_tracked", Type: query.Bool},
+		"qc_required":       {Column: "qc_required", Type: query.Bool},
+		"is_active":         {Column: "is_active", Type: query.Bool},
+		"created_at":        {Column: "created_at", Type: query.Time, Sortable: true},
+		"updated_at":        {Column: "updated_at", Type: query.Time, Sortable: true},
+	},
+	Default: []query.Sort{{Field: "name"}},
+	Key:     "id",
+}
 
+func (s *productServiceImpl) List(ctx context.Context, filters *models.ProductListFilters) ([]models.Product, int64, error) {
 	// Build WHERE clause
 	whereClause := "WHERE 1=1"
 	args := []interface{}{}
@@ -276,42 +297,51 @@ func (s *productServiceImpl) List(ctx context.Context, filters *models.ProductLi
 		argNum++
 	}
 
-	// Get total count
-	countQuery := fmt.Sprintf("SELECT COUNT(*) FROM products %s", whereClause)
-	var total int64
-	err := s.db.QueryRow(ctx, countQuery, args...).Scan(&total)
+	params := query.Default(filters.Query, filters.Page, filters.PageSize)
+	q, err := query.Build(productListSchema, params, argNum)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to count products: %w", err)
+		return nil, 0, err
+	}
+	whereClause += q.Where
+	args = append(args, q.Args...)
+
+	// Get total count; keyset pages follow next_cursor instead
+	var total int64
+	if !params.Keyset() {
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM products %s", whereClause)
+		err := s.db.QueryRow(ctx, countQuery, args...).Scan(&total)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to count products: %w", err)
+		}
 	}
 
 	// Get paginated results
-	offset := (filters.Page - 1) * filters.PageSize
-	query := fmt.Sprintf(`
+	listQuery := fmt.Sprintf(`
 		SELECT id, sku, barcode, upc, name, description, category_id,
 			   base_unit, is_catch_weight, catch_weight_unit, country_of_origin,
 			   shelf_life_days, min_shelf_life_days, is_lot_tracked, is_serialized,
-			   haccp_category, qc_required, is_active, created_at, updated_at
+			   haccp_category, qc_required, is_active, created_at, updated_at, name_translations, costing_method, %s
 		FROM products
+		%s%s
 		%s
-		ORDER BY name ASC
-		LIMIT $%d OFFSET $%d`, whereClause, argNum, argNum+1)
-
-	args = append(args, filters.PageSize, offset)
+		%s`, q.Cursor, whereClause, q.Keyset, q.OrderBy, q.Limit)
 
-	rows := s.db.Query(ctx, query, args...)
+	rows := s.db.Query(ctx, listQuery, append(args, q.PageArgs...)...)
 	defer rows.Close()
 
 	var products []models.Product
+	var cursors []string
 	for rows.Next() {
 		var p models.Product
 		var barcode, upc, description, catchWeightUnit, countryOfOrigin, haccpCategory *string
 		var shelfLifeDays, minShelfLifeDays *int
+		var cursor string
 
 		err := rows.Scan(
 			&p.ID, &p.SKU, &barcode, &upc, &p.Name, &description, &p.CategoryID,
 			&p.BaseUnit, &p.IsCatchWeight, &catchWeightUnit, &countryOfOrigin,
 			&shelfLifeDays, &minShelfLifeDays, &p.IsLotTracked, &p.IsSerialized,
-			&haccpCategory, &p.QCRequired, &p.IsActive, &p.CreatedAt, &p.UpdatedAt,
+			&haccpCategory, &p.QCRequired, &p.IsActive, &p.CreatedAt, &p.UpdatedAt, &p.NameTranslations, &p.CostingMethod, &cursor,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan product: %w", err)
@@ -344,9 +374,13 @@ func (s *productServiceImpl) List(ctx context.Context, filters *models.ProductLi
 		}
 
 		products = append(products, p)
+		cursors = append(cursors, cursor)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list products: %w", err)
 	}
 
-	return products, total, nil
+	return products[:params.Trim(cursors)], total, nil
 }
 
 // ============================================
@@ -355,14 +389,14 @@ func (s *productServiceImpl) List(ctx context.Context, filters *models.ProductLi
 
 func (s *productServiceImpl) CreateCategory(ctx context.Context, req *models.ProductCategory) (int, error) {
 	query := `
-		INSERT INTO product_categories (code, name, parent_id, gl_sales_account_id, gl_cogs_account_id, gl_inventory_account_id)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO product_categories (code, name, parent_id, gl_sales_account_id, gl_cogs_account_id, gl_inventory_account_id, name_translations)
+		VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7, '{}'::jsonb))
 		RETURNING id`
 
 	var id int
 	err := s.db.QueryRow(ctx, query,
 		req.Code, req.Name, req.ParentID,
-		req.GLSalesAccountID, req.GLCOGSAccountID, req.GLInventoryAccountID,
+		req.GLSalesAccountID, req.GLCOGSAccountID, req.GLInventoryAccountID, req.NameTranslations,
 	).Scan(&id)
 
 	if err != nil {
@@ -373,7 +407,8 @@ func (s *productServiceImpl) CreateCategory(ctx context.Context, req *models.Pro
 
 func (s *productServiceImpl) GetCategoryByID(ctx context.Context, id int) (*models.ProductCategory, error) {
 	query := `
-		SELECT id, code, name, parent_id, gl_sales_account_id, gl_cogs_account_id, gl_inventory_account_id
+		SELECT id, code, name, parent_id, gl_sales_account_id, gl_cogs_account_id, gl_inventory_account_id,
+			   name_translations
 		FROM product_categories
 		WHERE id = $1`
 
@@ -381,7 +416,7 @@ func (s *productServiceImpl) GetCategoryByID(ctx context.Context, id int) (*mode
 	var code *string
 	err := s.db.QueryRow(ctx, query, id).Scan(
 		&c.ID, &code, &c.Name, &c.ParentID,
-		&c.GLSalesAccountID, &c.GLCOGSAccountID, &c.GLInventoryAccountID,
+		&c.GLSalesAccountID, &c.GLCOGSAccountID, &c.GLInventoryAccountID, &c.NameTranslations,
 	)
 
 	if err != nil {
@@ -402,12 +437,13 @@ func (s *productServiceImpl) UpdateCategory(ctx context.Context, id int, req *mo
 	query := `
 		UPDATE product_categories SET
 			code = $1, name = $2, parent_id = $3,
-			gl_sales_account_id = $4, gl_cogs_account_id = $5, gl_inventory_account_id = $6
+			gl_sales_account_id = $4, gl_cogs_account_id = $5, gl_inventory_account_id = $6,
+			name_translations = COALESCE($8, name_translations)
 		WHERE id = $7`
 
 	result, err := s.db.Exec(ctx, query,
 		req.Code, req.Name, req.ParentID,
-		req.GLSalesAccountID, req.GLCOGSAccountID, req.GLInventoryAccountID, id,
+		req.GLSalesAccountID, req.GLCOGSAccountID, req.GLInventoryAccountID, id, req.NameTranslations,
 	)
 
 	if err != nil {
@@ -450,7 +486,8 @@ func (s *productServiceImpl) DeleteCategory(ctx context.Context, id int) error {
 
 func (s *productServiceImpl) ListCategories(ctx context.Context) ([]models.ProductCategory, error) {
 	query := `
-		SELECT id, code, name, parent_id, gl_sales_account_id, gl_cogs_account_id, gl_inventory_account_id
+		SELECT id, code, name, parent_id, gl_sales_account_id, gl_cogs_account_id, gl_inventory_account_id,
+			   name_translations
 		FROM product_categories
 		ORDER BY name ASC`
 
@@ -463,7 +500,7 @@ func (s *productServiceImpl) ListCategories(ctx context.Context) ([]models.Produ
 		var code *string
 		err := rows.Scan(
 			&c.ID, &code, &c.Name, &c.ParentID,
-			&c.GLSalesAccountID, &c.GLCOGSAccountID, &c.GLInventoryAccountID,
+			&c.GLSalesAccountID, &c.GLCOGSAccountID, &c.GLInventoryAccountID, &c.NameTranslations,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan category: %w", err)
@@ -2,14 +2,25 @@ package purchase_order
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
 	"time"
 
 	"github.com/anas-dev-92/FoodHive/core/postgres"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	salesOrderService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/sales_order"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/query"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
 	"github.com/jackc/pgx/v5"
 )
 
+var (
+	ErrVendorNotFound        = errors.New("vendor not found")
+	ErrPurchaseOrderNotFound = errors.New("purchase order not found")
+	ErrLineNotFound          = errors.New("purchase order line not found")
+)
+
 // ============================================
 // Service Interface
 // ============================================
@@ -34,6 +45,9 @@ type PurchaseOrderService interface {
 	CreateReceiving(ctx context.Context, req *models.CreateReceivingRequest, receivedBy int) (int, error)
 	GetReceiving(ctx context.Context, id int) (*models.ReceivingWithDetails, error)
 	ListReceivings(ctx context.Context, poID *int, warehouseID *int, limit int) ([]models.ReceivingWithDetails, error)
+
+	// Replenishment
+	GetReplenishmentSuggestions(ctx context.Context, filters *models.ReplenishmentFilters) ([]models.ReplenishmentSuggestion, error)
 }
 
 // ============================================
@@ -53,6 +67,17 @@ func New(db postgres.Executor) PurchaseOrderService {
 // ============================================
 
 func (s *purchaseOrderServiceImpl) Create(ctx context.Context, req *models.CreatePurchaseOrderRequest, createdBy int) (int, error) {
+	// The vendor must belong to the company the order is placed by
+	var exists bool
+	err := s.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM vendors WHERE id = $1 AND company_id = $2)`,
+		req.VendorID, tenant.Company(ctx)).Scan(&exists)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get vendor: %w", err)
+	}
+	if !exists {
+		return 0, ErrVendorNotFound
+	}
+
 	// Generate PO number
 	poNumber := s.generatePONumber(ctx)
 
@@ -75,14 +100,14 @@ func (s *purchaseOrderServiceImpl) Create(ctx context.Context, req *models.Creat
 	query := `
 		INSERT INTO purchase_orders (
 			po_number, vendor_id, warehouse_id, expected_date, status,
-			subtotal, total_amount, notes, buyer_id, created_by
-		) VALUES ($1, $2, $3, $4, 'DRAFT', $5, $5, $6, $7, $8)
+			subtotal, total_amount, notes, buyer_id, created_by, company_id
+		) VALUES ($1, $2, $3, $4, 'DRAFT', $5, $5, $6, $7, $8, $9)
 		RETURNING id`
 
 	var id int
-	err := s.db.QueryRow(ctx, query,
+	err = s.db.QueryRow(ctx, query,
 		poNumber, req.VendorID, req.WarehouseID, expectedDate,
-		subtotal, req.Notes, req.BuyerID, createdBy,
+		subtotal, req.Notes, req.BuyerID, createdBy, tenant.Company(ctx),
 	).Scan(&id)
 
 	if err != nil {
@@ -137,13 +162,13 @@ func (s *purchaseOrderServiceImpl) getPurchaseOrder(ctx context.Context, whereCl
 		JOIN vendors v ON po.vendor_id = v.id
 		JOIN warehouses w ON po.warehouse_id = w.id
 		LEFT JOIN employees e ON po.buyer_id = e.id
-		WHERE %s`, whereClause)
+		WHERE %s AND po.company_id = $2`, whereClause)
 
 	var po models.PurchaseOrderWithDetails
 	var expectedDate, receivedDate *time.Time
 	var notes *string
 
-	err := s.db.QueryRow(ctx, query, arg).Scan(
+	err := s.db.QueryRow(ctx, query, arg, tenant.Company(ctx)).Scan(
 		&po.Order.ID, &po.Order.PONumber, &po.Order.VendorID, &po.Order.WarehouseID, &po.Order.OrderDate,
 		&expectedDate, &receivedDate, &po.Order.Status, &po.Order.Subtotal, &po.Order.TaxAmount,
 		&po.Order.FreightAmount, &po.Order.TotalAmount, &notes, &po.Order.BuyerID, &po.Order.CreatedBy,
@@ -221,8 +246,8 @@ func (s *purchaseOrderServiceImpl) Update(ctx context.Context, id int, req *mode
 		argNum++
 	}
 
-	query += fmt.Sprintf(" WHERE id = $%d", argNum)
-	args = append(args, id)
+	query += fmt.Sprintf(" WHERE id = $%d AND company_id = $%d", argNum, argNum+1)
+	args = append(args, id, tenant.Company(ctx))
 
 	result, err := s.db.Exec(ctx, query, args...)
 	if err != nil {
@@ -238,7 +263,8 @@ func (s *purchaseOrderServiceImpl) Update(ctx context.Context, id int, req *mode
 
 func (s *purchaseOrderServiceImpl) Delete(ctx context.Context, id int) error {
 	// Only allow deletion of DRAFT POs
-	result, err := s.db.Exec(ctx, `DELETE FROM purchase_orders WHERE id = $1 AND status = 'DRAFT'`, id)
+	result, err := s.db.Exec(ctx, `DELETE FROM purchase_orders WHERE id = $1 AND company_id = $2 AND status = 'DRAFT'`,
+		id, tenant.Company(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to delete purchase order: %w", err)
 	}
@@ -248,17 +274,29 @@ func (s *purchaseOrderServiceImpl) Delete(ctx context.Context, id int) error {
 	return nil
 }
 
-func (s *purchaseOrderServiceImpl) List(ctx context.Context, filters *models.PurchaseOrderListFilters) ([]models.PurchaseOrderWithDetails, int64, error) {
-	if filters.Page < 1 {
-		filters.Page = 1
-	}
-	if filters.PageSize < 1 || filters.PageSize > 100 {
-		filters.PageSize = 20
-	}
+// purchaseOrderListSchema whitelists the fields clients may filter and sort on.
+var purchaseOrderListSchema = query.Schema{
+	Fields: map[string]query.Field{
+		"id":            {Column: "po.id", Type: query.Int, Sortable: true},
+		"po_number":     {Column: "po.po_number", Type: query.String, Sortable: true},
+		"vendor_id":     {Column: "po.vendor_id", Type: query.Int},
+		"warehouse_id":  {Column: "po.warehouse_id", Type: query.Int},
+		"order_date":    {Column: "po.order_date", Type: query.Date, Sortable: true},
+		"expected_date": {Column: "po.expected_date", Type: query.Date},
+		"status":        {Column: "po.status::text", Type: query.String},
+		"total_amount":  {Column: "po.total_amount", Type: query.Number, Sortable: true},
+		"buyer_id":      {Column: "po.buyer_id", Type: query.Int},
+		"vendor_name":   {Column: "v.name", Type: query.String, Sortable: true},
+		"created_at":    {Column: "po.created_at", Type: query.Time, Sortable: true},
+	},
+	Default: []query.Sort{{Field: "order_date", Desc: true}},
+	Key:     "id",
+}
 
-	whereClause := "WHERE 1=1"
-	args := []interface{}{}
-	argNum := 1
+func (s *purchaseOrderServiceImpl) List(ctx context.Context, filters *models.PurchaseOrderListFilters) ([]models.PurchaseOrderWithDetails, int64, error) {
+	whereClause := "WHERE po.company_id = $1"
+	args := []interface{}{tenant.Company(ctx)}
+	argNum := 2
 
 	if filters.VendorID != nil {
 		whereClause += fmt.Sprintf(" AND po.vendor_id = $%d", argNum)
@@ -291,37 +329,53 @@ func (s *purchaseOrderServiceImpl) List(ctx context.Context, filters *models.Pur
 		argNum++
 	}
 
-	// Count
-	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM purchase_orders po %s`, whereClause)
+	params := query.Default(filters.Query, filters.Page, filters.PageSize)
+	q, err := query.Build(purchaseOrderListSchema, params, argNum)
+	if err != nil {
+		return nil, 0, err
+	}
+	whereClause += q.Where
+	args = append(args, q.Args...)
+
+	// Count; keyset pages follow next_cursor instead
 	var total int64
-	s.db.QueryRow(ctx, countQuery, args...).Scan(&total)
+	if !params.Keyset() {
+		// Filters and the schema can refer to the joined vendor columns
+		countQuery := fmt.Sprintf(`
+			SELECT COUNT(*)
+			FROM purchase_orders po
+			JOIN vendors v ON po.vendor_id = v.id
+			%s`, whereClause)
+		if err := s.db.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+			return nil, 0, fmt.Errorf("failed to count purchase orders: %w", err)
+		}
+	}
 
 	// Query
-	offset := (filters.Page - 1) * filters.PageSize
-	query := fmt.Sprintf(`
+	listQuery := fmt.Sprintf(`
 		SELECT po.id, po.po_number, po.vendor_id, po.warehouse_id, po.order_date,
 			   po.expected_date, po.received_date, po.status, po.subtotal, po.tax_amount,
 			   po.freight_amount, po.total_amount, po.notes, po.buyer_id, po.created_by,
 			   po.created_at, po.updated_at,
 			   v.name as vendor_name, v.code as vendor_code,
 			   w.name as warehouse_name,
-			   COALESCE(e.first_name || ' ' || e.last_name, '') as buyer_name
+			   COALESCE(e.first_name || ' ' || e.last_name, '') as buyer_name, %s
 		FROM purchase_orders po
 		JOIN vendors v ON po.vendor_id = v.id
 		JOIN warehouses w ON po.warehouse_id = w.id
 		LEFT JOIN employees e ON po.buyer_id = e.id
+		%s%s
 		%s
-		ORDER BY po.order_date DESC, po.id DESC
-		LIMIT $%d OFFSET $%d`, whereClause, argNum, argNum+1)
+		%s`, q.Cursor, whereClause, q.Keyset, q.OrderBy, q.Limit)
 
-	args = append(args, filters.PageSize, offset)
-
-	rows := s.db.Query(ctx, query, args...)
+	rows := s.db.Query(ctx, listQuery, append(args, q.PageArgs...)...)
 	defer rows.Close()
 
 	var orders []models.PurchaseOrderWithDetails
+	var cursors []string
 	for rows.Next() {
 		var po models.PurchaseOrderWithDetails
+		var cursor string
 		var expectedDate, receivedDate *time.Time
 		var notes *string
 
@@ -330,7 +384,7 @@ func (s *purchaseOrderServiceImpl) List(ctx context.Context, filters *models.Pur
 			&expectedDate, &receivedDate, &po.Order.Status, &po.Order.Subtotal, &po.Order.TaxAmount,
 			&po.Order.FreightAmount, &po.Order.TotalAmount, &notes, &po.Order.BuyerID, &po.Order.CreatedBy,
 			&po.Order.CreatedAt, &po.Order.UpdatedAt,
-			&po.VendorName, &po.VendorCode, &po.WarehouseName, &po.BuyerName,
+			&po.VendorName, &po.VendorCode, &po.WarehouseName, &po.BuyerName, &cursor,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan purchase order: %w", err)
@@ -339,15 +393,19 @@ func (s *purchaseOrderServiceImpl) List(ctx context.Context, filters *models.Pur
 			po.Order.Notes = *notes
 		}
 		orders = append(orders, po)
+		cursors = append(cursors, cursor)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list purchase orders: %w", err)
 	}
 
-	return orders, total, nil
+	return orders[:params.Trim(cursors)], total, nil
 }
 
 func (s *purchaseOrderServiceImpl) Submit(ctx context.Context, id int) error {
 	result, err := s.db.Exec(ctx,
-		`UPDATE purchase_orders SET status = 'SUBMITTED', updated_at = NOW() WHERE id = $1 AND status = 'DRAFT'`,
-		id,
+		`UPDATE purchase_orders SET status = 'SUBMITTED', updated_at = NOW() WHERE id = $1 AND company_id = $2 AND status = 'DRAFT'`,
+		id, tenant.Company(ctx),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to submit purchase order: %w", err)
@@ -360,8 +418,9 @@ func (s *purchaseOrderServiceImpl) Submit(ctx context.Context, id int) error {
 
 func (s *purchaseOrderServiceImpl) Cancel(ctx context.Context, id int) error {
 	result, err := s.db.Exec(ctx,
-		`UPDATE purchase_orders SET status = 'CANCELLED', updated_at = NOW() WHERE id = $1 AND status IN ('DRAFT', 'SUBMITTED')`,
-		id,
+		`UPDATE purchase_orders SET status = 'CANCELLED', updated_at = NOW()
+		 WHERE id = $1 AND company_id = $2 AND status IN ('DRAFT', 'SUBMITTED')`,
+		id, tenant.Company(ctx),
 	)
 	if err != nil {
 		return fmt.Errorf("failed to cancel purchase order: %w", err)
@@ -377,6 +436,16 @@ func (s *purchaseOrderServiceImpl) Cancel(ctx context.Context, id int) error {
 // ============================================
 
 func (s *purchaseOrderServiceImpl) AddLine(ctx context.Context, poID int, req *models.CreatePurchaseOrderLineRequest) (int, error) {
+	var exists bool
+	err := s.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM purchase_orders WHERE id = $1 AND company_id = $2)`,
+		poID, tenant.Company(ctx)).Scan(&exists)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get purchase order: %w", err)
+	}
+	if !exists {
+		return 0, ErrPurchaseOrderNotFound
+	}
+
 	// Get next line number
 	var maxLine int
 	s.db.QueryRow(ctx, `SELECT COALESCE(MAX(line_number), 0) FROM purchase_order_lines WHERE po_id = $1`, poID).Scan(&maxLine)
@@ -396,7 +465,7 @@ func (s *purchaseOrderServiceImpl) AddLine(ctx context.Context, poID int, req *m
 		RETURNING id`
 
 	var id int
-	err := s.db.QueryRow(ctx, query,
+	err = s.db.QueryRow(ctx, query,
 		poID, maxLine+1, req.ProductID, req.Description, req.Quantity,
 		req.UnitOfMeasure, req.UnitCost, lineTotal, expDate,
 	).Scan(&id)
@@ -420,19 +489,23 @@ func (s *purchaseOrderServiceImpl) UpdateLine(ctx context.Context, lineID int, r
 	}
 
 	query := `
-		UPDATE purchase_order_lines SET
+		UPDATE purchase_order_lines pol SET
 			product_id = $1, description = $2, quantity_ordered = $3,
 			unit_of_measure = $4, unit_cost = $5, line_total = $6, expected_date = $7
-		WHERE id = $8
-		RETURNING po_id`
+		FROM purchase_orders po
+		WHERE pol.id = $8 AND po.id = pol.po_id AND po.company_id = $9
+		RETURNING pol.po_id`
 
 	var poID int
 	err := s.db.QueryRow(ctx, query,
 		req.ProductID, req.Description, req.Quantity,
-		req.UnitOfMeasure, req.UnitCost, lineTotal, expDate, lineID,
+		req.UnitOfMeasure, req.UnitCost, lineTotal, expDate, lineID, tenant.Company(ctx),
 	).Scan(&poID)
 
 	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrLineNotFound
+		}
 		return fmt.Errorf("failed to update PO line: %w", err)
 	}
 
@@ -442,8 +515,14 @@ func (s *purchaseOrderServiceImpl) UpdateLine(ctx context.Context, lineID int, r
 
 func (s *purchaseOrderServiceImpl) DeleteLine(ctx context.Context, lineID int) error {
 	var poID int
-	err := s.db.QueryRow(ctx, `DELETE FROM purchase_order_lines WHERE id = $1 RETURNING po_id`, lineID).Scan(&poID)
+	err := s.db.QueryRow(ctx, `
+		DELETE FROM purchase_order_lines pol USING purchase_orders po
+		WHERE pol.id = $1 AND po.id = pol.po_id AND po.company_id = $2
+		RETURNING pol.po_id`, lineID, tenant.Company(ctx)).Scan(&poID)
 	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrLineNotFound
+		}
 		return fmt.Errorf("failed to delete PO line: %w", err)
 	}
 	s.recalculateTotals(ctx, poID)
@@ -455,6 +534,47 @@ func (s *purchaseOrderServiceImpl) DeleteLine(ctx context.Context, lineID int) e
 // ============================================
 
 func (s *purchaseOrderServiceImpl) CreateReceiving(ctx context.Context, req *models.CreateReceivingRequest, receivedBy int) (int, error) {
+	companyID := tenant.Company(ctx)
+
+	// The vendor, the purchase order and its lines must all belong to the
+	// receiving company
+	var exists bool
+	err := s.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM vendors WHERE id = $1 AND company_id = $2)`,
+		req.VendorID, companyID).Scan(&exists)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get vendor: %w", err)
+	}
+	if !exists {
+		return 0, ErrVendorNotFound
+	}
+	if req.POID != nil {
+		err = s.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM purchase_orders WHERE id = $1 AND company_id = $2)`,
+			*req.POID, companyID).Scan(&exists)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get purchase order: %w", err)
+		}
+		if !exists {
+			return 0, ErrPurchaseOrderNotFound
+		}
+	}
+	for _, line := range req.Lines {
+		if line.POLineID == nil {
+			continue
+		}
+		err = s.db.QueryRow(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM purchase_order_lines pol
+				JOIN purchase_orders po ON po.id = pol.po_id
+				WHERE pol.id = $1 AND po.company_id = $2 AND ($3::int IS NULL OR pol.po_id = $3)
+			)`, *line.POLineID, companyID, req.POID).Scan(&exists)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get PO line: %w", err)
+		}
+		if !exists {
+			return 0, ErrLineNotFound
+		}
+	}
+
 	// Generate receiving number
 	recvNumber := s.generateReceivingNumber(ctx)
 
@@ -466,7 +586,7 @@ func (s *purchaseOrderServiceImpl) CreateReceiving(ctx context.Context, req *mod
 		RETURNING id`
 
 	var id int
-	err := s.db.QueryRow(ctx, query,
+	err = s.db.QueryRow(ctx, query,
 		recvNumber, req.POID, req.WarehouseID, req.VendorID, req.Notes, receivedBy,
 	).Scan(&id)
 
@@ -533,6 +653,37 @@ func (s *purchaseOrderServiceImpl) CreateReceiving(ctx context.Context, req *mod
 		s.updatePOStatus(ctx, *req.POID)
 	}
 
+	// Special orders take what was bought for them before other backorders
+	salesOrders := salesOrderService.New(s.db)
+	for _, line := range req.Lines {
+		if line.POLineID == nil {
+			continue
+		}
+		var orderLineID *int
+		s.db.QueryRow(ctx, `
+			SELECT rl.order_line_id FROM purchase_order_lines pol
+			JOIN purchase_requisition_lines rl ON rl.id = pol.requisition_line_id
+			WHERE pol.id = $1`, *line.POLineID).Scan(&orderLineID)
+		if orderLineID == nil {
+			continue
+		}
+		if _, err := salesOrders.ReceiveSpecialOrder(ctx, *orderLineID, line.Quantity); err != nil {
+			log.Printf("⚠ Special order allocation for receiving %s: %v", recvNumber, err)
+		}
+	}
+
+	// Received stock fills waiting backorders, oldest first. The receipt is
+	// already on hand, so a failure is logged and the report can release
+	// them later.
+	productIDs := make([]int, 0, len(req.Lines))
+	for _, line := range req.Lines {
+		productIDs = append(productIDs, line.ProductID)
+	}
+	release := &models.ReleaseBackordersRequest{WarehouseID: req.WarehouseID, ProductIDs: productIDs}
+	if _, err := salesOrders.ReleaseBackorders(ctx, release); err != nil {
+		log.Printf("⚠ Backorder release for receiving %s: %v", recvNumber, err)
+	}
+
 	return id, nil
 }
 
@@ -548,12 +699,12 @@ func (s *purchaseOrderServiceImpl) GetReceiving(ctx context.Context, id int) (*m
 		JOIN warehouses w ON r.warehouse_id = w.id
 		LEFT JOIN purchase_orders po ON r.po_id = po.id
 		LEFT JOIN employees e ON r.received_by = e.id
-		WHERE r.id = $1`
+		WHERE r.id = $1 AND v.company_id = $2`
 
 	var recv models.ReceivingWithDetails
 	var notes *string
 
-	err := s.db.QueryRow(ctx, query, id).Scan(
+	err := s.db.QueryRow(ctx, query, id, tenant.Company(ctx)).Scan(
 		&recv.Receiving.ID, &recv.Receiving.ReceivingNumber, &recv.Receiving.POID, &recv.Receiving.WarehouseID,
 		&recv.Receiving.VendorID, &recv.Receiving.ReceivedDate, &notes, &recv.Receiving.ReceivedBy,
 		&recv.Receiving.CreatedAt, &recv.VendorName, &recv.WarehouseName, &recv.PONumber, &recv.ReceiverName,
@@ -611,9 +762,9 @@ func (s *purchaseOrderServiceImpl) ListReceivings(ctx context.Context, poID *int
 		limit = 100
 	}
 
-	whereClause := "WHERE 1=1"
-	args := []interface{}{}
-	argNum := 1
+	whereClause := "WHERE v.company_id = $1"
+	args := []interface{}{tenant.Company(ctx)}
+	argNum := 2
 
 	if poID != nil {
 		whereClause += fmt.Sprintf(" AND r.po_id = $%d", argNum)
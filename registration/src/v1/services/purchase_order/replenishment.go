@@ -0,0 +1,111 @@
+package purchase_order
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	inventoryService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/inventory"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+)
+
+// ============================================
+// Replenishment
+// ============================================
+
+// GetReplenishmentSuggestions lists what the warehouse should reorder. A
+// product with recent lost sales is listed even above its reorder point,
+// so buyers see demand the stock levels alone would hide.
+func (s *purchaseOrderServiceImpl) GetReplenishmentSuggestions(ctx context.Context, filters *models.ReplenishmentFilters) ([]models.ReplenishmentSuggestion, error) {
+	weeks := filters.Weeks
+	if weeks <= 0 {
+		weeks = 4
+	}
+
+	vendorClause := ""
+	args := []interface{}{filters.WarehouseID, tenant.Company(ctx), weeks, inventoryService.QuarantineLocation}
+	if filters.VendorID != nil {
+		vendorClause = "AND p.primary_vendor_id = $5"
+		args = append(args, *filters.VendorID)
+	}
+
+	query := fmt.Sprintf(`
+		WITH stock AS (
+			SELECT p.id, p.sku, p.name, p.primary_vendor_id,
+				   COALESCE(p.reorder_point, 0) AS reorder_point, COALESCE(p.reorder_quantity, 0) AS reorder_quantity,
+				   COALESCE(p.last_purchase_cost, 0) AS last_cost,
+				   COALESCE((
+					   SELECT SUM(quantity_available) FROM inventory
+					   WHERE product_id = p.id AND warehouse_id = $1 AND location_code IS DISTINCT FROM $4
+				   ), 0) AS available,
+				   COALESCE((
+					   SELECT SUM(pol.quantity_ordered - pol.quantity_received)
+					   FROM purchase_order_lines pol JOIN purchase_orders po ON po.id = pol.po_id
+					   WHERE pol.product_id = p.id AND po.warehouse_id = $1 AND po.company_id = $2
+						 AND po.status IN ('DRAFT', 'SUBMITTED', 'CONFIRMED', 'PARTIAL')
+				   ), 0) AS on_order,
+				   COALESCE((
+					   SELECT SUM(sol.quantity_backordered)
+					   FROM sales_order_lines sol JOIN sales_orders so ON so.id = sol.order_id
+					   WHERE sol.product_id = p.id AND so.warehouse_id = $1 AND so.company_id = $2
+						 AND so.status = 'CONFIRMED'
+				   ), 0) AS backordered,
+				   COALESCE(lost.recent_qty, 0) AS lost_qty, COALESCE(lost.recent_revenue, 0) AS lost_revenue,
+				   COALESCE(lost.prior_qty, 0) AS prior_lost_qty
+			FROM products p
+			LEFT JOIN LATERAL (
+				SELECT SUM(q) FILTER (WHERE ls.created_at >= CURRENT_DATE - $3 * INTERVAL '1 week') AS recent_qty,
+					   SUM(ls.lost_revenue) FILTER (WHERE ls.created_at >= CURRENT_DATE - $3 * INTERVAL '1 week') AS recent_revenue,
+					   SUM(q) FILTER (WHERE ls.created_at < CURRENT_DATE - $3 * INTERVAL '1 week') AS prior_qty
+				FROM lost_sales ls
+				CROSS JOIN LATERAL (SELECT GREATEST(ls.quantity_requested - COALESCE(ls.quantity_available, 0), 0) AS q) lq
+				WHERE ls.product_id = p.id AND ls.warehouse_id = $1 AND ls.company_id = $2
+				  AND ls.created_at >= CURRENT_DATE - 2 * $3 * INTERVAL '1 week'
+			) lost ON true
+			WHERE p.is_active %s
+		)
+		SELECT st.id, st.sku, st.name, st.primary_vendor_id, COALESCE(v.name, ''),
+			   COALESCE(vp.unit_cost, vp.last_purchase_price, st.last_cost),
+			   st.available, st.on_order, st.backordered, st.reorder_point, st.reorder_quantity,
+			   st.lost_qty, st.lost_revenue, st.prior_lost_qty
+		FROM stock st
+		LEFT JOIN vendors v ON v.id = st.primary_vendor_id
+		LEFT JOIN vendor_products vp ON vp.vendor_id = st.primary_vendor_id AND vp.product_id = st.id
+		WHERE st.available + st.on_order - st.backordered <= st.reorder_point OR st.lost_qty > 0
+		ORDER BY st.lost_revenue DESC, st.sku`, vendorClause)
+
+	rows := s.db.Query(ctx, query, args...)
+	defer rows.Close()
+
+	suggestions := []models.ReplenishmentSuggestion{}
+	for rows.Next() {
+		var sg models.ReplenishmentSuggestion
+		err := rows.Scan(
+			&sg.ProductID, &sg.ProductSKU, &sg.ProductName, &sg.VendorID, &sg.VendorName,
+			&sg.UnitCost,
+			&sg.Available, &sg.OnOrder, &sg.Backordered, &sg.ReorderPoint, &sg.ReorderQuantity,
+			&sg.LostQuantity, &sg.LostRevenue, &sg.PriorLostQuantity,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan replenishment suggestion: %w", err)
+		}
+
+		sg.Position = sg.Available + sg.OnOrder - sg.Backordered
+		if sg.Position <= sg.ReorderPoint {
+			sg.SuggestedQuantity = sg.ReorderPoint + sg.ReorderQuantity - sg.Position
+		}
+		switch {
+		case sg.LostQuantity > sg.PriorLostQuantity:
+			sg.LostTrend = "UP"
+		case sg.LostQuantity < sg.PriorLostQuantity:
+			sg.LostTrend = "DOWN"
+		default:
+			sg.LostTrend = "FLAT"
+		}
+		suggestions = append(suggestions, sg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get replenishment suggestions: %w", err)
+	}
+	return suggestions, nil
+}
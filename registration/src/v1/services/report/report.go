@@ -0,0 +1,679 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/anas-dev-92/FoodHive/core/mailer"
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	arService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/ar"
+	documentService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/document"
+	inventoryService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/inventory"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrNotFound         = errors.New("report subscription not found")
+	ErrDeliveryNotFound = errors.New("report delivery not found")
+	ErrNotRetryable     = errors.New("only failed or retrying deliveries can be retried")
+)
+
+const (
+	// maxAttempts is how often a delivery is tried before it is marked FAILED.
+	maxAttempts = 5
+	// retryBackoff is the wait after the first failure, doubled on each retry.
+	retryBackoff = 5 * time.Minute
+	// batchSize caps the deliveries sent per scheduler tick.
+	batchSize = 20
+)
+
+// ReportService manages report subscriptions and emails the rendered reports
+// on their schedule.
+type ReportService interface {
+	// Subscriptions
+	CreateSubscription(ctx context.Context, req *models.CreateReportSubscriptionRequest, createdBy int) (int, error)
+	GetSubscription(ctx context.Context, id int) (*models.ReportSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]models.ReportSubscription, error)
+	UpdateSubscription(ctx context.Context, id int, req *models.UpdateReportSubscriptionRequest) error
+	DeleteSubscription(ctx context.Context, id int) error
+
+	// Running
+	Preview(ctx context.Context, id int) (*models.RenderedDocument, error)
+	SendNow(ctx context.Context, id int) (*models.ReportDelivery, error)
+
+	// Delivery log
+	ListDeliveries(ctx context.Context, filters *models.ReportDeliveryFilters) ([]models.ReportDelivery, int64, error)
+	GetDelivery(ctx context.Context, id int) (*models.ReportDelivery, error)
+	RetryDelivery(ctx context.Context, id int) (*models.ReportDelivery, error)
+
+	// Scheduling
+	ProcessDue(ctx context.Context) (int, error)
+	RunScheduler(ctx context.Context, interval time.Duration)
+}
+
+type reportServiceImpl struct {
+	db        postgres.Connection
+	mailer    mailer.Mailer
+	documents documentService.DocumentService
+	ar        arService.ARService
+	inventory inventoryService.InventoryService
+}
+
+// New creates the report service. The document service renders PDF output
+// on the company letterhead.
+func New(db postgres.Connection, mail mailer.Mailer, documents documentService.DocumentService) ReportService {
+	return &reportServiceImpl{
+		db:        db,
+		mailer:    mail,
+		documents: documents,
+		ar:        arService.New(db),
+		inventory: inventoryService.New(db),
+	}
+}
+
+// ============================================
+// Subscriptions
+// ============================================
+
+func (s *reportServiceImpl) CreateSubscription(ctx context.Context, req *models.CreateReportSubscriptionRequest, createdBy int) (int, error) {
+	sub := models.ReportSubscription{
+		Frequency:  req.Frequency,
+		TimeOfDay:  req.TimeOfDay,
+		DayOfWeek:  req.DayOfWeek,
+		DayOfMonth: req.DayOfMonth,
+		Timezone:   req.Timezone,
+	}
+	next, err := nextRun(&sub, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	params, err := json.Marshal(req.Parameters)
+	if err != nil {
+		return 0, fmt.Errorf("encoding parameters: %w", err)
+	}
+
+	var by *int
+	if createdBy > 0 {
+		by = &createdBy
+	}
+
+	var id int
+	err = s.db.QueryRow(ctx, `
+		INSERT INTO report_subscriptions (
+			name, report_type, parameters, format, frequency, time_of_day, day_of_week,
+			day_of_month, timezone, recipients, next_run_at, created_by, company_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id`,
+		strings.TrimSpace(req.Name), req.ReportType, params, req.Format, req.Frequency, req.TimeOfDay,
+		req.DayOfWeek, req.DayOfMonth, req.Timezone, cleanRecipients(req.Recipients), next, by, tenant.Company(ctx),
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("creating report subscription: %w", err)
+	}
+
+	return id, nil
+}
+
+const subscriptionColumns = `
+	id, name, report_type, parameters, format, frequency, time_of_day, day_of_week,
+	day_of_month, timezone, recipients, is_active, next_run_at, last_run_at,
+	created_by, created_at, updated_at, company_id`
+
+func scanSubscription(row pgx.Row) (*models.ReportSubscription, error) {
+	var sub models.ReportSubscription
+	var params []byte
+	err := row.Scan(
+		&sub.ID, &sub.Name, &sub.ReportType, &params, &sub.Format, &sub.Frequency, &sub.TimeOfDay, &sub.DayOfWeek,
+		&sub.DayOfMonth, &sub.Timezone, &sub.Recipients, &sub.IsActive, &sub.NextRunAt, &sub.LastRunAt,
+		&sub.CreatedBy, &sub.CreatedAt, &sub.UpdatedAt, &sub.CompanyID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if sub.Parameters, err = models.ParseReportParameters(params); err != nil {
+		return nil, fmt.Errorf("decoding parameters: %w", err)
+	}
+	return &sub, nil
+}
+
+func (s *reportServiceImpl) GetSubscription(ctx context.Context, id int) (*models.ReportSubscription, error) {
+	sub, err := scanSubscription(s.db.QueryRow(ctx,
+		`SELECT `+subscriptionColumns+` FROM report_subscriptions WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("getting report subscription: %w", err)
+	}
+	return sub, nil
+}
+
+func (s *reportServiceImpl) ListSubscriptions(ctx context.Context) ([]models.ReportSubscription, error) {
+	rows := s.db.Query(ctx, `SELECT `+subscriptionColumns+` FROM report_subscriptions ORDER BY name, id`)
+	defer rows.Close()
+
+	var subs []models.ReportSubscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning report subscription: %w", err)
+		}
+		subs = append(subs, *sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing report subscriptions: %w", err)
+	}
+
+	return subs, nil
+}
+
+func (s *reportServiceImpl) UpdateSubscription(ctx context.Context, id int, req *models.UpdateReportSubscriptionRequest) error {
+	sub, err := s.GetSubscription(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if req.Name != nil {
+		sub.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Parameters != nil {
+		sub.Parameters = *req.Parameters
+	}
+	if req.Format != nil {
+		sub.Format = *req.Format
+	}
+	if req.Frequency != nil {
+		sub.Frequency = *req.Frequency
+	}
+	if req.TimeOfDay != nil {
+		sub.TimeOfDay = *req.TimeOfDay
+	}
+	if req.DayOfWeek != nil {
+		sub.DayOfWeek = req.DayOfWeek
+	}
+	if req.DayOfMonth != nil {
+		sub.DayOfMonth = req.DayOfMonth
+	}
+	if req.Timezone != nil {
+		sub.Timezone = *req.Timezone
+	}
+	if req.Recipients != nil {
+		sub.Recipients = cleanRecipients(req.Recipients)
+	}
+	if req.IsActive != nil {
+		sub.IsActive = *req.IsActive
+	}
+
+	// The schedule may have changed, so always recompute the next run.
+	next, err := nextRun(sub, time.Now())
+	if err != nil {
+		return err
+	}
+
+	params, err := json.Marshal(sub.Parameters)
+	if err != nil {
+		return fmt.Errorf("encoding parameters: %w", err)
+	}
+
+	_, err = s.db.Exec(ctx, `
+		UPDATE report_subscriptions
+		SET name = $1, parameters = $2, format = $3, frequency = $4, time_of_day = $5,
+			day_of_week = $6, day_of_month = $7, timezone = $8, recipients = $9,
+			is_active = $10, next_run_at = $11, updated_at = NOW()
+		WHERE id = $12`,
+		sub.Name, params, sub.Format, sub.Frequency, sub.TimeOfDay,
+		sub.DayOfWeek, sub.DayOfMonth, sub.Timezone, sub.Recipients,
+		sub.IsActive, next, id,
+	)
+	if err != nil {
+		return fmt.Errorf("updating report subscription: %w", err)
+	}
+
+	return nil
+}
+
+func (s *reportServiceImpl) DeleteSubscription(ctx context.Context, id int) error {
+	result, err := s.db.Exec(ctx, `DELETE FROM report_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("deleting report subscription: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ============================================
+// Running
+// ============================================
+
+func (s *reportServiceImpl) Preview(ctx context.Context, id int) (*models.RenderedDocument, error) {
+	sub, err := s.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	_, file, err := s.render(ctx, sub, time.Now())
+	return file, err
+}
+
+// SendNow queues an unscheduled delivery and sends it straight away. The
+// delivery is returned whatever the outcome; a failed send is retried by the
+// scheduler like any other.
+func (s *reportServiceImpl) SendNow(ctx context.Context, id int) (*models.ReportDelivery, error) {
+	sub, err := s.GetSubscription(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveryID int
+	err = s.db.QueryRow(ctx, `
+		INSERT INTO report_deliveries (subscription_id, scheduled_for, status, next_attempt_at, recipients)
+		VALUES ($1, NOW(), 'PENDING', NOW(), $2)
+		RETURNING id`, sub.ID, sub.Recipients,
+	).Scan(&deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("queuing report delivery: %w", err)
+	}
+
+	if _, err := s.deliverNext(ctx, &deliveryID); err != nil {
+		return nil, err
+	}
+
+	return s.GetDelivery(ctx, deliveryID)
+}
+
+// ============================================
+// Delivery Log
+// ============================================
+
+const deliveryColumns = `
+	d.id, d.subscription_id, s.name, s.report_type, d.scheduled_for, d.status, d.attempts,
+	COALESCE(d.last_error, ''), d.next_attempt_at, d.recipients, COALESCE(d.file_name, ''),
+	d.sent_at, d.created_at`
+
+func scanDelivery(row pgx.Row) (*models.ReportDelivery, error) {
+	var d models.ReportDelivery
+	err := row.Scan(
+		&d.ID, &d.SubscriptionID, &d.SubscriptionName, &d.ReportType, &d.ScheduledFor, &d.Status, &d.Attempts,
+		&d.LastError, &d.NextAttemptAt, &d.Recipients, &d.FileName,
+		&d.SentAt, &d.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+func (s *reportServiceImpl) ListDeliveries(ctx context.Context, filters *models.ReportDeliveryFilters) ([]models.ReportDelivery, int64, error) {
+	if filters.Page < 1 {
+		filters.Page = 1
+	}
+	if filters.PageSize < 1 || filters.PageSize > 100 {
+		filters.PageSize = 20
+	}
+
+	whereClause := "WHERE 1=1"
+	args := []interface{}{}
+	argNum := 1
+
+	if filters.SubscriptionID != nil {
+		whereClause += fmt.Sprintf(" AND d.subscription_id = $%d", argNum)
+		args = append(args, *filters.SubscriptionID)
+		argNum++
+	}
+	if filters.Status != "" {
+		whereClause += fmt.Sprintf(" AND d.status = $%d", argNum)
+		args = append(args, filters.Status)
+		argNum++
+	}
+
+	var total int64
+	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM report_deliveries d %s`, whereClause)
+	if err := s.db.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("counting report deliveries: %w", err)
+	}
+
+	offset := (filters.Page - 1) * filters.PageSize
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM report_deliveries d
+		JOIN report_subscriptions s ON d.subscription_id = s.id
+		%s
+		ORDER BY d.created_at DESC, d.id DESC
+		LIMIT $%d OFFSET $%d`, deliveryColumns, whereClause, argNum, argNum+1)
+	args = append(args, filters.PageSize, offset)
+
+	rows := s.db.Query(ctx, query, args...)
+	defer rows.Close()
+
+	var deliveries []models.ReportDelivery
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("scanning report delivery: %w", err)
+		}
+		deliveries = append(deliveries, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("listing report deliveries: %w", err)
+	}
+
+	return deliveries, total, nil
+}
+
+func (s *reportServiceImpl) GetDelivery(ctx context.Context, id int) (*models.ReportDelivery, error) {
+	d, err := scanDelivery(s.db.QueryRow(ctx, `
+		SELECT `+deliveryColumns+`
+		FROM report_deliveries d
+		JOIN report_subscriptions s ON d.subscription_id = s.id
+		WHERE d.id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, fmt.Errorf("getting report delivery: %w", err)
+	}
+	return d, nil
+}
+
+// RetryDelivery resets the attempt counter of a failed delivery and sends it
+// again immediately.
+func (s *reportServiceImpl) RetryDelivery(ctx context.Context, id int) (*models.ReportDelivery, error) {
+	d, err := s.GetDelivery(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if d.Status != models.ReportDeliveryFailed && d.Status != models.ReportDeliveryRetrying {
+		return nil, ErrNotRetryable
+	}
+
+	_, err = s.db.Exec(ctx, `
+		UPDATE report_deliveries
+		SET status = 'PENDING', attempts = 0, next_attempt_at = NOW()
+		WHERE id = $1 AND status IN ('FAILED', 'RETRYING')`, id)
+	if err != nil {
+		return nil, fmt.Errorf("resetting report delivery: %w", err)
+	}
+
+	if _, err := s.deliverNext(ctx, &id); err != nil {
+		return nil, err
+	}
+
+	return s.GetDelivery(ctx, id)
+}
+
+// ============================================
+// Scheduling
+// ============================================
+
+// RunScheduler processes due subscriptions and retries every interval until
+// ctx is cancelled. Several API instances may run it; rows are claimed with
+// SKIP LOCKED so each delivery is sent once.
+func (s *reportServiceImpl) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if sent, err := s.ProcessDue(ctx); err != nil {
+			log.Printf("⚠ Report scheduler: %v", err)
+		} else if sent > 0 {
+			log.Printf("✓ Report scheduler processed %d deliveries", sent)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessDue queues deliveries for subscriptions whose run time has passed
+// and then works through pending deliveries, returning how many it attempted.
+func (s *reportServiceImpl) ProcessDue(ctx context.Context) (int, error) {
+	if err := s.enqueueDue(ctx); err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for processed < batchSize {
+		ok, err := s.deliverNext(ctx, nil)
+		if err != nil {
+			return processed, err
+		}
+		if !ok {
+			break
+		}
+		processed++
+	}
+
+	return processed, nil
+}
+
+// enqueueDue creates one delivery per due subscription and moves it to its
+// next run. Runs missed while the server was down collapse into one delivery.
+func (s *reportServiceImpl) enqueueDue(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	rows := tx.Query(ctx, `
+		SELECT `+subscriptionColumns+`
+		FROM report_subscriptions
+		WHERE is_active AND next_run_at <= NOW()
+		FOR UPDATE SKIP LOCKED`)
+	var due []*models.ReportSubscription
+	for rows.Next() {
+		sub, err := scanSubscription(rows)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("scanning due subscription: %w", err)
+		}
+		due = append(due, sub)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("finding due subscriptions: %w", err)
+	}
+
+	now := time.Now()
+	for _, sub := range due {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO report_deliveries (subscription_id, scheduled_for, status, next_attempt_at, recipients)
+			VALUES ($1, $2, 'PENDING', NOW(), $3)`, sub.ID, sub.NextRunAt, sub.Recipients)
+		if err != nil {
+			return fmt.Errorf("queuing report delivery: %w", err)
+		}
+
+		next, err := nextRun(sub, now)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `
+			UPDATE report_subscriptions SET next_run_at = $1, last_run_at = NOW() WHERE id = $2`, next, sub.ID)
+		if err != nil {
+			return fmt.Errorf("scheduling next run: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// deliverNext claims one pending delivery (a specific one when id is set),
+// renders and sends it, and records the outcome. It reports whether a
+// delivery was claimed.
+func (s *reportServiceImpl) deliverNext(ctx context.Context, id *int) (bool, error) {
+	tx, err := s.db.BeginTx(ctx)
+	if err != nil {
+		return false, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var deliveryID, subscriptionID, attempts int
+	var scheduledFor time.Time
+	var recipients []string
+	err = tx.QueryRow(ctx, `
+		SELECT id, subscription_id, attempts, scheduled_for, recipients
+		FROM report_deliveries
+		WHERE status IN ('PENDING', 'RETRYING') AND next_attempt_at <= NOW()
+		  AND ($1::int IS NULL OR id = $1)
+		ORDER BY next_attempt_at, id
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED`, id,
+	).Scan(&deliveryID, &subscriptionID, &attempts, &scheduledFor, &recipients)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("claiming report delivery: %w", err)
+	}
+
+	fileName, sendErr := s.send(ctx, subscriptionID, scheduledFor, recipients)
+	attempts++
+
+	if sendErr == nil {
+		_, err = tx.Exec(ctx, `
+			UPDATE report_deliveries
+			SET status = 'SENT', attempts = $1, last_error = NULL, next_attempt_at = NULL,
+				file_name = $2, sent_at = NOW()
+			WHERE id = $3`, attempts, fileName, deliveryID)
+	} else if attempts >= maxAttempts {
+		_, err = tx.Exec(ctx, `
+			UPDATE report_deliveries
+			SET status = 'FAILED', attempts = $1, last_error = $2, next_attempt_at = NULL, file_name = $3
+			WHERE id = $4`, attempts, sendErr.Error(), fileName, deliveryID)
+	} else {
+		backoff := retryBackoff * time.Duration(1<<(attempts-1))
+		_, err = tx.Exec(ctx, `
+			UPDATE report_deliveries
+			SET status = 'RETRYING', attempts = $1, last_error = $2, next_attempt_at = $3, file_name = $4
+			WHERE id = $5`, attempts, sendErr.Error(), time.Now().Add(backoff), fileName, deliveryID)
+	}
+	if err != nil {
+		return true, fmt.Errorf("recording report delivery: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return true, fmt.Errorf("committing report delivery: %w", err)
+	}
+
+	return true, nil
+}
+
+// send renders the subscription's report as of the scheduled time and emails
+// it to the recipients captured on the delivery.
+func (s *reportServiceImpl) send(ctx context.Context, subscriptionID int, scheduledFor time.Time, recipients []string) (string, error) {
+	sub, err := s.GetSubscription(ctx, subscriptionID)
+	if err != nil {
+		return "", err
+	}
+	// The scheduler has no signed-in user; report on the subscriber's company
+	ctx = tenant.WithCompany(ctx, sub.CompanyID)
+
+	table, file, err := s.render(ctx, sub, scheduledFor)
+	if err != nil {
+		return "", fmt.Errorf("rendering report: %w", err)
+	}
+
+	company := "FoodHive"
+	if profile, err := s.documents.GetCompanyProfile(ctx); err == nil && profile.CompanyName != "" {
+		company = profile.CompanyName
+	}
+
+	var body strings.Builder
+	fmt.Fprintf(&body, "Please find attached the %s report.\n\n", table.Title)
+	for _, kv := range table.Meta {
+		fmt.Fprintf(&body, "%s: %s\n", kv[0], kv[1])
+	}
+	for _, kv := range table.Totals {
+		fmt.Fprintf(&body, "%s: %s\n", kv[0], kv[1])
+	}
+	fmt.Fprintf(&body, "\nYou receive this email because you are subscribed to %q. Contact your %s administrator to change the subscription.\n", sub.Name, company)
+
+	err = s.mailer.Send(&mailer.Message{
+		To:      recipients,
+		Subject: fmt.Sprintf("[%s] %s - %s", company, sub.Name, scheduledFor.In(location(sub)).Format("02 Jan 2006")),
+		Body:    body.String(),
+		Attachments: []mailer.Attachment{{
+			FileName:    file.FileName,
+			ContentType: file.ContentType,
+			Content:     file.Content,
+		}},
+	})
+	if err != nil {
+		return file.FileName, err
+	}
+
+	return file.FileName, nil
+}
+
+// ============================================
+// Schedule Helpers
+// ============================================
+
+func location(sub *models.ReportSubscription) *time.Location {
+	if loc, err := time.LoadLocation(sub.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// nextRun returns the first scheduled time strictly after the given instant,
+// evaluated in the subscription's timezone.
+func nextRun(sub *models.ReportSubscription, after time.Time) (time.Time, error) {
+	loc, err := time.LoadLocation(sub.Timezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("loading timezone %q: %w", sub.Timezone, err)
+	}
+	clock, err := time.Parse("15:04", sub.TimeOfDay)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing time of day %q: %w", sub.TimeOfDay, err)
+	}
+
+	local := after.In(loc)
+	// Two months covers every daily, weekly and monthly (day <= 28) schedule.
+	for i := 0; i <= 62; i++ {
+		day := time.Date(local.Year(), local.Month(), local.Day()+i, clock.Hour(), clock.Minute(), 0, 0, loc)
+		if !day.After(after) {
+			continue
+		}
+		switch sub.Frequency {
+		case models.ReportFrequencyWeekly:
+			if sub.DayOfWeek == nil || int(day.Weekday()) != *sub.DayOfWeek {
+				continue
+			}
+		case models.ReportFrequencyMonthly:
+			if sub.DayOfMonth == nil || day.Day() != *sub.DayOfMonth {
+				continue
+			}
+		}
+		return day, nil
+	}
+
+	return time.Time{}, fmt.Errorf("no run time found for %s schedule", sub.Frequency)
+}
+
+func cleanRecipients(recipients []string) []string {
+	seen := make(map[string]bool, len(recipients))
+	var out []string
+	for _, r := range recipients {
+		r = strings.TrimSpace(r)
+		key := strings.ToLower(r)
+		if r == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, r)
+	}
+	return out
+}
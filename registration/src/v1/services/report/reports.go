@@ -0,0 +1,275 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+)
+
+// render runs the subscription's report as of the given time and converts it
+// to the subscription's format.
+func (s *reportServiceImpl) render(ctx context.Context, sub *models.ReportSubscription, asOf time.Time) (*models.ReportTable, *models.RenderedDocument, error) {
+	asOf = asOf.In(location(sub))
+
+	var table *models.ReportTable
+	var err error
+	switch sub.ReportType {
+	case models.ReportTypeARAging:
+		table, err = s.arAging(ctx, sub.Parameters)
+	case models.ReportTypeExpiringInventory:
+		table, err = s.expiringInventory(ctx, sub.Parameters)
+	case models.ReportTypeDailySales:
+		table, err = s.dailySales(ctx, sub.Parameters, asOf)
+	default:
+		err = fmt.Errorf("unknown report type %q", sub.ReportType)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	table.Meta = append([][2]string{{"Generated", asOf.Format("02 Jan 2006 15:04")}}, table.Meta...)
+
+	if sub.Format == models.ReportFormatCSV {
+		file, err := toCSV(table, asOf)
+		return table, file, err
+	}
+
+	file, err := s.documents.RenderReport(ctx, table)
+	return table, file, err
+}
+
+// ============================================
+// AR Aging
+// ============================================
+
+func (s *reportServiceImpl) arAging(ctx context.Context, params models.ReportParameters) (*models.ReportTable, error) {
+	aging, err := s.ar.GetAgingReport(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	table := &models.ReportTable{
+		Title: "AR Aging",
+		Columns: []models.ReportColumn{
+			{Title: "Customer", Width: 0.22},
+			{Title: "Code", Width: 0.10},
+			{Title: "Current", Width: 0.11, Numeric: true},
+			{Title: "1-30", Width: 0.11, Numeric: true},
+			{Title: "31-60", Width: 0.11, Numeric: true},
+			{Title: "61-90", Width: 0.11, Numeric: true},
+			{Title: "Over 90", Width: 0.12, Numeric: true},
+			{Title: "Total", Width: 0.12, Numeric: true},
+		},
+	}
+
+	var sum models.AgingBucket
+	for _, c := range aging {
+		if c.Aging.Total <= 0 || c.Aging.Total < params.MinBalance {
+			continue
+		}
+		table.Rows = append(table.Rows, []string{
+			c.CustomerName, c.CustomerCode,
+			amount(c.Aging.Current), amount(c.Aging.Days1_30), amount(c.Aging.Days31_60),
+			amount(c.Aging.Days61_90), amount(c.Aging.Over90), amount(c.Aging.Total),
+		})
+		sum.Current += c.Aging.Current
+		sum.Days1_30 += c.Aging.Days1_30
+		sum.Days31_60 += c.Aging.Days31_60
+		sum.Days61_90 += c.Aging.Days61_90
+		sum.Over90 += c.Aging.Over90
+		sum.Total += c.Aging.Total
+	}
+
+	table.Meta = [][2]string{{"Customers", fmt.Sprint(len(table.Rows))}}
+	if params.MinBalance > 0 {
+		table.Meta = append(table.Meta, [2]string{"Minimum Balance", amount(params.MinBalance)})
+	}
+	table.Totals = [][2]string{
+		{"Current", amount(sum.Current)},
+		{"1-30 Days", amount(sum.Days1_30)},
+		{"31-60 Days", amount(sum.Days31_60)},
+		{"61-90 Days", amount(sum.Days61_90)},
+		{"Over 90 Days", amount(sum.Over90)},
+		{"Total Outstanding", amount(sum.Total)},
+	}
+
+	return table, nil
+}
+
+// ============================================
+// Expiring Inventory
+// ============================================
+
+func (s *reportServiceImpl) expiringInventory(ctx context.Context, params models.ReportParameters) (*models.ReportTable, error) {
+	days := params.DaysToExpiry
+	if days == 0 {
+		days = 30
+	}
+
+	items, err := s.inventory.GetExpiringInventory(ctx, days, params.WarehouseID)
+	if err != nil {
+		return nil, err
+	}
+
+	table := &models.ReportTable{
+		Title: "Expiring Inventory",
+		Meta:  [][2]string{{"Expiring Within", fmt.Sprintf("%d days", days)}},
+		Columns: []models.ReportColumn{
+			{Title: "SKU", Width: 0.11},
+			{Title: "Product", Width: 0.23},
+			{Title: "Warehouse", Width: 0.14},
+			{Title: "Lot", Width: 0.11},
+			{Title: "Expiry", Width: 0.11},
+			{Title: "Days Left", Width: 0.08, Numeric: true},
+			{Title: "On Hand", Width: 0.10, Numeric: true},
+			{Title: "Value", Width: 0.12, Numeric: true},
+		},
+	}
+
+	var value float64
+	expired := 0
+	for _, item := range items {
+		v := item.Inventory.QuantityOnHand * item.Inventory.AverageCost
+		value += v
+		if item.DaysToExpiry < 0 {
+			expired++
+		}
+		expiry := time.Now().AddDate(0, 0, item.DaysToExpiry).Format("2006-01-02")
+		table.Rows = append(table.Rows, []string{
+			item.ProductSKU, item.ProductName, item.WarehouseName, item.Inventory.LotNumber, expiry,
+			fmt.Sprint(item.DaysToExpiry), quantity(item.Inventory.QuantityOnHand), amount(v),
+		})
+	}
+
+	table.Totals = [][2]string{
+		{"Lots", fmt.Sprint(len(items))},
+		{"Already Expired", fmt.Sprint(expired)},
+		{"Value at Risk", amount(value)},
+	}
+
+	return table, nil
+}
+
+// ============================================
+// Daily Sales Summary
+// ============================================
+
+func (s *reportServiceImpl) dailySales(ctx context.Context, params models.ReportParameters, asOf time.Time) (*models.ReportTable, error) {
+	daysBack := 1
+	if params.DaysBack != nil {
+		daysBack = *params.DaysBack
+	}
+	day := asOf.AddDate(0, 0, -daysBack).Format("2006-01-02")
+
+	rows := s.db.Query(ctx, `
+		SELECT c.customer_code, c.name, COUNT(*),
+			   COALESCE(SUM(so.subtotal), 0), COALESCE(SUM(so.discount_amount), 0),
+			   COALESCE(SUM(so.tax_amount), 0), COALESCE(SUM(so.total_amount), 0)
+		FROM sales_orders so
+		JOIN customers c ON so.customer_id = c.id
+		WHERE so.order_date = $1::date
+		  AND so.status NOT IN ('DRAFT', 'CANCELLED')
+		  AND ($2::int IS NULL OR so.warehouse_id = $2)
+		GROUP BY c.id, c.customer_code, c.name
+		ORDER BY SUM(so.total_amount) DESC`, day, params.WarehouseID)
+	defer rows.Close()
+
+	table := &models.ReportTable{
+		Title: "Daily Sales Summary",
+		Meta:  [][2]string{{"Sales Date", day}},
+		Columns: []models.ReportColumn{
+			{Title: "Code", Width: 0.12},
+			{Title: "Customer", Width: 0.28},
+			{Title: "Orders", Width: 0.08, Numeric: true},
+			{Title: "Subtotal", Width: 0.13, Numeric: true},
+			{Title: "Discount", Width: 0.12, Numeric: true},
+			{Title: "Tax", Width: 0.12, Numeric: true},
+			{Title: "Total", Width: 0.15, Numeric: true},
+		},
+	}
+
+	var orders int
+	var subtotal, discount, tax, total float64
+	for rows.Next() {
+		var code, name string
+		var n int
+		var sub, disc, tx, tot float64
+		if err := rows.Scan(&code, &name, &n, &sub, &disc, &tx, &tot); err != nil {
+			return nil, fmt.Errorf("scanning daily sales: %w", err)
+		}
+		table.Rows = append(table.Rows, []string{
+			code, name, fmt.Sprint(n), amount(sub), amount(disc), amount(tx), amount(tot),
+		})
+		orders += n
+		subtotal += sub
+		discount += disc
+		tax += tx
+		total += tot
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("getting daily sales: %w", err)
+	}
+
+	table.Meta = append(table.Meta, [2]string{"Customers", fmt.Sprint(len(table.Rows))})
+	table.Totals = [][2]string{
+		{"Orders", fmt.Sprint(orders)},
+		{"Subtotal", amount(subtotal)},
+		{"Discount", amount(discount)},
+		{"Tax", amount(tax)},
+		{"Total Sales", amount(total)},
+	}
+
+	return table, nil
+}
+
+// ============================================
+// Output Helpers
+// ============================================
+
+// toCSV writes the table with a header row; meta and totals follow as
+// label/value rows after a blank line so spreadsheets keep them apart.
+func toCSV(table *models.ReportTable, asOf time.Time) (*models.RenderedDocument, error) {
+	var buf bytes.Buffer
+	// Excel needs the byte order mark to read UTF-8 (Lao, Thai) correctly.
+	buf.WriteString("\ufeff")
+	w := csv.NewWriter(&buf)
+
+	header := make([]string, len(table.Columns))
+	for i, c := range table.Columns {
+		header[i] = c.Title
+	}
+	w.Write(header)
+	for _, row := range table.Rows {
+		w.Write(row)
+	}
+	if len(table.Meta)+len(table.Totals) > 0 {
+		w.Write([]string{})
+	}
+	for _, kv := range append(append([][2]string{}, table.Meta...), table.Totals...) {
+		w.Write([]string{kv[0], kv[1]})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, fmt.Errorf("writing csv: %w", err)
+	}
+
+	name := strings.ToLower(strings.ReplaceAll(table.Title, " ", "_"))
+	return &models.RenderedDocument{
+		FileName:    fmt.Sprintf("%s_%s.csv", name, asOf.Format("20060102")),
+		ContentType: "text/csv; charset=utf-8",
+		Content:     buf.Bytes(),
+	}, nil
+}
+
+// amount keeps figures machine readable; the PDF renderer adds separators.
+func amount(v float64) string {
+	return fmt.Sprintf("%.2f", v)
+}
+
+func quantity(v float64) string {
+	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.3f", v), "0"), ".")
+}
@@ -0,0 +1,686 @@
+package requisition
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	purchaseOrderService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/purchase_order"
+	salesOrderService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/sales_order"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrRequisitionNotFound = errors.New("purchase requisition not found")
+	ErrIllegalTransition   = errors.New("purchase requisition cannot move to that status")
+	ErrEmptyRequisition    = errors.New("purchase requisition has no lines")
+	ErrNoVendor            = errors.New("vendor is required to raise the purchase order")
+	ErrNoWarehouse         = errors.New("warehouse is required to raise the purchase order")
+	ErrNothingApproved     = errors.New("no quantity was approved on the requisition")
+)
+
+// ============================================
+// Service Interface
+// ============================================
+
+type RequisitionService interface {
+	Create(ctx context.Context, req *models.CreateRequisitionRequest, createdBy int) (int, error)
+	GetByID(ctx context.Context, id int) (*models.RequisitionWithDetails, error)
+	Update(ctx context.Context, id int, req *models.UpdateRequisitionRequest) error
+	Delete(ctx context.Context, id int) error
+	List(ctx context.Context, filters *models.RequisitionFilters) ([]models.RequisitionWithDetails, error)
+
+	// Approval
+	Submit(ctx context.Context, id int) error
+	Check(ctx context.Context, id int, checkedBy int) error
+	Authorize(ctx context.Context, id int, req *models.AuthorizeRequisitionRequest, authorizedBy int) error
+	Reject(ctx context.Context, id int, req *models.RejectRequisitionRequest, rejectedBy int) error
+	Cancel(ctx context.Context, id int) error
+	Convert(ctx context.Context, id int, req *models.ConvertRequisitionRequest, convertedBy int) (int, error)
+
+	// Special Orders
+	CreateSpecialOrder(ctx context.Context, orderLineID int, req *models.SpecialOrderRequest, createdBy int) (*models.SpecialOrderResult, error)
+}
+
+// ============================================
+// Service Implementation
+// ============================================
+
+type requisitionServiceImpl struct {
+	db postgres.Executor
+}
+
+func New(db postgres.Executor) RequisitionService {
+	return &requisitionServiceImpl{db: db}
+}
+
+// inTx runs fn in a transaction. When the service was built on a caller's
+// transaction, fn runs in that one.
+func (s *requisitionServiceImpl) inTx(ctx context.Context, fn func(tx *requisitionServiceImpl) error) error {
+	conn, ok := s.db.(postgres.Connection)
+	if !ok {
+		return fn(s)
+	}
+
+	tx, err := conn.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&requisitionServiceImpl{db: tx}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ============================================
+// Requisition CRUD
+// ============================================
+
+func (s *requisitionServiceImpl) Create(ctx context.Context, req *models.CreateRequisitionRequest, createdBy int) (int, error) {
+	priority := req.Priority
+	if priority == "" {
+		priority = models.PriorityNormal
+	}
+
+	var id int
+	err := s.inTx(ctx, func(tx *requisitionServiceImpl) error {
+		err := tx.db.QueryRow(ctx, `
+			INSERT INTO purchase_requisitions (
+				requisition_number, requester_id, department_id, supplier_id, warehouse_id,
+				required_date, priority, reason, remark, status, created_by, company_id
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), 'DRAFT', $2, $10)
+			RETURNING id`,
+			tx.generateRequisitionNumber(ctx), createdBy, req.DepartmentID, req.SupplierID, req.WarehouseID,
+			parseDate(req.RequiredDate), priority, req.Reason, req.Remark, tenant.Company(ctx),
+		).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("failed to create purchase requisition: %w", err)
+		}
+		return tx.replaceLines(ctx, id, req.Lines, nil)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+const requisitionSelect = `
+	SELECT pr.id, pr.requisition_number, pr.requester_id, pr.department_id, pr.supplier_id, pr.warehouse_id,
+		   COALESCE(pr.reason, ''), COALESCE(pr.remark, ''), COALESCE(pr.priority::text, 'NORMAL'),
+		   pr.document_date, pr.required_date, COALESCE(pr.total_amount, 0), pr.status,
+		   pr.checked_by, pr.checked_date, pr.authorized_by, pr.authorized_date,
+		   pr.rejected_by, pr.rejected_date, COALESCE(pr.rejection_reason, ''),
+		   pr.converted_po_id, pr.created_by, pr.created_at, pr.updated_at,
+		   COALESCE(e.english_name, ''), COALESCE(v.name, ''), COALESCE(w.name, ''),
+		   COALESCE(po.po_number, '')
+	FROM purchase_requisitions pr
+	LEFT JOIN employees e ON e.id = pr.requester_id
+	LEFT JOIN vendors v ON v.id = pr.supplier_id
+	LEFT JOIN warehouses w ON w.id = pr.warehouse_id
+	LEFT JOIN purchase_orders po ON po.id = pr.converted_po_id`
+
+func scanRequisition(row pgx.Row, r *models.RequisitionWithDetails) error {
+	return row.Scan(
+		&r.ID, &r.RequisitionNumber, &r.RequesterID, &r.DepartmentID, &r.SupplierID, &r.WarehouseID,
+		&r.Reason, &r.Remark, &r.Priority,
+		&r.DocumentDate, &r.RequiredDate, &r.TotalAmount, &r.Status,
+		&r.CheckedBy, &r.CheckedDate, &r.AuthorizedBy, &r.AuthorizedDate,
+		&r.RejectedBy, &r.RejectedDate, &r.RejectionReason,
+		&r.ConvertedPOID, &r.CreatedBy, &r.CreatedAt, &r.UpdatedAt,
+		&r.RequesterName, &r.SupplierName, &r.WarehouseName,
+		&r.ConvertedPONumber,
+	)
+}
+
+func (s *requisitionServiceImpl) GetByID(ctx context.Context, id int) (*models.RequisitionWithDetails, error) {
+	var r models.RequisitionWithDetails
+	err := scanRequisition(s.db.QueryRow(ctx, requisitionSelect+`
+		WHERE pr.id = $1 AND pr.company_id = $2`, id, tenant.Company(ctx)), &r)
+	if err == pgx.ErrNoRows {
+		return nil, ErrRequisitionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get purchase requisition: %w", err)
+	}
+
+	if r.Lines, err = s.getLines(ctx, id); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Update changes a draft requisition.
+func (s *requisitionServiceImpl) Update(ctx context.Context, id int, req *models.UpdateRequisitionRequest) error {
+	return s.inTx(ctx, func(tx *requisitionServiceImpl) error {
+		status, err := tx.lock(ctx, id)
+		if err != nil {
+			return err
+		}
+		if status != models.RequisitionDraft {
+			return fmt.Errorf("%w: only drafts can be changed", ErrIllegalTransition)
+		}
+
+		var requiredDate *time.Time
+		if req.RequiredDate != nil {
+			requiredDate = parseDate(*req.RequiredDate)
+		}
+		_, err = tx.db.Exec(ctx, `
+			UPDATE purchase_requisitions SET
+				department_id = COALESCE($1, department_id),
+				supplier_id = COALESCE($2, supplier_id),
+				warehouse_id = COALESCE($3, warehouse_id),
+				required_date = CASE WHEN $4 THEN $5 ELSE required_date END,
+				priority = COALESCE($6, priority),
+				reason = COALESCE($7, reason),
+				remark = COALESCE($8, remark),
+				updated_at = NOW()
+			WHERE id = $9`,
+			req.DepartmentID, req.SupplierID, req.WarehouseID,
+			req.RequiredDate != nil, requiredDate, req.Priority, req.Reason, req.Remark, id)
+		if err != nil {
+			return fmt.Errorf("failed to update purchase requisition: %w", err)
+		}
+
+		if req.Lines == nil {
+			return nil
+		}
+		// Special order lines stay tied to their sales order lines
+		orderLines, err := tx.orderLinesByProduct(ctx, id)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.db.Exec(ctx, `DELETE FROM purchase_requisition_lines WHERE requisition_id = $1`, id); err != nil {
+			return fmt.Errorf("failed to replace requisition lines: %w", err)
+		}
+		return tx.replaceLines(ctx, id, req.Lines, orderLines)
+	})
+}
+
+func (s *requisitionServiceImpl) Delete(ctx context.Context, id int) error {
+	tag, err := s.db.Exec(ctx, `
+		DELETE FROM purchase_requisitions WHERE id = $1 AND company_id = $2 AND status = 'DRAFT'`,
+		id, tenant.Company(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to delete purchase requisition: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		if _, err := s.GetByID(ctx, id); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: only drafts can be deleted", ErrIllegalTransition)
+	}
+	return nil
+}
+
+func (s *requisitionServiceImpl) List(ctx context.Context, filters *models.RequisitionFilters) ([]models.RequisitionWithDetails, error) {
+	whereClause := "WHERE pr.company_id = $1"
+	args := []interface{}{tenant.Company(ctx)}
+	argNum := 2
+
+	if filters.Status != nil {
+		whereClause += fmt.Sprintf(" AND pr.status = $%d", argNum)
+		args = append(args, *filters.Status)
+		argNum++
+	}
+	if filters.RequesterID != nil {
+		whereClause += fmt.Sprintf(" AND pr.requester_id = $%d", argNum)
+		args = append(args, *filters.RequesterID)
+		argNum++
+	}
+	if filters.OrderID != nil {
+		whereClause += fmt.Sprintf(` AND EXISTS (
+			SELECT 1 FROM purchase_requisition_lines rl JOIN sales_order_lines sol ON sol.id = rl.order_line_id
+			WHERE rl.requisition_id = pr.id AND sol.order_id = $%d)`, argNum)
+		args = append(args, *filters.OrderID)
+		argNum++
+	}
+
+	rows := s.db.Query(ctx, requisitionSelect+`
+		`+whereClause+`
+		ORDER BY pr.created_at DESC`, args...)
+	defer rows.Close()
+
+	requisitions := []models.RequisitionWithDetails{}
+	for rows.Next() {
+		var r models.RequisitionWithDetails
+		if err := scanRequisition(rows, &r); err != nil {
+			return nil, fmt.Errorf("failed to scan purchase requisition: %w", err)
+		}
+		requisitions = append(requisitions, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list purchase requisitions: %w", err)
+	}
+	return requisitions, nil
+}
+
+func (s *requisitionServiceImpl) getLines(ctx context.Context, id int) ([]models.RequisitionLine, error) {
+	rows := s.db.Query(ctx, `
+		SELECT l.id, l.requisition_id, l.line_number, l.product_id, p.sku, p.name, COALESCE(l.description, ''),
+			   l.quantity_requested, l.quantity_approved, COALESCE(l.unit_of_measure, ''),
+			   COALESCE(l.unit_price, 0), COALESCE(l.amount, 0), l.preferred_vendor_id,
+			   l.order_line_id, sol.order_id, COALESCE(so.order_number, ''), COALESCE(l.notes, '')
+		FROM purchase_requisition_lines l
+		JOIN products p ON p.id = l.product_id
+		LEFT JOIN sales_order_lines sol ON sol.id = l.order_line_id
+		LEFT JOIN sales_orders so ON so.id = sol.order_id
+		WHERE l.requisition_id = $1
+		ORDER BY l.line_number`, id)
+	defer rows.Close()
+
+	lines := []models.RequisitionLine{}
+	for rows.Next() {
+		var l models.RequisitionLine
+		if err := rows.Scan(&l.ID, &l.RequisitionID, &l.LineNumber, &l.ProductID, &l.ProductSKU, &l.ProductName, &l.Description,
+			&l.QuantityRequested, &l.QuantityApproved, &l.UnitOfMeasure,
+			&l.UnitPrice, &l.Amount, &l.PreferredVendorID,
+			&l.OrderLineID, &l.OrderID, &l.OrderNumber, &l.Notes); err != nil {
+			return nil, fmt.Errorf("failed to scan requisition line: %w", err)
+		}
+		lines = append(lines, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get requisition lines: %w", err)
+	}
+	return lines, nil
+}
+
+// ============================================
+// Approval
+// ============================================
+
+func (s *requisitionServiceImpl) Submit(ctx context.Context, id int) error {
+	return s.inTx(ctx, func(tx *requisitionServiceImpl) error {
+		status, err := tx.lock(ctx, id)
+		if err != nil {
+			return err
+		}
+		if status != models.RequisitionDraft {
+			return fmt.Errorf("%w: requisition is %s", ErrIllegalTransition, status)
+		}
+
+		var lines int
+		err = tx.db.QueryRow(ctx, `SELECT COUNT(*) FROM purchase_requisition_lines WHERE requisition_id = $1`, id).Scan(&lines)
+		if err != nil {
+			return fmt.Errorf("failed to count requisition lines: %w", err)
+		}
+		if lines == 0 {
+			return ErrEmptyRequisition
+		}
+		return tx.setStatus(ctx, id, models.RequisitionSubmitted, "")
+	})
+}
+
+func (s *requisitionServiceImpl) Check(ctx context.Context, id int, checkedBy int) error {
+	return s.inTx(ctx, func(tx *requisitionServiceImpl) error {
+		status, err := tx.lock(ctx, id)
+		if err != nil {
+			return err
+		}
+		if status != models.RequisitionSubmitted {
+			return fmt.Errorf("%w: requisition is %s", ErrIllegalTransition, status)
+		}
+		return tx.setStatus(ctx, id, models.RequisitionChecked,
+			"checked_by = $3, checked_date = CURRENT_DATE,", checkedBy)
+	})
+}
+
+// Authorize approves the requisition for purchasing. Lines not given are
+// approved as requested.
+func (s *requisitionServiceImpl) Authorize(ctx context.Context, id int, req *models.AuthorizeRequisitionRequest, authorizedBy int) error {
+	return s.inTx(ctx, func(tx *requisitionServiceImpl) error {
+		status, err := tx.lock(ctx, id)
+		if err != nil {
+			return err
+		}
+		if status != models.RequisitionChecked {
+			return fmt.Errorf("%w: requisition is %s", ErrIllegalTransition, status)
+		}
+
+		for _, line := range req.Lines {
+			tag, err := tx.db.Exec(ctx, `
+				UPDATE purchase_requisition_lines SET quantity_approved = $1, amount = $1 * COALESCE(unit_price, 0)
+				WHERE id = $2 AND requisition_id = $3`, line.QuantityApproved, line.LineID, id)
+			if err != nil {
+				return fmt.Errorf("failed to approve requisition line: %w", err)
+			}
+			if tag.RowsAffected() == 0 {
+				return fmt.Errorf("%w: line %d is not on the requisition", ErrRequisitionNotFound, line.LineID)
+			}
+		}
+		_, err = tx.db.Exec(ctx, `
+			UPDATE purchase_requisition_lines SET quantity_approved = quantity_requested
+			WHERE requisition_id = $1 AND quantity_approved IS NULL`, id)
+		if err != nil {
+			return fmt.Errorf("failed to approve requisition lines: %w", err)
+		}
+		if err := tx.updateTotal(ctx, id); err != nil {
+			return err
+		}
+		return tx.setStatus(ctx, id, models.RequisitionAuthorized,
+			"authorized_by = $3, authorized_date = CURRENT_DATE,", authorizedBy)
+	})
+}
+
+// Reject sends a submitted or checked requisition back to its requester
+// as a draft.
+func (s *requisitionServiceImpl) Reject(ctx context.Context, id int, req *models.RejectRequisitionRequest, rejectedBy int) error {
+	return s.inTx(ctx, func(tx *requisitionServiceImpl) error {
+		status, err := tx.lock(ctx, id)
+		if err != nil {
+			return err
+		}
+		if status != models.RequisitionSubmitted && status != models.RequisitionChecked {
+			return fmt.Errorf("%w: requisition is %s", ErrIllegalTransition, status)
+		}
+		return tx.setStatus(ctx, id, models.RequisitionDraft,
+			`rejected_by = $3, rejected_date = CURRENT_DATE, rejection_reason = $4,
+			 checked_by = NULL, checked_date = NULL,`, rejectedBy, req.Reason)
+	})
+}
+
+func (s *requisitionServiceImpl) Cancel(ctx context.Context, id int) error {
+	return s.inTx(ctx, func(tx *requisitionServiceImpl) error {
+		status, err := tx.lock(ctx, id)
+		if err != nil {
+			return err
+		}
+		if status == models.RequisitionConverted || status == models.RequisitionCancelled {
+			return fmt.Errorf("%w: requisition is %s", ErrIllegalTransition, status)
+		}
+		return tx.setStatus(ctx, id, models.RequisitionCancelled, "")
+	})
+}
+
+// Convert raises a purchase order for the approved quantities. Its lines
+// remember the requisition lines they came from, so receipts for special
+// orders reach the sales orders they were bought for.
+func (s *requisitionServiceImpl) Convert(ctx context.Context, id int, req *models.ConvertRequisitionRequest, convertedBy int) (int, error) {
+	var poID int
+	err := s.inTx(ctx, func(tx *requisitionServiceImpl) error {
+		status, err := tx.lock(ctx, id)
+		if err != nil {
+			return err
+		}
+		if status != models.RequisitionAuthorized {
+			return fmt.Errorf("%w: requisition is %s", ErrIllegalTransition, status)
+		}
+
+		var number string
+		var vendorID, warehouseID *int
+		var requiredDate *time.Time
+		err = tx.db.QueryRow(ctx, `
+			SELECT requisition_number, supplier_id, warehouse_id, required_date
+			FROM purchase_requisitions WHERE id = $1`, id).Scan(&number, &vendorID, &warehouseID, &requiredDate)
+		if err != nil {
+			return fmt.Errorf("failed to get purchase requisition: %w", err)
+		}
+		if req.VendorID != nil {
+			vendorID = req.VendorID
+		}
+		if req.WarehouseID != nil {
+			warehouseID = req.WarehouseID
+		}
+		if vendorID == nil {
+			return ErrNoVendor
+		}
+		if warehouseID == nil {
+			return ErrNoWarehouse
+		}
+
+		expectedDate := req.ExpectedDate
+		if expectedDate == "" && requiredDate != nil {
+			expectedDate = requiredDate.Format("2006-01-02")
+		}
+		poReq := &models.CreatePurchaseOrderRequest{
+			VendorID:     *vendorID,
+			WarehouseID:  *warehouseID,
+			ExpectedDate: expectedDate,
+			Notes:        "Requisition " + number,
+			BuyerID:      req.BuyerID,
+		}
+
+		rows := tx.db.Query(ctx, `
+			SELECT id, product_id, COALESCE(description, ''), quantity_approved,
+				   COALESCE(unit_of_measure, ''), COALESCE(unit_price, 0)
+			FROM purchase_requisition_lines
+			WHERE requisition_id = $1 AND quantity_approved > 0
+			ORDER BY line_number`, id)
+		var lineIDs []int
+		for rows.Next() {
+			var lineID int
+			var line models.CreatePurchaseOrderLineRequest
+			if err := rows.Scan(&lineID, &line.ProductID, &line.Description, &line.Quantity,
+				&line.UnitOfMeasure, &line.UnitCost); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan requisition line: %w", err)
+			}
+			lineIDs = append(lineIDs, lineID)
+			poReq.Lines = append(poReq.Lines, line)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to get requisition lines: %w", err)
+		}
+		if len(lineIDs) == 0 {
+			return ErrNothingApproved
+		}
+
+		poID, err = purchaseOrderService.New(tx.db).Create(ctx, poReq, convertedBy)
+		if err != nil {
+			return err
+		}
+
+		// Create numbers the PO lines in the order they were given
+		for i, lineID := range lineIDs {
+			_, err := tx.db.Exec(ctx, `
+				UPDATE purchase_order_lines SET requisition_line_id = $1 WHERE po_id = $2 AND line_number = $3`,
+				lineID, poID, i+1)
+			if err != nil {
+				return fmt.Errorf("failed to link purchase order line: %w", err)
+			}
+		}
+		if _, err := tx.db.Exec(ctx, `UPDATE purchase_orders SET requisition_id = $1 WHERE id = $2`, id, poID); err != nil {
+			return fmt.Errorf("failed to link purchase order: %w", err)
+		}
+
+		return tx.setStatus(ctx, id, models.RequisitionConverted,
+			"converted_po_id = $3, purchasing_employee_id = $4, purchasing_date = CURRENT_DATE,", poID, convertedBy)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return poID, nil
+}
+
+// ============================================
+// Special Orders
+// ============================================
+
+// CreateSpecialOrder backorders what a sales order line still needs and
+// submits a requisition for it, so the request reaches purchasing without
+// the rep having to pass it on.
+func (s *requisitionServiceImpl) CreateSpecialOrder(ctx context.Context, orderLineID int, req *models.SpecialOrderRequest, createdBy int) (*models.SpecialOrderResult, error) {
+	var result *models.SpecialOrderResult
+	err := s.inTx(ctx, func(tx *requisitionServiceImpl) error {
+		line, err := salesOrderService.New(tx.db).HoldForSpecialOrder(ctx, orderLineID)
+		if err != nil {
+			return err
+		}
+
+		// The product's primary vendor and its cost unless told otherwise
+		vendorID := req.VendorID
+		var unitCost float64
+		err = tx.db.QueryRow(ctx, `
+			SELECT COALESCE($2, p.primary_vendor_id),
+				   COALESCE(vp.unit_cost, vp.last_purchase_price, p.last_purchase_cost, p.standard_cost, 0)
+			FROM products p
+			LEFT JOIN vendor_products vp ON vp.product_id = p.id AND vp.vendor_id = COALESCE($2, p.primary_vendor_id)
+			WHERE p.id = $1`, line.ProductID, req.VendorID).Scan(&vendorID, &unitCost)
+		if err != nil {
+			return fmt.Errorf("failed to get product vendor: %w", err)
+		}
+		if req.UnitCost != nil {
+			unitCost = *req.UnitCost
+		}
+
+		quantity := line.Quantity
+		if req.Quantity != nil {
+			quantity = *req.Quantity
+		}
+		requiredDate := line.RequiredDate
+		if req.RequiredDate != "" {
+			requiredDate = parseDate(req.RequiredDate)
+		}
+		priority := req.Priority
+		if priority == "" {
+			priority = models.PriorityNormal
+		}
+
+		result = &models.SpecialOrderResult{
+			RequisitionNumber:   tx.generateRequisitionNumber(ctx),
+			QuantityRequested:   quantity,
+			QuantityBackordered: line.Quantity,
+		}
+		err = tx.db.QueryRow(ctx, `
+			INSERT INTO purchase_requisitions (
+				requisition_number, requester_id, supplier_id, warehouse_id, required_date,
+				priority, reason, remark, status, created_by, company_id
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), 'SUBMITTED', $2, $9)
+			RETURNING id`,
+			result.RequisitionNumber, createdBy, vendorID, line.WarehouseID, requiredDate,
+			priority, "Special order for sales order "+line.OrderNumber, req.Notes, tenant.Company(ctx),
+		).Scan(&result.RequisitionID)
+		if err != nil {
+			return fmt.Errorf("failed to create purchase requisition: %w", err)
+		}
+
+		lines := []models.RequisitionLineRequest{{
+			ProductID:         line.ProductID,
+			Quantity:          quantity,
+			UnitOfMeasure:     line.UnitOfMeasure,
+			UnitPrice:         unitCost,
+			PreferredVendorID: vendorID,
+			Notes:             req.Notes,
+		}}
+		return tx.replaceLines(ctx, result.RequisitionID, lines, map[int]int{line.ProductID: orderLineID})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ============================================
+// Helpers
+// ============================================
+
+// lock takes the requisition for update and gives its status.
+func (s *requisitionServiceImpl) lock(ctx context.Context, id int) (models.RequisitionStatus, error) {
+	var status models.RequisitionStatus
+	err := s.db.QueryRow(ctx, `
+		SELECT status FROM purchase_requisitions WHERE id = $1 AND company_id = $2 FOR UPDATE`,
+		id, tenant.Company(ctx)).Scan(&status)
+	if err == pgx.ErrNoRows {
+		return "", ErrRequisitionNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get purchase requisition: %w", err)
+	}
+	return status, nil
+}
+
+// setStatus moves the requisition to status. set holds extra assignments,
+// each ending in a comma, whose arguments start at $3.
+func (s *requisitionServiceImpl) setStatus(ctx context.Context, id int, status models.RequisitionStatus, set string, args ...interface{}) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE purchase_requisitions SET `+set+` status = $1, updated_at = NOW()
+		WHERE id = $2`, append([]interface{}{status, id}, args...)...)
+	if err != nil {
+		return fmt.Errorf("failed to update purchase requisition: %w", err)
+	}
+	return nil
+}
+
+// replaceLines inserts the lines and refreshes the total. orderLines ties
+// lines of a product to a sales order line.
+func (s *requisitionServiceImpl) replaceLines(ctx context.Context, id int, lines []models.RequisitionLineRequest, orderLines map[int]int) error {
+	for i, line := range lines {
+		var orderLineID *int
+		if lineID, ok := orderLines[line.ProductID]; ok {
+			orderLineID = &lineID
+		}
+		_, err := s.db.Exec(ctx, `
+			INSERT INTO purchase_requisition_lines (
+				requisition_id, line_number, product_id, product_code, description, stock_balance,
+				quantity_requested, unit_of_measure, unit_price, amount, preferred_vendor_id, order_line_id, notes
+			)
+			SELECT $1, $2, p.id, p.sku, NULLIF($4, ''),
+				   COALESCE((SELECT SUM(quantity_on_hand) FROM inventory WHERE product_id = p.id), 0),
+				   $5, COALESCE(NULLIF($6, ''), p.base_unit), $7, $5 * $7, $8, $9, NULLIF($10, '')
+			FROM products p WHERE p.id = $3`,
+			id, i+1, line.ProductID, line.Description,
+			line.Quantity, line.UnitOfMeasure, line.UnitPrice, line.PreferredVendorID, orderLineID, line.Notes)
+		if err != nil {
+			return fmt.Errorf("failed to create requisition line: %w", err)
+		}
+	}
+	return s.updateTotal(ctx, id)
+}
+
+// orderLinesByProduct returns the sales order lines the requisition's
+// special order lines are tied to.
+func (s *requisitionServiceImpl) orderLinesByProduct(ctx context.Context, id int) (map[int]int, error) {
+	rows := s.db.Query(ctx, `
+		SELECT product_id, order_line_id FROM purchase_requisition_lines
+		WHERE requisition_id = $1 AND order_line_id IS NOT NULL`, id)
+	defer rows.Close()
+
+	orderLines := make(map[int]int)
+	for rows.Next() {
+		var productID, orderLineID int
+		if err := rows.Scan(&productID, &orderLineID); err != nil {
+			return nil, fmt.Errorf("failed to scan requisition line: %w", err)
+		}
+		orderLines[productID] = orderLineID
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get requisition lines: %w", err)
+	}
+	return orderLines, nil
+}
+
+func (s *requisitionServiceImpl) updateTotal(ctx context.Context, id int) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE purchase_requisitions SET total_amount = COALESCE((
+			SELECT SUM(COALESCE(quantity_approved, quantity_requested) * COALESCE(unit_price, 0))
+			FROM purchase_requisition_lines WHERE requisition_id = $1), 0), updated_at = NOW()
+		WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("failed to update requisition total: %w", err)
+	}
+	return nil
+}
+
+func (s *requisitionServiceImpl) generateRequisitionNumber(ctx context.Context) string {
+	var count int64
+	s.db.QueryRow(ctx, `SELECT COUNT(*) FROM purchase_requisitions WHERE DATE(created_at) = CURRENT_DATE`).Scan(&count)
+	return fmt.Sprintf("PR%s%04d", time.Now().Format("20060102"), count+1)
+}
+
+func parseDate(s string) *time.Time {
+	if s == "" {
+		return nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
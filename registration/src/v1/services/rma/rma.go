@@ -0,0 +1,787 @@
+package rma
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	arService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/ar"
+	catchWeightService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/catch_weight"
+	glService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/gl"
+	inventoryService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/inventory"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrRMANotFound         = errors.New("return authorization not found")
+	ErrOrderNotFound       = errors.New("sales order not found")
+	ErrInvoiceNotFound     = errors.New("invoice not found")
+	ErrInvoiceHasNoOrder   = errors.New("invoice is not linked to a sales order")
+	ErrInvoiceMismatch     = errors.New("invoice is not for the sales order")
+	ErrOrderLineNotFound   = errors.New("order line not found on the sales order")
+	ErrLineNotFound        = errors.New("return line not found")
+	ErrExceedsReturnable   = errors.New("return quantity exceeds quantity shipped and not already returned")
+	ErrExceedsAuthorized   = errors.New("received quantity exceeds quantity authorized")
+	ErrExceedsReceived     = errors.New("disposition quantity exceeds quantity received and not yet dispositioned")
+	ErrNothingReceived     = errors.New("no goods were received on the return")
+	ErrDispositionNotFound = errors.New("quarantined goods not found or already released")
+	ErrIllegalTransition   = errors.New("return authorization cannot move to that status")
+)
+
+// ReferenceType marks inventory transactions and catch weight entries
+// made for returns.
+const ReferenceType = "RETURN"
+
+// quantityTolerance absorbs rounding in DECIMAL(10,3) quantities.
+const quantityTolerance = 0.0005
+
+// ============================================
+// Service Interface
+// ============================================
+
+type RMAService interface {
+	Create(ctx context.Context, req *models.CreateRMARequest, createdBy int) (int, error)
+	GetByID(ctx context.Context, id int) (*models.RMAWithDetails, error)
+	List(ctx context.Context, filters *models.RMAFilters) ([]models.RMAWithDetails, error)
+
+	// Workflow
+	Receive(ctx context.Context, id int, req *models.ReceiveRMARequest, receivedBy int) error
+	Approve(ctx context.Context, id int, approvedBy int) error
+	Reject(ctx context.Context, id int, req *models.RejectRMARequest) error
+	Cancel(ctx context.Context, id int) error
+
+	// QA disposition
+	Disposition(ctx context.Context, id int, req *models.DispositionRMARequest, createdBy int) error
+	ReleaseQuarantine(ctx context.Context, id, dispositionID int, req *models.ReleaseQuarantineRequest, releasedBy int) error
+}
+
+// ============================================
+// Service Implementation
+// ============================================
+
+type rmaServiceImpl struct {
+	db postgres.Executor
+}
+
+func New(db postgres.Executor) RMAService {
+	return &rmaServiceImpl{db: db}
+}
+
+// inTx runs fn in a transaction. When the service was built on a caller's
+// transaction, fn runs in that one.
+func (s *rmaServiceImpl) inTx(ctx context.Context, fn func(tx *rmaServiceImpl) error) error {
+	conn, ok := s.db.(postgres.Connection)
+	if !ok {
+		return fn(s)
+	}
+
+	tx, err := conn.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&rmaServiceImpl{db: tx}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ============================================
+// Return Authorization
+// ============================================
+
+// Create authorizes a return of shipped goods. Each line can return what
+// the order line shipped less what other open or completed returns took.
+func (s *rmaServiceImpl) Create(ctx context.Context, req *models.CreateRMARequest, createdBy int) (int, error) {
+	companyID := tenant.Company(ctx)
+
+	orderID := req.OrderID
+	if req.InvoiceID != nil {
+		var invoiceOrderID *int
+		err := s.db.QueryRow(ctx, `
+			SELECT order_id FROM ar_invoices
+			WHERE id = $1 AND company_id = $2 AND invoice_type = 'INVOICE' AND status <> 'VOID'`,
+			*req.InvoiceID, companyID).Scan(&invoiceOrderID)
+		if err == pgx.ErrNoRows {
+			return 0, ErrInvoiceNotFound
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to get invoice: %w", err)
+		}
+		if invoiceOrderID == nil {
+			return 0, ErrInvoiceHasNoOrder
+		}
+		if orderID != nil && *orderID != *invoiceOrderID {
+			return 0, ErrInvoiceMismatch
+		}
+		orderID = invoiceOrderID
+	}
+
+	var customerID, warehouseID int
+	err := s.db.QueryRow(ctx, `
+		SELECT customer_id, warehouse_id FROM sales_orders WHERE id = $1 AND company_id = $2`,
+		*orderID, companyID).Scan(&customerID, &warehouseID)
+	if err == pgx.ErrNoRows {
+		return 0, ErrOrderNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get sales order: %w", err)
+	}
+	if req.WarehouseID != nil {
+		warehouseID = *req.WarehouseID
+	}
+
+	var id int
+	err = s.inTx(ctx, func(tx *rmaServiceImpl) error {
+		err := tx.db.QueryRow(ctx, `
+			INSERT INTO rmas (
+				company_id, rma_number, customer_id, order_id, invoice_id, warehouse_id,
+				reason_code, notes, created_by
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9)
+			RETURNING id`,
+			companyID, tx.generateRMANumber(ctx), customerID, *orderID, req.InvoiceID, warehouseID,
+			req.ReasonCode, req.Notes, createdBy,
+		).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("failed to create return authorization: %w", err)
+		}
+
+		for i, line := range req.Lines {
+			// The order line lock keeps two returns from taking the same units
+			var productID int
+			var returnable, unitPrice float64
+			var lotNumber *string
+			err := tx.db.QueryRow(ctx, `
+				SELECT sol.product_id,
+					   sol.quantity_shipped - COALESCE((
+						   SELECT SUM(CASE WHEN r.status = 'REQUESTED' THEN rl.quantity_authorized
+										   ELSE rl.quantity_received END)
+						   FROM rma_lines rl JOIN rmas r ON r.id = rl.rma_id
+						   WHERE rl.order_line_id = sol.id AND r.status NOT IN ('REJECTED', 'CANCELLED')
+					   ), 0),
+					   sol.unit_price * (1 - COALESCE(sol.discount_percent, 0) / 100),
+					   NULLIF(sol.lot_number, '')
+				FROM sales_order_lines sol
+				WHERE sol.id = $1 AND sol.order_id = $2
+				FOR UPDATE OF sol`,
+				line.OrderLineID, *orderID).Scan(&productID, &returnable, &unitPrice, &lotNumber)
+			if err == pgx.ErrNoRows {
+				return fmt.Errorf("%w: line %d", ErrOrderLineNotFound, line.OrderLineID)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to get order line: %w", err)
+			}
+			if line.Quantity > returnable+quantityTolerance {
+				return fmt.Errorf("%w: line %d can return %.3f", ErrExceedsReturnable, line.OrderLineID, math.Max(returnable, 0))
+			}
+
+			reason := line.ReasonCode
+			if reason == "" {
+				reason = req.ReasonCode
+			}
+			_, err = tx.db.Exec(ctx, `
+				INSERT INTO rma_lines (
+					rma_id, line_number, order_line_id, product_id, quantity_authorized,
+					lot_number, unit_price, reason_code, notes
+				) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''))`,
+				id, i+1, line.OrderLineID, productID, line.Quantity,
+				lotNumber, unitPrice, reason, line.Notes)
+			if err != nil {
+				return fmt.Errorf("failed to create return line: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+const rmaSelect = `
+	SELECT r.id, r.rma_number, r.customer_id, r.order_id, r.invoice_id, r.warehouse_id,
+		   r.status, r.reason_code, COALESCE(r.notes, ''),
+		   r.received_by, r.received_at, r.approved_by, r.approved_at,
+		   r.credit_invoice_id, r.gl_journal_id, COALESCE(r.reject_reason, ''),
+		   r.created_by, r.created_at, r.updated_at,
+		   c.name, so.order_number, COALESCE(i.invoice_number, ''), COALESCE(w.name, ''),
+		   COALESCE((SELECT SUM(credit_amount) FROM rma_lines WHERE rma_id = r.id), 0)
+	FROM rmas r
+	JOIN customers c ON c.id = r.customer_id
+	JOIN sales_orders so ON so.id = r.order_id
+	LEFT JOIN ar_invoices i ON i.id = r.invoice_id
+	LEFT JOIN warehouses w ON w.id = r.warehouse_id`
+
+func scanRMA(row pgx.Row, r *models.RMAWithDetails) error {
+	return row.Scan(
+		&r.ID, &r.RMANumber, &r.CustomerID, &r.OrderID, &r.InvoiceID, &r.WarehouseID,
+		&r.Status, &r.ReasonCode, &r.Notes,
+		&r.ReceivedBy, &r.ReceivedAt, &r.ApprovedBy, &r.ApprovedAt,
+		&r.CreditInvoiceID, &r.GLJournalID, &r.RejectReason,
+		&r.CreatedBy, &r.CreatedAt, &r.UpdatedAt,
+		&r.CustomerName, &r.OrderNumber, &r.InvoiceNumber, &r.WarehouseName,
+		&r.CreditAmount,
+	)
+}
+
+func (s *rmaServiceImpl) GetByID(ctx context.Context, id int) (*models.RMAWithDetails, error) {
+	var r models.RMAWithDetails
+	err := scanRMA(s.db.QueryRow(ctx, rmaSelect+`
+		WHERE r.id = $1 AND r.company_id = $2`, id, tenant.Company(ctx)), &r)
+	if err == pgx.ErrNoRows {
+		return nil, ErrRMANotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get return authorization: %w", err)
+	}
+
+	if r.Lines, err = s.getLines(ctx, id); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (s *rmaServiceImpl) List(ctx context.Context, filters *models.RMAFilters) ([]models.RMAWithDetails, error) {
+	whereClause := "WHERE r.company_id = $1"
+	args := []interface{}{tenant.Company(ctx)}
+	argNum := 2
+
+	if filters.CustomerID != nil {
+		whereClause += fmt.Sprintf(" AND r.customer_id = $%d", argNum)
+		args = append(args, *filters.CustomerID)
+		argNum++
+	}
+	if filters.OrderID != nil {
+		whereClause += fmt.Sprintf(" AND r.order_id = $%d", argNum)
+		args = append(args, *filters.OrderID)
+		argNum++
+	}
+	if filters.Status != nil {
+		whereClause += fmt.Sprintf(" AND r.status = $%d", argNum)
+		args = append(args, *filters.Status)
+		argNum++
+	}
+
+	rows := s.db.Query(ctx, rmaSelect+`
+		`+whereClause+`
+		ORDER BY r.created_at DESC`, args...)
+	defer rows.Close()
+
+	rmas := []models.RMAWithDetails{}
+	for rows.Next() {
+		var r models.RMAWithDetails
+		if err := scanRMA(rows, &r); err != nil {
+			return nil, fmt.Errorf("failed to scan return authorization: %w", err)
+		}
+		rmas = append(rmas, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list return authorizations: %w", err)
+	}
+	return rmas, nil
+}
+
+func (s *rmaServiceImpl) getLines(ctx context.Context, id int) ([]models.RMALine, error) {
+	rows := s.db.Query(ctx, `
+		SELECT l.id, l.rma_id, l.line_number, l.order_line_id, l.product_id, p.sku, p.name,
+			   l.quantity_authorized, l.quantity_received,
+			   COALESCE((SELECT SUM(quantity) FROM rma_dispositions WHERE rma_line_id = l.id), 0),
+			   l.catch_weight_received, COALESCE(l.lot_number, ''), l.unit_price, l.reason_code,
+			   COALESCE(l.notes, ''), l.credit_amount
+		FROM rma_lines l
+		JOIN products p ON p.id = l.product_id
+		WHERE l.rma_id = $1
+		ORDER BY l.line_number`, id)
+
+	lines := []models.RMALine{}
+	index := make(map[int]int)
+	for rows.Next() {
+		var l models.RMALine
+		if err := rows.Scan(&l.ID, &l.RMAID, &l.LineNumber, &l.OrderLineID, &l.ProductID, &l.ProductSKU, &l.ProductName,
+			&l.QuantityAuthorized, &l.QuantityReceived, &l.QuantityDispositioned,
+			&l.CatchWeightReceived, &l.LotNumber, &l.UnitPrice, &l.ReasonCode,
+			&l.Notes, &l.CreditAmount); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan return line: %w", err)
+		}
+		l.Dispositions = []models.RMADisposition{}
+		index[l.ID] = len(lines)
+		lines = append(lines, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get return lines: %w", err)
+	}
+
+	rows = s.db.Query(ctx, `
+		SELECT d.id, d.rma_line_id, d.disposition, d.quantity, COALESCE(d.location_code, ''),
+			   d.released_to, d.released_at, d.released_by, COALESCE(d.notes, ''),
+			   d.created_by, d.created_at
+		FROM rma_dispositions d
+		JOIN rma_lines l ON l.id = d.rma_line_id
+		WHERE l.rma_id = $1
+		ORDER BY d.id`, id)
+	defer rows.Close()
+
+	for rows.Next() {
+		var d models.RMADisposition
+		if err := rows.Scan(&d.ID, &d.RMALineID, &d.Disposition, &d.Quantity, &d.LocationCode,
+			&d.ReleasedTo, &d.ReleasedAt, &d.ReleasedBy, &d.Notes,
+			&d.CreatedBy, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan return disposition: %w", err)
+		}
+		i := index[d.RMALineID]
+		lines[i].Dispositions = append(lines[i].Dispositions, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get return dispositions: %w", err)
+	}
+	return lines, nil
+}
+
+// ============================================
+// Workflow
+// ============================================
+
+// Receive records what came back. Catch weight lines record the returned
+// weight as a catch weight entry against the return line. Goods wait at
+// the dock, outside inventory, until QA dispositions them.
+func (s *rmaServiceImpl) Receive(ctx context.Context, id int, req *models.ReceiveRMARequest, receivedBy int) error {
+	return s.inTx(ctx, func(tx *rmaServiceImpl) error {
+		number, status, err := tx.lock(ctx, id)
+		if err != nil {
+			return err
+		}
+		if status != models.RMAStatusRequested {
+			return fmt.Errorf("%w: return is %s", ErrIllegalTransition, status)
+		}
+
+		var received float64
+		for _, line := range req.Lines {
+			var productID int
+			var authorized float64
+			var lotNumber string
+			var expectedWeight float64
+			var weightUOM models.WeightUOM
+			err := tx.db.QueryRow(ctx, `
+				UPDATE rma_lines l SET
+					quantity_received = $1,
+					lot_number = COALESCE(NULLIF($2, ''), l.lot_number),
+					catch_weight_received = $3
+				FROM sales_order_lines sol, products p
+				WHERE l.id = $4 AND l.rma_id = $5 AND sol.id = l.order_line_id AND p.id = l.product_id
+				RETURNING l.product_id, l.quantity_authorized, COALESCE(l.lot_number, ''),
+						  CASE WHEN sol.quantity_shipped > 0
+							   THEN COALESCE(sol.catch_weight, 0) * $1 / sol.quantity_shipped ELSE 0 END,
+						  COALESCE(p.catch_weight_unit, 'KG')`,
+				line.Quantity, line.LotNumber, line.CatchWeight, line.LineID, id,
+			).Scan(&productID, &authorized, &lotNumber, &expectedWeight, &weightUOM)
+			if err == pgx.ErrNoRows {
+				return fmt.Errorf("%w: line %d", ErrLineNotFound, line.LineID)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to receive return line: %w", err)
+			}
+			if line.Quantity > authorized+quantityTolerance {
+				return fmt.Errorf("%w: line %d authorized %.3f", ErrExceedsAuthorized, line.LineID, authorized)
+			}
+			received += line.Quantity
+
+			if line.CatchWeight != nil && line.Quantity > 0 {
+				_, err := catchWeightService.New(tx.db).QuickCaptureCatchWeight(ctx, models.QuickCatchWeightRequest{
+					ProductID:       productID,
+					ReferenceType:   ReferenceType,
+					ReferenceID:     line.LineID,
+					ReferenceNumber: number,
+					LotNumber:       lotNumber,
+					ExpectedWeight:  expectedWeight,
+					ActualWeight:    *line.CatchWeight,
+					PieceCount:      line.PieceCount,
+					WeightUOM:       weightUOM,
+				}, receivedBy)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		if received <= 0 {
+			return ErrNothingReceived
+		}
+
+		_, err = tx.db.Exec(ctx, `
+			UPDATE rmas SET status = $1, received_by = $2, received_at = NOW(),
+				notes = CONCAT_WS(E'\n', notes, NULLIF($3, '')), updated_at = NOW()
+			WHERE id = $4`, models.RMAStatusReceived, receivedBy, req.Notes, id)
+		if err != nil {
+			return fmt.Errorf("failed to receive return: %w", err)
+		}
+		return nil
+	})
+}
+
+// Approve credits the customer for what came back and posts the credit to
+// the GL. Lines credit the net price paid; catch weight lines credit the
+// returned weight at the price per weight the customer was charged. Tax is
+// credited at the order's rate; freight is not credited.
+func (s *rmaServiceImpl) Approve(ctx context.Context, id int, approvedBy int) error {
+	return s.inTx(ctx, func(tx *rmaServiceImpl) error {
+		number, status, err := tx.lock(ctx, id)
+		if err != nil {
+			return err
+		}
+		if status != models.RMAStatusReceived {
+			return fmt.Errorf("%w: return is %s", ErrIllegalTransition, status)
+		}
+
+		var customerID, orderID int
+		var orderNumber string
+		var orderSubtotal, orderTax float64
+		err = tx.db.QueryRow(ctx, `
+			SELECT r.customer_id, r.order_id, so.order_number, COALESCE(so.subtotal, 0), COALESCE(so.tax_amount, 0)
+			FROM rmas r JOIN sales_orders so ON so.id = r.order_id
+			WHERE r.id = $1`, id).Scan(&customerID, &orderID, &orderNumber, &orderSubtotal, &orderTax)
+		if err != nil {
+			return fmt.Errorf("failed to get returned order: %w", err)
+		}
+
+		type creditLine struct {
+			id     int
+			amount float64
+			line   models.CreateARInvoiceLineReq
+		}
+		rows := tx.db.Query(ctx, `
+			SELECT l.id, l.product_id, l.order_line_id, p.name, l.quantity_received, l.catch_weight_received,
+				   l.unit_price, COALESCE(sol.catch_weight, 0), sol.line_total
+			FROM rma_lines l
+			JOIN sales_order_lines sol ON sol.id = l.order_line_id
+			JOIN products p ON p.id = l.product_id
+			WHERE l.rma_id = $1 AND l.quantity_received > 0
+			ORDER BY l.line_number`, id)
+		var credits []creditLine
+		var subtotal float64
+		for rows.Next() {
+			var c creditLine
+			var productID, orderLineID int
+			var name string
+			var quantity, unitPrice, shippedWeight, lineTotal float64
+			var weight *float64
+			if err := rows.Scan(&c.id, &productID, &orderLineID, &name, &quantity, &weight,
+				&unitPrice, &shippedWeight, &lineTotal); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan return line: %w", err)
+			}
+			if weight != nil && shippedWeight > 0 {
+				quantity, unitPrice = *weight, lineTotal/shippedWeight
+			}
+			c.amount = math.Round(quantity*unitPrice*100) / 100
+			c.line = models.CreateARInvoiceLineReq{
+				ProductID:   &productID,
+				Description: name,
+				Quantity:    quantity,
+				UnitPrice:   unitPrice,
+				OrderLineID: &orderLineID,
+			}
+			credits = append(credits, c)
+			subtotal += c.amount
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to get return lines: %w", err)
+		}
+		if len(credits) == 0 {
+			return ErrNothingReceived
+		}
+
+		creditReq := &models.CreateARCreditMemoRequest{
+			CustomerID: customerID,
+			OrderID:    &orderID,
+			Notes:      fmt.Sprintf("Return %s for order %s", number, orderNumber),
+		}
+		if orderSubtotal > 0 {
+			creditReq.TaxAmount = math.Round(subtotal*orderTax/orderSubtotal*100) / 100
+		}
+		for _, c := range credits {
+			creditReq.Lines = append(creditReq.Lines, c.line)
+			_, err := tx.db.Exec(ctx, `UPDATE rma_lines SET credit_amount = $1 WHERE id = $2`, c.amount, c.id)
+			if err != nil {
+				return fmt.Errorf("failed to record line credit: %w", err)
+			}
+		}
+
+		creditID, err := arService.New(tx.db).CreateCreditMemo(ctx, creditReq, approvedBy)
+		if err != nil {
+			return err
+		}
+		journalID, err := glService.New(tx.db).PostFromAR(ctx, creditID, approvedBy)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.db.Exec(ctx, `
+			UPDATE rmas SET status = $1, approved_by = $2, approved_at = NOW(),
+				credit_invoice_id = $3, gl_journal_id = $4, updated_at = NOW()
+			WHERE id = $5`, models.RMAStatusApproved, approvedBy, creditID, journalID, id)
+		if err != nil {
+			return fmt.Errorf("failed to approve return: %w", err)
+		}
+		return tx.closeIfDone(ctx, id)
+	})
+}
+
+// Reject turns a return down before any goods were dispositioned.
+func (s *rmaServiceImpl) Reject(ctx context.Context, id int, req *models.RejectRMARequest) error {
+	return s.inTx(ctx, func(tx *rmaServiceImpl) error {
+		_, status, err := tx.lock(ctx, id)
+		if err != nil {
+			return err
+		}
+		if status != models.RMAStatusRequested && status != models.RMAStatusReceived {
+			return fmt.Errorf("%w: return is %s", ErrIllegalTransition, status)
+		}
+
+		tag, err := tx.db.Exec(ctx, `
+			UPDATE rmas SET status = $1, reject_reason = $2, updated_at = NOW()
+			WHERE id = $3 AND NOT EXISTS (
+				SELECT 1 FROM rma_dispositions d JOIN rma_lines l ON l.id = d.rma_line_id
+				WHERE l.rma_id = $3)`, models.RMAStatusRejected, req.Reason, id)
+		if err != nil {
+			return fmt.Errorf("failed to reject return: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("%w: goods were already dispositioned", ErrIllegalTransition)
+		}
+		return nil
+	})
+}
+
+func (s *rmaServiceImpl) Cancel(ctx context.Context, id int) error {
+	tag, err := s.db.Exec(ctx, `
+		UPDATE rmas SET status = $1, updated_at = NOW()
+		WHERE id = $2 AND company_id = $3 AND status = $4`,
+		models.RMAStatusCancelled, id, tenant.Company(ctx), models.RMAStatusRequested)
+	if err != nil {
+		return fmt.Errorf("failed to cancel return: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		if _, err := s.GetByID(ctx, id); err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: only requested returns can be cancelled", ErrIllegalTransition)
+	}
+	return nil
+}
+
+// ============================================
+// QA Disposition
+// ============================================
+
+// Disposition puts received goods back into inventory. Restocked goods go
+// to the location given at the cost the order line shipped at; quarantined
+// goods go to the QUARANTINE location, which picking and allocation skip;
+// destroyed goods are returned and disposed of so both show in the
+// inventory history.
+func (s *rmaServiceImpl) Disposition(ctx context.Context, id int, req *models.DispositionRMARequest, createdBy int) error {
+	return s.inTx(ctx, func(tx *rmaServiceImpl) error {
+		number, status, err := tx.lock(ctx, id)
+		if err != nil {
+			return err
+		}
+		if status != models.RMAStatusReceived && status != models.RMAStatusApproved {
+			return fmt.Errorf("%w: return is %s", ErrIllegalTransition, status)
+		}
+
+		var warehouseID int
+		if err := tx.db.QueryRow(ctx, `SELECT warehouse_id FROM rmas WHERE id = $1`, id).Scan(&warehouseID); err != nil {
+			return fmt.Errorf("failed to get return warehouse: %w", err)
+		}
+		inventory := inventoryService.New(tx.db)
+
+		for _, line := range req.Lines {
+			var productID int
+			var open, cost float64
+			var lotNumber string
+			err := tx.db.QueryRow(ctx, `
+				SELECT l.product_id,
+					   l.quantity_received - COALESCE((SELECT SUM(quantity) FROM rma_dispositions WHERE rma_line_id = l.id), 0),
+					   COALESCE(l.lot_number, ''), COALESCE(sol.cost, 0)
+				FROM rma_lines l
+				JOIN sales_order_lines sol ON sol.id = l.order_line_id
+				WHERE l.id = $1 AND l.rma_id = $2
+				FOR UPDATE OF l`, line.LineID, id).Scan(&productID, &open, &lotNumber, &cost)
+			if err == pgx.ErrNoRows {
+				return fmt.Errorf("%w: line %d", ErrLineNotFound, line.LineID)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to get return line: %w", err)
+			}
+			if line.Quantity > open+quantityTolerance {
+				return fmt.Errorf("%w: line %d has %.3f left", ErrExceedsReceived, line.LineID, math.Max(open, 0))
+			}
+
+			location := line.LocationCode
+			if line.Disposition != models.DispositionRestock {
+				location = inventoryService.QuarantineLocation
+			}
+			_, err = inventory.Return(ctx, &inventoryService.ReceiveRequest{
+				ProductID:       productID,
+				WarehouseID:     warehouseID,
+				LocationCode:    location,
+				LotNumber:       lotNumber,
+				Quantity:        line.Quantity,
+				UnitCost:        cost,
+				ReferenceType:   ReferenceType,
+				ReferenceID:     id,
+				ReferenceNumber: number,
+				Notes:           line.Notes,
+			}, createdBy)
+			if err != nil {
+				return err
+			}
+			if line.Disposition == models.DispositionDestroy {
+				location = ""
+				_, err := inventory.Dispose(ctx, &inventoryService.DisposeRequest{
+					ProductID:       productID,
+					WarehouseID:     warehouseID,
+					LocationCode:    inventoryService.QuarantineLocation,
+					LotNumber:       lotNumber,
+					Quantity:        line.Quantity,
+					ReferenceType:   ReferenceType,
+					ReferenceID:     id,
+					ReferenceNumber: number,
+					Notes:           line.Notes,
+				}, createdBy)
+				if err != nil {
+					return err
+				}
+			}
+
+			_, err = tx.db.Exec(ctx, `
+				INSERT INTO rma_dispositions (rma_line_id, disposition, quantity, location_code, notes, created_by)
+				VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6)`,
+				line.LineID, line.Disposition, line.Quantity, location, line.Notes, createdBy)
+			if err != nil {
+				return fmt.Errorf("failed to record disposition: %w", err)
+			}
+		}
+		return tx.closeIfDone(ctx, id)
+	})
+}
+
+// ReleaseQuarantine moves quarantined goods to stock or destroys them.
+func (s *rmaServiceImpl) ReleaseQuarantine(ctx context.Context, id, dispositionID int, req *models.ReleaseQuarantineRequest, releasedBy int) error {
+	return s.inTx(ctx, func(tx *rmaServiceImpl) error {
+		number, _, err := tx.lock(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		var productID, warehouseID int
+		var quantity float64
+		var lotNumber string
+		err = tx.db.QueryRow(ctx, `
+			SELECT l.product_id, r.warehouse_id, d.quantity, COALESCE(l.lot_number, '')
+			FROM rma_dispositions d
+			JOIN rma_lines l ON l.id = d.rma_line_id
+			JOIN rmas r ON r.id = l.rma_id
+			WHERE d.id = $1 AND r.id = $2 AND d.disposition = $3 AND d.released_to IS NULL
+			FOR UPDATE OF d`, dispositionID, id, models.DispositionQuarantine,
+		).Scan(&productID, &warehouseID, &quantity, &lotNumber)
+		if err == pgx.ErrNoRows {
+			return ErrDispositionNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get quarantined goods: %w", err)
+		}
+
+		inventory := inventoryService.New(tx.db)
+		if req.Disposition == models.DispositionRestock {
+			err = inventory.Transfer(ctx, &models.TransferInventoryRequest{
+				ProductID:        productID,
+				FromWarehouseID:  warehouseID,
+				ToWarehouseID:    warehouseID,
+				FromLocationCode: inventoryService.QuarantineLocation,
+				ToLocationCode:   req.LocationCode,
+				LotNumber:        lotNumber,
+				Quantity:         quantity,
+				Notes:            "Released from quarantine, return " + number,
+			}, releasedBy)
+		} else {
+			_, err = inventory.Dispose(ctx, &inventoryService.DisposeRequest{
+				ProductID:       productID,
+				WarehouseID:     warehouseID,
+				LocationCode:    inventoryService.QuarantineLocation,
+				LotNumber:       lotNumber,
+				Quantity:        quantity,
+				ReferenceType:   ReferenceType,
+				ReferenceID:     id,
+				ReferenceNumber: number,
+				Notes:           req.Notes,
+			}, releasedBy)
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.db.Exec(ctx, `
+			UPDATE rma_dispositions SET released_to = $1, released_at = NOW(), released_by = $2,
+				notes = CONCAT_WS(E'\n', notes, NULLIF($3, ''))
+			WHERE id = $4`, req.Disposition, releasedBy, req.Notes, dispositionID)
+		if err != nil {
+			return fmt.Errorf("failed to release quarantined goods: %w", err)
+		}
+		return tx.closeIfDone(ctx, id)
+	})
+}
+
+// ============================================
+// Helpers
+// ============================================
+
+// lock takes the return for update and gives its number and status.
+func (s *rmaServiceImpl) lock(ctx context.Context, id int) (string, models.RMAStatus, error) {
+	var number string
+	var status models.RMAStatus
+	err := s.db.QueryRow(ctx, `
+		SELECT rma_number, status FROM rmas WHERE id = $1 AND company_id = $2 FOR UPDATE`,
+		id, tenant.Company(ctx)).Scan(&number, &status)
+	if err == pgx.ErrNoRows {
+		return "", "", ErrRMANotFound
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get return authorization: %w", err)
+	}
+	return number, status, nil
+}
+
+// closeIfDone closes an approved return once every unit received has been
+// dispositioned and nothing is left in quarantine.
+func (s *rmaServiceImpl) closeIfDone(ctx context.Context, id int) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE rmas r SET status = $1, updated_at = NOW()
+		WHERE r.id = $2 AND r.status = $3
+		  AND NOT EXISTS (
+			  SELECT 1 FROM rma_lines l
+			  WHERE l.rma_id = r.id
+				AND l.quantity_received > COALESCE((SELECT SUM(quantity) FROM rma_dispositions WHERE rma_line_id = l.id), 0) + $4)
+		  AND NOT EXISTS (
+			  SELECT 1 FROM rma_dispositions d JOIN rma_lines l ON l.id = d.rma_line_id
+			  WHERE l.rma_id = r.id AND d.disposition = $5 AND d.released_to IS NULL)`,
+		models.RMAStatusClosed, id, models.RMAStatusApproved, quantityTolerance, models.DispositionQuarantine)
+	if err != nil {
+		return fmt.Errorf("failed to close return: %w", err)
+	}
+	return nil
+}
+
+func (s *rmaServiceImpl) generateRMANumber(ctx context.Context) string {
+	var count int64
+	s.db.QueryRow(ctx, `SELECT COUNT(*) FROM rmas WHERE DATE(created_at) = CURRENT_DATE AND company_id = $1`,
+		tenant.Company(ctx)).Scan(&count)
+	return fmt.Sprintf("RMA%s%04d", time.Now().Format("20060102"), count+1)
+}
@@ -0,0 +1,269 @@
+package sales_order
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	inventoryService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/inventory"
+)
+
+// ============================================
+// Inventory Allocation
+// ============================================
+//
+// Confirmed orders hold stock through sales_order_allocations, one row per
+// inventory row (lot and location) a line draws from. Every path that
+// touches inventory walks products, and rows within a product, in ID order
+// so concurrent orders lock rows in the same sequence. Stock reserved for
+// the order's customer, customer group or sales rep is used up by what the
+// order allocates, and handed back when the order releases it.
+
+// allocatesStock reports whether orders of this type take stock out of the
+// warehouse. Credit memos bring stock back instead.
+func allocatesStock(orderType models.OrderType) bool {
+	return orderType != models.OrderTypeCreditMemo && orderType != models.OrderTypeQuote
+}
+
+// moveStock applies the inventory side of an action that has just changed
+// the order's status.
+func (s *salesOrderServiceImpl) moveStock(ctx context.Context, o *orderState, action models.OrderAction, userID int) error {
+	if !allocatesStock(o.orderType) {
+		return nil
+	}
+	switch action {
+	case models.OrderActionConfirm:
+		return s.allocateOrder(ctx, o)
+	case models.OrderActionCancel:
+		if err := s.releaseAllocations(ctx, "a.order_id = $1", o.id); err != nil {
+			return err
+		}
+		return s.closeBackorders(ctx, o)
+	case models.OrderActionShip:
+		return s.shipOrder(ctx, o, userID)
+	}
+	return nil
+}
+
+type unallocatedLine struct {
+	id        int
+	productID int
+	lotNumber string
+	quantity  float64
+}
+
+// allocateOrder allocates whatever each line still needs.
+func (s *salesOrderServiceImpl) allocateOrder(ctx context.Context, o *orderState) error {
+	lines, err := s.unallocatedLines(ctx, o)
+	if err != nil {
+		return err
+	}
+	for _, l := range lines {
+		if err := s.allocateLine(ctx, o, l); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unallocatedLines returns what each line still needs, in lock order.
+// Backordered quantities wait for stock to arrive instead.
+func (s *salesOrderServiceImpl) unallocatedLines(ctx context.Context, o *orderState) ([]unallocatedLine, error) {
+	rows := s.db.Query(ctx, `
+		SELECT id, product_id, COALESCE(lot_number, ''),
+			   quantity_ordered - quantity_shipped - quantity_allocated - quantity_backordered
+		FROM sales_order_lines
+		WHERE order_id = $1 AND quantity_ordered - quantity_shipped - quantity_allocated - quantity_backordered > 0
+		ORDER BY product_id, id`, o.id)
+
+	var lines []unallocatedLine
+	for rows.Next() {
+		var l unallocatedLine
+		if err := rows.Scan(&l.id, &l.productID, &l.lotNumber, &l.quantity); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan order line: %w", err)
+		}
+		lines = append(lines, l)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get order lines: %w", err)
+	}
+	return lines, nil
+}
+
+// allocateLine allocates l.quantity to the line, or nothing if the
+// warehouse cannot cover all of it.
+func (s *salesOrderServiceImpl) allocateLine(ctx context.Context, o *orderState, l unallocatedLine) error {
+	// Stock must still be good on the day the order ships
+	usableOn := time.Now().Truncate(24 * time.Hour)
+	if o.requestedShipDate != nil && o.requestedShipDate.After(usableOn) {
+		usableOn = *o.requestedShipDate
+	}
+
+	inventory := inventoryService.New(s.db)
+	allocations, err := inventory.Allocate(ctx, &inventoryService.AllocateRequest{
+		ProductID:   l.productID,
+		WarehouseID: o.warehouseID,
+		Quantity:    l.quantity,
+		LotNumber:   l.lotNumber,
+		UsableOn:    usableOn,
+		CustomerID:  o.customerID,
+		SalesRepID:  o.salesRepID,
+		OrderID:     o.id,
+	})
+	if err != nil {
+		return err
+	}
+	err = inventory.ConsumeReservations(ctx, &inventoryService.ConsumeReservationsRequest{
+		ProductID:   l.productID,
+		WarehouseID: o.warehouseID,
+		CustomerID:  o.customerID,
+		SalesRepID:  o.salesRepID,
+		OrderID:     o.id,
+		OrderLineID: l.id,
+		Quantity:    l.quantity,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, a := range allocations {
+		_, err := s.db.Exec(ctx, `
+			INSERT INTO sales_order_allocations (order_id, order_line_id, inventory_id, quantity)
+			VALUES ($1, $2, $3, $4)`, o.id, l.id, a.InventoryID, a.Quantity)
+		if err != nil {
+			return fmt.Errorf("failed to record allocation: %w", err)
+		}
+	}
+
+	_, err = s.db.Exec(ctx, `
+		UPDATE sales_order_lines SET quantity_allocated = quantity_allocated + $1 WHERE id = $2`,
+		l.quantity, l.id)
+	if err != nil {
+		return fmt.Errorf("failed to update line allocation: %w", err)
+	}
+	return nil
+}
+
+type allocationRow struct {
+	id          int
+	lineID      int
+	inventoryID int
+	quantity    float64
+}
+
+// allocations returns the allocations matching where, in lock order.
+func (s *salesOrderServiceImpl) allocations(ctx context.Context, where string, arg int) ([]allocationRow, error) {
+	rows := s.db.Query(ctx, fmt.Sprintf(`
+		SELECT a.id, a.order_line_id, a.inventory_id, a.quantity
+		FROM sales_order_allocations a
+		JOIN inventory i ON a.inventory_id = i.id
+		WHERE %s
+		ORDER BY i.product_id, i.id`, where), arg)
+	defer rows.Close()
+
+	var result []allocationRow
+	for rows.Next() {
+		var a allocationRow
+		if err := rows.Scan(&a.id, &a.lineID, &a.inventoryID, &a.quantity); err != nil {
+			return nil, fmt.Errorf("failed to scan allocation: %w", err)
+		}
+		result = append(result, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get allocations: %w", err)
+	}
+	return result, nil
+}
+
+// releaseAllocations hands allocated stock back to the warehouse, for a
+// whole order or a single line.
+func (s *salesOrderServiceImpl) releaseAllocations(ctx context.Context, where string, arg int) error {
+	allocations, err := s.allocations(ctx, where, arg)
+	if err != nil {
+		return err
+	}
+
+	inventory := inventoryService.New(s.db)
+	for _, a := range allocations {
+		if err := inventory.ReleaseAllocation(ctx, a.inventoryID, a.quantity); err != nil {
+			return err
+		}
+		if _, err := s.db.Exec(ctx, `DELETE FROM sales_order_allocations WHERE id = $1`, a.id); err != nil {
+			return fmt.Errorf("failed to delete allocation: %w", err)
+		}
+		// The line keeps what it took from reservations only for stock it
+		// still holds or has shipped
+		var keep float64
+		err := s.db.QueryRow(ctx, `
+			UPDATE sales_order_lines SET quantity_allocated = GREATEST(quantity_allocated - $1, 0) WHERE id = $2
+			RETURNING quantity_allocated + quantity_shipped`,
+			a.quantity, a.lineID).Scan(&keep)
+		if err != nil {
+			return fmt.Errorf("failed to update line allocation: %w", err)
+		}
+		if err := inventory.ReturnReservations(ctx, a.lineID, keep); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// shipAllocations relieves on-hand stock for what is allocated to the
+// order, moves it to the lines' shipped quantity and records the cost. A
+// line found in limits ships no more than its limit; the rest stays
+// allocated. It returns the quantity shipped.
+func (s *salesOrderServiceImpl) shipAllocations(ctx context.Context, o *orderState, limits map[int]float64, shippedBy int) (float64, error) {
+	allocations, err := s.allocations(ctx, "a.order_id = $1", o.id)
+	if err != nil {
+		return 0, err
+	}
+
+	var shipped float64
+
+	inventory := inventoryService.New(s.db)
+	for _, a := range allocations {
+		qty := a.quantity
+		if limit, ok := limits[a.lineID]; ok {
+			qty = min(qty, limit)
+			limits[a.lineID] = limit - qty
+		}
+		if qty <= 0 {
+			continue
+		}
+
+		unitCost, err := inventory.ShipAllocation(ctx, &inventoryService.ShipAllocationRequest{
+			InventoryID:     a.inventoryID,
+			Quantity:        qty,
+			ReferenceType:   "SALES_ORDER",
+			ReferenceID:     o.id,
+			ReferenceNumber: o.orderNumber,
+		}, shippedBy)
+		if err != nil {
+			return 0, err
+		}
+		shipped += qty
+
+		// Line cost is the quantity-weighted average of the rows shipped from
+		_, err = s.db.Exec(ctx, `
+			UPDATE sales_order_lines SET
+				cost = (COALESCE(cost, 0) * quantity_shipped + $1 * $2) / (quantity_shipped + $2),
+				quantity_shipped = quantity_shipped + $2,
+				quantity_allocated = GREATEST(quantity_allocated - $2, 0)
+			WHERE id = $3`, unitCost, qty, a.lineID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to update shipped quantity: %w", err)
+		}
+		if qty < a.quantity {
+			_, err = s.db.Exec(ctx, `UPDATE sales_order_allocations SET quantity = quantity - $1 WHERE id = $2`, qty, a.id)
+		} else {
+			_, err = s.db.Exec(ctx, `DELETE FROM sales_order_allocations WHERE id = $1`, a.id)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to update allocation: %w", err)
+		}
+	}
+	return shipped, nil
+}
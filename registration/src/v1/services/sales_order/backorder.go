@@ -0,0 +1,402 @@
+package sales_order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	inventoryService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/inventory"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+)
+
+// ============================================
+// Partial Shipment and Backorders
+// ============================================
+//
+// A picked order ships what was picked; any other order ships what is
+// allocated to it. What a line could not ship is handled by the customer's
+// backorder policy: it stays open on the order, moves to a backorder order,
+// or is cancelled as a lost sale. Backordered quantities hold no stock; they
+// are allocated, oldest first, when stock arrives.
+
+// shortLine is what a line still needs after a shipment.
+type shortLine struct {
+	id        int
+	productID int
+	short     float64
+}
+
+// shipOrder ships what the order has ready and backorders the rest.
+func (s *salesOrderServiceImpl) shipOrder(ctx context.Context, o *orderState, shippedBy int) error {
+	var limits map[int]float64
+	if o.status == models.OrderStatusPicking {
+		// Only what was picked goes on the truck
+		var err error
+		if limits, err = s.pickedQuantities(ctx, o.id); err != nil {
+			return err
+		}
+	} else {
+		// Lines added or changed since confirmation are topped up where the
+		// warehouse can cover them; the rest is backordered below
+		lines, err := s.unallocatedLines(ctx, o)
+		if err != nil {
+			return err
+		}
+		for _, l := range lines {
+			if err := s.allocateLine(ctx, o, l); err != nil && !errors.Is(err, inventoryService.ErrInsufficientStock) {
+				return err
+			}
+		}
+	}
+
+	shipped, err := s.shipAllocations(ctx, o, limits, shippedBy)
+	if err != nil {
+		return err
+	}
+	if shipped <= 0 {
+		return fmt.Errorf("%w: nothing on order %s is picked or allocated", ErrNothingToShip, o.orderNumber)
+	}
+
+	// Stock allocated but not picked goes back to the shelf
+	if err := s.releaseAllocations(ctx, "a.order_id = $1", o.id); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec(ctx, `UPDATE sales_order_lines SET quantity_picked = 0 WHERE order_id = $1`, o.id); err != nil {
+		return fmt.Errorf("failed to clear picked quantities: %w", err)
+	}
+
+	if err := s.backorderRemainder(ctx, o); err != nil {
+		return err
+	}
+
+	// Freight is charged on what went out, catch weights as weighed
+	return s.recalculateTotals(ctx, o.id)
+}
+
+// pickedQuantities returns how much of each line was picked, capped at
+// what the line still needs.
+func (s *salesOrderServiceImpl) pickedQuantities(ctx context.Context, orderID int) (map[int]float64, error) {
+	rows := s.db.Query(ctx, `
+		SELECT id, LEAST(quantity_picked, quantity_ordered - quantity_shipped)
+		FROM sales_order_lines
+		WHERE order_id = $1`, orderID)
+	defer rows.Close()
+
+	picked := make(map[int]float64)
+	for rows.Next() {
+		var id int
+		var qty float64
+		if err := rows.Scan(&id, &qty); err != nil {
+			return nil, fmt.Errorf("failed to scan picked quantity: %w", err)
+		}
+		picked[id] = qty
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get picked quantities: %w", err)
+	}
+	return picked, nil
+}
+
+// backorderRemainder applies the customer's backorder policy to what the
+// lines still need after a shipment. Pre-paid orders keep their backorders
+// on the order the payment is for. An order with backorders left stays
+// CONFIRMED so they can ship later.
+func (s *salesOrderServiceImpl) backorderRemainder(ctx context.Context, o *orderState) error {
+	short, err := s.shortLines(ctx, o.id)
+	if err != nil {
+		return err
+	}
+
+	policy := models.BackorderSameOrder
+	if len(short) > 0 && o.orderType != models.OrderTypePrePaid {
+		err := s.db.QueryRow(ctx, `SELECT backorder_policy FROM customers WHERE id = $1`, o.customerID).Scan(&policy)
+		if err != nil {
+			return fmt.Errorf("failed to get backorder policy: %w", err)
+		}
+	}
+
+	switch policy {
+	case models.BackorderNewOrder:
+		if err := s.splitBackorder(ctx, o, short); err != nil {
+			return err
+		}
+	case models.BackorderNone:
+		// A picked order fell short on the shelf; any other at allocation
+		reasonCode := models.LostSaleOutOfStock
+		if o.status == models.OrderStatusPicking {
+			reasonCode = models.LostSaleShortPick
+		}
+		if err := s.recordShortLines(ctx, o, short, reasonCode, "Short shipped, customer takes no backorders"); err != nil {
+			return err
+		}
+	default:
+		for _, l := range short {
+			_, err := s.db.Exec(ctx, `
+				UPDATE sales_order_lines SET quantity_backordered = quantity_backordered + $1 WHERE id = $2`,
+				l.short, l.id)
+			if err != nil {
+				return fmt.Errorf("failed to backorder line: %w", err)
+			}
+		}
+	}
+
+	_, err = s.db.Exec(ctx, `
+		UPDATE sales_orders SET status = 'CONFIRMED', updated_at = NOW()
+		WHERE id = $1 AND EXISTS (
+			SELECT 1 FROM sales_order_lines WHERE order_id = $1 AND quantity_backordered > 0
+		)`, o.id)
+	if err != nil {
+		return fmt.Errorf("failed to keep order open for backorders: %w", err)
+	}
+	return nil
+}
+
+// shortLines returns the lines that still need more than has shipped or
+// is already backordered.
+func (s *salesOrderServiceImpl) shortLines(ctx context.Context, orderID int) ([]shortLine, error) {
+	rows := s.db.Query(ctx, `
+		SELECT id, product_id,
+			   quantity_ordered - quantity_shipped - quantity_allocated - quantity_backordered
+		FROM sales_order_lines
+		WHERE order_id = $1 AND quantity_ordered - quantity_shipped - quantity_allocated - quantity_backordered > 0.0005
+		ORDER BY line_number`, orderID)
+	defer rows.Close()
+
+	var lines []shortLine
+	for rows.Next() {
+		var l shortLine
+		if err := rows.Scan(&l.id, &l.productID, &l.short); err != nil {
+			return nil, fmt.Errorf("failed to scan order line: %w", err)
+		}
+		lines = append(lines, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get order lines: %w", err)
+	}
+	return lines, nil
+}
+
+// splitBackorder moves the short quantities to a new CONFIRMED order linked
+// to the original. It was confirmed with the original, so it is not credit
+// checked again.
+func (s *salesOrderServiceImpl) splitBackorder(ctx context.Context, o *orderState, short []shortLine) error {
+	if len(short) == 0 {
+		return nil
+	}
+
+	var id int
+	err := s.db.QueryRow(ctx, `
+		INSERT INTO sales_orders (
+			order_number, customer_id, ship_to_id, order_type, warehouse_id, route_id, status,
+			notes, po_number, created_by, company_id, sales_rep_id, backorder_of_id
+		)
+		SELECT $1, customer_id, ship_to_id, order_type, warehouse_id, route_id, 'CONFIRMED',
+			   notes, po_number, created_by, company_id, sales_rep_id, id
+		FROM sales_orders
+		WHERE id = $2
+		RETURNING id`, s.generateOrderNumber(ctx), o.id).Scan(&id)
+	if err != nil {
+		return fmt.Errorf("failed to create backorder: %w", err)
+	}
+
+	for _, l := range short {
+		_, err := s.db.Exec(ctx, `
+			INSERT INTO sales_order_lines (
+				order_id, line_number, product_id, description, quantity_ordered, quantity_backordered,
+				unit_of_measure, unit_price, discount_percent, line_total, lot_number, cost
+			)
+			SELECT $1::int, line_number, product_id, description, $2::numeric, $2::numeric,
+				   unit_of_measure, unit_price, discount_percent,
+				   $2::numeric * unit_price * (1 - COALESCE(discount_percent, 0) / 100), lot_number, cost
+			FROM sales_order_lines
+			WHERE id = $3`, id, l.short, l.id)
+		if err != nil {
+			return fmt.Errorf("failed to create backorder line: %w", err)
+		}
+	}
+
+	return s.recalculateTotals(ctx, id)
+}
+
+// closeBackorders settles a cancelled order that has partly shipped. What
+// shipped stands, so the order closes as SHIPPED and whatever it was still
+// waiting for is recorded as lost sales.
+func (s *salesOrderServiceImpl) closeBackorders(ctx context.Context, o *orderState) error {
+	var shipped float64
+	err := s.db.QueryRow(ctx, `
+		SELECT COALESCE(SUM(quantity_shipped), 0) FROM sales_order_lines WHERE order_id = $1`, o.id).Scan(&shipped)
+	if err != nil {
+		return fmt.Errorf("failed to get shipped quantity: %w", err)
+	}
+	if shipped <= 0 {
+		return nil
+	}
+
+	// With backorders cleared, everything unshipped counts as short
+	if _, err := s.db.Exec(ctx, `UPDATE sales_order_lines SET quantity_backordered = 0 WHERE order_id = $1`, o.id); err != nil {
+		return fmt.Errorf("failed to clear backorders: %w", err)
+	}
+	short, err := s.shortLines(ctx, o.id)
+	if err != nil {
+		return err
+	}
+	if err := s.recordShortLines(ctx, o, short, models.LostSaleBackorderCancelled, "Backorder cancelled"); err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(ctx, `UPDATE sales_orders SET status = 'SHIPPED', updated_at = NOW() WHERE id = $1`, o.id)
+	if err != nil {
+		return fmt.Errorf("failed to close order: %w", err)
+	}
+	return nil
+}
+
+// lineShipped returns how much of a line has shipped.
+func (s *salesOrderServiceImpl) lineShipped(ctx context.Context, lineID int) (float64, error) {
+	var shipped float64
+	err := s.db.QueryRow(ctx, `SELECT quantity_shipped FROM sales_order_lines WHERE id = $1`, lineID).Scan(&shipped)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get order line: %w", err)
+	}
+	return shipped, nil
+}
+
+// ============================================
+// Backorder Fill
+// ============================================
+
+// GetBackorders lists lines waiting for stock, oldest first within each
+// product, and marks the ones the warehouse's available stock can fill.
+// Stock reserved for other customers does not count as available.
+func (s *salesOrderServiceImpl) GetBackorders(ctx context.Context, filters *models.BackorderFilters) ([]models.BackorderLine, error) {
+	whereClause := "WHERE so.company_id = $1 AND so.status = 'CONFIRMED' AND sol.quantity_backordered > 0"
+	args := []interface{}{tenant.Company(ctx)}
+	argNum := 2
+
+	if filters.WarehouseID != nil {
+		whereClause += fmt.Sprintf(" AND so.warehouse_id = $%d", argNum)
+		args = append(args, *filters.WarehouseID)
+		argNum++
+	}
+	if filters.ProductID != nil {
+		whereClause += fmt.Sprintf(" AND sol.product_id = $%d", argNum)
+		args = append(args, *filters.ProductID)
+		argNum++
+	}
+	if filters.CustomerID != nil {
+		whereClause += fmt.Sprintf(" AND so.customer_id = $%d", argNum)
+		args = append(args, *filters.CustomerID)
+		argNum++
+	}
+
+	query := fmt.Sprintf(`
+		SELECT sol.id, so.id, so.order_number, so.order_date, so.backorder_of_id,
+			   c.id, c.name, so.warehouse_id, p.id, p.sku, p.name, sol.quantity_backordered,
+			   GREATEST(COALESCE((
+				   SELECT SUM(i.quantity_available) FROM inventory i
+				   WHERE i.product_id = sol.product_id AND i.warehouse_id = so.warehouse_id
+					 AND (i.expiry_date IS NULL OR i.expiry_date >= CURRENT_DATE)
+			   ), 0) - %s, 0)
+		FROM sales_order_lines sol
+		JOIN sales_orders so ON sol.order_id = so.id
+		JOIN customers c ON so.customer_id = c.id
+		JOIN products p ON sol.product_id = p.id
+		%s
+		ORDER BY so.warehouse_id, sol.product_id, so.order_date, so.id, sol.id`,
+		inventoryService.ReservedForOthers("sol.product_id", "so.warehouse_id", "so.customer_id", "COALESCE(so.sales_rep_id, 0)", "so.id"), whereClause)
+
+	rows := s.db.Query(ctx, query, args...)
+	defer rows.Close()
+
+	type stockKey struct{ warehouseID, productID int }
+	claimed := make(map[stockKey]float64)
+
+	var lines []models.BackorderLine
+	for rows.Next() {
+		var b models.BackorderLine
+		err := rows.Scan(
+			&b.OrderLineID, &b.OrderID, &b.OrderNumber, &b.OrderDate, &b.BackorderOfID,
+			&b.CustomerID, &b.CustomerName, &b.WarehouseID, &b.ProductID, &b.ProductSKU, &b.ProductName,
+			&b.QuantityBackordered, &b.Available,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan backorder: %w", err)
+		}
+
+		// Older backorders for the product are filled first
+		key := stockKey{b.WarehouseID, b.ProductID}
+		if claimed[key]+b.QuantityBackordered <= b.Available+0.0005 {
+			b.CanFill = true
+			claimed[key] += b.QuantityBackordered
+		}
+		lines = append(lines, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get backorders: %w", err)
+	}
+	return lines, nil
+}
+
+// ReleaseBackorders allocates stock that has arrived to the warehouse's
+// backorders, oldest first. A backorder the stock cannot cover in full keeps
+// waiting and the next one is tried.
+func (s *salesOrderServiceImpl) ReleaseBackorders(ctx context.Context, req *models.ReleaseBackordersRequest) (*models.BackorderRelease, error) {
+	result := &models.BackorderRelease{Released: []models.BackorderLine{}}
+	err := s.inTx(ctx, func(tx *salesOrderServiceImpl) error {
+		backorders, err := tx.GetBackorders(ctx, &models.BackorderFilters{WarehouseID: &req.WarehouseID})
+		if err != nil {
+			return err
+		}
+
+		for _, b := range backorders {
+			if len(req.ProductIDs) > 0 && !slices.Contains(req.ProductIDs, b.ProductID) {
+				continue
+			}
+
+			o, err := tx.loadState(ctx, b.OrderID)
+			if err != nil {
+				return err
+			}
+			if o.status != models.OrderStatusConfirmed {
+				continue
+			}
+
+			// Read again under the order's lock
+			l := unallocatedLine{id: b.OrderLineID, productID: b.ProductID}
+			err = tx.db.QueryRow(ctx, `
+				SELECT COALESCE(lot_number, ''), quantity_backordered FROM sales_order_lines WHERE id = $1`,
+				b.OrderLineID).Scan(&l.lotNumber, &l.quantity)
+			if err != nil {
+				return fmt.Errorf("failed to get backordered line: %w", err)
+			}
+			if l.quantity <= 0 {
+				continue
+			}
+
+			err = tx.allocateLine(ctx, o, l)
+			if errors.Is(err, inventoryService.ErrInsufficientStock) {
+				result.Waiting++
+				continue
+			}
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.db.Exec(ctx, `
+				UPDATE sales_order_lines SET quantity_backordered = GREATEST(quantity_backordered - $1, 0) WHERE id = $2`,
+				l.quantity, l.id)
+			if err != nil {
+				return fmt.Errorf("failed to release backorder: %w", err)
+			}
+			b.QuantityBackordered = l.quantity
+			result.Released = append(result.Released, b)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
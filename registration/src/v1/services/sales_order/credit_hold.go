@@ -0,0 +1,159 @@
+package sales_order
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	arService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/ar"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+)
+
+// ============================================
+// Credit Hold
+// ============================================
+//
+// Orders are checked against the customer's credit when they are entered
+// and again when they are confirmed. A held order stays DRAFT until the
+// customer's position improves or AR releases it; a released order is not
+// checked again.
+
+// creditChecked reports whether orders of this type are sold on terms.
+// Quotes and credit memos commit the customer to nothing, and pre-paid
+// orders are paid before they ship.
+func creditChecked(orderType models.OrderType) bool {
+	switch orderType {
+	case models.OrderTypeQuote, models.OrderTypeCreditMemo, models.OrderTypePrePaid:
+		return false
+	}
+	return true
+}
+
+// creditHoldReasons explains why the order would take the customer past its
+// credit terms. It returns nothing when the order may proceed.
+func (s *salesOrderServiceImpl) creditHoldReasons(ctx context.Context, o *orderState) ([]string, error) {
+	credit, err := arService.New(s.db).GetCustomerCredit(ctx, o.customerID)
+	if err != nil {
+		return nil, err
+	}
+
+	var overdueDays int
+	err = s.db.QueryRow(ctx, `SELECT credit_hold_overdue_days FROM companies WHERE id = $1`,
+		tenant.Company(ctx)).Scan(&overdueDays)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credit hold settings: %w", err)
+	}
+
+	var reasons []string
+	if overdueDays > 0 && credit.OldestOverdue >= overdueDays {
+		reasons = append(reasons, fmt.Sprintf("oldest invoice is %d days overdue, limit is %d days",
+			credit.OldestOverdue, overdueDays))
+	}
+
+	// A zero credit limit means none has been set
+	if credit.CreditLimit > 0 {
+		open, err := s.openOrderAmount(ctx, o)
+		if err != nil {
+			return nil, err
+		}
+		exposure := open + o.totalAmount
+		if exposure > credit.AvailableCredit+0.005 {
+			reasons = append(reasons, fmt.Sprintf("order total %.2f with %.2f on other open orders exceeds available credit %.2f of limit %.2f",
+				o.totalAmount, open, credit.AvailableCredit, credit.CreditLimit))
+		}
+	}
+
+	return reasons, nil
+}
+
+// openOrderAmount is what the customer's other confirmed orders will add to
+// its balance once they are invoiced. Partly invoiced orders count only
+// the part not yet billed; the rest is already in the balance.
+func (s *salesOrderServiceImpl) openOrderAmount(ctx context.Context, o *orderState) (float64, error) {
+	var amount float64
+	err := s.db.QueryRow(ctx, `
+		SELECT COALESCE(SUM(GREATEST(so.total_amount - COALESCE(billed.amount, 0), 0)), 0)
+		FROM sales_orders so
+		LEFT JOIN LATERAL (
+			SELECT SUM(i.total_amount) AS amount
+			FROM ar_invoices i
+			WHERE i.order_id = so.id AND i.status <> 'VOID' AND i.invoice_type = 'INVOICE'
+		) billed ON true
+		WHERE so.customer_id = $1 AND so.id <> $2
+		  AND so.status IN ('CONFIRMED', 'PICKING', 'SHIPPED', 'DELIVERED')
+		  AND so.order_type NOT IN ('QUOTE', 'CREDIT_MEMO', 'PRE_PAID')`, o.customerID, o.id).Scan(&amount)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get open order amount: %w", err)
+	}
+	return amount, nil
+}
+
+// holdOnCredit checks a DRAFT order's credit and records the result: a
+// failing order is put on credit hold, a passing one has any earlier hold
+// lifted. It runs outside the confirmation so a hold stays recorded when
+// the confirmation is refused.
+func (s *salesOrderServiceImpl) holdOnCredit(ctx context.Context, id int) error {
+	o, err := s.loadState(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !creditChecked(o.orderType) || o.status != models.OrderStatusDraft || o.creditReleased {
+		return nil
+	}
+
+	reasons, err := s.creditHoldReasons(ctx, o)
+	if err != nil {
+		return err
+	}
+
+	if len(reasons) == 0 {
+		if o.creditHold {
+			_, err := s.db.Exec(ctx, `
+				UPDATE sales_orders SET credit_hold = false, updated_at = NOW()
+				WHERE id = $1 AND company_id = $2`, id, tenant.Company(ctx))
+			if err != nil {
+				return fmt.Errorf("failed to lift credit hold: %w", err)
+			}
+		}
+		return nil
+	}
+
+	reason := strings.Join(reasons, "; ")
+	_, err = s.db.Exec(ctx, `
+		UPDATE sales_orders SET
+			credit_hold = true, credit_hold_reason = $1,
+			credit_held_at = CASE WHEN credit_hold THEN credit_held_at ELSE NOW() END,
+			updated_at = NOW()
+		WHERE id = $2 AND company_id = $3`, reason, id, tenant.Company(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to place credit hold: %w", err)
+	}
+	return fmt.Errorf("%w: %s", ErrCreditHold, reason)
+}
+
+// ReleaseCreditHold lets a held order be confirmed despite its credit, and
+// records who approved it and why.
+func (s *salesOrderServiceImpl) ReleaseCreditHold(ctx context.Context, id int, reason string, releasedBy int) error {
+	o, err := s.loadState(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !o.creditHold || o.status != models.OrderStatusDraft {
+		return fmt.Errorf("%w: order is not on credit hold", ErrIllegalTransition)
+	}
+
+	result, err := s.db.Exec(ctx, `
+		UPDATE sales_orders SET
+			credit_hold = false, credit_released_at = NOW(), credit_released_by = NULLIF($1, 0),
+			credit_release_reason = $2, updated_at = NOW()
+		WHERE id = $3 AND company_id = $4 AND credit_hold`,
+		releasedBy, reason, id, tenant.Company(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to release credit hold: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("%w: order was changed by another user", ErrIllegalTransition)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package sales_order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	customerItemService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/customer_item"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrUnknownCustomerCode  = errors.New("customer item code is not mapped")
+	ErrCustomerCodeMismatch = errors.New("customer item code maps to a different product")
+)
+
+// ============================================
+// Customer Item Codes
+// ============================================
+//
+// A line may be entered by the customer's own item code in the customer's
+// unit. The code is resolved to our product before the order's
+// transaction starts, so codes nobody has mapped stay queued for the
+// sales desk even though the order itself is refused. The quantity and
+// any price given are converted to our unit; the line keeps the code and
+// the unit the customer ordered in.
+
+func (s *salesOrderServiceImpl) resolveCustomerItems(ctx context.Context, customerID int, orderID *int, lines ...*models.CreateSalesOrderLineRequest) error {
+	items := customerItemService.New(s.db)
+
+	var unknown []string
+	for _, line := range lines {
+		if line.CustomerItemCode == "" {
+			continue
+		}
+
+		mapping, err := items.Resolve(ctx, customerID, line.CustomerItemCode, line.UnitOfMeasure)
+		if errors.Is(err, customerItemService.ErrUnknownCode) {
+			err = items.QueueUnknown(ctx, &models.QueueCustomerCodeRequest{
+				CustomerID:   customerID,
+				CustomerCode: line.CustomerItemCode,
+				CustomerUOM:  line.UnitOfMeasure,
+				Description:  line.Notes,
+				Source:       models.CustomerCodeFromOrder,
+				OrderID:      orderID,
+			})
+			if err != nil {
+				return err
+			}
+			unknown = append(unknown, line.CustomerItemCode)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if line.ProductID != 0 && line.ProductID != mapping.ProductID {
+			return fmt.Errorf("%w: %s is %s", ErrCustomerCodeMismatch, line.CustomerItemCode, mapping.ProductSKU)
+		}
+
+		line.ProductID = mapping.ProductID
+		line.CustomerUOM = line.UnitOfMeasure
+		line.UnitOfMeasure = mapping.UnitOfMeasure
+		line.Quantity *= mapping.ConversionFactor
+		line.UnitPrice /= mapping.ConversionFactor
+	}
+
+	if len(unknown) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownCustomerCode, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// fillCustomerItemCode gives a line entered by our product the customer's
+// code for it, so their documents carry it. Only a mapping to the line's
+// unit is used; the customer's code for a case means nothing on a line
+// sold by the each.
+func (s *salesOrderServiceImpl) fillCustomerItemCode(ctx context.Context, customerID int, line *models.CreateSalesOrderLineRequest) error {
+	if line.CustomerItemCode != "" {
+		return nil
+	}
+
+	mapping, err := customerItemService.New(s.db).CodeFor(ctx, customerID, line.ProductID, line.UnitOfMeasure)
+	if err != nil {
+		return err
+	}
+	if mapping == nil || mapping.UnitOfMeasure != line.UnitOfMeasure || mapping.ConversionFactor != 1 {
+		return nil
+	}
+	line.CustomerItemCode = mapping.CustomerCode
+	line.CustomerUOM = mapping.CustomerUOM
+	return nil
+}
+
+// orderCustomer reads the customer of an order, or of the order a line
+// belongs to.
+func (s *salesOrderServiceImpl) orderCustomer(ctx context.Context, orderID, lineID int) (int, int, error) {
+	var customerID int
+	err := s.db.QueryRow(ctx, `
+		SELECT so.id, so.customer_id FROM sales_orders so
+		WHERE so.company_id = $3
+		  AND (so.id = $1 OR so.id = (SELECT order_id FROM sales_order_lines WHERE id = $2))`,
+		orderID, lineID, tenant.Company(ctx)).Scan(&orderID, &customerID)
+	if err == pgx.ErrNoRows {
+		if lineID != 0 {
+			return 0, 0, ErrLineNotFound
+		}
+		return 0, 0, ErrOrderNotFound
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get sales order: %w", err)
+	}
+	return orderID, customerID, nil
+}
@@ -0,0 +1,177 @@
+package sales_order
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	pricingService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/pricing"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+)
+
+// ============================================
+// Freight
+// ============================================
+//
+// Freight is worked out by the freight rules with the order totals, on the
+// order's subtotal and weight, until someone allowed to overrides it. It is
+// billed with the first invoice and stays as it is from then on.
+
+// orderWeight is a line's weight in kilograms: the caught weight of catch
+// weight lines once weighed, otherwise the product's unit weight for what
+// has shipped or, until it ships, what is ordered.
+const orderWeight = `
+	CASE WHEN p.is_catch_weight AND COALESCE(sol.catch_weight, 0) > 0
+		 THEN sol.catch_weight *
+			  CASE COALESCE(p.catch_weight_unit, 'KG')
+				  WHEN 'LB' THEN 0.45359237 WHEN 'GR' THEN 0.001 WHEN 'OZ' THEN 0.028349523125 ELSE 1 END
+		 ELSE CASE WHEN sol.quantity_shipped > 0 THEN sol.quantity_shipped ELSE sol.quantity_ordered END
+			  * COALESCE(p.weight_kg, 0) END`
+
+// calculateFreight prices the order with the freight rules. Credit memos
+// and backorders split from an order carry no freight; the original order
+// was charged for the delivery.
+func (s *salesOrderServiceImpl) calculateFreight(ctx context.Context, orderID int) (*models.FreightCalculation, error) {
+	var orderType models.OrderType
+	var routeID *int
+	var subtotal, weight float64
+	var backorder bool
+	err := s.db.QueryRow(ctx, fmt.Sprintf(`
+		SELECT so.order_type, so.route_id, COALESCE(so.subtotal, 0), so.backorder_of_id IS NOT NULL,
+			   COALESCE((
+				   SELECT SUM(%s)
+				   FROM sales_order_lines sol
+				   JOIN products p ON p.id = sol.product_id
+				   WHERE sol.order_id = so.id
+			   ), 0)
+		FROM sales_orders so
+		WHERE so.id = $1 AND so.company_id = $2`, orderWeight),
+		orderID, tenant.Company(ctx)).Scan(&orderType, &routeID, &subtotal, &backorder, &weight)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order for freight: %w", err)
+	}
+
+	fulfillment := models.FulfillmentDelivery
+	if orderType == models.OrderTypePickUp {
+		fulfillment = models.FulfillmentPickUp
+	}
+	if orderType == models.OrderTypeCreditMemo || backorder {
+		return &models.FreightCalculation{
+			Fulfillment: fulfillment,
+			RouteID:     routeID,
+			OrderValue:  subtotal,
+			WeightKg:    weight,
+		}, nil
+	}
+
+	return pricingService.New(s.db).CalculateFreight(ctx, &models.FreightRequest{
+		RouteID:     routeID,
+		Fulfillment: fulfillment,
+		OrderValue:  subtotal,
+		WeightKg:    weight,
+	})
+}
+
+// applyFreight sets the calculated freight on an order whose freight is
+// neither overridden nor invoiced.
+func (s *salesOrderServiceImpl) applyFreight(ctx context.Context, orderID int) error {
+	calc, err := s.calculateFreight(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(ctx, `
+		UPDATE sales_orders so SET freight_amount = $2
+		WHERE so.id = $1 AND NOT so.freight_overridden
+		  AND NOT EXISTS (
+			  SELECT 1 FROM ar_invoices i
+			  WHERE i.order_id = so.id AND i.status <> 'VOID' AND i.invoice_type = 'INVOICE'
+		  )`, orderID, calc.Amount)
+	if err != nil {
+		return fmt.Errorf("failed to set freight: %w", err)
+	}
+	return nil
+}
+
+func (s *salesOrderServiceImpl) GetFreight(ctx context.Context, id int) (*models.OrderFreight, error) {
+	freight := models.OrderFreight{OrderID: id}
+	err := s.db.QueryRow(ctx, `
+		SELECT COALESCE(freight_amount, 0), freight_overridden, COALESCE(freight_override_reason, ''),
+			   freight_overridden_by, freight_overridden_at
+		FROM sales_orders
+		WHERE id = $1 AND company_id = $2`, id, tenant.Company(ctx)).Scan(
+		&freight.FreightAmount, &freight.Overridden, &freight.OverrideReason,
+		&freight.OverriddenBy, &freight.OverriddenAt,
+	)
+	if err != nil {
+		return nil, ErrOrderNotFound
+	}
+
+	if freight.Calculated, err = s.calculateFreight(ctx, id); err != nil {
+		return nil, err
+	}
+	return &freight, nil
+}
+
+// OverrideFreight replaces the calculated freight with the amount given.
+// The freight override page permission is what allows it.
+func (s *salesOrderServiceImpl) OverrideFreight(ctx context.Context, id int, req *models.OverrideFreightRequest, userID int) error {
+	return s.inTx(ctx, func(tx *salesOrderServiceImpl) error {
+		if err := tx.freightEditable(ctx, id); err != nil {
+			return err
+		}
+		_, err := tx.db.Exec(ctx, `
+			UPDATE sales_orders SET
+				freight_amount = $2, freight_overridden = true, freight_override_reason = $3,
+				freight_overridden_by = NULLIF($4, 0), freight_overridden_at = NOW()
+			WHERE id = $1`, id, req.FreightAmount, req.Reason, userID)
+		if err != nil {
+			return fmt.Errorf("failed to override freight: %w", err)
+		}
+		return tx.recalculateTotals(ctx, id)
+	})
+}
+
+// ClearFreightOverride hands the order's freight back to the rules.
+func (s *salesOrderServiceImpl) ClearFreightOverride(ctx context.Context, id int) error {
+	return s.inTx(ctx, func(tx *salesOrderServiceImpl) error {
+		if err := tx.freightEditable(ctx, id); err != nil {
+			return err
+		}
+		_, err := tx.db.Exec(ctx, `
+			UPDATE sales_orders SET
+				freight_overridden = false, freight_override_reason = NULL,
+				freight_overridden_by = NULL, freight_overridden_at = NULL
+			WHERE id = $1`, id)
+		if err != nil {
+			return fmt.Errorf("failed to clear freight override: %w", err)
+		}
+		return tx.recalculateTotals(ctx, id)
+	})
+}
+
+// freightEditable locks the order and refuses freight changes once the
+// freight is invoiced or the order is closed.
+func (s *salesOrderServiceImpl) freightEditable(ctx context.Context, id int) error {
+	o, err := s.loadState(ctx, id)
+	if err != nil {
+		return err
+	}
+	switch o.status {
+	case models.OrderStatusInvoiced, models.OrderStatusCancelled, models.OrderStatusConverted, models.OrderStatusExpired:
+		return fmt.Errorf("%w: freight cannot change on a %s order", ErrIllegalTransition, o.status)
+	}
+
+	var billed bool
+	err = s.db.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM ar_invoices
+			WHERE order_id = $1 AND status <> 'VOID' AND invoice_type = 'INVOICE'
+		)`, id).Scan(&billed)
+	if err != nil {
+		return fmt.Errorf("failed to check freight billing: %w", err)
+	}
+	if billed {
+		return ErrFreightBilled
+	}
+	return nil
+}
@@ -0,0 +1,527 @@
+package sales_order
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	arService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/ar"
+	inventoryService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/inventory"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+// quoteValidityDays is how long a quote stays open when no expiry is given.
+const quoteValidityDays = 30
+
+// ============================================
+// Transition Rules
+// ============================================
+
+// transition lists the statuses an action may start from and the status it
+// leaves the order in. An empty to keeps the current status.
+type transition struct {
+	from []models.OrderStatus
+	to   models.OrderStatus
+}
+
+var transitions = map[models.OrderAction]transition{
+	models.OrderActionConfirm: {from: []models.OrderStatus{models.OrderStatusDraft}, to: models.OrderStatusConfirmed},
+	models.OrderActionCancel:  {from: []models.OrderStatus{models.OrderStatusDraft, models.OrderStatusConfirmed}, to: models.OrderStatusCancelled},
+	models.OrderActionShip:    {from: []models.OrderStatus{models.OrderStatusConfirmed, models.OrderStatusPicking}, to: models.OrderStatusShipped},
+	models.OrderActionConvert: {from: []models.OrderStatus{models.OrderStatusDraft}, to: models.OrderStatusConverted},
+	models.OrderActionRelease: {from: []models.OrderStatus{models.OrderStatusDraft}},
+	models.OrderActionCredit:  {from: []models.OrderStatus{models.OrderStatusConfirmed}, to: models.OrderStatusInvoiced},
+	models.OrderActionDeliver: {from: []models.OrderStatus{models.OrderStatusShipped}, to: models.OrderStatusDelivered},
+}
+
+// orderTypeActions lists the actions each order type accepts. Quotes never
+// ship; they are converted into an order. Credit memos never ship either;
+// processing one credits the customer instead. Whatever ships is delivered
+// with a proof of delivery.
+var orderTypeActions = map[models.OrderType][]models.OrderAction{
+	models.OrderTypeStandard:   {models.OrderActionConfirm, models.OrderActionCancel, models.OrderActionShip, models.OrderActionDeliver},
+	models.OrderTypeAdvance:    {models.OrderActionConfirm, models.OrderActionCancel, models.OrderActionShip, models.OrderActionDeliver},
+	models.OrderTypePrePaid:    {models.OrderActionConfirm, models.OrderActionCancel, models.OrderActionShip, models.OrderActionDeliver},
+	models.OrderTypePickUp:     {models.OrderActionConfirm, models.OrderActionCancel, models.OrderActionShip, models.OrderActionDeliver},
+	models.OrderTypeOnHold:     {models.OrderActionRelease, models.OrderActionConfirm, models.OrderActionCancel, models.OrderActionShip, models.OrderActionDeliver},
+	models.OrderTypeQuote:      {models.OrderActionConvert, models.OrderActionCancel},
+	models.OrderTypeCreditMemo: {models.OrderActionConfirm, models.OrderActionCancel, models.OrderActionCredit},
+}
+
+// statusActions maps a status requested through Update to the action that
+// reaches it.
+var statusActions = map[models.OrderStatus]models.OrderAction{
+	models.OrderStatusConfirmed: models.OrderActionConfirm,
+	models.OrderStatusCancelled: models.OrderActionCancel,
+	models.OrderStatusShipped:   models.OrderActionShip,
+}
+
+func permits(orderType models.OrderType, status models.OrderStatus, action models.OrderAction) bool {
+	allowed := false
+	for _, a := range orderTypeActions[orderType] {
+		if a == action {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		return false
+	}
+	for _, from := range transitions[action].from {
+		if from == status {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedActions returns the actions the type and status permit, without
+// the type-specific checks that need the database.
+func allowedActions(orderType models.OrderType, status models.OrderStatus) []models.OrderAction {
+	var actions []models.OrderAction
+	for _, action := range orderTypeActions[orderType] {
+		if permits(orderType, status, action) {
+			actions = append(actions, action)
+		}
+	}
+	return actions
+}
+
+// ============================================
+// Order State
+// ============================================
+
+type orderState struct {
+	id                int
+	orderNumber       string
+	customerID        int
+	salesRepID        int
+	orderType         models.OrderType
+	status            models.OrderStatus
+	warehouseID       int
+	totalAmount       float64
+	requestedShipDate *time.Time
+	quoteExpiryDate   *time.Time
+	holdReleased      bool
+	creditHold        bool
+	creditReleased    bool
+}
+
+// loadState reads the order for an action. Inside a transaction the row
+// stays locked, so actions and line edits on one order run one at a time.
+func (s *salesOrderServiceImpl) loadState(ctx context.Context, id int) (*orderState, error) {
+	var o orderState
+	err := s.db.QueryRow(ctx, `
+		SELECT id, order_number, customer_id, COALESCE(sales_rep_id, 0), order_type, status, warehouse_id, COALESCE(total_amount, 0),
+			   requested_ship_date, quote_expiry_date, hold_released_at IS NOT NULL,
+			   credit_hold, credit_released_at IS NOT NULL
+		FROM sales_orders
+		WHERE id = $1 AND company_id = $2
+		FOR UPDATE`, id, tenant.Company(ctx)).Scan(
+		&o.id, &o.orderNumber, &o.customerID, &o.salesRepID, &o.orderType, &o.status, &o.warehouseID, &o.totalAmount,
+		&o.requestedShipDate, &o.quoteExpiryDate, &o.holdReleased,
+		&o.creditHold, &o.creditReleased,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, ErrOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sales order: %w", err)
+	}
+	return &o, nil
+}
+
+// linesEditable refuses line changes once the order has left DRAFT or
+// CONFIRMED. A confirmed pre-paid order has been invoiced, so its lines
+// stay as billed; cancel it and enter a new order instead.
+func (o *orderState) linesEditable() error {
+	if o.status != models.OrderStatusDraft && o.status != models.OrderStatusConfirmed {
+		return fmt.Errorf("%w: lines cannot change on a %s order", ErrIllegalTransition, o.status)
+	}
+	if o.orderType == models.OrderTypePrePaid && o.status == models.OrderStatusConfirmed {
+		return fmt.Errorf("%w: lines cannot change on a confirmed %s order", ErrIllegalTransition, o.orderType)
+	}
+	return nil
+}
+
+// check applies the transition table and then the rules specific to the
+// order's type.
+func (s *salesOrderServiceImpl) check(ctx context.Context, o *orderState, action models.OrderAction) error {
+	if !permits(o.orderType, o.status, action) {
+		return fmt.Errorf("%w: cannot %s a %s order in %s status", ErrIllegalTransition, action, o.orderType, o.status)
+	}
+
+	switch {
+	case o.orderType == models.OrderTypeOnHold && action == models.OrderActionRelease && o.holdReleased:
+		return fmt.Errorf("%w: hold was already released", ErrIllegalTransition)
+
+	case o.orderType == models.OrderTypeOnHold && action == models.OrderActionConfirm && !o.holdReleased:
+		return fmt.Errorf("%w: release the hold before confirming", ErrOrderOnHold)
+
+	case o.orderType == models.OrderTypeAdvance && action == models.OrderActionShip:
+		if o.requestedShipDate != nil && o.requestedShipDate.After(time.Now()) {
+			return fmt.Errorf("%w: ships on %s", ErrNotYetScheduled, o.requestedShipDate.Format("2006-01-02"))
+		}
+
+	case o.orderType == models.OrderTypePrePaid && action == models.OrderActionShip:
+		paid, err := s.amountPaid(ctx, o.id)
+		if err != nil {
+			return err
+		}
+		if paid < o.totalAmount-0.005 {
+			return fmt.Errorf("%w: %.2f of %.2f applied", ErrPaymentRequired, paid, o.totalAmount)
+		}
+
+	case o.orderType == models.OrderTypeQuote && action == models.OrderActionConvert:
+		if o.quoteExpiryDate != nil && o.quoteExpiryDate.Before(time.Now().Truncate(24*time.Hour)) {
+			s.db.Exec(ctx, `UPDATE sales_orders SET status = 'EXPIRED', updated_at = NOW() WHERE id = $1 AND status = 'DRAFT'`, o.id)
+			return fmt.Errorf("%w: expired on %s", ErrQuoteExpired, o.quoteExpiryDate.Format("2006-01-02"))
+		}
+	}
+
+	return nil
+}
+
+// amountPaid is what customer payments have covered on the order's invoices.
+// Pre-paid orders are invoiced on confirmation (see invoicePrePaid) so they
+// can be paid before anything ships.
+func (s *salesOrderServiceImpl) amountPaid(ctx context.Context, orderID int) (float64, error) {
+	var paid float64
+	err := s.db.QueryRow(ctx, `
+		SELECT COALESCE(SUM(amount_paid), 0)
+		FROM ar_invoices
+		WHERE order_id = $1 AND company_id = $2 AND status <> 'VOID' AND invoice_type = 'INVOICE'`,
+		orderID, tenant.Company(ctx)).Scan(&paid)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get order payments: %w", err)
+	}
+	return paid, nil
+}
+
+// apply moves the order to the action's target status. The update only
+// matches the status the checks ran against, so a concurrent change fails
+// instead of being overwritten.
+func (s *salesOrderServiceImpl) apply(ctx context.Context, o *orderState, action models.OrderAction) error {
+	query := `UPDATE sales_orders SET status = $1, updated_at = NOW()`
+	if action == models.OrderActionShip {
+		query += `, actual_ship_date = CURRENT_DATE`
+	}
+	query += ` WHERE id = $2 AND company_id = $3 AND status = $4`
+
+	result, err := s.db.Exec(ctx, query, transitions[action].to, o.id, tenant.Company(ctx), o.status)
+	if err != nil {
+		return fmt.Errorf("failed to %s sales order: %w", action, err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("%w: order was changed by another user", ErrIllegalTransition)
+	}
+	return nil
+}
+
+// transition runs an action and its stock movements in one transaction.
+func (s *salesOrderServiceImpl) transition(ctx context.Context, id int, action models.OrderAction, userID int) error {
+	if action == models.OrderActionConfirm {
+		if err := s.holdOnCredit(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	return s.inTx(ctx, func(tx *salesOrderServiceImpl) error {
+		o, err := tx.loadState(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.check(ctx, o, action); err != nil {
+			return err
+		}
+		if err := tx.apply(ctx, o, action); err != nil {
+			return err
+		}
+		if err := tx.moveStock(ctx, o, action, userID); err != nil {
+			return err
+		}
+		if action == models.OrderActionConfirm && o.orderType == models.OrderTypePrePaid {
+			return tx.invoicePrePaid(ctx, o, userID)
+		}
+		if action == models.OrderActionCancel && o.orderType == models.OrderTypePrePaid && o.status == models.OrderStatusConfirmed {
+			return tx.unbillPrePaid(ctx, o, userID)
+		}
+		return nil
+	})
+}
+
+// invoicePrePaid bills a pre-paid order as it is confirmed, so the customer
+// can pay it before anything ships.
+func (s *salesOrderServiceImpl) invoicePrePaid(ctx context.Context, o *orderState, userID int) error {
+	var hasLines bool
+	err := s.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM sales_order_lines WHERE order_id = $1 AND quantity_ordered > quantity_invoiced)`,
+		o.id).Scan(&hasLines)
+	if err != nil {
+		return fmt.Errorf("failed to get order lines: %w", err)
+	}
+	if !hasLines {
+		return nil
+	}
+
+	if _, err := arService.New(s.db).CreateFromOrder(ctx, o.id, userID); err != nil {
+		return fmt.Errorf("failed to invoice pre-paid order: %w", err)
+	}
+	return nil
+}
+
+// unbillPrePaid takes back the invoice of a cancelled pre-paid order. An
+// invoice nothing has been paid on is voided; once the customer has paid,
+// they are credited what was billed and will not ship.
+func (s *salesOrderServiceImpl) unbillPrePaid(ctx context.Context, o *orderState, userID int) error {
+	paid, err := s.amountPaid(ctx, o.id)
+	if err != nil {
+		return err
+	}
+
+	ar := arService.New(s.db)
+	if paid < 0.005 {
+		rows := s.db.Query(ctx, `
+			SELECT id FROM ar_invoices
+			WHERE order_id = $1 AND company_id = $2 AND status <> 'VOID' AND invoice_type = 'INVOICE'`,
+			o.id, tenant.Company(ctx))
+		var invoiceIDs []int
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan invoice: %w", err)
+			}
+			invoiceIDs = append(invoiceIDs, id)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to get order invoices: %w", err)
+		}
+
+		for _, id := range invoiceIDs {
+			if err := ar.VoidInvoice(ctx, id); err != nil {
+				return fmt.Errorf("failed to void pre-paid invoice: %w", err)
+			}
+		}
+		return nil
+	}
+
+	if _, err := ar.CreateCreditFromOrder(ctx, o.id, userID); err != nil {
+		return fmt.Errorf("failed to credit pre-paid order: %w", err)
+	}
+	return nil
+}
+
+// inTx runs fn in a transaction. When the service was built on a caller's
+// transaction, fn joins it instead.
+func (s *salesOrderServiceImpl) inTx(ctx context.Context, fn func(tx *salesOrderServiceImpl) error) error {
+	conn, ok := s.db.(postgres.Connection)
+	if !ok {
+		return fn(s)
+	}
+
+	tx, err := conn.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&salesOrderServiceImpl{db: tx}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ============================================
+// Order Actions
+// ============================================
+
+// Confirm allocates stock to every line; the order stays DRAFT if any line
+// cannot be covered or the customer's credit puts it on hold. Pre-paid
+// orders are invoiced as they are confirmed.
+func (s *salesOrderServiceImpl) Confirm(ctx context.Context, id int, confirmedBy int) error {
+	return s.transition(ctx, id, models.OrderActionConfirm, confirmedBy)
+}
+
+// Cancel releases any stock the order holds. An order that has partly
+// shipped closes as SHIPPED instead, giving up its backorders. A confirmed
+// pre-paid order's invoice is voided, or credited once it has been paid.
+func (s *salesOrderServiceImpl) Cancel(ctx context.Context, id int, cancelledBy int) error {
+	return s.transition(ctx, id, models.OrderActionCancel, cancelledBy)
+}
+
+// Ship relieves on-hand stock for what was picked, or allocated when the
+// order was not picked, and logs the SHIP inventory transactions. The rest
+// is backordered by the customer's policy.
+func (s *salesOrderServiceImpl) Ship(ctx context.Context, id int, shippedBy int) error {
+	return s.transition(ctx, id, models.OrderActionShip, shippedBy)
+}
+
+// Deliver closes a shipped order as DELIVERED. It is called with the proof
+// of delivery, which records what the customer accepted.
+func (s *salesOrderServiceImpl) Deliver(ctx context.Context, id int, deliveredBy int) error {
+	return s.transition(ctx, id, models.OrderActionDeliver, deliveredBy)
+}
+
+// ConvertQuote turns a quote into a new sales order with the quote's lines
+// and prices, and closes the quote as CONVERTED.
+func (s *salesOrderServiceImpl) ConvertQuote(ctx context.Context, id int, req *models.ConvertQuoteRequest, createdBy int) (int, error) {
+	// Checked outside the transaction so an expired quote stays marked EXPIRED
+	o, err := s.loadState(ctx, id)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.check(ctx, o, models.OrderActionConvert); err != nil {
+		return 0, err
+	}
+
+	var orderID int
+	err = s.inTx(ctx, func(tx *salesOrderServiceImpl) error {
+		quote, err := tx.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		orderType := req.OrderType
+		if orderType == "" {
+			orderType = models.OrderTypeStandard
+		}
+
+		create := &models.CreateSalesOrderRequest{
+			CustomerID:        quote.Order.CustomerID,
+			ShipToID:          quote.Order.ShipToID,
+			OrderType:         orderType,
+			RequestedShipDate: req.RequestedShipDate,
+			WarehouseID:       quote.Order.WarehouseID,
+			Notes:             quote.Order.Notes,
+			PONumber:          req.PONumber,
+			HoldReason:        req.HoldReason,
+		}
+		if orderType != models.OrderTypePickUp {
+			create.RouteID = quote.Order.RouteID
+		}
+		if create.PONumber == "" {
+			create.PONumber = quote.Order.PONumber
+		}
+		for _, line := range quote.Lines {
+			create.Lines = append(create.Lines, models.CreateSalesOrderLineRequest{
+				ProductID:       line.ProductID,
+				Quantity:        line.QuantityOrdered,
+				UnitOfMeasure:   line.UnitOfMeasure,
+				UnitPrice:       line.UnitPrice,
+				DiscountPercent: line.DiscountPercent,
+				LotNumber:       line.LotNumber,
+				Notes:           line.Description,
+			})
+		}
+
+		orderID, err = tx.Create(ctx, create, createdBy)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.db.Exec(ctx, `UPDATE sales_orders SET source_quote_id = $1 WHERE id = $2`, id, orderID); err != nil {
+			return fmt.Errorf("failed to link order to quote: %w", err)
+		}
+
+		return tx.apply(ctx, o, models.OrderActionConvert)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return orderID, nil
+}
+
+// ReleaseHold records why an on-hold order may proceed. The order keeps its
+// type and status; confirming it is then allowed.
+func (s *salesOrderServiceImpl) ReleaseHold(ctx context.Context, id int, reason string, releasedBy int) error {
+	o, err := s.loadState(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.check(ctx, o, models.OrderActionRelease); err != nil {
+		return err
+	}
+
+	result, err := s.db.Exec(ctx, `
+		UPDATE sales_orders SET
+			hold_released_at = NOW(), hold_released_by = $1, hold_release_reason = $2, updated_at = NOW()
+		WHERE id = $3 AND company_id = $4 AND status = $5 AND hold_released_at IS NULL`,
+		releasedBy, reason, id, tenant.Company(ctx), o.status)
+	if err != nil {
+		return fmt.Errorf("failed to release hold: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("%w: order was changed by another user", ErrIllegalTransition)
+	}
+	return nil
+}
+
+// ProcessCreditMemo posts a confirmed credit memo to AR as a credit and,
+// when asked, returns the credited goods to stock.
+func (s *salesOrderServiceImpl) ProcessCreditMemo(ctx context.Context, id int, req *models.ProcessCreditMemoRequest, processedBy int) (*models.CreditMemoResult, error) {
+	result := &models.CreditMemoResult{OrderID: id, ReturnedToStock: req.ReturnToStock}
+
+	err := s.inTx(ctx, func(tx *salesOrderServiceImpl) error {
+		o, err := tx.loadState(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.check(ctx, o, models.OrderActionCredit); err != nil {
+			return err
+		}
+		if err := tx.apply(ctx, o, models.OrderActionCredit); err != nil {
+			return err
+		}
+
+		result.ARCreditID, err = arService.New(tx.db).CreateCreditFromOrder(ctx, id, processedBy)
+		if err != nil {
+			return err
+		}
+
+		if !req.ReturnToStock {
+			return nil
+		}
+
+		rows := tx.db.Query(ctx, `
+			SELECT product_id, quantity_ordered, COALESCE(lot_number, ''), COALESCE(cost, 0)
+			FROM sales_order_lines
+			WHERE order_id = $1 AND quantity_ordered > 0
+			ORDER BY line_number`, id)
+		var returns []inventoryService.ReceiveRequest
+		for rows.Next() {
+			r := inventoryService.ReceiveRequest{
+				WarehouseID:     o.warehouseID,
+				LocationCode:    req.LocationCode,
+				ReferenceType:   "CREDIT_MEMO",
+				ReferenceID:     id,
+				ReferenceNumber: o.orderNumber,
+			}
+			if err := rows.Scan(&r.ProductID, &r.Quantity, &r.LotNumber, &r.UnitCost); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan credit memo line: %w", err)
+			}
+			returns = append(returns, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to get credit memo lines: %w", err)
+		}
+
+		inventory := inventoryService.New(tx.db)
+		for i := range returns {
+			if _, err := inventory.Return(ctx, &returns[i], processedBy); err != nil {
+				return err
+			}
+		}
+		result.LinesReturned = len(returns)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
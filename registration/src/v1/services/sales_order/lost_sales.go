@@ -0,0 +1,225 @@
+package sales_order
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+)
+
+// ============================================
+// Lost Sales
+// ============================================
+//
+// Demand the warehouse could not fill is recorded where it falls short: a
+// shipment the customer will not take a backorder for, a cancelled
+// backorder, or a line deleted for want of stock. Each row carries the
+// order's customer, rep and warehouse and the line's net price, so the
+// reports do not depend on the order staying as it was.
+
+func (s *salesOrderServiceImpl) RecordLostSale(ctx context.Context, req *models.RecordLostSaleRequest, recordedBy int) error {
+	reasonCode := req.ReasonCode
+	if reasonCode == "" {
+		reasonCode = models.LostSaleOther
+	}
+
+	// Without a line the product's list price values the sale
+	tag, err := s.db.Exec(ctx, `
+		INSERT INTO lost_sales (
+			order_id, order_line_id, product_id, quantity_requested, quantity_available,
+			reason_code, reason, company_id, customer_id, sales_rep_id, warehouse_id,
+			unit_price, lost_revenue, recorded_by
+		)
+		SELECT so.id, sol.id, p.id, $4::numeric, $5::numeric,
+			   $6, NULLIF($7, ''), so.company_id, so.customer_id, so.sales_rep_id, so.warehouse_id,
+			   price.unit_price, ROUND(GREATEST($4::numeric - $5::numeric, 0) * price.unit_price, 2), NULLIF($8, 0)
+		FROM sales_orders so
+		JOIN products p ON p.id = $3
+		LEFT JOIN sales_order_lines sol ON sol.id = $2 AND sol.order_id = so.id
+		CROSS JOIN LATERAL (
+			SELECT COALESCE(sol.unit_price * (1 - COALESCE(sol.discount_percent, 0) / 100), p.price_1, 0) AS unit_price
+		) price
+		WHERE so.id = $1 AND so.company_id = $9`,
+		req.OrderID, req.OrderLineID, req.ProductID, req.QuantityRequested, req.QuantityAvailable,
+		reasonCode, req.Reason, recordedBy, tenant.Company(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to record lost sale: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrOrderNotFound
+	}
+	return nil
+}
+
+// recordShortLines records what each line could not fill.
+func (s *salesOrderServiceImpl) recordShortLines(ctx context.Context, o *orderState, lines []shortLine, reasonCode models.LostSaleReason, reason string) error {
+	for _, l := range lines {
+		lineID := l.id
+		err := s.RecordLostSale(ctx, &models.RecordLostSaleRequest{
+			OrderID:           o.id,
+			OrderLineID:       &lineID,
+			ProductID:         l.productID,
+			QuantityRequested: l.short,
+			ReasonCode:        reasonCode,
+			Reason:            reason,
+		}, 0)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordLostLine records everything a line has not shipped.
+func (s *salesOrderServiceImpl) recordLostLine(ctx context.Context, o *orderState, lineID int, reasonCode models.LostSaleReason, reason string) error {
+	l := shortLine{id: lineID}
+	err := s.db.QueryRow(ctx, `
+		SELECT product_id, quantity_ordered - quantity_shipped FROM sales_order_lines WHERE id = $1`,
+		lineID).Scan(&l.productID, &l.short)
+	if err != nil {
+		return fmt.Errorf("failed to get order line: %w", err)
+	}
+	if l.short <= 0 {
+		return nil
+	}
+	return s.recordShortLines(ctx, o, []shortLine{l}, reasonCode, reason)
+}
+
+func (s *salesOrderServiceImpl) GetLostSales(ctx context.Context, orderID *int, limit int) ([]models.LostSale, error) {
+	if limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	whereClause := "WHERE ls.company_id = $1"
+	args := []interface{}{tenant.Company(ctx)}
+	argNum := 2
+
+	if orderID != nil {
+		whereClause += fmt.Sprintf(" AND ls.order_id = $%d", argNum)
+		args = append(args, *orderID)
+		argNum++
+	}
+
+	query := fmt.Sprintf(`
+		SELECT ls.id, ls.order_id, ls.order_line_id, ls.product_id, p.name,
+			   ls.customer_id, COALESCE(c.name, ''), ls.sales_rep_id, ls.warehouse_id,
+			   COALESCE(ls.quantity_requested, 0), COALESCE(ls.quantity_available, 0), ls.unit_price, ls.lost_revenue,
+			   ls.reason_code, COALESCE(ls.reason, ''), ls.created_at
+		FROM lost_sales ls
+		JOIN products p ON ls.product_id = p.id
+		LEFT JOIN customers c ON c.id = ls.customer_id
+		%s
+		ORDER BY ls.created_at DESC
+		LIMIT $%d`, whereClause, argNum)
+
+	args = append(args, limit)
+
+	rows := s.db.Query(ctx, query, args...)
+	defer rows.Close()
+
+	var lostSales []models.LostSale
+	for rows.Next() {
+		var ls models.LostSale
+		err := rows.Scan(
+			&ls.ID, &ls.OrderID, &ls.OrderLineID, &ls.ProductID, &ls.ProductName,
+			&ls.CustomerID, &ls.CustomerName, &ls.SalesRepID, &ls.WarehouseID,
+			&ls.QuantityRequested, &ls.QuantityAvailable, &ls.UnitPrice, &ls.LostRevenue,
+			&ls.ReasonCode, &ls.Reason, &ls.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan lost sale: %w", err)
+		}
+		lostSales = append(lostSales, ls)
+	}
+
+	return lostSales, nil
+}
+
+// lostSalesGroups maps each grouping to its key, label and join.
+var lostSalesGroups = map[models.LostSalesGroup]struct {
+	key, label, join string
+}{
+	models.LostSalesByProduct:   {"ls.product_id", "p.sku || ' ' || p.name", "JOIN products p ON p.id = ls.product_id"},
+	models.LostSalesByCustomer:  {"ls.customer_id", "COALESCE(c.name, '')", "LEFT JOIN customers c ON c.id = ls.customer_id"},
+	models.LostSalesByRep:       {"ls.sales_rep_id", "COALESCE(e.english_name, '')", "LEFT JOIN employees e ON e.id = ls.sales_rep_id"},
+	models.LostSalesByWarehouse: {"ls.warehouse_id", "COALESCE(w.name, '')", "LEFT JOIN warehouses w ON w.id = ls.warehouse_id"},
+	models.LostSalesByWeek:      {"NULL::int", "TO_CHAR(DATE_TRUNC('week', ls.created_at), 'YYYY-MM-DD')", ""},
+}
+
+// GetLostSalesReport totals lost quantity and revenue by the chosen group,
+// largest revenue first; weeks run in date order.
+func (s *salesOrderServiceImpl) GetLostSalesReport(ctx context.Context, filters *models.LostSalesReportFilters) ([]models.LostSalesSummary, error) {
+	group, ok := lostSalesGroups[filters.GroupBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown lost sales grouping %q", filters.GroupBy)
+	}
+
+	whereClause := "WHERE ls.company_id = $1"
+	args := []interface{}{tenant.Company(ctx)}
+	argNum := 2
+
+	if filters.DateFrom != "" {
+		whereClause += fmt.Sprintf(" AND ls.created_at >= $%d::date", argNum)
+		args = append(args, filters.DateFrom)
+		argNum++
+	} else {
+		whereClause += " AND ls.created_at >= CURRENT_DATE - INTERVAL '12 weeks'"
+	}
+	if filters.DateTo != "" {
+		whereClause += fmt.Sprintf(" AND ls.created_at < $%d::date + 1", argNum)
+		args = append(args, filters.DateTo)
+		argNum++
+	}
+	if filters.WarehouseID != nil {
+		whereClause += fmt.Sprintf(" AND ls.warehouse_id = $%d", argNum)
+		args = append(args, *filters.WarehouseID)
+		argNum++
+	}
+	if filters.ProductID != nil {
+		whereClause += fmt.Sprintf(" AND ls.product_id = $%d", argNum)
+		args = append(args, *filters.ProductID)
+		argNum++
+	}
+	if filters.CustomerID != nil {
+		whereClause += fmt.Sprintf(" AND ls.customer_id = $%d", argNum)
+		args = append(args, *filters.CustomerID)
+		argNum++
+	}
+	if filters.ReasonCode != nil {
+		whereClause += fmt.Sprintf(" AND ls.reason_code = $%d", argNum)
+		args = append(args, *filters.ReasonCode)
+		argNum++
+	}
+
+	orderBy := "SUM(ls.lost_revenue) DESC"
+	if filters.GroupBy == models.LostSalesByWeek {
+		orderBy = "2"
+	}
+
+	query := fmt.Sprintf(`
+		SELECT %[1]s, %[2]s, COUNT(*),
+			   COALESCE(SUM(GREATEST(ls.quantity_requested - COALESCE(ls.quantity_available, 0), 0)), 0),
+			   COALESCE(SUM(ls.lost_revenue), 0)
+		FROM lost_sales ls
+		%[3]s
+		%[4]s
+		GROUP BY 1, 2
+		ORDER BY %[5]s`, group.key, group.label, group.join, whereClause, orderBy)
+
+	rows := s.db.Query(ctx, query, args...)
+	defer rows.Close()
+
+	report := []models.LostSalesSummary{}
+	for rows.Next() {
+		var row models.LostSalesSummary
+		if err := rows.Scan(&row.GroupID, &row.Label, &row.Occurrences, &row.QuantityLost, &row.LostRevenue); err != nil {
+			return nil, fmt.Errorf("failed to scan lost sales summary: %w", err)
+		}
+		report = append(report, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get lost sales report: %w", err)
+	}
+	return report, nil
+}
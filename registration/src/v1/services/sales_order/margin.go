@@ -0,0 +1,130 @@
+package sales_order
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	pricingService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/pricing"
+)
+
+// ============================================
+// Margin Guard
+// ============================================
+//
+// Every line captures its cost from the product's costing method when it
+// is entered or changed. Lines under a BLOCK margin rule are refused; lines
+// sold below cost that get through are logged for the exceptions report.
+
+// orderLine is a line about to be written, priced and checked.
+type orderLine struct {
+	req       *models.CreateSalesOrderLineRequest
+	unitPrice float64
+	lineTotal float64
+	check     *models.MarginCheck
+}
+
+// guardLine prices a line and checks it against the margin rules. Credit
+// memos give money back, so only their cost is captured.
+func (s *salesOrderServiceImpl) guardLine(ctx context.Context, o *orderState, req *models.CreateSalesOrderLineRequest) (*orderLine, error) {
+	l := &orderLine{req: req, unitPrice: req.UnitPrice}
+	if l.unitPrice == 0 {
+		l.unitPrice = s.getProductPrice(ctx, o.customerID, req.ProductID)
+	}
+	netPrice := l.unitPrice * (1 - req.DiscountPercent/100)
+	l.lineTotal = req.Quantity * netPrice
+
+	check, err := pricingService.New(s.db).CheckMargin(ctx, &models.MarginCheckRequest{
+		ProductID:   req.ProductID,
+		WarehouseID: o.warehouseID,
+		CustomerID:  o.customerID,
+		SalesRepID:  o.salesRepID,
+		UnitPrice:   netPrice,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if check.Blocked && o.orderType != models.OrderTypeCreditMemo {
+		return nil, fmt.Errorf("%w: product %d: %s", ErrMarginTooLow, req.ProductID, strings.Join(check.Warnings, "; "))
+	}
+	l.check = check
+	return l, nil
+}
+
+// recordBelowCost keeps the exceptions report in step with a line: any
+// earlier entry is replaced, and one is logged if the line sells below
+// cost. Quotes and credit memos are not sales and are never logged.
+func (s *salesOrderServiceImpl) recordBelowCost(ctx context.Context, o *orderState, lineID int, l *orderLine, userID int) error {
+	if _, err := s.db.Exec(ctx, `DELETE FROM margin_exceptions WHERE order_line_id = $1`, lineID); err != nil {
+		return fmt.Errorf("failed to clear margin exception: %w", err)
+	}
+	if !l.check.IsBelowCost || !allocatesStock(o.orderType) {
+		return nil
+	}
+
+	e := &models.MarginException{
+		OrderID:       o.id,
+		OrderLineID:   &lineID,
+		ProductID:     l.req.ProductID,
+		CustomerID:    o.customerID,
+		Quantity:      l.req.Quantity,
+		UnitPrice:     l.check.UnitPrice,
+		UnitCost:      l.check.UnitCost,
+		CostingMethod: l.check.CostingMethod,
+		MarginPercent: l.check.MarginPercent,
+	}
+	if o.salesRepID > 0 {
+		e.SalesRepID = &o.salesRepID
+	}
+	if userID > 0 {
+		e.CreatedBy = &userID
+	}
+	return pricingService.New(s.db).RecordMarginException(ctx, e)
+}
+
+// lineResult reports a line's margin together with the order's.
+func (s *salesOrderServiceImpl) lineResult(ctx context.Context, orderID, lineID int, l *orderLine) (*models.SalesOrderLineResult, error) {
+	lineCost := l.check.UnitCost * l.req.Quantity
+	result := &models.SalesOrderLineResult{
+		LineID:            lineID,
+		UnitCost:          l.check.UnitCost,
+		CostingMethod:     l.check.CostingMethod,
+		LineMargin:        l.lineTotal - lineCost,
+		LineMarginPercent: marginPercent(l.lineTotal, lineCost),
+		IsBelowCost:       l.check.IsBelowCost,
+		Warnings:          l.check.Warnings,
+	}
+
+	revenue, cost, err := s.orderCost(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	result.OrderMargin = revenue - cost
+	result.OrderMarginPercent = marginPercent(revenue, cost)
+	return result, nil
+}
+
+// orderCost returns the order's line revenue and the cost captured for it.
+func (s *salesOrderServiceImpl) orderCost(ctx context.Context, orderID int) (float64, float64, error) {
+	var revenue, cost float64
+	err := s.db.QueryRow(ctx, `
+		SELECT COALESCE(SUM(line_total), 0), COALESCE(SUM(COALESCE(cost, 0) * quantity_ordered), 0)
+		FROM sales_order_lines
+		WHERE order_id = $1`, orderID).Scan(&revenue, &cost)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get order margin: %w", err)
+	}
+	return revenue, cost, nil
+}
+
+// marginPercent is margin over revenue, as pricing reports it.
+func marginPercent(revenue, cost float64) float64 {
+	if revenue > 0 {
+		return (revenue - cost) / revenue * 100
+	}
+	if cost > 0 {
+		return -100
+	}
+	return 0
+}
@@ -0,0 +1,249 @@
+package sales_order
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	inventoryService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/inventory"
+	pricingService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/pricing"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+// ============================================
+// Order Guide Drafts
+// ============================================
+//
+// A customer's order guide is proposed as an order: each product is
+// suggested at its average weekly usage scaled to the days since the last
+// delivery, priced through the pricing hierarchy and checked against the
+// warehouse's available stock. The client edits the proposal in bulk and
+// accepts it as a DRAFT order.
+
+const (
+	// defaultCoverDays is the usage suggested for a customer with no
+	// delivery yet: one week.
+	defaultCoverDays = 7
+	// maxCoverDays keeps a long gap between orders from suggesting more
+	// than a month of stock.
+	maxCoverDays = 28
+)
+
+// SuggestFromGuide proposes an order from the customer's order guide with
+// the client's edits applied. Nothing is written.
+func (s *salesOrderServiceImpl) SuggestFromGuide(ctx context.Context, req *models.OrderGuideDraftRequest) (*models.OrderGuideDraft, error) {
+	deliveryDate := time.Now().UTC().Truncate(24 * time.Hour)
+	if req.RequestedShipDate != "" {
+		if t, err := time.Parse("2006-01-02", req.RequestedShipDate); err == nil {
+			deliveryDate = t
+		}
+	}
+
+	draft := &models.OrderGuideDraft{
+		CustomerID:  req.CustomerID,
+		WarehouseID: req.WarehouseID,
+		CoverDays:   defaultCoverDays,
+		Lines:       []models.OrderGuideLine{},
+	}
+
+	last, err := s.lastDelivery(ctx, req.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+	if last != nil {
+		draft.LastDeliveryDate = models.CustomDate(*last)
+		draft.DaysSinceDelivery = int(deliveryDate.Sub(*last).Hours() / 24)
+		draft.CoverDays = min(max(draft.DaysSinceDelivery, 1), maxCoverDays)
+	}
+
+	entries, err := s.GetOrderGuide(ctx, req.CustomerID, req.WarehouseID)
+	if err != nil {
+		return nil, err
+	}
+
+	edits := make(map[int]*models.OrderGuideLineEdit, len(req.Lines))
+	for i := range req.Lines {
+		edits[req.Lines[i].ProductID] = &req.Lines[i]
+	}
+
+	for _, e := range entries {
+		line := models.OrderGuideLine{
+			ProductID:      e.ProductID,
+			ProductSKU:     e.ProductSKU,
+			ProductName:    e.ProductName,
+			UnitOfMeasure:  e.UnitOfMeasure,
+			IsPushItem:     e.IsPushItem,
+			OnGuide:        true,
+			AvgWeeklyQty:   e.AvgWeeklyQty,
+			LastOrderedQty: e.LastOrderedQty,
+			SuggestedQty:   suggestQty(&e, draft.CoverDays),
+			Available:      e.Available,
+		}
+		line.Quantity = line.SuggestedQty
+		if err := s.fillGuideLine(ctx, req, &line, edits[e.ProductID]); err != nil {
+			return nil, err
+		}
+		delete(edits, e.ProductID)
+		draft.Lines = append(draft.Lines, line)
+	}
+
+	// Products the client added follow the guide in the order given
+	for _, edit := range req.Lines {
+		if edits[edit.ProductID] == nil {
+			continue
+		}
+		line, err := s.guideProduct(ctx, edit.ProductID, req.CustomerID, req.WarehouseID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.fillGuideLine(ctx, req, line, edits[edit.ProductID]); err != nil {
+			return nil, err
+		}
+		draft.Lines = append(draft.Lines, *line)
+	}
+
+	for _, line := range draft.Lines {
+		draft.TotalAmount += line.LineTotal
+		if line.Shortage {
+			draft.ShortageCount++
+		}
+	}
+
+	return draft, nil
+}
+
+// CreateFromGuide creates a DRAFT order from the proposal, leaving off
+// lines with nothing to order. Shortages do not stop the order; stock is
+// allocated when it is confirmed.
+func (s *salesOrderServiceImpl) CreateFromGuide(ctx context.Context, req *models.OrderGuideDraftRequest, createdBy int) (*models.OrderGuideDraft, error) {
+	draft, err := s.SuggestFromGuide(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	order := &models.CreateSalesOrderRequest{
+		CustomerID:        req.CustomerID,
+		ShipToID:          req.ShipToID,
+		OrderType:         req.OrderType,
+		RequestedShipDate: req.RequestedShipDate,
+		WarehouseID:       req.WarehouseID,
+		RouteID:           req.RouteID,
+		Notes:             req.Notes,
+		PONumber:          req.PONumber,
+	}
+	for _, line := range draft.Lines {
+		if line.Quantity <= 0 {
+			continue
+		}
+		order.Lines = append(order.Lines, models.CreateSalesOrderLineRequest{
+			ProductID:       line.ProductID,
+			Quantity:        line.Quantity,
+			UnitOfMeasure:   line.UnitOfMeasure,
+			UnitPrice:       line.UnitPrice,
+			DiscountPercent: line.DiscountPercent,
+		})
+	}
+	if len(order.Lines) == 0 {
+		return nil, ErrEmptyDraft
+	}
+
+	id, err := s.Create(ctx, order, createdBy)
+	if err != nil {
+		return nil, err
+	}
+	draft.OrderID = &id
+	return draft, nil
+}
+
+// fillGuideLine applies the client's edit to a proposed line, prices it
+// and flags a shortage against the warehouse's available stock.
+func (s *salesOrderServiceImpl) fillGuideLine(ctx context.Context, req *models.OrderGuideDraftRequest, line *models.OrderGuideLine, edit *models.OrderGuideLineEdit) error {
+	if edit != nil {
+		line.Edited = true
+		if edit.Quantity != nil {
+			line.Quantity = *edit.Quantity
+		}
+		if edit.DiscountPercent != nil {
+			line.DiscountPercent = *edit.DiscountPercent
+		}
+	}
+
+	if edit != nil && edit.UnitPrice != nil {
+		line.UnitPrice = *edit.UnitPrice
+		line.PriceSource = "Manual"
+	} else {
+		// Quantity breaks are priced on what is ordered
+		price, err := pricingService.New(s.db).GetPrice(ctx, &models.PriceLookupRequest{
+			ProductID:  line.ProductID,
+			CustomerID: &req.CustomerID,
+			Quantity:   max(line.Quantity, 1),
+			AsOfDate:   req.RequestedShipDate,
+		})
+		if err != nil {
+			return err
+		}
+		line.UnitPrice = price.Price
+		line.PriceLevel = price.PriceLevel
+		line.PriceSource = price.PriceSource
+	}
+
+	line.LineTotal = line.Quantity * line.UnitPrice * (1 - line.DiscountPercent/100)
+	if line.Quantity > line.Available {
+		line.Shortage = true
+		line.ShortQty = line.Quantity - max(line.Available, 0)
+	}
+	return nil
+}
+
+// suggestQty scales average weekly usage to the days the order covers,
+// rounded up to whole units. Products without usage history fall back to
+// the guide's default quantity.
+func suggestQty(e *models.OrderGuideEntry, coverDays int) float64 {
+	if e.AvgWeeklyQty > 0 {
+		return math.Ceil(e.AvgWeeklyQty*float64(coverDays)/7 - 0.0005)
+	}
+	return e.DefaultQuantity
+}
+
+// lastDelivery is the ship date of the customer's latest delivered order,
+// or nil if it has never had one.
+func (s *salesOrderServiceImpl) lastDelivery(ctx context.Context, customerID int) (*time.Time, error) {
+	var last *time.Time
+	err := s.db.QueryRow(ctx, `
+		SELECT MAX(COALESCE(actual_ship_date, requested_ship_date, order_date))
+		FROM sales_orders
+		WHERE customer_id = $1 AND company_id = $2
+		  AND status IN ('SHIPPED', 'DELIVERED', 'INVOICED')
+		  AND order_type NOT IN ('QUOTE', 'CREDIT_MEMO')`,
+		customerID, tenant.Company(ctx)).Scan(&last)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last delivery: %w", err)
+	}
+	return last, nil
+}
+
+// guideProduct starts a line for a product the client added to the guide.
+// What is reserved for other customers is not available to this one.
+func (s *salesOrderServiceImpl) guideProduct(ctx context.Context, productID, customerID, warehouseID int) (*models.OrderGuideLine, error) {
+	line := &models.OrderGuideLine{ProductID: productID}
+	err := s.db.QueryRow(ctx, `
+		SELECT p.sku, p.name, COALESCE(pu.abbreviation, 'EA'),
+			   GREATEST(COALESCE((SELECT SUM(quantity_available) FROM inventory
+								  WHERE product_id = p.id AND warehouse_id = $2), 0)
+						- `+inventoryService.ReservedForOthers("p.id", "$2", "$3", "0", "0")+`, 0)
+		FROM products p
+		LEFT JOIN product_units pu ON p.default_unit_id = pu.id
+		WHERE p.id = $1 AND p.is_active = true`, productID, warehouseID, customerID).Scan(
+		&line.ProductSKU, &line.ProductName, &line.UnitOfMeasure, &line.Available,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("%w: %d", ErrProductNotFound, productID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+	return line, nil
+}
@@ -0,0 +1,59 @@
+package sales_order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	pickingService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/picking"
+)
+
+// ============================================
+// Route Assignment
+// ============================================
+
+// routed reports whether orders of this type go out on a delivery route.
+// Pick-up orders are collected, and quotes and credit memos do not ship.
+func routed(orderType models.OrderType) bool {
+	switch orderType {
+	case models.OrderTypePickUp, models.OrderTypeQuote, models.OrderTypeCreditMemo:
+		return false
+	}
+	return true
+}
+
+// assignRoute fills in what a new order left out: the route from its
+// ship-to or else its customer, and the ship date of that route's next run
+// the order can still make. An order whose route has no calendar keeps an
+// empty ship date.
+func (s *salesOrderServiceImpl) assignRoute(ctx context.Context, req *models.CreateSalesOrderRequest, orderType models.OrderType) (*int, string, error) {
+	routeID, shipDate := req.RouteID, req.RequestedShipDate
+	if !routed(orderType) {
+		return routeID, shipDate, nil
+	}
+
+	if routeID == nil {
+		err := s.db.QueryRow(ctx, `
+			SELECT COALESCE(
+				(SELECT route_id FROM customer_ship_to WHERE id = $2::int AND customer_id = $1),
+				(SELECT default_route_id FROM customers WHERE id = $1))`,
+			req.CustomerID, req.ShipToID).Scan(&routeID)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get customer route: %w", err)
+		}
+	}
+	if routeID == nil || shipDate != "" {
+		return routeID, shipDate, nil
+	}
+
+	run, err := pickingService.New(s.db).NextRouteRun(ctx, *routeID, time.Now())
+	if errors.Is(err, pickingService.ErrNoRouteRun) || errors.Is(err, pickingService.ErrRouteNotFound) {
+		return routeID, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return routeID, time.Time(run.RunDate).Format("2006-01-02"), nil
+}
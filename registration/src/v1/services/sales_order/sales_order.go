@@ -2,14 +2,37 @@ package sales_order
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/anas-dev-92/FoodHive/core/postgres"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	inventoryService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/inventory"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/query"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
 	"github.com/jackc/pgx/v5"
 )
 
+var (
+	ErrOrderNotFound     = errors.New("sales order not found")
+	ErrLineNotFound      = errors.New("order line not found")
+	ErrIllegalTransition = errors.New("illegal order transition")
+	ErrQuoteExpired      = errors.New("quote has expired")
+	ErrPaymentRequired   = errors.New("pre-paid order has not been paid")
+	ErrOrderOnHold       = errors.New("order is on hold")
+	ErrNotYetScheduled   = errors.New("advance order is scheduled for a later date")
+	ErrPickUpNotRouted   = errors.New("pick-up orders are not routed")
+	ErrCreditHold        = errors.New("order is on credit hold")
+	ErrMarginTooLow      = errors.New("line is below the minimum margin")
+	ErrProductNotFound   = errors.New("product not found")
+	ErrEmptyDraft        = errors.New("order guide draft has no lines to order")
+	ErrNothingToShip     = errors.New("order has nothing ready to ship")
+	ErrLineCovered       = errors.New("order line is already covered by stock or backorders")
+	ErrFreightBilled     = errors.New("freight has already been invoiced")
+	ErrCustomerNotFound  = errors.New("customer not found")
+)
+
 // ============================================
 // Service Interface
 // ============================================
@@ -22,21 +45,46 @@ type SalesOrderService interface {
 	Update(ctx context.Context, id int, req *models.UpdateSalesOrderRequest) error
 	Delete(ctx context.Context, id int) error
 	List(ctx context.Context, filters *models.SalesOrderListFilters) ([]models.SalesOrderWithDetails, int64, error)
-	Confirm(ctx context.Context, id int) error
-	Cancel(ctx context.Context, id int) error
-	Ship(ctx context.Context, id int) error
+	Confirm(ctx context.Context, id int, confirmedBy int) error
+	Cancel(ctx context.Context, id int, cancelledBy int) error
+	Ship(ctx context.Context, id int, shippedBy int) error
+	Deliver(ctx context.Context, id int, deliveredBy int) error
+
+	// Order Type Lifecycle
+	ConvertQuote(ctx context.Context, id int, req *models.ConvertQuoteRequest, createdBy int) (int, error)
+	ReleaseHold(ctx context.Context, id int, reason string, releasedBy int) error
+	ProcessCreditMemo(ctx context.Context, id int, req *models.ProcessCreditMemoRequest, processedBy int) (*models.CreditMemoResult, error)
+
+	// Credit Hold
+	ReleaseCreditHold(ctx context.Context, id int, reason string, releasedBy int) error
 
 	// Sales Order Lines
-	AddLine(ctx context.Context, orderID int, req *models.CreateSalesOrderLineRequest) (int, error)
-	UpdateLine(ctx context.Context, lineID int, req *models.CreateSalesOrderLineRequest) error
-	DeleteLine(ctx context.Context, lineID int) error
+	AddLine(ctx context.Context, orderID int, req *models.CreateSalesOrderLineRequest, userID int) (*models.SalesOrderLineResult, error)
+	UpdateLine(ctx context.Context, lineID int, req *models.CreateSalesOrderLineRequest, userID int) (*models.SalesOrderLineResult, error)
+	DeleteLine(ctx context.Context, lineID int, lostReason models.LostSaleReason) error
 
 	// Order Guide
 	GetOrderGuide(ctx context.Context, customerID int, warehouseID int) ([]models.OrderGuideEntry, error)
+	SuggestFromGuide(ctx context.Context, req *models.OrderGuideDraftRequest) (*models.OrderGuideDraft, error)
+	CreateFromGuide(ctx context.Context, req *models.OrderGuideDraftRequest, createdBy int) (*models.OrderGuideDraft, error)
+
+	// Backorders
+	GetBackorders(ctx context.Context, filters *models.BackorderFilters) ([]models.BackorderLine, error)
+	ReleaseBackorders(ctx context.Context, req *models.ReleaseBackordersRequest) (*models.BackorderRelease, error)
+
+	// Special Orders
+	HoldForSpecialOrder(ctx context.Context, lineID int) (*models.SpecialOrderLine, error)
+	ReceiveSpecialOrder(ctx context.Context, lineID int, quantity float64) (float64, error)
 
 	// Lost Sales
-	RecordLostSale(ctx context.Context, orderID, productID int, qtyRequested, qtyAvailable float64, reason string) error
+	RecordLostSale(ctx context.Context, req *models.RecordLostSaleRequest, recordedBy int) error
 	GetLostSales(ctx context.Context, orderID *int, limit int) ([]models.LostSale, error)
+	GetLostSalesReport(ctx context.Context, filters *models.LostSalesReportFilters) ([]models.LostSalesSummary, error)
+
+	// Freight
+	GetFreight(ctx context.Context, id int) (*models.OrderFreight, error)
+	OverrideFreight(ctx context.Context, id int, req *models.OverrideFreightRequest, userID int) error
+	ClearFreightOverride(ctx context.Context, id int) error
 }
 
 // ============================================
@@ -56,73 +104,151 @@ func New(db postgres.Executor) SalesOrderService {
 // ============================================
 
 func (s *salesOrderServiceImpl) Create(ctx context.Context, req *models.CreateSalesOrderRequest, createdBy int) (int, error) {
+	// The customer must belong to the company the order is taken in
+	var exists bool
+	err := s.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM customers WHERE id = $1 AND company_id = $2)`,
+		req.CustomerID, tenant.Company(ctx)).Scan(&exists)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get customer: %w", err)
+	}
+	if !exists {
+		return 0, ErrCustomerNotFound
+	}
+
 	// Generate order number
 	orderNumber := s.generateOrderNumber(ctx)
 
+	// Set default order type if not provided
+	orderType := req.OrderType
+	if orderType == "" {
+		orderType = models.OrderTypeStandard
+	}
+
+	// Routed orders without a route or date go on the next run they can make
+	routeID, shipDate, err := s.assignRoute(ctx, req, orderType)
+	if err != nil {
+		return 0, err
+	}
+
 	// Parse requested ship date
 	var reqShipDate *time.Time
-	if req.RequestedShipDate != "" {
-		t, err := time.Parse("2006-01-02", req.RequestedShipDate)
+	if shipDate != "" {
+		t, err := time.Parse("2006-01-02", shipDate)
 		if err == nil {
 			reqShipDate = &t
 		}
 	}
 
-	// Set default order type if not provided
-	orderType := req.OrderType
-	if orderType == "" {
-		orderType = models.OrderTypeStandard
+	// Quotes are valid for quoteValidityDays unless an expiry is given
+	var quoteExpiry *time.Time
+	if orderType == models.OrderTypeQuote {
+		t := time.Now().AddDate(0, 0, quoteValidityDays)
+		if req.QuoteExpiryDate != "" {
+			if parsed, err := time.Parse("2006-01-02", req.QuoteExpiryDate); err == nil {
+				t = parsed
+			}
+		}
+		quoteExpiry = &t
 	}
 
-	// Insert header
-	query := `
-		INSERT INTO sales_orders (
-			order_number, customer_id, ship_to_id, order_type, requested_ship_date,
-			warehouse_id, route_id, status, notes, po_number, created_by
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, 'DRAFT', $8, $9, $10)
-		RETURNING id`
-
-	var id int
-	err := s.db.QueryRow(ctx, query,
-		orderNumber, req.CustomerID, req.ShipToID, orderType, reqShipDate,
-		req.WarehouseID, req.RouteID, req.Notes, req.PONumber, createdBy,
-	).Scan(&id)
+	var holdReason *string
+	if orderType == models.OrderTypeOnHold && req.HoldReason != "" {
+		holdReason = &req.HoldReason
+	}
 
-	if err != nil {
-		return 0, fmt.Errorf("failed to create sales order: %w", err)
+	lines := make([]*models.CreateSalesOrderLineRequest, len(req.Lines))
+	for i := range req.Lines {
+		lines[i] = &req.Lines[i]
+	}
+	if err := s.resolveCustomerItems(ctx, req.CustomerID, nil, lines...); err != nil {
+		return 0, err
 	}
 
-	// Insert lines
-	for i, line := range req.Lines {
-		// Get product price if not provided
-		unitPrice := line.UnitPrice
-		if unitPrice == 0 {
-			unitPrice = s.getProductPrice(ctx, req.CustomerID, line.ProductID)
+	var id int
+	err = s.inTx(ctx, func(tx *salesOrderServiceImpl) error {
+		// Insert header; the salesperson defaults to the customer's
+		query := `
+			INSERT INTO sales_orders (
+				order_number, customer_id, ship_to_id, order_type, requested_ship_date,
+				warehouse_id, route_id, status, notes, po_number, created_by, company_id,
+				quote_expiry_date, hold_reason, sales_rep_id
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, 'DRAFT', $8, $9, NULLIF($10, 0), $11, $12, $13,
+				(SELECT sales_rep_id FROM customers WHERE id = $2))
+			RETURNING id`
+
+		err := tx.db.QueryRow(ctx, query,
+			orderNumber, req.CustomerID, req.ShipToID, orderType, reqShipDate,
+			req.WarehouseID, routeID, req.Notes, req.PONumber, createdBy, tenant.Company(ctx),
+			quoteExpiry, holdReason,
+		).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("failed to create sales order: %w", err)
 		}
 
-		lineTotal := line.Quantity * unitPrice * (1 - line.DiscountPercent/100)
+		o, err := tx.loadState(ctx, id)
+		if err != nil {
+			return err
+		}
 
-		lineQuery := `
-			INSERT INTO sales_order_lines (
-				order_id, line_number, product_id, description, quantity_ordered,
-				unit_of_measure, unit_price, discount_percent, line_total, lot_number
-			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+		// Insert lines
+		for i := range req.Lines {
+			if _, err := tx.insertLine(ctx, o, i+1, &req.Lines[i], createdBy); err != nil {
+				return err
+			}
+		}
 
-		_, err := s.db.Exec(ctx, lineQuery,
-			id, i+1, line.ProductID, line.Notes, line.Quantity,
-			line.UnitOfMeasure, unitPrice, line.DiscountPercent, lineTotal, line.LotNumber,
-		)
-		if err != nil {
-			return 0, fmt.Errorf("failed to create order line: %w", err)
+		// Calculate totals
+		if err := tx.recalculateTotals(ctx, id); err != nil {
+			return err
 		}
-	}
 
-	// Calculate totals
-	s.recalculateTotals(ctx, id)
+		// A held order is still created; the hold is reported on the order
+		if err := tx.holdOnCredit(ctx, id); err != nil && !errors.Is(err, ErrCreditHold) {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
 
 	return id, nil
 }
 
+// insertLine guards, costs and writes a new line.
+func (s *salesOrderServiceImpl) insertLine(ctx context.Context, o *orderState, lineNumber int, req *models.CreateSalesOrderLineRequest, userID int) (*models.SalesOrderLineResult, error) {
+	l, err := s.guardLine(ctx, o, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.fillCustomerItemCode(ctx, o.customerID, req); err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO sales_order_lines (
+			order_id, line_number, product_id, description, quantity_ordered,
+			unit_of_measure, unit_price, discount_percent, line_total, lot_number, cost,
+			customer_item_code, customer_uom
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, NULLIF($12, ''), NULLIF($13, ''))
+		RETURNING id`
+
+	var id int
+	err = s.db.QueryRow(ctx, query,
+		o.id, lineNumber, req.ProductID, req.Notes, req.Quantity,
+		req.UnitOfMeasure, l.unitPrice, req.DiscountPercent, l.lineTotal, req.LotNumber, l.check.UnitCost,
+		req.CustomerItemCode, req.CustomerUOM,
+	).Scan(&id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add order line: %w", err)
+	}
+
+	if err := s.recordBelowCost(ctx, o, id, l, userID); err != nil {
+		return nil, err
+	}
+	return s.lineResult(ctx, o.id, id, l)
+}
+
 func (s *salesOrderServiceImpl) GetByID(ctx context.Context, id int) (*models.SalesOrderWithDetails, error) {
 	return s.getSalesOrder(ctx, "so.id = $1", id)
 }
@@ -135,9 +261,14 @@ func (s *salesOrderServiceImpl) getSalesOrder(ctx context.Context, whereClause s
 	query := fmt.Sprintf(`
 		SELECT so.id, so.order_number, so.customer_id, so.ship_to_id, so.order_type,
 			   so.order_date, so.requested_ship_date, so.actual_ship_date, so.warehouse_id,
-			   so.route_id, so.status, so.subtotal, so.tax_amount, so.freight_amount,
+			   so.route_id, so.status, so.subtotal, so.tax_amount, so.freight_amount, so.freight_overridden,
 			   so.discount_amount, so.total_amount, so.notes, so.po_number, so.sales_rep_id,
 			   so.created_by, so.created_at, so.updated_at,
+			   so.quote_expiry_date, so.source_quote_id, COALESCE(so.hold_reason, ''),
+			   so.hold_released_at, so.hold_released_by, COALESCE(so.hold_release_reason, ''),
+			   so.credit_hold, COALESCE(so.credit_hold_reason, ''), so.credit_held_at,
+			   so.credit_released_at, so.credit_released_by, COALESCE(so.credit_release_reason, ''),
+			   so.backorder_of_id,
 			   c.name as customer_name, c.customer_code,
 			   COALESCE(cst.name, '') as ship_to_name,
 			   COALESCE(cst.address_line1 || ', ' || cst.city, '') as ship_to_address,
@@ -150,26 +281,31 @@ func (s *salesOrderServiceImpl) getSalesOrder(ctx context.Context, whereClause s
 		LEFT JOIN customer_ship_to cst ON so.ship_to_id = cst.id
 		LEFT JOIN employees e ON so.sales_rep_id = e.id
 		LEFT JOIN routes rt ON so.route_id = rt.id
-		WHERE %s`, whereClause)
+		WHERE %s AND so.company_id = $2`, whereClause)
 
 	var order models.SalesOrderWithDetails
 	var reqShipDate, actShipDate *time.Time
 	var notes, poNumber *string
 
-	err := s.db.QueryRow(ctx, query, arg).Scan(
+	err := s.db.QueryRow(ctx, query, arg, tenant.Company(ctx)).Scan(
 		&order.Order.ID, &order.Order.OrderNumber, &order.Order.CustomerID, &order.Order.ShipToID,
 		&order.Order.OrderType, &order.Order.OrderDate, &reqShipDate, &actShipDate,
 		&order.Order.WarehouseID, &order.Order.RouteID, &order.Order.Status,
-		&order.Order.Subtotal, &order.Order.TaxAmount, &order.Order.FreightAmount,
+		&order.Order.Subtotal, &order.Order.TaxAmount, &order.Order.FreightAmount, &order.Order.FreightOverridden,
 		&order.Order.DiscountAmount, &order.Order.TotalAmount, &notes, &poNumber,
 		&order.Order.SalesRepID, &order.Order.CreatedBy, &order.Order.CreatedAt, &order.Order.UpdatedAt,
+		&order.Order.QuoteExpiryDate, &order.Order.SourceQuoteID, &order.Order.HoldReason,
+		&order.Order.HoldReleasedAt, &order.Order.HoldReleasedBy, &order.Order.HoldReleaseReason,
+		&order.Order.CreditHold, &order.Order.CreditHoldReason, &order.Order.CreditHeldAt,
+		&order.Order.CreditReleasedAt, &order.Order.CreditReleasedBy, &order.Order.CreditReleaseReason,
+		&order.Order.BackorderOfID,
 		&order.CustomerName, &order.CustomerCode, &order.ShipToName, &order.ShipToAddress,
 		&order.WarehouseName, &order.SalesRepName, &order.RouteName,
 	)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
-			return nil, fmt.Errorf("sales order not found")
+			return nil, ErrOrderNotFound
 		}
 		return nil, fmt.Errorf("failed to get sales order: %w", err)
 	}
@@ -184,8 +320,10 @@ func (s *salesOrderServiceImpl) getSalesOrder(ctx context.Context, whereClause s
 	// Get lines
 	linesQuery := `
 		SELECT id, order_id, line_number, product_id, description, quantity_ordered,
-			   quantity_shipped, unit_of_measure, unit_price, discount_percent, line_total,
-			   lot_number, expiry_date, catch_weight, cost
+			   quantity_shipped, quantity_allocated, quantity_picked, quantity_backordered, quantity_invoiced,
+			   unit_of_measure, unit_price, discount_percent, line_total,
+			   lot_number, expiry_date, catch_weight, COALESCE(cost, 0),
+			   COALESCE(customer_item_code, ''), COALESCE(customer_uom, '')
 		FROM sales_order_lines
 		WHERE order_id = $1
 		ORDER BY line_number`
@@ -200,9 +338,11 @@ func (s *salesOrderServiceImpl) getSalesOrder(ctx context.Context, whereClause s
 
 		err := rows.Scan(
 			&line.ID, &line.OrderID, &line.LineNumber, &line.ProductID, &desc,
-			&line.QuantityOrdered, &line.QuantityShipped, &line.UnitOfMeasure,
+			&line.QuantityOrdered, &line.QuantityShipped, &line.QuantityAllocated,
+			&line.QuantityPicked, &line.QuantityBackordered, &line.QuantityInvoiced, &line.UnitOfMeasure,
 			&line.UnitPrice, &line.DiscountPercent, &line.LineTotal,
 			&lotNum, &expDate, &line.CatchWeight, &line.Cost,
+			&line.CustomerItemCode, &line.CustomerUOM,
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan order line: %w", err)
@@ -213,13 +353,50 @@ func (s *salesOrderServiceImpl) getSalesOrder(ctx context.Context, whereClause s
 		if lotNum != nil {
 			line.LotNumber = *lotNum
 		}
+		lineCost := line.Cost * line.QuantityOrdered
+		line.Margin = line.LineTotal - lineCost
+		line.MarginPercent = marginPercent(line.LineTotal, lineCost)
+		order.TotalCost += lineCost
+		order.Margin += line.Margin
 		order.Lines = append(order.Lines, line)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get order lines: %w", err)
+	}
+
+	order.AllowedActions = allowedActions(order.Order.OrderType, order.Order.Status)
+	order.MarginPercent = marginPercent(order.Margin+order.TotalCost, order.TotalCost)
 
 	return &order, nil
 }
 
 func (s *salesOrderServiceImpl) Update(ctx context.Context, id int, req *models.UpdateSalesOrderRequest) error {
+	if req.RouteID != nil {
+		var orderType models.OrderType
+		err := s.db.QueryRow(ctx, `SELECT order_type FROM sales_orders WHERE id = $1 AND company_id = $2`,
+			id, tenant.Company(ctx)).Scan(&orderType)
+		if err == pgx.ErrNoRows {
+			return ErrOrderNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get sales order: %w", err)
+		}
+		if orderType == models.OrderTypePickUp {
+			return ErrPickUpNotRouted
+		}
+	}
+
+	// Status changes go through the same rules as the order actions
+	if req.Status != nil {
+		action, ok := statusActions[*req.Status]
+		if !ok {
+			return fmt.Errorf("%w: status cannot be set to %s directly", ErrIllegalTransition, *req.Status)
+		}
+		if err := s.transition(ctx, id, action, 0); err != nil {
+			return err
+		}
+	}
+
 	query := `UPDATE sales_orders SET updated_at = NOW()`
 	args := []interface{}{}
 	argNum := 1
@@ -250,14 +427,9 @@ func (s *salesOrderServiceImpl) Update(ctx context.Context, id int, req *models.
 		args = append(args, *req.PONumber)
 		argNum++
 	}
-	if req.Status != nil {
-		query += fmt.Sprintf(", status = $%d", argNum)
-		args = append(args, *req.Status)
-		argNum++
-	}
 
-	query += fmt.Sprintf(" WHERE id = $%d", argNum)
-	args = append(args, id)
+	query += fmt.Sprintf(" WHERE id = $%d AND company_id = $%d", argNum, argNum+1)
+	args = append(args, id, tenant.Company(ctx))
 
 	result, err := s.db.Exec(ctx, query, args...)
 	if err != nil {
@@ -265,14 +437,20 @@ func (s *salesOrderServiceImpl) Update(ctx context.Context, id int, req *models.
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("sales order not found")
+		return ErrOrderNotFound
+	}
+
+	// A new route can change the delivery fee
+	if req.RouteID != nil {
+		return s.recalculateTotals(ctx, id)
 	}
 
 	return nil
 }
 
 func (s *salesOrderServiceImpl) Delete(ctx context.Context, id int) error {
-	result, err := s.db.Exec(ctx, `DELETE FROM sales_orders WHERE id = $1 AND status = 'DRAFT'`, id)
+	result, err := s.db.Exec(ctx, `DELETE FROM sales_orders WHERE id = $1 AND company_id = $2 AND status = 'DRAFT'`,
+		id, tenant.Company(ctx))
 	if err != nil {
 		return fmt.Errorf("failed to delete sales order: %w", err)
 	}
@@ -282,17 +460,33 @@ func (s *salesOrderServiceImpl) Delete(ctx context.Context, id int) error {
 	return nil
 }
 
-func (s *salesOrderServiceImpl) List(ctx context.Context, filters *models.SalesOrderListFilters) ([]models.SalesOrderWithDetails, int64, error) {
-	if filters.Page < 1 {
-		filters.Page = 1
-	}
-	if filters.PageSize < 1 || filters.PageSize > 100 {
-		filters.PageSize = 20
-	}
+// salesOrderListSchema whitelists the fields clients may filter and sort on.
+var salesOrderListSchema = query.Schema{
+	Fields: map[string]query.Field{
+		"id":                  {Column: "so.id", Type: query.Int, Sortable: true},
+		"order_number":        {Column: "so.order_number", Type: query.String, Sortable: true},
+		"customer_id":         {Column: "so.customer_id", Type: query.Int},
+		"order_type":          {Column: "so.order_type::text", Type: query.String},
+		"order_date":          {Column: "so.order_date", Type: query.Date, Sortable: true},
+		"requested_ship_date": {Column: "so.requested_ship_date", Type: query.Date},
+		"warehouse_id":        {Column: "so.warehouse_id", Type: query.Int},
+		"route_id":            {Column: "so.route_id", Type: query.Int},
+		"status":              {Column: "so.status::text", Type: query.String},
+		"total_amount":        {Column: "so.total_amount", Type: query.Number, Sortable: true},
+		"po_number":           {Column: "so.po_number", Type: query.String},
+		"sales_rep_id":        {Column: "so.sales_rep_id", Type: query.Int},
+		"customer_name":       {Column: "c.name", Type: query.String, Sortable: true},
+		"customer_code":       {Column: "c.customer_code", Type: query.String},
+		"created_at":          {Column: "so.created_at", Type: query.Time, Sortable: true},
+	},
+	Default: []query.Sort{{Field: "order_date", Desc: true}},
+	Key:     "id",
+}
 
-	whereClause := "WHERE 1=1"
-	args := []interface{}{}
-	argNum := 1
+func (s *salesOrderServiceImpl) List(ctx context.Context, filters *models.SalesOrderListFilters) ([]models.SalesOrderWithDetails, int64, error) {
+	whereClause := "WHERE so.company_id = $1"
+	args := []interface{}{tenant.Company(ctx)}
+	argNum := 2
 
 	if filters.CustomerID != nil {
 		whereClause += fmt.Sprintf(" AND so.customer_id = $%d", argNum)
@@ -324,6 +518,11 @@ func (s *salesOrderServiceImpl) List(ctx context.Context, filters *models.SalesO
 		args = append(args, *filters.SalesRepID)
 		argNum++
 	}
+	if filters.CreditHold != nil {
+		whereClause += fmt.Sprintf(" AND so.credit_hold = $%d", argNum)
+		args = append(args, *filters.CreditHold)
+		argNum++
+	}
 	if filters.DateFrom != "" {
 		whereClause += fmt.Sprintf(" AND so.order_date >= $%d", argNum)
 		args = append(args, filters.DateFrom)
@@ -334,44 +533,72 @@ func (s *salesOrderServiceImpl) List(ctx context.Context, filters *models.SalesO
 		args = append(args, filters.DateTo)
 		argNum++
 	}
+	if filters.CustomerItemCode != "" {
+		whereClause += fmt.Sprintf(` AND EXISTS (
+			SELECT 1 FROM sales_order_lines sol
+			WHERE sol.order_id = so.id AND UPPER(sol.customer_item_code) = UPPER($%d))`, argNum)
+		args = append(args, filters.CustomerItemCode)
+		argNum++
+	}
+
+	params := query.Default(filters.Query, filters.Page, filters.PageSize)
+	q, err := query.Build(salesOrderListSchema, params, argNum)
+	if err != nil {
+		return nil, 0, err
+	}
+	whereClause += q.Where
+	args = append(args, q.Args...)
 
-	// Count
-	countQuery := fmt.Sprintf(`SELECT COUNT(*) FROM sales_orders so %s`, whereClause)
+	// Count; keyset pages follow next_cursor instead
 	var total int64
-	s.db.QueryRow(ctx, countQuery, args...).Scan(&total)
+	if !params.Keyset() {
+		// Filters and the schema can refer to the joined customer columns
+		countQuery := fmt.Sprintf(`
+			SELECT COUNT(*)
+			FROM sales_orders so
+			JOIN customers c ON so.customer_id = c.id
+			%s`, whereClause)
+		if err := s.db.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+			return nil, 0, fmt.Errorf("failed to count sales orders: %w", err)
+		}
+	}
 
 	// Query
-	offset := (filters.Page - 1) * filters.PageSize
-	query := fmt.Sprintf(`
+	listQuery := fmt.Sprintf(`
 		SELECT so.id, so.order_number, so.customer_id, so.ship_to_id, so.order_type,
 			   so.order_date, so.requested_ship_date, so.actual_ship_date, so.warehouse_id,
-			   so.route_id, so.status, so.subtotal, so.tax_amount, so.freight_amount,
+			   so.route_id, so.status, so.subtotal, so.tax_amount, so.freight_amount, so.freight_overridden,
 			   so.discount_amount, so.total_amount, so.notes, so.po_number, so.sales_rep_id,
 			   so.created_by, so.created_at, so.updated_at,
+			   so.quote_expiry_date, so.source_quote_id, COALESCE(so.hold_reason, ''),
+			   so.hold_released_at, so.hold_released_by, COALESCE(so.hold_release_reason, ''),
+			   so.credit_hold, COALESCE(so.credit_hold_reason, ''), so.credit_held_at,
+			   so.credit_released_at, so.credit_released_by, COALESCE(so.credit_release_reason, ''),
+			   so.backorder_of_id,
 			   c.name as customer_name, c.customer_code,
 			   COALESCE(cst.name, '') as ship_to_name,
 			   COALESCE(cst.address_line1 || ', ' || cst.city, '') as ship_to_address,
 			   w.name as warehouse_name,
 			   COALESCE(e.first_name || ' ' || e.last_name, '') as sales_rep_name,
-			   COALESCE(rt.name, '') as route_name
+			   COALESCE(rt.name, '') as route_name, %s
 		FROM sales_orders so
 		JOIN customers c ON so.customer_id = c.id
 		JOIN warehouses w ON so.warehouse_id = w.id
 		LEFT JOIN customer_ship_to cst ON so.ship_to_id = cst.id
 		LEFT JOIN employees e ON so.sales_rep_id = e.id
 		LEFT JOIN routes rt ON so.route_id = rt.id
+		%s%s
 		%s
-		ORDER BY so.order_date DESC, so.id DESC
-		LIMIT $%d OFFSET $%d`, whereClause, argNum, argNum+1)
-
-	args = append(args, filters.PageSize, offset)
+		%s`, q.Cursor, whereClause, q.Keyset, q.OrderBy, q.Limit)
 
-	rows := s.db.Query(ctx, query, args...)
+	rows := s.db.Query(ctx, listQuery, append(args, q.PageArgs...)...)
 	defer rows.Close()
 
 	var orders []models.SalesOrderWithDetails
+	var cursors []string
 	for rows.Next() {
 		var order models.SalesOrderWithDetails
+		var cursor string
 		var reqShipDate, actShipDate *time.Time
 		var notes, poNumber *string
 
@@ -379,11 +606,16 @@ func (s *salesOrderServiceImpl) List(ctx context.Context, filters *models.SalesO
 			&order.Order.ID, &order.Order.OrderNumber, &order.Order.CustomerID, &order.Order.ShipToID,
 			&order.Order.OrderType, &order.Order.OrderDate, &reqShipDate, &actShipDate,
 			&order.Order.WarehouseID, &order.Order.RouteID, &order.Order.Status,
-			&order.Order.Subtotal, &order.Order.TaxAmount, &order.Order.FreightAmount,
+			&order.Order.Subtotal, &order.Order.TaxAmount, &order.Order.FreightAmount, &order.Order.FreightOverridden,
 			&order.Order.DiscountAmount, &order.Order.TotalAmount, &notes, &poNumber,
 			&order.Order.SalesRepID, &order.Order.CreatedBy, &order.Order.CreatedAt, &order.Order.UpdatedAt,
+			&order.Order.QuoteExpiryDate, &order.Order.SourceQuoteID, &order.Order.HoldReason,
+			&order.Order.HoldReleasedAt, &order.Order.HoldReleasedBy, &order.Order.HoldReleaseReason,
+			&order.Order.CreditHold, &order.Order.CreditHoldReason, &order.Order.CreditHeldAt,
+			&order.Order.CreditReleasedAt, &order.Order.CreditReleasedBy, &order.Order.CreditReleaseReason,
+			&order.Order.BackorderOfID,
 			&order.CustomerName, &order.CustomerCode, &order.ShipToName, &order.ShipToAddress,
-			&order.WarehouseName, &order.SalesRepName, &order.RouteName,
+			&order.WarehouseName, &order.SalesRepName, &order.RouteName, &cursor,
 		)
 		if err != nil {
 			return nil, 0, fmt.Errorf("failed to scan sales order: %w", err)
@@ -395,130 +627,201 @@ func (s *salesOrderServiceImpl) List(ctx context.Context, filters *models.SalesO
 			order.Order.PONumber = *poNumber
 		}
 		orders = append(orders, order)
+		cursors = append(cursors, cursor)
 	}
-
-	return orders, total, nil
-}
-
-func (s *salesOrderServiceImpl) Confirm(ctx context.Context, id int) error {
-	result, err := s.db.Exec(ctx,
-		`UPDATE sales_orders SET status = 'CONFIRMED', updated_at = NOW() WHERE id = $1 AND status = 'DRAFT'`,
-		id,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to confirm sales order: %w", err)
-	}
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("sales order not found or not in DRAFT status")
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list sales orders: %w", err)
 	}
-	return nil
-}
 
-func (s *salesOrderServiceImpl) Cancel(ctx context.Context, id int) error {
-	result, err := s.db.Exec(ctx,
-		`UPDATE sales_orders SET status = 'CANCELLED', updated_at = NOW() WHERE id = $1 AND status IN ('DRAFT', 'CONFIRMED')`,
-		id,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to cancel sales order: %w", err)
-	}
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("sales order not found or cannot be cancelled")
-	}
-	return nil
-}
-
-func (s *salesOrderServiceImpl) Ship(ctx context.Context, id int) error {
-	result, err := s.db.Exec(ctx,
-		`UPDATE sales_orders SET status = 'SHIPPED', actual_ship_date = CURRENT_DATE, updated_at = NOW() 
-		 WHERE id = $1 AND status = 'CONFIRMED'`,
-		id,
-	)
-	if err != nil {
-		return fmt.Errorf("failed to ship sales order: %w", err)
-	}
-	if result.RowsAffected() == 0 {
-		return fmt.Errorf("sales order not found or not in CONFIRMED status")
-	}
-	return nil
+	return orders[:params.Trim(cursors)], total, nil
 }
 
 // ============================================
 // Sales Order Lines
 // ============================================
 
-func (s *salesOrderServiceImpl) AddLine(ctx context.Context, orderID int, req *models.CreateSalesOrderLineRequest) (int, error) {
-	// Get next line number
-	var maxLine int
-	s.db.QueryRow(ctx, `SELECT COALESCE(MAX(line_number), 0) FROM sales_order_lines WHERE order_id = $1`, orderID).Scan(&maxLine)
-
-	// Get customer ID for pricing
-	var customerID int
-	s.db.QueryRow(ctx, `SELECT customer_id FROM sales_orders WHERE id = $1`, orderID).Scan(&customerID)
+// Line changes on a confirmed order release the line's stock and allocate
+// it again, in the same transaction as the change.
 
-	// Get product price if not provided
-	unitPrice := req.UnitPrice
-	if unitPrice == 0 {
-		unitPrice = s.getProductPrice(ctx, customerID, req.ProductID)
+func (s *salesOrderServiceImpl) AddLine(ctx context.Context, orderID int, req *models.CreateSalesOrderLineRequest, userID int) (*models.SalesOrderLineResult, error) {
+	if req.CustomerItemCode != "" {
+		_, customerID, err := s.orderCustomer(ctx, orderID, 0)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.resolveCustomerItems(ctx, customerID, &orderID, req); err != nil {
+			return nil, err
+		}
 	}
 
-	lineTotal := req.Quantity * unitPrice * (1 - req.DiscountPercent/100)
+	var result *models.SalesOrderLineResult
+	err := s.inTx(ctx, func(tx *salesOrderServiceImpl) error {
+		o, err := tx.loadState(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		if err := o.linesEditable(); err != nil {
+			return err
+		}
 
-	query := `
-		INSERT INTO sales_order_lines (
-			order_id, line_number, product_id, description, quantity_ordered,
-			unit_of_measure, unit_price, discount_percent, line_total, lot_number
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-		RETURNING id`
+		// Get next line number
+		var maxLine int
+		tx.db.QueryRow(ctx, `SELECT COALESCE(MAX(line_number), 0) FROM sales_order_lines WHERE order_id = $1`, orderID).Scan(&maxLine)
 
-	var id int
-	err := s.db.QueryRow(ctx, query,
-		orderID, maxLine+1, req.ProductID, req.Notes, req.Quantity,
-		req.UnitOfMeasure, unitPrice, req.DiscountPercent, lineTotal, req.LotNumber,
-	).Scan(&id)
+		result, err = tx.insertLine(ctx, o, maxLine+1, req, userID)
+		if err != nil {
+			return err
+		}
 
+		if err := tx.recalculateTotals(ctx, orderID); err != nil {
+			return err
+		}
+		return tx.reallocate(ctx, o)
+	})
 	if err != nil {
-		return 0, fmt.Errorf("failed to add order line: %w", err)
+		return nil, err
 	}
-
-	s.recalculateTotals(ctx, orderID)
-	return id, nil
+	return result, nil
 }
 
-func (s *salesOrderServiceImpl) UpdateLine(ctx context.Context, lineID int, req *models.CreateSalesOrderLineRequest) error {
-	lineTotal := req.Quantity * req.UnitPrice * (1 - req.DiscountPercent/100)
+func (s *salesOrderServiceImpl) UpdateLine(ctx context.Context, lineID int, req *models.CreateSalesOrderLineRequest, userID int) (*models.SalesOrderLineResult, error) {
+	if req.CustomerItemCode != "" {
+		orderID, customerID, err := s.orderCustomer(ctx, 0, lineID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.resolveCustomerItems(ctx, customerID, &orderID, req); err != nil {
+			return nil, err
+		}
+	}
 
-	query := `
-		UPDATE sales_order_lines SET
-			product_id = $1, description = $2, quantity_ordered = $3,
-			unit_of_measure = $4, unit_price = $5, discount_percent = $6, 
-			line_total = $7, lot_number = $8
-		WHERE id = $9
-		RETURNING order_id`
+	var result *models.SalesOrderLineResult
+	err := s.inTx(ctx, func(tx *salesOrderServiceImpl) error {
+		o, err := tx.lineOrder(ctx, lineID)
+		if err != nil {
+			return err
+		}
 
-	var orderID int
-	err := s.db.QueryRow(ctx, query,
-		req.ProductID, req.Notes, req.Quantity,
-		req.UnitOfMeasure, req.UnitPrice, req.DiscountPercent,
-		lineTotal, req.LotNumber, lineID,
-	).Scan(&orderID)
+		shipped, err := tx.lineShipped(ctx, lineID)
+		if err != nil {
+			return err
+		}
+		if req.Quantity < shipped {
+			return fmt.Errorf("%w: %.2f of the line has already shipped", ErrIllegalTransition, shipped)
+		}
+
+		l, err := tx.guardLine(ctx, o, req)
+		if err != nil {
+			return err
+		}
+		if err := tx.fillCustomerItemCode(ctx, o.customerID, req); err != nil {
+			return err
+		}
+
+		if err := tx.releaseAllocations(ctx, "a.order_line_id = $1", lineID); err != nil {
+			return err
+		}
+
+		query := `
+			UPDATE sales_order_lines SET
+				product_id = $1, description = $2, quantity_ordered = $3,
+				unit_of_measure = $4, unit_price = $5, discount_percent = $6,
+				line_total = $7, lot_number = $8, cost = $9,
+				quantity_backordered = LEAST(quantity_backordered, GREATEST($3 - quantity_shipped, 0)),
+				customer_item_code = NULLIF($11, ''), customer_uom = NULLIF($12, '')
+			WHERE id = $10`
+
+		_, err = tx.db.Exec(ctx, query,
+			req.ProductID, req.Notes, req.Quantity,
+			req.UnitOfMeasure, l.unitPrice, req.DiscountPercent,
+			l.lineTotal, req.LotNumber, l.check.UnitCost, lineID,
+			req.CustomerItemCode, req.CustomerUOM,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to update order line: %w", err)
+		}
+
+		if err := tx.recordBelowCost(ctx, o, lineID, l, userID); err != nil {
+			return err
+		}
+		result, err = tx.lineResult(ctx, o.id, lineID, l)
+		if err != nil {
+			return err
+		}
 
+		if err := tx.recalculateTotals(ctx, o.id); err != nil {
+			return err
+		}
+		return tx.reallocate(ctx, o)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to update order line: %w", err)
+		return nil, err
 	}
+	return result, nil
+}
 
-	s.recalculateTotals(ctx, orderID)
-	return nil
+// DeleteLine removes a line from the order. A line dropped because the
+// warehouse cannot supply it gives lostReason, and is recorded as a lost
+// sale before it goes.
+func (s *salesOrderServiceImpl) DeleteLine(ctx context.Context, lineID int, lostReason models.LostSaleReason) error {
+	return s.inTx(ctx, func(tx *salesOrderServiceImpl) error {
+		o, err := tx.lineOrder(ctx, lineID)
+		if err != nil {
+			return err
+		}
+		shipped, err := tx.lineShipped(ctx, lineID)
+		if err != nil {
+			return err
+		}
+		if shipped > 0 {
+			return fmt.Errorf("%w: %.2f of the line has already shipped", ErrIllegalTransition, shipped)
+		}
+		if lostReason != "" {
+			if err := tx.recordLostLine(ctx, o, lineID, lostReason, "Line deleted"); err != nil {
+				return err
+			}
+		}
+		if err := tx.releaseAllocations(ctx, "a.order_line_id = $1", lineID); err != nil {
+			return err
+		}
+		// The line is not sold after all, so it leaves the exceptions report
+		if _, err := tx.db.Exec(ctx, `DELETE FROM margin_exceptions WHERE order_line_id = $1`, lineID); err != nil {
+			return fmt.Errorf("failed to clear margin exception: %w", err)
+		}
+		if _, err := tx.db.Exec(ctx, `DELETE FROM sales_order_lines WHERE id = $1`, lineID); err != nil {
+			return fmt.Errorf("failed to delete order line: %w", err)
+		}
+		return tx.recalculateTotals(ctx, o.id)
+	})
 }
 
-func (s *salesOrderServiceImpl) DeleteLine(ctx context.Context, lineID int) error {
+// lineOrder loads and locks the order a line belongs to.
+func (s *salesOrderServiceImpl) lineOrder(ctx context.Context, lineID int) (*orderState, error) {
 	var orderID int
-	err := s.db.QueryRow(ctx, `DELETE FROM sales_order_lines WHERE id = $1 RETURNING order_id`, lineID).Scan(&orderID)
+	err := s.db.QueryRow(ctx, `SELECT order_id FROM sales_order_lines WHERE id = $1`, lineID).Scan(&orderID)
+	if err == pgx.ErrNoRows {
+		return nil, ErrLineNotFound
+	}
 	if err != nil {
-		return fmt.Errorf("failed to delete order line: %w", err)
+		return nil, fmt.Errorf("failed to get order line: %w", err)
 	}
-	s.recalculateTotals(ctx, orderID)
-	return nil
+
+	o, err := s.loadState(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := o.linesEditable(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// reallocate covers changed lines of a confirmed order.
+func (s *salesOrderServiceImpl) reallocate(ctx context.Context, o *orderState) error {
+	if o.status != models.OrderStatusConfirmed || !allocatesStock(o.orderType) {
+		return nil
+	}
+	return s.allocateOrder(ctx, o)
 }
 
 // ============================================
@@ -529,11 +832,11 @@ func (s *salesOrderServiceImpl) GetOrderGuide(ctx context.Context, customerID in
 	query := `
 		SELECT p.id, p.sku, p.name,
 			   COALESCE(cog.default_quantity, 0) as default_qty,
-			   COALESCE(cog.last_ordered_qty, 0) as last_ordered_qty,
-			   COALESCE(cog.avg_weekly_qty, 0) as avg_weekly_qty,
-			   COALESCE(i.quantity_on_hand, 0) as on_hand,
-			   COALESCE(i.quantity_allocated, 0) as allocated,
-			   COALESCE(i.quantity_available, 0) as available,
+			   COALESCE(cog.last_ordered_quantity, 0) as last_ordered_qty,
+			   COALESCE(cog.avg_weekly_quantity, 0) as avg_weekly_qty,
+			   COALESCE(i.on_hand, 0) as on_hand,
+			   COALESCE(i.allocated, 0) as allocated,
+			   GREATEST(COALESCE(i.available, 0) - ` + inventoryService.ReservedForOthers("p.id", "$2", "$1", "0", "0") + `, 0) as available,
 			   COALESCE(cp.price, p.base_price, 0) as unit_price,
 			   COALESCE(pu.abbreviation, 'EA') as unit_of_measure,
 			   COALESCE(cog.is_push_item, false) as is_push_item
@@ -542,7 +845,12 @@ func (s *salesOrderServiceImpl) GetOrderGuide(ctx context.Context, customerID in
 		LEFT JOIN product_units pu ON p.default_unit_id = pu.id
 		LEFT JOIN customer_pricing cp ON cp.customer_id = $1 AND cp.product_id = p.id
 			AND cp.effective_date <= CURRENT_DATE AND (cp.expiry_date IS NULL OR cp.expiry_date >= CURRENT_DATE)
-		LEFT JOIN inventory i ON i.product_id = p.id AND i.warehouse_id = $2
+		LEFT JOIN LATERAL (
+			SELECT SUM(quantity_on_hand) as on_hand, SUM(quantity_allocated) as allocated,
+				   SUM(quantity_available) as available
+			FROM inventory
+			WHERE product_id = p.id AND warehouse_id = $2
+		) i ON true
 		WHERE cog.customer_id = $1 AND p.is_active = true
 		ORDER BY p.name`
 
@@ -567,71 +875,6 @@ func (s *salesOrderServiceImpl) GetOrderGuide(ctx context.Context, customerID in
 	return entries, nil
 }
 
-// ============================================
-// Lost Sales
-// ============================================
-
-func (s *salesOrderServiceImpl) RecordLostSale(ctx context.Context, orderID, productID int, qtyRequested, qtyAvailable float64, reason string) error {
-	query := `
-		INSERT INTO lost_sales (order_id, product_id, quantity_requested, quantity_available, reason)
-		VALUES ($1, $2, $3, $4, $5)`
-
-	_, err := s.db.Exec(ctx, query, orderID, productID, qtyRequested, qtyAvailable, reason)
-	if err != nil {
-		return fmt.Errorf("failed to record lost sale: %w", err)
-	}
-	return nil
-}
-
-func (s *salesOrderServiceImpl) GetLostSales(ctx context.Context, orderID *int, limit int) ([]models.LostSale, error) {
-	if limit <= 0 || limit > 500 {
-		limit = 100
-	}
-
-	whereClause := "WHERE 1=1"
-	args := []interface{}{}
-	argNum := 1
-
-	if orderID != nil {
-		whereClause += fmt.Sprintf(" AND ls.order_id = $%d", argNum)
-		args = append(args, *orderID)
-		argNum++
-	}
-
-	query := fmt.Sprintf(`
-		SELECT ls.id, ls.order_id, ls.product_id, p.name, ls.quantity_requested,
-			   ls.quantity_available, ls.reason, ls.created_at
-		FROM lost_sales ls
-		JOIN products p ON ls.product_id = p.id
-		%s
-		ORDER BY ls.created_at DESC
-		LIMIT $%d`, whereClause, argNum)
-
-	args = append(args, limit)
-
-	rows := s.db.Query(ctx, query, args...)
-	defer rows.Close()
-
-	var lostSales []models.LostSale
-	for rows.Next() {
-		var ls models.LostSale
-		var reason *string
-		err := rows.Scan(
-			&ls.ID, &ls.OrderID, &ls.ProductID, &ls.ProductName,
-			&ls.QuantityRequested, &ls.QuantityAvailable, &reason, &ls.CreatedAt,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan lost sale: %w", err)
-		}
-		if reason != nil {
-			ls.Reason = *reason
-		}
-		lostSales = append(lostSales, ls)
-	}
-
-	return lostSales, nil
-}
-
 // ============================================
 // Helper Functions
 // ============================================
@@ -662,11 +905,25 @@ func (s *salesOrderServiceImpl) getProductPrice(ctx context.Context, customerID,
 	return price
 }
 
-func (s *salesOrderServiceImpl) recalculateTotals(ctx context.Context, orderID int) {
-	s.db.Exec(ctx, `
+// recalculateTotals totals the lines, applies the freight rules to the new
+// subtotal and weight, then totals the order.
+func (s *salesOrderServiceImpl) recalculateTotals(ctx context.Context, orderID int) error {
+	_, err := s.db.Exec(ctx, `
 		UPDATE sales_orders SET
 			subtotal = (SELECT COALESCE(SUM(line_total), 0) FROM sales_order_lines WHERE order_id = $1),
-			total_amount = subtotal + tax_amount + freight_amount - discount_amount,
 			updated_at = NOW()
 		WHERE id = $1`, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to total order lines: %w", err)
+	}
+	if err := s.applyFreight(ctx, orderID); err != nil {
+		return err
+	}
+	_, err = s.db.Exec(ctx, `
+		UPDATE sales_orders SET total_amount = subtotal + tax_amount + freight_amount - discount_amount
+		WHERE id = $1`, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to total order: %w", err)
+	}
+	return nil
 }
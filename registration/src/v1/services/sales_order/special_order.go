@@ -0,0 +1,142 @@
+package sales_order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	inventoryService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/inventory"
+	"github.com/jackc/pgx/v5"
+)
+
+// ============================================
+// Special Orders
+// ============================================
+//
+// A special order buys an item for one order line. The line's open
+// quantity is backordered so the order confirms without the stock, and
+// the goods received for it are allocated to the line ahead of other
+// backorders.
+
+// HoldForSpecialOrder backorders what the line still needs so purchasing
+// can buy it. Call it in the transaction that raises the requisition.
+func (s *salesOrderServiceImpl) HoldForSpecialOrder(ctx context.Context, lineID int) (*models.SpecialOrderLine, error) {
+	var orderID int
+	err := s.db.QueryRow(ctx, `SELECT order_id FROM sales_order_lines WHERE id = $1`, lineID).Scan(&orderID)
+	if err == pgx.ErrNoRows {
+		return nil, ErrLineNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get order line: %w", err)
+	}
+
+	var line *models.SpecialOrderLine
+	err = s.inTx(ctx, func(tx *salesOrderServiceImpl) error {
+		o, err := tx.loadState(ctx, orderID)
+		if err != nil {
+			return err
+		}
+		if err := o.linesEditable(); err != nil {
+			return err
+		}
+		if !allocatesStock(o.orderType) {
+			return fmt.Errorf("%w: %s orders take no stock", ErrIllegalTransition, o.orderType)
+		}
+
+		line = &models.SpecialOrderLine{
+			OrderID:      o.id,
+			OrderNumber:  o.orderNumber,
+			OrderLineID:  lineID,
+			WarehouseID:  o.warehouseID,
+			RequiredDate: o.requestedShipDate,
+		}
+		err = tx.db.QueryRow(ctx, `
+			SELECT product_id, COALESCE(unit_of_measure, ''),
+				   quantity_ordered - quantity_shipped - quantity_allocated - quantity_backordered
+			FROM sales_order_lines WHERE id = $1`,
+			lineID).Scan(&line.ProductID, &line.UnitOfMeasure, &line.Quantity)
+		if err != nil {
+			return fmt.Errorf("failed to get order line: %w", err)
+		}
+		if line.Quantity <= 0.0005 {
+			return ErrLineCovered
+		}
+
+		_, err = tx.db.Exec(ctx, `
+			UPDATE sales_order_lines SET quantity_backordered = quantity_backordered + $1 WHERE id = $2`,
+			line.Quantity, lineID)
+		if err != nil {
+			return fmt.Errorf("failed to backorder order line: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return line, nil
+}
+
+// ReceiveSpecialOrder allocates received goods to the line they were
+// bought for, up to what it has backordered. A draft order keeps them on
+// hand for confirmation to allocate. It returns the quantity taken.
+func (s *salesOrderServiceImpl) ReceiveSpecialOrder(ctx context.Context, lineID int, quantity float64) (float64, error) {
+	var orderID int
+	err := s.db.QueryRow(ctx, `SELECT order_id FROM sales_order_lines WHERE id = $1`, lineID).Scan(&orderID)
+	if err == pgx.ErrNoRows {
+		return 0, ErrLineNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get order line: %w", err)
+	}
+
+	var taken float64
+	err = s.inTx(ctx, func(tx *salesOrderServiceImpl) error {
+		o, err := tx.loadState(ctx, orderID)
+		if err != nil {
+			return err
+		}
+
+		// Read again under the order's lock
+		l := unallocatedLine{id: lineID}
+		var backordered float64
+		err = tx.db.QueryRow(ctx, `
+			SELECT product_id, COALESCE(lot_number, ''), quantity_backordered FROM sales_order_lines WHERE id = $1`,
+			lineID).Scan(&l.productID, &l.lotNumber, &backordered)
+		if err != nil {
+			return fmt.Errorf("failed to get order line: %w", err)
+		}
+		l.quantity = min(quantity, backordered)
+		if l.quantity <= 0 {
+			return nil
+		}
+
+		switch o.status {
+		case models.OrderStatusConfirmed:
+			err := tx.allocateLine(ctx, o, l)
+			if errors.Is(err, inventoryService.ErrInsufficientStock) {
+				// Received into another warehouse; it waits with the other backorders
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		case models.OrderStatusDraft:
+		default:
+			return nil
+		}
+
+		_, err = tx.db.Exec(ctx, `
+			UPDATE sales_order_lines SET quantity_backordered = GREATEST(quantity_backordered - $1, 0) WHERE id = $2`,
+			l.quantity, lineID)
+		if err != nil {
+			return fmt.Errorf("failed to release backorder: %w", err)
+		}
+		taken = l.quantity
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return taken, nil
+}
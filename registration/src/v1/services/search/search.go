@@ -0,0 +1,160 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+)
+
+// SearchService looks up products, customers, vendors and ship-to addresses
+// by partial name, code or contact details for type-ahead in order entry.
+type SearchService interface {
+	Search(ctx context.Context, req *models.SearchRequest) ([]models.SearchResult, error)
+}
+
+type searchServiceImpl struct {
+	db postgres.Connection
+}
+
+func New(db postgres.Connection) SearchService {
+	return &searchServiceImpl{db: db}
+}
+
+// searchQuery prepares the normalized text, LIKE patterns and full-text query
+// once for all result types. $1 is the raw text, $2 the prefix tsquery and
+// $5 the company whose customers and vendors may be returned.
+const searchQuery = `
+	WITH q AS (
+		SELECT n AS text,
+			   '%%' || e || '%%' AS contains,
+			   e || '%%' AS prefix,
+			   CASE WHEN $2 = '' THEN NULL
+					ELSE to_tsquery('simple', $2) || to_tsquery('english', $2) END AS tsq,
+			   $5::int AS company_id
+		FROM (SELECT n, replace(replace(replace(n, '\', '\\'), '%%', '\%%'), '_', '\_') AS e
+			  FROM (SELECT search_normalize($1) AS n) s) s
+	)
+	SELECT type, id, code, name, detail, customer_id, is_active, score
+	FROM (%s) results
+	ORDER BY score DESC, name
+	LIMIT $4`
+
+// Each branch scores a row by exact or leading code match first, then
+// full-text rank, word similarity and plain substring matches. The substring
+// test is what finds Lao and Thai text, which has no spaces between words.
+var searchBranches = map[models.SearchResultType]string{
+	models.SearchResultProduct: `
+		SELECT 'product' AS type, p.id, p.sku AS code, p.name,
+			   COALESCE(p.description, '') AS detail, NULL::int AS customer_id, p.is_active,
+			   (CASE WHEN lower(p.sku) = q.text OR lower(COALESCE(p.barcode, '')) = q.text
+						  OR lower(COALESCE(p.upc, '')) = q.text THEN 2
+					 WHEN lower(p.sku) LIKE q.prefix THEN 1 ELSE 0 END` + scoreTail("p") + `
+		FROM products p, q
+		WHERE ` + matchClause("p"),
+
+	models.SearchResultCustomer: `
+		SELECT 'customer' AS type, c.id, c.customer_code AS code, c.name,
+			   COALESCE(NULLIF(c.full_address, ''), NULLIF(c.mobile, ''), COALESCE(c.tel, '')) AS detail,
+			   NULL::int AS customer_id, c.is_active,
+			   (CASE WHEN lower(c.customer_code) = q.text THEN 2
+					 WHEN lower(c.customer_code) LIKE q.prefix THEN 1 ELSE 0 END` + scoreTail("c") + `
+		FROM customers c, q
+		WHERE c.company_id = q.company_id AND ` + matchClause("c"),
+
+	models.SearchResultVendor: `
+		SELECT 'vendor' AS type, v.id, v.vendor_code AS code, v.name,
+			   concat_ws(', ', NULLIF(v.city, ''), NULLIF(v.phone, '')) AS detail,
+			   NULL::int AS customer_id, v.is_active,
+			   (CASE WHEN lower(v.vendor_code) = q.text THEN 2
+					 WHEN lower(v.vendor_code) LIKE q.prefix THEN 1 ELSE 0 END` + scoreTail("v") + `
+		FROM vendors v, q
+		WHERE v.company_id = q.company_id AND ` + matchClause("v"),
+
+	models.SearchResultShipTo: `
+		SELECT 'ship_to' AS type, st.id, COALESCE(st.ship_to_code, '') AS code,
+			   COALESCE(NULLIF(st.name, ''), cu.name) AS name,
+			   concat_ws(', ', cu.name, NULLIF(st.address_line1, ''), NULLIF(st.city, '')) AS detail,
+			   st.customer_id, COALESCE(st.is_active, true) AND cu.is_active AS is_active,
+			   (CASE WHEN lower(COALESCE(st.ship_to_code, '')) = q.text THEN 2
+					 WHEN lower(COALESCE(st.ship_to_code, '')) LIKE q.prefix THEN 1 ELSE 0 END` + scoreTail("st") + `
+		FROM customer_ship_to st
+		JOIN customers cu ON cu.id = st.customer_id, q
+		WHERE cu.company_id = q.company_id AND ` + matchClause("st"),
+}
+
+func scoreTail(alias string) string {
+	return fmt.Sprintf(`
+				+ COALESCE(ts_rank_cd(%[1]s.search_vector, q.tsq, 32), 0)
+				+ word_similarity(q.text, %[1]s.search_text)
+				+ CASE WHEN %[1]s.search_text LIKE q.prefix THEN 0.5
+					   WHEN %[1]s.search_text LIKE q.contains THEN 0.25 ELSE 0 END)::float8 AS score`, alias)
+}
+
+// matchClause uses the full-text, trigram and LIKE operators so each test
+// can be answered from the GIN indexes on search_vector and search_text.
+func matchClause(alias string) string {
+	return fmt.Sprintf(`(%[1]s.search_vector @@ q.tsq OR %[1]s.search_text LIKE q.contains OR q.text <%% %[1]s.search_text)
+		  AND ($3 OR %[1]s.is_active)
+		ORDER BY score DESC
+		LIMIT $4`, alias)
+}
+
+func (s *searchServiceImpl) Search(ctx context.Context, req *models.SearchRequest) ([]models.SearchResult, error) {
+	var branches []string
+	for _, t := range req.Types {
+		if branch, ok := searchBranches[t]; ok {
+			branches = append(branches, "("+branch+")")
+		}
+	}
+	if len(branches) == 0 {
+		return []models.SearchResult{}, nil
+	}
+
+	sql := fmt.Sprintf(searchQuery, strings.Join(branches, "\n\t\tUNION ALL\n"))
+	rows := s.db.Query(ctx, sql, req.Query, prefixQuery(req.Query), req.IncludeInactive, req.Limit, tenant.Company(ctx))
+	defer rows.Close()
+
+	results := []models.SearchResult{}
+	for rows.Next() {
+		var r models.SearchResult
+		if err := rows.Scan(&r.Type, &r.ID, &r.Code, &r.Name, &r.Detail, &r.CustomerID, &r.IsActive, &r.Score); err != nil {
+			return nil, fmt.Errorf("scanning search result: %w", err)
+		}
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("searching: %w", err)
+	}
+
+	return results, nil
+}
+
+// prefixQuery turns the Latin words and numbers of the search text into a
+// tsquery matching every word as a prefix ("fresh sal" -> "fresh:* & sal:*").
+// Lao and Thai words are left to the substring match. Tokens only contain
+// letters and digits, so the result is always valid tsquery syntax.
+func prefixQuery(text string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsMark(r) && !unicode.IsDigit(r)
+	})
+
+	var terms []string
+	for _, w := range words {
+		latin := true
+		for _, r := range w {
+			if !unicode.In(r, unicode.Latin, unicode.Nd, unicode.Mn) {
+				latin = false
+				break
+			}
+		}
+		if latin {
+			terms = append(terms, w+":*")
+		}
+	}
+	return strings.Join(terms, " & ")
+}
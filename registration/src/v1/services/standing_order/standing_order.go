@@ -0,0 +1,873 @@
+package standing_order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/anas-dev-92/FoodHive/core/postgres"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/models"
+	pickingService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/picking"
+	salesOrderService "github.com/anas-dev-92/FoodHive/registration/src/v1/services/sales_order"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/tenant"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrStandingOrderNotFound = errors.New("standing order not found")
+	ErrSkipNotFound          = errors.New("skipped delivery not found")
+	ErrNotADeliveryDay       = errors.New("date is not a delivery day of the standing order")
+	ErrDeliveryInProgress    = errors.New("delivery is already being picked or has shipped")
+)
+
+// Messages recorded on deliveries that are not generated
+const (
+	msgSkippedByCustomer = "Skipped by customer"
+	msgPaused            = "Standing order is paused"
+	msgNoRouteRun        = "Route does not run on this day"
+	msgHoliday           = "Delivery holiday"
+)
+
+// ============================================
+// Service Interface
+// ============================================
+
+type StandingOrderService interface {
+	Create(ctx context.Context, req *models.CreateStandingOrderRequest, createdBy int) (int, error)
+	GetByID(ctx context.Context, id int) (*models.StandingOrderWithDetails, error)
+	Update(ctx context.Context, id int, req *models.UpdateStandingOrderRequest) error
+	Delete(ctx context.Context, id int) error
+	List(ctx context.Context, filters *models.StandingOrderFilters) ([]models.StandingOrderWithDetails, error)
+
+	// Customer changes
+	Pause(ctx context.Context, id int, req *models.PauseStandingOrderRequest) error
+	Resume(ctx context.Context, id int) error
+	SkipDelivery(ctx context.Context, id int, req *models.SkipDeliveryRequest, createdBy int) error
+	UnskipDelivery(ctx context.Context, id int, deliveryDate string) error
+	GetSchedule(ctx context.Context, id int, days int) ([]models.StandingOrderDelivery, error)
+
+	// Generation
+	Generate(ctx context.Context) (*models.GenerateStandingOrdersResult, error)
+	ProcessDue(ctx context.Context) (int, error)
+	RunScheduler(ctx context.Context, interval time.Duration)
+}
+
+// ============================================
+// Service Implementation
+// ============================================
+
+type standingOrderServiceImpl struct {
+	db postgres.Executor
+}
+
+func New(db postgres.Executor) StandingOrderService {
+	return &standingOrderServiceImpl{db: db}
+}
+
+// inTx runs fn in a transaction. When the service was built on a caller's
+// transaction fn joins it instead.
+func (s *standingOrderServiceImpl) inTx(ctx context.Context, fn func(tx *standingOrderServiceImpl) error) error {
+	conn, ok := s.db.(postgres.Connection)
+	if !ok {
+		return fn(s)
+	}
+
+	tx, err := conn.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(&standingOrderServiceImpl{db: tx}); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// ============================================
+// Standing Order CRUD
+// ============================================
+
+func (s *standingOrderServiceImpl) Create(ctx context.Context, req *models.CreateStandingOrderRequest, createdBy int) (int, error) {
+	var id int
+	err := s.inTx(ctx, func(tx *standingOrderServiceImpl) error {
+		err := tx.db.QueryRow(ctx, `
+			INSERT INTO standing_orders (
+				company_id, customer_id, ship_to_id, warehouse_id, name, delivery_days,
+				effective_from, effective_to, days_ahead, po_number, notes, created_by
+			) VALUES ($1, $2, $3, $4, $5, $6,
+				COALESCE(NULLIF($7, '')::date, CURRENT_DATE), NULLIF($8, '')::date, $9,
+				NULLIF($10, ''), NULLIF($11, ''), NULLIF($12, 0))
+			RETURNING id`,
+			tenant.Company(ctx), req.CustomerID, req.ShipToID, req.WarehouseID, req.Name, req.DeliveryDays,
+			req.EffectiveFrom, req.EffectiveTo, req.DaysAhead, req.PONumber, req.Notes, createdBy,
+		).Scan(&id)
+		if err != nil {
+			return fmt.Errorf("failed to create standing order: %w", err)
+		}
+		return tx.insertLines(ctx, id, req.Lines)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (s *standingOrderServiceImpl) insertLines(ctx context.Context, id int, lines []models.StandingOrderLineRequest) error {
+	for i, line := range lines {
+		_, err := s.db.Exec(ctx, `
+			INSERT INTO standing_order_lines (
+				standing_order_id, line_number, product_id, quantity, unit_of_measure, unit_price, notes
+			) VALUES ($1, $2, $3, $4,
+				COALESCE(NULLIF($5, ''), (SELECT base_unit FROM products WHERE id = $3)), $6, NULLIF($7, ''))`,
+			id, i+1, line.ProductID, line.Quantity, line.UnitOfMeasure, line.UnitPrice, line.Notes)
+		if err != nil {
+			return fmt.Errorf("failed to add standing order line: %w", err)
+		}
+	}
+	return nil
+}
+
+const standingOrderSelect = `
+	SELECT so.id, so.customer_id, so.ship_to_id, so.warehouse_id, so.name, so.delivery_days,
+		   so.effective_from, so.effective_to, so.days_ahead, so.paused_at, so.paused_until,
+		   COALESCE(so.pause_reason, ''), COALESCE(so.po_number, ''), COALESCE(so.notes, ''),
+		   so.is_active, so.created_by, so.created_at, so.updated_at,
+		   c.name, COALESCE(cst.name, ''), COALESCE(w.name, '')
+	FROM standing_orders so
+	JOIN customers c ON c.id = so.customer_id
+	LEFT JOIN customer_ship_to cst ON cst.id = so.ship_to_id
+	LEFT JOIN warehouses w ON w.id = so.warehouse_id`
+
+func scanStandingOrder(row pgx.Row, so *models.StandingOrderWithDetails) error {
+	return row.Scan(
+		&so.ID, &so.CustomerID, &so.ShipToID, &so.WarehouseID, &so.Name, &so.DeliveryDays,
+		&so.EffectiveFrom, &so.EffectiveTo, &so.DaysAhead, &so.PausedAt, &so.PausedUntil,
+		&so.PauseReason, &so.PONumber, &so.Notes,
+		&so.IsActive, &so.CreatedBy, &so.CreatedAt, &so.UpdatedAt,
+		&so.CustomerName, &so.ShipToName, &so.WarehouseName,
+	)
+}
+
+func (s *standingOrderServiceImpl) GetByID(ctx context.Context, id int) (*models.StandingOrderWithDetails, error) {
+	var so models.StandingOrderWithDetails
+	err := scanStandingOrder(s.db.QueryRow(ctx, standingOrderSelect+`
+		WHERE so.id = $1 AND so.company_id = $2`, id, tenant.Company(ctx)), &so)
+	if err == pgx.ErrNoRows {
+		return nil, ErrStandingOrderNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get standing order: %w", err)
+	}
+
+	if so.Lines, err = s.getLines(ctx, id); err != nil {
+		return nil, err
+	}
+	if so.Skips, err = s.getSkips(ctx, id); err != nil {
+		return nil, err
+	}
+	return &so, nil
+}
+
+func (s *standingOrderServiceImpl) getLines(ctx context.Context, id int) ([]models.StandingOrderLine, error) {
+	rows := s.db.Query(ctx, `
+		SELECT l.id, l.standing_order_id, l.line_number, l.product_id, p.sku, p.name,
+			   l.quantity, COALESCE(l.unit_of_measure, ''), l.unit_price, COALESCE(l.notes, '')
+		FROM standing_order_lines l
+		JOIN products p ON p.id = l.product_id
+		WHERE l.standing_order_id = $1
+		ORDER BY l.line_number`, id)
+	defer rows.Close()
+
+	lines := []models.StandingOrderLine{}
+	for rows.Next() {
+		var l models.StandingOrderLine
+		if err := rows.Scan(&l.ID, &l.StandingOrderID, &l.LineNumber, &l.ProductID, &l.ProductSKU, &l.ProductName,
+			&l.Quantity, &l.UnitOfMeasure, &l.UnitPrice, &l.Notes); err != nil {
+			return nil, fmt.Errorf("failed to scan standing order line: %w", err)
+		}
+		lines = append(lines, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get standing order lines: %w", err)
+	}
+	return lines, nil
+}
+
+func (s *standingOrderServiceImpl) getSkips(ctx context.Context, id int) ([]models.StandingOrderSkip, error) {
+	rows := s.db.Query(ctx, `
+		SELECT id, standing_order_id, delivery_date, COALESCE(reason, ''), created_by, created_at
+		FROM standing_order_skips
+		WHERE standing_order_id = $1 AND delivery_date >= CURRENT_DATE
+		ORDER BY delivery_date`, id)
+	defer rows.Close()
+
+	skips := []models.StandingOrderSkip{}
+	for rows.Next() {
+		var sk models.StandingOrderSkip
+		if err := rows.Scan(&sk.ID, &sk.StandingOrderID, &sk.DeliveryDate, &sk.Reason, &sk.CreatedBy, &sk.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan skipped delivery: %w", err)
+		}
+		skips = append(skips, sk)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get skipped deliveries: %w", err)
+	}
+	return skips, nil
+}
+
+// Update changes the standing order. Deliveries already generated keep
+// their orders; skipped and failed ones are looked at again.
+func (s *standingOrderServiceImpl) Update(ctx context.Context, id int, req *models.UpdateStandingOrderRequest) error {
+	return s.inTx(ctx, func(tx *standingOrderServiceImpl) error {
+		result, err := tx.db.Exec(ctx, `
+			UPDATE standing_orders SET
+				ship_to_id = COALESCE($1, ship_to_id),
+				warehouse_id = COALESCE($2, warehouse_id),
+				name = COALESCE($3, name),
+				delivery_days = COALESCE($4, delivery_days),
+				effective_from = COALESCE(NULLIF($5, '')::date, effective_from),
+				effective_to = CASE WHEN $6 THEN NULLIF($7, '')::date ELSE effective_to END,
+				days_ahead = COALESCE($8, days_ahead),
+				po_number = COALESCE($9, po_number),
+				notes = COALESCE($10, notes),
+				is_active = COALESCE($11, is_active),
+				updated_at = NOW()
+			WHERE id = $12 AND company_id = $13`,
+			req.ShipToID, req.WarehouseID, req.Name, req.DeliveryDays,
+			stringOrEmpty(req.EffectiveFrom), req.EffectiveTo != nil, stringOrEmpty(req.EffectiveTo),
+			req.DaysAhead, req.PONumber, req.Notes, req.IsActive, id, tenant.Company(ctx))
+		if err != nil {
+			return fmt.Errorf("failed to update standing order: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return ErrStandingOrderNotFound
+		}
+
+		if req.Lines != nil {
+			if _, err := tx.db.Exec(ctx, `DELETE FROM standing_order_lines WHERE standing_order_id = $1`, id); err != nil {
+				return fmt.Errorf("failed to replace standing order lines: %w", err)
+			}
+			if err := tx.insertLines(ctx, id, req.Lines); err != nil {
+				return err
+			}
+		}
+		return tx.reopenRuns(ctx, id, nil)
+	})
+}
+
+func stringOrEmpty(p *string) string {
+	if p == nil {
+		return ""
+	}
+	return *p
+}
+
+// reopenRuns forgets skipped and failed deliveries from today on, or on a
+// single date, so the generator evaluates them again.
+func (s *standingOrderServiceImpl) reopenRuns(ctx context.Context, id int, date *time.Time) error {
+	_, err := s.db.Exec(ctx, `
+		DELETE FROM standing_order_runs
+		WHERE standing_order_id = $1 AND status IN ('SKIPPED', 'FAILED')
+		  AND CASE WHEN $2::date IS NULL THEN delivery_date >= CURRENT_DATE ELSE delivery_date = $2 END`,
+		id, date)
+	if err != nil {
+		return fmt.Errorf("failed to reopen standing order deliveries: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the standing order. Sales orders it generated stay.
+func (s *standingOrderServiceImpl) Delete(ctx context.Context, id int) error {
+	result, err := s.db.Exec(ctx, `DELETE FROM standing_orders WHERE id = $1 AND company_id = $2`,
+		id, tenant.Company(ctx))
+	if err != nil {
+		return fmt.Errorf("failed to delete standing order: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrStandingOrderNotFound
+	}
+	return nil
+}
+
+func (s *standingOrderServiceImpl) List(ctx context.Context, filters *models.StandingOrderFilters) ([]models.StandingOrderWithDetails, error) {
+	whereClause := "WHERE so.company_id = $1"
+	args := []interface{}{tenant.Company(ctx)}
+	argNum := 2
+
+	if filters.CustomerID != nil {
+		whereClause += fmt.Sprintf(" AND so.customer_id = $%d", argNum)
+		args = append(args, *filters.CustomerID)
+		argNum++
+	}
+	if filters.ActiveOnly {
+		whereClause += " AND so.is_active = true AND (so.effective_to IS NULL OR so.effective_to >= CURRENT_DATE)"
+	}
+
+	rows := s.db.Query(ctx, standingOrderSelect+`
+		`+whereClause+`
+		ORDER BY c.name, so.name`, args...)
+	defer rows.Close()
+
+	orders := []models.StandingOrderWithDetails{}
+	for rows.Next() {
+		var so models.StandingOrderWithDetails
+		if err := scanStandingOrder(rows, &so); err != nil {
+			return nil, fmt.Errorf("failed to scan standing order: %w", err)
+		}
+		orders = append(orders, so)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list standing orders: %w", err)
+	}
+	return orders, nil
+}
+
+// ============================================
+// Pause and Skip
+// ============================================
+
+// Pause stops deliveries until req.Until, or until the standing order is
+// resumed. Orders already generated for the paused days are cancelled
+// unless the warehouse has started on them.
+func (s *standingOrderServiceImpl) Pause(ctx context.Context, id int, req *models.PauseStandingOrderRequest) error {
+	return s.inTx(ctx, func(tx *standingOrderServiceImpl) error {
+		result, err := tx.db.Exec(ctx, `
+			UPDATE standing_orders SET
+				paused_at = NOW(), paused_until = NULLIF($1, '')::date,
+				pause_reason = NULLIF($2, ''), updated_at = NOW()
+			WHERE id = $3 AND company_id = $4`,
+			req.Until, req.Reason, id, tenant.Company(ctx))
+		if err != nil {
+			return fmt.Errorf("failed to pause standing order: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return ErrStandingOrderNotFound
+		}
+
+		rows := tx.db.Query(ctx, `
+			SELECT r.delivery_date
+			FROM standing_order_runs r
+			JOIN sales_orders o ON o.id = r.sales_order_id
+			WHERE r.standing_order_id = $1 AND r.status = 'GENERATED'
+			  AND r.delivery_date >= CURRENT_DATE
+			  AND (NULLIF($2, '')::date IS NULL OR r.delivery_date <= NULLIF($2, '')::date)
+			  AND o.status IN ('DRAFT', 'CONFIRMED')`, id, req.Until)
+		var dates []time.Time
+		for rows.Next() {
+			var d time.Time
+			if err := rows.Scan(&d); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan generated delivery: %w", err)
+			}
+			dates = append(dates, d)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to find generated deliveries: %w", err)
+		}
+
+		for _, d := range dates {
+			if err := tx.cancelGenerated(ctx, id, d, msgPaused); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Resume restarts deliveries from the next delivery day the generator has
+// not passed yet.
+func (s *standingOrderServiceImpl) Resume(ctx context.Context, id int) error {
+	return s.inTx(ctx, func(tx *standingOrderServiceImpl) error {
+		result, err := tx.db.Exec(ctx, `
+			UPDATE standing_orders SET
+				paused_at = NULL, paused_until = NULL, pause_reason = NULL, updated_at = NOW()
+			WHERE id = $1 AND company_id = $2`, id, tenant.Company(ctx))
+		if err != nil {
+			return fmt.Errorf("failed to resume standing order: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return ErrStandingOrderNotFound
+		}
+		return tx.reopenRuns(ctx, id, nil)
+	})
+}
+
+// SkipDelivery drops a single delivery. When its order was already
+// generated the order is cancelled, which fails once picking has begun.
+func (s *standingOrderServiceImpl) SkipDelivery(ctx context.Context, id int, req *models.SkipDeliveryRequest, createdBy int) error {
+	date, err := time.Parse("2006-01-02", req.DeliveryDate)
+	if err != nil {
+		return fmt.Errorf("invalid delivery date: %w", err)
+	}
+
+	so, err := s.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deliversOn(&so.StandingOrder, date) {
+		return ErrNotADeliveryDay
+	}
+
+	return s.inTx(ctx, func(tx *standingOrderServiceImpl) error {
+		_, err := tx.db.Exec(ctx, `
+			INSERT INTO standing_order_skips (standing_order_id, delivery_date, reason, created_by)
+			VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, 0))
+			ON CONFLICT (standing_order_id, delivery_date) DO UPDATE SET reason = EXCLUDED.reason`,
+			id, date, req.Reason, createdBy)
+		if err != nil {
+			return fmt.Errorf("failed to skip delivery: %w", err)
+		}
+		return tx.cancelGenerated(ctx, id, date, skippedMessage(req.Reason))
+	})
+}
+
+// cancelGenerated cancels the order generated for a delivery, if any, and
+// records the delivery as skipped.
+func (s *standingOrderServiceImpl) cancelGenerated(ctx context.Context, id int, date time.Time, message string) error {
+	var orderID *int
+	err := s.db.QueryRow(ctx, `
+		SELECT sales_order_id FROM standing_order_runs
+		WHERE standing_order_id = $1 AND delivery_date = $2 AND status = 'GENERATED'
+		FOR UPDATE`, id, date).Scan(&orderID)
+	if err == pgx.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get generated delivery: %w", err)
+	}
+
+	if orderID != nil {
+		err := salesOrderService.New(s.db).Cancel(ctx, *orderID, 0)
+		if errors.Is(err, salesOrderService.ErrIllegalTransition) {
+			var status string
+			if err := s.db.QueryRow(ctx, `SELECT status FROM sales_orders WHERE id = $1`, *orderID).Scan(&status); err != nil {
+				return fmt.Errorf("failed to get generated order: %w", err)
+			}
+			if status != string(models.OrderStatusCancelled) {
+				return ErrDeliveryInProgress
+			}
+		} else if err != nil {
+			return err
+		}
+	}
+
+	_, err = s.db.Exec(ctx, `
+		UPDATE standing_order_runs SET status = 'SKIPPED', message = $3
+		WHERE standing_order_id = $1 AND delivery_date = $2`, id, date, message)
+	if err != nil {
+		return fmt.Errorf("failed to skip generated delivery: %w", err)
+	}
+	return nil
+}
+
+// UnskipDelivery brings back a skipped delivery. An order cancelled for it
+// stays cancelled; the generator creates a new one.
+func (s *standingOrderServiceImpl) UnskipDelivery(ctx context.Context, id int, deliveryDate string) error {
+	date, err := time.Parse("2006-01-02", deliveryDate)
+	if err != nil {
+		return fmt.Errorf("invalid delivery date: %w", err)
+	}
+	if _, err := s.GetByID(ctx, id); err != nil {
+		return err
+	}
+
+	return s.inTx(ctx, func(tx *standingOrderServiceImpl) error {
+		result, err := tx.db.Exec(ctx, `
+			DELETE FROM standing_order_skips WHERE standing_order_id = $1 AND delivery_date = $2`, id, date)
+		if err != nil {
+			return fmt.Errorf("failed to unskip delivery: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return ErrSkipNotFound
+		}
+		return tx.reopenRuns(ctx, id, &date)
+	})
+}
+
+func skippedMessage(reason string) string {
+	if reason == "" {
+		return msgSkippedByCustomer
+	}
+	return msgSkippedByCustomer + ": " + reason
+}
+
+// ============================================
+// Schedule
+// ============================================
+
+// GetSchedule lists the standing order's deliveries over the next days:
+// what the generator did for each, or what it will do as things stand.
+func (s *standingOrderServiceImpl) GetSchedule(ctx context.Context, id int, days int) ([]models.StandingOrderDelivery, error) {
+	so, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	from := today()
+	to := from.AddDate(0, 0, days)
+
+	runs, err := s.runsBetween(ctx, id, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule := []models.StandingOrderDelivery{}
+	for _, date := range deliveryDates(&so.StandingOrder, from, to) {
+		if run, ok := runs[date.Format("2006-01-02")]; ok {
+			schedule = append(schedule, run)
+			continue
+		}
+
+		d := models.StandingOrderDelivery{
+			StandingOrderID: id,
+			DeliveryDate:    models.CustomDate(date),
+			Status:          models.StandingOrderScheduled,
+		}
+		reason, err := s.skipReason(ctx, &so.StandingOrder, date)
+		if err != nil {
+			return nil, err
+		}
+		if reason != "" {
+			d.Status, d.Message = models.StandingOrderSkipped, reason
+		}
+		schedule = append(schedule, d)
+	}
+	return schedule, nil
+}
+
+func (s *standingOrderServiceImpl) runsBetween(ctx context.Context, id int, from, to time.Time) (map[string]models.StandingOrderDelivery, error) {
+	rows := s.db.Query(ctx, `
+		SELECT delivery_date, status, sales_order_id, COALESCE(message, '')
+		FROM standing_order_runs
+		WHERE standing_order_id = $1 AND delivery_date BETWEEN $2 AND $3`, id, from, to)
+	defer rows.Close()
+
+	runs := make(map[string]models.StandingOrderDelivery)
+	for rows.Next() {
+		d := models.StandingOrderDelivery{StandingOrderID: id}
+		if err := rows.Scan(&d.DeliveryDate, &d.Status, &d.SalesOrderID, &d.Message); err != nil {
+			return nil, fmt.Errorf("failed to scan standing order delivery: %w", err)
+		}
+		runs[time.Time(d.DeliveryDate).Format("2006-01-02")] = d
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get standing order deliveries: %w", err)
+	}
+	return runs, nil
+}
+
+// deliversOn reports whether date is one of the standing order's delivery
+// weekdays within its effective dates.
+func deliversOn(so *models.StandingOrder, date time.Time) bool {
+	if date.Before(time.Time(so.EffectiveFrom)) {
+		return false
+	}
+	if !so.EffectiveTo.IsZero() && date.After(time.Time(so.EffectiveTo)) {
+		return false
+	}
+	for _, dow := range so.DeliveryDays {
+		if int(date.Weekday()) == dow {
+			return true
+		}
+	}
+	return false
+}
+
+// deliveryDates lists the standing order's delivery days from from to to,
+// both included.
+func deliveryDates(so *models.StandingOrder, from, to time.Time) []time.Time {
+	var dates []time.Time
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		if deliversOn(so, d) {
+			dates = append(dates, d)
+		}
+	}
+	return dates
+}
+
+// skipReason says why a delivery day gets no order, or "" when it does.
+// The customer's route calendar decides; customers without a route deliver
+// on any day but company holidays.
+func (s *standingOrderServiceImpl) skipReason(ctx context.Context, so *models.StandingOrder, date time.Time) (string, error) {
+	var skip *string
+	err := s.db.QueryRow(ctx, `
+		SELECT COALESCE(reason, '') FROM standing_order_skips
+		WHERE standing_order_id = $1 AND delivery_date = $2`, so.ID, date).Scan(&skip)
+	if err != nil && err != pgx.ErrNoRows {
+		return "", fmt.Errorf("failed to get skipped delivery: %w", err)
+	}
+	if skip != nil {
+		return skippedMessage(*skip), nil
+	}
+
+	if !so.PausedAt.IsZero() && (so.PausedUntil.IsZero() || !date.After(time.Time(so.PausedUntil))) {
+		return msgPaused, nil
+	}
+
+	var routeID *int
+	err = s.db.QueryRow(ctx, `
+		SELECT COALESCE(
+			(SELECT route_id FROM customer_ship_to WHERE id = $2::int AND customer_id = $1),
+			(SELECT default_route_id FROM customers WHERE id = $1))`,
+		so.CustomerID, so.ShipToID).Scan(&routeID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get customer route: %w", err)
+	}
+
+	if routeID != nil {
+		runs, err := pickingService.New(s.db).RouteRunsOn(ctx, *routeID, date)
+		if errors.Is(err, pickingService.ErrRouteNotFound) {
+			runs, err = true, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if !runs {
+			return msgNoRouteRun, nil
+		}
+		return "", nil
+	}
+
+	var holiday bool
+	err = s.db.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM delivery_holidays WHERE company_id = $1 AND holiday_date = $2)`,
+		tenant.Company(ctx), date).Scan(&holiday)
+	if err != nil {
+		return "", fmt.Errorf("failed to check delivery holidays: %w", err)
+	}
+	if holiday {
+		return msgHoliday, nil
+	}
+	return "", nil
+}
+
+// ============================================
+// Generation
+// ============================================
+
+// Generate creates the draft sales orders of the company's standing orders
+// for deliveries within each one's days ahead. A delivery is generated,
+// skipped or failed once; later runs leave it alone.
+func (s *standingOrderServiceImpl) Generate(ctx context.Context) (*models.GenerateStandingOrdersResult, error) {
+	rows := s.db.Query(ctx, `
+		SELECT so.id, COALESCE(so.days_ahead, c.standing_order_days_ahead)
+		FROM standing_orders so
+		JOIN companies c ON c.id = so.company_id
+		WHERE so.company_id = $1 AND so.is_active = true
+		  AND (so.effective_to IS NULL OR so.effective_to >= CURRENT_DATE)
+		ORDER BY so.id`, tenant.Company(ctx))
+	type due struct{ id, daysAhead int }
+	var orders []due
+	for rows.Next() {
+		var d due
+		if err := rows.Scan(&d.id, &d.daysAhead); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan standing order: %w", err)
+		}
+		orders = append(orders, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to find due standing orders: %w", err)
+	}
+
+	result := &models.GenerateStandingOrdersResult{Deliveries: []models.StandingOrderDelivery{}}
+	from := today()
+	for _, d := range orders {
+		so, err := s.GetByID(ctx, d.id)
+		if err != nil {
+			return result, err
+		}
+		for _, date := range deliveryDates(&so.StandingOrder, from, from.AddDate(0, 0, d.daysAhead)) {
+			delivery, err := s.generateDelivery(ctx, so, date)
+			if err != nil {
+				return result, err
+			}
+			if delivery == nil {
+				continue
+			}
+			switch delivery.Status {
+			case models.StandingOrderGenerated:
+				result.Generated++
+			case models.StandingOrderSkipped:
+				result.Skipped++
+			case models.StandingOrderFailed:
+				result.Failed++
+			}
+			result.Deliveries = append(result.Deliveries, *delivery)
+		}
+	}
+	return result, nil
+}
+
+// generateDelivery records what happens to one delivery and creates its
+// order in the same transaction, so another server running the generator
+// waits on the claim and then finds it taken. It returns nil when the
+// delivery was already handled. An order that cannot be created is
+// recorded as failed rather than stopping the run.
+func (s *standingOrderServiceImpl) generateDelivery(ctx context.Context, so *models.StandingOrderWithDetails, date time.Time) (*models.StandingOrderDelivery, error) {
+	reason, err := s.skipReason(ctx, &so.StandingOrder, date)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := &models.StandingOrderDelivery{
+		StandingOrderID: so.ID,
+		DeliveryDate:    models.CustomDate(date),
+		Status:          models.StandingOrderGenerated,
+	}
+	if reason != "" {
+		delivery.Status, delivery.Message = models.StandingOrderSkipped, reason
+	}
+
+	claimed := false
+	err = s.inTx(ctx, func(tx *standingOrderServiceImpl) error {
+		var runID int
+		err := tx.db.QueryRow(ctx, `
+			INSERT INTO standing_order_runs (standing_order_id, delivery_date, status, message)
+			VALUES ($1, $2, $3, NULLIF($4, ''))
+			ON CONFLICT (standing_order_id, delivery_date) DO NOTHING
+			RETURNING id`, so.ID, date, delivery.Status, delivery.Message).Scan(&runID)
+		if err == pgx.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to claim standing order delivery: %w", err)
+		}
+		claimed = true
+		if delivery.Status != models.StandingOrderGenerated {
+			return nil
+		}
+
+		orderID, err := salesOrderService.New(tx.db).Create(ctx, salesOrderRequest(so, date), createdBy(so))
+		if err != nil {
+			return err
+		}
+		delivery.SalesOrderID = &orderID
+		_, err = tx.db.Exec(ctx, `UPDATE standing_order_runs SET sales_order_id = $1 WHERE id = $2`, orderID, runID)
+		if err != nil {
+			return fmt.Errorf("failed to record generated order: %w", err)
+		}
+		return nil
+	})
+	if err != nil && delivery.Status == models.StandingOrderGenerated && claimed {
+		// The claim went with the rollback; record the failure in its place
+		delivery.Status, delivery.Message, delivery.SalesOrderID = models.StandingOrderFailed, err.Error(), nil
+		result, err := s.db.Exec(ctx, `
+			INSERT INTO standing_order_runs (standing_order_id, delivery_date, status, message)
+			VALUES ($1, $2, 'FAILED', $3)
+			ON CONFLICT (standing_order_id, delivery_date) DO NOTHING`, so.ID, date, delivery.Message)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record failed delivery: %w", err)
+		}
+		if result.RowsAffected() == 0 {
+			return nil, nil
+		}
+		return delivery, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !claimed {
+		return nil, nil
+	}
+	return delivery, nil
+}
+
+func salesOrderRequest(so *models.StandingOrderWithDetails, date time.Time) *models.CreateSalesOrderRequest {
+	req := &models.CreateSalesOrderRequest{
+		CustomerID:        so.CustomerID,
+		ShipToID:          so.ShipToID,
+		OrderType:         models.OrderTypeStandard,
+		RequestedShipDate: date.Format("2006-01-02"),
+		WarehouseID:       so.WarehouseID,
+		PONumber:          so.PONumber,
+		Notes:             so.Notes,
+	}
+	if req.Notes == "" {
+		req.Notes = "Standing order: " + so.Name
+	}
+	for _, l := range so.Lines {
+		line := models.CreateSalesOrderLineRequest{
+			ProductID:     l.ProductID,
+			Quantity:      l.Quantity,
+			UnitOfMeasure: l.UnitOfMeasure,
+			Notes:         l.Notes,
+		}
+		if l.UnitPrice != nil {
+			line.UnitPrice = *l.UnitPrice
+		}
+		req.Lines = append(req.Lines, line)
+	}
+	return req
+}
+
+func createdBy(so *models.StandingOrderWithDetails) int {
+	if so.CreatedBy == nil {
+		return 0
+	}
+	return *so.CreatedBy
+}
+
+// ProcessDue runs the generator for every company with standing orders and
+// returns how many orders it created. A company that fails is logged and
+// the others still run.
+func (s *standingOrderServiceImpl) ProcessDue(ctx context.Context) (int, error) {
+	rows := s.db.Query(ctx, `SELECT DISTINCT company_id FROM standing_orders WHERE is_active = true`)
+	var companies []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("scanning standing order company: %w", err)
+		}
+		companies = append(companies, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("finding standing order companies: %w", err)
+	}
+
+	generated := 0
+	for _, companyID := range companies {
+		// The scheduler has no signed-in user; generate in each company
+		result, err := s.Generate(tenant.WithCompany(ctx, companyID))
+		if result != nil {
+			generated += result.Generated
+		}
+		if err != nil {
+			log.Printf("⚠ Standing orders for company %d: %v", companyID, err)
+		}
+	}
+	return generated, nil
+}
+
+// RunScheduler generates due standing orders every interval until ctx is
+// cancelled.
+func (s *standingOrderServiceImpl) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if generated, err := s.ProcessDue(ctx); err != nil {
+			log.Printf("⚠ Standing order scheduler: %v", err)
+		} else if generated > 0 {
+			log.Printf("✓ Standing order scheduler generated %d orders", generated)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func today() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
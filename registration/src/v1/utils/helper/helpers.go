@@ -10,6 +10,9 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/i18n"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/utils/query"
 )
 
 // ============================================
@@ -162,8 +165,18 @@ func WriteJSON(w http.ResponseWriter, status int, data Envelope, headers http.He
 // Response Helpers
 // ============================================
 
+// ErrorResponse writes {"error": ..., "code": ...}. English messages found in
+// the catalog are translated to the request language and carry their stable
+// code; anything else is sent as is under a code derived from the status.
 func ErrorResponse(w http.ResponseWriter, r *http.Request, status int, message any) {
 	env := Envelope{"error": message}
+	if text, ok := message.(string); ok {
+		code, translated := i18n.Translate(i18n.Request(r), text)
+		if code == "" {
+			code = statusCode(status)
+		}
+		env = Envelope{"error": translated, "code": code}
+	}
 	err := WriteJSON(w, status, env, nil)
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -178,8 +191,24 @@ func SuccessResponse(w http.ResponseWriter, r *http.Request, status int, data an
 	}
 }
 
+// FailedValidationResponse sends the translated message per field under
+// "error" and the matching stable codes under "codes".
 func FailedValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]string) {
-	ErrorResponse(w, r, http.StatusUnprocessableEntity, errors)
+	lang := i18n.Request(r)
+	messages := make(map[string]string, len(errors))
+	codes := make(map[string]string, len(errors))
+	for field, message := range errors {
+		code, text := i18n.Translate(lang, message)
+		if code == "" {
+			code = "validation.invalid"
+		}
+		messages[field], codes[field] = text, code
+	}
+
+	env := Envelope{"error": messages, "code": "validation.failed", "codes": codes}
+	if err := WriteJSON(w, http.StatusUnprocessableEntity, env, nil); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
 }
 
 func BadRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
@@ -209,13 +238,26 @@ func ConflictResponse(w http.ResponseWriter, r *http.Request) {
 }
 
 func MethodNotAllowedResponse(w http.ResponseWriter, r *http.Request) {
-	ErrorResponse(w, r, http.StatusMethodNotAllowed, fmt.Sprintf("the %s method is not supported for this resource", r.Method))
+	env := Envelope{"error": i18n.T(i18n.Request(r), "http.method_not_allowed", r.Method), "code": "http.method_not_allowed"}
+	if err := WriteJSON(w, http.StatusMethodNotAllowed, env, nil); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
 }
 
 func RateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
 	ErrorResponse(w, r, http.StatusTooManyRequests, "rate limit exceeded")
 }
 
+// statusCode names the error code used for messages that have no catalog
+// entry, e.g. "http.bad_request" for 400.
+func statusCode(status int) string {
+	text := strings.ToLower(http.StatusText(status))
+	if text == "" {
+		return "http.error"
+	}
+	return "http." + strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text)
+}
+
 // CreatedResponse sends a 201 response with the created resource ID
 func CreatedResponse(w http.ResponseWriter, r *http.Request, id int, message string) {
 	env := Envelope{
@@ -224,3 +266,61 @@ func CreatedResponse(w http.ResponseWriter, r *http.Request, id int, message str
 	}
 	WriteJSON(w, http.StatusCreated, env, nil)
 }
+
+// FileResponse streams a generated file. It is shown inline unless the client
+// asks for a download with ?download=true.
+func FileResponse(w http.ResponseWriter, r *http.Request, fileName, contentType string, content []byte) {
+	disposition := "inline"
+	if r.URL.Query().Get("download") == "true" {
+		disposition = "attachment"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, fileName))
+	w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
+
+// ListResponse writes a list page in the shared envelope. Offset requests get
+// page numbers and totals; keyset requests get the next cursor instead of a
+// total, which would need a full count. Sparse fields are applied here.
+func ListResponse(w http.ResponseWriter, r *http.Request, items any, total int64, p *query.Params) {
+	data, err := query.Select(items, p.Fields)
+	if err != nil {
+		ServerErrorResponse(w, r, err)
+		return
+	}
+
+	pagination := Envelope{"has_more": p.HasMore}
+	if p.NextCursor != "" {
+		pagination["next_cursor"] = p.NextCursor
+	}
+	if p.Keyset() {
+		pagination["limit"] = p.Size()
+	} else {
+		totalPages := int(total) / p.PageSize
+		if int(total)%p.PageSize > 0 {
+			totalPages++
+		}
+		pagination["page"] = p.Page
+		pagination["page_size"] = p.PageSize
+		pagination["total_items"] = total
+		pagination["total_pages"] = totalPages
+	}
+
+	SuccessResponse(w, r, http.StatusOK, Envelope{
+		"data":       data,
+		"pagination": pagination,
+	})
+}
+
+// ListErrorResponse answers 400 for a malformed list query and 500 otherwise.
+func ListErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, query.ErrInvalid) {
+		BadRequestResponse(w, r, err)
+		return
+	}
+	ServerErrorResponse(w, r, err)
+}
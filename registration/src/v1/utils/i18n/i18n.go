@@ -0,0 +1,182 @@
+// Package i18n translates API messages and printed document labels into
+// English, Lao and Thai.
+//
+// Catalogs map a stable code such as "error.customer_not_found" to its text.
+// The codes never change, so clients can branch on them while users read
+// the translated text. The rest of the code keeps writing plain English;
+// messages are looked up by their English text when a response is written,
+// and anything not in the catalog is passed through untranslated.
+package i18n
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type Lang string
+
+const (
+	English Lang = "en"
+	Lao     Lang = "lo"
+	Thai    Lang = "th"
+)
+
+// Default is used when the client states no preference we support.
+const Default = English
+
+// Supported lists the languages with a catalog, in negotiation order.
+var Supported = []Lang{English, Lao, Thai}
+
+// Valid reports whether code names a supported language.
+func Valid(code string) bool {
+	for _, l := range Supported {
+		if string(l) == code {
+			return true
+		}
+	}
+	return false
+}
+
+//go:embed catalogs/*.json
+var catalogFS embed.FS
+
+var (
+	// catalogs holds code -> text per language.
+	catalogs = map[Lang]map[string]string{}
+	// codes maps English message text back to its code.
+	codes = map[string]string{}
+	// labels maps English document labels to their code.
+	labels = map[string]string{}
+)
+
+func init() {
+	for _, l := range Supported {
+		data, err := catalogFS.ReadFile("catalogs/" + string(l) + ".json")
+		if err != nil {
+			panic(fmt.Sprintf("i18n: missing catalog for %s: %v", l, err))
+		}
+		m := map[string]string{}
+		if err := json.Unmarshal(data, &m); err != nil {
+			panic(fmt.Sprintf("i18n: invalid catalog for %s: %v", l, err))
+		}
+		catalogs[l] = m
+	}
+
+	for code, text := range catalogs[English] {
+		if strings.HasPrefix(code, "label.") {
+			labels[text] = code
+		} else {
+			codes[text] = code
+		}
+	}
+}
+
+// T returns the text for code in lang, formatted with args. Missing
+// translations fall back to English, and unknown codes to the code itself.
+func T(lang Lang, code string, args ...any) string {
+	text, ok := catalogs[lang][code]
+	if !ok {
+		if text, ok = catalogs[English][code]; !ok {
+			text = code
+		}
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(text, args...)
+	}
+	return text
+}
+
+// Translate looks up an English API message and returns its code and the
+// text in lang. Wrapped errors ("customer not found: sql: no rows") are
+// matched on their leading message and keep the detail in English. Unknown
+// messages come back unchanged with an empty code.
+func Translate(lang Lang, message string) (code, text string) {
+	if code, ok := codes[message]; ok {
+		return code, T(lang, code)
+	}
+	if i := strings.Index(message, ": "); i > 0 {
+		if code, ok := codes[message[:i]]; ok {
+			return code, T(lang, code) + message[i:]
+		}
+	}
+	return "", message
+}
+
+// Label translates a printed document label given in English.
+func Label(lang Lang, english string) string {
+	if code, ok := labels[english]; ok {
+		return T(lang, code)
+	}
+	return english
+}
+
+// Negotiate picks the best supported language from an Accept-Language
+// header such as "lo-LA,lo;q=0.9,en;q=0.5". Region subtags are ignored.
+func Negotiate(header string) Lang {
+	type pref struct {
+		lang Lang
+		q    float64
+	}
+	var prefs []pref
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = f
+		}
+		base, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if q > 0 && Valid(base) {
+			prefs = append(prefs, pref{Lang(base), q})
+		}
+	}
+	if len(prefs) == 0 {
+		return Default
+	}
+	sort.SliceStable(prefs, func(i, j int) bool { return prefs[i].q > prefs[j].q })
+	return prefs[0].lang
+}
+
+type contextKey string
+
+const langKey = contextKey("lang")
+
+// WithLang stores the negotiated language on the request context.
+func WithLang(ctx context.Context, lang Lang) context.Context {
+	return context.WithValue(ctx, langKey, lang)
+}
+
+// FromContext returns the language stored by WithLang, or Default.
+func FromContext(ctx context.Context) Lang {
+	if lang, ok := ctx.Value(langKey).(Lang); ok {
+		return lang
+	}
+	return Default
+}
+
+// Request returns the language for r, negotiating it from the header when
+// the locale middleware has not run.
+func Request(r *http.Request) Lang {
+	if lang, ok := r.Context().Value(langKey).(Lang); ok {
+		return lang
+	}
+	return Negotiate(r.Header.Get("Accept-Language"))
+}
+
+// Pick returns the translation for lang from a name_translations map,
+// falling back to the untranslated name.
+func Pick(lang Lang, name string, translations map[string]string) string {
+	if t := translations[string(lang)]; t != "" {
+		return t
+	}
+	return name
+}
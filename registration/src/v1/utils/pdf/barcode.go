@@ -0,0 +1,84 @@
+package pdf
+
+import (
+	"fmt"
+)
+
+// code128Patterns holds the bar/space module widths for Code 128 symbols
+// 0-105 (105 is Start C); the stop pattern is kept separately.
+var code128Patterns = [106]string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232",
+}
+
+const (
+	code128StartB = 104
+	code128Stop   = "2331112"
+)
+
+// Code128 encodes printable ASCII data with code set B and returns the
+// alternating bar and space widths in modules, starting with a bar.
+func Code128(data string) ([]int, error) {
+	if data == "" {
+		return nil, fmt.Errorf("barcode data is empty")
+	}
+
+	symbols := []int{code128StartB}
+	checksum := code128StartB
+	for i, r := range data {
+		if r < 32 || r > 126 {
+			return nil, fmt.Errorf("barcode character %q is not supported", r)
+		}
+		v := int(r) - 32
+		symbols = append(symbols, v)
+		checksum += v * (i + 1)
+	}
+	symbols = append(symbols, checksum%103)
+
+	var modules []int
+	for _, s := range symbols {
+		for _, c := range code128Patterns[s] {
+			modules = append(modules, int(c-'0'))
+		}
+	}
+	for _, c := range code128Stop {
+		modules = append(modules, int(c-'0'))
+	}
+	return modules, nil
+}
+
+// Barcode draws a Code 128 barcode of data filling the w x h box at (x, y).
+func (d *Document) Barcode(x, y, w, h float64, data string) error {
+	modules, err := Code128(data)
+	if err != nil {
+		return err
+	}
+
+	total := 0
+	for _, m := range modules {
+		total += m
+	}
+	// Quiet zones of ten modules on each side.
+	unit := w / float64(total+20)
+	cx := x + unit*10
+
+	d.out("q 0 0 0 rg")
+	for i, m := range modules {
+		bw := unit * float64(m)
+		if i%2 == 0 {
+			d.out("%s %s %s %s re f", num(cx), num(d.y(y+h)), num(bw), num(h))
+		}
+		cx += bw
+	}
+	d.out("Q")
+	return nil
+}
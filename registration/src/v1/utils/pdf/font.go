@@ -0,0 +1,241 @@
+package pdf
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// face is a font as used inside one document.
+type face interface {
+	name() string
+	setName(string)
+	hasRune(r rune) bool
+	// advance returns the glyph width in 1/1000 em.
+	advance(r rune) float64
+	// encode converts runes into the byte string shown by Tj and marks the
+	// glyphs as used.
+	encode(runes []rune) []byte
+	used() bool
+	write(w *writer) (int, error)
+}
+
+// ============================================
+// Standard Type1 Font (WinAnsi)
+// ============================================
+
+type standardFace struct {
+	resName  string
+	baseFont string
+	widths   [95]float64 // widths for ' ' .. '~'
+	isUsed   bool
+}
+
+func newStandardFace(baseFont string, widths [95]float64) *standardFace {
+	return &standardFace{baseFont: baseFont, widths: widths}
+}
+
+func (f *standardFace) name() string       { return f.resName }
+func (f *standardFace) setName(n string)   { f.resName = n }
+func (f *standardFace) used() bool         { return f.isUsed }
+func (f *standardFace) hasRune(r rune) bool { return winAnsiByte(r) != 0 }
+
+func (f *standardFace) advance(r rune) float64 {
+	if r >= ' ' && r <= '~' {
+		return f.widths[r-' ']
+	}
+	return 556
+}
+
+func (f *standardFace) encode(runes []rune) []byte {
+	f.isUsed = true
+	out := make([]byte, 0, len(runes))
+	for _, r := range runes {
+		b := winAnsiByte(r)
+		if b == 0 {
+			b = '?'
+		}
+		out = append(out, b)
+	}
+	return out
+}
+
+func (f *standardFace) write(w *writer) (int, error) {
+	ref := w.alloc()
+	w.object(ref, fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", f.baseFont))
+	return ref, nil
+}
+
+// winAnsiByte maps a rune to its WinAnsi code, or 0 when it has none.
+func winAnsiByte(r rune) byte {
+	switch {
+	case r >= 0x20 && r <= 0x7E:
+		return byte(r)
+	case r >= 0xA0 && r <= 0xFF:
+		return byte(r)
+	}
+	switch r {
+	case '€':
+		return 0x80
+	case '‘':
+		return 0x91
+	case '’':
+		return 0x92
+	case '“':
+		return 0x93
+	case '”':
+		return 0x94
+	case '•':
+		return 0x95
+	case '–':
+		return 0x96
+	case '—':
+		return 0x97
+	}
+	return 0
+}
+
+// Glyph widths of the standard 14 fonts for printable ASCII (from the Adobe
+// AFM files).
+var helveticaWidths = [95]float64{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]float64{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
+
+// ============================================
+// Embedded TrueType Font (Type0 / Identity-H)
+// ============================================
+
+type trueTypeFace struct {
+	resName string
+	font    *TrueTypeFont
+	glyphs  map[uint16]rune
+}
+
+func newTrueTypeFace(f *TrueTypeFont) *trueTypeFace {
+	return &trueTypeFace{font: f, glyphs: make(map[uint16]rune)}
+}
+
+func (f *trueTypeFace) name() string     { return f.resName }
+func (f *trueTypeFace) setName(n string) { f.resName = n }
+func (f *trueTypeFace) used() bool       { return len(f.glyphs) > 0 }
+
+func (f *trueTypeFace) hasRune(r rune) bool {
+	_, ok := f.font.cmap[r]
+	return ok
+}
+
+func (f *trueTypeFace) advance(r rune) float64 {
+	return f.font.scaled(f.font.advanceWidth(f.font.cmap[r]))
+}
+
+func (f *trueTypeFace) encode(runes []rune) []byte {
+	out := make([]byte, 0, len(runes)*2)
+	for _, r := range runes {
+		gid := f.font.cmap[r]
+		if _, ok := f.glyphs[gid]; !ok {
+			f.glyphs[gid] = r
+		}
+		out = append(out, byte(gid>>8), byte(gid))
+	}
+	return out
+}
+
+func (f *trueTypeFace) write(w *writer) (int, error) {
+	ff := f.font
+	baseFont := sanitizeName(ff.Name)
+
+	gids := make([]int, 0, len(f.glyphs))
+	for gid := range f.glyphs {
+		gids = append(gids, int(gid))
+	}
+	sort.Ints(gids)
+
+	fileRef := w.alloc()
+	w.flateStream(fileRef, fmt.Sprintf("/Length1 %d ", len(ff.data)), ff.data)
+
+	descRef := w.alloc()
+	w.object(descRef, fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%s %s %s %s] /ItalicAngle 0 /Ascent %s /Descent %s /CapHeight %s /StemV 80 /FontFile2 %d 0 R >>",
+		baseFont,
+		num(ff.scaled(float64(ff.bbox[0]))), num(ff.scaled(float64(ff.bbox[1]))),
+		num(ff.scaled(float64(ff.bbox[2]))), num(ff.scaled(float64(ff.bbox[3]))),
+		num(ff.scaled(float64(ff.ascent))), num(ff.scaled(float64(ff.descent))),
+		num(ff.scaled(float64(ff.capHeight))), fileRef))
+
+	var widths strings.Builder
+	for _, gid := range gids {
+		fmt.Fprintf(&widths, "%d [%s] ", gid, num(ff.scaled(ff.advanceWidth(uint16(gid)))))
+	}
+
+	cidRef := w.alloc()
+	w.object(cidRef, fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /DW 1000 /W [%s] /CIDToGIDMap /Identity >>",
+		baseFont, descRef, widths.String()))
+
+	toUniRef := w.alloc()
+	w.flateStream(toUniRef, "", toUnicodeCMap(gids, f.glyphs))
+
+	fontRef := w.alloc()
+	w.object(fontRef, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		baseFont, cidRef, toUniRef))
+
+	return fontRef, nil
+}
+
+// toUnicodeCMap lets viewers extract and search the text drawn with glyph ids.
+func toUnicodeCMap(gids []int, glyphs map[uint16]rune) []byte {
+	var b strings.Builder
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	b.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	b.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	b.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+
+	for start := 0; start < len(gids); start += 100 {
+		end := start + 100
+		if end > len(gids) {
+			end = len(gids)
+		}
+		fmt.Fprintf(&b, "%d beginbfchar\n", end-start)
+		for _, gid := range gids[start:end] {
+			fmt.Fprintf(&b, "<%04X> %s\n", gid, utf16Hex(glyphs[uint16(gid)]))
+		}
+		b.WriteString("endbfchar\n")
+	}
+
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return []byte(b.String())
+}
+
+func utf16Hex(r rune) string {
+	if r > 0xFFFF {
+		r -= 0x10000
+		return fmt.Sprintf("<%04X%04X>", 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+	}
+	return fmt.Sprintf("<%04X>", r)
+}
+
+// sanitizeName strips characters that are not allowed in PDF names.
+func sanitizeName(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r > ' ' && r < 0x7F && !strings.ContainsRune("()<>[]{}/%#", r) {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		return "EmbeddedFont"
+	}
+	return b.String()
+}
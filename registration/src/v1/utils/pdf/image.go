@@ -0,0 +1,134 @@
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+)
+
+// Image is a raster image registered with a document.
+type Image struct {
+	name   string
+	Width  int
+	Height int
+
+	data       []byte
+	filter     string
+	colorSpace string
+	components int
+	alpha      []byte
+}
+
+// LoadImage registers a JPEG, PNG or GIF image with the document. JPEG data is
+// embedded as-is; other formats are re-encoded losslessly.
+func (d *Document) LoadImage(data []byte) (*Image, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decoding image: %w", err)
+	}
+	if cfg.Width == 0 || cfg.Height == 0 {
+		return nil, errors.New("image has no pixels")
+	}
+
+	img := &Image{
+		name:   fmt.Sprintf("Im%d", len(d.images)+1),
+		Width:  cfg.Width,
+		Height: cfg.Height,
+	}
+
+	if format == "jpeg" {
+		img.data = data
+		img.filter = "/DCTDecode"
+		switch cfg.ColorModel {
+		case color.GrayModel:
+			img.colorSpace, img.components = "/DeviceGray", 1
+		case color.CMYKModel:
+			img.colorSpace, img.components = "/DeviceCMYK", 4
+		default:
+			img.colorSpace, img.components = "/DeviceRGB", 3
+		}
+		// Validate the stream so a corrupt logo fails here rather than in the viewer.
+		if _, err := jpeg.Decode(bytes.NewReader(data)); err != nil {
+			return nil, fmt.Errorf("decoding jpeg: %w", err)
+		}
+	} else {
+		src, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("decoding image: %w", err)
+		}
+		encodeRaw(img, src)
+	}
+
+	d.images = append(d.images, img)
+	return img, nil
+}
+
+// encodeRaw converts any image into 8-bit RGB samples plus an optional soft
+// mask for transparency.
+func encodeRaw(img *Image, src image.Image) {
+	bounds := src.Bounds()
+	rgb := make([]byte, 0, bounds.Dx()*bounds.Dy()*3)
+	alpha := make([]byte, 0, bounds.Dx()*bounds.Dy())
+	opaque := true
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(src.At(x, y)).(color.NRGBA)
+			rgb = append(rgb, c.R, c.G, c.B)
+			alpha = append(alpha, c.A)
+			if c.A != 0xFF {
+				opaque = false
+			}
+		}
+	}
+
+	img.data = deflate(rgb)
+	img.filter = "/FlateDecode"
+	img.colorSpace, img.components = "/DeviceRGB", 3
+	if !opaque {
+		img.alpha = deflate(alpha)
+	}
+}
+
+func (img *Image) write(w *writer) int {
+	smask := ""
+	if img.alpha != nil {
+		maskRef := w.alloc()
+		w.stream(maskRef, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /FlateDecode ",
+			img.Width, img.Height), img.alpha)
+		smask = fmt.Sprintf("/SMask %d 0 R ", maskRef)
+	}
+
+	decode := ""
+	if img.colorSpace == "/DeviceCMYK" {
+		// Adobe CMYK JPEGs are stored inverted.
+		decode = "/Decode [1 0 1 0 1 0 1 0] "
+	}
+
+	ref := w.alloc()
+	w.stream(ref, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 /Filter %s %s%s",
+		img.Width, img.Height, img.colorSpace, img.filter, smask, decode), img.data)
+	return ref
+}
+
+// DrawImage places img with its top-left corner at (x, y) scaled to w x h.
+func (d *Document) DrawImage(img *Image, x, y, w, h float64) {
+	d.out("q %s 0 0 %s %s %s cm /%s Do Q", num(w), num(h), num(x), num(d.y(y+h)), img.name)
+}
+
+// FitImage draws img inside the box, preserving its aspect ratio, and returns
+// the size actually used.
+func (d *Document) FitImage(img *Image, x, y, maxW, maxH float64) (float64, float64) {
+	w, h := maxW, maxW*float64(img.Height)/float64(img.Width)
+	if h > maxH {
+		h = maxH
+		w = maxH * float64(img.Width) / float64(img.Height)
+	}
+	d.DrawImage(img, x, y, w, h)
+	return w, h
+}
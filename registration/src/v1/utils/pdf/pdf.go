@@ -0,0 +1,530 @@
+// Package pdf is a small, dependency-free PDF writer used to render business
+// documents (invoices, delivery notes, purchase orders, ...) on the server.
+//
+// Coordinates are expressed in points (1/72 inch) with the origin at the
+// top-left corner of the page; y grows downwards like on screen.
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+)
+
+// ============================================
+// Page Sizes
+// ============================================
+
+const (
+	A4Width  = 595.28
+	A4Height = 841.89
+)
+
+// FontStyle selects the regular or bold font chain.
+type FontStyle int
+
+const (
+	Regular FontStyle = iota
+	Bold
+)
+
+// Align controls horizontal text alignment inside a box.
+type Align int
+
+const (
+	AlignLeft Align = iota
+	AlignCenter
+	AlignRight
+)
+
+// ============================================
+// Document
+// ============================================
+
+type page struct {
+	content bytes.Buffer
+}
+
+// Document is an in-memory PDF document. It is not safe for concurrent use.
+type Document struct {
+	width, height float64
+
+	pages []*page
+	cur   *page
+
+	faces  map[FontStyle][]face
+	all    []face
+	images []*Image
+	alphas map[string]string
+
+	style    FontStyle
+	fontSize float64
+	fillRGB  [3]float64
+
+	footer func(d *Document, pageNum, pageCount int)
+
+	Title   string
+	Author  string
+	Subject string
+}
+
+// New creates an empty A4 portrait document. TrueType fonts passed in regular
+// and bold are tried in order for every character before falling back to the
+// built-in Helvetica, which only covers Latin-1.
+func New(regular, bold []*TrueTypeFont) *Document {
+	d := &Document{
+		width:    A4Width,
+		height:   A4Height,
+		faces:    make(map[FontStyle][]face),
+		alphas:   make(map[string]string),
+		fontSize: 10,
+	}
+
+	for _, f := range regular {
+		d.faces[Regular] = append(d.faces[Regular], d.register(newTrueTypeFace(f)))
+	}
+	for _, f := range bold {
+		d.faces[Bold] = append(d.faces[Bold], d.register(newTrueTypeFace(f)))
+	}
+	// Without a dedicated bold font reuse the regular TrueType chain so
+	// non-Latin text still renders in bold rows.
+	if len(bold) == 0 {
+		d.faces[Bold] = append(d.faces[Bold], d.faces[Regular]...)
+	}
+
+	d.faces[Regular] = append(d.faces[Regular], d.register(newStandardFace("Helvetica", helveticaWidths)))
+	d.faces[Bold] = append(d.faces[Bold], d.register(newStandardFace("Helvetica-Bold", helveticaBoldWidths)))
+
+	return d
+}
+
+func (d *Document) register(f face) face {
+	f.setName(fmt.Sprintf("F%d", len(d.all)+1))
+	d.all = append(d.all, f)
+	return f
+}
+
+// Width returns the page width in points.
+func (d *Document) Width() float64 { return d.width }
+
+// Height returns the page height in points.
+func (d *Document) Height() float64 { return d.height }
+
+// PageCount returns the number of pages added so far.
+func (d *Document) PageCount() int { return len(d.pages) }
+
+// SetFooter registers a callback drawn on every page when the document is
+// written, so it can print "page x of y".
+func (d *Document) SetFooter(fn func(d *Document, pageNum, pageCount int)) {
+	d.footer = fn
+}
+
+// AddPage starts a new page and makes it current.
+func (d *Document) AddPage() {
+	p := &page{}
+	d.pages = append(d.pages, p)
+	d.cur = p
+}
+
+func (d *Document) out(format string, args ...interface{}) {
+	if d.cur == nil {
+		d.AddPage()
+	}
+	fmt.Fprintf(&d.cur.content, format, args...)
+	d.cur.content.WriteByte('\n')
+}
+
+// y converts a top-left based coordinate into PDF user space.
+func (d *Document) y(v float64) float64 {
+	return d.height - v
+}
+
+// ============================================
+// Graphics State
+// ============================================
+
+// SetFont selects the font style and size (in points) for following text.
+func (d *Document) SetFont(style FontStyle, size float64) {
+	d.style = style
+	d.fontSize = size
+}
+
+// FontSize returns the current font size.
+func (d *Document) FontSize() float64 { return d.fontSize }
+
+// SetTextColor sets the RGB fill colour (0-255) used for text.
+func (d *Document) SetTextColor(r, g, b int) {
+	d.fillRGB = [3]float64{float64(r) / 255, float64(g) / 255, float64(b) / 255}
+}
+
+// SetLineWidth sets the stroke width in points.
+func (d *Document) SetLineWidth(w float64) {
+	d.out("%s w", num(w))
+}
+
+// SetStrokeColor sets the RGB stroke colour (0-255).
+func (d *Document) SetStrokeColor(r, g, b int) {
+	d.out("%s %s %s RG", num(float64(r)/255), num(float64(g)/255), num(float64(b)/255))
+}
+
+// SetFillColor sets the RGB fill colour (0-255) used for shapes.
+func (d *Document) SetFillColor(r, g, b int) {
+	d.out("%s %s %s rg", num(float64(r)/255), num(float64(g)/255), num(float64(b)/255))
+}
+
+// ============================================
+// Shapes
+// ============================================
+
+// Line draws a straight line.
+func (d *Document) Line(x1, y1, x2, y2 float64) {
+	d.out("%s %s m %s %s l S", num(x1), num(d.y(y1)), num(x2), num(d.y(y2)))
+}
+
+// Rect strokes a rectangle whose top-left corner is (x, y).
+func (d *Document) Rect(x, y, w, h float64) {
+	d.out("%s %s %s %s re S", num(x), num(d.y(y+h)), num(w), num(h))
+}
+
+// FillRect fills a rectangle with the current fill colour.
+func (d *Document) FillRect(x, y, w, h float64) {
+	d.out("%s %s %s %s re f", num(x), num(d.y(y+h)), num(w), num(h))
+}
+
+// ============================================
+// Text
+// ============================================
+
+type run struct {
+	face  face
+	runes []rune
+}
+
+// runs splits s into consecutive runs that can be drawn with a single face.
+func (d *Document) runs(s string) []run {
+	chain := d.faces[d.style]
+	var out []run
+	for _, r := range s {
+		if r == '\n' || r == '\r' || r == '\t' {
+			r = ' '
+		}
+		f := chain[len(chain)-1]
+		for _, c := range chain {
+			if c.hasRune(r) {
+				f = c
+				break
+			}
+		}
+		if n := len(out); n > 0 && out[n-1].face == f {
+			out[n-1].runes = append(out[n-1].runes, r)
+			continue
+		}
+		out = append(out, run{face: f, runes: []rune{r}})
+	}
+	return out
+}
+
+// TextWidth returns the width of s in points using the current font.
+func (d *Document) TextWidth(s string) float64 {
+	var w float64
+	for _, r := range d.runs(s) {
+		for _, c := range r.runes {
+			w += r.face.advance(c)
+		}
+	}
+	return w * d.fontSize / 1000
+}
+
+// Text draws s with its baseline at (x, y).
+func (d *Document) Text(x, y float64, s string) {
+	if s == "" {
+		return
+	}
+	d.out("q BT %s %s %s rg", num(d.fillRGB[0]), num(d.fillRGB[1]), num(d.fillRGB[2]))
+	d.out("%s %s Td", num(x), num(d.y(y)))
+	for _, r := range d.runs(s) {
+		d.out("/%s %s Tf <%x> Tj", r.face.name(), num(d.fontSize), r.face.encode(r.runes))
+	}
+	d.out("ET Q")
+}
+
+// TextIn draws s inside a box of width w starting at x, aligned as requested.
+// Text that does not fit is truncated with an ellipsis.
+func (d *Document) TextIn(x, y, w float64, s string, align Align) {
+	s = d.Truncate(s, w)
+	tw := d.TextWidth(s)
+	switch align {
+	case AlignCenter:
+		x += (w - tw) / 2
+	case AlignRight:
+		x += w - tw
+	}
+	d.Text(x, y, s)
+}
+
+// Truncate shortens s so it fits in w points, appending "..." when cut.
+func (d *Document) Truncate(s string, w float64) string {
+	if d.TextWidth(s) <= w {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		candidate := strings.TrimSpace(string(runes)) + "..."
+		if d.TextWidth(candidate) <= w {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// Wrap breaks s into lines no wider than w. Words are split on spaces; scripts
+// written without spaces (Lao, Thai) are broken between characters.
+func (d *Document) Wrap(s string, w float64) []string {
+	var lines []string
+	for _, para := range strings.Split(s, "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if d.TextWidth(candidate) <= w {
+				line = candidate
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			for d.TextWidth(word) > w {
+				runes := []rune(word)
+				n := len(runes) - 1
+				for n > 1 && d.TextWidth(string(runes[:n])) > w {
+					n--
+				}
+				lines = append(lines, string(runes[:n]))
+				word = string(runes[n:])
+			}
+			line = word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// Watermark draws large, translucent text diagonally across the current page.
+func (d *Document) Watermark(s string) {
+	prevStyle, prevSize, prevRGB := d.style, d.fontSize, d.fillRGB
+	d.SetFont(Bold, 96)
+	for d.TextWidth(s) > d.width*1.05 && d.fontSize > 24 {
+		d.fontSize -= 4
+	}
+	w := d.TextWidth(s)
+
+	angle := math.Atan2(d.height, d.width)
+	cos, sin := math.Cos(angle), math.Sin(angle)
+	cx, cy := d.width/2, d.height/2
+	// Start the baseline so the text is centred on the page after rotation.
+	tx := cx - cos*w/2 + sin*d.fontSize/3
+	ty := cy - sin*w/2 - cos*d.fontSize/3
+
+	d.out("q /%s gs", d.alpha(0.18))
+	d.out("%s %s %s %s %s %s cm", num(cos), num(sin), num(-sin), num(cos), num(tx), num(ty))
+	d.out("BT 0.6 0.6 0.6 rg 0 0 Td")
+	for _, r := range d.runs(s) {
+		d.out("/%s %s Tf <%x> Tj", r.face.name(), num(d.fontSize), r.face.encode(r.runes))
+	}
+	d.out("ET Q")
+
+	d.style, d.fontSize, d.fillRGB = prevStyle, prevSize, prevRGB
+}
+
+// alpha returns the name of an ExtGState with the given fill opacity.
+func (d *Document) alpha(a float64) string {
+	key := num(a)
+	if name, ok := d.alphas[key]; ok {
+		return name
+	}
+	name := fmt.Sprintf("GS%d", len(d.alphas)+1)
+	d.alphas[key] = name
+	return name
+}
+
+// ============================================
+// Output
+// ============================================
+
+type writer struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+// alloc reserves an object number without writing it yet.
+func (w *writer) alloc() int {
+	w.offsets = append(w.offsets, 0)
+	return len(w.offsets)
+}
+
+func (w *writer) begin(ref int) {
+	w.offsets[ref-1] = w.buf.Len()
+	fmt.Fprintf(&w.buf, "%d 0 obj\n", ref)
+}
+
+func (w *writer) end() {
+	w.buf.WriteString("endobj\n")
+}
+
+// object writes a complete dictionary object.
+func (w *writer) object(ref int, dict string) {
+	w.begin(ref)
+	w.buf.WriteString(dict)
+	w.buf.WriteString("\n")
+	w.end()
+}
+
+// stream writes a stream object; extra holds additional dictionary entries.
+func (w *writer) stream(ref int, extra string, data []byte) {
+	w.begin(ref)
+	fmt.Fprintf(&w.buf, "<< /Length %d %s>>\nstream\n", len(data), extra)
+	w.buf.Write(data)
+	w.buf.WriteString("\nendstream\n")
+	w.end()
+}
+
+// flateStream compresses data and writes it as a FlateDecode stream.
+func (w *writer) flateStream(ref int, extra string, data []byte) {
+	w.stream(ref, "/Filter /FlateDecode "+extra, deflate(data))
+}
+
+func deflate(data []byte) []byte {
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	zw.Write(data)
+	zw.Close()
+	return b.Bytes()
+}
+
+// WriteTo renders the document and writes it to out.
+func (d *Document) WriteTo(out io.Writer) (int64, error) {
+	b, err := d.Bytes()
+	if err != nil {
+		return 0, err
+	}
+	n, err := out.Write(b)
+	return int64(n), err
+}
+
+// Bytes renders the document to a byte slice.
+func (d *Document) Bytes() ([]byte, error) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+	if d.footer != nil {
+		for i, p := range d.pages {
+			d.cur = p
+			d.footer(d, i+1, len(d.pages))
+		}
+	}
+
+	w := &writer{}
+	w.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	catalogRef := w.alloc()
+	pagesRef := w.alloc()
+	infoRef := w.alloc()
+
+	// Fonts and images are shared by all pages through a single resource
+	// dictionary.
+	var res strings.Builder
+	res.WriteString("<< /ProcSet [/PDF /Text /ImageB /ImageC] /Font <<")
+	for _, f := range d.all {
+		if !f.used() {
+			continue
+		}
+		ref, err := f.write(w)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&res, " /%s %d 0 R", f.name(), ref)
+	}
+	res.WriteString(" >>")
+	if len(d.images) > 0 {
+		res.WriteString(" /XObject <<")
+		for _, img := range d.images {
+			fmt.Fprintf(&res, " /%s %d 0 R", img.name, img.write(w))
+		}
+		res.WriteString(" >>")
+	}
+	if len(d.alphas) > 0 {
+		res.WriteString(" /ExtGState <<")
+		for a, name := range d.alphas {
+			fmt.Fprintf(&res, " /%s << /Type /ExtGState /ca %s /CA %s >>", name, a, a)
+		}
+		res.WriteString(" >>")
+	}
+	res.WriteString(" >>")
+	resRef := w.alloc()
+	w.object(resRef, res.String())
+
+	var kids []string
+	for _, p := range d.pages {
+		contentRef := w.alloc()
+		w.flateStream(contentRef, "", p.content.Bytes())
+		pageRef := w.alloc()
+		w.object(pageRef, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources %d 0 R /Contents %d 0 R >>",
+			pagesRef, num(d.width), num(d.height), resRef, contentRef))
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageRef))
+	}
+
+	w.object(pagesRef, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)))
+	w.object(catalogRef, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesRef))
+	w.object(infoRef, fmt.Sprintf("<< /Title %s /Author %s /Subject %s /Producer (FoodHive ERP) /CreationDate (D:%s) >>",
+		textString(d.Title), textString(d.Author), textString(d.Subject), time.Now().UTC().Format("20060102150405Z")))
+
+	xref := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, off := range w.offsets {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n",
+		len(w.offsets)+1, catalogRef, infoRef, xref)
+
+	return w.buf.Bytes(), nil
+}
+
+// ============================================
+// Helpers
+// ============================================
+
+// num formats a number compactly for content streams.
+func num(v float64) string {
+	s := fmt.Sprintf("%.3f", v)
+	s = strings.TrimRight(s, "0")
+	s = strings.TrimSuffix(s, ".")
+	if s == "-0" || s == "" {
+		return "0"
+	}
+	return s
+}
+
+// textString encodes s as a UTF-16BE hex string suitable for the info
+// dictionary.
+func textString(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, r := range s {
+		if r > 0xFFFF {
+			r -= 0x10000
+			fmt.Fprintf(&b, "%04X%04X", 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+			continue
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	b.WriteString(">")
+	return b.String()
+}
@@ -0,0 +1,262 @@
+package pdf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// TrueTypeFont is a parsed TrueType font that can be embedded in documents.
+// It is immutable after loading and may be shared between documents.
+type TrueTypeFont struct {
+	Name string
+
+	data       []byte
+	unitsPerEm float64
+	bbox       [4]int16
+	ascent     int16
+	descent    int16
+	capHeight  int16
+	advances   []uint16
+	cmap       map[rune]uint16
+}
+
+var errShortFont = errors.New("truncated font data")
+
+// LoadTrueTypeFile reads and parses a .ttf file.
+func LoadTrueTypeFile(path string) (*TrueTypeFont, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading font %s: %w", path, err)
+	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	f, err := LoadTrueType(name, data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing font %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// LoadTrueType parses TrueType font data. Only glyf-based fonts are supported;
+// CFF flavoured OpenType (.otf) fonts are rejected.
+func LoadTrueType(name string, data []byte) (*TrueTypeFont, error) {
+	if len(data) < 12 {
+		return nil, errShortFont
+	}
+	switch string(data[:4]) {
+	case "\x00\x01\x00\x00", "true":
+	case "OTTO":
+		return nil, errors.New("CFF based OpenType fonts are not supported, use a TrueType (.ttf) font")
+	case "ttcf":
+		return nil, errors.New("font collections (.ttc) are not supported")
+	default:
+		return nil, errors.New("not a TrueType font")
+	}
+
+	tables := make(map[string][]byte)
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		rec := 12 + i*16
+		if rec+16 > len(data) {
+			return nil, errShortFont
+		}
+		tag := string(data[rec : rec+4])
+		off := int(binary.BigEndian.Uint32(data[rec+8:]))
+		length := int(binary.BigEndian.Uint32(data[rec+12:]))
+		if off < 0 || length < 0 || off+length > len(data) {
+			return nil, fmt.Errorf("table %q out of range", tag)
+		}
+		tables[tag] = data[off : off+length]
+	}
+
+	for _, tag := range []string{"head", "hhea", "hmtx", "maxp", "cmap", "glyf"} {
+		if _, ok := tables[tag]; !ok {
+			return nil, fmt.Errorf("missing %q table", tag)
+		}
+	}
+
+	f := &TrueTypeFont{Name: name, data: data}
+
+	head := tables["head"]
+	if len(head) < 54 {
+		return nil, errShortFont
+	}
+	f.unitsPerEm = float64(binary.BigEndian.Uint16(head[18:]))
+	if f.unitsPerEm == 0 {
+		f.unitsPerEm = 1000
+	}
+	for i := range f.bbox {
+		f.bbox[i] = int16(binary.BigEndian.Uint16(head[36+i*2:]))
+	}
+
+	hhea := tables["hhea"]
+	if len(hhea) < 36 {
+		return nil, errShortFont
+	}
+	f.ascent = int16(binary.BigEndian.Uint16(hhea[4:]))
+	f.descent = int16(binary.BigEndian.Uint16(hhea[6:]))
+	f.capHeight = f.ascent
+	numHMetrics := int(binary.BigEndian.Uint16(hhea[34:]))
+
+	if os2 := tables["OS/2"]; len(os2) >= 90 && binary.BigEndian.Uint16(os2) >= 2 {
+		f.capHeight = int16(binary.BigEndian.Uint16(os2[88:]))
+	}
+
+	hmtx := tables["hmtx"]
+	if len(hmtx) < numHMetrics*4 {
+		return nil, errShortFont
+	}
+	f.advances = make([]uint16, numHMetrics)
+	for i := range f.advances {
+		f.advances[i] = binary.BigEndian.Uint16(hmtx[i*4:])
+	}
+
+	cmap, err := parseCmap(tables["cmap"])
+	if err != nil {
+		return nil, err
+	}
+	f.cmap = cmap
+
+	return f, nil
+}
+
+// advanceWidth returns the advance of a glyph in font units.
+func (f *TrueTypeFont) advanceWidth(gid uint16) float64 {
+	if len(f.advances) == 0 {
+		return 0
+	}
+	if int(gid) < len(f.advances) {
+		return float64(f.advances[gid])
+	}
+	return float64(f.advances[len(f.advances)-1])
+}
+
+// scaled converts font units to 1/1000 em.
+func (f *TrueTypeFont) scaled(v float64) float64 {
+	return v * 1000 / f.unitsPerEm
+}
+
+// HasRune reports whether the font has a glyph for r.
+func (f *TrueTypeFont) HasRune(r rune) bool {
+	_, ok := f.cmap[r]
+	return ok
+}
+
+// parseCmap builds the rune to glyph mapping from the best Unicode subtable.
+func parseCmap(b []byte) (map[rune]uint16, error) {
+	if len(b) < 4 {
+		return nil, errShortFont
+	}
+	n := int(binary.BigEndian.Uint16(b[2:]))
+
+	var best []byte
+	bestScore := 0
+	for i := 0; i < n; i++ {
+		rec := 4 + i*8
+		if rec+8 > len(b) {
+			return nil, errShortFont
+		}
+		platform := binary.BigEndian.Uint16(b[rec:])
+		encoding := binary.BigEndian.Uint16(b[rec+2:])
+		off := int(binary.BigEndian.Uint32(b[rec+4:]))
+		if off+4 > len(b) {
+			continue
+		}
+		format := binary.BigEndian.Uint16(b[off:])
+
+		score := 0
+		switch {
+		case format == 12 && (platform == 3 && encoding == 10 || platform == 0):
+			score = 3
+		case format == 4 && platform == 3 && encoding == 1:
+			score = 2
+		case format == 4 && platform == 0:
+			score = 1
+		}
+		if score > bestScore {
+			best, bestScore = b[off:], score
+		}
+	}
+	if best == nil {
+		return nil, errors.New("no Unicode cmap subtable")
+	}
+
+	if binary.BigEndian.Uint16(best) == 12 {
+		return parseCmap12(best)
+	}
+	return parseCmap4(best)
+}
+
+func parseCmap4(b []byte) (map[rune]uint16, error) {
+	if len(b) < 14 {
+		return nil, errShortFont
+	}
+	segCount := int(binary.BigEndian.Uint16(b[6:])) / 2
+	endOff := 14
+	startOff := endOff + segCount*2 + 2
+	deltaOff := startOff + segCount*2
+	rangeOff := deltaOff + segCount*2
+	if rangeOff+segCount*2 > len(b) {
+		return nil, errShortFont
+	}
+
+	m := make(map[rune]uint16)
+	for i := 0; i < segCount; i++ {
+		end := int(binary.BigEndian.Uint16(b[endOff+i*2:]))
+		start := int(binary.BigEndian.Uint16(b[startOff+i*2:]))
+		delta := int(binary.BigEndian.Uint16(b[deltaOff+i*2:]))
+		ro := int(binary.BigEndian.Uint16(b[rangeOff+i*2:]))
+		if start > end || start == 0xFFFF {
+			continue
+		}
+		for c := start; c <= end; c++ {
+			var gid int
+			if ro == 0 {
+				gid = (c + delta) & 0xFFFF
+			} else {
+				addr := rangeOff + i*2 + ro + (c-start)*2
+				if addr+2 > len(b) {
+					break
+				}
+				gid = int(binary.BigEndian.Uint16(b[addr:]))
+				if gid != 0 {
+					gid = (gid + delta) & 0xFFFF
+				}
+			}
+			if gid != 0 {
+				m[rune(c)] = uint16(gid)
+			}
+		}
+	}
+	return m, nil
+}
+
+func parseCmap12(b []byte) (map[rune]uint16, error) {
+	if len(b) < 16 {
+		return nil, errShortFont
+	}
+	groups := int(binary.BigEndian.Uint32(b[12:]))
+	if 16+groups*12 > len(b) {
+		return nil, errShortFont
+	}
+
+	m := make(map[rune]uint16)
+	for i := 0; i < groups; i++ {
+		g := b[16+i*12:]
+		start := binary.BigEndian.Uint32(g)
+		end := binary.BigEndian.Uint32(g[4:])
+		gid := binary.BigEndian.Uint32(g[8:])
+		if end < start || end > 0x10FFFF {
+			continue
+		}
+		for c := start; c <= end; c++ {
+			if id := gid + (c - start); id != 0 && id <= 0xFFFF {
+				m[rune(c)] = uint16(id)
+			}
+		}
+	}
+	return m, nil
+}
@@ -0,0 +1,290 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Type int
+
+const (
+	String Type = iota
+	Int
+	Number
+	Date
+	Time
+	Bool
+)
+
+// cast is the SQL type a text parameter is converted to for comparison.
+func (t Type) cast() string {
+	switch t {
+	case Int:
+		return "bigint"
+	case Number:
+		return "numeric"
+	case Date:
+		return "date"
+	case Time:
+		return "timestamp"
+	case Bool:
+		return "boolean"
+	default:
+		return "text"
+	}
+}
+
+// check validates a filter value before it reaches the database so bad input
+// is a 400 rather than a SQL error.
+func (t Type) check(v string) bool {
+	var err error
+	switch t {
+	case Int:
+		_, err = strconv.ParseInt(v, 10, 64)
+	case Number:
+		_, err = strconv.ParseFloat(v, 64)
+	case Date:
+		_, err = time.Parse("2006-01-02", v)
+	case Time:
+		if _, err = time.Parse(time.RFC3339, v); err != nil {
+			if _, err = time.Parse("2006-01-02T15:04:05", v); err != nil {
+				_, err = time.Parse("2006-01-02", v)
+			}
+		}
+	case Bool:
+		_, err = strconv.ParseBool(v)
+	}
+	return err == nil
+}
+
+// Field maps an API field name to a SQL expression. Sortable fields must be
+// NOT NULL (wrap nullable columns in COALESCE) because keyset conditions do
+// not match NULLs. Enum columns should be cast to text.
+type Field struct {
+	Column   string
+	Type     Type
+	Sortable bool
+}
+
+// Schema whitelists the fields of one list. Key names a unique NOT NULL
+// field, normally the primary key, that is always the last sort key so the
+// order is total and cursors are stable.
+type Schema struct {
+	Fields  map[string]Field
+	Default []Sort
+	Key     string
+}
+
+// Clause is the SQL produced for one request. Where and Args are the filters
+// and are shared with the count query; Keyset, OrderBy and Limit with
+// PageArgs only apply to the page query. Cursor is a select expression the
+// service must read into the cursor list passed to Params.Trim.
+type Clause struct {
+	Where    string
+	Args     []interface{}
+	Keyset   string
+	OrderBy  string
+	Limit    string
+	PageArgs []interface{}
+	Cursor   string
+}
+
+// Build validates p against the schema and renders it as SQL. argNum is the
+// next free placeholder number after the service's own arguments.
+func Build(schema Schema, p *Params, argNum int) (*Clause, error) {
+	c := &Clause{}
+
+	// Filters
+	var conds []string
+	for _, f := range p.Filters {
+		field, ok := schema.Fields[f.Field]
+		if !ok {
+			return nil, fmt.Errorf("%w: cannot filter on %q", ErrInvalid, f.Field)
+		}
+		cond, args, err := filterSQL(field, f, argNum)
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, cond)
+		c.Args = append(c.Args, args...)
+		argNum += len(args)
+	}
+	for _, cond := range conds {
+		c.Where += " AND " + cond
+	}
+
+	// Sort, always ending with the unique key
+	sorts := p.Sort
+	if len(sorts) == 0 {
+		sorts = schema.Default
+	}
+	var keys []Sort
+	seen := map[string]bool{}
+	for _, s := range sorts {
+		field, ok := schema.Fields[s.Field]
+		if !ok || !field.Sortable {
+			return nil, fmt.Errorf("%w: cannot sort by %q", ErrInvalid, s.Field)
+		}
+		if seen[s.Field] {
+			continue
+		}
+		seen[s.Field] = true
+		keys = append(keys, s)
+	}
+	if !seen[schema.Key] {
+		desc := len(keys) > 0 && keys[len(keys)-1].Desc
+		keys = append(keys, Sort{Field: schema.Key, Desc: desc})
+	}
+
+	var order, exprs, sig []string
+	for _, k := range keys {
+		col := schema.Fields[k.Field].Column
+		dir := "ASC"
+		if k.Desc {
+			dir = "DESC"
+			sig = append(sig, "-"+k.Field)
+		} else {
+			sig = append(sig, k.Field)
+		}
+		order = append(order, col+" "+dir)
+		exprs = append(exprs, col)
+	}
+	c.OrderBy = "ORDER BY " + strings.Join(order, ", ")
+	c.Cursor = "json_build_array(" + strings.Join(exprs, ", ") + ")::text"
+	p.signature = strings.Join(sig, ",")
+
+	// Keyset condition from the cursor
+	if p.Cursor != "" {
+		values, err := decodeCursor(p.Cursor, p.signature, len(keys))
+		if err != nil {
+			return nil, err
+		}
+		cond, args := keysetSQL(schema, keys, values, argNum)
+		c.Keyset = " AND " + cond
+		c.PageArgs = append(c.PageArgs, args...)
+		argNum += len(args)
+	}
+
+	// One extra row tells whether there is a next page.
+	size := p.Size()
+	if p.Keyset() {
+		c.Limit = fmt.Sprintf("LIMIT $%d", argNum)
+		c.PageArgs = append(c.PageArgs, size+1)
+	} else {
+		page := p.Page
+		if page < 1 {
+			page = 1
+		}
+		p.Page, p.PageSize = page, size
+		c.Limit = fmt.Sprintf("LIMIT $%d OFFSET $%d", argNum, argNum+1)
+		c.PageArgs = append(c.PageArgs, size+1, (page-1)*size)
+	}
+
+	return c, nil
+}
+
+func filterSQL(field Field, f Filter, argNum int) (string, []interface{}, error) {
+	ph := func(i int) string { return fmt.Sprintf("$%d::text::%s", argNum+i, field.Type.cast()) }
+	bad := func() (string, []interface{}, error) {
+		return "", nil, fmt.Errorf("%w: invalid value %q for %s[%s]", ErrInvalid, f.Value, f.Field, f.Op)
+	}
+
+	switch f.Op {
+	case OpEq, OpNe, OpGt, OpGte, OpLt, OpLte:
+		if !field.Type.check(f.Value) {
+			return bad()
+		}
+		if field.Type == Bool && f.Op != OpEq && f.Op != OpNe {
+			return "", nil, fmt.Errorf("%w: %s only supports eq and ne", ErrInvalid, f.Field)
+		}
+		ops := map[Op]string{OpEq: "=", OpNe: "<>", OpGt: ">", OpGte: ">=", OpLt: "<", OpLte: "<="}
+		return fmt.Sprintf("%s %s %s", field.Column, ops[f.Op], ph(0)), []interface{}{f.Value}, nil
+
+	case OpIn, OpNotIn:
+		values := strings.Split(f.Value, ",")
+		for i := range values {
+			values[i] = strings.TrimSpace(values[i])
+			if !field.Type.check(values[i]) {
+				return bad()
+			}
+		}
+		cond := fmt.Sprintf("%s = ANY($%d::text[]::%s[])", field.Column, argNum, field.Type.cast())
+		if f.Op == OpNotIn {
+			cond = "NOT (" + cond + ")"
+		}
+		return cond, []interface{}{values}, nil
+
+	case OpBetween:
+		parts := strings.Split(f.Value, ",")
+		if len(parts) != 2 || !field.Type.check(strings.TrimSpace(parts[0])) || !field.Type.check(strings.TrimSpace(parts[1])) {
+			return bad()
+		}
+		return fmt.Sprintf("%s BETWEEN %s AND %s", field.Column, ph(0), ph(1)),
+			[]interface{}{strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])}, nil
+
+	case OpLike:
+		if field.Type != String {
+			return "", nil, fmt.Errorf("%w: like is only supported on text fields", ErrInvalid)
+		}
+		escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(f.Value)
+		return fmt.Sprintf("%s ILIKE $%d", field.Column, argNum), []interface{}{"%" + escaped + "%"}, nil
+
+	case OpNull:
+		isNull, err := strconv.ParseBool(f.Value)
+		if err != nil {
+			return bad()
+		}
+		if isNull {
+			return field.Column + " IS NULL", nil, nil
+		}
+		return field.Column + " IS NOT NULL", nil, nil
+	}
+
+	return "", nil, fmt.Errorf("%w: unknown operator %q", ErrInvalid, f.Op)
+}
+
+// keysetSQL renders "after the cursor row" for the sort keys. When every key
+// sorts the same way a row comparison is used, which Postgres can answer
+// from a composite index; mixed directions expand to an OR chain.
+func keysetSQL(schema Schema, keys []Sort, values []string, argNum int) (string, []interface{}) {
+	args := make([]interface{}, len(values))
+	ph := make([]string, len(values))
+	cols := make([]string, len(keys))
+	for i, k := range keys {
+		field := schema.Fields[k.Field]
+		args[i] = values[i]
+		ph[i] = fmt.Sprintf("$%d::text::%s", argNum+i, field.Type.cast())
+		cols[i] = field.Column
+	}
+
+	uniform := true
+	for _, k := range keys {
+		if k.Desc != keys[0].Desc {
+			uniform = false
+		}
+	}
+	if uniform {
+		op := ">"
+		if keys[0].Desc {
+			op = "<"
+		}
+		return fmt.Sprintf("(%s) %s (%s)", strings.Join(cols, ", "), op, strings.Join(ph, ", ")), args
+	}
+
+	var ors []string
+	for i, k := range keys {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, fmt.Sprintf("%s = %s", cols[j], ph[j]))
+		}
+		op := ">"
+		if k.Desc {
+			op = "<"
+		}
+		ands = append(ands, fmt.Sprintf("%s %s %s", cols[i], op, ph[i]))
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
@@ -0,0 +1,56 @@
+package query
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Select trims every item of a list response to the requested fields. Field
+// names are JSON keys; nested keys use dots ("invoice.invoice_number").
+// Unknown fields are ignored. Without fields the data is returned unchanged.
+func Select(data any, fields []string) (any, error) {
+	if len(fields) == 0 {
+		return data, nil
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var items []map[string]any
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+
+	out := make([]map[string]any, len(items))
+	for i, item := range items {
+		picked := map[string]any{}
+		for _, f := range fields {
+			pick(item, picked, strings.Split(f, "."))
+		}
+		out[i] = picked
+	}
+	return out, nil
+}
+
+// pick copies the value at path from src into dst, creating nested objects.
+func pick(src, dst map[string]any, path []string) {
+	v, ok := src[path[0]]
+	if !ok {
+		return
+	}
+	if len(path) == 1 {
+		dst[path[0]] = v
+		return
+	}
+	child, ok := v.(map[string]any)
+	if !ok {
+		return
+	}
+	next, ok := dst[path[0]].(map[string]any)
+	if !ok {
+		next = map[string]any{}
+		dst[path[0]] = next
+	}
+	pick(child, next, path[1:])
+}
@@ -0,0 +1,261 @@
+// Package query is the shared list query layer. List endpoints accept the
+// same URL parameters and services turn them into parameterized SQL through
+// a per-list Schema that whitelists the fields a client may use:
+//
+//	sort=-invoice_date,invoice_number     multi-field sort, "-" for descending
+//	total_amount[gte]=100                 comparison: eq ne gt gte lt lte
+//	invoice_date[between]=2026-01-01,2026-01-31
+//	status[in]=OPEN,PARTIAL               in / nin take comma separated values
+//	name[like]=rice                       case-insensitive contains, text only
+//	due_date[null]=false                  IS NULL / IS NOT NULL
+//	fields=id,invoice.invoice_number      sparse field selection on the response
+//	limit=50&cursor=...                   keyset pagination
+//	page=3&page_size=20                   offset pagination (legacy)
+//
+// Keyset cursors encode the sort values of the last row, so deep pages cost
+// the same as the first one. Offset pagination is kept for existing clients.
+package query
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalid wraps every error caused by a bad query string so handlers can
+// answer 400 instead of 500.
+var ErrInvalid = errors.New("invalid list query")
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+type Op string
+
+const (
+	OpEq      Op = "eq"
+	OpNe      Op = "ne"
+	OpGt      Op = "gt"
+	OpGte     Op = "gte"
+	OpLt      Op = "lt"
+	OpLte     Op = "lte"
+	OpIn      Op = "in"
+	OpNotIn   Op = "nin"
+	OpLike    Op = "like"
+	OpNull    Op = "null"
+	OpBetween Op = "between"
+)
+
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+type Filter struct {
+	Field string
+	Op    Op
+	Value string
+}
+
+// Params is a parsed list query. NextCursor and HasMore are filled in by the
+// service once the page has been read.
+type Params struct {
+	Sort     []Sort
+	Filters  []Filter
+	Fields   []string
+	Cursor   string
+	Limit    int
+	Page     int
+	PageSize int
+
+	NextCursor string
+	HasMore    bool
+
+	signature string
+}
+
+// reserved parameters are never treated as filters.
+var reserved = map[string]bool{
+	"sort": true, "fields": true, "cursor": true, "limit": true, "page": true, "page_size": true,
+}
+
+var filterKeyRX = regexp.MustCompile(`^([a-z][a-z0-9_.]*)\[([a-z]+)\]$`)
+
+// Parse reads the shared list parameters from a query string. Plain
+// parameters such as status=OPEN are left to the endpoint's own filters;
+// only bracketed keys are parsed as shared filters.
+func Parse(values url.Values) (*Params, error) {
+	p := &Params{}
+
+	if s := values.Get("sort"); s != "" {
+		for _, part := range strings.Split(s, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			p.Sort = append(p.Sort, Sort{Field: strings.TrimLeft(part, "+-"), Desc: strings.HasPrefix(part, "-")})
+		}
+	}
+
+	if s := values.Get("fields"); s != "" {
+		for _, f := range strings.Split(s, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				p.Fields = append(p.Fields, f)
+			}
+		}
+	}
+
+	p.Cursor = values.Get("cursor")
+
+	var err error
+	if p.Limit, err = optionalInt(values, "limit"); err != nil {
+		return nil, err
+	}
+	if p.Page, err = optionalInt(values, "page"); err != nil {
+		return nil, err
+	}
+	if p.PageSize, err = optionalInt(values, "page_size"); err != nil {
+		return nil, err
+	}
+
+	// Walk keys in order so the generated SQL is stable between requests.
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if reserved[key] {
+			continue
+		}
+		vals := values[key]
+		m := filterKeyRX.FindStringSubmatch(key)
+		if m == nil {
+			continue
+		}
+		for _, v := range vals {
+			p.Filters = append(p.Filters, Filter{Field: m[1], Op: Op(m[2]), Value: v})
+		}
+	}
+
+	return p, nil
+}
+
+func optionalInt(values url.Values, key string) (int, error) {
+	s := values.Get(key)
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%w: %s must be a positive number", ErrInvalid, key)
+	}
+	return n, nil
+}
+
+// Default returns p, or offset parameters built from a legacy filter struct
+// when the caller did not go through Parse (internal callers). The legacy
+// page is ignored when the request asked for a cursor or a limit.
+func Default(p *Params, page, pageSize int) *Params {
+	if p == nil {
+		p = &Params{}
+	}
+	if p.Page == 0 && p.Cursor == "" && p.Limit == 0 {
+		p.Page = page
+	}
+	if p.PageSize == 0 {
+		p.PageSize = pageSize
+	}
+	return p
+}
+
+// Keyset reports whether the request uses cursor pagination. A cursor or a
+// limit without a page selects it; total counts are skipped in this mode.
+func (p *Params) Keyset() bool {
+	return p.Cursor != "" || (p.Limit > 0 && p.Page == 0)
+}
+
+// Size is the number of rows returned per page after defaults and limits.
+func (p *Params) Size() int {
+	n := p.PageSize
+	if p.Keyset() {
+		n = p.Limit
+	}
+	if n < 1 {
+		return DefaultLimit
+	}
+	if n > MaxLimit {
+		return MaxLimit
+	}
+	return n
+}
+
+// Trim is called with the cursor column of every row read (one more than the
+// page size is fetched). It records HasMore and NextCursor and returns how
+// many rows belong to the page.
+func (p *Params) Trim(cursors []string) int {
+	n := p.Size()
+	if len(cursors) <= n {
+		p.HasMore = false
+		p.NextCursor = ""
+		return len(cursors)
+	}
+	p.HasMore = true
+	p.NextCursor = encodeCursor(p.signature, cursors[n-1])
+	return n
+}
+
+// ============================================
+// Cursor Encoding
+// ============================================
+
+type cursorToken struct {
+	Sort   string            `json:"s"`
+	Values []json.RawMessage `json:"v"`
+}
+
+// encodeCursor wraps the JSON array of sort values produced by the database
+// together with the sort it belongs to.
+func encodeCursor(signature, values string) string {
+	token, _ := json.Marshal(cursorToken{Sort: signature, Values: rawArray(values)})
+	return base64.RawURLEncoding.EncodeToString(token)
+}
+
+func rawArray(values string) []json.RawMessage {
+	var out []json.RawMessage
+	json.Unmarshal([]byte(values), &out)
+	return out
+}
+
+func decodeCursor(cursor, signature string, n int) ([]string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalid)
+	}
+	var token cursorToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalid)
+	}
+	if token.Sort != signature || len(token.Values) != n {
+		return nil, fmt.Errorf("%w: cursor does not match the requested sort", ErrInvalid)
+	}
+
+	values := make([]string, n)
+	for i, raw := range token.Values {
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			values[i] = s
+			continue
+		}
+		values[i] = string(raw)
+	}
+	return values, nil
+}
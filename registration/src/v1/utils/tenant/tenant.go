@@ -0,0 +1,30 @@
+// Package tenant carries the company a request works in.
+//
+// Customers, vendors, the chart of accounts, fiscal years and the sales,
+// purchasing and receivable documents all belong to one company. The company
+// is picked at login and stored in the token; the auth middleware puts it on
+// the request context and services read it from there to scope their queries.
+package tenant
+
+import "context"
+
+// DefaultCompany is the company that data from before multi-company support
+// was migrated into. Tokens without a company claim work in it.
+const DefaultCompany = 1
+
+type contextKey string
+
+const companyKey = contextKey("company")
+
+// WithCompany stores the selected company on the context.
+func WithCompany(ctx context.Context, companyID int) context.Context {
+	return context.WithValue(ctx, companyKey, companyID)
+}
+
+// Company returns the company stored by WithCompany, or DefaultCompany.
+func Company(ctx context.Context) int {
+	if id, ok := ctx.Value(companyKey).(int); ok && id > 0 {
+		return id
+	}
+	return DefaultCompany
+}
@@ -8,12 +8,19 @@ import (
 	"github.com/go-chi/chi/v5"
 
 	// Routes - Currently implemented
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/analytics"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/ap"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/ar"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/bank"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/catch_weight"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/commission"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/company"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/customer"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/customer_item"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/delivery"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/department"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/document"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/edi"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/employee"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/finance"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/gl"
@@ -21,11 +28,18 @@ import (
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/login"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/payroll"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/picking"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/portal"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/portal_user"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/pricing"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/product"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/purchase_order"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/report"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/requisition"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/rma"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/role"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/sales_order"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/search"
+	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/standing_order"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/vendor"
 	"github.com/anas-dev-92/FoodHive/registration/src/v1/routes/warehouse"
 	// TODO: Uncomment as routes are implemented
@@ -48,24 +62,38 @@ func Router(
 	// ===========================================
 	app.Mount("/auth", login.Router(db, jwtService))
 
+	// ===========================================
+	// Customer Portal (own sign-in)
+	// ===========================================
+	app.Mount("/portal", portal.Router(db, jwtService))
+
 	// ===========================================
 	// Phase 1: Foundation - Master Data
 	// ===========================================
+	app.Mount("/companies", company.Router(db, jwtService, authService))
 	app.Mount("/employees", employee.Router(db, jwtService, authService))
 	app.Mount("/departments", department.Router(db, jwtService, authService))
 	app.Mount("/roles", role.Router(db, jwtService, authService))
 	app.Mount("/customers", customer.Router(db, jwtService, authService))
+	app.Mount("/customer-items", customer_item.Router(db, jwtService, authService))
+	app.Mount("/portal-users", portal_user.Router(db, jwtService, authService))
 	app.Mount("/vendors", vendor.Router(db, jwtService, authService))
 	app.Mount("/warehouses", warehouse.Router(db, jwtService, authService))
 	app.Mount("/inventory", inventory.Router(db, jwtService, authService))
+	app.Mount("/search", search.Router(db, jwtService, authService))
 
 	// ===========================================
 	// Phase 2: Core ERP - Transactions
 	// ===========================================
 	app.Mount("/purchase-orders", purchase_order.Router(db, jwtService, authService))
+	app.Mount("/requisitions", requisition.Router(db, jwtService, authService))
 	app.Mount("/ar", ar.Router(db, jwtService, authService))
 	app.Mount("/ap", ap.Router(db, jwtService, authService))
 	app.Mount("/sales-orders", sales_order.Router(db, jwtService, authService))
+	app.Mount("/standing-orders", standing_order.Router(db, jwtService, authService))
+	app.Mount("/rma", rma.Router(db, jwtService, authService))
+	app.Mount("/deliveries", delivery.Router(db, jwtService, authService))
+	app.Mount("/edi", edi.Router(db, jwtService, authService))
 
 	// ===========================================
 	// Phase 3: Advanced - Financial
@@ -74,8 +102,12 @@ func Router(
 	app.Mount("/pricing", pricing.Router(db, jwtService, authService))
 	app.Mount("/bank", bank.Router(db, jwtService, authService))
 	app.Mount("/payroll", payroll.Router(db, jwtService, authService))
+	app.Mount("/commissions", commission.Router(db, jwtService, authService))
 	app.Mount("/finance", finance.Router(db, jwtService, authService))
 	app.Mount("/products", product.Router(db, jwtService, authService))
+	app.Mount("/documents", document.Router(db, jwtService, authService))
+	app.Mount("/reports", report.Router(db, jwtService, authService))
+	app.Mount("/analytics", analytics.Router(db, jwtService, authService))
 
 	// ===========================================
 	// Phase 4: WMS - Warehouse Operations